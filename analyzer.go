@@ -29,9 +29,33 @@ type LogAnalysis struct {
 	HourLevelCounts     map[int]map[string]int    // Hour -> Level -> Count
 	DayLevelCounts      map[string]map[string]int // Day -> Level -> Count
 	MonthLevelCounts    map[string]map[string]int // Month -> Level -> Count
+	Activity            []CountedItem             // Entries per --bucket period, in chronological order
+	ActivityLevelCounts map[string]map[string]int // Period -> Level -> Count
+	ActivityBucket      string                    // Period of Activity, empty without --bucket
 	CommonPatterns      []CountedItem
 	NotificationTypes   []CountedItem   // For notification logs: message, clear, etc.
 	NotificationStatuses []CountedItem  // For notification logs: Sent, Received, etc.
+	LoggingGaps          []LogGap       // Unusually long silences, longest first
+	TypicalInterval      time.Duration  // Median interval between consecutive entries
+	GapThreshold         time.Duration  // Minimum silence reported as a gap
+	ErrorSignatures      []ErrorSignature // Clustered errors with first/last seen times
+	OngoingSignatures    int              // Error signatures still occurring, before the --top limit
+	SignatureCount       int              // Error signatures, before the --top limit
+	Incidents            IncidentMetrics  // Error bursts and error-free periods
+	TopIPs               []CountedItem    // Client IPs from the ip_address extra
+	TopErrorIPs          []CountedItem    // Client IPs among error entries
+	TopUserAgents        []CountedItem    // Clients from the user_agent extra
+	TopErrorUserAgents   []CountedItem    // Clients among error entries
+	ErrorCorrelations    []ErrorCorrelation // Extras values overrepresented among errors
+	RuntimeMetrics       []RuntimeMetric  // Goroutine, memory and DB connection time series
+	Restarts             []time.Time      // Detected server starts
+	ClockSkews           []ClockSkew      // Clock skew of the nodes of a support packet
+	RuleFindings         []RuleFinding    // Findings of the user-defined analysis rules
+	ComplianceExports    ExportMonitor    // Compliance/message export job runs
+	DataRetention        RetentionMonitor // Data retention job runs
+	FetchErrors          []HostFetchErrors // Image proxy and link preview errors by host
+	PushProxy            PushProxyAnalysis // Push proxy failures and the notification delivery funnel
+	NotificationLatency  NotificationLatency // Time from sending notifications to their reception, per platform
 }
 
 // TimeRange represents the time span of analyzed logs
@@ -46,8 +70,10 @@ type CountedItem struct {
 	Count int
 }
 
-// analyzeAndDisplayStats analyzes log entries and displays statistics
-func analyzeAndDisplayStats(logs []LogEntry, writer io.Writer, showDupes bool, verboseAnalysis bool) {
+// analyzeAndDisplayStats analyzes log entries and displays statistics.
+// topLimit controls how many top sources, users and error messages are kept
+// (0 keeps all of them) and fullOutput lists every kept item in the output.
+func analyzeAndDisplayStats(logs []LogEntry, writer io.Writer, showDupes bool, verboseAnalysis bool, topLimit int, fullOutput bool) {
 	if len(logs) == 0 {
 		_, _ = fmt.Fprintln(writer, "No log entries to analyze.")
 		return
@@ -73,177 +99,81 @@ func analyzeAndDisplayStats(logs []LogEntry, writer io.Writer, showDupes bool, v
 	// Only consider logs deduplicated if they actually have duplicate counts AND showDupes is true
 	isDeduplicated := hasDuplicateCounts && totalEntries > uniqueEntries && showDupes
 
-	analysis := analyzeLogs(logs, showDupes)
-	displayAnalysis(analysis, writer, isDeduplicated, uniqueEntries, verboseAnalysis)
+	analysis := analyzeLogs(logs, showDupes, topLimit)
+	displayAnalysis(analysis, writer, isDeduplicated, uniqueEntries, verboseAnalysis, fullOutput)
 }
 
-// analyzeLogs performs analysis on log entries
-func analyzeLogs(logs []LogEntry, showDupes bool) LogAnalysis {
-	analysis := LogAnalysis{
-		TotalEntries:     len(logs),
-		LevelCounts:      make(map[string]int),
-		HourLevelCounts:  make(map[int]map[string]int),
-		DayLevelCounts:   make(map[string]map[string]int),
-		MonthLevelCounts: make(map[string]map[string]int),
-	}
-
-	// Initialize maps for counting
-	sourceCounts := make(map[string]int)
-	userCounts := make(map[string]int)
-	errorMsgCounts := make(map[string]int)
-	hourCounts := make(map[int]int)
-	dayOfWeekCounts := make(map[string]int)
-	monthCounts := make(map[string]int)
-	patternCounts := make(map[string]int)
-	notificationTypeCounts := make(map[string]int)
-	notificationStatusCounts := make(map[string]int)
-
-	// Set initial time range
-	if len(logs) > 0 {
-		analysis.TimeRange.Start = logs[0].Timestamp
-		analysis.TimeRange.End = logs[0].Timestamp
-	}
-
-	// Track total entries including duplicates
-	totalWithDuplicates := 0
-
-	// Process each log entry
+// analyzeLogs performs analysis on log entries, keeping at most topLimit
+// top sources, users and error messages (0 keeps all of them)
+func analyzeLogs(logs []LogEntry, showDupes bool, topLimit int) LogAnalysis {
+	// Statistics computed one entry at a time
+	stats := newLogStats(showDupes)
 	for _, log := range logs {
-		// Get the count (either the duplicate count or 1 if not set)
-		count := 1
-		if showDupes && log.DuplicateCount > 1 {
-			count = log.DuplicateCount
-		}
-		totalWithDuplicates += count
-		// Update time range
-		if log.Timestamp.Before(analysis.TimeRange.Start) {
-			analysis.TimeRange.Start = log.Timestamp
-		}
-		if log.Timestamp.After(analysis.TimeRange.End) {
-			analysis.TimeRange.End = log.Timestamp
-		}
-
-		// Count log levels
-		level := strings.ToUpper(log.Level)
-		analysis.LevelCounts[level] += count
-
-		// Count sources
-		if log.Source != "" {
-			sourceCounts[log.Source] += count
-		}
-
-		// Count users
-		if log.User != "" {
-			userCounts[log.User] += count
-		}
+		stats.add(log)
+	}
+	analysis := stats.result(topLimit)
 
-		// Count error messages
-		if strings.EqualFold(log.Level, "error") || strings.EqualFold(log.Level, "fatal") {
-			// Get first 50 chars of message or full message if shorter
-			shortMsg := log.Message
-			if len(shortMsg) > 50 {
-				shortMsg = shortMsg[:50] + "..."
-			}
-			errorMsgCounts[shortMsg] += count
-		}
+	// Runtime metrics logged by periodic health entries
+	analysis.RuntimeMetrics = analyzeRuntimeMetrics(logs)
 
-		// Count activity by hour
-		hour := log.Timestamp.Hour()
-		hourCounts[hour] += count
-		
-		// Track level distribution by hour
-		if _, exists := analysis.HourLevelCounts[hour]; !exists {
-			analysis.HourLevelCounts[hour] = make(map[string]int)
-		}
-		analysis.HourLevelCounts[hour][level] += count
-		
-		// Count activity by day of week
-		dayOfWeek := log.Timestamp.Weekday().String()
-		dayOfWeekCounts[dayOfWeek] += count
-		
-		// Track level distribution by day of week
-		if _, exists := analysis.DayLevelCounts[dayOfWeek]; !exists {
-			analysis.DayLevelCounts[dayOfWeek] = make(map[string]int)
-		}
-		analysis.DayLevelCounts[dayOfWeek][level] += count
-		
-		// Count activity by month
-		month := log.Timestamp.Month().String()
-		monthCounts[month] += count
-		
-		// Track level distribution by month
-		if _, exists := analysis.MonthLevelCounts[month]; !exists {
-			analysis.MonthLevelCounts[month] = make(map[string]int)
-		}
-		analysis.MonthLevelCounts[month][level] += count
-
-		// Identify common patterns in messages
-		words := strings.Fields(log.Message)
-		if len(words) > 0 {
-			pattern := words[0]
-			if len(words) > 1 {
-				pattern += " " + words[1]
-			}
-			patternCounts[pattern] += count
-		}
-		
-		// Count notification types and statuses if present
-		if log.LogSource == "notifications" {
-			if log.Type != "" {
-				notificationTypeCounts[log.Type] += count
-			}
-			if log.Status != "" {
-				notificationStatusCounts[log.Status] += count
-			}
-		}
-	}
+	// Image proxy and link preview errors grouped by destination host
+	analysis.FetchErrors = analyzeFetchErrors(logs, showDupes, topLimit)
 
-	// Calculate error rate
-	errorCount := analysis.LevelCounts["ERROR"] + analysis.LevelCounts["FATAL"]
-	analysis.ErrorRate = float64(errorCount) / float64(totalWithDuplicates) * 100
+	// Extras values the errors have in common
+	analysis.ErrorCorrelations = analyzeErrorCorrelations(logs, showDupes, topLimit)
 
-	// Update total entries to include duplicates
-	analysis.TotalEntries = totalWithDuplicates
+	// Findings of the rules of the config file and --rules
+	analysis.RuleFindings = evaluateRules(analysisRules, logs, showDupes)
 
-	// Convert maps to sorted slices
-	analysis.TopSources = mapToSortedSlice(sourceCounts, 10)
-	analysis.TopUsers = mapToSortedSlice(userCounts, 10)
-	analysis.TopErrorMessages = mapToSortedSlice(errorMsgCounts, 10)
+	// Error signatures count the duplicates of deduplicated entries until their latest one
+	analysis.ErrorSignatures, analysis.OngoingSignatures, analysis.SignatureCount = analyzeErrorSignatures(logs, analysis.TimeRange, topLimit)
 
-	// Convert hourCounts (map[int]int) to string keys for mapToSortedSlice
-	hourCountsStr := make(map[string]int)
-	for hour, count := range hourCounts {
-		hourCountsStr[fmt.Sprintf("%d", hour)] = count
+	// Deduplicated entries only keep their first and last timestamps, which would show up
+	// as false gaps, bursts and restarts, so only track these for complete logs
+	if hasDuplicateCounts(logs) {
+		logger.Warn("Gaps, incidents, restarts, clock skew, job runs and push notifications are not analyzed for deduplicated entries, analyze without --trim to see them")
+	} else {
+		analysis.LoggingGaps, analysis.TypicalInterval, analysis.GapThreshold = detectLoggingGaps(logs)
+		analysis.Incidents = analyzeIncidentMetrics(logs, analysis.TimeRange)
+		analysis.Restarts = detectRestarts(logs)
+		analysis.ClockSkews = estimateClockSkew(logs)
+		analysis.ComplianceExports = analyzeComplianceExports(logs, analysis.TimeRange)
+		analysis.DataRetention = analyzeDataRetention(logs)
+		analysis.PushProxy = analyzePushProxy(logs)
+		analysis.NotificationLatency = analyzeNotificationLatency(logs)
 	}
-	analysis.BusiestHours = mapToSortedSlice(hourCountsStr, 24)
-	
-	// Add day of week and month activity
-	analysis.ActivityByDayOfWeek = mapToSortedSlice(dayOfWeekCounts, 7)
-	analysis.ActivityByMonth = mapToSortedSlice(monthCounts, 12)
-
-	analysis.CommonPatterns = mapToSortedSlice(patternCounts, 10)
-	
-	// Add notification-specific information if present
-	analysis.NotificationTypes = mapToSortedSlice(notificationTypeCounts, 10) 
-	analysis.NotificationStatuses = mapToSortedSlice(notificationStatusCounts, 10)
 
 	return analysis
 }
 
-// mapToSortedSlice converts a map to a sorted slice of CountedItems
+// hasDuplicateCounts reports whether any entry represents several deduplicated entries
+func hasDuplicateCounts(logs []LogEntry) bool {
+	for _, log := range logs {
+		if log.DuplicateCount > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// mapToSortedSlice converts a map to a sorted slice of CountedItems.
+// A limit of 0 or less keeps all items.
 func mapToSortedSlice(m map[string]int, limit int) []CountedItem {
 	var items []CountedItem
 	for k, v := range m {
 		items = append(items, CountedItem{Item: k, Count: v})
 	}
 
-	// Sort by count (descending)
+	// Sort by count (descending), then by name so that ties are listed the same on every run
 	sort.Slice(items, func(i, j int) bool {
-		return items[i].Count > items[j].Count
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Item < items[j].Item
 	})
 
 	// Limit the number of items
-	if len(items) > limit {
+	if limit > 0 && len(items) > limit {
 		items = items[:limit]
 	}
 
@@ -305,7 +235,8 @@ func formatHeaderStats(totalEntries int, isDeduplicated bool, uniqueEntries int,
 // formatLevelDistribution formats the log level distribution line
 func formatLevelDistribution(levelCounts map[string]int, totalEntries int, showPercentages bool) string {
 	var parts []string
-	for level, count := range levelCounts {
+	for _, item := range mapToSortedSlice(levelCounts, 0) {
+		level, count := item.Item, item.Count
 		levelColor := getLevelColor(level)
 		if showPercentages {
 			percentage := float64(count) / float64(totalEntries) * 100
@@ -317,11 +248,11 @@ func formatLevelDistribution(levelCounts map[string]int, totalEntries int, showP
 	return strings.Join(parts, " • ")
 }
 
-// formatTopItemsLine formats a line of top N items with counts
+// formatTopItemsLine formats a line of top N items with counts (0 formats all items)
 func formatTopItemsLine(items []CountedItem, maxItems int, truncateLength int) string {
 	var parts []string
 	for i, item := range items {
-		if i >= maxItems {
+		if maxItems > 0 && i >= maxItems {
 			break
 		}
 		text := item.Item
@@ -333,6 +264,14 @@ func formatTopItemsLine(items []CountedItem, maxItems int, truncateLength int) s
 	return strings.Join(parts, " • ")
 }
 
+// userAgentTruncateLength returns how many characters of a user agent to show
+func userAgentTruncateLength(fullOutput bool) int {
+	if fullOutput {
+		return 0
+	}
+	return 50
+}
+
 // findMaxCountAndCreateMap finds the maximum count and creates a map for easier lookup
 func findMaxCountAndCreateMap(items []CountedItem) (int, map[string]int) {
 	maxCount := 0
@@ -368,8 +307,9 @@ func createHourMap(items []CountedItem) (int, map[int]int) {
 	return maxCount, hourMap
 }
 
-// displayAnalysis prints the analysis results
-func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool, uniqueEntries int, verboseAnalysis bool) {
+// displayAnalysis prints the analysis results. When fullOutput is set, every
+// kept source and error message is listed instead of the top 3.
+func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool, uniqueEntries int, verboseAnalysis bool, fullOutput bool) {
 
 	// Calculate duration once
 	duration := analysis.TimeRange.End.Sub(analysis.TimeRange.Start).Round(time.Second)
@@ -391,9 +331,18 @@ func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool
 	levelDistribution := formatLevelDistribution(analysis.LevelCounts, analysis.TotalEntries, verboseAnalysis)
 	_, _ = fmt.Fprintf(writer, "%sLevels:%s %s\n", colorSubHeader, colorReset, levelDistribution)
 
+	// Findings of the user-defined analysis rules
+	displayRuleFindings(analysis, writer, verboseAnalysis)
+
+	// Number of top items to show per line (0 shows all of them)
+	maxItems := 3
+	if fullOutput {
+		maxItems = 0
+	}
+
 	// Top sources
 	if len(analysis.TopSources) > 0 {
-		sourcesLine := formatTopItemsLine(analysis.TopSources, 3, 0)
+		sourcesLine := formatTopItemsLine(analysis.TopSources, maxItems, 0)
 		_, _ = fmt.Fprintf(writer, "%sSources:%s %s\n", colorSubHeader, colorReset, sourcesLine)
 	}
 
@@ -404,12 +353,47 @@ func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool
 		if !verboseAnalysis {
 			truncateLength = 30
 		}
-		errorsLine := formatTopItemsLine(analysis.TopErrorMessages, 3, truncateLength)
+		if fullOutput {
+			truncateLength = 0
+		}
+		errorsLine := formatTopItemsLine(analysis.TopErrorMessages, maxItems, truncateLength)
 		_, _ = fmt.Fprintf(writer, "%sTop Errors:%s %s\n", colorSubHeader, colorReset, errorsLine)
 	}
 
-	// Peak hours - only in compact mode
-	if !verboseAnalysis {
+	// Top users - only when listing everything
+	if fullOutput && len(analysis.TopUsers) > 0 {
+		usersLine := formatTopItemsLine(analysis.TopUsers, maxItems, 0)
+		_, _ = fmt.Fprintf(writer, "%sUsers:%s %s\n", colorSubHeader, colorReset, usersLine)
+	}
+
+	// Client IPs and user agents (if present)
+	if len(analysis.TopIPs) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sClient IPs:%s %s\n", colorSubHeader, colorReset,
+			formatTopItemsLine(analysis.TopIPs, maxItems, 0))
+	}
+	if len(analysis.TopUserAgents) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sUser Agents:%s %s\n", colorSubHeader, colorReset,
+			formatTopItemsLine(analysis.TopUserAgents, maxItems, userAgentTruncateLength(fullOutput)))
+	}
+	if verboseAnalysis && len(analysis.TopErrorIPs) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sClient IPs (errors):%s %s\n", colorSubHeader, colorReset,
+			formatTopItemsLine(analysis.TopErrorIPs, maxItems, 0))
+	}
+	if verboseAnalysis && len(analysis.TopErrorUserAgents) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sUser Agents (errors):%s %s\n", colorSubHeader, colorReset,
+			formatTopItemsLine(analysis.TopErrorUserAgents, maxItems, userAgentTruncateLength(fullOutput)))
+	}
+	displayErrorCorrelations(analysis, writer, verboseAnalysis)
+
+	// Peak periods of --bucket, or peak hours - only in compact mode
+	if !verboseAnalysis && analysis.ActivityBucket != "" {
+		sortedPeriods := append([]CountedItem(nil), analysis.Activity...)
+		sort.SliceStable(sortedPeriods, func(i, j int) bool {
+			return sortedPeriods[i].Count > sortedPeriods[j].Count
+		})
+		_, _ = fmt.Fprintf(writer, "%sPeak %ss:%s %s\n", colorSubHeader, activityBucketTitle(analysis.ActivityBucket),
+			colorReset, formatTopItemsLine(sortedPeriods, 3, 0))
+	} else if !verboseAnalysis {
 		// Sort hours by activity and show top 3
 		sortedHours := make([]CountedItem, 0, len(analysis.BusiestHours))
 		for _, hour := range analysis.BusiestHours {
@@ -428,11 +412,37 @@ func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool
 		peakHoursLine = strings.ReplaceAll(peakHoursLine, "(", "h(")
 		_, _ = fmt.Fprintf(writer, "%sPeak Hours:%s %s\n", colorSubHeader, colorReset, peakHoursLine)
 	}
+
+	// Silent periods in the log
+	displayLoggingGaps(analysis, writer, verboseAnalysis)
+
+	// Server restarts
+	if len(analysis.Restarts) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sRestarts:%s %d (last at %s)\n", colorSubHeader, colorReset,
+			len(analysis.Restarts), analysis.Restarts[len(analysis.Restarts)-1].Format("2006-01-02 15:04:05"))
+	}
+
+	// Clock differences between the nodes of a support packet
+	displayClockSkew(analysis, writer, verboseAnalysis)
+
+	// First/last seen per error signature
+	displayErrorSignatures(analysis, writer, verboseAnalysis)
+
+	// Error bursts and error-free periods
+	displayIncidentMetrics(analysis, writer, verboseAnalysis)
+
+	// Goroutine, memory and DB connection growth
+	displayRuntimeMetrics(analysis, writer, verboseAnalysis)
+	displayComplianceExports(analysis, writer, verboseAnalysis)
+	displayDataRetention(analysis, writer, verboseAnalysis)
+	displayFetchErrors(analysis, writer, verboseAnalysis)
+	displayPushProxy(analysis, writer, verboseAnalysis)
+	displayNotificationLatency(analysis, writer, verboseAnalysis)
 	
 	
 	// Activity by month (if time range spans multiple months) - verbose only
 	timeSpan := analysis.TimeRange.End.Sub(analysis.TimeRange.Start)
-	if verboseAnalysis && timeSpan.Hours() >= 24*30 && len(analysis.ActivityByMonth) > 0 {
+	if verboseAnalysis && analysis.ActivityBucket == "" && timeSpan.Hours() >= 24*30 && len(analysis.ActivityByMonth) > 0 {
 		_, _ = fmt.Fprintf(writer, "%sActivity by Month:%s\n", colorSubHeader, colorReset)
 		maxCount, monthMap := findMaxCountAndCreateMap(analysis.ActivityByMonth)
 		
@@ -474,7 +484,9 @@ func displayAnalysis(analysis LogAnalysis, writer io.Writer, isDeduplicated bool
 	}
 
 	// Activity sections at the bottom - verbose only
-	if verboseAnalysis {
+	if verboseAnalysis && analysis.ActivityBucket != "" {
+		displayActivityBuckets(analysis, writer)
+	} else if verboseAnalysis {
 		// Activity by hour
 		_, _ = fmt.Fprintf(writer, "%sActivity by Hour:%s\n", colorSubHeader, colorReset)
 		maxCount, hourMap := createHourMap(analysis.BusiestHours)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// activityBuckets are the periods of --bucket
+var activityBuckets = []string{"hour", "day", "week", "month"}
+
+// validateActivityBucket checks the period of --bucket, empty for the default activity by
+// hour of the day, day of the week and month of the year
+func validateActivityBucket(bucket string) error {
+	if bucket == "" {
+		return nil
+	}
+	for _, b := range activityBuckets {
+		if bucket == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --bucket %q: expected %s", bucket, strings.Join(activityBuckets, ", "))
+}
+
+// activityBucketLabel returns the period of a time: "2025-01-06 14:00", "2025-01-06", the
+// ISO 8601 week "2025-W02" or "2025-01". Labels of the same period sort chronologically.
+func activityBucketLabel(t time.Time, bucket string) string {
+	switch bucket {
+	case "hour":
+		return t.Format("2006-01-02 15:00")
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// nextActivityBucket returns a time in the period of --bucket following that of t
+func nextActivityBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "hour":
+		return t.Add(time.Hour)
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		// From the first of the month, as adding a month to January 31 skips February
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// activityBucketTitle returns the period of --bucket capitalized for headings, e.g. "Week"
+func activityBucketTitle(bucket string) string {
+	if bucket == "" {
+		return ""
+	}
+	return strings.ToUpper(bucket[:1]) + bucket[1:]
+}
+
+// sortActivityBuckets converts the counts per period to a slice in chronological order
+func sortActivityBuckets(counts map[string]int) []CountedItem {
+	items := make([]CountedItem, 0, len(counts))
+	for label, count := range counts {
+		items = append(items, CountedItem{Item: label, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Item < items[j].Item })
+	return items
+}
+
+// displayActivityBuckets writes the activity per --bucket period, in chronological order,
+// in place of the activity by hour, day of the week and month
+func displayActivityBuckets(analysis LogAnalysis, writer io.Writer) {
+	if len(analysis.Activity) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(writer, "%sActivity by %s:%s\n", colorSubHeader, activityBucketTitle(analysis.ActivityBucket), colorReset)
+	maxCount, _ := findMaxCountAndCreateMap(analysis.Activity)
+	width := 0
+	for _, period := range analysis.Activity {
+		width = max(width, len(period.Item))
+	}
+	for _, period := range analysis.Activity {
+		barLength := int(float64(period.Count) / float64(maxCount) * 30)
+		levelColor := getDominantLevelColor(analysis.ActivityLevelCounts[period.Item], period.Count)
+		_, _ = fmt.Fprintf(writer, "%-*s: %s%s%s (%d)\n", width, period.Item, levelColor, strings.Repeat("█", barLength), colorReset, period.Count)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
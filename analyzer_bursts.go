@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// burstMaxInterval is the longest pause between errors that still belong to the same burst
+	burstMaxInterval = time.Minute
+	// burstMinErrors is the minimum number of errors that make up a burst
+	burstMinErrors = 5
+)
+
+// ErrorBurst represents a period with a high concentration of errors
+type ErrorBurst struct {
+	Start time.Time
+	End   time.Time
+	Count int
+}
+
+// Duration returns how long the burst lasted
+func (b ErrorBurst) Duration() time.Duration {
+	return b.End.Sub(b.Start)
+}
+
+// IncidentMetrics contains quantitative metrics about errors over time
+type IncidentMetrics struct {
+	ErrorBursts           []ErrorBurst  // Detected bursts in chronological order
+	AverageBurstDuration  time.Duration // Mean duration of the bursts
+	MeanTimeBetweenBursts time.Duration // Mean time between the starts of consecutive bursts
+	LongestErrorFree      time.Duration // Longest period without any error
+	LongestErrorFreeStart time.Time     // Start of the longest error-free period
+	TimeSinceLastError    time.Duration // Time from the last error to the end of the log
+	LastError             time.Time     // Timestamp of the last error (zero if there are none)
+}
+
+// analyzeIncidentMetrics computes error burst and error-free period metrics
+func analyzeIncidentMetrics(logs []LogEntry, timeRange TimeRange) IncidentMetrics {
+	var metrics IncidentMetrics
+
+	var errorTimes []time.Time
+	for _, log := range logs {
+		if isErrorLevel(log.Level) {
+			errorTimes = append(errorTimes, log.Timestamp)
+		}
+	}
+	if len(errorTimes) == 0 {
+		metrics.LongestErrorFree = timeRange.End.Sub(timeRange.Start)
+		metrics.LongestErrorFreeStart = timeRange.Start
+		return metrics
+	}
+	sort.Slice(errorTimes, func(i, j int) bool {
+		return errorTimes[i].Before(errorTimes[j])
+	})
+
+	metrics.LastError = errorTimes[len(errorTimes)-1]
+	metrics.TimeSinceLastError = timeRange.End.Sub(metrics.LastError)
+
+	// Error-free periods, including before the first and after the last error
+	metrics.LongestErrorFree = errorTimes[0].Sub(timeRange.Start)
+	metrics.LongestErrorFreeStart = timeRange.Start
+	for i := 1; i < len(errorTimes); i++ {
+		if d := errorTimes[i].Sub(errorTimes[i-1]); d > metrics.LongestErrorFree {
+			metrics.LongestErrorFree = d
+			metrics.LongestErrorFreeStart = errorTimes[i-1]
+		}
+	}
+	if metrics.TimeSinceLastError > metrics.LongestErrorFree {
+		metrics.LongestErrorFree = metrics.TimeSinceLastError
+		metrics.LongestErrorFreeStart = metrics.LastError
+	}
+
+	// Group errors into bursts
+	current := ErrorBurst{Start: errorTimes[0], End: errorTimes[0], Count: 1}
+	for _, ts := range errorTimes[1:] {
+		if ts.Sub(current.End) <= burstMaxInterval {
+			current.End = ts
+			current.Count++
+			continue
+		}
+		if current.Count >= burstMinErrors {
+			metrics.ErrorBursts = append(metrics.ErrorBursts, current)
+		}
+		current = ErrorBurst{Start: ts, End: ts, Count: 1}
+	}
+	if current.Count >= burstMinErrors {
+		metrics.ErrorBursts = append(metrics.ErrorBursts, current)
+	}
+
+	if len(metrics.ErrorBursts) > 0 {
+		var total time.Duration
+		for _, burst := range metrics.ErrorBursts {
+			total += burst.Duration()
+		}
+		metrics.AverageBurstDuration = total / time.Duration(len(metrics.ErrorBursts))
+	}
+	if len(metrics.ErrorBursts) > 1 {
+		first := metrics.ErrorBursts[0].Start
+		last := metrics.ErrorBursts[len(metrics.ErrorBursts)-1].Start
+		metrics.MeanTimeBetweenBursts = last.Sub(first) / time.Duration(len(metrics.ErrorBursts)-1)
+	}
+
+	return metrics
+}
+
+// displayIncidentMetrics prints error burst and error-free period metrics
+func displayIncidentMetrics(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	metrics := analysis.Incidents
+	if metrics.LastError.IsZero() {
+		return
+	}
+
+	if !verboseAnalysis {
+		_, _ = fmt.Fprintf(writer, "%sIncidents:%s %d error burst(s) • longest error-free %s • last error %s ago\n",
+			colorSubHeader, colorReset, len(metrics.ErrorBursts),
+			metrics.LongestErrorFree.Round(time.Second), metrics.TimeSinceLastError.Round(time.Second))
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sIncident Metrics:%s\n", colorSubHeader, colorReset)
+	_, _ = fmt.Fprintf(writer, "  Error bursts: %d\n", len(metrics.ErrorBursts))
+	if len(metrics.ErrorBursts) > 0 {
+		_, _ = fmt.Fprintf(writer, "  Average burst duration: %s\n", metrics.AverageBurstDuration.Round(time.Second))
+	}
+	if metrics.MeanTimeBetweenBursts > 0 {
+		_, _ = fmt.Fprintf(writer, "  Mean time between bursts: %s\n", metrics.MeanTimeBetweenBursts.Round(time.Second))
+	}
+	_, _ = fmt.Fprintf(writer, "  Longest error-free period: %s (from %s)\n",
+		metrics.LongestErrorFree.Round(time.Second), metrics.LongestErrorFreeStart.Format("2006-01-02 15:04:05"))
+	_, _ = fmt.Fprintf(writer, "  Time since last error: %s (at %s)\n",
+		metrics.TimeSinceLastError.Round(time.Second), metrics.LastError.Format("2006-01-02 15:04:05"))
+	for _, burst := range metrics.ErrorBursts {
+		_, _ = fmt.Fprintf(writer, "  Burst %s → %s: %d errors\n",
+			burst.Start.Format("2006-01-02 15:04:05"), burst.End.Format("15:04:05"), burst.Count)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
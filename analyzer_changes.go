@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// proposedChangesFence marks the block of the configuration changes proposed by an analysis
+const proposedChangesFence = "```proposed-changes"
+
+// proposedChangesInstructions asks the model for the configuration changes it recommends as a
+// patch of the sanitized configuration provided with the logs
+const proposedChangesInstructions = `
+
+The sanitized configuration of the server (config.json without secrets) is provided after the logs. If you recommend configuration changes, list them after the report in a fenced code block marked proposed-changes, before any other code block:
+
+` + proposedChangesFence + `
+{"patch": {"SqlSettings": {"MaxOpenConns": 300}}, "steps": ["System Console > Environment > Database > Maximum Open Connections: set to 300"]}
+` + "```" + `
+
+The patch contains only the settings to change, with their new values, nested as in config.json, so that it can be applied with mmctl config patch. The steps give the System Console path and new value of each change. Only change settings present in the provided configuration. Leave the block out if you recommend no configuration change.`
+
+// proposedChanges are the configuration changes recommended by an analysis: a partial
+// config.json and the System Console steps making the same changes
+type proposedChanges struct {
+	Patch map[string]any `json:"patch"`
+	Steps []string       `json:"steps"`
+}
+
+// parseProposedChanges removes the proposed changes block of an analysis requested with
+// proposedChangesInstructions. Without a block the changes are nil.
+func parseProposedChanges(text string) (string, *proposedChanges, error) {
+	start := strings.Index(text, proposedChangesFence)
+	if start < 0 {
+		return text, nil, nil
+	}
+	block := text[start+len(proposedChangesFence):]
+	end := strings.Index(block, "```")
+	if end < 0 {
+		return text, nil, fmt.Errorf("unterminated proposed changes block")
+	}
+
+	var changes proposedChanges
+	if err := json.Unmarshal([]byte(block[:end]), &changes); err != nil {
+		return text, nil, fmt.Errorf("invalid proposed changes block: %v", err)
+	}
+	if len(changes.Patch) == 0 && len(changes.Steps) == 0 {
+		return text, nil, fmt.Errorf("empty proposed changes block")
+	}
+
+	return strings.TrimSpace(text[:start]) + "\n\n" + strings.TrimSpace(block[end+len("```"):]), &changes, nil
+}
+
+// splitProposedChanges returns an analysis without its proposed changes block, and the
+// changes. An invalid block is left in the analysis.
+func splitProposedChanges(text string) (string, *proposedChanges) {
+	report, changes, err := parseProposedChanges(text)
+	if err != nil {
+		logger.Warn("failed to parse the proposed changes of the analysis", "error", err)
+		return text, nil
+	}
+	return strings.TrimSpace(report), changes
+}
+
+// unknownSettings returns the settings of a patch that the configuration does not have, e.g.
+// SqlSettings.MaxOpenConnections, sorted
+func unknownSettings(patch, config map[string]any) []string {
+	var unknown []string
+	var walk func(patch, config map[string]any, prefix string)
+	walk = func(patch, config map[string]any, prefix string) {
+		for key, value := range patch {
+			current, ok := config[key]
+			if !ok {
+				unknown = append(unknown, prefix+key)
+				continue
+			}
+			nestedPatch, patchIsObject := value.(map[string]any)
+			nestedConfig, configIsObject := current.(map[string]any)
+			if patchIsObject && configIsObject {
+				walk(nestedPatch, nestedConfig, prefix+key+".")
+			}
+		}
+	}
+	walk(patch, config, "")
+	sort.Strings(unknown)
+	return unknown
+}
+
+// proposedChangesMarkdown renders the proposed changes, warning about the settings the server
+// configuration does not have
+func proposedChangesMarkdown(changes *proposedChanges, serverConfig string) string {
+	var out strings.Builder
+	out.WriteString("## Proposed changes\n")
+	if len(changes.Patch) > 0 {
+		patch, _ := json.MarshalIndent(changes.Patch, "", "  ")
+		out.WriteString("\nApply this patch of config.json with `mmctl config patch <file>`, or follow the System Console steps.\n\n")
+		_, _ = fmt.Fprintf(&out, "```json\n%s\n```\n", patch)
+
+		var config map[string]any
+		if err := json.Unmarshal([]byte(serverConfig), &config); err == nil {
+			if unknown := unknownSettings(changes.Patch, config); len(unknown) > 0 {
+				_, _ = fmt.Fprintf(&out, "\n> **Check before applying:** the server configuration has no %s.\n", strings.Join(unknown, ", "))
+			}
+		}
+	}
+	if len(changes.Steps) > 0 {
+		out.WriteString("\nSystem Console steps:\n\n")
+		for i, step := range changes.Steps {
+			_, _ = fmt.Fprintf(&out, "%d. %s\n", i+1, step)
+		}
+	}
+	return out.String()
+}
+
+// saveProposedChanges writes the patch of the proposed changes, for mmctl config patch
+func saveProposedChanges(changes *proposedChanges, path string) error {
+	if len(changes.Patch) == 0 {
+		return fmt.Errorf("the analysis proposed no configuration patch")
+	}
+	data, err := json.MarshalIndent(changes.Patch, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	runStats.output(path)
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Thresholds of the extras values reported as common to errors
+const (
+	correlationMinErrors     = 3   // Error entries with the value
+	correlationMinErrorShare = 0.2 // Share of the errors with the value
+	correlationMinLift       = 2.0 // How many times more common the value is among errors than overall
+)
+
+// correlationIgnoredKeys are the extras that describe the error itself, or are unique to an
+// entry, rather than what the failing entries have in common
+var correlationIgnoredKeys = []string{"error", "err", "errors", "stack", "stacktrace", "request_id",
+	"timestamp", "time", "duration", "elapsed"}
+
+// ErrorCorrelation is an extras value overrepresented among errors, such as one channel, one
+// client IP or one user agent most failing requests have in common
+type ErrorCorrelation struct {
+	Field        string
+	Value        string
+	Errors       int     // Error entries with the value
+	Total        int     // Entries with the value
+	ErrorShare   float64 // Share of the errors with the value
+	OverallShare float64 // Share of all entries with the value
+	Lift         float64 // ErrorShare / OverallShare
+}
+
+// correlationFields returns the fields of an entry compared between errors and all entries:
+// its extras, except the ignored ones, and its user as user_id
+func correlationFields(log LogEntry) map[string]string {
+	fields := make(map[string]string, len(log.Extras)+1)
+	for key, value := range log.Extras {
+		if value != "" && !contains(correlationIgnoredKeys, strings.ToLower(key)) {
+			fields[key] = value
+		}
+	}
+	if log.User != "" {
+		fields["user_id"] = log.User
+	}
+	return fields
+}
+
+// analyzeErrorCorrelations finds the extras values much more common among errors than in the
+// whole log, the common denominator of the errors, most errors first, keeping at most limit
+// values (all when limit is 0 or less)
+func analyzeErrorCorrelations(logs []LogEntry, showDupes bool, limit int) []ErrorCorrelation {
+	entryCount := func(log LogEntry) int {
+		if showDupes && log.DuplicateCount > 1 {
+			return log.DuplicateCount
+		}
+		return 1
+	}
+
+	// Values of the errors first, so that only their totals are counted over all entries
+	// rather than those of every value, such as unique request IDs
+	errorCounts := make(map[string]map[string]int)
+	totalErrors := 0
+	for _, log := range logs {
+		if !isErrorLevel(log.Level) {
+			continue
+		}
+		count := entryCount(log)
+		totalErrors += count
+		for key, value := range correlationFields(log) {
+			if errorCounts[key] == nil {
+				errorCounts[key] = make(map[string]int)
+			}
+			errorCounts[key][value] += count
+		}
+	}
+	if totalErrors == 0 {
+		return nil
+	}
+
+	totalCounts := make(map[string]map[string]int)
+	totalEntries := 0
+	for _, log := range logs {
+		count := entryCount(log)
+		totalEntries += count
+		for key, value := range correlationFields(log) {
+			if _, ok := errorCounts[key][value]; !ok {
+				continue
+			}
+			if totalCounts[key] == nil {
+				totalCounts[key] = make(map[string]int)
+			}
+			totalCounts[key][value] += count
+		}
+	}
+
+	var correlations []ErrorCorrelation
+	for key, values := range errorCounts {
+		for value, errors := range values {
+			total := totalCounts[key][value]
+			errorShare := float64(errors) / float64(totalErrors)
+			overallShare := float64(total) / float64(totalEntries)
+			lift := errorShare / overallShare
+			if errors < correlationMinErrors || errorShare < correlationMinErrorShare || lift < correlationMinLift {
+				continue
+			}
+			correlations = append(correlations, ErrorCorrelation{
+				Field: key, Value: value, Errors: errors, Total: total,
+				ErrorShare: errorShare, OverallShare: overallShare, Lift: lift,
+			})
+		}
+	}
+	sort.Slice(correlations, func(i, j int) bool {
+		a, b := correlations[i], correlations[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Lift != b.Lift {
+			return a.Lift > b.Lift
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Value < b.Value
+	})
+	if limit > 0 && len(correlations) > limit {
+		correlations = correlations[:limit]
+	}
+	return correlations
+}
+
+// displayErrorCorrelations writes the values common to errors: the top three on a line, or
+// each with its shares of the errors and of all entries in verbose mode
+func displayErrorCorrelations(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.ErrorCorrelations) == 0 {
+		return
+	}
+
+	if !verboseAnalysis {
+		var parts []string
+		for i, c := range analysis.ErrorCorrelations {
+			if i >= 3 {
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s (%.0f%% of errors, %.0f%% overall)",
+				c.Field, truncateString(c.Value, 40), c.ErrorShare*100, c.OverallShare*100))
+		}
+		_, _ = fmt.Fprintf(writer, "%sCommon to Errors:%s %s\n", colorSubHeader, colorReset, strings.Join(parts, " • "))
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sValues Common to Errors:%s\n", colorSubHeader, colorReset)
+	for _, c := range analysis.ErrorCorrelations {
+		_, _ = fmt.Fprintf(writer, "  %s=%s: %d error(s), %.0f%% of errors vs %.0f%% of all entries (%.1f× more common among errors)\n",
+			c.Field, c.Value, c.Errors, c.ErrorShare*100, c.OverallShare*100, c.Lift)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// exportDefaultInterval is the expected time between export runs when the logs hold
+	// too few runs to measure it; message exports run daily by default
+	exportDefaultInterval = 24 * time.Hour
+	// exportStoppedMultiplier is how many expected intervals may pass without a run
+	// before exports are reported as stopped
+	exportStoppedMultiplier = 2
+)
+
+// exportWorkers identify export job entries by their worker or job type
+var exportWorkers = []string{"messageexport", "compliance"}
+
+// exportMessagePhrases identify export job entries by their message
+var exportMessagePhrases = []string{"message export", "compliance export", "messageexport", "message_export"}
+
+// exportCountKeys are the extras holding the number of exported posts
+var exportCountKeys = []string{"exported_posts", "posts_exported", "post_count", "num_posts", "total_posts", "messages_exported", "exported"}
+
+// exportCountPattern extracts the number of exported posts from a message
+var exportCountPattern = regexp.MustCompile(`(?i)(\d+) (?:posts|messages)`)
+
+// ExportRun is a single run of the compliance/message export job
+type ExportRun struct {
+	JobRun
+	Exported int // Exported posts, when logged
+}
+
+// ExportMonitor summarizes the compliance/message export runs found in the logs
+type ExportMonitor struct {
+	Runs             []ExportRun   // Runs in chronological order
+	Failures         int           // Failed runs
+	Exported         int           // Posts exported by all runs
+	AverageDuration  time.Duration // Mean duration of finished runs
+	MaxDuration      time.Duration // Longest finished run
+	LastRun          time.Time     // Start of the last run
+	LastSuccess      time.Time     // End of the last completed run
+	ExpectedInterval time.Duration // Median time between runs, or the daily default
+	SinceLastRun     time.Duration // Time from the last run to the end of the logs
+	Stopped          bool          // Whether no run happened for several expected intervals
+}
+
+// exportedCount returns the number of exported posts logged by an entry
+func exportedCount(log LogEntry) (int, bool) {
+	for _, key := range exportCountKeys {
+		if count, err := strconv.Atoi(log.Extras[key]); err == nil {
+			return count, true
+		}
+	}
+	if match := exportCountPattern.FindStringSubmatch(log.Message); match != nil {
+		count, err := strconv.Atoi(match[1])
+		return count, err == nil
+	}
+	return 0, false
+}
+
+// analyzeComplianceExports groups export job entries into runs and flags exports that
+// stopped running
+func analyzeComplianceExports(logs []LogEntry, timeRange TimeRange) ExportMonitor {
+	var entries []LogEntry
+	for _, log := range logs {
+		if isJobEntry(log, exportWorkers, exportMessagePhrases) {
+			entries = append(entries, log)
+		}
+	}
+	if len(entries) == 0 {
+		return ExportMonitor{}
+	}
+
+	runs := groupJobRuns(entries)
+	monitor := ExportMonitor{ExpectedInterval: exportDefaultInterval}
+	for _, run := range runs {
+		exportRun := ExportRun{JobRun: run}
+		for _, log := range run.Entries {
+			if count, ok := exportedCount(log); ok {
+				exportRun.Exported = count
+			}
+		}
+		monitor.Runs = append(monitor.Runs, exportRun)
+		monitor.Exported += exportRun.Exported
+		switch run.Status {
+		case jobFailed:
+			monitor.Failures++
+		case jobCompleted:
+			monitor.LastSuccess = run.End
+		}
+	}
+	monitor.AverageDuration, monitor.MaxDuration = jobRunDurations(runs)
+
+	if interval := jobRunInterval(runs); interval > 0 {
+		monitor.ExpectedInterval = interval
+	}
+	monitor.LastRun = runs[len(runs)-1].Start
+	monitor.SinceLastRun = timeRange.End.Sub(monitor.LastRun)
+	monitor.Stopped = monitor.SinceLastRun > exportStoppedMultiplier*monitor.ExpectedInterval
+	return monitor
+}
+
+// displayComplianceExports writes the export runs, with a warning when exports stopped
+// running or never succeeded
+func displayComplianceExports(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	exports := analysis.ComplianceExports
+	if len(exports.Runs) == 0 {
+		return
+	}
+
+	header := "Compliance Exports:"
+	if verboseAnalysis {
+		header = "Compliance Export Runs:"
+	}
+	_, _ = fmt.Fprintf(writer, "%s%s%s %d run(s) • %d failed • %d posts exported • avg %s, max %s\n",
+		colorSubHeader, header, colorReset, len(exports.Runs), exports.Failures, exports.Exported,
+		exports.AverageDuration.Round(time.Second), exports.MaxDuration.Round(time.Second))
+	if exports.Stopped {
+		_, _ = fmt.Fprintf(writer, "  %sNo export run for %s before the end of the logs (expected every %s)%s\n",
+			colorRed, exports.SinceLastRun.Round(time.Minute), exports.ExpectedInterval.Round(time.Minute), colorReset)
+	}
+	if exports.LastSuccess.IsZero() {
+		_, _ = fmt.Fprintf(writer, "  %sNo export run completed successfully%s\n", colorRed, colorReset)
+	}
+
+	if !verboseAnalysis {
+		return
+	}
+	for _, run := range exports.Runs {
+		line := formatJobRun(run.JobRun)
+		if run.Exported > 0 {
+			line += fmt.Sprintf(", %d posts", run.Exported)
+		}
+		if run.Error != "" {
+			line += fmt.Sprintf(": %s%s%s", colorRed, truncateString(run.Error, 100), colorReset)
+		}
+		_, _ = fmt.Fprintln(writer, line)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fetchConsistentFailures is the minimum number of failed fetches of a host reported as
+// consistently failing
+const fetchConsistentFailures = 5
+
+// Kinds of fetches made by the server on behalf of clients
+const (
+	fetchImageProxy  = "image proxy"
+	fetchLinkPreview = "link preview"
+)
+
+// imageProxyPhrases identify image proxy errors by their message or caller
+var imageProxyPhrases = []string{"image proxy", "imageproxy", "getimage", "get image", "fetch image"}
+
+// linkPreviewPhrases identify link preview (opengraph) errors by their message or caller
+var linkPreviewPhrases = []string{"opengraph", "open graph", "link metadata", "linkmetadata", "link preview",
+	"post_metadata", "permalink preview"}
+
+// fetchURLKeys are the extras holding the fetched URL
+var fetchURLKeys = []string{"url", "requested_url", "request_url", "image_url", "remote_url", "link", "image"}
+
+// fetchURLPattern finds a URL in a message or error
+var fetchURLPattern = regexp.MustCompile(`https?://[^\s"'<>,)]+`)
+
+// fetchReasons classify why a fetch failed, in order of precedence
+var fetchReasons = []struct {
+	reason  string
+	phrases []string
+}{
+	{"DNS lookup failed", []string{"no such host", "server misbehaving"}},
+	{"address forbidden", []string{"address forbidden", "untrusted internal", "not allowed"}},
+	{"connection refused", []string{"connection refused", "connection reset"}},
+	{"timeout", []string{"timeout", "timed out", "deadline exceeded"}},
+	{"TLS error", []string{"x509", "certificate", "tls"}},
+}
+
+// networkFetchReasons are the reasons that usually come from firewall or proxy rules
+var networkFetchReasons = []string{"DNS lookup failed", "address forbidden", "connection refused", "timeout"}
+
+// HostFetchErrors are the failed image proxy and link preview fetches of a destination host
+type HostFetchErrors struct {
+	Host       string
+	Count      int
+	Kinds      []string // image proxy and/or link preview
+	Reason     string   // Most common classified reason, "other" when unknown
+	Example    string   // Error of the first failure
+	FirstSeen  time.Time
+	LastSeen   time.Time
+	Consistent bool // Whether the host failed at least fetchConsistentFailures times
+}
+
+// fetchErrorKind returns whether an entry is an image proxy or link preview error
+func fetchErrorKind(log LogEntry) (string, bool) {
+	level := strings.ToLower(log.Level)
+	if level != "warn" && level != "warning" && !isErrorLevel(level) {
+		return "", false
+	}
+	text := strings.ToLower(log.Message + " " + log.Source)
+	switch {
+	case containsAny(text, imageProxyPhrases):
+		return fetchImageProxy, true
+	case containsAny(text, linkPreviewPhrases):
+		return fetchLinkPreview, true
+	}
+	return "", false
+}
+
+// fetchHost returns the destination host of a failed fetch, from its URL extras or a URL
+// in its message or error
+func fetchHost(log LogEntry) string {
+	var candidates []string
+	for _, key := range fetchURLKeys {
+		candidates = append(candidates, log.Extras[key])
+	}
+	candidates = append(candidates, fetchURLPattern.FindString(log.Message),
+		fetchURLPattern.FindString(log.Extras["error"]), fetchURLPattern.FindString(log.Extras["err"]))
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if u, err := url.Parse(candidate); err == nil && u.Hostname() != "" {
+			return strings.ToLower(u.Hostname())
+		}
+	}
+	return ""
+}
+
+// fetchReason classifies why a fetch failed from its message and error
+func fetchReason(log LogEntry) string {
+	text := strings.ToLower(log.Message + " " + log.Extras["error"] + " " + log.Extras["err"])
+	for _, r := range fetchReasons {
+		if containsAny(text, r.phrases) {
+			return r.reason
+		}
+	}
+	return "other"
+}
+
+// analyzeFetchErrors groups image proxy and link preview errors by destination host, most
+// failing hosts first, keeping at most limit hosts (all when limit is 0 or less)
+func analyzeFetchErrors(logs []LogEntry, showDupes bool, limit int) []HostFetchErrors {
+	hosts := make(map[string]*HostFetchErrors)
+	reasons := make(map[string]map[string]int)
+	for _, log := range logs {
+		kind, ok := fetchErrorKind(log)
+		if !ok {
+			continue
+		}
+		host := fetchHost(log)
+		if host == "" {
+			continue
+		}
+
+		count := 1
+		if showDupes && log.DuplicateCount > 1 {
+			count = log.DuplicateCount
+		}
+		errors, ok := hosts[host]
+		if !ok {
+			errors = &HostFetchErrors{Host: host, FirstSeen: log.Timestamp, LastSeen: log.Timestamp}
+			hosts[host] = errors
+			reasons[host] = make(map[string]int)
+		}
+		errors.Count += count
+		if !contains(errors.Kinds, kind) {
+			errors.Kinds = append(errors.Kinds, kind)
+		}
+		if log.Timestamp.Before(errors.FirstSeen) {
+			errors.FirstSeen = log.Timestamp
+			errors.Example = ""
+		}
+		if log.Timestamp.After(errors.LastSeen) {
+			errors.LastSeen = log.Timestamp
+		}
+		if errors.Example == "" {
+			errors.Example = log.Message
+			if detail := log.Extras["error"]; detail != "" {
+				errors.Example += ": " + detail
+			}
+		}
+		reasons[host][fetchReason(log)] += count
+	}
+
+	result := make([]HostFetchErrors, 0, len(hosts))
+	for host, errors := range hosts {
+		errors.Reason = mapToSortedSlice(reasons[host], 1)[0].Item
+		errors.Consistent = errors.Count >= fetchConsistentFailures
+		sort.Strings(errors.Kinds)
+		result = append(result, *errors)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Host < result[j].Host
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// displayFetchErrors writes the image proxy and link preview errors per host, pointing
+// out consistently failing hosts
+func displayFetchErrors(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.FetchErrors) == 0 {
+		return
+	}
+
+	var total int
+	var consistent []string
+	for _, host := range analysis.FetchErrors {
+		total += host.Count
+		if host.Consistent {
+			consistent = append(consistent, fmt.Sprintf("%s (%s)", host.Host, host.Reason))
+		}
+	}
+
+	if !verboseAnalysis {
+		_, _ = fmt.Fprintf(writer, "%sFetch Errors:%s %d failed image proxy/link preview fetch(es) across %d host(s)\n",
+			colorSubHeader, colorReset, total, len(analysis.FetchErrors))
+		if len(consistent) > 0 {
+			_, _ = fmt.Fprintf(writer, "  %sConsistently failing: %s%s\n", colorRed, strings.Join(consistent, ", "), colorReset)
+		}
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sImage Proxy and Link Preview Errors by Host:%s\n", colorSubHeader, colorReset)
+	for _, host := range analysis.FetchErrors {
+		line := fmt.Sprintf("  %s: %d (%s, %s) %s → %s", host.Host, host.Count, strings.Join(host.Kinds, ", "), host.Reason,
+			host.FirstSeen.Format("2006-01-02 15:04:05"), host.LastSeen.Format("2006-01-02 15:04:05"))
+		if host.Consistent {
+			line += fmt.Sprintf(" %sCONSISTENTLY FAILING%s", colorRed, colorReset)
+			if contains(networkFetchReasons, host.Reason) {
+				line += " - check firewall, proxy and AllowedUntrustedInternalConnections settings"
+			}
+		}
+		_, _ = fmt.Fprintln(writer, line)
+		_, _ = fmt.Fprintf(writer, "    %s\n", truncateString(host.Example, 120))
+	}
+	_, _ = fmt.Fprintln(writer)
+}
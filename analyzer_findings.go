@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// findingsInstructions asks the model to end its report with the findings as JSON, citing
+// the numbered log entries they are based on
+const findingsInstructions = `
+
+After the report, list your findings in a fenced code block marked json, and nothing after it:
+
+` + "```json" + `
+{"findings": [{"title": "Short title", "severity": "error", "summary": "One or two sentences", "evidence": [12, 15]}]}
+` + "```" + `
+
+Severity is one of error, warning or info. Evidence lists the numbers of the log entries, as numbered in the logs provided, that support the finding. Every finding must cite at least one entry: do not report conclusions that no provided entry supports.`
+
+// AIFinding is a finding of an AI analysis with the numbers of the analyzed log entries it
+// cites, starting at 1
+type AIFinding struct {
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Evidence []int  `json:"evidence"`
+}
+
+// logsForAnalysis returns the entries sent to the model: the most recent maxEntries, or
+// defaultMaxLogEntries if maxEntries is not set
+func logsForAnalysis(logs []LogEntry, maxEntries int) []LogEntry {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxLogEntries
+	}
+	if len(logs) > maxEntries {
+		return logs[len(logs)-maxEntries:]
+	}
+	return logs
+}
+
+// parseAIFindings splits an analysis requested with findingsInstructions into the report and
+// the findings. Without a findings block the whole text is the report.
+func parseAIFindings(text string) (string, []AIFinding, error) {
+	start := strings.LastIndex(text, "```json")
+	if start < 0 {
+		return text, nil, nil
+	}
+	block := text[start+len("```json"):]
+	end := strings.Index(block, "```")
+	if end < 0 {
+		return text, nil, fmt.Errorf("unterminated findings block")
+	}
+
+	var parsed struct {
+		Findings []AIFinding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(block[:end]), &parsed); err != nil {
+		return text, nil, fmt.Errorf("invalid findings block: %v", err)
+	}
+
+	report := strings.TrimSpace(text[:start] + block[end+len("```"):])
+	return report, parsed.Findings, nil
+}
+
+// citedFinding is an AI finding with the analyzed entries it cites
+type citedFinding struct {
+	AIFinding
+	Numbers []int      // Cited numbers of analyzed entries
+	Entries []LogEntry // The entries of Numbers
+	Unknown []int      // Cited numbers of no analyzed entry
+}
+
+// citeFindings resolves the evidence numbers of findings to the analyzed entries
+func citeFindings(findings []AIFinding, analyzed []LogEntry) []citedFinding {
+	cited := make([]citedFinding, 0, len(findings))
+	for _, finding := range findings {
+		c := citedFinding{AIFinding: finding}
+		for _, number := range finding.Evidence {
+			if number < 1 || number > len(analyzed) {
+				c.Unknown = append(c.Unknown, number)
+				continue
+			}
+			c.Numbers = append(c.Numbers, number)
+			c.Entries = append(c.Entries, analyzed[number-1])
+		}
+		cited = append(cited, c)
+	}
+	return cited
+}
+
+// withCitedEvidence replaces the findings block of an analysis requested with
+// findingsInstructions by the findings followed by the raw entries they cite, so conclusions
+// can be checked against the logs. Findings citing no analyzed entry are marked unverified.
+// Without a valid findings block the analysis is returned as is.
+func withCitedEvidence(text string, analyzed []LogEntry) string {
+	report, findings, err := parseAIFindings(text)
+	if err != nil {
+		logger.Warn("failed to parse the findings of the analysis", "error", err)
+		return text
+	}
+	if len(findings) == 0 {
+		return report
+	}
+
+	var out strings.Builder
+	out.WriteString(report)
+	out.WriteString("\n\n## AI Findings\n")
+	for i, finding := range citeFindings(findings, analyzed) {
+		_, _ = fmt.Fprintf(&out, "\n### %d. [%s] %s\n\n", i+1, strings.ToUpper(finding.Severity), finding.Title)
+		if finding.Summary != "" {
+			_, _ = fmt.Fprintf(&out, "%s\n\n", finding.Summary)
+		}
+		if len(finding.Unknown) > 0 {
+			numbers := make([]string, len(finding.Unknown))
+			for j, number := range finding.Unknown {
+				numbers[j] = strconv.Itoa(number)
+			}
+			_, _ = fmt.Fprintf(&out, "> Cited entries that were not analyzed, ignored: %s\n\n", strings.Join(numbers, ", "))
+		}
+		if len(finding.Entries) == 0 {
+			out.WriteString("> **Unverified:** this finding cites no analyzed log entry.\n")
+			continue
+		}
+		out.WriteString("Evidence:\n\n```\n")
+		for j, entry := range finding.Entries {
+			_, _ = fmt.Fprintf(&out, "%d. %s\n", finding.Numbers[j], evidenceLine(entry))
+		}
+		out.WriteString("```\n")
+	}
+	return out.String()
+}
+
+// evidenceLine returns the original line of an entry with its location, if known
+func evidenceLine(entry LogEntry) string {
+	line := entry.Raw
+	if line == "" {
+		line = fmt.Sprintf("%s [%s] %s", entry.Timestamp.Format("2006-01-02 15:04:05.000"), entry.Level, entry.Message)
+	}
+	if entry.SourceFile != "" && entry.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", entry.SourceFile, entry.Line, line)
+	}
+	return line
+}
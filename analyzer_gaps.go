@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// gapCadenceMultiplier is how many times the typical interval between entries
+	// a silence must last to be reported as a gap
+	gapCadenceMultiplier = 30
+	// gapMinDuration is the shortest silence ever reported as a gap
+	gapMinDuration = time.Minute
+	// gapMaxReported is the maximum number of gaps kept in the analysis
+	gapMaxReported = 10
+)
+
+// LogGap represents an unusually long period without any log entries
+type LogGap struct {
+	Start    time.Time // Timestamp of the last entry before the gap
+	End      time.Time // Timestamp of the first entry after the gap
+	Duration time.Duration
+}
+
+// detectLoggingGaps finds silences between consecutive entries that are much longer
+// than the typical cadence of the log. It returns the longest gaps first along with
+// the median interval between entries and the threshold that was applied.
+func detectLoggingGaps(logs []LogEntry) ([]LogGap, time.Duration, time.Duration) {
+	if len(logs) < 3 {
+		return nil, 0, 0
+	}
+
+	timestamps := make([]time.Time, len(logs))
+	for i, log := range logs {
+		timestamps[i] = log.Timestamp
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i].Before(timestamps[j])
+	})
+
+	intervals := make([]time.Duration, 0, len(timestamps)-1)
+	for i := 1; i < len(timestamps); i++ {
+		intervals = append(intervals, timestamps[i].Sub(timestamps[i-1]))
+	}
+
+	sortedIntervals := append([]time.Duration(nil), intervals...)
+	sort.Slice(sortedIntervals, func(i, j int) bool {
+		return sortedIntervals[i] < sortedIntervals[j]
+	})
+	median := sortedIntervals[len(sortedIntervals)/2]
+
+	threshold := median * gapCadenceMultiplier
+	if threshold < gapMinDuration {
+		threshold = gapMinDuration
+	}
+
+	var gaps []LogGap
+	for i, interval := range intervals {
+		if interval >= threshold {
+			gaps = append(gaps, LogGap{
+				Start:    timestamps[i],
+				End:      timestamps[i+1],
+				Duration: interval,
+			})
+		}
+	}
+
+	// Longest gaps first
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gaps[i].Duration > gaps[j].Duration
+	})
+	if len(gaps) > gapMaxReported {
+		gaps = gaps[:gapMaxReported]
+	}
+
+	return gaps, median, threshold
+}
+
+// displayLoggingGaps prints the detected logging gaps
+func displayLoggingGaps(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.LoggingGaps) == 0 {
+		return
+	}
+
+	longest := analysis.LoggingGaps[0]
+	if !verboseAnalysis {
+		_, _ = fmt.Fprintf(writer, "%sGaps:%s %d silent period(s), longest %s from %s\n",
+			colorSubHeader, colorReset, len(analysis.LoggingGaps),
+			longest.Duration.Round(time.Second), longest.Start.Format("2006-01-02 15:04:05"))
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sLogging Gaps:%s (typical interval %s, threshold %s)\n",
+		colorSubHeader, colorReset, analysis.TypicalInterval.Round(time.Millisecond), analysis.GapThreshold)
+	for _, gap := range analysis.LoggingGaps {
+		_, _ = fmt.Fprintf(writer, "  %s → %s (%s)\n",
+			gap.Start.Format("2006-01-02 15:04:05"),
+			gap.End.Format("2006-01-02 15:04:05"),
+			gap.Duration.Round(time.Second))
+	}
+	_, _ = fmt.Fprintln(writer)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of a background job run
+const (
+	jobCompleted  = "completed"
+	jobFailed     = "failed"
+	jobUnfinished = "unfinished"
+)
+
+// jobWorkerKeys are the extras naming the job or worker an entry belongs to
+var jobWorkerKeys = []string{"worker", "job_type", "jobtype", "job", "type"}
+
+// JobRun is a single run of a background job such as the message export or data retention
+type JobRun struct {
+	JobID   string
+	Start   time.Time
+	End     time.Time
+	Status  string     // completed, failed, or unfinished when no outcome was logged
+	Error   string     // Message of the first error of a failed run
+	Entries []LogEntry `json:"-"` // Entries logged by the run
+}
+
+// Duration returns how long the run took, or zero for unfinished runs
+func (r JobRun) Duration() time.Duration {
+	if r.Status == jobUnfinished {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// isJobEntry reports whether an entry is logged by a job, from its worker extras matched
+// without underscores and case (so both message_export and MessageExportWorker match
+// "messageexport") or from phrases of its message
+func isJobEntry(log LogEntry, workers, phrases []string) bool {
+	for _, key := range jobWorkerKeys {
+		value := strings.ToLower(strings.ReplaceAll(log.Extras[key], "_", ""))
+		if value != "" && containsAny(value, workers) {
+			return true
+		}
+	}
+	return containsAny(strings.ToLower(log.Message), phrases)
+}
+
+// groupJobRuns groups the entries of a job into runs, by job ID when logged or else by
+// start and outcome messages
+func groupJobRuns(entries []LogEntry) []JobRun {
+	entries = append([]LogEntry(nil), entries...)
+	sortEntries(entries)
+
+	var runs []*JobRun
+	byJob := make(map[string]*JobRun)
+	var current *JobRun
+	for _, log := range entries {
+		message := strings.ToLower(log.Message)
+		jobID := log.Extras["job_id"]
+		starting := strings.Contains(message, "start") || strings.Contains(message, "new candidate job")
+
+		run := byJob[jobID]
+		if jobID == "" {
+			run = current
+			if run != nil && starting {
+				// A new run starts while the previous one never logged its outcome
+				run = nil
+			}
+		}
+		if run == nil || run.Status != jobUnfinished {
+			run = &JobRun{JobID: jobID, Start: log.Timestamp, Status: jobUnfinished}
+			runs = append(runs, run)
+			if jobID != "" {
+				byJob[jobID] = run
+			}
+		}
+		if jobID == "" {
+			current = run
+		}
+
+		run.End = log.Timestamp
+		run.Entries = append(run.Entries, log)
+		switch {
+		case isErrorLevel(log.Level) || strings.Contains(message, "fail"):
+			run.Status = jobFailed
+			if run.Error == "" {
+				run.Error = log.Message
+			}
+		case containsAny(message, []string{"complete", "finished", "success", "done"}):
+			run.Status = jobCompleted
+		}
+	}
+
+	grouped := make([]JobRun, 0, len(runs))
+	for _, run := range runs {
+		grouped = append(grouped, *run)
+	}
+	return grouped
+}
+
+// jobRunInterval returns the median time between the starts of consecutive runs, or zero
+// with fewer than two runs
+func jobRunInterval(runs []JobRun) time.Duration {
+	if len(runs) < 2 {
+		return 0
+	}
+	intervals := make([]time.Duration, 0, len(runs)-1)
+	for i := 1; i < len(runs); i++ {
+		intervals = append(intervals, runs[i].Start.Sub(runs[i-1].Start))
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)/2]
+}
+
+// jobRunDurations returns the mean and longest durations of the finished runs
+func jobRunDurations(runs []JobRun) (average, longest time.Duration) {
+	var finished int
+	var total time.Duration
+	for _, run := range runs {
+		if run.Status == jobUnfinished {
+			continue
+		}
+		finished++
+		total += run.Duration()
+		longest = max(longest, run.Duration())
+	}
+	if finished > 0 {
+		average = total / time.Duration(finished)
+	}
+	return average, longest
+}
+
+// formatJobRun describes a run for the detailed analysis: start, outcome and duration
+func formatJobRun(run JobRun) string {
+	line := fmt.Sprintf("  %s %s", run.Start.Format("2006-01-02 15:04:05"), run.Status)
+	if run.Status != jobUnfinished {
+		line += fmt.Sprintf(" in %s", run.Duration().Round(time.Second))
+	}
+	return line
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AnthropicRequest represents the request structure for Anthropic API
+type AnthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Messages    []AnthropicMessage `json:"messages"`
+	System      []AnthropicContent `json:"system"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        float64            `json:"top_p,omitempty"`
+	Thinking    *ThinkingConfig    `json:"thinking,omitempty"`
+}
+
+// ThinkingConfig represents the configuration for thinking mode
+type ThinkingConfig struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// AnthropicMessage represents a message in the Anthropic API request
+type AnthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []AnthropicContent `json:"content"`
+}
+
+// AnthropicContent is a text block of a request, cached with the blocks before it if it has
+// a cache control
+type AnthropicContent struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// AnthropicCacheControl marks the end of a prompt prefix to cache
+type AnthropicCacheControl struct {
+	Type string `json:"type"` // ephemeral: kept for 5 minutes after its last use
+}
+
+// AnthropicUsage represents token usage information, including the prompt cache
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+}
+
+// anthropicPromptContent returns the system prompt and the user message of an analysis. The
+// system prompt and the logs are cached, so that analyses of the same logs within minutes
+// (another --problem, a rerun) only pay for the instructions.
+func anthropicPromptContent(prompt AnalysisPrompt) ([]AnthropicContent, []AnthropicMessage) {
+	cache := &AnthropicCacheControl{Type: "ephemeral"}
+	system := []AnthropicContent{{Type: "text", Text: prompt.SystemPrompt, CacheControl: cache}}
+	content := []AnthropicContent{{Type: "text", Text: prompt.LogPrompt, CacheControl: cache}}
+	if prompt.Instructions != "" {
+		content = append(content, AnthropicContent{Type: "text", Text: prompt.Instructions})
+	}
+	return system, []AnthropicMessage{{Role: "user", Content: content}}
+}
+
+// AnthropicResponse represents the response structure from Anthropic API
+type AnthropicResponse struct {
+	Content []ContentBlock  `json:"content"`
+	ID      string          `json:"id"`
+	Model   string          `json:"model"`
+	Type    string          `json:"type"`
+	Usage   AnthropicUsage  `json:"usage"`
+	Error   *AnthropicError `json:"error,omitempty"`
+}
+
+// ContentBlock represents a content block in the Anthropic API response
+type ContentBlock struct {
+	Text     string `json:"text"`
+	Type     string `json:"type"`               // text, thinking or redacted_thinking
+	Thinking string `json:"thinking,omitempty"` // Text of thinking blocks
+}
+
+// anthropicAnalysisText returns the analysis of the text blocks of a response, preceded by
+// the thinking blocks if showThinking is set. Redacted thinking is encrypted and left out.
+func anthropicAnalysisText(response AnthropicResponse, showThinking bool) string {
+	var thinking, analysis []string
+	for _, block := range response.Content {
+		switch block.Type {
+		case "thinking":
+			thinking = append(thinking, strings.TrimSpace(block.Thinking))
+		case "text":
+			analysis = append(analysis, block.Text)
+		}
+	}
+	if !showThinking || len(thinking) == 0 {
+		return strings.Join(analysis, "")
+	}
+	return "## LLM THINKING PROCESS\n\n" + strings.Join(thinking, "\n\n") +
+		"\n\n## FINAL ANALYSIS\n\n" + strings.Join(analysis, "")
+}
+
+// AnthropicError represents an error from the Anthropic API
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicProvider analyzes logs with the Anthropic Messages API
+type anthropicProvider struct {
+	baseURL string
+}
+
+// Name returns the name of the API in messages
+func (p anthropicProvider) Name() string {
+	return "Anthropic API"
+}
+
+// Timeout returns how long to wait for an answer
+func (p anthropicProvider) Timeout(model string, config LLMConfig) time.Duration {
+	return 60 * time.Second
+}
+
+// NewRequest returns the request of an analysis, with extended thinking if a budget is set
+func (p anthropicProvider) NewRequest(prompt AnalysisPrompt, model string, config LLMConfig) (*http.Request, error) {
+	system, messages := anthropicPromptContent(prompt)
+	request := AnthropicRequest{
+		Model:     model,
+		MaxTokens: config.maxOutputTokens(),
+		Messages:  messages,
+		System:    system,
+	}
+	// Recent Claude models accept a temperature or a top_p, not both
+	if config.TopP > 0 {
+		request.TopP = config.TopP
+	} else {
+		temperature := config.temperature()
+		request.Temperature = &temperature
+	}
+
+	// Enable thinking mode if thinkingBudget is set
+	if config.ThinkingBudget > 0 {
+		// If thinking is enabled but the model isn't specified, default to Sonnet
+		if config.Model == "" {
+			request.Model = "claude-3-7-sonnet-latest"
+		}
+
+		// Ensure max_tokens is larger than thinking budget (Claude requirement)
+		request.MaxTokens = config.ThinkingBudget + config.maxOutputTokens()
+
+		// Set temperature to 1 when thinking is enabled (Claude requirement)
+		config.samplingIgnored("extended thinking requires the default sampling")
+		temperature := 1.0
+		request.Temperature, request.TopP = &temperature, 0
+
+		request.Thinking = &ThinkingConfig{
+			Type:         "enabled",
+			BudgetTokens: config.ThinkingBudget,
+		}
+		_, _ = fmt.Fprintf(config.writer(), "Extended thinking mode enabled with %d tokens budget (total max tokens: %d)\n",
+			config.ThinkingBudget, request.MaxTokens)
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req, err := http.NewRequest("POST", p.baseURL+"/v1/messages", bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", config.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+// ParseResponse returns the analysis of an answer and shows its token usage
+func (p anthropicProvider) ParseResponse(body []byte, model string, config LLMConfig) (string, error) {
+	var response AnthropicResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+	if response.Error != nil {
+		return "", fmt.Errorf("anthropic API error: %s - %s", response.Error.Type, response.Error.Message)
+	}
+
+	// Show token usage, with the part of the prompt written to or read from the cache
+	_, _ = fmt.Fprintf(config.writer(), "Token usage - Input: %d, Cache write: %d, Cache read: %d, Output: %d\n",
+		response.Usage.InputTokens,
+		response.Usage.CacheCreationInputTokens,
+		response.Usage.CacheReadInputTokens,
+		response.Usage.OutputTokens)
+
+	return anthropicAnalysisText(response, config.ShowThinking), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// llmDebugRedacted replaces the API keys in the files of --llm-debug
+const llmDebugRedacted = "REDACTED"
+
+// llmDebugSecretHeaders are the request headers carrying the API key of a provider
+var llmDebugSecretHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"}
+
+// llmDebugRequest is the request written by --llm-debug
+type llmDebugRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// llmDebugDump writes the request and the answers of an analysis to the directory of
+// --llm-debug, to see what a provider actually answered
+type llmDebugDump struct {
+	dir    string
+	prefix string // Time and provider, shared by the files of an analysis
+	apiKey string
+	w      io.Writer
+}
+
+// newLLMDebugDump returns the dump of an analysis, or nil without --llm-debug
+func newLLMDebugDump(config LLMConfig, now time.Time) *llmDebugDump {
+	if config.DebugDir == "" {
+		return nil
+	}
+	return &llmDebugDump{
+		dir:    config.DebugDir,
+		prefix: fmt.Sprintf("%s-%s", now.Format("20060102-150405"), config.Provider),
+		apiKey: config.APIKey,
+		w:      config.writer(),
+	}
+}
+
+// redact removes the API key from text
+func (d *llmDebugDump) redact(text string) string {
+	if d.apiKey == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, d.apiKey, llmDebugRedacted)
+}
+
+// writeRequest writes a request without its API key
+func (d *llmDebugDump) writeRequest(req *http.Request) error {
+	if d == nil {
+		return nil
+	}
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		if body, err = io.ReadAll(reader); err != nil {
+			return err
+		}
+	}
+
+	request := llmDebugRequest{
+		Method:  req.Method,
+		URL:     d.redact(req.URL.String()),
+		Headers: map[string]string{},
+		Body:    json.RawMessage(d.redact(string(body))),
+	}
+	for name := range req.Header {
+		request.Headers[name] = d.redact(req.Header.Get(name))
+	}
+	for _, name := range llmDebugSecretHeaders {
+		if req.Header.Get(name) != "" {
+			request.Headers[name] = llmDebugRedacted
+		}
+	}
+	if !json.Valid(request.Body) {
+		request.Body, _ = json.Marshal(string(request.Body))
+	}
+
+	data, err := json.MarshalIndent(request, "", "  ")
+	if err != nil {
+		return err
+	}
+	return d.write("request.json", data)
+}
+
+// writeResponse writes the raw answer of an attempt
+func (d *llmDebugDump) writeResponse(attempt int, body []byte) error {
+	if d == nil {
+		return nil
+	}
+	return d.write(fmt.Sprintf("response-%d.json", attempt), []byte(d.redact(string(body))))
+}
+
+// write writes a file of the dump, readable only by the user since it holds logs
+func (d *llmDebugDump) write(name string, data []byte) error {
+	if err := os.MkdirAll(d.dir, 0o700); err != nil {
+		return fmt.Errorf("error creating the --llm-debug directory: %v", err)
+	}
+	path := filepath.Join(d.dir, d.prefix+"-"+name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	_, _ = fmt.Fprintf(d.w, "Wrote %s\n", path)
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GeminiRequest represents the request structure for Gemini API
+type GeminiRequest struct {
+	SystemInstruction *GeminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent        `json:"contents"`
+	GenerationConfig  GeminiGenerationConfig `json:"generationConfig"`
+	SafetySettings    []GeminiSafetySetting  `json:"safetySettings,omitempty"`
+}
+
+// GeminiContent represents a content part in the Gemini API request
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // Empty for the system instruction
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart represents a content part in a Gemini content message
+type GeminiPart struct {
+	Text string `json:"text"`
+}
+
+// GeminiGenerationConfig represents generation parameters for Gemini API
+type GeminiGenerationConfig struct {
+	Temperature     float64 `json:"temperature"`
+	MaxOutputTokens int     `json:"maxOutputTokens"`
+	TopP            float64 `json:"topP,omitempty"`
+	TopK            int     `json:"topK,omitempty"`
+}
+
+// GeminiSafetySetting represents safety settings for Gemini API
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// GeminiResponse represents the response structure from Gemini API
+type GeminiResponse struct {
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	Error          *GeminiError          `json:"error,omitempty"`
+}
+
+// GeminiCandidate represents a completion candidate in the Gemini API response
+type GeminiCandidate struct {
+	Content       GeminiContent        `json:"content"`
+	FinishReason  string               `json:"finishReason"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GeminiSafetyRating represents a safety rating in the Gemini API response
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+}
+
+// GeminiPromptFeedback represents feedback about the prompt
+type GeminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason,omitempty"` // Set when the prompt itself was blocked
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings"`
+}
+
+// geminiSafetyThresholds are the values of --gemini-safety and the threshold they set for
+// every harm category
+var geminiSafetyThresholds = map[string]string{
+	"none":             "BLOCK_NONE",
+	"only-high":        "BLOCK_ONLY_HIGH",
+	"medium-and-above": "BLOCK_MEDIUM_AND_ABOVE",
+	"low-and-above":    "BLOCK_LOW_AND_ABOVE",
+}
+
+// geminiHarmCategories are the categories of the Gemini safety filters
+var geminiHarmCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+}
+
+// geminiSafetySettings returns the safety settings of a --gemini-safety threshold, none
+// for the default thresholds of the API
+func geminiSafetySettings(threshold string) ([]GeminiSafetySetting, error) {
+	if threshold == "" {
+		return nil, nil
+	}
+	value, ok := geminiSafetyThresholds[threshold]
+	if !ok {
+		return nil, fmt.Errorf("invalid Gemini safety threshold %q (expected none, only-high, medium-and-above or low-and-above)", threshold)
+	}
+	settings := make([]GeminiSafetySetting, len(geminiHarmCategories))
+	for i, category := range geminiHarmCategories {
+		settings[i] = GeminiSafetySetting{Category: category, Threshold: value}
+	}
+	return settings, nil
+}
+
+// newGeminiRequest returns the request of an analysis, with the system prompt as the system
+// instruction
+func newGeminiRequest(prompt AnalysisPrompt, config LLMConfig) (GeminiRequest, error) {
+	safetySettings, err := geminiSafetySettings(config.GeminiSafety)
+	if err != nil {
+		return GeminiRequest{}, err
+	}
+	topP := 0.95
+	if config.TopP > 0 {
+		topP = config.TopP
+	}
+	return GeminiRequest{
+		SystemInstruction: &GeminiContent{Parts: []GeminiPart{{Text: prompt.SystemPrompt}}},
+		Contents:          []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: prompt.UserPrompt}}}},
+		GenerationConfig: GeminiGenerationConfig{
+			Temperature:     config.temperature(),
+			MaxOutputTokens: config.maxOutputTokens(),
+			TopP:            topP,
+		},
+		SafetySettings: safetySettings,
+	}, nil
+}
+
+// geminiBlockedCategories returns the harm categories rated as likely, e.g.
+// "HARM_CATEGORY_DANGEROUS_CONTENT (HIGH)"
+func geminiBlockedCategories(ratings []GeminiSafetyRating) string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Probability == "MEDIUM" || rating.Probability == "HIGH" {
+			categories = append(categories, fmt.Sprintf("%s (%s)", rating.Category, rating.Probability))
+		}
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(categories, ", ")
+}
+
+// geminiAnalysisText returns the text of the first candidate of a response, or why Gemini
+// did not answer
+func geminiAnalysisText(response GeminiResponse) (string, error) {
+	const hint = "; the logs may contain content the safety filters object to, retry with --gemini-safety only-high or none"
+	if feedback := response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return "", fmt.Errorf("gemini blocked the prompt (%s)%s%s", feedback.BlockReason, geminiBlockedCategories(feedback.SafetyRatings), hint)
+	}
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("no completions returned from Gemini API")
+	}
+
+	candidate := response.Candidates[0]
+	switch candidate.FinishReason {
+	case "SAFETY":
+		return "", fmt.Errorf("gemini stopped the analysis for safety reasons%s%s", geminiBlockedCategories(candidate.SafetyRatings), hint)
+	case "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "", fmt.Errorf("gemini stopped the analysis (finish reason %s)", candidate.FinishReason)
+	}
+
+	var analysisText string
+	for _, part := range candidate.Content.Parts {
+		analysisText += part.Text
+	}
+	return analysisText, nil
+}
+
+// GeminiError represents an error from the Gemini API
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// geminiProvider analyzes logs with the Gemini generateContent API
+type geminiProvider struct {
+	baseURL string
+}
+
+// Name returns the name of the API in messages
+func (p geminiProvider) Name() string {
+	return "Gemini API"
+}
+
+// Timeout returns how long to wait for an answer
+func (p geminiProvider) Timeout(model string, config LLMConfig) time.Duration {
+	return 60 * time.Second
+}
+
+// NewRequest returns the request of an analysis. The API key is sent in a header rather
+// than the URL, which would show it in error messages.
+func (p geminiProvider) NewRequest(prompt AnalysisPrompt, model string, config LLMConfig) (*http.Request, error) {
+	request, err := newGeminiRequest(prompt, config)
+	if err != nil {
+		return nil, err
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	apiURL := fmt.Sprintf("%s/v1beta/models/%s:generateContent", p.baseURL, url.PathEscape(model))
+	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", config.APIKey)
+	return req, nil
+}
+
+// ParseResponse returns the analysis of an answer
+func (p geminiProvider) ParseResponse(body []byte, model string, config LLMConfig) (string, error) {
+	var response GeminiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+	if response.Error != nil {
+		return "", fmt.Errorf("gemini API error (code %d): %s", response.Error.Code, response.Error.Message)
+	}
+	return geminiAnalysisText(response)
+}
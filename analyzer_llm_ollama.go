@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaRequest represents the request structure for Ollama API
+type OllamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  OllamaOptions   `json:"options,omitempty"`
+}
+
+// OllamaMessage represents a message in the Ollama API request
+type OllamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// OllamaOptions represents configuration options for the Ollama API
+type OllamaOptions struct {
+	Temperature float64 `json:"temperature,omitempty"`
+	TopP        float64 `json:"top_p,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// OllamaResponse represents the response structure from Ollama API
+type OllamaResponse struct {
+	Model              string        `json:"model"`
+	CreatedAt          string        `json:"created_at"`
+	Message            OllamaMessage `json:"message"`
+	Done               bool          `json:"done"`
+	TotalDuration      int64         `json:"total_duration"`
+	LoadDuration       int64         `json:"load_duration"`
+	PromptEvalCount    int           `json:"prompt_eval_count"`
+	PromptEvalDuration int64         `json:"prompt_eval_duration"`
+	EvalCount          int           `json:"eval_count"`
+	EvalDuration       int64         `json:"eval_duration"`
+}
+
+// ollamaProvider analyzes logs with the chat API of an Ollama server
+type ollamaProvider struct {
+	baseURL string // OllamaHost
+}
+
+// Name returns the name of the API in messages
+func (p ollamaProvider) Name() string {
+	return "Ollama"
+}
+
+// Timeout returns how long to wait for an answer, set with --ollama-timeout since local
+// models can be slow
+func (p ollamaProvider) Timeout(model string, config LLMConfig) time.Duration {
+	return time.Duration(OllamaTimeout) * time.Second
+}
+
+// NewRequest returns the request of an analysis
+func (p ollamaProvider) NewRequest(prompt AnalysisPrompt, model string, config LLMConfig) (*http.Request, error) {
+	request := OllamaRequest{
+		Model: model,
+		Messages: []OllamaMessage{
+			{Role: "system", Content: prompt.SystemPrompt},
+			{Role: "user", Content: prompt.UserPrompt},
+		},
+		Stream: false,
+		Options: OllamaOptions{
+			Temperature: config.temperature(),
+			TopP:        config.TopP,
+			NumPredict:  config.maxOutputTokens(),
+		},
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(p.baseURL, "/")+"/api/chat", bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// ParseResponse returns the analysis of an answer and shows how long the model took
+func (p ollamaProvider) ParseResponse(body []byte, model string, config LLMConfig) (string, error) {
+	var response OllamaResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+	_, _ = fmt.Fprintf(config.writer(), "Request completed in %.2f seconds\n", float64(response.TotalDuration)/1e9)
+	return response.Message.Content, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// OpenAIRequest represents the request structure for OpenAI API
+type OpenAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []OpenAIMessage `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"` // Not supported by reasoning models
+	MaxTokens   int             `json:"max_tokens,omitempty"`  // Replaced by max_completion_tokens for reasoning models
+	TopP        float64         `json:"top_p,omitempty"`       // Not supported by reasoning models
+
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"` // Reasoning and answer tokens
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`      // low, medium or high
+}
+
+// OpenAIMessage represents a message in the OpenAI API request
+type OpenAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// OpenAIResponse represents the response structure from OpenAI API
+type OpenAIResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   OpenAIUsage    `json:"usage"`
+	Error   *OpenAIError   `json:"error,omitempty"`
+}
+
+// OpenAIChoice represents a completion choice in the OpenAI API response
+type OpenAIChoice struct {
+	Index        int           `json:"index"`
+	Message      OpenAIMessage `json:"message"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+// OpenAIUsage represents token usage information
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+const (
+	// openAIReasoningTokens is the reasoning allowance of reasoning models without --thinking-budget
+	openAIReasoningTokens = 20000
+)
+
+// openAIReasoningModel matches the OpenAI reasoning models (o1, o3-mini, o4-mini...), which
+// reject temperature and max_tokens
+var openAIReasoningModel = regexp.MustCompile(`^o\d+(-|$)`)
+
+// isOpenAIReasoningModel reports whether an OpenAI model is a reasoning model
+func isOpenAIReasoningModel(model string) bool {
+	return openAIReasoningModel.MatchString(model)
+}
+
+// openAIReasoningEffort maps a --thinking-budget to the reasoning effort of OpenAI reasoning
+// models, empty for the default effort of the API
+func openAIReasoningEffort(thinkingBudget int) string {
+	switch {
+	case thinkingBudget <= 0:
+		return ""
+	case thinkingBudget < 8000:
+		return "low"
+	case thinkingBudget < 24000:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// newOpenAIRequest returns the chat completion request of an analysis. Reasoning models get
+// the system prompt as developer message, and a completion limit that leaves room for the
+// reasoning on top of the analysis.
+func newOpenAIRequest(prompt AnalysisPrompt, model string, config LLMConfig) OpenAIRequest {
+	if !isOpenAIReasoningModel(model) {
+		temperature := config.temperature()
+		return OpenAIRequest{
+			Model: model,
+			Messages: []OpenAIMessage{
+				{Role: "system", Content: prompt.SystemPrompt},
+				{Role: "user", Content: prompt.UserPrompt},
+			},
+			Temperature: &temperature,
+			MaxTokens:   config.maxOutputTokens(),
+			TopP:        config.TopP,
+		}
+	}
+
+	reasoningTokens := openAIReasoningTokens
+	if config.ThinkingBudget > 0 {
+		reasoningTokens = config.ThinkingBudget
+	}
+	return OpenAIRequest{
+		Model: model,
+		Messages: []OpenAIMessage{
+			{Role: "developer", Content: prompt.SystemPrompt},
+			{Role: "user", Content: prompt.UserPrompt},
+		},
+		MaxCompletionTokens: config.maxOutputTokens() + reasoningTokens,
+		ReasoningEffort:     openAIReasoningEffort(config.ThinkingBudget),
+	}
+}
+
+// OpenAIError represents an error from the OpenAI API
+type OpenAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
+
+// openAIProvider analyzes logs with the OpenAI Chat Completions API
+type openAIProvider struct {
+	baseURL string
+}
+
+// Name returns the name of the API in messages
+func (p openAIProvider) Name() string {
+	return "OpenAI API"
+}
+
+// Timeout returns how long to wait for an answer, longer for reasoning models which think
+// before answering
+func (p openAIProvider) Timeout(model string, config LLMConfig) time.Duration {
+	if isOpenAIReasoningModel(model) {
+		return 5 * time.Minute
+	}
+	return 60 * time.Second
+}
+
+// NewRequest returns the request of an analysis
+func (p openAIProvider) NewRequest(prompt AnalysisPrompt, model string, config LLMConfig) (*http.Request, error) {
+	request := newOpenAIRequest(prompt, model, config)
+	if isOpenAIReasoningModel(model) {
+		config.samplingIgnored(model + " does not support them")
+	}
+	if request.ReasoningEffort != "" {
+		_, _ = fmt.Fprintf(config.writer(), "Using %s reasoning effort for the thinking budget of %d tokens\n",
+			request.ReasoningEffort, config.ThinkingBudget)
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req, err := http.NewRequest("POST", p.baseURL+"/v1/chat/completions", bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	return req, nil
+}
+
+// ParseResponse returns the analysis of an answer and shows its token usage
+func (p openAIProvider) ParseResponse(body []byte, model string, config LLMConfig) (string, error) {
+	var response OpenAIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+	if response.Error != nil {
+		return "", fmt.Errorf("OpenAI API error: %s (type: %s, code: %s)",
+			response.Error.Message, response.Error.Type, response.Error.Code)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no completions returned from OpenAI API")
+	}
+
+	reasoning := isOpenAIReasoningModel(model)
+	analysisText := response.Choices[0].Message.Content
+	if reasoning && analysisText == "" && response.Choices[0].FinishReason == "length" {
+		return "", fmt.Errorf("%s used all %d completion tokens before answering, raise --thinking-budget",
+			model, newOpenAIRequest(AnalysisPrompt{}, model, config).MaxCompletionTokens)
+	}
+
+	_, _ = fmt.Fprintf(config.writer(), "Token usage - Prompt: %d, Completion: %d, Total: %d\n",
+		response.Usage.PromptTokens,
+		response.Usage.CompletionTokens,
+		response.Usage.TotalTokens)
+	if reasoning {
+		_, _ = fmt.Fprintf(config.writer(), "Reasoning tokens: %d\n", response.Usage.CompletionTokensDetails.ReasoningTokens)
+	}
+	return analysisText, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// llmMaxAttempts is how many times a request is sent before a transient error is reported
+	llmMaxAttempts = 3
+	// llmMaxRetryAfter caps the wait asked for by a Retry-After header
+	llmMaxRetryAfter = 30 * time.Second
+)
+
+// llmRetryDelay is the wait before the first retry, doubled for each following one
+var llmRetryDelay = 2 * time.Second
+
+// providerURLs are the base URLs of the provider APIs; Ollama uses OllamaHost
+var providerURLs = map[LLMProvider]string{
+	ProviderAnthropic: "https://api.anthropic.com",
+	ProviderOpenAI:    "https://api.openai.com",
+	ProviderGemini:    "https://generativelanguage.googleapis.com",
+}
+
+// Provider is an LLM API logs are analyzed with. runAnalysis takes care of the prompt, the
+// HTTP exchange, retries and errors common to all providers.
+type Provider interface {
+	// Name returns the name of the API in messages, e.g. "Anthropic API"
+	Name() string
+	// Timeout returns how long to wait for the answer of a model
+	Timeout(model string, config LLMConfig) time.Duration
+	// NewRequest returns the HTTP request analyzing a prompt with a model
+	NewRequest(prompt AnalysisPrompt, model string, config LLMConfig) (*http.Request, error)
+	// ParseResponse returns the analysis of a successful answer, or why it has none
+	ParseResponse(body []byte, model string, config LLMConfig) (string, error)
+}
+
+// newProvider returns the implementation of a provider
+func newProvider(provider LLMProvider) (Provider, error) {
+	switch provider {
+	case ProviderAnthropic:
+		return anthropicProvider{baseURL: providerURLs[ProviderAnthropic]}, nil
+	case ProviderOpenAI:
+		return openAIProvider{baseURL: providerURLs[ProviderOpenAI]}, nil
+	case ProviderGemini:
+		return geminiProvider{baseURL: providerURLs[ProviderGemini]}, nil
+	case ProviderOllama:
+		return ollamaProvider{baseURL: OllamaHost}, nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
+	}
+}
+
+// runAnalysis analyzes logs with a provider and displays or hands over the analysis
+func runAnalysis(provider Provider, logs []LogEntry, config LLMConfig) error {
+	model := config.Model
+	if model == "" {
+		model = getDefaultModel(config.Provider)
+	}
+
+	// Try to get the human-friendly model name
+	if modelInfo, found := GetModelInfo(config.Provider, model); found {
+		_, _ = fmt.Fprintf(config.writer(), "Analyzing logs with %s API using %s (%s)...\n",
+			config.Provider, modelInfo.Name, model)
+	} else {
+		_, _ = fmt.Fprintf(config.writer(), "Analyzing logs with %s API using %s...\n",
+			config.Provider, model)
+	}
+
+	prompt, err := prepareAnalysisPrompts(logs, config)
+	if err != nil {
+		return err
+	}
+	req, err := provider.NewRequest(prompt, model, config)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(config.writer(), "Sending request to %s...\n", provider.Name())
+	client := newHTTPClient(provider.Timeout(model, config))
+	debug := newLLMDebugDump(config, time.Now())
+	if err := debug.writeRequest(req); err != nil {
+		return err
+	}
+	body, err := sendLLMRequest(client, req, provider.Name(), config.writer(), debug)
+	if err != nil {
+		return err
+	}
+
+	analysisText, err := provider.ParseResponse(body, model, config)
+	if err != nil && config.DebugDir == "" && strings.HasPrefix(err.Error(), "error parsing response") {
+		return fmt.Errorf("%v (rerun with --llm-debug <dir> to see the answer)", err)
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(analysisText) == "" {
+		return fmt.Errorf("no analysis returned from %s", provider.Name())
+	}
+	return config.handleAnalysis(analysisText)
+}
+
+// retryableStatus reports whether an answer is worth retrying: rate limits and overloaded or
+// unavailable servers
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		529: // Anthropic: overloaded
+		return true
+	}
+	return false
+}
+
+// sendLLMRequest sends a request and returns the body of its successful answer. Connection
+// failures and transient errors are retried with increasing delays, or after the delay asked
+// for by the server. Timeouts are not retried, since the model would likely be as slow again.
+// The answers are written to the debug dump, if any.
+func sendLLMRequest(client *http.Client, req *http.Request, name string, w io.Writer, debug *llmDebugDump) ([]byte, error) {
+	delay := llmRetryDelay
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			var netErr net.Error
+			if attempt == llmMaxAttempts || (errors.As(err, &netErr) && netErr.Timeout()) {
+				return nil, fmt.Errorf("error sending request to %s: %v", name, err)
+			}
+			_, _ = fmt.Fprintf(w, "Could not reach %s, retrying in %s (attempt %d of %d)...\n", name, delay, attempt+1, llmMaxAttempts)
+			time.Sleep(delay)
+			delay *= 2
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading response: %v", err)
+		}
+		if err := debug.writeResponse(attempt, body); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+		if !retryableStatus(resp.StatusCode) || attempt == llmMaxAttempts {
+			return nil, fmt.Errorf("error from %s (%s)%s", name, resp.Status, apiErrorDetail(body))
+		}
+
+		wait := delay
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait = min(time.Duration(seconds)*time.Second, llmMaxRetryAfter)
+		}
+		_, _ = fmt.Fprintf(w, "%s answered with %s, retrying in %s (attempt %d of %d)...\n", name, resp.Status, wait, attempt+1, llmMaxAttempts)
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// apiErrorDetail returns the message of an error answer: {"error": {"type", "message"}} for
+// Anthropic, OpenAI and Gemini, {"error": "..."} for Ollama, or the beginning of the body
+func apiErrorDetail(body []byte) string {
+	var answer struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &answer) == nil && len(answer.Error) > 0 {
+		var detail struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"` // Gemini
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(answer.Error, &detail) == nil && detail.Message != "" {
+			if kind := detail.Type + detail.Status; kind != "" {
+				return fmt.Sprintf(": %s: %s", kind, detail.Message)
+			}
+			return ": " + detail.Message
+		}
+		var message string
+		if json.Unmarshal(answer.Error, &message) == nil && message != "" {
+			return ": " + message
+		}
+	}
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		return ""
+	}
+	return ": " + truncateString(text, 300)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// providerConformanceCase describes what a provider sends and how it reads answers
+type providerConformanceCase struct {
+	provider LLMProvider
+	model    string
+	path     string
+	// auth checks the authentication of a request
+	auth func(t *testing.T, r *http.Request)
+	// prompt returns the system and user prompts of a request body
+	prompt func(body map[string]any) (string, string)
+	// answer is a successful answer with the analysis "Database unreachable"
+	answer string
+	// empty is a successful answer without analysis
+	empty string
+	// unauthorized is the answer to an invalid API key, and errUnauthorized its error
+	unauthorized    string
+	errUnauthorized string
+}
+
+// jsonField returns the value at a path of object keys and array indexes, or nil
+func jsonField(value any, path ...any) any {
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil
+			}
+			value = object[key]
+		case int:
+			array, ok := value.([]any)
+			if !ok || key >= len(array) {
+				return nil
+			}
+			value = array[key]
+		}
+	}
+	return value
+}
+
+// jsonString returns the string at a path, or ""
+func jsonString(value any, path ...any) string {
+	text, _ := jsonField(value, path...).(string)
+	return text
+}
+
+var providerConformanceCases = []providerConformanceCase{
+	{
+		provider: ProviderAnthropic,
+		model:    "claude-sonnet-4-20250514",
+		path:     "/v1/messages",
+		auth: func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+			assert.Equal(t, "2023-06-01", r.Header.Get("anthropic-version"))
+		},
+		prompt: func(body map[string]any) (string, string) {
+			// The logs and the instructions are separate blocks, so that the logs can be cached
+			return jsonString(body, "system", 0, "text"), jsonString(body, "messages", 0, "content", 0, "text") +
+				"\n\n" + jsonString(body, "messages", 0, "content", 1, "text")
+		},
+		answer:          `{"content": [{"type": "text", "text": "Database unreachable"}], "usage": {"input_tokens": 10, "output_tokens": 2}}`,
+		empty:           `{"content": [], "usage": {"input_tokens": 10, "output_tokens": 0}}`,
+		unauthorized:    `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`,
+		errUnauthorized: "error from Anthropic API (401 Unauthorized): authentication_error: invalid x-api-key",
+	},
+	{
+		provider: ProviderOpenAI,
+		model:    "gpt-4o",
+		path:     "/v1/chat/completions",
+		auth: func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		},
+		prompt: func(body map[string]any) (string, string) {
+			return jsonString(body, "messages", 0, "content"), jsonString(body, "messages", 1, "content")
+		},
+		answer:          `{"choices": [{"message": {"role": "assistant", "content": "Database unreachable"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 10, "completion_tokens": 2, "total_tokens": 12}}`,
+		empty:           `{"choices": [{"message": {"role": "assistant", "content": ""}, "finish_reason": "stop"}]}`,
+		unauthorized:    `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error", "code": "invalid_api_key"}}`,
+		errUnauthorized: "error from OpenAI API (401 Unauthorized): invalid_request_error: Incorrect API key provided",
+	},
+	{
+		provider: ProviderGemini,
+		model:    "gemini-2.0-flash",
+		path:     "/v1beta/models/gemini-2.0-flash:generateContent",
+		auth: func(t *testing.T, r *http.Request) {
+			assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+			assert.Empty(t, r.URL.Query().Get("key"), "the API key is not sent in the URL")
+		},
+		prompt: func(body map[string]any) (string, string) {
+			return jsonString(body, "systemInstruction", "parts", 0, "text"), jsonString(body, "contents", 0, "parts", 0, "text")
+		},
+		answer:          `{"candidates": [{"content": {"parts": [{"text": "Database "}, {"text": "unreachable"}]}, "finishReason": "STOP"}]}`,
+		empty:           `{"candidates": [{"content": {"parts": []}, "finishReason": "STOP"}]}`,
+		unauthorized:    `{"error": {"code": 401, "message": "API key not valid", "status": "UNAUTHENTICATED"}}`,
+		errUnauthorized: "error from Gemini API (401 Unauthorized): UNAUTHENTICATED: API key not valid",
+	},
+	{
+		provider: ProviderOllama,
+		model:    "llama3",
+		path:     "/api/chat",
+		auth: func(t *testing.T, r *http.Request) {
+			assert.Empty(t, r.Header.Get("Authorization"), "Ollama is not sent the API key")
+		},
+		prompt: func(body map[string]any) (string, string) {
+			return jsonString(body, "messages", 0, "content"), jsonString(body, "messages", 1, "content")
+		},
+		answer:          `{"model": "llama3", "message": {"role": "assistant", "content": "Database unreachable"}, "done": true, "total_duration": 1500000000}`,
+		empty:           `{"model": "llama3", "message": {"role": "assistant", "content": ""}, "done": true}`,
+		unauthorized:    `{"error": "unauthorized"}`,
+		errUnauthorized: "error from Ollama (401 Unauthorized): unauthorized",
+	},
+}
+
+// TestProviderConformance checks that every provider sends the prompts with its
+// authentication and reads answers and errors the same way
+func TestProviderConformance(t *testing.T) {
+	logs := []LogEntry{{Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Level: "error", Message: "Failed to ping DB"}}
+
+	for _, tc := range providerConformanceCases {
+		t.Run(string(tc.provider), func(t *testing.T) {
+			var status int
+			var answer string
+			var requests []*http.Request
+			var bodies []map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				var body map[string]any
+				assert.NoError(t, json.Unmarshal(data, &body), "the request body is JSON")
+				requests = append(requests, r)
+				bodies = append(bodies, body)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(answer))
+			}))
+			defer server.Close()
+			useProviderURL(t, tc.provider, server.URL)
+
+			var analysis string
+			config := LLMConfig{
+				Provider: tc.provider,
+				Model:    tc.model,
+				APIKey:   "test-key",
+				Problem:  "the app cannot reach the database",
+				Writer:   &bytes.Buffer{},
+				Output:   func(text string) error { analysis = text; return nil },
+			}
+			if tc.provider == ProviderOllama {
+				config.APIKey = ""
+			}
+			expected, err := prepareAnalysisPrompts(logs, config)
+			require.NoError(t, err)
+
+			// Request shape, authentication and response parsing
+			status, answer = http.StatusOK, tc.answer
+			require.NoError(t, analyzeWithLLM(logs, config))
+			assert.Equal(t, "Database unreachable", analysis)
+			require.Len(t, requests, 1)
+			r := requests[0]
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, tc.path, r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			tc.auth(t, r)
+			if tc.provider != ProviderGemini { // Gemini has the model in the path
+				assert.Equal(t, tc.model, jsonString(bodies[0], "model"))
+			}
+			system, user := tc.prompt(bodies[0])
+			assert.Equal(t, expected.SystemPrompt, system)
+			assert.Equal(t, expected.UserPrompt, user)
+
+			// An answer without analysis is an error
+			status, answer = http.StatusOK, tc.empty
+			provider, err := newProvider(tc.provider)
+			require.NoError(t, err)
+			assert.EqualError(t, analyzeWithLLM(logs, config), "no analysis returned from "+provider.Name())
+
+			// Errors show the status and the message of the provider, and are not retried
+			requests = nil
+			status, answer = http.StatusUnauthorized, tc.unauthorized
+			assert.EqualError(t, analyzeWithLLM(logs, config), tc.errUnauthorized)
+			assert.Len(t, requests, 1)
+
+			// Overloaded servers are retried
+			requests = nil
+			status, answer = http.StatusServiceUnavailable, tc.unauthorized
+			assert.Error(t, analyzeWithLLM(logs, config))
+			assert.Len(t, requests, llmMaxAttempts)
+
+			// Answers that are not JSON
+			status, answer = http.StatusOK, "<html>proxy error</html>"
+			err = analyzeWithLLM(logs, config)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "error parsing response")
+			assert.Contains(t, err.Error(), "rerun with --llm-debug <dir> to see the answer")
+		})
+	}
+}
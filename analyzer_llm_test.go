@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGeminiRequest(t *testing.T) {
+	prompt := AnalysisPrompt{SystemPrompt: "You are an expert log analyzer.", UserPrompt: "Here are 2 log entries"}
+
+	request, err := newGeminiRequest(prompt, LLMConfig{})
+	require.NoError(t, err)
+	data, err := json.Marshal(request)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, map[string]any{"parts": []any{map[string]any{"text": "You are an expert log analyzer."}}}, decoded["systemInstruction"])
+	assert.Equal(t, []any{map[string]any{"role": "user", "parts": []any{map[string]any{"text": "Here are 2 log entries"}}}}, decoded["contents"])
+	assert.NotContains(t, decoded, "safetySettings", "the API defaults apply without --gemini-safety")
+
+	request, err = newGeminiRequest(prompt, LLMConfig{GeminiSafety: "only-high"})
+	require.NoError(t, err)
+	require.Len(t, request.SafetySettings, len(geminiHarmCategories))
+	for _, setting := range request.SafetySettings {
+		assert.Equal(t, "BLOCK_ONLY_HIGH", setting.Threshold)
+	}
+
+	_, err = newGeminiRequest(prompt, LLMConfig{GeminiSafety: "high"})
+	assert.EqualError(t, err, `invalid Gemini safety threshold "high" (expected none, only-high, medium-and-above or low-and-above)`)
+}
+
+func TestGeminiAnalysisText(t *testing.T) {
+	text, err := geminiAnalysisText(GeminiResponse{Candidates: []GeminiCandidate{{
+		Content:      GeminiContent{Parts: []GeminiPart{{Text: "## Summary"}, {Text: "\nAll good"}}},
+		FinishReason: "STOP",
+	}}})
+	require.NoError(t, err)
+	assert.Equal(t, "## Summary\nAll good", text)
+
+	_, err = geminiAnalysisText(GeminiResponse{Candidates: []GeminiCandidate{{
+		FinishReason: "SAFETY",
+		SafetyRatings: []GeminiSafetyRating{
+			{Category: "HARM_CATEGORY_HARASSMENT", Probability: "NEGLIGIBLE"},
+			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "HIGH"},
+		},
+	}}})
+	assert.EqualError(t, err, "gemini stopped the analysis for safety reasons: HARM_CATEGORY_DANGEROUS_CONTENT (HIGH); "+
+		"the logs may contain content the safety filters object to, retry with --gemini-safety only-high or none")
+
+	_, err = geminiAnalysisText(GeminiResponse{PromptFeedback: &GeminiPromptFeedback{BlockReason: "SAFETY"}})
+	assert.EqualError(t, err, "gemini blocked the prompt (SAFETY); "+
+		"the logs may contain content the safety filters object to, retry with --gemini-safety only-high or none")
+
+	_, err = geminiAnalysisText(GeminiResponse{Candidates: []GeminiCandidate{{FinishReason: "RECITATION"}}})
+	assert.EqualError(t, err, "gemini stopped the analysis (finish reason RECITATION)")
+
+	_, err = geminiAnalysisText(GeminiResponse{})
+	assert.EqualError(t, err, "no completions returned from Gemini API")
+}
+
+func TestNewOpenAIRequest(t *testing.T) {
+	prompt := AnalysisPrompt{SystemPrompt: "You are an expert log analyzer.", UserPrompt: "Here are 2 log entries"}
+
+	data, err := json.Marshal(newOpenAIRequest(prompt, "gpt-4o", LLMConfig{ThinkingBudget: 10000}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "gpt-4o",
+		"messages": [{"role": "system", "content": "You are an expert log analyzer."}, {"role": "user", "content": "Here are 2 log entries"}],
+		"temperature": 0.3,
+		"max_tokens": 4000
+	}`, string(data))
+
+	data, err = json.Marshal(newOpenAIRequest(prompt, "o4-mini", LLMConfig{ThinkingBudget: 10000}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "o4-mini",
+		"messages": [{"role": "developer", "content": "You are an expert log analyzer."}, {"role": "user", "content": "Here are 2 log entries"}],
+		"max_completion_tokens": 14000,
+		"reasoning_effort": "medium"
+	}`, string(data))
+
+	request := newOpenAIRequest(prompt, "o3", LLMConfig{})
+	assert.Equal(t, defaultMaxOutputTokens+openAIReasoningTokens, request.MaxCompletionTokens)
+	assert.Empty(t, request.ReasoningEffort, "the API default effort applies without --thinking-budget")
+}
+
+func TestOpenAIReasoningModels(t *testing.T) {
+	for _, model := range []string{"o1", "o3", "o3-mini", "o4-mini", "o1-2024-12-17"} {
+		assert.True(t, isOpenAIReasoningModel(model), model)
+	}
+	for _, model := range []string{"gpt-4o", "gpt-4o-mini", "omni-moderation-latest", "gpt-3.5-turbo"} {
+		assert.False(t, isOpenAIReasoningModel(model), model)
+	}
+
+	assert.Equal(t, "", openAIReasoningEffort(0))
+	assert.Equal(t, "low", openAIReasoningEffort(2000))
+	assert.Equal(t, "medium", openAIReasoningEffort(8000))
+	assert.Equal(t, "high", openAIReasoningEffort(32000))
+}
+
+func TestPrepareAnalysisPrompts(t *testing.T) {
+	logs := []LogEntry{{
+		Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"),
+		Level:     "error",
+		Source:    "sqlstore",
+		Message:   "Failed to ping DB",
+		Extras:    map[string]string{"retry": "3", "error": "timeout", "caller": "sqlstore/store.go:240"},
+	}}
+
+	first, err := prepareAnalysisPrompts(logs, LLMConfig{Problem: "Users cannot log in", Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	second, err := prepareAnalysisPrompts(logs, LLMConfig{Problem: "Messages are delayed", Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+
+	assert.Equal(t, first.LogPrompt, second.LogPrompt, "the logs are formatted the same way for every analysis")
+	assert.Contains(t, first.LogPrompt, "Extras: caller=sqlstore/store.go:240, error=timeout, retry=3\n")
+	assert.True(t, strings.HasPrefix(first.UserPrompt, first.LogPrompt), "the logs come before the problem")
+	assert.Equal(t, "I'm investigating this problem: Users cannot log in\n\nPlease provide a detailed analysis of these logs focusing on this problem.", first.Instructions)
+	assert.Equal(t, first.LogPrompt+"\n\n"+first.Instructions, first.UserPrompt)
+
+	thinking, err := prepareAnalysisPrompts(logs, LLMConfig{ThinkingBudget: 10000, Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.Empty(t, thinking.Instructions)
+	assert.Equal(t, thinking.LogPrompt, thinking.UserPrompt)
+}
+
+func TestAnthropicPromptContent(t *testing.T) {
+	system, messages := anthropicPromptContent(AnalysisPrompt{
+		SystemPrompt: "You are an expert log analyzer.",
+		LogPrompt:    "Here are 1 Mattermost server log entries to analyze:",
+		Instructions: "Please provide a detailed analysis of these logs.",
+	})
+	data, err := json.Marshal(AnthropicRequest{System: system, Messages: messages})
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []any{map[string]any{
+		"type": "text", "text": "You are an expert log analyzer.", "cache_control": map[string]any{"type": "ephemeral"},
+	}}, decoded["system"])
+	assert.Equal(t, []any{map[string]any{"role": "user", "content": []any{
+		map[string]any{"type": "text", "text": "Here are 1 Mattermost server log entries to analyze:", "cache_control": map[string]any{"type": "ephemeral"}},
+		map[string]any{"type": "text", "text": "Please provide a detailed analysis of these logs."},
+	}}}, decoded["messages"])
+
+	_, messages = anthropicPromptContent(AnalysisPrompt{LogPrompt: "logs"})
+	assert.Len(t, messages[0].Content, 1, "no empty text block without instructions")
+}
+
+// anthropicThinkingResponse is an extended thinking answer of the Messages API
+const anthropicThinkingResponse = `{
+  "id": "msg_01",
+  "type": "message",
+  "role": "assistant",
+  "model": "claude-sonnet-4-20250514",
+  "content": [
+    {"type": "thinking", "thinking": "The errors start at 10:00 with DB pings failing.", "signature": "EqQBCgIYAhIM"},
+    {"type": "redacted_thinking", "data": "EmwKAhgBEgy3va3pzix"},
+    {"type": "text", "text": "## Summary\n\nThe database became unreachable at 10:00."}
+  ],
+  "stop_reason": "end_turn",
+  "usage": {"input_tokens": 2095, "cache_creation_input_tokens": 0, "cache_read_input_tokens": 1800, "output_tokens": 503}
+}`
+
+func TestAnthropicAnalysisText(t *testing.T) {
+	var response AnthropicResponse
+	require.NoError(t, json.Unmarshal([]byte(anthropicThinkingResponse), &response))
+	assert.Equal(t, 1800, response.Usage.CacheReadInputTokens)
+
+	assert.Equal(t, "## Summary\n\nThe database became unreachable at 10:00.", anthropicAnalysisText(response, false))
+	assert.Equal(t, "## LLM THINKING PROCESS\n\nThe errors start at 10:00 with DB pings failing.\n\n"+
+		"## FINAL ANALYSIS\n\n## Summary\n\nThe database became unreachable at 10:00.", anthropicAnalysisText(response, true))
+
+	response.Content = response.Content[2:]
+	assert.Equal(t, "## Summary\n\nThe database became unreachable at 10:00.", anthropicAnalysisText(response, true),
+		"no thinking section without thinking blocks")
+}
+
+// useProviderURL points a provider to a test server and disables the retry delay
+func useProviderURL(t *testing.T, provider LLMProvider, url string) {
+	oldURLs, oldHost, oldDelay := providerURLs, OllamaHost, llmRetryDelay
+	providerURLs = map[LLMProvider]string{}
+	for name, base := range oldURLs {
+		providerURLs[name] = base
+	}
+	providerURLs[provider] = url
+	OllamaHost = url
+	llmRetryDelay = 0
+	t.Cleanup(func() { providerURLs, OllamaHost, llmRetryDelay = oldURLs, oldHost, oldDelay })
+}
+
+func TestSendLLMRequest(t *testing.T) {
+	useProviderURL(t, ProviderAnthropic, "")
+	var attempts int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		switch r.URL.Path {
+		case "/flaky":
+			if attempts < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(529)
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok": true}`))
+		case "/overloaded":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "max_tokens is too large", "type": "invalid_request_error"}}`))
+		}
+	}))
+	defer server.Close()
+
+	send := func(path string) ([]byte, string, error) {
+		req, err := http.NewRequest("POST", server.URL+path, strings.NewReader(`{"prompt": "logs"}`))
+		require.NoError(t, err)
+		var out bytes.Buffer
+		body, err := sendLLMRequest(server.Client(), req, "Anthropic API", &out, nil)
+		return body, out.String(), err
+	}
+
+	body, out, err := send("/flaky")
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok": true}`, string(body))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []string{`{"prompt": "logs"}`, `{"prompt": "logs"}`, `{"prompt": "logs"}`}, bodies, "retries send the body again")
+	assert.Contains(t, out, "Anthropic API answered with 529 status code 529, retrying in 0s (attempt 2 of 3)...")
+
+	attempts = 0
+	_, _, err = send("/overloaded")
+	assert.EqualError(t, err, "error from Anthropic API (503 Service Unavailable): overloaded_error: Overloaded")
+	assert.Equal(t, llmMaxAttempts, attempts)
+
+	attempts = 0
+	_, _, err = send("/invalid")
+	assert.EqualError(t, err, "error from Anthropic API (400 Bad Request): invalid_request_error: max_tokens is too large")
+	assert.Equal(t, 1, attempts, "client errors are not retried")
+}
+
+func TestAPIErrorDetail(t *testing.T) {
+	assert.Equal(t, ": INVALID_ARGUMENT: API key not valid", apiErrorDetail([]byte(`{"error": {"code": 400, "message": "API key not valid", "status": "INVALID_ARGUMENT"}}`)))
+	assert.Equal(t, `: model "llama9" not found, try pulling it first`, apiErrorDetail([]byte(`{"error": "model \"llama9\" not found, try pulling it first"}`)))
+	assert.Equal(t, ": upstream connect error", apiErrorDetail([]byte("upstream connect error\n")))
+	assert.Equal(t, "", apiErrorDetail(nil))
+}
+
+func TestAnalyzeWithLLM(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(anthropicThinkingResponse))
+	}))
+	defer server.Close()
+	useProviderURL(t, ProviderAnthropic, server.URL)
+
+	var analysis string
+	var out bytes.Buffer
+	config := LLMConfig{
+		Provider: ProviderAnthropic,
+		Model:    "claude-sonnet-4-20250514",
+		APIKey:   "sk-test",
+		Writer:   &out,
+		Output:   func(text string) error { analysis = text; return nil },
+	}
+	require.NoError(t, analyzeWithLLM([]LogEntry{{Level: "error", Message: "Failed to ping DB"}}, config))
+	assert.Equal(t, "## Summary\n\nThe database became unreachable at 10:00.", analysis)
+	assert.Contains(t, out.String(), "Analyzing logs with anthropic API using Claude 4 Sonnet (claude-sonnet-4-20250514)...\n")
+	assert.Contains(t, out.String(), "Token usage - Input: 2095, Cache write: 0, Cache read: 1800, Output: 503\n")
+
+	config.Provider = "mistral"
+	assert.EqualError(t, analyzeWithLLM(nil, config), "unsupported LLM provider: mistral")
+}
+
+func TestGenerationParameters(t *testing.T) {
+	prompt := AnalysisPrompt{SystemPrompt: "system", UserPrompt: "logs", LogPrompt: "logs"}
+	temperature := 0.7
+	config := LLMConfig{Temperature: &temperature, TopP: 0.9, MaxOutputTokens: 16000, Writer: &bytes.Buffer{}}
+
+	requestBody := func(provider Provider, model string, config LLMConfig) map[string]any {
+		req, err := provider.NewRequest(prompt, model, config)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		return body
+	}
+
+	body := requestBody(anthropicProvider{}, "claude-sonnet-4-20250514", config)
+	assert.Equal(t, 16000.0, body["max_tokens"])
+	assert.Equal(t, 0.9, body["top_p"])
+	assert.NotContains(t, body, "temperature", "Claude accepts a temperature or a top_p")
+	body = requestBody(anthropicProvider{}, "claude-sonnet-4-20250514", LLMConfig{Temperature: &temperature})
+	assert.Equal(t, 0.7, body["temperature"])
+	assert.Equal(t, 4000.0, body["max_tokens"])
+
+	var out bytes.Buffer
+	thinking := config
+	thinking.ThinkingBudget, thinking.Writer = 10000, &out
+	body = requestBody(anthropicProvider{}, "claude-sonnet-4-20250514", thinking)
+	assert.Equal(t, 26000.0, body["max_tokens"])
+	assert.Equal(t, 1.0, body["temperature"])
+	assert.NotContains(t, body, "top_p")
+	assert.Contains(t, out.String(), "Ignoring --temperature and --top-p: extended thinking requires the default sampling\n")
+
+	body = requestBody(openAIProvider{}, "gpt-4o", config)
+	assert.Equal(t, 0.7, body["temperature"])
+	assert.Equal(t, 0.9, body["top_p"])
+	assert.Equal(t, 16000.0, body["max_tokens"])
+	out.Reset()
+	body = requestBody(openAIProvider{}, "o3", thinking)
+	assert.Equal(t, 26000.0, body["max_completion_tokens"])
+	assert.NotContains(t, body, "temperature")
+	assert.NotContains(t, body, "top_p")
+	assert.Contains(t, out.String(), "Ignoring --temperature and --top-p: o3 does not support them\n")
+
+	body = requestBody(geminiProvider{}, "gemini-2.0-flash", config)
+	assert.Equal(t, map[string]any{"temperature": 0.7, "topP": 0.9, "maxOutputTokens": 16000.0}, body["generationConfig"])
+	body = requestBody(geminiProvider{}, "gemini-2.0-flash", LLMConfig{})
+	assert.Equal(t, map[string]any{"temperature": 0.3, "topP": 0.95, "maxOutputTokens": 4000.0}, body["generationConfig"])
+
+	body = requestBody(ollamaProvider{}, "llama3", config)
+	assert.Equal(t, map[string]any{"temperature": 0.7, "top_p": 0.9, "num_predict": 16000.0}, body["options"])
+}
+
+func TestValidateGenerationFlags(t *testing.T) {
+	oldTemperature, oldTopP, oldMaxOutputTokens := temperature, topP, maxOutputTokens
+	t.Cleanup(func() { temperature, topP, maxOutputTokens = oldTemperature, oldTopP, oldMaxOutputTokens })
+
+	temperature, topP, maxOutputTokens = 1.5, 0, 8000
+	assert.NoError(t, validateGenerationFlags(ProviderOpenAI))
+	assert.EqualError(t, validateGenerationFlags(ProviderAnthropic), "invalid --temperature 1.5: anthropic accepts 0 to 1")
+
+	temperature, topP = 0.3, 1.2
+	assert.EqualError(t, validateGenerationFlags(ProviderGemini), "invalid --top-p 1.2: expected 0 to 1")
+
+	topP, maxOutputTokens = 0.9, 0
+	assert.EqualError(t, validateGenerationFlags(ProviderOllama), "invalid --max-output-tokens 0: expected a positive number")
+}
+
+func TestLLMDebugDump(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error": {"code": 503, "message": "The model is overloaded", "status": "UNAVAILABLE"}}`))
+			return
+		}
+		_, _ = w.Write([]byte("<html>Bad gateway</html>"))
+	}))
+	defer server.Close()
+	useProviderURL(t, ProviderGemini, server.URL)
+
+	dir := filepath.Join(t.TempDir(), "llm-debug")
+	var out bytes.Buffer
+	config := LLMConfig{Provider: ProviderGemini, Model: "gemini-2.0-flash", APIKey: "AIza-secret", DebugDir: dir, Writer: &out}
+	err := analyzeWithLLM([]LogEntry{{Level: "error", Message: "Failed to ping DB"}}, config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error parsing response")
+	assert.NotContains(t, err.Error(), "--llm-debug")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Regexp(t, `/\d{8}-\d{6}-gemini-request\.json$`, files[0])
+	assert.Contains(t, out.String(), "Wrote "+files[0]+"\n")
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "AIza-secret")
+	var request llmDebugRequest
+	require.NoError(t, json.Unmarshal(data, &request))
+	assert.Equal(t, "POST", request.Method)
+	assert.Equal(t, server.URL+"/v1beta/models/gemini-2.0-flash:generateContent", request.URL)
+	assert.Equal(t, "REDACTED", request.Headers["X-Goog-Api-Key"])
+	assert.Contains(t, string(request.Body), "Failed to ping DB")
+
+	assert.True(t, strings.HasSuffix(files[1], "-gemini-response-1.json"))
+	data, err = os.ReadFile(files[1])
+	require.NoError(t, err)
+	assert.Equal(t, `{"error": {"code": 503, "message": "The model is overloaded", "status": "UNAVAILABLE"}}`, string(data))
+	assert.True(t, strings.HasSuffix(files[2], "-gemini-response-2.json"))
+	data, err = os.ReadFile(files[2])
+	require.NoError(t, err)
+	assert.Equal(t, "<html>Bad gateway</html>", string(data))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Degradation of the notification latency: windows of notifications sent within
+// latencyWindow whose median latency is latencyDegradationFactor times that of the platform,
+// and at least latencyDegradationFloor so that fast deliveries getting a bit slower don't count
+const (
+	latencyWindow            = 10 * time.Minute
+	latencyWindowMinSamples  = 5
+	latencyDegradationFactor = 3.0
+	latencyDegradationFloor  = time.Second
+)
+
+// latencyBucketBounds are the upper bounds of the buckets of the latency histograms, the
+// last bucket holding the latencies above the last bound
+var latencyBucketBounds = []time.Duration{
+	100 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second,
+	5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// NotificationLatency is the distribution of the time between a notification being sent to
+// the push proxy and the device acknowledging it, per device platform
+type NotificationLatency struct {
+	Platforms []PlatformLatency    // Most notifications first
+	Degraded  []LatencyDegradation // Periods of slow deliveries, in time order
+}
+
+// PlatformLatency is the latency distribution of the notifications of a device platform
+type PlatformLatency struct {
+	Platform  string // iOS, Android, another platform or "unknown"
+	Count     int    // Notifications both sent and received
+	Median    time.Duration
+	P95       time.Duration
+	Max       time.Duration
+	Histogram []int // Notifications per bucket of latencyBucketBounds, plus one above them
+}
+
+// LatencyDegradation is a period when the notifications of a platform took much longer to
+// be delivered than usual
+type LatencyDegradation struct {
+	Platform string
+	Start    time.Time // Sending of the first slow notification
+	End      time.Time // Sending of the last slow notification
+	Count    int       // Notifications sent in the period
+	Median   time.Duration
+}
+
+// notificationDelivery is a notification both sent and received
+type notificationDelivery struct {
+	sent    time.Time
+	latency time.Duration
+}
+
+// analyzeNotificationLatency pairs the sent and received entries of notification logs by
+// ack ID, and computes the latency distribution and periods of degradation per platform
+func analyzeNotificationLatency(logs []LogEntry) NotificationLatency {
+	type pending struct {
+		sent     time.Time
+		received time.Time
+		platform string
+	}
+	notifications := make(map[string]*pending)
+	for _, log := range logs {
+		if log.LogSource != "notifications" || log.AckID == "" {
+			continue
+		}
+		stage := pushFunnelStatuses[strings.ToLower(log.Status)]
+		if stage != pushSent && stage != pushReceived {
+			continue
+		}
+		n, ok := notifications[log.AckID]
+		if !ok {
+			n = &pending{}
+			notifications[log.AckID] = n
+		}
+		if n.platform == "" {
+			n.platform = pushPlatform(log)
+		}
+		// The first sending and reception of a notification count, not the retries
+		if stage == pushSent && (n.sent.IsZero() || log.Timestamp.Before(n.sent)) {
+			n.sent = log.Timestamp
+		}
+		if stage == pushReceived && (n.received.IsZero() || log.Timestamp.Before(n.received)) {
+			n.received = log.Timestamp
+		}
+	}
+
+	byPlatform := make(map[string][]notificationDelivery)
+	for _, n := range notifications {
+		if n.sent.IsZero() || n.received.IsZero() || n.received.Before(n.sent) {
+			continue
+		}
+		platform := n.platform
+		if platform == "" {
+			platform = "unknown"
+		}
+		byPlatform[platform] = append(byPlatform[platform], notificationDelivery{sent: n.sent, latency: n.received.Sub(n.sent)})
+	}
+
+	var result NotificationLatency
+	for platform, deliveries := range byPlatform {
+		sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].sent.Before(deliveries[j].sent) })
+		latencies := make([]time.Duration, len(deliveries))
+		for i, d := range deliveries {
+			latencies[i] = d.latency
+		}
+		stats := PlatformLatency{Platform: platform, Count: len(latencies), Histogram: latencyHistogram(latencies)}
+		sortDurations(latencies)
+		stats.Median = durationPercentile(latencies, 0.5)
+		stats.P95 = durationPercentile(latencies, 0.95)
+		stats.Max = latencies[len(latencies)-1]
+		result.Platforms = append(result.Platforms, stats)
+		result.Degraded = append(result.Degraded, detectLatencyDegradation(platform, deliveries, stats.Median)...)
+	}
+	sort.Slice(result.Platforms, func(i, j int) bool {
+		if result.Platforms[i].Count != result.Platforms[j].Count {
+			return result.Platforms[i].Count > result.Platforms[j].Count
+		}
+		return result.Platforms[i].Platform < result.Platforms[j].Platform
+	})
+	sort.Slice(result.Degraded, func(i, j int) bool {
+		if !result.Degraded[i].Start.Equal(result.Degraded[j].Start) {
+			return result.Degraded[i].Start.Before(result.Degraded[j].Start)
+		}
+		return result.Degraded[i].Platform < result.Degraded[j].Platform
+	})
+	return result
+}
+
+// detectLatencyDegradation finds the windows of deliveries, sorted by sending time, whose
+// median latency is much higher than the median of the platform, merging adjacent windows
+func detectLatencyDegradation(platform string, deliveries []notificationDelivery, median time.Duration) []LatencyDegradation {
+	threshold := time.Duration(math.Max(float64(median)*latencyDegradationFactor, float64(latencyDegradationFloor)))
+
+	var periods []LatencyDegradation
+	var current []notificationDelivery // Deliveries of the period being merged
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		latencies := make([]time.Duration, len(current))
+		for i, d := range current {
+			latencies[i] = d.latency
+		}
+		sortDurations(latencies)
+		periods = append(periods, LatencyDegradation{Platform: platform, Start: current[0].sent,
+			End: current[len(current)-1].sent, Count: len(current), Median: durationPercentile(latencies, 0.5)})
+		current = nil
+	}
+
+	lastWindow := time.Time{}
+	for start := 0; start < len(deliveries); {
+		window := deliveries[start].sent.Truncate(latencyWindow)
+		end := start
+		for end < len(deliveries) && deliveries[end].sent.Truncate(latencyWindow).Equal(window) {
+			end++
+		}
+		samples := deliveries[start:end]
+		latencies := make([]time.Duration, len(samples))
+		for i, d := range samples {
+			latencies[i] = d.latency
+		}
+		sortDurations(latencies)
+
+		degraded := len(samples) >= latencyWindowMinSamples && durationPercentile(latencies, 0.5) >= threshold
+		if !degraded || !window.Equal(lastWindow.Add(latencyWindow)) {
+			flush()
+		}
+		if degraded {
+			current = append(current, samples...)
+			lastWindow = window
+		}
+		start = end
+	}
+	flush()
+	return periods
+}
+
+// latencyHistogram counts the latencies per bucket of latencyBucketBounds
+func latencyHistogram(latencies []time.Duration) []int {
+	histogram := make([]int, len(latencyBucketBounds)+1)
+	for _, latency := range latencies {
+		bucket := sort.Search(len(latencyBucketBounds), func(i int) bool { return latency <= latencyBucketBounds[i] })
+		histogram[bucket]++
+	}
+	return histogram
+}
+
+// latencyBucketLabel names a bucket of latencyBucketBounds, e.g. "≤500ms" or ">30s"
+func latencyBucketLabel(bucket int) string {
+	if bucket == len(latencyBucketBounds) {
+		return ">" + latencyBucketBounds[bucket-1].String()
+	}
+	return "≤" + latencyBucketBounds[bucket].String()
+}
+
+// sortDurations sorts durations in increasing order
+func sortDurations(durations []time.Duration) {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+}
+
+// durationPercentile returns the nearest-rank percentile p, from 0 to 1, of sorted durations
+func durationPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// formatLatency rounds a latency for display, to the millisecond below a second
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// displayNotificationLatency writes the latency of notifications per platform and the
+// periods of degradation, with a histogram per platform in verbose mode
+func displayNotificationLatency(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	latency := analysis.NotificationLatency
+	if len(latency.Platforms) == 0 {
+		return
+	}
+
+	parts := make([]string, 0, len(latency.Platforms))
+	for _, p := range latency.Platforms {
+		parts = append(parts, fmt.Sprintf("%s median %s, p95 %s (%d)", p.Platform, formatLatency(p.Median), formatLatency(p.P95), p.Count))
+	}
+	_, _ = fmt.Fprintf(writer, "%sNotification Latency:%s %s\n", colorSubHeader, colorReset, strings.Join(parts, " • "))
+	for _, d := range latency.Degraded {
+		_, _ = fmt.Fprintf(writer, "  %sSlow deliveries (%s): %s → %s, median %s over %d notification(s)%s\n",
+			colorRed, d.Platform, d.Start.Format("2006-01-02 15:04:05"), d.End.Format("2006-01-02 15:04:05"),
+			formatLatency(d.Median), d.Count, colorReset)
+	}
+	if !verboseAnalysis {
+		return
+	}
+
+	for _, p := range latency.Platforms {
+		_, _ = fmt.Fprintf(writer, "  %s (max %s):\n", p.Platform, formatLatency(p.Max))
+		maxCount := 0
+		for _, count := range p.Histogram {
+			maxCount = max(maxCount, count)
+		}
+		for bucket, count := range p.Histogram {
+			if count == 0 {
+				continue
+			}
+			bar := strings.Repeat("█", max(1, count*30/maxCount))
+			_, _ = fmt.Fprintf(writer, "    %7s %s %d\n", latencyBucketLabel(bucket), bar, count)
+		}
+	}
+	_, _ = fmt.Fprintln(writer)
+}
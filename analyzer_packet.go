@@ -0,0 +1,322 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goroutineTopStacks is the number of most common goroutine stacks reported per dump
+const goroutineTopStacks = 5
+
+// goroutineStackFrames is the number of frames shown of each reported stack
+const goroutineStackFrames = 3
+
+// PacketDiagnostics describes the files of a support packet other than logs and config:
+// metadata, diagnostics and profiles, with the goroutine dumps summarized
+type PacketDiagnostics struct {
+	Files      []PacketDiagnosticFile
+	Goroutines []GoroutineDump
+}
+
+// PacketDiagnosticFile is a metadata, diagnostics or profile file of a support packet
+type PacketDiagnosticFile struct {
+	Name string // Path in the zip file
+	Kind string // metadata, diagnostics, goroutines, heap profile, cpu profile or profile
+	Node string // Node of the file in packets of a cluster
+	Size uint64
+}
+
+// GoroutineDump summarizes a goroutine dump of a support packet
+type GoroutineDump struct {
+	File      string
+	Node      string
+	Total     int
+	States    []CountedItem    // Goroutines by state, most common first; empty for dumps without states
+	TopStacks []GoroutineStack // Most common stacks
+}
+
+// GoroutineStack is a stack shared by goroutines of a dump
+type GoroutineStack struct {
+	Count  int
+	State  string   // State of the goroutines, empty if the dump has no states
+	Frames []string // Functions of the stack, innermost first
+}
+
+// goroutineHeaderPattern matches the header of a goroutine of a full dump (debug=2), e.g.
+// "goroutine 42 [chan receive, 12 minutes]:"
+var goroutineHeaderPattern = regexp.MustCompile(`^goroutine \d+ \[([^\],]+)`)
+
+// goroutineProfilePattern matches the header of a stack of a goroutine profile (debug=1),
+// e.g. "12 @ 0x43a0c5 0x40b2bc"
+var goroutineProfilePattern = regexp.MustCompile(`^(\d+) @ `)
+
+// packetDiagnosticKind returns the kind of a metadata, diagnostics or profile file of a
+// support packet, or false for other files
+func packetDiagnosticKind(name string) (string, bool) {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	switch {
+	case contains(supportPacketMetadataFiles, base):
+		return "metadata", true
+	case strings.Contains(base, "diagnostic"):
+		return "diagnostics", true
+	case strings.Contains(base, "goroutine"):
+		return "goroutines", true
+	case strings.HasSuffix(base, ".prof") || strings.HasSuffix(base, ".pprof") || strings.Contains(base, "profile"):
+		switch {
+		case strings.Contains(base, "heap"):
+			return "heap profile", true
+		case strings.Contains(base, "cpu"):
+			return "cpu profile", true
+		}
+		return "profile", true
+	}
+	return "", false
+}
+
+// readPacketDiagnostics lists the metadata, diagnostics and profile files of a support packet
+// and summarizes its goroutine dumps
+func readPacketDiagnostics(zipFilePath string) (PacketDiagnostics, error) {
+	var diagnostics PacketDiagnostics
+	reader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return diagnostics, fmt.Errorf("failed to open support packet: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || isSupportPacketLogFile(file.Name) {
+			continue
+		}
+		kind, ok := packetDiagnosticKind(file.Name)
+		if !ok {
+			continue
+		}
+		node := newPacketLogFile(file.Name, file.UncompressedSize64).Node
+		diagnostics.Files = append(diagnostics.Files, PacketDiagnosticFile{
+			Name: file.Name, Kind: kind, Node: node, Size: file.UncompressedSize64,
+		})
+		if kind != "goroutines" {
+			continue
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			logger.Warn("Failed to read goroutine dump from support packet", "file", file.Name, "error", err)
+			continue
+		}
+		dump, err := parseGoroutineDump(src)
+		_ = src.Close()
+		if err != nil {
+			// Binary (protobuf) profiles are only listed
+			logger.Debug("skipping goroutine dump", "file", file.Name, "error", err)
+			continue
+		}
+		dump.File, dump.Node = file.Name, node
+		diagnostics.Goroutines = append(diagnostics.Goroutines, dump)
+	}
+	return diagnostics, nil
+}
+
+// parseGoroutineDump summarizes a text goroutine dump, as written by the goroutine profile
+// with debug=2 (one stack per goroutine, with its state) or debug=1 (counted stacks)
+func parseGoroutineDump(r io.Reader) (GoroutineDump, error) {
+	var dump GoroutineDump
+	stateCounts := make(map[string]int)
+	stackCounts := make(map[string]*GoroutineStack)
+
+	var current *GoroutineStack
+	flush := func() {
+		if current == nil {
+			return
+		}
+		key := current.State + "\n" + strings.Join(current.Frames, "\n")
+		if stack, ok := stackCounts[key]; ok {
+			stack.Count += current.Count
+		} else {
+			stackCounts[key] = current
+		}
+		dump.Total += current.Count
+		if current.State != "" {
+			stateCounts[current.State] += current.Count
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	const maxCapacity = 512 * 1024 // 512KB, as for log files
+	scanner.Buffer(make([]byte, maxCapacity), maxCapacity)
+	recognized := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := goroutineHeaderPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			current = &GoroutineStack{Count: 1, State: match[1]}
+			recognized = true
+			continue
+		}
+		if match := goroutineProfilePattern.FindStringSubmatch(line); match != nil {
+			flush()
+			count, _ := strconv.Atoi(match[1])
+			current = &GoroutineStack{Count: count}
+			recognized = true
+			continue
+		}
+		if current == nil || strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if frame := goroutineFrame(line); frame != "" {
+			current.Frames = append(current.Frames, frame)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return dump, err
+	}
+	if !recognized {
+		return dump, fmt.Errorf("not a text goroutine dump")
+	}
+
+	dump.States = mapToSortedSlice(stateCounts, 0)
+	sort.Slice(dump.States, func(i, j int) bool {
+		if dump.States[i].Count != dump.States[j].Count {
+			return dump.States[i].Count > dump.States[j].Count
+		}
+		return dump.States[i].Item < dump.States[j].Item
+	})
+	stacks := make([]GoroutineStack, 0, len(stackCounts))
+	for _, stack := range stackCounts {
+		stacks = append(stacks, *stack)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		if stacks[i].Count != stacks[j].Count {
+			return stacks[i].Count > stacks[j].Count
+		}
+		return strings.Join(stacks[i].Frames, "\n") < strings.Join(stacks[j].Frames, "\n")
+	})
+	if len(stacks) > goroutineTopStacks {
+		stacks = stacks[:goroutineTopStacks]
+	}
+	dump.TopStacks = stacks
+	return dump, nil
+}
+
+// goroutineFrame returns the function of a line of a goroutine stack, without its package
+// path and arguments, or an empty string for the file and line of the frames of full dumps.
+// Frames of full dumps are "pkg.Func(0x1, 0x2)", those of profiles are
+// "#	0x4606b0	pkg.Func+0xb0	/path/file.go:123".
+func goroutineFrame(line string) string {
+	if strings.HasPrefix(line, "#") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return ""
+		}
+		function, _, _ := strings.Cut(fields[2], "+0x")
+		return path.Base(function)
+	}
+	if strings.HasPrefix(line, "\t") {
+		return ""
+	}
+	if created, ok := strings.CutPrefix(line, "created by "); ok {
+		function, _, _ := strings.Cut(created, " in goroutine ")
+		return "created by " + path.Base(function)
+	}
+	if i := strings.LastIndex(line, "("); i > 0 {
+		line = line[:i]
+	}
+	return path.Base(line)
+}
+
+// displayPacketDiagnostics writes the metadata, diagnostics and profile files of the support
+// packet, and the goroutine states and most common stacks of its goroutine dumps
+func displayPacketDiagnostics(diagnostics PacketDiagnostics, writer io.Writer, verboseAnalysis bool) {
+	if len(diagnostics.Files) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sSUPPORT PACKET FILES%s\n", colorHeaderBold, colorReset)
+	for _, file := range diagnostics.Files {
+		line := fmt.Sprintf("%s%s:%s %s (%.1f KB)", colorSubHeader, file.Kind, colorReset, file.Name, float64(file.Size)/1024)
+		if file.Node != "" {
+			line += ", node " + file.Node
+		}
+		_, _ = fmt.Fprintln(writer, line)
+	}
+
+	for _, dump := range diagnostics.Goroutines {
+		header := "Goroutines"
+		if dump.Node != "" {
+			header += " (" + dump.Node + ")"
+		}
+		line := fmt.Sprintf("%s%s:%s %d", colorSubHeader, header, colorReset, dump.Total)
+		if len(dump.States) > 0 {
+			line += " • " + formatTopItemsLine(dump.States, 0, 0)
+		}
+		_, _ = fmt.Fprintln(writer, line)
+
+		stacks := dump.TopStacks
+		if !verboseAnalysis && len(stacks) > 3 {
+			stacks = stacks[:3]
+		}
+		for _, stack := range stacks {
+			frames := stack.Frames
+			if len(frames) > goroutineStackFrames {
+				frames = frames[:goroutineStackFrames]
+			}
+			line := fmt.Sprintf("  %d×", stack.Count)
+			if stack.State != "" {
+				line += " [" + stack.State + "]"
+			}
+			_, _ = fmt.Fprintln(writer, line+" "+strings.Join(frames, " ← "))
+		}
+	}
+}
+
+// displayPacketDiagnosticsPorcelain writes the files and goroutine dumps of the support packet
+// as porcelain records
+func displayPacketDiagnosticsPorcelain(diagnostics PacketDiagnostics, w io.Writer) {
+	for _, file := range diagnostics.Files {
+		writePorcelainRecord(w, "packet_file", file.Kind, file.Name, file.Node, strconv.FormatUint(file.Size, 10))
+	}
+	for _, dump := range diagnostics.Goroutines {
+		writePorcelainRecord(w, "goroutines", dump.File, strconv.Itoa(dump.Total))
+		for _, state := range dump.States {
+			writePorcelainRecord(w, "goroutine_state", dump.File, state.Item, strconv.Itoa(state.Count))
+		}
+		for _, stack := range dump.TopStacks {
+			writePorcelainRecord(w, "goroutine_stack", dump.File, strconv.Itoa(stack.Count), stack.State, strings.Join(stack.Frames, " < "))
+		}
+	}
+}
+
+// summarizePacketDiagnostics names the kinds of diagnostic files of the support packet, and
+// describes its goroutine dumps in a sentence each
+func summarizePacketDiagnostics(diagnostics PacketDiagnostics) []string {
+	if len(diagnostics.Files) == 0 {
+		return nil
+	}
+	var kinds []string
+	for _, file := range diagnostics.Files {
+		if !contains(kinds, file.Kind) {
+			kinds = append(kinds, file.Kind)
+		}
+	}
+	sentences := []string{fmt.Sprintf("The support packet has %s: %s.",
+		countNoun(len(diagnostics.Files), "diagnostic file", "diagnostic files"), joinAnd(kinds))}
+	for _, dump := range diagnostics.Goroutines {
+		sentence := fmt.Sprintf("The goroutine dump %s has %s", dump.File, countNoun(dump.Total, "goroutine", "goroutines"))
+		if len(dump.States) > 0 {
+			top := dump.States[0]
+			sentence += fmt.Sprintf(", %d of them in %s", top.Count, top.Item)
+		}
+		sentences = append(sentences, sentence+".")
+	}
+	return sentences
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pushCorrelationWindow is how close to a push proxy failure an undelivered notification
+// must be to be attributed to it
+const pushCorrelationWindow = 5 * time.Minute
+
+// pushProxyPhrases identify push proxy failures by their message, error or caller
+var pushProxyPhrases = []string{"push proxy", "pushproxy", "push notification", "push.mattermost.com",
+	"hpns", "send_push", "push server"}
+
+// pushStatusCodePattern extracts the HTTP status code returned by the push proxy
+var pushStatusCodePattern = regexp.MustCompile(`(?i)status(?:[ _]?code)?[=: ]+(\d{3})`)
+
+// pushPlatformKeys are the extras naming the device platform of a notification
+var pushPlatformKeys = []string{"platform", "device_platform", "platform_type"}
+
+// pushDeviceKeys are the extras holding a device ID, prefixed with its platform
+var pushDeviceKeys = []string{"device_id", "deviceId", "deviceid"}
+
+// Stages of the notification delivery funnel
+const (
+	pushSent     = "sent"
+	pushReceived = "received"
+	pushFailed   = "failed"
+)
+
+// pushFunnelStatuses maps notification log statuses to funnel stages
+var pushFunnelStatuses = map[string]string{
+	"sent":        pushSent,
+	"received":    pushReceived,
+	"delivered":   pushReceived,
+	"not sent":    pushFailed,
+	"error":       pushFailed,
+	"failed":      pushFailed,
+	"unsupported": pushFailed,
+}
+
+// PushProxyAnalysis summarizes failures contacting the push proxy and the delivery of
+// notifications found in notification logs
+type PushProxyAnalysis struct {
+	Failures            int           // Server entries reporting push proxy failures
+	FirstFailure        time.Time     // First push proxy failure
+	LastFailure         time.Time     // Last push proxy failure
+	Hosts               []CountedItem // Push proxies that failed, e.g. push.mattermost.com
+	ErrorCodes          []CountedItem // HTTP status codes or network errors
+	Platforms           []CountedItem // Device platforms of the failures and undelivered notifications
+	Sent                int           // Notifications sent to the push proxy
+	Received            int           // Notifications acknowledged by devices
+	Undelivered         int           // Notifications not sent or failed
+	UndeliveredNearFail int           // Undelivered notifications within pushCorrelationWindow of a push proxy failure
+}
+
+// isPushProxyFailure reports whether a server entry reports a failure contacting the push proxy
+func isPushProxyFailure(log LogEntry) bool {
+	level := strings.ToLower(log.Level)
+	if log.LogSource == "notifications" || (level != "warn" && level != "warning" && !isErrorLevel(level)) {
+		return false
+	}
+	return containsAny(strings.ToLower(log.Message+" "+log.Source+" "+log.Extras["error"]+" "+log.Extras["err"]), pushProxyPhrases)
+}
+
+// pushErrorCode returns the HTTP status code of a push proxy failure, or else its
+// classified network error
+func pushErrorCode(log LogEntry) string {
+	if match := pushStatusCodePattern.FindStringSubmatch(log.Message + " " + log.Extras["error"] + " " + log.Extras["err"]); match != nil {
+		return "HTTP " + match[1]
+	}
+	for _, key := range []string{"status_code", "statusCode"} {
+		if code := log.Extras[key]; code != "" {
+			return "HTTP " + code
+		}
+	}
+	return fetchReason(log)
+}
+
+// pushPlatform returns the device platform of an entry, from its platform extras or the
+// prefix of its device ID (apple_rn-v2:..., android:...)
+func pushPlatform(log LogEntry) string {
+	platform := ""
+	for _, key := range pushPlatformKeys {
+		if platform = log.Extras[key]; platform != "" {
+			break
+		}
+	}
+	if platform == "" {
+		for _, key := range pushDeviceKeys {
+			if prefix, _, found := strings.Cut(log.Extras[key], ":"); found {
+				platform = prefix
+				break
+			}
+		}
+	}
+	switch platform = strings.ToLower(platform); {
+	case platform == "":
+		return ""
+	case strings.HasPrefix(platform, "apple") || platform == "ios":
+		return "iOS"
+	case strings.HasPrefix(platform, "android"):
+		return "Android"
+	}
+	return platform
+}
+
+// analyzePushProxy finds failures contacting the push proxy with their error codes and
+// platforms, and the delivery funnel of notification logs, counting the undelivered
+// notifications that happened close to a push proxy failure
+func analyzePushProxy(logs []LogEntry) PushProxyAnalysis {
+	var analysis PushProxyAnalysis
+	hosts := make(map[string]int)
+	codes := make(map[string]int)
+	platforms := make(map[string]int)
+	var failures []time.Time
+	var undelivered []time.Time
+
+	for _, log := range logs {
+		if stage, ok := pushFunnelStatuses[strings.ToLower(log.Status)]; ok && log.LogSource == "notifications" {
+			switch stage {
+			case pushSent:
+				analysis.Sent++
+			case pushReceived:
+				analysis.Received++
+			case pushFailed:
+				analysis.Undelivered++
+				undelivered = append(undelivered, log.Timestamp)
+				if platform := pushPlatform(log); platform != "" {
+					platforms[platform]++
+				}
+			}
+			continue
+		}
+		if !isPushProxyFailure(log) {
+			continue
+		}
+
+		analysis.Failures++
+		failures = append(failures, log.Timestamp)
+		if analysis.FirstFailure.IsZero() || log.Timestamp.Before(analysis.FirstFailure) {
+			analysis.FirstFailure = log.Timestamp
+		}
+		if log.Timestamp.After(analysis.LastFailure) {
+			analysis.LastFailure = log.Timestamp
+		}
+		if host := fetchHost(log); host != "" {
+			hosts[host]++
+		}
+		codes[pushErrorCode(log)]++
+		if platform := pushPlatform(log); platform != "" {
+			platforms[platform]++
+		}
+	}
+
+	for _, at := range undelivered {
+		for _, failure := range failures {
+			if diff := at.Sub(failure); diff <= pushCorrelationWindow && diff >= -pushCorrelationWindow {
+				analysis.UndeliveredNearFail++
+				break
+			}
+		}
+	}
+	analysis.Hosts = mapToSortedSlice(hosts, 0)
+	analysis.ErrorCodes = mapToSortedSlice(codes, 0)
+	analysis.Platforms = mapToSortedSlice(platforms, 0)
+	return analysis
+}
+
+// formatCountedItems formats counted items as "a 3, b 1"
+func formatCountedItems(items []CountedItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, fmt.Sprintf("%s %d", item.Item, item.Count))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// displayPushProxy writes the push proxy failures and the notification delivery funnel
+func displayPushProxy(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	push := analysis.PushProxy
+	if push.Failures == 0 && push.Sent+push.Received+push.Undelivered == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sPush Proxy:%s\n", colorSubHeader, colorReset)
+	if push.Failures > 0 {
+		_, _ = fmt.Fprintf(writer, "  Failures: %d", push.Failures)
+		if len(push.Hosts) > 0 {
+			_, _ = fmt.Fprintf(writer, " contacting %s", formatCountedItems(push.Hosts))
+		}
+		if len(push.ErrorCodes) > 0 {
+			_, _ = fmt.Fprintf(writer, " • %s", formatCountedItems(push.ErrorCodes))
+		}
+		_, _ = fmt.Fprintln(writer)
+	}
+	if len(push.Platforms) > 0 {
+		_, _ = fmt.Fprintf(writer, "  Platforms: %s\n", formatCountedItems(push.Platforms))
+	}
+
+	if push.Sent+push.Received+push.Undelivered > 0 {
+		line := fmt.Sprintf("  Delivery: %d sent → %d received", push.Sent, push.Received)
+		if push.Sent > 0 {
+			line += fmt.Sprintf(" (%.1f%%)", float64(push.Received)/float64(push.Sent)*100)
+		}
+		line += fmt.Sprintf(" • %d undelivered", push.Undelivered)
+		if push.UndeliveredNearFail > 0 {
+			line += fmt.Sprintf(", %s%d within %s of a push proxy failure%s",
+				colorRed, push.UndeliveredNearFail, pushCorrelationWindow, colorReset)
+		}
+		_, _ = fmt.Fprintln(writer, line)
+	}
+
+	if verboseAnalysis && push.Failures > 0 {
+		_, _ = fmt.Fprintf(writer, "  Failures from %s to %s\n",
+			push.FirstFailure.Format("2006-01-02 15:04:05"), push.LastFailure.Format("2006-01-02 15:04:05"))
+	}
+	if verboseAnalysis {
+		_, _ = fmt.Fprintln(writer)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// retentionDBWindow is how long before and after a run DB latency warnings are
+	// attributed to it
+	retentionDBWindow = 5 * time.Minute
+	// retentionMinTimeouts is the minimum number of timed out runs reported as consistent
+	retentionMinTimeouts = 2
+	// retentionTimeoutShare is the share of the runs that must time out to be consistent
+	retentionTimeoutShare = 0.5
+)
+
+// retentionWorkers identify data retention job entries by their worker or job type
+var retentionWorkers = []string{"dataretention"}
+
+// retentionMessagePhrases identify data retention job entries by their message
+var retentionMessagePhrases = []string{"data retention", "dataretention", "data_retention", "retention policy", "retention policies"}
+
+// retentionDeletedKeys are the extras holding the number of deleted rows
+var retentionDeletedKeys = []string{"rows_deleted", "deleted_rows", "num_deleted", "deleted_count", "deleted"}
+
+// retentionDeletedPattern extracts the number of deleted rows from a message
+var retentionDeletedPattern = regexp.MustCompile(`(?i)deleted (\d+)|(\d+) (?:rows|posts|records) deleted`)
+
+// timeoutPhrases identify entries reporting a timeout
+var timeoutPhrases = []string{"timeout", "timed out", "deadline exceeded", "canceling statement"}
+
+// dbLatencyPhrases identify warnings about a slow or unreachable database
+var dbLatencyPhrases = []string{"slow query", "query took", "long running query", "statement timeout",
+	"failed to ping db", "lock wait timeout", "database is slow", "db latency", "connection to the database"}
+
+// RetentionRun is a single run of the data retention job
+type RetentionRun struct {
+	JobRun
+	Policies    int  // Distinct policies applied
+	RowsDeleted int  // Rows deleted, summed over the entries of the run
+	TimedOut    bool // Whether the run reported a timeout
+	DBWarnings  int  // DB latency warnings logged around the run
+}
+
+// RetentionMonitor summarizes the data retention runs found in the logs
+type RetentionMonitor struct {
+	Runs               []RetentionRun // Runs in chronological order
+	Failures           int            // Failed runs
+	TimedOut           int            // Runs that reported a timeout
+	RowsDeleted        int            // Rows deleted by all runs
+	AverageDuration    time.Duration  // Mean duration of finished runs
+	MaxDuration        time.Duration  // Longest finished run
+	DBWarnings         int            // DB latency warnings logged around the timed out runs
+	ConsistentTimeouts bool           // Whether runs keep timing out
+}
+
+// isDBLatencyWarning reports whether an entry warns about a slow or unreachable database
+func isDBLatencyWarning(log LogEntry) bool {
+	level := strings.ToLower(log.Level)
+	if level != "warn" && level != "warning" && !isErrorLevel(level) {
+		return false
+	}
+	return containsAny(strings.ToLower(log.Message), dbLatencyPhrases)
+}
+
+// retentionDeleted returns the number of rows deleted logged by an entry
+func retentionDeleted(log LogEntry) (int, bool) {
+	for _, key := range retentionDeletedKeys {
+		if count, err := strconv.Atoi(log.Extras[key]); err == nil {
+			return count, true
+		}
+	}
+	if match := retentionDeletedPattern.FindStringSubmatch(log.Message); match != nil {
+		count, err := strconv.Atoi(match[1] + match[2])
+		return count, err == nil
+	}
+	return 0, false
+}
+
+// analyzeDataRetention groups data retention job entries into runs, counts the policies
+// applied and rows deleted, and flags runs that keep timing out along with the DB latency
+// warnings logged around them
+func analyzeDataRetention(logs []LogEntry) RetentionMonitor {
+	var entries, dbWarnings []LogEntry
+	for _, log := range logs {
+		if isJobEntry(log, retentionWorkers, retentionMessagePhrases) {
+			entries = append(entries, log)
+		} else if isDBLatencyWarning(log) {
+			dbWarnings = append(dbWarnings, log)
+		}
+	}
+	if len(entries) == 0 {
+		return RetentionMonitor{}
+	}
+
+	runs := groupJobRuns(entries)
+	var monitor RetentionMonitor
+	for _, run := range runs {
+		retentionRun := RetentionRun{JobRun: run}
+		policies := make(map[string]bool)
+		for _, log := range run.Entries {
+			if policy := log.Extras["policy_id"]; policy != "" {
+				policies[policy] = true
+			}
+			if count, ok := retentionDeleted(log); ok {
+				retentionRun.RowsDeleted += count
+			}
+			if containsAny(strings.ToLower(log.Message+" "+log.Extras["error"]), timeoutPhrases) {
+				retentionRun.TimedOut = true
+			}
+		}
+		retentionRun.Policies = len(policies)
+
+		from, to := run.Start.Add(-retentionDBWindow), run.End.Add(retentionDBWindow)
+		for _, warning := range dbWarnings {
+			if !warning.Timestamp.Before(from) && !warning.Timestamp.After(to) {
+				retentionRun.DBWarnings++
+			}
+		}
+
+		monitor.Runs = append(monitor.Runs, retentionRun)
+		monitor.RowsDeleted += retentionRun.RowsDeleted
+		if run.Status == jobFailed {
+			monitor.Failures++
+		}
+		if retentionRun.TimedOut {
+			monitor.TimedOut++
+			monitor.DBWarnings += retentionRun.DBWarnings
+		}
+	}
+	monitor.AverageDuration, monitor.MaxDuration = jobRunDurations(runs)
+	monitor.ConsistentTimeouts = monitor.TimedOut >= retentionMinTimeouts &&
+		float64(monitor.TimedOut) >= retentionTimeoutShare*float64(len(runs))
+	return monitor
+}
+
+// displayDataRetention writes the data retention runs, with a warning when runs keep
+// timing out
+func displayDataRetention(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	retention := analysis.DataRetention
+	if len(retention.Runs) == 0 {
+		return
+	}
+
+	header := "Data Retention:"
+	if verboseAnalysis {
+		header = "Data Retention Runs:"
+	}
+	_, _ = fmt.Fprintf(writer, "%s%s%s %d run(s) • %d failed • %d timed out • %d rows deleted • avg %s, max %s\n",
+		colorSubHeader, header, colorReset, len(retention.Runs), retention.Failures, retention.TimedOut,
+		retention.RowsDeleted, retention.AverageDuration.Round(time.Second), retention.MaxDuration.Round(time.Second))
+	if retention.ConsistentTimeouts {
+		line := fmt.Sprintf("  %sRuns keep timing out (%d of %d)", colorRed, retention.TimedOut, len(retention.Runs))
+		if retention.DBWarnings > 0 {
+			line += fmt.Sprintf(", with %d DB latency warning(s) around them", retention.DBWarnings)
+		}
+		_, _ = fmt.Fprintln(writer, line+colorReset)
+	}
+
+	if !verboseAnalysis {
+		return
+	}
+	for _, run := range retention.Runs {
+		line := formatJobRun(run.JobRun) + fmt.Sprintf(", %d polic(ies), %d rows deleted", run.Policies, run.RowsDeleted)
+		if run.TimedOut {
+			line += fmt.Sprintf(" %sTIMED OUT%s", colorRed, colorReset)
+		}
+		if run.DBWarnings > 0 {
+			line += fmt.Sprintf(" (%d DB latency warning(s))", run.DBWarnings)
+		}
+		if run.Error != "" {
+			line += fmt.Sprintf(": %s%s%s", colorRed, truncateString(run.Error, 100), colorReset)
+		}
+		_, _ = fmt.Fprintln(writer, line)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
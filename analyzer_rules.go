@@ -0,0 +1,628 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ruleSeverities are the severities of rule findings, most severe first
+var ruleSeverities = []string{"error", "warning", "info"}
+
+// ruleMaxEvidence is the maximum number of matching entries kept as evidence of a finding
+const ruleMaxEvidence = 10
+
+// ruleFields are the entry fields rule conditions can test, besides extras.<name>
+var ruleFields = []string{"level", "message", "source", "user", "raw", "type", "status"}
+
+// AnalysisRule is a user-defined rule reporting a finding when log entries match its
+// condition. Rule files hold one or more rules such as:
+//
+//	# Timeouts talking to the database
+//	when level == "error" and message contains "context deadline exceeded"
+//	then finding("Database timeouts", severity="error", summary="Check the database load", min_count=5)
+type AnalysisRule struct {
+	ID       string // Stable identifier of exported findings, defaults to the title in kebab case
+	Title    string
+	Severity string // error, warning or info
+	Summary  string
+	MinCount int    // Matching entries needed to report the finding
+	Origin   string // File and line of the rule, e.g. team.rules:3
+	cond     ruleExpr
+}
+
+// RuleFinding is a finding of an analysis rule with the entries it matched
+type RuleFinding struct {
+	ID        string
+	Title     string
+	Severity  string
+	Summary   string
+	Origin    string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Example   string     // Message of the first matching entry
+	Evidence  []LogEntry // First matching entries, at most ruleMaxEvidence
+}
+
+// analysisRules are the rules of the config file and --rules, loaded by commands reading logs
+var analysisRules []AnalysisRule
+
+// ruleExpr is a condition of an analysis rule
+type ruleExpr interface {
+	match(entry LogEntry) bool
+}
+
+type ruleAnd struct{ left, right ruleExpr }
+
+func (e ruleAnd) match(entry LogEntry) bool { return e.left.match(entry) && e.right.match(entry) }
+
+type ruleOr struct{ left, right ruleExpr }
+
+func (e ruleOr) match(entry LogEntry) bool { return e.left.match(entry) || e.right.match(entry) }
+
+type ruleNot struct{ expr ruleExpr }
+
+func (e ruleNot) match(entry LogEntry) bool { return !e.expr.match(entry) }
+
+// ruleComparison compares a field of an entry with a value, e.g. message contains "X"
+type ruleComparison struct {
+	field string
+	op    string // ==, !=, contains, startswith, endswith or matches
+	value string
+	regex *regexp.Regexp // Compiled value of matches
+}
+
+func (e ruleComparison) match(entry LogEntry) bool {
+	value := ruleFieldValue(entry, e.field)
+	switch e.op {
+	case "==":
+		return value == e.value
+	case "!=":
+		return value != e.value
+	case "contains":
+		return strings.Contains(value, e.value)
+	case "startswith":
+		return strings.HasPrefix(value, e.value)
+	case "endswith":
+		return strings.HasSuffix(value, e.value)
+	case "matches":
+		return e.regex.MatchString(value)
+	}
+	return false
+}
+
+// ruleFieldValue returns a field of an entry. Levels are lowercase so that rules do not
+// depend on how each log format writes them.
+func ruleFieldValue(entry LogEntry, field string) string {
+	switch field {
+	case "level":
+		return strings.ToLower(entry.Level)
+	case "message":
+		return entry.Message
+	case "source":
+		return entry.Source
+	case "user":
+		return entry.User
+	case "raw":
+		return entry.Raw
+	case "type":
+		return entry.Type
+	case "status":
+		return entry.Status
+	}
+	return entry.Extras[strings.TrimPrefix(field, "extras.")]
+}
+
+// ruleTokenKind is the kind of a token of a rule file
+type ruleTokenKind int
+
+const (
+	ruleWord   ruleTokenKind = iota // Keyword, field or argument name
+	ruleString                      // Double-quoted string, unquoted
+	ruleNumber
+	ruleSymbol // ==, !=, =, (, ) or ,
+)
+
+// ruleToken is a token of a rule file
+type ruleToken struct {
+	kind ruleTokenKind
+	text string
+	line int
+}
+
+// tokenizeRules splits a rule file into tokens, skipping whitespace and # comments
+func tokenizeRules(file, src string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) || src[end] != '"' {
+				return nil, fmt.Errorf("%s:%d: unterminated string", file, line)
+			}
+			text, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid string %s: %v", file, line, src[i:end+1], err)
+			}
+			tokens = append(tokens, ruleToken{ruleString, text, line})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, ruleToken{ruleNumber, src[i:end], line})
+			i = end
+		case isRuleWordByte(c) && (c < '0' || c > '9') && c != '.':
+			end := i
+			for end < len(src) && isRuleWordByte(src[end]) {
+				end++
+			}
+			tokens = append(tokens, ruleToken{ruleWord, src[i:end], line})
+			i = end
+		case strings.HasPrefix(src[i:], "==") || strings.HasPrefix(src[i:], "!="):
+			tokens = append(tokens, ruleToken{ruleSymbol, src[i : i+2], line})
+			i += 2
+		case strings.ContainsRune("=(),", rune(c)):
+			tokens = append(tokens, ruleToken{ruleSymbol, string(c), line})
+			i++
+		default:
+			return nil, fmt.Errorf("%s:%d: unexpected character %q", file, line, c)
+		}
+	}
+	return tokens, nil
+}
+
+// isRuleWordByte reports whether a byte can be part of a word: ASCII letters, digits, _ and
+// the dot of extras.<name>
+func isRuleWordByte(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// ruleParser parses the tokens of a rule file
+type ruleParser struct {
+	file   string
+	tokens []ruleToken
+	pos    int
+}
+
+// parseRules parses the rules of a rule file
+func parseRules(file, src string) ([]AnalysisRule, error) {
+	tokens, err := tokenizeRules(file, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{file: file, tokens: tokens}
+	var rules []AnalysisRule
+	for p.pos < len(p.tokens) {
+		rule, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// errorf returns an error at the line of the current token
+func (p *ruleParser) errorf(format string, args ...any) error {
+	line := 1
+	switch {
+	case p.pos < len(p.tokens):
+		line = p.tokens[p.pos].line
+	case len(p.tokens) > 0:
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("%s:%d: %s", p.file, line, fmt.Sprintf(format, args...))
+}
+
+// peek reports whether the current token has the given kind and text
+func (p *ruleParser) peek(kind ruleTokenKind, text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text
+}
+
+// next returns the current token and moves to the next one
+func (p *ruleParser) next() (ruleToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return ruleToken{}, false
+	}
+	p.pos++
+	return p.tokens[p.pos-1], true
+}
+
+// expect consumes the current token if it has the given kind and text
+func (p *ruleParser) expect(kind ruleTokenKind, text string) error {
+	if !p.peek(kind, text) {
+		return p.errorf("expected %q, found %s", text, p.describe())
+	}
+	p.pos++
+	return nil
+}
+
+// describe describes the current token for error messages
+func (p *ruleParser) describe() string {
+	if p.pos >= len(p.tokens) {
+		return "end of file"
+	}
+	token := p.tokens[p.pos]
+	if token.kind == ruleString {
+		return strconv.Quote(token.text)
+	}
+	return fmt.Sprintf("%q", token.text)
+}
+
+// parseRule parses 'when <condition> then finding(<arguments>)'
+func (p *ruleParser) parseRule() (AnalysisRule, error) {
+	rule := AnalysisRule{Severity: "warning", MinCount: 1}
+	if p.pos < len(p.tokens) {
+		rule.Origin = fmt.Sprintf("%s:%d", p.file, p.tokens[p.pos].line)
+	}
+	if err := p.expect(ruleWord, "when"); err != nil {
+		return rule, err
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return rule, err
+	}
+	rule.cond = cond
+	if err := p.expect(ruleWord, "then"); err != nil {
+		return rule, err
+	}
+	if err := p.expect(ruleWord, "finding"); err != nil {
+		return rule, err
+	}
+	if err := p.parseFindingArguments(&rule); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// parseOr parses conditions joined by or
+func (p *ruleParser) parseOr() (ruleExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(ruleWord, "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = ruleOr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses conditions joined by and, which binds tighter than or
+func (p *ruleParser) parseAnd() (ruleExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(ruleWord, "and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = ruleAnd{left, right}
+	}
+	return left, nil
+}
+
+// parseNot parses a negated, parenthesized or single comparison
+func (p *ruleParser) parseNot() (ruleExpr, error) {
+	if p.peek(ruleWord, "not") {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return ruleNot{expr}, nil
+	}
+	if p.peek(ruleSymbol, "(") {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(ruleSymbol, ")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses '<field> <operator> "<value>"'
+func (p *ruleParser) parseComparison() (ruleExpr, error) {
+	field, ok := p.next()
+	if !ok || field.kind != ruleWord {
+		if ok {
+			p.pos--
+		}
+		return nil, p.errorf("expected a field, found %s", p.describe())
+	}
+	if !contains(ruleFields, field.text) && (!strings.HasPrefix(field.text, "extras.") || field.text == "extras.") {
+		p.pos--
+		return nil, p.errorf("unknown field %q (expected %s or extras.<name>)", field.text, strings.Join(ruleFields, ", "))
+	}
+
+	op, ok := p.next()
+	validOp := ok && ((op.kind == ruleSymbol && (op.text == "==" || op.text == "!=")) ||
+		(op.kind == ruleWord && contains([]string{"contains", "startswith", "endswith", "matches"}, op.text)))
+	if !validOp {
+		if ok {
+			p.pos--
+		}
+		return nil, p.errorf("expected ==, !=, contains, startswith, endswith or matches, found %s", p.describe())
+	}
+
+	value, ok := p.next()
+	if !ok || (value.kind != ruleString && value.kind != ruleNumber) {
+		if ok {
+			p.pos--
+		}
+		return nil, p.errorf("expected a quoted value, found %s", p.describe())
+	}
+
+	comparison := ruleComparison{field: field.text, op: op.text, value: value.text}
+	if field.text == "level" && op.text != "matches" {
+		comparison.value = strings.ToLower(value.text)
+	}
+	if op.text == "matches" {
+		regex, err := regexp.Compile(value.text)
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("invalid regular expression: %v", err)
+		}
+		comparison.regex = regex
+	}
+	return comparison, nil
+}
+
+// parseFindingArguments parses '("<title>", id="...", severity="...", summary="...", min_count=N)'
+func (p *ruleParser) parseFindingArguments(rule *AnalysisRule) error {
+	if err := p.expect(ruleSymbol, "("); err != nil {
+		return err
+	}
+	for first := true; !p.peek(ruleSymbol, ")"); first = false {
+		if !first {
+			if err := p.expect(ruleSymbol, ","); err != nil {
+				return err
+			}
+		}
+		if first && p.pos < len(p.tokens) && p.tokens[p.pos].kind == ruleString {
+			rule.Title = p.tokens[p.pos].text
+			p.pos++
+			continue
+		}
+
+		name, ok := p.next()
+		if !ok || name.kind != ruleWord {
+			if ok {
+				p.pos--
+			}
+			return p.errorf("expected an argument, found %s", p.describe())
+		}
+		if err := p.expect(ruleSymbol, "="); err != nil {
+			return err
+		}
+		value, ok := p.next()
+		if !ok || (value.kind != ruleString && value.kind != ruleNumber) {
+			if ok {
+				p.pos--
+			}
+			return p.errorf("expected a value for %s, found %s", name.text, p.describe())
+		}
+
+		switch name.text {
+		case "title":
+			rule.Title = value.text
+		case "id":
+			rule.ID = value.text
+		case "summary":
+			rule.Summary = value.text
+		case "severity":
+			rule.Severity = strings.ToLower(value.text)
+			if !contains(ruleSeverities, rule.Severity) {
+				p.pos--
+				return p.errorf("invalid severity %q (expected %s)", value.text, strings.Join(ruleSeverities, ", "))
+			}
+		case "min_count":
+			count, err := strconv.Atoi(value.text)
+			if err != nil || count < 1 {
+				p.pos--
+				return p.errorf("min_count must be a positive number")
+			}
+			rule.MinCount = count
+		default:
+			p.pos -= 3
+			return p.errorf("unknown argument %q (expected title, id, severity, summary or min_count)", name.text)
+		}
+	}
+	if err := p.expect(ruleSymbol, ")"); err != nil {
+		return err
+	}
+	if rule.Title == "" {
+		return fmt.Errorf("%s: the finding has no title", rule.Origin)
+	}
+	if rule.ID == "" {
+		rule.ID = ruleID(rule.Title)
+	}
+	return nil
+}
+
+// ruleID returns the default identifier of a rule, its title in kebab case
+func ruleID(title string) string {
+	var id strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(title) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return id.String()
+}
+
+// loadRuleFile reads the rules of a rule file
+func loadRuleFile(path string) ([]AnalysisRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRules(path, string(data))
+}
+
+// ruleFilePaths returns the rule files of the config file and of --rules. Relative paths of
+// the config file are relative to the config directory, and its patterns may match no file.
+func ruleFilePaths(config Config, files []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range config.Rules {
+		if !filepath.IsAbs(pattern) {
+			dir, err := configDir()
+			if err != nil {
+				return nil, err
+			}
+			pattern = filepath.Join(dir, pattern)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule file pattern %q: %v", pattern, err)
+		}
+		paths = append(paths, matches...)
+	}
+	return append(paths, files...), nil
+}
+
+// loadAnalysisRules loads the rules of the config file and of the given rule files
+func loadAnalysisRules(files []string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	paths, err := ruleFilePaths(config, files)
+	if err != nil {
+		return err
+	}
+
+	var rules []AnalysisRule
+	for _, path := range paths {
+		loaded, err := loadRuleFile(path)
+		if err != nil {
+			return fmt.Errorf("error loading analysis rules: %v", err)
+		}
+		rules = append(rules, loaded...)
+	}
+	analysisRules = rules
+	logger.Debug("loaded analysis rules", "files", len(paths), "rules", len(rules))
+	return nil
+}
+
+// evaluateRules returns the findings of the rules matching at least MinCount entries, most
+// severe first and then by number of matching entries
+func evaluateRules(rules []AnalysisRule, logs []LogEntry, showDupes bool) []RuleFinding {
+	var findings []RuleFinding
+	for _, rule := range rules {
+		finding := RuleFinding{ID: rule.ID, Title: rule.Title, Severity: rule.Severity, Summary: rule.Summary, Origin: rule.Origin}
+		for _, log := range logs {
+			if !rule.cond.match(log) {
+				continue
+			}
+			count := 1
+			if showDupes && log.DuplicateCount > 1 {
+				count = log.DuplicateCount
+			}
+			if finding.Count == 0 {
+				finding.Example = log.Message
+				finding.FirstSeen, finding.LastSeen = log.Timestamp, log.Timestamp
+			}
+			finding.Count += count
+			if len(finding.Evidence) < ruleMaxEvidence {
+				finding.Evidence = append(finding.Evidence, log)
+			}
+			if log.Timestamp.Before(finding.FirstSeen) {
+				finding.FirstSeen = log.Timestamp
+			}
+			if log.Timestamp.After(finding.LastSeen) {
+				finding.LastSeen = log.Timestamp
+			}
+		}
+		if finding.Count > 0 && finding.Count >= rule.MinCount {
+			findings = append(findings, finding)
+		}
+	}
+
+	severityRank := func(severity string) int {
+		for i, s := range ruleSeverities {
+			if s == severity {
+				return i
+			}
+		}
+		return len(ruleSeverities)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if rankI, rankJ := severityRank(findings[i].Severity), severityRank(findings[j].Severity); rankI != rankJ {
+			return rankI < rankJ
+		}
+		return findings[i].Count > findings[j].Count
+	})
+	return findings
+}
+
+// displayRuleFindings prints the findings of the analysis rules
+func displayRuleFindings(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.RuleFindings) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sRule Findings:%s\n", colorSubHeader, colorReset)
+	for _, finding := range analysis.RuleFindings {
+		_, _ = fmt.Fprintf(writer, "  %s[%s]%s %s (%d) • first %s • last %s\n",
+			getLevelColor(finding.Severity), finding.Severity, colorReset, finding.Title, finding.Count,
+			finding.FirstSeen.Format("2006-01-02 15:04:05"),
+			finding.LastSeen.Format("2006-01-02 15:04:05"))
+		if finding.Summary != "" {
+			_, _ = fmt.Fprintf(writer, "      %s\n", finding.Summary)
+		}
+		if verboseAnalysis {
+			_, _ = fmt.Fprintf(writer, "      e.g. %s (rule at %s)\n", truncateString(finding.Example, 80), finding.Origin)
+		}
+	}
+	_, _ = fmt.Fprintln(writer)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// leakMinSamples is the minimum number of samples needed to judge growth
+	leakMinSamples = 5
+	// leakMinIncreaseRatio is the share of changing sample-to-sample steps that must increase
+	leakMinIncreaseRatio = 0.8
+	// leakMinGrowth is the minimum ratio between the last and first sample
+	leakMinGrowth = 1.2
+)
+
+// runtimeMetricKeys are the extras keys found in runtime/health log lines with the metric
+// they measure. When an entry has several keys of a metric, such as heap_alloc and rss, the
+// first one in this order counts.
+var runtimeMetricKeys = []struct {
+	key    string
+	metric string
+}{
+	{"goroutines", "goroutines"},
+	{"num_goroutines", "goroutines"},
+	{"goroutine_count", "goroutines"},
+	{"heap_alloc", "memory"},
+	{"alloc", "memory"},
+	{"mem_alloc", "memory"},
+	{"memory", "memory"},
+	{"rss", "memory"},
+	{"db_connections", "db_connections"},
+	{"open_connections", "db_connections"},
+	{"open_conns", "db_connections"},
+}
+
+// runtimeMetricValues returns the values of the runtime metric keys of an entry's extras by
+// lowercase key. Of keys differing only in case, the smallest counts, so that the result
+// doesn't depend on the order of the map.
+func runtimeMetricValues(extras map[string]string) map[string]string {
+	var values, keys map[string]string
+	for key, value := range extras {
+		lower := strings.ToLower(key)
+		if !slices.ContainsFunc(runtimeMetricKeys, func(k struct{ key, metric string }) bool { return k.key == lower }) {
+			continue
+		}
+		if values == nil {
+			values, keys = make(map[string]string), make(map[string]string)
+		}
+		if previous, ok := keys[lower]; !ok || key < previous {
+			values[lower], keys[lower] = value, key
+		}
+	}
+	return values
+}
+
+// runtimeMessagePatterns extract runtime metrics from well-known server messages
+var runtimeMessagePatterns = []struct {
+	regex  *regexp.Regexp
+	metric string
+}{
+	{regexp.MustCompile(`(?i)number of running goroutines \(?(\d+)\)?`), "goroutines"},
+	{regexp.MustCompile(`(?i)goroutines:\s*(\d+)`), "goroutines"},
+}
+
+// byteUnits maps memory size suffixes to their multiplier
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"gib", 1 << 30}, {"gb", 1 << 30}, {"g", 1 << 30},
+	{"mib", 1 << 20}, {"mb", 1 << 20}, {"m", 1 << 20},
+	{"kib", 1 << 10}, {"kb", 1 << 10}, {"k", 1 << 10},
+	{"b", 1},
+}
+
+// MetricSample is a single observation of a runtime metric
+type MetricSample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// RuntimeMetric is a time series of a runtime metric found in the logs
+type RuntimeMetric struct {
+	Name          string
+	Samples       []MetricSample // Chronological samples
+	Min           float64
+	Max           float64
+	LeakSuspected bool // Whether the metric grows monotonically
+}
+
+// analyzeRuntimeMetrics collects goroutine, memory and DB connection metrics logged by
+// periodic runtime/health entries and flags metrics that keep growing
+func analyzeRuntimeMetrics(logs []LogEntry) []RuntimeMetric {
+	series := make(map[string][]MetricSample)
+
+	for _, log := range logs {
+		found := make(map[string]bool)
+		values := runtimeMetricValues(log.Extras)
+		for _, k := range runtimeMetricKeys {
+			value, ok := values[k.key]
+			metric := k.metric
+			if !ok || found[metric] {
+				continue
+			}
+			var parsed float64
+			var err error
+			if metric == "memory" {
+				parsed, err = parseByteSize(value)
+			} else {
+				parsed, err = strconv.ParseFloat(strings.Trim(value, "\""), 64)
+			}
+			if err != nil {
+				continue
+			}
+			series[metric] = append(series[metric], MetricSample{Timestamp: log.Timestamp, Value: parsed})
+			found[metric] = true
+		}
+
+		for _, p := range runtimeMessagePatterns {
+			if found[p.metric] {
+				continue
+			}
+			if match := p.regex.FindStringSubmatch(log.Message); match != nil {
+				if parsed, err := strconv.ParseFloat(match[1], 64); err == nil {
+					series[p.metric] = append(series[p.metric], MetricSample{Timestamp: log.Timestamp, Value: parsed})
+					found[p.metric] = true
+				}
+			}
+		}
+	}
+
+	var metrics []RuntimeMetric
+	for name, samples := range series {
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].Timestamp.Before(samples[j].Timestamp)
+		})
+
+		metric := RuntimeMetric{Name: name, Samples: samples, Min: samples[0].Value, Max: samples[0].Value}
+		for _, sample := range samples {
+			if sample.Value < metric.Min {
+				metric.Min = sample.Value
+			}
+			if sample.Value > metric.Max {
+				metric.Max = sample.Value
+			}
+		}
+		metric.LeakSuspected = isMonotonicGrowth(samples)
+		metrics = append(metrics, metric)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+// isMonotonicGrowth reports whether a series grows (almost) monotonically by a meaningful
+// amount. Steps where the value does not change are ignored, since the same value is
+// often logged by several entries.
+func isMonotonicGrowth(samples []MetricSample) bool {
+	if len(samples) < leakMinSamples {
+		return false
+	}
+
+	increases, decreases := 0, 0
+	for i := 1; i < len(samples); i++ {
+		switch {
+		case samples[i].Value > samples[i-1].Value:
+			increases++
+		case samples[i].Value < samples[i-1].Value:
+			decreases++
+		}
+	}
+
+	first, last := samples[0].Value, samples[len(samples)-1].Value
+	if first <= 0 || increases+decreases < leakMinSamples-1 {
+		return false
+	}
+	return float64(increases)/float64(increases+decreases) >= leakMinIncreaseRatio && last/first >= leakMinGrowth
+}
+
+// parseByteSize parses a memory size such as "512MB", "1.5GiB" or a plain number of bytes
+func parseByteSize(value string) (float64, error) {
+	normalized := strings.ToLower(strings.TrimSpace(strings.Trim(value, "\"")))
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(normalized, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(normalized, unit.suffix)), 64)
+			if err != nil {
+				return 0, err
+			}
+			return number * unit.multiplier, nil
+		}
+	}
+	return strconv.ParseFloat(normalized, 64)
+}
+
+// formatMetricValue formats a metric value for display
+func formatMetricValue(name string, value float64) string {
+	if name == "memory" {
+		return fmt.Sprintf("%.1fMB", value/(1<<20))
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+// displayRuntimeMetrics prints the runtime metric time series summaries
+func displayRuntimeMetrics(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.RuntimeMetrics) == 0 {
+		return
+	}
+
+	if !verboseAnalysis {
+		var leaks []string
+		for _, metric := range analysis.RuntimeMetrics {
+			if metric.LeakSuspected {
+				leaks = append(leaks, metric.Name)
+			}
+		}
+		if len(leaks) > 0 {
+			_, _ = fmt.Fprintf(writer, "%sPossible Leaks:%s %s%s%s growing steadily\n",
+				colorSubHeader, colorReset, colorRed, strings.Join(leaks, ", "), colorReset)
+		}
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sRuntime Metrics:%s\n", colorSubHeader, colorReset)
+	for _, metric := range analysis.RuntimeMetrics {
+		first := metric.Samples[0].Value
+		last := metric.Samples[len(metric.Samples)-1].Value
+		line := fmt.Sprintf("  %s: %s → %s (min %s, max %s, %d samples)",
+			metric.Name,
+			formatMetricValue(metric.Name, first), formatMetricValue(metric.Name, last),
+			formatMetricValue(metric.Name, metric.Min), formatMetricValue(metric.Name, metric.Max),
+			len(metric.Samples))
+		if metric.LeakSuspected {
+			line += fmt.Sprintf(" %sLEAK SUSPECTED%s", colorRed, colorReset)
+		}
+		_, _ = fmt.Fprintln(writer, line)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
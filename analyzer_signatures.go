@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ongoingMinWindow is the shortest window at the end of the time range in which an
+// error must have occurred to be considered ongoing
+const ongoingMinWindow = 5 * time.Minute
+
+// ErrorSignature represents a cluster of error messages that share the same normalized form
+type ErrorSignature struct {
+	Signature string    // Normalized message
+	Example   string    // First raw message seen for this signature
+	Count     int       // Number of occurrences
+	FirstSeen time.Time // Timestamp of the first occurrence
+	LastSeen  time.Time // Timestamp of the last occurrence
+	Ongoing   bool      // Whether the error still occurs at the end of the time range
+}
+
+// analyzeErrorSignatures clusters error entries by normalized message and tracks when each
+// cluster was first and last seen. An error is ongoing when its last occurrence falls in
+// the final tenth of the time range (or the final ongoingMinWindow, whichever is longer).
+// Deduplicated entries count their duplicates, until the latest one. It also returns the number of ongoing signatures and of signatures before the limit.
+func analyzeErrorSignatures(logs []LogEntry, timeRange TimeRange, limit int) ([]ErrorSignature, int, int) {
+	signatures := make(map[string]*ErrorSignature)
+	for _, log := range logs {
+		if !isErrorLevel(log.Level) {
+			continue
+		}
+
+		count := log.DuplicateCount
+		if count < 1 {
+			count = 1
+		}
+
+		key := normalizeLogMessage(log.Message)
+		sig, exists := signatures[key]
+		if !exists {
+			sig = &ErrorSignature{
+				Signature: key,
+				Example:   log.Message,
+				FirstSeen: log.Timestamp,
+				LastSeen:  log.lastSeen(),
+			}
+			signatures[key] = sig
+		}
+		sig.Count += count
+		if log.Timestamp.Before(sig.FirstSeen) {
+			sig.FirstSeen = log.Timestamp
+		}
+		if last := log.lastSeen(); last.After(sig.LastSeen) {
+			sig.LastSeen = last
+		}
+	}
+
+	window := timeRange.End.Sub(timeRange.Start) / 10
+	if window < ongoingMinWindow {
+		window = ongoingMinWindow
+	}
+	ongoingSince := timeRange.End.Add(-window)
+
+	ongoing := 0
+	result := make([]ErrorSignature, 0, len(signatures))
+	for _, sig := range signatures {
+		sig.Ongoing = !sig.LastSeen.Before(ongoingSince)
+		if sig.Ongoing {
+			ongoing++
+		}
+		result = append(result, *sig)
+	}
+
+	// Most frequent first, ties broken by signature for a stable order
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Signature < result[j].Signature
+	})
+
+	total := len(result)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, ongoing, total
+}
+
+// displayErrorSignatures prints first/last seen information for each error signature
+func displayErrorSignatures(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	if len(analysis.ErrorSignatures) == 0 {
+		return
+	}
+
+	if !verboseAnalysis {
+		// Counted before --top keeps the most frequent signatures
+		_, _ = fmt.Fprintf(writer, "%sOngoing Errors:%s %d of %d error signatures still occurring\n",
+			colorSubHeader, colorReset, analysis.OngoingSignatures, analysis.SignatureCount)
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "%sError Signatures:%s\n", colorSubHeader, colorReset)
+	for _, sig := range analysis.ErrorSignatures {
+		status := colorGreen + "stopped" + colorReset
+		if sig.Ongoing {
+			status = colorRed + "ongoing" + colorReset
+		}
+		_, _ = fmt.Fprintf(writer, "  %s (%d) • first %s • last %s • %s\n",
+			truncateString(sig.Example, 60), sig.Count,
+			sig.FirstSeen.Format("2006-01-02 15:04:05"),
+			sig.LastSeen.Format("2006-01-02 15:04:05"),
+			status)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// clockSkewThreshold is the skew from which the order of entries across nodes is unreliable
+	clockSkewThreshold = time.Second
+	// clockSkewMinRequests is the number of request IDs logged by two nodes needed to estimate
+	// their skew from them rather than from server starts
+	clockSkewMinRequests = 3
+	// clockSkewStartWindow is how close the starts of two nodes must be to be taken as the
+	// same restart of the cluster
+	clockSkewStartWindow = 10 * time.Minute
+)
+
+// ClockSkew is the estimated difference between the clock of a node and the clock of the
+// reference node of a support packet, the node with the most entries
+type ClockSkew struct {
+	Node      string
+	Reference string
+	Offset    time.Duration // How far the clock of Node is ahead of the clock of Reference
+	Samples   int           // Request IDs or server starts the offset is the median of
+	Method    string        // "request_id" or "start"
+}
+
+// unreliable reports whether the skew makes the order of entries across the nodes unreliable
+func (s ClockSkew) unreliable() bool {
+	return s.Offset >= clockSkewThreshold || s.Offset <= -clockSkewThreshold
+}
+
+// formatSkewOffset formats an offset with its sign, e.g. +2.3s or -850ms
+func formatSkewOffset(offset time.Duration) string {
+	if offset < 0 {
+		return "-" + (-offset).Round(time.Millisecond).String()
+	}
+	return "+" + offset.Round(time.Millisecond).String()
+}
+
+// medianDuration returns the median of durations, which it sorts
+func medianDuration(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations[len(durations)/2]
+}
+
+// estimateClockSkew estimates the clock skew of each node of a support packet relative to the
+// node with the most entries. A request ID logged by both nodes happened at about the same
+// time on both, so the median difference of their first timestamps is the skew. Without
+// enough shared request IDs, the starts of the nodes within clockSkewStartWindow of each other
+// are taken as the same restart of the cluster, which is less precise. Nodes sharing neither
+// have no estimate.
+func estimateClockSkew(logs []LogEntry) []ClockSkew {
+	nodeLogs := make(map[string][]LogEntry)
+	for _, log := range logs {
+		if log.Node != "" {
+			nodeLogs[log.Node] = append(nodeLogs[log.Node], log)
+		}
+	}
+	if len(nodeLogs) < 2 {
+		return nil
+	}
+	nodes := make([]string, 0, len(nodeLogs))
+	for node := range nodeLogs {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	reference := nodes[0]
+	for _, node := range nodes {
+		if len(nodeLogs[node]) > len(nodeLogs[reference]) {
+			reference = node
+		}
+	}
+
+	// First timestamp of each request ID per node
+	requests := make(map[string]map[string]time.Time)
+	for _, log := range logs {
+		id := log.Extras["request_id"]
+		if id == "" || log.Node == "" {
+			continue
+		}
+		seen, ok := requests[id]
+		if !ok {
+			seen = make(map[string]time.Time)
+			requests[id] = seen
+		}
+		if first, ok := seen[log.Node]; !ok || log.Timestamp.Before(first) {
+			seen[log.Node] = log.Timestamp
+		}
+	}
+	referenceStarts := detectRestarts(nodeLogs[reference])
+
+	var skews []ClockSkew
+	for _, node := range nodes {
+		if node == reference {
+			continue
+		}
+		var offsets []time.Duration
+		for _, seen := range requests {
+			referenceTime, inReference := seen[reference]
+			nodeTime, inNode := seen[node]
+			if inReference && inNode {
+				offsets = append(offsets, nodeTime.Sub(referenceTime))
+			}
+		}
+		if len(offsets) >= clockSkewMinRequests {
+			skews = append(skews, ClockSkew{Node: node, Reference: reference, Offset: medianDuration(offsets), Samples: len(offsets), Method: "request_id"})
+			continue
+		}
+
+		offsets = offsets[:0]
+		for _, start := range detectRestarts(nodeLogs[node]) {
+			closest := clockSkewStartWindow + 1
+			for _, referenceStart := range referenceStarts {
+				if offset := start.Sub(referenceStart); offset.Abs() < closest.Abs() {
+					closest = offset
+				}
+			}
+			if closest.Abs() <= clockSkewStartWindow {
+				offsets = append(offsets, closest)
+			}
+		}
+		if len(offsets) > 0 {
+			skews = append(skews, ClockSkew{Node: node, Reference: reference, Offset: medianDuration(offsets), Samples: len(offsets), Method: "start"})
+		}
+	}
+	return skews
+}
+
+// handleClockSkew warns when the clocks of the nodes of a support packet differ enough to make
+// the order of their entries unreliable. With correct, it shifts the entries of each node by
+// its estimated skew instead, and sorts them again.
+func handleClockSkew(logs []LogEntry, correct bool) {
+	skews := estimateClockSkew(logs)
+	for _, skew := range skews {
+		if !correct {
+			if skew.unreliable() {
+				logger.Warn("The clock of a node differs, the order of entries across nodes is unreliable (see --correct-skew)",
+					"node", skew.Node, "reference", skew.Reference, "offset", formatSkewOffset(skew.Offset))
+			}
+			continue
+		}
+		for i := range logs {
+			if logs[i].Node == skew.Node {
+				logs[i].Timestamp = logs[i].Timestamp.Add(-skew.Offset)
+			}
+		}
+		logger.Info("Corrected the clock skew of a node", "node", skew.Node, "reference", skew.Reference, "offset", formatSkewOffset(skew.Offset))
+	}
+	if correct && len(skews) > 0 {
+		sortEntries(logs)
+	}
+}
+
+// describeSkewSamples describes what the offset of a skew was estimated from
+func describeSkewSamples(skew ClockSkew) string {
+	if skew.Method == "request_id" {
+		return countNoun(skew.Samples, "request ID", "request IDs")
+	}
+	return countNoun(skew.Samples, "server start", "server starts")
+}
+
+// displayClockSkew prints the clock skew of the nodes, only the unreliable ones in the compact
+// analysis
+func displayClockSkew(analysis LogAnalysis, writer io.Writer, verboseAnalysis bool) {
+	var unreliable []ClockSkew
+	for _, skew := range analysis.ClockSkews {
+		if skew.unreliable() {
+			unreliable = append(unreliable, skew)
+		}
+	}
+
+	if !verboseAnalysis {
+		if len(unreliable) == 0 {
+			return
+		}
+		_, _ = fmt.Fprintf(writer, "%sClock Skew:%s", colorSubHeader, colorReset)
+		for i, skew := range unreliable {
+			separator := ","
+			if i == 0 {
+				separator = ""
+			}
+			_, _ = fmt.Fprintf(writer, "%s %s %s", separator, skew.Node, formatSkewOffset(skew.Offset))
+		}
+		_, _ = fmt.Fprintf(writer, " vs %s (order across nodes unreliable)\n", unreliable[0].Reference)
+		return
+	}
+
+	if len(analysis.ClockSkews) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(writer, "%sClock Skew:%s (relative to %s)\n", colorSubHeader, colorReset, analysis.ClockSkews[0].Reference)
+	for _, skew := range analysis.ClockSkews {
+		warning := ""
+		if skew.unreliable() {
+			warning = fmt.Sprintf(" %s⚠ order across nodes unreliable%s", colorYellow, colorReset)
+		}
+		_, _ = fmt.Fprintf(writer, "  %s: %s (%s)%s\n", skew.Node, formatSkewOffset(skew.Offset), describeSkewSamples(skew), warning)
+	}
+	_, _ = fmt.Fprintln(writer)
+}
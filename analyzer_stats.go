@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logStats computes the statistics of the analysis that only count entries, one entry at a
+// time, so that they can be computed while the entries stream in without holding them all
+type logStats struct {
+	analysis            LogAnalysis
+	showDupes           bool
+	totalWithDuplicates int
+
+	sourceCounts             map[string]int
+	userCounts               map[string]int
+	errorMsgCounts           map[string]int
+	hourCounts               map[int]int
+	dayOfWeekCounts          map[string]int
+	monthCounts              map[string]int
+	bucketCounts             map[string]int
+	patternCounts            map[string]int
+	notificationTypeCounts   map[string]int
+	notificationStatusCounts map[string]int
+	ipCounts                 map[string]int
+	errorIPCounts            map[string]int
+	userAgentCounts          map[string]int
+	errorUserAgentCounts     map[string]int
+}
+
+// newLogStats returns empty statistics, counting deduplicated entries as many times as they
+// occurred with showDupes
+func newLogStats(showDupes bool) *logStats {
+	return &logStats{
+		analysis: LogAnalysis{
+			LevelCounts:      make(map[string]int),
+			HourLevelCounts:  make(map[int]map[string]int),
+			DayLevelCounts:   make(map[string]map[string]int),
+			MonthLevelCounts: make(map[string]map[string]int),
+			ActivityBucket:   activityBucket,
+		},
+		showDupes:                showDupes,
+		sourceCounts:             make(map[string]int),
+		userCounts:               make(map[string]int),
+		errorMsgCounts:           make(map[string]int),
+		hourCounts:               make(map[int]int),
+		dayOfWeekCounts:          make(map[string]int),
+		monthCounts:              make(map[string]int),
+		bucketCounts:             make(map[string]int),
+		patternCounts:            make(map[string]int),
+		notificationTypeCounts:   make(map[string]int),
+		notificationStatusCounts: make(map[string]int),
+		ipCounts:                 make(map[string]int),
+		errorIPCounts:            make(map[string]int),
+		userAgentCounts:          make(map[string]int),
+		errorUserAgentCounts:     make(map[string]int),
+	}
+}
+
+// add counts an entry
+func (s *logStats) add(log LogEntry) {
+	analysis := &s.analysis
+
+	// Get the count (either the duplicate count or 1 if not set)
+	count := 1
+	if s.showDupes && log.DuplicateCount > 1 {
+		count = log.DuplicateCount
+	}
+
+	// Update time range
+	if analysis.TotalEntries == 0 || log.Timestamp.Before(analysis.TimeRange.Start) {
+		analysis.TimeRange.Start = log.Timestamp
+	}
+	// Deduplicated entries last until their latest duplicate
+	if last := log.lastSeen(); analysis.TotalEntries == 0 || last.After(analysis.TimeRange.End) {
+		analysis.TimeRange.End = last
+	}
+	analysis.TotalEntries++
+	s.totalWithDuplicates += count
+
+	// Count log levels
+	level := strings.ToUpper(log.Level)
+	analysis.LevelCounts[level] += count
+
+	// Count sources
+	if log.Source != "" {
+		s.sourceCounts[log.Source] += count
+	}
+
+	// Count users
+	if log.User != "" {
+		s.userCounts[log.User] += count
+	}
+
+	// Count error messages
+	if strings.EqualFold(log.Level, "error") || strings.EqualFold(log.Level, "fatal") {
+		// Get first 50 chars of message or full message if shorter
+		shortMsg := log.Message
+		if len(shortMsg) > 50 {
+			shortMsg = shortMsg[:50] + "..."
+		}
+		s.errorMsgCounts[shortMsg] += count
+	}
+
+	// Count client IPs and user agents
+	isError := isErrorLevel(log.Level)
+	if ip := log.Extras["ip_address"]; ip != "" {
+		s.ipCounts[ip] += count
+		if isError {
+			s.errorIPCounts[ip] += count
+		}
+	}
+	if userAgent := log.Extras["user_agent"]; userAgent != "" {
+		s.userAgentCounts[userAgent] += count
+		if isError {
+			s.errorUserAgentCounts[userAgent] += count
+		}
+	}
+
+	// Count activity and the level distribution by hour, day of week and month
+	hour := log.Timestamp.Hour()
+	s.hourCounts[hour] += count
+	if _, exists := analysis.HourLevelCounts[hour]; !exists {
+		analysis.HourLevelCounts[hour] = make(map[string]int)
+	}
+	analysis.HourLevelCounts[hour][level] += count
+
+	dayOfWeek := log.Timestamp.Weekday().String()
+	s.dayOfWeekCounts[dayOfWeek] += count
+	if _, exists := analysis.DayLevelCounts[dayOfWeek]; !exists {
+		analysis.DayLevelCounts[dayOfWeek] = make(map[string]int)
+	}
+	analysis.DayLevelCounts[dayOfWeek][level] += count
+
+	month := log.Timestamp.Month().String()
+	s.monthCounts[month] += count
+	if _, exists := analysis.MonthLevelCounts[month]; !exists {
+		analysis.MonthLevelCounts[month] = make(map[string]int)
+	}
+	analysis.MonthLevelCounts[month][level] += count
+
+	// Count activity and the level distribution per --bucket period
+	if analysis.ActivityBucket != "" {
+		period := activityBucketLabel(log.Timestamp, analysis.ActivityBucket)
+		s.bucketCounts[period] += count
+		if analysis.ActivityLevelCounts == nil {
+			analysis.ActivityLevelCounts = make(map[string]map[string]int)
+		}
+		if _, exists := analysis.ActivityLevelCounts[period]; !exists {
+			analysis.ActivityLevelCounts[period] = make(map[string]int)
+		}
+		analysis.ActivityLevelCounts[period][level] += count
+	}
+
+	// Identify common patterns in messages
+	words := strings.Fields(log.Message)
+	if len(words) > 0 {
+		pattern := words[0]
+		if len(words) > 1 {
+			pattern += " " + words[1]
+		}
+		s.patternCounts[pattern] += count
+	}
+
+	// Count notification types and statuses if present
+	if log.LogSource == "notifications" {
+		if log.Type != "" {
+			s.notificationTypeCounts[log.Type] += count
+		}
+		if log.Status != "" {
+			s.notificationStatusCounts[log.Status] += count
+		}
+	}
+}
+
+// result returns the analysis of the counted entries, keeping at most topLimit top sources,
+// users and error messages (0 keeps all of them). The analyses that need all entries, such
+// as logging gaps and error signatures, are left empty.
+func (s *logStats) result(topLimit int) LogAnalysis {
+	analysis := s.analysis
+
+	// Calculate error rate
+	errorCount := analysis.LevelCounts["ERROR"] + analysis.LevelCounts["FATAL"]
+	analysis.ErrorRate = float64(errorCount) / float64(s.totalWithDuplicates) * 100
+
+	// Update total entries to include duplicates
+	analysis.TotalEntries = s.totalWithDuplicates
+
+	// Convert maps to sorted slices
+	analysis.TopSources = mapToSortedSlice(s.sourceCounts, topLimit)
+	analysis.TopUsers = mapToSortedSlice(s.userCounts, topLimit)
+	analysis.TopErrorMessages = mapToSortedSlice(s.errorMsgCounts, topLimit)
+	analysis.TopIPs = mapToSortedSlice(s.ipCounts, topLimit)
+	analysis.TopErrorIPs = mapToSortedSlice(s.errorIPCounts, topLimit)
+	analysis.TopUserAgents = mapToSortedSlice(s.userAgentCounts, topLimit)
+	analysis.TopErrorUserAgents = mapToSortedSlice(s.errorUserAgentCounts, topLimit)
+
+	// Convert hourCounts (map[int]int) to string keys for mapToSortedSlice
+	hourCountsStr := make(map[string]int)
+	for hour, count := range s.hourCounts {
+		hourCountsStr[fmt.Sprintf("%d", hour)] = count
+	}
+	analysis.BusiestHours = mapToSortedSlice(hourCountsStr, 24)
+
+	// Add day of week and month activity
+	analysis.ActivityByDayOfWeek = mapToSortedSlice(s.dayOfWeekCounts, 7)
+	analysis.ActivityByMonth = mapToSortedSlice(s.monthCounts, 12)
+	if analysis.ActivityBucket != "" {
+		analysis.Activity = sortActivityBuckets(s.bucketCounts)
+	}
+
+	analysis.CommonPatterns = mapToSortedSlice(s.patternCounts, 10)
+
+	// Add notification-specific information if present
+	analysis.NotificationTypes = mapToSortedSlice(s.notificationTypeCounts, 10)
+	analysis.NotificationStatuses = mapToSortedSlice(s.notificationStatusCounts, 10)
+
+	return analysis
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Uses the mustParseTime function from parser_test.go
@@ -79,7 +85,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	}
 
 	t.Run("analyze basic statistics", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		
 		// Check total entries
 		assert.Equal(t, 9, analysis.TotalEntries)
@@ -99,7 +105,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze hour distribution", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		hourMap := make(map[string]int)
 		
 		for _, hour := range analysis.BusiestHours {
@@ -115,7 +121,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze day of week distribution", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		dayMap := make(map[string]int)
 		
 		for _, day := range analysis.ActivityByDayOfWeek {
@@ -131,7 +137,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze month distribution", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		monthMap := make(map[string]int)
 		
 		for _, month := range analysis.ActivityByMonth {
@@ -144,7 +150,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze level distribution by hour", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		
 		// Check hour 10 level distribution
 		hourLevels := analysis.HourLevelCounts[10]
@@ -158,7 +164,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze level distribution by day", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		
 		// Check Wednesday level distribution
 		wedLevels := analysis.DayLevelCounts["Wednesday"]
@@ -173,7 +179,7 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 
 	t.Run("analyze level distribution by month", func(t *testing.T) {
-		analysis := analyzeLogs(logs, false)
+		analysis := analyzeLogs(logs, false, 10)
 		
 		// Check January level distribution
 		janLevels := analysis.MonthLevelCounts["January"]
@@ -184,6 +190,48 @@ func TestAnalyzeLogs(t *testing.T) {
 	})
 }
 
+func TestAnalyzeLogsTopLimit(t *testing.T) {
+	var logs []LogEntry
+	for i := 0; i < 30; i++ {
+		logs = append(logs, LogEntry{
+			Timestamp: mustParseTime(t, "2025-01-01 10:00:00.000 Z"),
+			Level:     "ERROR",
+			Message:   fmt.Sprintf("Failure number %d", i),
+			Source:    fmt.Sprintf("pkg/file%d.go:1", i),
+			User:      fmt.Sprintf("user%d", i),
+		})
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		analysis := analyzeLogs(logs, false, 10)
+		assert.Len(t, analysis.TopSources, 10)
+		assert.Len(t, analysis.TopUsers, 10)
+		assert.Len(t, analysis.TopErrorMessages, 10)
+	})
+
+	t.Run("custom limit", func(t *testing.T) {
+		analysis := analyzeLogs(logs, false, 25)
+		assert.Len(t, analysis.TopSources, 25)
+		assert.Len(t, analysis.TopUsers, 25)
+		assert.Len(t, analysis.TopErrorMessages, 25)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		analysis := analyzeLogs(logs, false, 0)
+		assert.Len(t, analysis.TopSources, 30)
+		assert.Len(t, analysis.TopUsers, 30)
+		assert.Len(t, analysis.TopErrorMessages, 30)
+	})
+
+	t.Run("full output lists all items", func(t *testing.T) {
+		var buf bytes.Buffer
+		analyzeAndDisplayStats(logs, &buf, false, false, 0, true)
+		output := buf.String()
+		assert.Contains(t, output, "pkg/file29.go:1(1)")
+		assert.Contains(t, output, "user29(1)")
+	})
+}
+
 func TestGetDominantLevelColor(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -292,7 +340,7 @@ func TestDisplayAnalysis(t *testing.T) {
 
 	t.Run("display analysis output formatting", func(t *testing.T) {
 		var buf bytes.Buffer
-		displayAnalysis(analysis, &buf, false, 10, true)
+		displayAnalysis(analysis, &buf, false, 10, true, false)
 		output := buf.String()
 		
 		// Check that all expected sections are present
@@ -316,7 +364,7 @@ func TestDisplayAnalysis(t *testing.T) {
 
 	t.Run("display analysis with deduplication info", func(t *testing.T) {
 		var buf bytes.Buffer
-		displayAnalysis(analysis, &buf, true, 8, true) // 8 unique entries out of 10 total
+		displayAnalysis(analysis, &buf, true, 8, true, false) // 8 unique entries out of 10 total
 		output := buf.String()
 		
 		// Check deduplication info (verbose analysis shows entries count and duration)
@@ -333,7 +381,7 @@ func TestDisplayAnalysis(t *testing.T) {
 		}
 		
 		var buf bytes.Buffer
-		displayAnalysis(shortAnalysis, &buf, false, 10, true)
+		displayAnalysis(shortAnalysis, &buf, false, 10, true, false)
 		output := buf.String()
 		
 		// Day of week chart should NOT be present for short time ranges
@@ -368,22 +416,23 @@ func TestAnalyzeAndDisplayStats(t *testing.T) {
 
 	t.Run("display stats without duplicates", func(t *testing.T) {
 		var buf bytes.Buffer
-		analyzeAndDisplayStats(logs, &buf, false, false)
+		analyzeAndDisplayStats(logs, &buf, false, false, 10, false)
 		output := buf.String()
 		
 		assert.Contains(t, output, "3 entries")
-		assert.NotContains(t, output, "Deduplication Ratio")
+		assert.NotContains(t, output, "unique)", "the unique entries are only counted when deduplicated")
 	})
 
 	t.Run("handle empty logs", func(t *testing.T) {
 		var buf bytes.Buffer
-		analyzeAndDisplayStats([]LogEntry{}, &buf, false, false)
+		analyzeAndDisplayStats([]LogEntry{}, &buf, false, false, 10, false)
 		output := buf.String()
 		
 		assert.Contains(t, output, "No log entries to analyze.")
 	})
 
 	t.Run("display stats with duplicates", func(t *testing.T) {
+		initLogger()
 		// Create logs with duplicate counts
 		duplicateLogs := []LogEntry{
 			{
@@ -403,9 +452,943 @@ func TestAnalyzeAndDisplayStats(t *testing.T) {
 		}
 		
 		var buf bytes.Buffer
-		analyzeAndDisplayStats(duplicateLogs, &buf, true, false)
+		analyzeAndDisplayStats(duplicateLogs, &buf, true, false, 10, false)
 		output := buf.String()
 		
 		assert.Contains(t, output, "5 entries (2 unique)")
 	})
-}
\ No newline at end of file
+}
+func TestDetectLoggingGaps(t *testing.T) {
+	initLogger()
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+
+	// One entry every 10 seconds with a 45 minute outage in the middle
+	var logs []LogEntry
+	for i := 0; i < 60; i++ {
+		logs = append(logs, LogEntry{Timestamp: start.Add(time.Duration(i) * 10 * time.Second), Level: "info"})
+	}
+	resume := start.Add(10*time.Minute + 45*time.Minute)
+	for i := 0; i < 60; i++ {
+		logs = append(logs, LogEntry{Timestamp: resume.Add(time.Duration(i) * 10 * time.Second), Level: "info"})
+	}
+
+	t.Run("detects outage", func(t *testing.T) {
+		gaps, median, threshold := detectLoggingGaps(logs)
+		require.Len(t, gaps, 1)
+		assert.Equal(t, 10*time.Second, median)
+		assert.Equal(t, 5*time.Minute, threshold)
+		assert.Equal(t, start.Add(590*time.Second), gaps[0].Start)
+		assert.Equal(t, resume, gaps[0].End)
+	})
+
+	t.Run("regular cadence has no gaps", func(t *testing.T) {
+		gaps, _, _ := detectLoggingGaps(logs[:60])
+		assert.Empty(t, gaps)
+	})
+
+	t.Run("shown in analysis", func(t *testing.T) {
+		var buf bytes.Buffer
+		analyzeAndDisplayStats(logs, &buf, false, false, 10, false)
+		assert.Contains(t, buf.String(), "Gaps:")
+		assert.Contains(t, buf.String(), "1 silent period(s)")
+	})
+
+	t.Run("skipped for deduplicated logs", func(t *testing.T) {
+		deduped := append([]LogEntry(nil), logs...)
+		deduped[0].DuplicateCount = 5
+		analysis := analyzeLogs(deduped, true, 10)
+		assert.Empty(t, analysis.LoggingGaps)
+	})
+}
+
+func TestAnalyzeErrorSignatures(t *testing.T) {
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2025-01-01 10:00:00.000 Z"), Level: "error", Message: "Failed to connect to 10.0.0.1"},
+		{Timestamp: mustParseTime(t, "2025-01-01 10:30:00.000 Z"), Level: "error", Message: "Failed to connect to 10.0.0.2"},
+		{Timestamp: mustParseTime(t, "2025-01-01 11:00:00.000 Z"), Level: "error", Message: "Plugin crashed"},
+		{Timestamp: mustParseTime(t, "2025-01-01 11:30:00.000 Z"), Level: "info", Message: "Still running"},
+		{Timestamp: mustParseTime(t, "2025-01-01 12:00:00.000 Z"), Level: "error", Message: "Failed to connect to 10.0.0.3"},
+	}
+	timeRange := TimeRange{Start: logs[0].Timestamp, End: logs[len(logs)-1].Timestamp}
+
+	signatures, ongoing, total := analyzeErrorSignatures(logs, timeRange, 10)
+	require.Len(t, signatures, 2)
+	assert.Equal(t, 1, ongoing)
+	assert.Equal(t, 2, total)
+
+	assert.Equal(t, "Failed to connect to 10.0.0.1", signatures[0].Example)
+	assert.Equal(t, 3, signatures[0].Count)
+	assert.Equal(t, logs[0].Timestamp, signatures[0].FirstSeen)
+	assert.Equal(t, logs[4].Timestamp, signatures[0].LastSeen)
+	assert.True(t, signatures[0].Ongoing)
+
+	assert.Equal(t, "Plugin crashed", signatures[1].Example)
+	assert.False(t, signatures[1].Ongoing)
+
+	var buf bytes.Buffer
+	displayAnalysis(LogAnalysis{TimeRange: timeRange, ErrorSignatures: signatures}, &buf, false, 0, true, false)
+	assert.Contains(t, buf.String(), "Error Signatures:")
+	assert.Contains(t, buf.String(), "ongoing")
+	assert.Contains(t, buf.String(), "stopped")
+
+	t.Run("deduplicated entries", func(t *testing.T) {
+		initLogger()
+		lastSeen := logs[4].Timestamp
+		deduplicated := []LogEntry{logs[0], logs[2], logs[3]}
+		deduplicated[0].DuplicateCount, deduplicated[0].LastSeen = 3, &lastSeen
+
+		analysis := analyzeLogs(deduplicated, true, 10)
+		assert.Equal(t, timeRange, analysis.TimeRange, "the time range lasts until the latest duplicate")
+		require.Len(t, analysis.ErrorSignatures, 2)
+		assert.Equal(t, 3, analysis.ErrorSignatures[0].Count)
+		assert.Equal(t, lastSeen, analysis.ErrorSignatures[0].LastSeen)
+		assert.True(t, analysis.ErrorSignatures[0].Ongoing, "the latest duplicate is still occurring")
+		assert.Equal(t, 1, analysis.OngoingSignatures)
+	})
+
+	t.Run("counted before --top", func(t *testing.T) {
+		// Plugin crashed is cut by --top 1, it still counts as a stopped signature
+		signatures, ongoing, total := analyzeErrorSignatures(logs, timeRange, 1)
+		require.Len(t, signatures, 1)
+		assert.Equal(t, 1, ongoing)
+		assert.Equal(t, 2, total)
+
+		var buf bytes.Buffer
+		displayErrorSignatures(analyzeLogs(logs, false, 1), &buf, false)
+		assert.Contains(t, buf.String(), "1 of 2 error signatures still occurring")
+	})
+}
+
+func TestAnalyzeIncidentMetrics(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+
+	var logs []LogEntry
+	// Two bursts of errors 10 seconds apart, one at 10:10 and one at 11:00
+	for _, burstStart := range []time.Duration{10 * time.Minute, 60 * time.Minute} {
+		for i := 0; i < 6; i++ {
+			logs = append(logs, LogEntry{Timestamp: start.Add(burstStart + time.Duration(i)*10*time.Second), Level: "error"})
+		}
+	}
+	// Isolated error that is not a burst
+	logs = append(logs, LogEntry{Timestamp: start.Add(90 * time.Minute), Level: "error"})
+	timeRange := TimeRange{Start: start, End: start.Add(2 * time.Hour)}
+
+	metrics := analyzeIncidentMetrics(logs, timeRange)
+	require.Len(t, metrics.ErrorBursts, 2)
+	assert.Equal(t, 6, metrics.ErrorBursts[0].Count)
+	assert.Equal(t, 50*time.Second, metrics.AverageBurstDuration)
+	assert.Equal(t, 50*time.Minute, metrics.MeanTimeBetweenBursts)
+	assert.Equal(t, start.Add(90*time.Minute), metrics.LastError)
+	assert.Equal(t, 30*time.Minute, metrics.TimeSinceLastError)
+	assert.Equal(t, 49*time.Minute+10*time.Second, metrics.LongestErrorFree)
+
+	t.Run("no errors", func(t *testing.T) {
+		metrics := analyzeIncidentMetrics([]LogEntry{{Timestamp: start, Level: "info"}}, timeRange)
+		assert.Empty(t, metrics.ErrorBursts)
+		assert.Equal(t, 2*time.Hour, metrics.LongestErrorFree)
+	})
+}
+
+func TestAnalyzeClientIPsAndUserAgents(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Extras: map[string]string{"ip_address": "10.0.0.1", "user_agent": "Mattermost Desktop"}},
+		{Timestamp: ts, Level: "info", Extras: map[string]string{"ip_address": "10.0.0.1", "user_agent": "Mattermost Desktop"}},
+		{Timestamp: ts, Level: "error", Extras: map[string]string{"ip_address": "10.0.0.2", "user_agent": "Mattermost Mobile/2.1"}},
+		{Timestamp: ts, Level: "error", Extras: map[string]string{"ip_address": "10.0.0.2", "user_agent": "Mattermost Mobile/2.1"}},
+		{Timestamp: ts, Level: "error", Extras: map[string]string{"ip_address": "10.0.0.2", "user_agent": "Mattermost Mobile/2.1"}},
+		{Timestamp: ts, Level: "info", Message: "no client info"},
+	}
+
+	analysis := analyzeLogs(logs, false, 10)
+	assert.Equal(t, []CountedItem{{Item: "10.0.0.2", Count: 3}, {Item: "10.0.0.1", Count: 2}}, analysis.TopIPs)
+	assert.Equal(t, []CountedItem{{Item: "10.0.0.2", Count: 3}}, analysis.TopErrorIPs)
+	assert.Equal(t, []CountedItem{{Item: "Mattermost Mobile/2.1", Count: 3}, {Item: "Mattermost Desktop", Count: 2}}, analysis.TopUserAgents)
+	assert.Equal(t, []CountedItem{{Item: "Mattermost Mobile/2.1", Count: 3}}, analysis.TopErrorUserAgents)
+
+	var buf bytes.Buffer
+	displayAnalysis(analysis, &buf, false, len(logs), true, false)
+	output := buf.String()
+	assert.Contains(t, output, "Client IPs:")
+	assert.Contains(t, output, "Client IPs (errors):")
+	assert.Contains(t, output, "User Agents (errors):")
+}
+
+func TestAnalyzeRuntimeMetrics(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+
+	var logs []LogEntry
+	for i := 0; i < 6; i++ {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		logs = append(logs,
+			LogEntry{Timestamp: ts, Level: "info", Message: "Runtime stats", Extras: map[string]string{
+				"goroutines":     fmt.Sprintf("%d", 1000+i*500),
+				"heap_alloc":     "512MB",
+				"db_connections": fmt.Sprintf("%d", 20+i%2),
+			}},
+			LogEntry{Timestamp: ts.Add(time.Minute), Level: "warn", Message: fmt.Sprintf("The number of running goroutines (%d) is over the health threshold", 1000+i*500)},
+		)
+	}
+
+	metrics := analyzeRuntimeMetrics(logs)
+	require.Len(t, metrics, 3)
+
+	byName := make(map[string]RuntimeMetric)
+	for _, metric := range metrics {
+		byName[metric.Name] = metric
+	}
+
+	assert.Len(t, byName["goroutines"].Samples, 12)
+	assert.Equal(t, 3500.0, byName["goroutines"].Max)
+	assert.True(t, byName["goroutines"].LeakSuspected)
+
+	assert.Equal(t, float64(512<<20), byName["memory"].Max)
+	assert.False(t, byName["memory"].LeakSuspected)
+	assert.False(t, byName["db_connections"].LeakSuspected)
+
+	t.Run("steady growth is flagged", func(t *testing.T) {
+		var statsOnly []LogEntry
+		for i := 0; i < len(logs); i += 2 {
+			statsOnly = append(statsOnly, logs[i])
+		}
+		metrics := analyzeRuntimeMetrics(statsOnly)
+		for _, metric := range metrics {
+			assert.Equal(t, metric.Name == "goroutines", metric.LeakSuspected, metric.Name)
+		}
+	})
+
+	t.Run("the first key of a metric counts", func(t *testing.T) {
+		var growing []LogEntry
+		for i := 0; i < 6; i++ {
+			growing = append(growing, LogEntry{Timestamp: start.Add(time.Duration(i) * time.Hour), Extras: map[string]string{
+				"rss":        fmt.Sprintf("%dMB", 1000+i*200),
+				"HEAP_ALLOC": "512MB",
+				"mem_alloc":  "256MB",
+				"memory":     "128MB",
+			}})
+		}
+		// The order of the extras changes from run to run
+		for run := 0; run < 20; run++ {
+			metrics := analyzeRuntimeMetrics(growing)
+			require.Len(t, metrics, 1)
+			assert.Equal(t, float64(512<<20), metrics[0].Max, "heap_alloc comes before the other memory keys")
+			assert.False(t, metrics[0].LeakSuspected)
+		}
+	})
+
+	t.Run("parse byte sizes", func(t *testing.T) {
+		size, err := parseByteSize("1.5GiB")
+		require.NoError(t, err)
+		assert.Equal(t, 1.5*(1<<30), size)
+		size, err = parseByteSize("2048")
+		require.NoError(t, err)
+		assert.Equal(t, 2048.0, size)
+	})
+}
+
+func TestAnalyzeComplianceExports(t *testing.T) {
+	export := func(ts, level, msg, jobID string, extras map[string]string) LogEntry {
+		if extras == nil {
+			extras = map[string]string{}
+		}
+		extras["worker"] = "MessageExportWorker"
+		if jobID != "" {
+			extras["job_id"] = jobID
+		}
+		return LogEntry{Timestamp: mustParseTime(t, ts), Level: level, Message: msg, Extras: extras}
+	}
+	logs := []LogEntry{
+		export("2024-03-01 01:00:00.000 Z", "debug", "Worker received a new candidate job.", "a", nil),
+		export("2024-03-01 01:05:00.000 Z", "info", "Worker: Job is complete", "a", map[string]string{"exported_posts": "1200"}),
+		{Timestamp: mustParseTime(t, "2024-03-01 12:00:00.000 Z"), Level: "info", Message: "Bulk export started", Extras: map[string]string{"worker": "ExportProcess"}},
+		export("2024-03-02 01:00:00.000 Z", "debug", "Worker received a new candidate job.", "b", nil),
+		export("2024-03-02 01:30:00.000 Z", "error", "Worker: Job failed", "b", map[string]string{"error": "unable to write to file store"}),
+		export("2024-03-03 01:00:00.000 Z", "debug", "Worker received a new candidate job.", "c", nil),
+		export("2024-03-03 01:15:00.000 Z", "info", "Worker: Job is complete", "c", map[string]string{"exported_posts": "300"}),
+		{Timestamp: mustParseTime(t, "2024-03-08 12:00:00.000 Z"), Level: "info", Message: "Server is running"},
+	}
+	timeRange := TimeRange{Start: logs[0].Timestamp, End: logs[len(logs)-1].Timestamp}
+
+	exports := analyzeComplianceExports(logs, timeRange)
+	require.Len(t, exports.Runs, 3, "bulk exports are not compliance exports")
+	failed := exports.Runs[1]
+	assert.Equal(t, "b", failed.JobID)
+	assert.Equal(t, mustParseTime(t, "2024-03-02 01:00:00.000 Z"), failed.Start)
+	assert.Equal(t, mustParseTime(t, "2024-03-02 01:30:00.000 Z"), failed.End)
+	assert.Equal(t, jobFailed, failed.Status)
+	assert.Equal(t, "Worker: Job failed", failed.Error)
+	assert.Zero(t, failed.Exported)
+	assert.Equal(t, 1, exports.Failures)
+	assert.Equal(t, 1500, exports.Exported)
+	assert.Equal(t, 30*time.Minute, exports.MaxDuration)
+	assert.Equal(t, 50*time.Minute/3, exports.AverageDuration)
+	assert.Equal(t, mustParseTime(t, "2024-03-03 01:15:00.000 Z"), exports.LastSuccess)
+	assert.Equal(t, 24*time.Hour, exports.ExpectedInterval)
+	assert.True(t, exports.Stopped, "no run for 5 days while runs were daily")
+
+	t.Run("runs without job IDs", func(t *testing.T) {
+		logs := []LogEntry{
+			{Timestamp: mustParseTime(t, "2024-03-01 01:00:00.000 Z"), Level: "info", Message: "Starting message export"},
+			{Timestamp: mustParseTime(t, "2024-03-01 01:02:00.000 Z"), Level: "info", Message: "Message export finished: 42 posts exported"},
+			{Timestamp: mustParseTime(t, "2024-03-01 02:00:00.000 Z"), Level: "info", Message: "Starting message export"},
+			{Timestamp: mustParseTime(t, "2024-03-01 03:00:00.000 Z"), Level: "info", Message: "Starting message export"},
+		}
+		exports := analyzeComplianceExports(logs, TimeRange{Start: logs[0].Timestamp, End: logs[3].Timestamp})
+		require.Len(t, exports.Runs, 3)
+		assert.Equal(t, jobCompleted, exports.Runs[0].Status)
+		assert.Equal(t, 42, exports.Runs[0].Exported)
+		assert.Equal(t, jobUnfinished, exports.Runs[1].Status, "a new start ends a run without outcome")
+		assert.Zero(t, exports.Runs[1].Duration())
+		assert.Equal(t, time.Hour, exports.ExpectedInterval)
+		assert.False(t, exports.Stopped)
+	})
+
+	assert.Empty(t, analyzeComplianceExports([]LogEntry{logs[2], logs[7]}, timeRange).Runs)
+}
+
+func TestAnalyzeDataRetention(t *testing.T) {
+	retention := func(ts, level, msg, jobID string, extras map[string]string) LogEntry {
+		if extras == nil {
+			extras = map[string]string{}
+		}
+		extras["worker"] = "DataRetention"
+		extras["job_id"] = jobID
+		return LogEntry{Timestamp: mustParseTime(t, ts), Level: level, Message: msg, Extras: extras}
+	}
+	logs := []LogEntry{
+		retention("2024-03-01 00:00:00.000 Z", "debug", "Worker received a new candidate job.", "a", nil),
+		retention("2024-03-01 00:10:00.000 Z", "info", "Applied retention policy", "a", map[string]string{"policy_id": "p1", "rows_deleted": "500"}),
+		retention("2024-03-01 00:20:00.000 Z", "info", "Applied retention policy", "a", map[string]string{"policy_id": "p2", "rows_deleted": "250"}),
+		retention("2024-03-01 00:21:00.000 Z", "info", "Worker: Job is complete", "a", nil),
+		retention("2024-03-02 00:00:00.000 Z", "debug", "Worker received a new candidate job.", "b", nil),
+		{Timestamp: mustParseTime(t, "2024-03-02 00:40:00.000 Z"), Level: "warn", Message: "Slow query detected", Extras: map[string]string{"duration": "45s"}},
+		retention("2024-03-02 01:00:00.000 Z", "error", "Worker: Job failed", "b", map[string]string{"error": "pq: canceling statement due to statement timeout"}),
+		retention("2024-03-03 00:00:00.000 Z", "debug", "Worker received a new candidate job.", "c", nil),
+		retention("2024-03-03 01:00:00.000 Z", "error", "Data retention batch failed: context deadline exceeded", "c", nil),
+		{Timestamp: mustParseTime(t, "2024-03-03 01:03:00.000 Z"), Level: "warn", Message: "Failed to ping DB", Extras: map[string]string{}},
+		{Timestamp: mustParseTime(t, "2024-03-03 02:00:00.000 Z"), Level: "warn", Message: "Slow query detected", Extras: map[string]string{}},
+	}
+
+	monitor := analyzeDataRetention(logs)
+	require.Len(t, monitor.Runs, 3)
+	first := monitor.Runs[0]
+	assert.Equal(t, jobCompleted, first.Status)
+	assert.Equal(t, 2, first.Policies)
+	assert.Equal(t, 750, first.RowsDeleted)
+	assert.False(t, first.TimedOut)
+	assert.Equal(t, 21*time.Minute, first.Duration())
+
+	assert.True(t, monitor.Runs[1].TimedOut, "timeouts are found in the error extra")
+	assert.Equal(t, 1, monitor.Runs[1].DBWarnings)
+	assert.True(t, monitor.Runs[2].TimedOut)
+	assert.Equal(t, 1, monitor.Runs[2].DBWarnings, "warnings long after the run are not attributed to it")
+
+	assert.Equal(t, 2, monitor.Failures)
+	assert.Equal(t, 2, monitor.TimedOut)
+	assert.Equal(t, 750, monitor.RowsDeleted)
+	assert.Equal(t, 2, monitor.DBWarnings)
+	assert.Equal(t, time.Hour, monitor.MaxDuration)
+	assert.True(t, monitor.ConsistentTimeouts)
+
+	monitor = analyzeDataRetention(logs[:7])
+	assert.False(t, monitor.ConsistentTimeouts, "a single timeout is not consistent")
+
+	count, ok := retentionDeleted(LogEntry{Message: "Deleted 42 rows from Posts"})
+	assert.True(t, ok)
+	assert.Equal(t, 42, count)
+}
+
+func TestAnalyzeFetchErrors(t *testing.T) {
+	var logs []LogEntry
+	for i := 0; i < fetchConsistentFailures; i++ {
+		logs = append(logs, LogEntry{Timestamp: mustParseTime(t, fmt.Sprintf("2024-03-01 1%d:00:00.000 Z", i)), Level: "warn",
+			Message: "Failed to get link metadata", Source: "app/post_metadata.go:520",
+			Extras: map[string]string{"requested_url": "https://intranet.example.com/page", "error": "dial tcp 10.0.0.1:443: i/o timeout"}})
+	}
+	logs = append(logs,
+		LogEntry{Timestamp: mustParseTime(t, "2024-03-01 09:00:00.000 Z"), Level: "error",
+			Message: "ImageProxy: failed to fetch image https://Images.Example.org/cat.png", Extras: map[string]string{"error": "x509: certificate signed by unknown authority"}},
+		LogEntry{Timestamp: mustParseTime(t, "2024-03-01 09:30:00.000 Z"), Level: "warn",
+			Message: "Failed to get link metadata", Extras: map[string]string{"url": "https://intranet.example.com/other"}},
+		LogEntry{Timestamp: mustParseTime(t, "2024-03-01 09:45:00.000 Z"), Level: "info",
+			Message: "Image proxy fetched https://images.example.org/dog.png"},
+		LogEntry{Timestamp: mustParseTime(t, "2024-03-01 09:50:00.000 Z"), Level: "warn", Message: "Failed to get link metadata"},
+	)
+
+	hosts := analyzeFetchErrors(logs, true, 0)
+	require.Len(t, hosts, 2)
+	intranet := hosts[0]
+	assert.Equal(t, "intranet.example.com", intranet.Host)
+	assert.Equal(t, 6, intranet.Count)
+	assert.Equal(t, []string{fetchLinkPreview}, intranet.Kinds)
+	assert.Equal(t, "timeout", intranet.Reason)
+	assert.Equal(t, "Failed to get link metadata", intranet.Example, "the example is the first failure")
+	assert.Equal(t, mustParseTime(t, "2024-03-01 09:30:00.000 Z"), intranet.FirstSeen)
+	assert.Equal(t, mustParseTime(t, "2024-03-01 14:00:00.000 Z"), intranet.LastSeen)
+	assert.True(t, intranet.Consistent)
+
+	images := hosts[1]
+	assert.Equal(t, "images.example.org", images.Host, "hosts are found in messages and lowercased")
+	assert.Equal(t, []string{fetchImageProxy}, images.Kinds)
+	assert.Equal(t, "TLS error", images.Reason)
+	assert.False(t, images.Consistent)
+
+	assert.Len(t, analyzeFetchErrors(logs, true, 1), 1)
+}
+
+func TestAnalyzeErrorCorrelations(t *testing.T) {
+	var logs []LogEntry
+	// 20 entries spread over 4 channels; the errors are all in channel c1 and of one user agent
+	for i := 0; i < 20; i++ {
+		logs = append(logs, LogEntry{Level: "info", Message: "Post created", User: fmt.Sprintf("user%d", i%5),
+			Extras: map[string]string{"channel_id": fmt.Sprintf("c%d", i%4), "request_id": fmt.Sprintf("r%d", i)}})
+	}
+	for i := 0; i < 6; i++ {
+		logs = append(logs, LogEntry{Level: "error", Message: "Failed to create post", User: fmt.Sprintf("user%d", i%3),
+			Extras: map[string]string{"channel_id": "c1", "user_agent": "Mattermost Mobile/2.1", "request_id": fmt.Sprintf("e%d", i),
+				"error": "context deadline exceeded"}})
+	}
+	logs = append(logs, LogEntry{Level: "info", Message: "Login", Extras: map[string]string{"user_agent": "Mattermost Mobile/2.1"}})
+
+	correlations := analyzeErrorCorrelations(logs, true, 0)
+	require.Len(t, correlations, 2)
+	agent := correlations[0]
+	assert.Equal(t, "user_agent", agent.Field, "ties in errors are broken by the lift")
+	assert.Equal(t, 6, agent.Errors)
+	assert.Equal(t, 7, agent.Total)
+
+	channel := correlations[1]
+	assert.Equal(t, "channel_id", channel.Field)
+	assert.Equal(t, "c1", channel.Value)
+	assert.Equal(t, 6, channel.Errors)
+	assert.Equal(t, 11, channel.Total)
+	assert.InDelta(t, 1.0, channel.ErrorShare, 0.001)
+	assert.InDelta(t, 11.0/27, channel.OverallShare, 0.001)
+	assert.Less(t, channel.Lift, agent.Lift)
+
+	for _, c := range correlations {
+		assert.NotContains(t, []string{"error", "request_id", "user_id"}, c.Field,
+			"the error itself, unique values and values as common overall are not reported")
+	}
+
+	assert.Len(t, analyzeErrorCorrelations(logs, true, 1), 1)
+	assert.Empty(t, analyzeErrorCorrelations(logs[:20], true, 0), "no errors")
+}
+
+func TestAnalyzePushProxy(t *testing.T) {
+	lines := []string{
+		`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"error","msg":"Failed to send push notification","caller":"app/notification_push.go:260","device_id":"apple_rn-v2:abc","error":"Post \"https://push.mattermost.com/api/v1/send_push\": dial tcp: i/o timeout"}`,
+		`{"timestamp":"2024-03-01 10:01:00.000 Z","level":"error","msg":"Failed to send push notification","device_id":"android_rn-v2:def","error":"push proxy responded with status code 503 from https://push.mattermost.com/api/v1/send_push"}`,
+		`{"timestamp":"2024-03-01 10:02:00.000 Z","level":"error","msg":"Failed to send push notification","device_id":"apple_rn-v2:ghi","error":"Post \"https://push.mattermost.com/api/v1/send_push\": context deadline exceeded"}`,
+		`{"timestamp":"2024-03-01 10:00:30.000 Z","level":"info","msg":"Notification sent","logSource":"notifications","ackId":"1","type":"message","status":"Sent"}`,
+		`{"timestamp":"2024-03-01 10:00:31.000 Z","level":"info","msg":"Notification received","logSource":"notifications","ackId":"1","type":"message","status":"Received"}`,
+		`{"timestamp":"2024-03-01 10:01:00.000 Z","level":"info","msg":"Notification sent","logSource":"notifications","ackId":"2","type":"message","status":"Sent"}`,
+		`{"timestamp":"2024-03-01 10:03:00.000 Z","level":"error","msg":"Notification error","logSource":"notifications","ackId":"3","type":"message","status":"Error","deviceId":"apple:jkl"}`,
+		`{"timestamp":"2024-03-01 12:00:00.000 Z","level":"error","msg":"Notification error","logSource":"notifications","ackId":"4","type":"message","status":"Error"}`,
+		`{"timestamp":"2024-03-01 12:00:00.000 Z","level":"info","msg":"Push notification settings updated"}`,
+	}
+	var logs []LogEntry
+	for _, line := range lines {
+		entry, err := parseJSONLine(line)
+		require.NoError(t, err)
+		logs = append(logs, entry)
+	}
+
+	push := analyzePushProxy(logs)
+	assert.Equal(t, 3, push.Failures)
+	assert.Equal(t, mustParseTime(t, "2024-03-01 10:00:00.000 Z"), push.FirstFailure)
+	assert.Equal(t, mustParseTime(t, "2024-03-01 10:02:00.000 Z"), push.LastFailure)
+	assert.Equal(t, []CountedItem{{Item: "push.mattermost.com", Count: 3}}, push.Hosts)
+	assert.ElementsMatch(t, []CountedItem{{Item: "timeout", Count: 2}, {Item: "HTTP 503", Count: 1}}, push.ErrorCodes)
+	assert.Equal(t, []CountedItem{{Item: "iOS", Count: 3}, {Item: "Android", Count: 1}}, push.Platforms)
+	assert.Equal(t, 2, push.Sent)
+	assert.Equal(t, 1, push.Received)
+	assert.Equal(t, 2, push.Undelivered)
+	assert.Equal(t, 1, push.UndeliveredNearFail, "only the error close to the proxy failures is correlated")
+
+	assert.Zero(t, analyzePushProxy(logs[len(logs)-1:]).Failures, "info entries are not failures")
+
+	var out bytes.Buffer
+	displayPushProxy(LogAnalysis{PushProxy: push}, &out, false)
+	assert.Contains(t, ansiEscape.ReplaceAllString(out.String(), ""), "  Failures: 3 contacting push.mattermost.com 3 • timeout 2, HTTP 503 1\n")
+
+	out.Reset()
+	displayPushProxy(LogAnalysis{PushProxy: analyzePushProxy(logs[3:6])}, &out, false)
+	assert.NotContains(t, out.String(), "Failures", "delivery counts are reported without failures")
+	assert.Contains(t, out.String(), "  Delivery: 2 sent → 1 received (50.0%)")
+}
+
+func TestAnalyzeNotificationLatency(t *testing.T) {
+	var logs []LogEntry
+	notification := func(ackID, status, platform string, at time.Time) LogEntry {
+		return LogEntry{Timestamp: at, Level: "info", LogSource: "notifications", AckID: ackID, Status: status,
+			Extras: map[string]string{"platform": platform}}
+	}
+	start := mustParseTime(t, "2024-03-01 10:00:00.000 Z")
+	// An hour of iOS notifications taking 200ms, slowed down to 5s between 10:30 and 10:40
+	for i := 0; i < 60; i++ {
+		sent := start.Add(time.Duration(i) * time.Minute)
+		latency := 200 * time.Millisecond
+		if i >= 30 && i < 40 {
+			latency = 5 * time.Second
+		}
+		ackID := fmt.Sprintf("ios-%d", i)
+		logs = append(logs, notification(ackID, "Sent", "ios", sent), notification(ackID, "Received", "", sent.Add(latency)))
+	}
+	logs = append(logs,
+		notification("android-1", "Sent", "android", start), notification("android-1", "Received", "android", start.Add(time.Second)),
+		notification("android-2", "Sent", "android", start), // Never received
+	)
+
+	latency := analyzeNotificationLatency(logs)
+	require.Len(t, latency.Platforms, 2)
+	ios := latency.Platforms[0]
+	assert.Equal(t, "iOS", ios.Platform)
+	assert.Equal(t, 60, ios.Count)
+	assert.Equal(t, 200*time.Millisecond, ios.Median)
+	assert.Equal(t, 5*time.Second, ios.P95)
+	assert.Equal(t, 50, ios.Histogram[1], "≤500ms")
+	assert.Equal(t, 10, ios.Histogram[4], "≤5s")
+	assert.Equal(t, PlatformLatency{Platform: "Android", Count: 1, Median: time.Second, P95: time.Second, Max: time.Second,
+		Histogram: []int{0, 0, 1, 0, 0, 0, 0, 0}}, latency.Platforms[1])
+
+	require.Len(t, latency.Degraded, 1)
+	assert.Equal(t, LatencyDegradation{Platform: "iOS", Start: start.Add(30 * time.Minute), End: start.Add(39 * time.Minute),
+		Count: 10, Median: 5 * time.Second}, latency.Degraded[0])
+
+	assert.Empty(t, analyzeNotificationLatency(logs[:1]).Platforms, "sent but not received")
+}
+
+func TestLogStats(t *testing.T) {
+	initLogger()
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2025-01-01 10:05:00.000 Z"), Level: "info", Message: "User logged in", Source: "app/login.go", User: "alice"},
+		{Timestamp: mustParseTime(t, "2025-01-01 09:00:00.000 Z"), Level: "error", Message: "Failed to ping DB", Source: "sqlstore/store.go", DuplicateCount: 3},
+		{Timestamp: mustParseTime(t, "2025-01-01 11:30:00.000 Z"), Level: "error", Message: "Failed to ping DB", Source: "sqlstore/store.go",
+			Extras: map[string]string{"ip_address": "10.0.0.1"}},
+	}
+
+	// Entries streamed out of order give the same statistics as analyzeLogs
+	stats := newLogStats(true)
+	for _, log := range logs {
+		stats.add(log)
+	}
+	streamed := stats.result(5)
+	analysis := analyzeLogs(logs, true, 5)
+
+	assert.Equal(t, 5, streamed.TotalEntries)
+	assert.Equal(t, analysis.TotalEntries, streamed.TotalEntries)
+	assert.Equal(t, TimeRange{Start: logs[1].Timestamp, End: logs[2].Timestamp}, streamed.TimeRange)
+	assert.Equal(t, analysis.LevelCounts, streamed.LevelCounts)
+	assert.InDelta(t, 80.0, streamed.ErrorRate, 0.01)
+	assert.Equal(t, analysis.TopSources, streamed.TopSources)
+	assert.Equal(t, analysis.TopErrorIPs, streamed.TopErrorIPs)
+	assert.Equal(t, analysis.HourLevelCounts, streamed.HourLevelCounts)
+	assert.Empty(t, streamed.ErrorSignatures, "analyses needing all entries are left out")
+}
+
+func TestActivityBuckets(t *testing.T) {
+	initLogger()
+	// ISO 8601 weeks start on Monday, and the first days of January can belong to the last
+	// week of the previous year
+	assert.Equal(t, "2020-W53", activityBucketLabel(mustParseTime(t, "2021-01-03 12:00:00.000 Z"), "week"))
+	assert.Equal(t, "2021-W01", activityBucketLabel(mustParseTime(t, "2021-01-04 12:00:00.000 Z"), "week"))
+	assert.Equal(t, "2021-01-04 12:00", activityBucketLabel(mustParseTime(t, "2021-01-04 12:34:00.000 Z"), "hour"))
+	assert.Equal(t, "2021-01-04", activityBucketLabel(mustParseTime(t, "2021-01-04 12:34:00.000 Z"), "day"))
+	assert.Equal(t, "2021-01", activityBucketLabel(mustParseTime(t, "2021-01-04 12:34:00.000 Z"), "month"))
+	assert.Equal(t, "2021-02", activityBucketLabel(nextActivityBucket(mustParseTime(t, "2021-01-31 12:00:00.000 Z"), "month"), "month"))
+
+	assert.NoError(t, validateActivityBucket(""))
+	assert.NoError(t, validateActivityBucket("week"))
+	assert.ErrorContains(t, validateActivityBucket("fortnight"), "invalid --bucket")
+
+	activityBucket = "week"
+	t.Cleanup(func() { activityBucket = "" })
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2021-01-12 10:00:00.000 Z"), Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: mustParseTime(t, "2021-01-03 10:00:00.000 Z"), Level: "info", Message: "Server started"},
+		{Timestamp: mustParseTime(t, "2021-01-13 10:00:00.000 Z"), Level: "error", Message: "Failed to ping DB", DuplicateCount: 2},
+	}
+	analysis := analyzeLogs(logs, true, 10)
+	assert.Equal(t, "week", analysis.ActivityBucket)
+	assert.Equal(t, []CountedItem{{Item: "2020-W53", Count: 1}, {Item: "2021-W02", Count: 3}}, analysis.Activity)
+	assert.Equal(t, map[string]int{"ERROR": 3}, analysis.ActivityLevelCounts["2021-W02"])
+
+	var buf bytes.Buffer
+	displayAnalysis(analysis, &buf, false, len(logs), true, false)
+	output := buf.String()
+	assert.Contains(t, output, "Activity by Week:")
+	assert.Contains(t, output, "2020-W53: ")
+	assert.NotContains(t, output, "Activity by Hour:")
+
+	buf.Reset()
+	displayAnalysis(analysis, &buf, false, len(logs), false, false)
+	assert.Contains(t, buf.String(), "Peak Weeks:\033[0m 2021-W02(3) • 2020-W53(1)")
+}
+
+func TestParseAIFindings(t *testing.T) {
+	text := "## Summary\n\nDatabase errors.\n\n```json\n" +
+		`{"findings": [{"title": "Connection pool exhausted", "severity": "error", "summary": "Queries time out.", "evidence": [2, 3]}]}` +
+		"\n```\n"
+
+	report, findings, err := parseAIFindings(text)
+	require.NoError(t, err)
+	assert.Equal(t, "## Summary\n\nDatabase errors.", report)
+	require.Len(t, findings, 1)
+	assert.Equal(t, AIFinding{Title: "Connection pool exhausted", Severity: "error", Summary: "Queries time out.", Evidence: []int{2, 3}}, findings[0])
+
+	// Without a findings block the whole text is the report
+	report, findings, err = parseAIFindings("## Summary")
+	require.NoError(t, err)
+	assert.Equal(t, "## Summary", report)
+	assert.Empty(t, findings)
+
+	_, _, err = parseAIFindings("```json\n{\"findings\": [\n```")
+	assert.Error(t, err)
+	_, _, err = parseAIFindings("```json\n{}")
+	assert.Error(t, err)
+}
+
+func TestWithCitedEvidence(t *testing.T) {
+	initLogger()
+	analyzed := []LogEntry{
+		{Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Level: "info", Message: "Server started"},
+		{Raw: `{"level":"error","msg":"Failed to ping DB"}`, SourceFile: "mattermost.log", Line: 812},
+		{Timestamp: mustParseTime(t, "2024-03-01 10:00:05.000 Z"), Level: "error", Message: "Failed to ping DB"},
+	}
+	text := "## Summary\n\nThe database is unreachable.\n\n```json\n" +
+		`{"findings": [{"title": "Database unreachable", "severity": "error", "summary": "Pings fail.", "evidence": [2, 3, 7]},` +
+		` {"title": "Disk full", "severity": "warning", "summary": "Guessed.", "evidence": []}]}` +
+		"\n```\n"
+
+	assert.Equal(t, `## Summary
+
+The database is unreachable.
+
+## AI Findings
+
+### 1. [ERROR] Database unreachable
+
+Pings fail.
+
+> Cited entries that were not analyzed, ignored: 7
+
+Evidence:
+
+`+"```"+`
+2. mattermost.log:812: {"level":"error","msg":"Failed to ping DB"}
+3. 2024-03-01 10:00:05.000 [error] Failed to ping DB
+`+"```"+`
+
+### 2. [WARNING] Disk full
+
+Guessed.
+
+> **Unverified:** this finding cites no analyzed log entry.
+`, withCitedEvidence(text, analyzed))
+
+	// Without findings the report is kept, and a malformed block is left as is
+	assert.Equal(t, "## Summary", withCitedEvidence("## Summary", analyzed))
+	assert.Equal(t, "Report\n```json\n{\"findings\": [\n```", withCitedEvidence("Report\n```json\n{\"findings\": [\n```", analyzed))
+}
+
+func TestLogsForAnalysis(t *testing.T) {
+	logs := make([]LogEntry, defaultMaxLogEntries+5)
+	for i := range logs {
+		logs[i].Message = fmt.Sprintf("entry %d", i)
+	}
+
+	assert.Len(t, logsForAnalysis(logs, 0), defaultMaxLogEntries)
+	recent := logsForAnalysis(logs, 3)
+	require.Len(t, recent, 3)
+	assert.Equal(t, "entry 102", recent[0].Message)
+	assert.Len(t, logsForAnalysis(logs[:2], 3), 2)
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules("team.rules", `# Timeouts talking to the database
+when level == "ERROR" and (message contains "deadline exceeded" or message matches "timeout after \\d+s")
+then finding("Database timeouts", severity="error", summary="Check the database load", min_count=2)
+
+when not source startswith "app/" and extras.plugin_id == "com.acme.sync" then finding(title="Sync plugin", id="acme/sync")
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "Database timeouts", rules[0].Title)
+	assert.Equal(t, "database-timeouts", rules[0].ID, "the ID defaults to the title in kebab case")
+	assert.Equal(t, "error", rules[0].Severity)
+	assert.Equal(t, "Check the database load", rules[0].Summary)
+	assert.Equal(t, 2, rules[0].MinCount)
+	assert.Equal(t, "team.rules:2", rules[0].Origin)
+	assert.Equal(t, "acme/sync", rules[1].ID)
+	assert.Equal(t, "warning", rules[1].Severity, "severity defaults to warning")
+	assert.Equal(t, 1, rules[1].MinCount)
+	assert.Equal(t, "team.rules:5", rules[1].Origin)
+
+	assert.True(t, rules[0].cond.match(LogEntry{Level: "error", Message: "query: context deadline exceeded"}), "levels are compared in lowercase")
+	assert.True(t, rules[0].cond.match(LogEntry{Level: "error", Message: "timeout after 30s"}))
+	assert.False(t, rules[0].cond.match(LogEntry{Level: "warn", Message: "timeout after 30s"}))
+	assert.True(t, rules[1].cond.match(LogEntry{Source: "plugins/sync.go:1", Extras: map[string]string{"plugin_id": "com.acme.sync"}}))
+	assert.False(t, rules[1].cond.match(LogEntry{Source: "app/sync.go:1", Extras: map[string]string{"plugin_id": "com.acme.sync"}}))
+
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{`when level == "error"`, `team.rules:1: expected "then", found end of file`},
+		{`when lvl == "error" then finding("X")`, `team.rules:1: unknown field "lvl"`},
+		{`when level is "error" then finding("X")`, `team.rules:1: expected ==, !=, contains, startswith, endswith or matches, found "is"`},
+		{"when level == \"error\"\nthen finding(severity=\"fatal\", title=\"X\")", `team.rules:2: invalid severity "fatal"`},
+		{`when message matches "(" then finding("X")`, `team.rules:1: invalid regular expression`},
+		{`when level == "error" then finding(summary="no title")`, `team.rules:1: the finding has no title`},
+		{`when message contains "bad \q" then finding("X")`, `team.rules:1: invalid string`},
+		{"when message contains \"unterminated\nthen finding(\"X\")", `team.rules:1: unterminated string`},
+		{`when level == "error" then finding("X", count=2)`, `team.rules:1: unknown argument "count"`},
+	} {
+		_, err := parseRules("team.rules", tc.src)
+		assert.ErrorContains(t, err, tc.err, tc.src)
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	rules, err := parseRules("team.rules", `
+when message contains "timeout" then finding("Timeouts", min_count=3)
+when level == "error" then finding("Errors", severity="error")
+when message contains "missing" then finding("Never matched")
+`)
+	require.NoError(t, err)
+	logs := []LogEntry{
+		{Timestamp: ts.Add(time.Minute), Level: "error", Message: "db timeout"},
+		{Timestamp: ts, Level: "warn", Message: "api timeout", DuplicateCount: 2},
+		{Timestamp: ts.Add(2 * time.Minute), Level: "info", Message: "ok"},
+	}
+
+	findings := evaluateRules(rules, logs, true)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "Errors", findings[0].Title, "most severe first")
+	assert.Equal(t, RuleFinding{
+		ID: "timeouts", Title: "Timeouts", Severity: "warning", Origin: "team.rules:2", Count: 3,
+		FirstSeen: ts, LastSeen: ts.Add(time.Minute), Example: "db timeout",
+		Evidence: logs[:2],
+	}, findings[1])
+
+	// Without counting duplicates the timeouts are below min_count
+	findings = evaluateRules(rules, logs, false)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "Errors", findings[0].Title)
+
+	var out bytes.Buffer
+	displayRuleFindings(LogAnalysis{RuleFindings: findings}, &out, false)
+	assert.Contains(t, out.String(), "[error]\033[0m Errors (1) • first 2025-01-01 10:01:00")
+}
+
+func TestLoadAnalysisRules(t *testing.T) {
+	initLogger()
+	dir := useTempConfigDir(t)
+	t.Cleanup(func() { analysisRules = nil })
+	configDir := filepath.Dir(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, "rules"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "rules", "team.rules"), []byte(`when level == "error" then finding("Team")`), 0o644))
+	require.NoError(t, writeConfigFile(Config{Rules: []string{"rules/*.rules"}}, dir))
+
+	extra := filepath.Join(t.TempDir(), "extra.rules")
+	require.NoError(t, os.WriteFile(extra, []byte(`when level == "warn" then finding("Extra")`), 0o644))
+
+	require.NoError(t, loadAnalysisRules([]string{extra}))
+	require.Len(t, analysisRules, 2)
+	assert.Equal(t, "Team", analysisRules[0].Title)
+	assert.Equal(t, "Extra", analysisRules[1].Title)
+
+	assert.ErrorContains(t, loadAnalysisRules([]string{filepath.Join(t.TempDir(), "missing.rules")}), "error loading analysis rules")
+}
+
+func TestProposedChanges(t *testing.T) {
+	initLogger()
+	analysis := "## Summary\n\nThe connection pool is exhausted.\n\n```proposed-changes\n" +
+		`{"patch": {"SqlSettings": {"MaxOpenConns": 300, "MaxOpenConnections": 300}}, "steps": ["System Console > Environment > Database > Maximum Open Connections: set to 300"]}` +
+		"\n```\n\n```json\n{\"findings\": []}\n```"
+
+	report, changes := splitProposedChanges(analysis)
+	require.NotNil(t, changes)
+	assert.Equal(t, "## Summary\n\nThe connection pool is exhausted.\n\n```json\n{\"findings\": []}\n```", report)
+
+	markdown := proposedChangesMarkdown(changes, `{"SqlSettings":{"MaxOpenConns":100,"MaxIdleConns":20}}`)
+	assert.Equal(t, "## Proposed changes\n\n"+
+		"Apply this patch of config.json with `mmctl config patch <file>`, or follow the System Console steps.\n\n"+
+		"```json\n{\n  \"SqlSettings\": {\n    \"MaxOpenConnections\": 300,\n    \"MaxOpenConns\": 300\n  }\n}\n```\n\n"+
+		"> **Check before applying:** the server configuration has no SqlSettings.MaxOpenConnections.\n\n"+
+		"System Console steps:\n\n1. System Console > Environment > Database > Maximum Open Connections: set to 300\n", markdown)
+
+	file := filepath.Join(t.TempDir(), "patch.json")
+	require.NoError(t, saveProposedChanges(changes, file))
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"SqlSettings\": {\n    \"MaxOpenConnections\": 300,\n    \"MaxOpenConns\": 300\n  }\n}\n", string(data))
+
+	// Analyses without changes or with an invalid block are kept as they are
+	report, changes = splitProposedChanges("No configuration change needed.")
+	assert.Nil(t, changes)
+	assert.Equal(t, "No configuration change needed.", report)
+	invalid := "Report\n\n```proposed-changes\n{\"patch\": \n```"
+	report, changes = splitProposedChanges(invalid)
+	assert.Nil(t, changes)
+	assert.Equal(t, invalid, report)
+}
+
+// fullGoroutineDump is a goroutine dump written with debug=2
+const fullGoroutineDump = `goroutine 1 [chan receive, 12 minutes]:
+github.com/mattermost/mattermost/server/v8/cmd/mattermost/commands.runServer(0xc000123)
+	/mattermost/server/cmd/mattermost/commands/server.go:120 +0x5a5
+main.main()
+	/mattermost/server/cmd/mattermost/main.go:20 +0x1c
+
+goroutine 40 [select]:
+database/sql.(*DB).connectionOpener(0xc0001, {0x1, 0x2})
+	/usr/local/go/src/database/sql/sql.go:1218 +0x87
+created by database/sql.OpenDB in goroutine 1
+	/usr/local/go/src/database/sql/sql.go:791 +0x165
+
+goroutine 41 [select]:
+database/sql.(*DB).connectionOpener(0xc0002, {0x1, 0x2})
+	/usr/local/go/src/database/sql/sql.go:1218 +0x87
+created by database/sql.OpenDB in goroutine 1
+	/usr/local/go/src/database/sql/sql.go:791 +0x165
+
+goroutine 42 [IO wait, 3 minutes]:
+internal/poll.runtime_pollWait(0x7f, 0x72)
+	/usr/local/go/src/runtime/netpoll.go:345 +0x85
+`
+
+// countedGoroutineDump is a goroutine profile written with debug=1
+const countedGoroutineDump = `goroutine profile: total 7
+5 @ 0x43a0c5 0x40b2bc
+#	0x4a1b2c	database/sql.(*DB).connectionOpener+0x87	/usr/local/go/src/database/sql/sql.go:1218
+
+2 @ 0x43a0c5
+#	0x4606b0	runtime/pprof.writeRuntimeProfile+0xb0	/usr/local/go/src/runtime/pprof/pprof.go:738
+#	0x460690	runtime/pprof.writeGoroutine+0x4c	/usr/local/go/src/runtime/pprof/pprof.go:700
+`
+
+func TestParseGoroutineDump(t *testing.T) {
+	t.Run("full dump", func(t *testing.T) {
+		dump, err := parseGoroutineDump(strings.NewReader(fullGoroutineDump))
+		require.NoError(t, err)
+		assert.Equal(t, 4, dump.Total)
+		assert.Equal(t, []CountedItem{{"select", 2}, {"IO wait", 1}, {"chan receive", 1}}, dump.States)
+		require.Len(t, dump.TopStacks, 3)
+		assert.Equal(t, GoroutineStack{
+			Count:  2,
+			State:  "select",
+			Frames: []string{"sql.(*DB).connectionOpener", "created by sql.OpenDB"},
+		}, dump.TopStacks[0])
+	})
+
+	t.Run("counted dump", func(t *testing.T) {
+		dump, err := parseGoroutineDump(strings.NewReader(countedGoroutineDump))
+		require.NoError(t, err)
+		assert.Equal(t, 7, dump.Total)
+		assert.Empty(t, dump.States)
+		require.Len(t, dump.TopStacks, 2)
+		assert.Equal(t, 5, dump.TopStacks[0].Count)
+		assert.Equal(t, []string{"pprof.writeRuntimeProfile", "pprof.writeGoroutine"}, dump.TopStacks[1].Frames)
+	})
+
+	t.Run("binary profile", func(t *testing.T) {
+		_, err := parseGoroutineDump(strings.NewReader("\x1f\x8b\x08\x00binary"))
+		assert.Error(t, err)
+	})
+}
+
+func TestReadPacketDiagnostics(t *testing.T) {
+	initLogger()
+	path := writePacket(t, map[string]string{
+		"packet/node-1/mattermost.log":        "",
+		"packet/node-1/metadata.json":         `{"server_version": "10.2.0"}`,
+		"packet/node-1/diagnostics.yaml":      "os: linux\n",
+		"packet/node-1/goroutines":            fullGoroutineDump,
+		"packet/node-1/heap.prof":             "\x1f\x8b",
+		"packet/node-1/cpu.prof":              "\x1f\x8b",
+		"packet/node-1/sanitized_config.json": "{}",
+	})
+	diagnostics, err := readPacketDiagnostics(path)
+	require.NoError(t, err)
+
+	kinds := make(map[string]string)
+	for _, file := range diagnostics.Files {
+		kinds[file.Name] = file.Kind
+		assert.Equal(t, "node-1", file.Node)
+	}
+	assert.Equal(t, map[string]string{
+		"packet/node-1/metadata.json":    "metadata",
+		"packet/node-1/diagnostics.yaml": "diagnostics",
+		"packet/node-1/goroutines":       "goroutines",
+		"packet/node-1/heap.prof":        "heap profile",
+		"packet/node-1/cpu.prof":         "cpu profile",
+	}, kinds)
+	require.Len(t, diagnostics.Goroutines, 1)
+	assert.Equal(t, 4, diagnostics.Goroutines[0].Total)
+
+	var buf bytes.Buffer
+	displayPacketDiagnostics(diagnostics, &buf, false)
+	assert.Contains(t, buf.String(), "SUPPORT PACKET FILES")
+	assert.Contains(t, buf.String(), "select(2) • IO wait(1) • chan receive(1)")
+	assert.Contains(t, buf.String(), "2× [select] sql.(*DB).connectionOpener ← created by sql.OpenDB")
+
+	summary := summarizePacketDiagnostics(diagnostics)
+	require.Len(t, summary, 2)
+	assert.Contains(t, summary[1], "4 goroutines, 2 of them in select")
+}
+
+func TestClockSkew(t *testing.T) {
+	initLogger()
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	entry := func(node string, offset time.Duration, message, requestID string) LogEntry {
+		log := LogEntry{Timestamp: start.Add(offset), Level: "info", Message: message, Node: node}
+		if requestID != "" {
+			log.Extras = map[string]string{"request_id": requestID}
+		}
+		return log
+	}
+	// The clock of node2 is 2.5 seconds ahead, node3 only shares a restart with node1
+	logs := []LogEntry{
+		entry("node1", 0, "Server is initializing...", ""),
+		entry("node1", time.Minute, "Received HTTP request", "a"),
+		entry("node1", 2*time.Minute, "Received HTTP request", "b"),
+		entry("node1", 3*time.Minute, "Received HTTP request", "c"),
+		entry("node1", 4*time.Minute, "Ping", ""),
+		entry("node2", time.Minute+2500*time.Millisecond, "Forwarded HTTP request", "a"),
+		entry("node2", 2*time.Minute+2400*time.Millisecond, "Forwarded HTTP request", "b"),
+		entry("node2", 3*time.Minute+2600*time.Millisecond, "Forwarded HTTP request", "c"),
+		entry("node3", 200*time.Millisecond, "Server is initializing...", ""),
+	}
+
+	skews := estimateClockSkew(logs)
+	require.Len(t, skews, 2)
+	assert.Equal(t, ClockSkew{Node: "node2", Reference: "node1", Offset: 2500 * time.Millisecond, Samples: 3, Method: "request_id"}, skews[0])
+	assert.Equal(t, ClockSkew{Node: "node3", Reference: "node1", Offset: 200 * time.Millisecond, Samples: 1, Method: "start"}, skews[1])
+	assert.True(t, skews[0].unreliable())
+	assert.False(t, skews[1].unreliable())
+
+	var buf bytes.Buffer
+	displayClockSkew(LogAnalysis{ClockSkews: skews}, &buf, false)
+	assert.Contains(t, buf.String(), "node2 +2.5s vs node1 (order across nodes unreliable)")
+	buf.Reset()
+	displayClockSkew(LogAnalysis{ClockSkews: skews}, &buf, true)
+	assert.Contains(t, buf.String(), "node3: +200ms (1 server start)")
+
+	assert.Empty(t, estimateClockSkew(logs[:5]), "a single node has no skew")
+
+	// The correction moves the entries of node2 back next to the same requests of node1
+	handleClockSkew(logs, true)
+	for i, log := range logs {
+		if i > 0 {
+			assert.False(t, log.Timestamp.Before(logs[i-1].Timestamp), "the entries are sorted again")
+		}
+		if log.Node == "node2" && log.Extras["request_id"] == "a" {
+			assert.Equal(t, mustParseTime(t, "2025-01-01 10:01:00.000 Z"), log.Timestamp)
+		}
+	}
+	for _, skew := range estimateClockSkew(logs) {
+		assert.False(t, skew.unreliable(), skew.Node)
+	}
+}
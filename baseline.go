@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// baselineVersion is the version of the baseline file format
+	baselineVersion = 1
+	// baselineZThreshold is the z-score above which a deviation is considered significant
+	baselineZThreshold = 3.0
+	// baselineMinCount is the minimum number of occurrences before a signature spike is reported
+	baselineMinCount = 5
+	// baselineMinLatencySamples is the minimum number of latency samples needed for a comparison
+	baselineMinLatencySamples = 10
+)
+
+// latencyExtraKeys lists the extras keys that may hold a request latency
+var latencyExtraKeys = []string{"duration", "elapsed", "latency", "took"}
+
+// Baseline is a stored profile of a healthy log used for anomaly detection
+type Baseline struct {
+	Version         int            `json:"version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	Files           []string       `json:"files,omitempty"`
+	Start           time.Time      `json:"start"`
+	End             time.Time      `json:"end"`
+	TotalEntries    int            `json:"total_entries"`
+	ErrorEntries    int            `json:"error_entries"`
+	LevelCounts     map[string]int `json:"level_counts"`
+	ErrorSignatures map[string]int `json:"error_signatures"`
+	Latency         LatencyProfile `json:"latency"`
+}
+
+// LatencyProfile summarizes latency values (in milliseconds) found in log extras
+type LatencyProfile struct {
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean_ms"`
+	StdDev  float64 `json:"stddev_ms"`
+	P95     float64 `json:"p95_ms"`
+}
+
+// BaselineDeviation describes a statistically significant difference from a baseline
+type BaselineDeviation struct {
+	Kind     string  // "error_rate", "new_signature", "signature_spike" or "latency"
+	Item     string  // Signature or metric the deviation refers to
+	Baseline float64 // Baseline value (rate per hour, percentage or milliseconds)
+	Current  float64 // Current value in the same unit
+	Score    float64 // z-score of the deviation (0 for new signatures)
+}
+
+// buildBaseline creates a baseline profile from log entries
+func buildBaseline(logs []LogEntry, files []string) Baseline {
+	baseline := Baseline{
+		Version:         baselineVersion,
+		CreatedAt:       time.Now().UTC(),
+		Files:           files,
+		LevelCounts:     make(map[string]int),
+		ErrorSignatures: make(map[string]int),
+	}
+
+	var latencies []float64
+	for i, log := range logs {
+		count := log.DuplicateCount
+		if count < 1 {
+			count = 1
+		}
+
+		if i == 0 || log.Timestamp.Before(baseline.Start) {
+			baseline.Start = log.Timestamp
+		}
+		if i == 0 || log.Timestamp.After(baseline.End) {
+			baseline.End = log.Timestamp
+		}
+
+		baseline.TotalEntries += count
+		baseline.LevelCounts[strings.ToUpper(log.Level)] += count
+
+		if isErrorLevel(log.Level) {
+			baseline.ErrorEntries += count
+			baseline.ErrorSignatures[normalizeLogMessage(log.Message)] += count
+		}
+
+		if latency, ok := entryLatency(log); ok {
+			latencies = append(latencies, latency)
+		}
+	}
+
+	baseline.Latency = buildLatencyProfile(latencies)
+	return baseline
+}
+
+// durationHours returns the span covered by the baseline in hours (at least one minute)
+func (b Baseline) durationHours() float64 {
+	return spanHours(b.Start, b.End)
+}
+
+// spanHours returns the hours between start and end, with a floor of one minute
+// so rates stay finite for logs that cover a single instant
+func spanHours(start, end time.Time) float64 {
+	return math.Max(end.Sub(start).Hours(), 1.0/60)
+}
+
+// isErrorLevel reports whether a log level counts as an error
+func isErrorLevel(level string) bool {
+	return strings.EqualFold(level, "error") || strings.EqualFold(level, "fatal")
+}
+
+// entryLatency extracts a latency value in milliseconds from the extras of a log entry.
+// Values with a unit (e.g. "150ms", "1.2s") are parsed as durations, plain numbers are
+// taken as milliseconds.
+func entryLatency(log LogEntry) (float64, bool) {
+	for _, key := range latencyExtraKeys {
+		value, ok := log.Extras[key]
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, "\"")
+		if d, err := time.ParseDuration(value); err == nil {
+			return float64(d) / float64(time.Millisecond), true
+		}
+		if ms, err := strconv.ParseFloat(value, 64); err == nil {
+			return ms, true
+		}
+	}
+	return 0, false
+}
+
+// buildLatencyProfile computes summary statistics for latency samples
+func buildLatencyProfile(latencies []float64) LatencyProfile {
+	profile := LatencyProfile{Samples: len(latencies)}
+	if len(latencies) == 0 {
+		return profile
+	}
+
+	sum := 0.0
+	for _, l := range latencies {
+		sum += l
+	}
+	profile.Mean = sum / float64(len(latencies))
+
+	variance := 0.0
+	for _, l := range latencies {
+		variance += (l - profile.Mean) * (l - profile.Mean)
+	}
+	profile.StdDev = math.Sqrt(variance / float64(len(latencies)))
+
+	sorted := append([]float64(nil), latencies...)
+	sort.Float64s(sorted)
+	profile.P95 = sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+
+	return profile
+}
+
+// saveBaseline writes a baseline profile to a JSON file
+func saveBaseline(baseline Baseline, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(baseline)
+}
+
+// loadBaseline reads a baseline profile from a JSON file
+func loadBaseline(filePath string) (Baseline, error) {
+	var baseline Baseline
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return baseline, err
+	}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return baseline, fmt.Errorf("invalid baseline file: %v", err)
+	}
+	if baseline.Version != baselineVersion {
+		return baseline, fmt.Errorf("unsupported baseline version %d (expected %d)", baseline.Version, baselineVersion)
+	}
+	return baseline, nil
+}
+
+// compareWithBaseline returns the statistically significant deviations of the
+// current logs from the baseline, most significant first
+func compareWithBaseline(baseline Baseline, logs []LogEntry) []BaselineDeviation {
+	current := buildBaseline(logs, nil)
+	var deviations []BaselineDeviation
+
+	// Overall error rate (two-proportion z-test)
+	if baseline.TotalEntries > 0 && current.TotalEntries > 0 {
+		p1 := float64(baseline.ErrorEntries) / float64(baseline.TotalEntries)
+		p2 := float64(current.ErrorEntries) / float64(current.TotalEntries)
+		pooled := float64(baseline.ErrorEntries+current.ErrorEntries) / float64(baseline.TotalEntries+current.TotalEntries)
+		se := math.Sqrt(pooled * (1 - pooled) * (1/float64(baseline.TotalEntries) + 1/float64(current.TotalEntries)))
+		if se > 0 {
+			if z := (p2 - p1) / se; z >= baselineZThreshold {
+				deviations = append(deviations, BaselineDeviation{
+					Kind: "error_rate", Item: "error rate",
+					Baseline: p1 * 100, Current: p2 * 100, Score: z,
+				})
+			}
+		}
+	}
+
+	// Error signatures (Poisson rate comparison)
+	baseHours := baseline.durationHours()
+	curHours := current.durationHours()
+	for signature, count := range current.ErrorSignatures {
+		baseCount, known := baseline.ErrorSignatures[signature]
+		if !known {
+			deviations = append(deviations, BaselineDeviation{
+				Kind: "new_signature", Item: signature,
+				Current: float64(count) / curHours,
+			})
+			continue
+		}
+
+		expected := float64(baseCount) / baseHours * curHours
+		z := (float64(count) - expected) / math.Sqrt(math.Max(expected, 1))
+		if count >= baselineMinCount && z >= baselineZThreshold {
+			deviations = append(deviations, BaselineDeviation{
+				Kind: "signature_spike", Item: signature,
+				Baseline: float64(baseCount) / baseHours, Current: float64(count) / curHours, Score: z,
+			})
+		}
+	}
+
+	// Latency (Welch z-test on means)
+	bl, cl := baseline.Latency, current.Latency
+	if bl.Samples >= baselineMinLatencySamples && cl.Samples >= baselineMinLatencySamples {
+		se := math.Sqrt(bl.StdDev*bl.StdDev/float64(bl.Samples) + cl.StdDev*cl.StdDev/float64(cl.Samples))
+		if se > 0 {
+			if z := (cl.Mean - bl.Mean) / se; z >= baselineZThreshold {
+				deviations = append(deviations, BaselineDeviation{
+					Kind: "latency", Item: "mean latency",
+					Baseline: bl.Mean, Current: cl.Mean, Score: z,
+				})
+			}
+		}
+	}
+
+	// Scored deviations first (by z-score), then new signatures (by rate)
+	sort.SliceStable(deviations, func(i, j int) bool {
+		newI, newJ := deviations[i].Kind == "new_signature", deviations[j].Kind == "new_signature"
+		if newI != newJ {
+			return !newI
+		}
+		if newI {
+			return deviations[i].Current > deviations[j].Current
+		}
+		return deviations[i].Score > deviations[j].Score
+	})
+
+	return deviations
+}
+
+// displayBaselineComparison prints the deviations from a baseline
+func displayBaselineComparison(baseline Baseline, deviations []BaselineDeviation, writer io.Writer) {
+	_, _ = fmt.Fprintf(writer, "%sBASELINE COMPARISON%s\n", colorHeaderBold, colorReset)
+	_, _ = fmt.Fprintf(writer, "Baseline: %d entries • %s to %s\n",
+		baseline.TotalEntries,
+		baseline.Start.Format("2006-01-02 15:04:05"),
+		baseline.End.Format("2006-01-02 15:04:05"))
+
+	if len(deviations) == 0 {
+		_, _ = fmt.Fprintln(writer, "No significant deviations from baseline")
+		_, _ = fmt.Fprintln(writer)
+		return
+	}
+
+	for _, d := range deviations {
+		switch d.Kind {
+		case "error_rate":
+			_, _ = fmt.Fprintf(writer, "%sError rate:%s %.1f%% → %.1f%% (z=%.1f)\n",
+				colorSubHeader, colorReset, d.Baseline, d.Current, d.Score)
+		case "latency":
+			_, _ = fmt.Fprintf(writer, "%sLatency:%s mean %.0fms → %.0fms (z=%.1f)\n",
+				colorSubHeader, colorReset, d.Baseline, d.Current, d.Score)
+		case "signature_spike":
+			_, _ = fmt.Fprintf(writer, "%sSpike:%s %s (%.1f/h → %.1f/h, z=%.1f)\n",
+				colorSubHeader, colorReset, truncateString(d.Item, 80), d.Baseline, d.Current, d.Score)
+		case "new_signature":
+			_, _ = fmt.Fprintf(writer, "%sNew:%s %s%s%s (%.1f/h)\n",
+				colorSubHeader, colorReset, colorRed, truncateString(d.Item, 80), colorReset, d.Current)
+		}
+	}
+	_, _ = fmt.Fprintln(writer)
+}
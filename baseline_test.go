@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaseline(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 00:00:00.000 Z")
+
+	// Healthy day: one database error per hour among regular info traffic
+	var healthy []LogEntry
+	for i := 0; i < 24; i++ {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		healthy = append(healthy,
+			LogEntry{Timestamp: ts, Level: "error", Message: "Database connection failed"},
+			LogEntry{Timestamp: ts.Add(time.Minute), Level: "info", Message: "Request handled", Extras: map[string]string{"duration": "100ms"}},
+			LogEntry{Timestamp: ts.Add(2 * time.Minute), Level: "info", Message: "Request handled", Extras: map[string]string{"duration": "120ms"}},
+			LogEntry{Timestamp: ts.Add(3 * time.Minute), Level: "info", Message: "Request handled", DuplicateCount: 10},
+		)
+	}
+
+	t.Run("build baseline", func(t *testing.T) {
+		baseline := buildBaseline(healthy, []string{"healthy.log"})
+		assert.Equal(t, 24*13, baseline.TotalEntries)
+		assert.Equal(t, 24, baseline.ErrorEntries)
+		assert.Equal(t, 24, baseline.ErrorSignatures[normalizeLogMessage("Database connection failed")])
+		assert.Equal(t, 48, baseline.Latency.Samples)
+		assert.InDelta(t, 110, baseline.Latency.Mean, 0.01)
+		assert.InDelta(t, 120, baseline.Latency.P95, 0.01)
+	})
+
+	t.Run("save and load roundtrip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		baseline := buildBaseline(healthy, []string{"healthy.log"})
+		require.NoError(t, saveBaseline(baseline, path))
+
+		loaded, err := loadBaseline(path)
+		require.NoError(t, err)
+		assert.Equal(t, baseline.TotalEntries, loaded.TotalEntries)
+		assert.Equal(t, baseline.ErrorSignatures, loaded.ErrorSignatures)
+		assert.True(t, baseline.Start.Equal(loaded.Start))
+	})
+
+	t.Run("no deviations for similar log", func(t *testing.T) {
+		baseline := buildBaseline(healthy, nil)
+		deviations := compareWithBaseline(baseline, healthy[:40])
+		assert.Empty(t, deviations)
+	})
+
+	t.Run("detects spikes and new signatures", func(t *testing.T) {
+		baseline := buildBaseline(healthy, nil)
+
+		var incident []LogEntry
+		for i := 0; i < 30; i++ {
+			ts := start.Add(time.Duration(i) * time.Minute)
+			incident = append(incident,
+				LogEntry{Timestamp: ts, Level: "error", Message: "Database connection failed"},
+				LogEntry{Timestamp: ts, Level: "info", Message: "Request handled", Extras: map[string]string{"duration": "900ms"}},
+			)
+		}
+		incident = append(incident, LogEntry{Timestamp: start, Level: "error", Message: "Failed to upload file to S3"})
+
+		deviations := compareWithBaseline(baseline, incident)
+		kinds := make(map[string]bool)
+		for _, d := range deviations {
+			kinds[d.Kind] = true
+		}
+		assert.True(t, kinds["error_rate"])
+		assert.True(t, kinds["signature_spike"])
+		assert.True(t, kinds["new_signature"])
+		assert.True(t, kinds["latency"])
+
+		var buf bytes.Buffer
+		displayBaselineComparison(baseline, deviations, &buf)
+		assert.Contains(t, buf.String(), "BASELINE COMPARISON")
+		assert.Contains(t, buf.String(), "failed to upload file to s3")
+	})
+}
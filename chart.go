@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Layout of the PNG chart, in pixels: the activity by hour or --bucket period on the left and the level
+// distribution on the right
+const (
+	chartWidth      = 1000
+	chartHeight     = 500
+	chartMargin     = 20
+	chartFontScale  = 2                        // Pixels per dot of the bitmap font
+	chartCharWidth  = (5 + 1) * chartFontScale // Glyph and spacing
+	chartLineHeight = (7 + 4) * chartFontScale // Glyph and spacing
+	chartHoursLeft  = 80                       // Left of the activity plot, after the axis labels
+	chartHoursRight = 680
+	chartPlotTop    = 90
+	chartPlotBottom = 440
+	chartLevelsLeft = 720
+)
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartAxis       = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	chartGrid       = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	chartOther      = color.RGBA{0xa0, 0xa0, 0xa0, 0xff}
+)
+
+// chartLevels are the levels stacked in the bars of the activity, from the bottom, with
+// their colors matching those of the terminal. Other levels are stacked on top in gray.
+var chartLevels = []struct {
+	levels []string
+	color  color.RGBA
+}{
+	{[]string{"ERROR", "FATAL", "CRITICAL"}, color.RGBA{0xd6, 0x27, 0x28, 0xff}},
+	{[]string{"WARN", "WARNING"}, color.RGBA{0xe8, 0xa3, 0x17, 0xff}},
+	{[]string{"INFO"}, color.RGBA{0x2c, 0xa0, 0x2c, 0xff}},
+	{[]string{"DEBUG"}, color.RGBA{0x1f, 0x77, 0xb4, 0xff}},
+}
+
+// chartFont is a 5x7 bitmap font, each row of a glyph holding its dots in the 5 low bits
+// with the leftmost dot first. Text is drawn in upper case.
+var chartFont = map[rune][7]uint8{
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11100, 0b10010, 0b10001, 0b10001, 0b10001, 0b10010, 0b11100},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	':': {0b00000, 0b01100, 0b01100, 0b00000, 0b01100, 0b01100, 0b00000},
+	'-': {0b00000, 0b00000, 0b00000, 0b11111, 0b00000, 0b00000, 0b00000},
+	'.': {0b00000, 0b00000, 0b00000, 0b00000, 0b00000, 0b01100, 0b01100},
+	',': {0b00000, 0b00000, 0b00000, 0b00000, 0b01100, 0b00100, 0b01000},
+	'/': {0b00000, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b00000},
+	'%': {0b11000, 0b11001, 0b00010, 0b00100, 0b01000, 0b10011, 0b00011},
+	'(': {0b00010, 0b00100, 0b01000, 0b01000, 0b01000, 0b00100, 0b00010},
+	')': {0b01000, 0b00100, 0b00010, 0b00010, 0b00010, 0b00100, 0b01000},
+}
+
+// drawChartText draws text with its top left corner at x, y. Characters missing from the
+// font are left blank.
+func drawChartText(img draw.Image, x, y int, text string, c color.Color) {
+	for _, r := range strings.ToUpper(text) {
+		glyph := chartFont[r]
+		for row, dots := range glyph {
+			for col := 0; col < 5; col++ {
+				if dots&(1<<(4-col)) == 0 {
+					continue
+				}
+				dot := image.Rect(x+col*chartFontScale, y+row*chartFontScale, x+(col+1)*chartFontScale, y+(row+1)*chartFontScale)
+				draw.Draw(img, dot, image.NewUniform(c), image.Point{}, draw.Src)
+			}
+		}
+		x += chartCharWidth
+	}
+}
+
+// fillChartRect fills the rectangle from x0, y0 to x1, y1
+func fillChartRect(img draw.Image, x0, y0, x1, y1 int, c color.Color) {
+	draw.Draw(img, image.Rect(x0, y0, x1, y1), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// chartLevelColor returns the color of a level, gray for the levels not in chartLevels
+func chartLevelColor(level string) color.RGBA {
+	for _, group := range chartLevels {
+		for _, l := range group.levels {
+			if l == level {
+				return group.color
+			}
+		}
+	}
+	return chartOther
+}
+
+// chartColumn is a bar of the activity plot, with the counts of its levels
+type chartColumn struct {
+	label  string
+	levels map[string]int
+}
+
+// chartColumns returns the bars of the activity plot: the 24 hours of the day, or each
+// period of --bucket from the first to the last entry
+func chartColumns(analysis LogAnalysis) []chartColumn {
+	var columns []chartColumn
+	if analysis.ActivityBucket == "" {
+		for hour := 0; hour < 24; hour++ {
+			columns = append(columns, chartColumn{label: fmt.Sprintf("%02d", hour), levels: analysis.HourLevelCounts[hour]})
+		}
+		return columns
+	}
+	if analysis.TotalEntries == 0 {
+		return nil
+	}
+	// Periods without entries get an empty bar, so that the time axis is linear
+	last := activityBucketLabel(analysis.TimeRange.End, analysis.ActivityBucket)
+	for t := analysis.TimeRange.Start; ; t = nextActivityBucket(t, analysis.ActivityBucket) {
+		label := activityBucketLabel(t, analysis.ActivityBucket)
+		columns = append(columns, chartColumn{label: label, levels: analysis.ActivityLevelCounts[label]})
+		if label >= last {
+			return columns
+		}
+	}
+}
+
+// renderChart draws the activity by hour of the day or --bucket period, stacked by level, and
+// the level distribution of the analysis
+func renderChart(analysis LogAnalysis) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	fillChartRect(img, 0, 0, chartWidth, chartHeight, chartBackground)
+
+	title := fmt.Sprintf("%d entries", analysis.TotalEntries)
+	if analysis.TotalEntries > 0 {
+		title += fmt.Sprintf(" - %s to %s - error rate %.1f%%", analysis.TimeRange.Start.Format("2006-01-02 15:04"),
+			analysis.TimeRange.End.Format("2006-01-02 15:04"), analysis.ErrorRate)
+	}
+	drawChartText(img, chartMargin, chartMargin, title, chartText)
+	columns := chartColumns(analysis)
+	heading := "Activity by hour"
+	if analysis.ActivityBucket != "" {
+		heading = "Activity by " + analysis.ActivityBucket
+	}
+	drawChartText(img, chartHoursLeft, chartPlotTop-2*chartLineHeight, heading, chartText)
+	drawChartText(img, chartLevelsLeft, chartPlotTop-2*chartLineHeight, "Levels", chartText)
+
+	// Activity: one bar per hour or --bucket period, the levels of chartLevels stacked from
+	// the bottom
+	maxCount := 0
+	for _, column := range columns {
+		total := 0
+		for _, count := range column.levels {
+			total += count
+		}
+		maxCount = max(maxCount, total)
+	}
+	plotHeight := chartPlotBottom - chartPlotTop
+	for i := 0; i <= 4; i++ {
+		y := chartPlotBottom - i*plotHeight/4
+		fillChartRect(img, chartHoursLeft, y, chartHoursRight, y+1, chartGrid)
+		label := fmt.Sprintf("%d", maxCount*i/4)
+		drawChartText(img, chartHoursLeft-8-len(label)*chartCharWidth, y-7*chartFontScale/2, label, chartText)
+	}
+	plotWidth := chartHoursRight - chartHoursLeft
+	// Label every hour of the day multiple of 3, and as many periods as fit without overlapping
+	labelEvery := 3
+	if analysis.ActivityBucket != "" && len(columns) > 0 {
+		labelWidth := (len(columns[0].label) + 2) * chartCharWidth
+		labelEvery = max(1, (len(columns)*labelWidth+plotWidth-1)/plotWidth)
+	}
+	for i, column := range columns {
+		left, right := chartHoursLeft+i*plotWidth/len(columns), chartHoursLeft+(i+1)*plotWidth/len(columns)
+		padding := min(2, (right-left)/4)
+		if i%labelEvery == 0 && left+len(column.label)*chartCharWidth <= chartLevelsLeft-chartMargin {
+			drawChartText(img, left, chartPlotBottom+8, column.label, chartText)
+		}
+		if maxCount == 0 || len(column.levels) == 0 {
+			continue
+		}
+		stacked := 0
+		stack := func(count int, c color.Color) {
+			top := chartPlotBottom - (stacked+count)*plotHeight/maxCount
+			fillChartRect(img, left+padding, top, max(right-padding, left+1), chartPlotBottom-stacked*plotHeight/maxCount, c)
+			stacked += count
+		}
+		known := make(map[string]bool)
+		for _, group := range chartLevels {
+			count := 0
+			for _, level := range group.levels {
+				count += column.levels[level]
+				known[level] = true
+			}
+			stack(count, group.color)
+		}
+		others := 0
+		for level, count := range column.levels {
+			if !known[level] {
+				others += count
+			}
+		}
+		stack(others, chartOther)
+	}
+	fillChartRect(img, chartHoursLeft, chartPlotTop, chartHoursLeft+1, chartPlotBottom+1, chartAxis)
+	fillChartRect(img, chartHoursLeft, chartPlotBottom, chartHoursRight, chartPlotBottom+1, chartAxis)
+
+	// Level distribution: one labeled bar per level, most common first
+	levels := make([]CountedItem, 0, len(analysis.LevelCounts))
+	total := 0
+	for level, count := range analysis.LevelCounts {
+		levels = append(levels, CountedItem{Item: level, Count: count})
+		total += count
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].Count != levels[j].Count {
+			return levels[i].Count > levels[j].Count
+		}
+		return levels[i].Item < levels[j].Item
+	})
+	barWidth := chartWidth - chartMargin - chartLevelsLeft
+	y := chartPlotTop
+	for _, level := range levels {
+		if y+2*chartLineHeight > chartPlotBottom {
+			break
+		}
+		label := fmt.Sprintf("%s %d (%.0f%%)", level.Item, level.Count, float64(level.Count)/float64(total)*100)
+		drawChartText(img, chartLevelsLeft, y, label, chartText)
+		width := max(1, level.Count*barWidth/levels[0].Count)
+		fillChartRect(img, chartLevelsLeft, y+chartLineHeight-4, chartLevelsLeft+width, y+2*chartLineHeight-8, chartLevelColor(level.Item))
+		y += 2 * chartLineHeight
+	}
+
+	return img
+}
+
+// writeChartPNG writes the chart of the analysis as a PNG image
+func writeChartPNG(analysis LogAnalysis, writer io.Writer) error {
+	return png.Encode(writer, renderChart(analysis))
+}
+
+// exportChartPNG writes the chart of the analysis to a PNG file
+func exportChartPNG(analysis LogAnalysis, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	if err := writeChartPNG(analysis, file); err != nil {
+		return err
+	}
+	return file.Close()
+}
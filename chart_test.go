@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteChartPNG(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: start, Level: "info", Message: "Server started"},
+		{Timestamp: start.Add(time.Minute), Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: start.Add(2 * time.Minute), Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: start.Add(3 * time.Hour), Level: "trace", Message: "Ping"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeChartPNG(analyzeLogs(logs, false, 10), &buf))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, chartWidth, img.Bounds().Dx())
+	assert.Equal(t, chartHeight, img.Bounds().Dy())
+
+	// The 10:00 bar stacks the errors at the bottom under the info entry, the 13:00 bar is gray
+	slot := (chartHoursRight - chartHoursLeft) / 24
+	center := func(hour int) int { return chartHoursLeft + hour*slot + slot/2 }
+	assert.Equal(t, chartLevels[0].color, img.At(center(10), chartPlotBottom-1))
+	assert.Equal(t, chartLevels[2].color, img.At(center(10), chartPlotTop+1))
+	assert.Equal(t, chartOther, img.At(center(13), chartPlotBottom-1))
+	assert.Equal(t, chartBackground, img.At(center(13), chartPlotTop+1))
+	assert.Equal(t, chartBackground, img.At(center(11), chartPlotBottom-1), "no entries at 11:00")
+
+	// With --bucket, one bar per period from the first to the last entry, empty periods included
+	activityBucket = "day"
+	t.Cleanup(func() { activityBucket = "" })
+	days := []LogEntry{
+		{Timestamp: start, Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: start.AddDate(0, 0, 2), Level: "info", Message: "Server started"},
+	}
+	analysis := analyzeLogs(days, false, 10)
+	columns := chartColumns(analysis)
+	require.Len(t, columns, 3)
+	assert.Equal(t, "2025-01-02", columns[1].label)
+	assert.Empty(t, columns[1].levels)
+	img = renderChart(analysis)
+	third := (chartHoursRight - chartHoursLeft) / 3
+	assert.Equal(t, chartLevels[0].color, img.At(chartHoursLeft+third/2, chartPlotBottom-1))
+	assert.Equal(t, chartBackground, img.At(chartHoursLeft+third+third/2, chartPlotBottom-1), "no entries on January 2")
+	assert.Equal(t, chartLevels[2].color, img.At(chartHoursLeft+2*third+third/2, chartPlotBottom-1))
+
+	// Empty logs still render the axes
+	buf.Reset()
+	require.NoError(t, writeChartPNG(analyzeLogs(nil, false, 10), &buf))
+	_, err = png.Decode(&buf)
+	require.NoError(t, err)
+
+	assert.Error(t, exportChartPNG(analyzeLogs(logs, false, 10), filepath.Join(t.TempDir(), "missing", "chart.png")))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configFileName is the name of the configuration file in the lamp config directory
+const configFileName = "config.json"
+
+// Config holds the settings read from the lamp configuration file
+type Config struct {
+	Theme  string            `json:"theme,omitempty"`  // Default interactive mode theme
+	Colors map[string]string `json:"colors,omitempty"` // Interactive mode color overrides, see tuiTheme
+
+	Profiles map[string]Profile `json:"profiles,omitempty"` // Named flag defaults, selected with --profile
+	Defaults Profile            `json:"defaults,omitempty"` // Flag defaults of all commands, written by 'lamp init'
+	APIKeys  map[string]string  `json:"api_keys,omitempty"` // LLM provider API keys in plain text, by provider
+
+	Parsers []ParserPlugin `json:"parsers,omitempty"` // External parsers of custom log files
+	Rules   []string       `json:"rules,omitempty"`   // Analysis rule files, relative to the config directory
+
+	SMTP *SMTPSettings `json:"smtp,omitempty"` // Mail server of --email-to
+}
+
+// configDir returns the lamp config directory, e.g. ~/.config/lamp on Linux
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lamp"), nil
+}
+
+// configPath returns the path of the configuration file
+func configPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+// loadConfig reads the configuration file. A missing file yields an empty configuration.
+func loadConfig() (Config, error) {
+	path, err := configPath()
+	if err != nil {
+		return Config{}, nil
+	}
+	return readConfigFile(path)
+}
+
+// readConfigFile reads a configuration file, returning an empty configuration if it does not exist
+func readConfigFile(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// writeConfigFile writes a configuration file as indented JSON, creating its directory if
+// needed. A file holding API keys is only readable by the user.
+func writeConfigFile(config Config, path string) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0o644)
+	if len(config.APIKeys) > 0 || (config.SMTP != nil && config.SMTP.Password != "") {
+		perm = 0o600
+	}
+	if err := os.WriteFile(path, append(data, '\n'), perm); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file", func(t *testing.T) {
+		config, err := readConfigFile(filepath.Join(dir, "missing.json"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, config)
+	})
+
+	t.Run("theme and colors", func(t *testing.T) {
+		path := filepath.Join(dir, "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"theme": "light", "colors": {"selection": "#c0d8ff"}}`), 0o644))
+
+		config, err := readConfigFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "light", config.Theme)
+		assert.Equal(t, map[string]string{"selection": "#c0d8ff"}, config.Colors)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		path := filepath.Join(dir, "broken.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"theme":`), 0o644))
+
+		_, err := readConfigFile(path)
+		assert.ErrorContains(t, err, "invalid config file")
+	})
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lamp", "config.json")
+	config := Config{Theme: "mono", Defaults: Profile{"llm-provider": "ollama"}}
+
+	require.NoError(t, writeConfigFile(config, path))
+	read, err := readConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, config, read)
+
+	// A file holding API keys is only readable by the user
+	config.APIKeys = map[string]string{"openai": "sk-test"}
+	require.NoError(t, writeConfigFile(config, path))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+// useTempConfigDir points the user config directory at a temporary directory on all platforms
+func useTempConfigDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	path, err := configPath()
+	require.NoError(t, err)
+	return path
+}
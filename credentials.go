@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keyProviders are the LLM providers that need an API key
+var keyProviders = []string{string(ProviderAnthropic), string(ProviderOpenAI), string(ProviderGemini)}
+
+// lookupAPIKey returns the API key of an LLM provider from its environment variable, the
+// system keychain or the config file, in that order. It returns an empty string if no key
+// is set.
+func lookupAPIKey(provider LLMProvider) string {
+	key, _ := findAPIKey(provider)
+	return key
+}
+
+// findAPIKey returns the API key of an LLM provider as lookupAPIKey does, with a description
+// of where it was found
+func findAPIKey(provider LLMProvider) (key, source string) {
+	envVar := getAPIKeyEnvVar(provider)
+	if key := getEnvAPIKey(envVar); key != "" {
+		return key, "the " + envVar + " environment variable"
+	}
+	if key, err := keychainGet(string(provider)); err == nil && key != "" {
+		return key, "the system keychain"
+	}
+	config, err := loadConfig()
+	if err != nil || config.APIKeys[string(provider)] == "" {
+		return "", ""
+	}
+	return config.APIKeys[string(provider)], "the config file"
+}
+
+// parseKeyProvider checks that a provider takes an API key
+func parseKeyProvider(name string) (LLMProvider, error) {
+	if !contains(keyProviders, name) {
+		return "", fmt.Errorf("invalid provider %q, expected one of: %s", name, strings.Join(keyProviders, ", "))
+	}
+	return LLMProvider(name), nil
+}
+
+// hasKeychainKey reports whether the system keychain holds an API key for a provider
+func hasKeychainKey(provider LLMProvider) bool {
+	key, err := keychainGet(string(provider))
+	return err == nil && key != ""
+}
+
+// removeKeychainKey removes the API key of a provider from the system keychain
+func removeKeychainKey(provider LLMProvider) error {
+	err := keychainDelete(string(provider))
+	if errors.Is(err, errKeychainNotFound) {
+		return fmt.Errorf("no %s API key in the system keychain", provider)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFakeKeychain replaces the system keychain with a map for the duration of a test
+func useFakeKeychain(t *testing.T) map[string]string {
+	keys := make(map[string]string)
+	get, set, del := keychainGet, keychainSet, keychainDelete
+	t.Cleanup(func() {
+		keychainGet, keychainSet, keychainDelete = get, set, del
+	})
+
+	keychainGet = func(account string) (string, error) {
+		key, ok := keys[account]
+		if !ok {
+			return "", errKeychainNotFound
+		}
+		return key, nil
+	}
+	keychainSet = func(account, secret string) error {
+		keys[account] = secret
+		return nil
+	}
+	keychainDelete = func(account string) error {
+		if _, ok := keys[account]; !ok {
+			return errKeychainNotFound
+		}
+		delete(keys, account)
+		return nil
+	}
+	return keys
+}
+
+func TestLookupAPIKey(t *testing.T) {
+	path := useTempConfigDir(t)
+	keychain := useFakeKeychain(t)
+	t.Setenv("OPENAI_API_KEY", "")
+	assert.Empty(t, lookupAPIKey(ProviderOpenAI))
+
+	require.NoError(t, writeConfigFile(Config{APIKeys: map[string]string{"openai": "sk-config"}}, path))
+	assert.Equal(t, "sk-config", lookupAPIKey(ProviderOpenAI))
+
+	keychain["openai"] = "sk-keychain"
+	assert.Equal(t, "sk-keychain", lookupAPIKey(ProviderOpenAI))
+
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	assert.Equal(t, "sk-env", lookupAPIKey(ProviderOpenAI))
+
+	key, source := findAPIKey(ProviderOpenAI)
+	assert.Equal(t, "sk-env", key)
+	assert.Equal(t, "the OPENAI_API_KEY environment variable", source)
+}
+
+func TestParseKeyProvider(t *testing.T) {
+	provider, err := parseKeyProvider("gemini")
+	require.NoError(t, err)
+	assert.Equal(t, ProviderGemini, provider)
+
+	_, err = parseKeyProvider("ollama")
+	assert.ErrorContains(t, err, "expected one of: anthropic, openai, gemini")
+}
+
+func TestRemoveKeychainKey(t *testing.T) {
+	keychain := useFakeKeychain(t)
+	keychain["anthropic"] = "sk-test"
+
+	require.NoError(t, removeKeychainKey(ProviderAnthropic))
+	assert.Empty(t, keychain)
+	assert.ErrorContains(t, removeKeychainKey(ProviderAnthropic), "no anthropic API key in the system keychain")
+}
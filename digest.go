@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestMaxNewErrors is the number of new error signatures listed per period
+const digestMaxNewErrors = 5
+
+// digestGroupings are the periods a digest can summarize the logs by
+var digestGroupings = []string{"day", "week"}
+
+// DigestError is an error signature first seen in a period, with its occurrences in the period
+type DigestError struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// DigestEvent is a notable event of a period: a restart, an error burst or a logging gap
+type DigestEvent struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"` // restart, error_burst or logging_gap
+	Detail string    `json:"detail"`
+}
+
+// DigestPeriod summarizes the logs of a day or a week
+type DigestPeriod struct {
+	SchemaVersion int           `json:"schema_version"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	Entries       int           `json:"entries"`
+	Errors        int           `json:"errors"`
+	Warnings      int           `json:"warnings"`
+	ErrorRate     float64       `json:"error_rate"`
+	NewErrors     []DigestError `json:"new_errors,omitempty"` // Signatures not seen in earlier periods, most frequent first
+	Events        []DigestEvent `json:"events,omitempty"`     // In chronological order
+}
+
+// periodStart returns the start of the day or of the week (from Monday) of t
+func periodStart(t time.Time, groupBy string) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if groupBy == "week" {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	return start
+}
+
+// nextPeriod returns the start of the period following the one starting at start
+func nextPeriod(start time.Time, groupBy string) time.Time {
+	if groupBy == "week" {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// buildDigest summarizes logs by day or week, from the first to the last entry. Periods
+// without entries are kept, since a silent day is notable in itself.
+func buildDigest(logs []LogEntry, groupBy string) ([]DigestPeriod, error) {
+	if !contains(digestGroupings, groupBy) {
+		return nil, fmt.Errorf("invalid --group-by %q (expected %s)", groupBy, strings.Join(digestGroupings, " or "))
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	logs = append([]LogEntry(nil), logs...)
+	sortEntries(logs)
+
+	// Periods follow the time zone of the first entry
+	location := logs[0].Timestamp.Location()
+	var periods []DigestPeriod
+	index := map[string]int{}
+	for start := periodStart(logs[0].Timestamp, groupBy); !start.After(logs[len(logs)-1].Timestamp); start = nextPeriod(start, groupBy) {
+		index[start.Format(time.DateOnly)] = len(periods)
+		periods = append(periods, DigestPeriod{Start: start, End: nextPeriod(start, groupBy)})
+	}
+	periodOf := func(t time.Time) *DigestPeriod {
+		return &periods[index[periodStart(t.In(location), groupBy).Format(time.DateOnly)]]
+	}
+
+	// Occurrences of each error signature in the period it was first seen in
+	newErrors := map[string]*DigestError{}
+	firstSeen := map[string]*DigestPeriod{}
+	for _, log := range logs {
+		period := periodOf(log.Timestamp)
+		period.Entries++
+		switch {
+		case isErrorLevel(log.Level):
+			period.Errors++
+			signature := normalizeLogMessage(log.Message)
+			if _, seen := firstSeen[signature]; !seen {
+				firstSeen[signature] = period
+				newErrors[signature] = &DigestError{Message: log.Message}
+			}
+			if firstSeen[signature] == period {
+				newErrors[signature].Count++
+			}
+		case strings.EqualFold(log.Level, "warn") || strings.EqualFold(log.Level, "warning"):
+			period.Warnings++
+		}
+	}
+	for signature, period := range firstSeen {
+		period.NewErrors = append(period.NewErrors, *newErrors[signature])
+	}
+
+	timeRange := TimeRange{Start: logs[0].Timestamp, End: logs[len(logs)-1].Timestamp}
+	for _, restart := range detectRestarts(logs) {
+		period := periodOf(restart)
+		period.Events = append(period.Events, DigestEvent{Time: restart, Kind: "restart", Detail: "Server started"})
+	}
+	for _, burst := range analyzeIncidentMetrics(logs, timeRange).ErrorBursts {
+		period := periodOf(burst.Start)
+		period.Events = append(period.Events, DigestEvent{Time: burst.Start, Kind: "error_burst",
+			Detail: fmt.Sprintf("Error burst: %d errors in %s", burst.Count, burst.Duration().Round(time.Second))})
+	}
+	gaps, _, _ := detectLoggingGaps(logs)
+	for _, gap := range gaps {
+		period := periodOf(gap.Start)
+		period.Events = append(period.Events, DigestEvent{Time: gap.Start, Kind: "logging_gap",
+			Detail: fmt.Sprintf("Logging gap of %s", gap.Duration.Round(time.Second))})
+	}
+
+	for i := range periods {
+		period := &periods[i]
+		if period.Entries > 0 {
+			period.ErrorRate = float64(period.Errors) / float64(period.Entries) * 100
+		}
+		sort.Slice(period.NewErrors, func(i, j int) bool {
+			if period.NewErrors[i].Count != period.NewErrors[j].Count {
+				return period.NewErrors[i].Count > period.NewErrors[j].Count
+			}
+			return period.NewErrors[i].Message < period.NewErrors[j].Message
+		})
+		sort.SliceStable(period.Events, func(i, j int) bool { return period.Events[i].Time.Before(period.Events[j].Time) })
+	}
+	return periods, nil
+}
+
+// periodLabel names a period, e.g. "2024-03-01 Fri" or "Week of 2024-02-26"
+func periodLabel(period DigestPeriod, groupBy string) string {
+	if groupBy == "week" {
+		return "Week of " + period.Start.Format("2006-01-02")
+	}
+	return period.Start.Format("2006-01-02 Mon")
+}
+
+// displayDigest writes one compact block per period, or the periods as JSON
+func displayDigest(periods []DigestPeriod, groupBy string, asJSON bool, w io.Writer) error {
+	if asJSON {
+		for i := range periods {
+			periods[i].SchemaVersion = schemaVersion
+		}
+		data, err := json.MarshalIndent(periods, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	if len(periods) == 0 {
+		_, _ = fmt.Fprintln(w, "No log entries found")
+		return nil
+	}
+
+	title := "DAILY DIGEST"
+	eventFormat := "15:04:05"
+	if groupBy == "week" {
+		title = "WEEKLY DIGEST"
+		eventFormat = "Mon 15:04:05"
+	}
+	_, _ = fmt.Fprintf(w, "%s%s%s\n", colorHeaderBold, title, colorReset)
+	for _, period := range periods {
+		_, _ = fmt.Fprintf(w, "\n%s%s%s  ", colorSubHeader, periodLabel(period, groupBy), colorReset)
+		if period.Entries == 0 {
+			_, _ = fmt.Fprintln(w, "no entries")
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%d entries • Error rate: %.1f%% • %d errors • %d warnings\n",
+			period.Entries, period.ErrorRate, period.Errors, period.Warnings)
+
+		if len(period.NewErrors) > 0 {
+			var items []string
+			for _, newError := range period.NewErrors[:min(len(period.NewErrors), digestMaxNewErrors)] {
+				items = append(items, fmt.Sprintf("%s (%d)", truncateString(newError.Message, 80), newError.Count))
+			}
+			if more := len(period.NewErrors) - digestMaxNewErrors; more > 0 {
+				items = append(items, fmt.Sprintf("%d more", more))
+			}
+			_, _ = fmt.Fprintf(w, "  New errors: %s\n", strings.Join(items, " • "))
+		}
+		for _, event := range period.Events {
+			_, _ = fmt.Fprintf(w, "  %s %s\n", event.Time.Format(eventFormat), event.Detail)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDigest(t *testing.T) {
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2024-03-03 09:10:00.000 Z"), Level: "error", Message: "Unable to upload file"},
+		{Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Level: "info", Message: "Server is initializing..."},
+		{Timestamp: mustParseTime(t, "2024-03-01 10:05:00.000 Z"), Level: "error", Message: "Failed to ping DB after 3 retries"},
+		{Timestamp: mustParseTime(t, "2024-03-01 11:00:00.000 Z"), Level: "warn", Message: "Slow request"},
+		{Timestamp: mustParseTime(t, "2024-03-03 09:00:00.000 Z"), Level: "error", Message: "Failed to ping DB after 5 retries"},
+	}
+	for i := 0; i < burstMinErrors; i++ {
+		logs = append(logs, LogEntry{Timestamp: mustParseTime(t, fmt.Sprintf("2024-03-03 09:20:%02d.000 Z", i)), Level: "error", Message: "Unable to upload file"})
+	}
+
+	periods, err := buildDigest(logs, "day")
+	require.NoError(t, err)
+	require.Len(t, periods, 3)
+
+	first := periods[0]
+	assert.Equal(t, mustParseTime(t, "2024-03-01 00:00:00.000 Z"), first.Start)
+	assert.Equal(t, 3, first.Entries)
+	assert.Equal(t, 1, first.Errors)
+	assert.Equal(t, 1, first.Warnings)
+	assert.InDelta(t, 33.3, first.ErrorRate, 0.1)
+	assert.Equal(t, []DigestError{{Message: "Failed to ping DB after 3 retries", Count: 1}}, first.NewErrors)
+	require.Len(t, first.Events, 2)
+	assert.Equal(t, "restart", first.Events[0].Kind)
+	assert.Equal(t, "logging_gap", first.Events[1].Kind)
+
+	assert.Zero(t, periods[1].Entries, "days without entries are kept")
+
+	last := periods[2]
+	assert.Equal(t, 7, last.Entries)
+	assert.Equal(t, []DigestError{{Message: "Unable to upload file", Count: 6}}, last.NewErrors,
+		"errors seen on an earlier day are not new")
+	require.Len(t, last.Events, 1)
+	assert.Equal(t, "Error burst: 5 errors in 4s", last.Events[0].Detail)
+
+	weeks, err := buildDigest(logs, "week")
+	require.NoError(t, err)
+	require.Len(t, weeks, 1)
+	assert.Equal(t, mustParseTime(t, "2024-02-26 00:00:00.000 Z"), weeks[0].Start, "weeks start on Monday")
+	assert.Equal(t, len(logs), weeks[0].Entries)
+
+	_, err = buildDigest(logs, "month")
+	assert.EqualError(t, err, `invalid --group-by "month" (expected day or week)`)
+}
+
+func TestDisplayDigest(t *testing.T) {
+	periods := []DigestPeriod{
+		{Start: mustParseTime(t, "2024-03-01 00:00:00.000 Z"), Entries: 4, Errors: 1, ErrorRate: 25,
+			NewErrors: []DigestError{{Message: "Failed to ping DB", Count: 1}},
+			Events:    []DigestEvent{{Time: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Kind: "restart", Detail: "Server started"}}},
+		{Start: mustParseTime(t, "2024-03-02 00:00:00.000 Z")},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, displayDigest(periods, "day", false, &out))
+	assert.Equal(t, colorHeaderBold+"DAILY DIGEST"+colorReset+"\n\n"+
+		colorSubHeader+"2024-03-01 Fri"+colorReset+"  4 entries • Error rate: 25.0% • 1 errors • 0 warnings\n"+
+		"  New errors: Failed to ping DB (1)\n"+
+		"  10:00:00 Server started\n\n"+
+		colorSubHeader+"2024-03-02 Sat"+colorReset+"  no entries\n", out.String())
+
+	out.Reset()
+	require.NoError(t, displayDigest(nil, "day", false, &out))
+	assert.Equal(t, "No log entries found\n", out.String())
+}
@@ -1,22 +1,32 @@
 package main
 
 import (
+	"compress/gzip"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-// writeLogsToJSON writes log entries to a JSON file
+// writeLogsToJSON writes log entries to a JSON file, as JSON Lines if its extension is
+// .jsonl or .ndjson
 func writeLogsToJSON(logs []LogEntry, filePath string) error {
 	file, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	if isJSONLinesFile(filePath) {
+		return writeLogsNDJSON(logs, file)
+	}
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
@@ -47,78 +57,101 @@ func displayLogsPretty(logs []LogEntry, writer io.Writer) {
 		return
 	}
 
+	occurrences := 0
 	for _, log := range logs {
-		// Format timestamp
-		timestamp := log.Timestamp.Format("2006-01-02 15:04:05")
-
-		// Color the log level
-		var levelColored string
-		switch strings.ToUpper(log.Level) {
-		case "ERROR", "FATAL", "CRITICAL":
-			levelColored = colorRed + log.Level + colorReset
-		case "WARN", "WARNING":
-			levelColored = colorYellow + log.Level + colorReset
-		case "INFO":
-			levelColored = colorGreen + log.Level + colorReset
-		case "DEBUG":
-			levelColored = colorBlue + log.Level + colorReset
-		default:
-			levelColored = log.Level
+		displayLogPretty(log, writer)
+		occurrences += max(log.DuplicateCount, 1)
+	}
+
+	// Print summary
+	if occurrences > len(logs) {
+		_, _ = fmt.Fprintf(writer, "\nDisplayed %d log entries, grouping %d occurrences\n", len(logs), occurrences)
+		return
+	}
+	_, _ = fmt.Fprintf(writer, "\nDisplayed %d log entries\n", len(logs))
+}
+
+// displayLogPretty outputs a log entry in a human-readable colored format. Deduplicated
+// entries show their earliest and latest occurrences, so that how long they recurred is visible.
+func displayLogPretty(log LogEntry, writer io.Writer) {
+	// Format timestamp
+	timestamp := log.Timestamp.Format("2006-01-02 15:04:05")
+	if lastSeen := log.lastSeen(); lastSeen.After(log.Timestamp) {
+		last := lastSeen.Format("2006-01-02 15:04:05")
+		if lastSeen.YearDay() == log.Timestamp.YearDay() && lastSeen.Year() == log.Timestamp.Year() {
+			last = lastSeen.Format("15:04:05")
 		}
+		timestamp += fmt.Sprintf(" → %s (%s)", last, formatSpan(lastSeen.Sub(log.Timestamp)))
+	}
+
+	// Color the log level
+	var levelColored string
+	switch strings.ToUpper(log.Level) {
+	case "ERROR", "FATAL", "CRITICAL":
+		levelColored = colorRed + log.Level + colorReset
+	case "WARN", "WARNING":
+		levelColored = colorYellow + log.Level + colorReset
+	case "INFO":
+		levelColored = colorGreen + log.Level + colorReset
+	case "DEBUG":
+		levelColored = colorBlue + log.Level + colorReset
+	default:
+		levelColored = log.Level
+	}
 
-		// Print the formatted log entry
-		_, _ = fmt.Fprintf(writer, "%s [%s] %s%s%s",
-			colorCyan+timestamp+colorReset,
-			levelColored,
-			colorBold+log.Source+colorReset,
-			colorWhite+" → "+colorReset,
-			log.Message,
-		)
-
-		// Print duplicate count if more than 1
-		if log.DuplicateCount > 1 {
+	// Print the formatted log entry
+	_, _ = fmt.Fprintf(writer, "%s [%s] %s%s%s",
+		colorCyan+timestamp+colorReset,
+		levelColored,
+		colorBold+log.Source+colorReset,
+		colorWhite+" → "+colorReset,
+		log.Message,
+	)
+
+	// Print duplicate count if more than 1
+	if log.DuplicateCount > 1 {
+		if len(log.NodeCounts) > 0 {
+			_, _ = fmt.Fprintf(writer, " %s(repeated %d times: %s)%s", colorYellow, log.DuplicateCount, formatNodeCounts(log.NodeCounts), colorReset)
+		} else {
 			_, _ = fmt.Fprintf(writer, " %s(repeated %d times)%s", colorYellow, log.DuplicateCount, colorReset)
 		}
-		_, _ = fmt.Fprintln(writer)
+	}
+	_, _ = fmt.Fprintln(writer)
 
-		// Print user if available
-		if log.User != "" {
-			_, _ = fmt.Fprintf(writer, "  %sUser:%s %s\n", colorPurple, colorReset, log.User)
-		}
+	// Print user if available
+	if log.User != "" {
+		_, _ = fmt.Fprintf(writer, "  %sUser:%s %s\n", colorPurple, colorReset, log.User)
+	}
 
-		// Print source if available
-		if log.Source != "" {
-			_, _ = fmt.Fprintf(writer, "  %sSource:%s %s\n", colorPurple, colorReset, log.Source)
+	// Print source if available
+	if log.Source != "" {
+		_, _ = fmt.Fprintf(writer, "  %sSource:%s %s\n", colorPurple, colorReset, log.Source)
+	}
+	
+	// Print notification-specific fields if available
+	if log.LogSource == "notifications" {
+		_, _ = fmt.Fprintf(writer, "  %sLog Source:%s %s\n", colorPurple, colorReset, log.LogSource)
+		
+		if log.AckID != "" {
+			_, _ = fmt.Fprintf(writer, "  %sAck ID:%s %s\n", colorPurple, colorReset, log.AckID)
 		}
 		
-		// Print notification-specific fields if available
-		if log.LogSource == "notifications" {
-			_, _ = fmt.Fprintf(writer, "  %sLog Source:%s %s\n", colorPurple, colorReset, log.LogSource)
-			
-			if log.AckID != "" {
-				_, _ = fmt.Fprintf(writer, "  %sAck ID:%s %s\n", colorPurple, colorReset, log.AckID)
-			}
-			
-			if log.Type != "" {
-				_, _ = fmt.Fprintf(writer, "  %sType:%s %s\n", colorPurple, colorReset, log.Type)
-			}
-			
-			if log.Status != "" {
-				_, _ = fmt.Fprintf(writer, "  %sStatus:%s %s\n", colorPurple, colorReset, log.Status)
-			}
+		if log.Type != "" {
+			_, _ = fmt.Fprintf(writer, "  %sType:%s %s\n", colorPurple, colorReset, log.Type)
 		}
-
-		// Print extras if available
-		for key, value := range log.Extras {
-			_, _ = fmt.Fprintf(writer, "  %s%s:%s %s\n", colorPurple, key, colorReset, value)
+		
+		if log.Status != "" {
+			_, _ = fmt.Fprintf(writer, "  %sStatus:%s %s\n", colorPurple, colorReset, log.Status)
 		}
+	}
 
-		// Add a separator between entries
-		_, _ = fmt.Fprintln(writer, strings.Repeat("-", 80))
+	// Print extras if available
+	for key, value := range log.Extras {
+		_, _ = fmt.Fprintf(writer, "  %s%s:%s %s\n", colorPurple, key, colorReset, value)
 	}
 
-	// Print summary
-	_, _ = fmt.Fprintf(writer, "\nDisplayed %d log entries\n", len(logs))
+	// Add a separator between entries
+	_, _ = fmt.Fprintln(writer, strings.Repeat("-", 80))
 }
 
 // displayLogsJSON outputs logs in JSON format
@@ -137,41 +170,153 @@ func displayLogsJSON(logs []LogEntry, writer io.Writer) {
 	_, _ = fmt.Fprintln(writer, string(output))
 }
 
+// csvOptions are the settings of exported CSV files
+type csvOptions struct {
+	Delimiter    rune // Field separator, a comma when zero
+	ExpandExtras bool // One column per extras key instead of a single Extras column
+}
+
+// csvExportOptions returns the settings of exported CSV files from the --csv-delimiter and
+// --csv-expand-extras flags
+func csvExportOptions() (csvOptions, error) {
+	options := csvOptions{Delimiter: ',', ExpandExtras: csvExpandExtras}
+	switch delimiter := []rune(csvDelimiter); {
+	case csvDelimiter == "" || csvDelimiter == ",":
+	case csvDelimiter == "tab" || csvDelimiter == `\t`:
+		options.Delimiter = '\t'
+	case len(delimiter) == 1 && delimiter[0] != '"' && delimiter[0] != '\r' && delimiter[0] != '\n' && delimiter[0] != utf8.RuneError:
+		options.Delimiter = delimiter[0]
+	default:
+		return options, fmt.Errorf("--csv-delimiter must be a single character other than a quote or a newline, or tab, got %q", csvDelimiter)
+	}
+	return options, nil
+}
+
 // exportToCSV exports log entries to a CSV file
-func exportToCSV(logs []LogEntry, filePath string) error {
+func exportToCSV(logs []LogEntry, filePath string, options csvOptions) error {
+	return writeCSVFile(filePath, options, eachEntry(logs))
+}
+
+// eachEntry returns a function calling fn with each entry of logs until it fails, to write
+// the entries in memory like those spilled to disk
+func eachEntry(logs []LogEntry) func(func(LogEntry) error) error {
+	return func(fn func(LogEntry) error) error {
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// writeCSVFile writes the entries iterated by each to a CSV file, compressed with gzip when
+// its name ends in .gz. Expanding the extras iterates the entries twice, to collect the keys
+// of the columns first.
+func writeCSVFile(filePath string, options csvOptions, each func(func(LogEntry) error) error) error {
+	var extrasKeys []string
+	if options.ExpandExtras {
+		keys := make(map[string]bool)
+		if err := each(func(log LogEntry) error {
+			for key := range log.Extras {
+				keys[key] = true
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for key := range keys {
+			extrasKeys = append(extrasKeys, key)
+		}
+		sort.Strings(extrasKeys)
+	}
+
 	file, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	var out io.Writer = file
+	var compressed *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(filePath), ".gz") {
+		compressed = gzip.NewWriter(file)
+		out = compressed
+	}
+	writer := csv.NewWriter(out)
+	if options.Delimiter != 0 {
+		writer.Comma = options.Delimiter
+	}
 
-	// Write header
-	header := []string{"Timestamp", "Level", "Source", "Message", "User", "LogSource", "AckID", "Type", "Status", "Extras"}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(csvHeaderRow(options, extrasKeys)); err != nil {
 		return err
 	}
-
-	// Write data
-	for _, log := range logs {
-		row := []string{
-			log.Timestamp.Format(time.RFC3339),
-			log.Level,
-			log.Source,
-			log.Message,
-			log.User,
-			log.LogSource,
-			log.AckID,
-			log.Type,
-			log.Status,
-			log.ExtrasToString(),
-		}
-		if err := writer.Write(row); err != nil {
+	if err := each(func(log LogEntry) error {
+		return writer.Write(csvRow(log, options, extrasKeys))
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if compressed != nil {
+		if err := compressed.Close(); err != nil {
 			return err
 		}
 	}
+	return file.Close()
+}
 
-	return nil
+// csvHeader names the columns of exported CSV files
+var csvHeader = []string{"Timestamp", "Level", "Source", "Message", "User", "LogSource", "AckID", "Type", "Status", "Extras", "SourceFile", "Node"}
+
+// csvHeaderRow returns the header of an exported CSV file, with the extras keys in place of
+// the Extras column when they are expanded
+func csvHeaderRow(options csvOptions, extrasKeys []string) []string {
+	if !options.ExpandExtras {
+		return csvHeader
+	}
+	return csvExpand(csvHeader, extrasKeys)
+}
+
+// csvRow returns the row of a log entry in an exported CSV file
+func csvRow(log LogEntry, options csvOptions, extrasKeys []string) []string {
+	record := csvRecord(log)
+	if !options.ExpandExtras {
+		return record
+	}
+	values := make([]string, len(extrasKeys))
+	for i, key := range extrasKeys {
+		values[i] = log.Extras[key]
+	}
+	return csvExpand(record, values)
+}
+
+// csvExpand replaces the Extras column of a row with the columns of the extras
+func csvExpand(row []string, extras []string) []string {
+	column := slices.Index(csvHeader, "Extras")
+	expanded := make([]string, 0, len(row)-1+len(extras))
+	expanded = append(expanded, row[:column]...)
+	expanded = append(expanded, extras...)
+	return append(expanded, row[column+1:]...)
+}
+
+// csvRecord returns the row of a log entry in exported CSV files
+func csvRecord(log LogEntry) []string {
+	return []string{
+		log.Timestamp.Format(time.RFC3339),
+		log.Level,
+		log.Source,
+		log.Message,
+		log.User,
+		log.LogSource,
+		log.AckID,
+		log.Type,
+		log.Status,
+		log.ExtrasToString(),
+		log.SourceFile,
+		log.Node,
+	}
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportToCSV(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "Login; ok", Extras: map[string]string{"user_id": "u1", "ip_address": "10.0.0.1"}},
+		{Timestamp: ts, Level: "error", Message: "Failed", Extras: map[string]string{"error": "timeout"}, Node: "node1"},
+	}
+	dir := t.TempDir()
+
+	readCSV := func(path string, delimiter rune) [][]string {
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+		reader := csv.NewReader(file)
+		if filepath.Ext(path) == ".gz" {
+			compressed, err := gzip.NewReader(file)
+			require.NoError(t, err)
+			reader = csv.NewReader(compressed)
+		}
+		reader.Comma = delimiter
+		records, err := reader.ReadAll()
+		require.NoError(t, err)
+		return records
+	}
+
+	path := filepath.Join(dir, "logs.csv")
+	require.NoError(t, exportToCSV(logs, path, csvOptions{}))
+	records := readCSV(path, ',')
+	require.Len(t, records, 3)
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, "ip_address=10.0.0.1, user_id=u1", records[1][9])
+
+	path = filepath.Join(dir, "logs.csv.gz")
+	require.NoError(t, exportToCSV(logs, path, csvOptions{Delimiter: ';', ExpandExtras: true}))
+	records = readCSV(path, ';')
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"Timestamp", "Level", "Source", "Message", "User", "LogSource", "AckID", "Type", "Status",
+		"error", "ip_address", "user_id", "SourceFile", "Node"}, records[0])
+	assert.Equal(t, []string{"2025-01-01T10:00:00Z", "info", "", "Login; ok", "", "", "", "", "", "", "10.0.0.1", "u1", "", ""}, records[1])
+	assert.Equal(t, []string{"timeout", "", ""}, records[2][9:12])
+	assert.Equal(t, "node1", records[2][13])
+}
+
+func TestCSVExportOptions(t *testing.T) {
+	t.Cleanup(func() { csvDelimiter, csvExpandExtras = ",", false })
+
+	for delimiter, expected := range map[string]rune{"": ',', ",": ',', ";": ';', "|": '|', "tab": '\t', `\t`: '\t'} {
+		csvDelimiter = delimiter
+		options, err := csvExportOptions()
+		require.NoError(t, err, delimiter)
+		assert.Equal(t, expected, options.Delimiter, delimiter)
+	}
+	for _, delimiter := range []string{`"`, "\n", ";;"} {
+		csvDelimiter = delimiter
+		_, err := csvExportOptions()
+		assert.Error(t, err, delimiter)
+	}
+
+	csvDelimiter, csvExpandExtras = ",", true
+	options, err := csvExportOptions()
+	require.NoError(t, err)
+	assert.True(t, options.ExpandExtras)
+}
+
+func TestDisplayLogsPrettyTrimmed(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: start, Level: "error", Source: "app/db.go", Message: "Failed to ping DB", Node: "node1"},
+		{Timestamp: start.Add(time.Hour), Level: "error", Source: "app/db.go", Message: "Failed to ping DB", Node: "node2"},
+		{Timestamp: start.Add(4*time.Hour + 32*time.Minute), Level: "error", Source: "app/db.go", Message: "Failed to ping DB", Node: "node1"},
+		{Timestamp: start.Add(time.Minute), Level: "info", Source: "app/server.go", Message: "Server started", Node: "node1"},
+	}
+	trimmed := trimDuplicateLogsSequential(logs, 0.8, 100, 10, newPlainProgress(nil, len(logs), ""))
+	require.Len(t, trimmed, 2)
+
+	var buf bytes.Buffer
+	displayLogsPretty(trimmed, &buf)
+	output := buf.String()
+	assert.Contains(t, output, "2025-01-01 10:00:00 → 14:32:00 (4 hours 32 minutes)")
+	assert.Contains(t, output, "(repeated 3 times: node1: 2, node2: 1)")
+	assert.Contains(t, output, "Displayed 2 log entries, grouping 4 occurrences")
+
+	buf.Reset()
+	displayLogsPretty(logs[3:], &buf)
+	assert.NotContains(t, buf.String(), "→ 1", "entries seen once have a single timestamp")
+	assert.Contains(t, buf.String(), "Displayed 1 log entries\n")
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"golang.org/x/term"
+)
+
+// doctorTimeout bounds each request made by 'lamp doctor'
+const doctorTimeout = 10 * time.Second
+
+// keyCheckURLs are the endpoints listing the models of each provider, a cheap request used
+// to check that an API key is accepted
+var keyCheckURLs = map[LLMProvider]string{
+	ProviderAnthropic: "https://api.anthropic.com/v1/models",
+	ProviderOpenAI:    "https://api.openai.com/v1/models",
+	ProviderGemini:    "https://generativelanguage.googleapis.com/v1beta/models",
+}
+
+// checkStatus is the outcome of a diagnostic check
+type checkStatus int
+
+const (
+	checkOK      checkStatus = iota
+	checkSkipped             // Not set up, which is fine unless it is needed
+	checkWarning             // Works with limitations
+	checkFailed              // Set up but broken
+)
+
+// doctorCheck is the result of a diagnostic check, with how to fix a problem
+type doctorCheck struct {
+	name   string
+	status checkStatus
+	detail string
+	fix    string
+}
+
+// doctor runs the diagnostics of 'lamp doctor'
+type doctor struct {
+	client      *http.Client
+	provider    LLMProvider // Provider used for AI analysis, whose problems are failures
+	ollamaHost  string
+	ollamaModel string // Model checked on the Ollama server
+}
+
+// newDoctor returns a doctor for the provider, model and Ollama server of the flags, falling
+// back to the config file defaults
+func newDoctor(config Config, provider, model, host string) *doctor {
+	if provider == "" {
+		provider = config.Defaults["llm-provider"]
+	}
+	if provider == "" {
+		provider = string(ProviderAnthropic)
+	}
+	if model == "" {
+		model = config.Defaults["llm-model"]
+	}
+	if provider != string(ProviderOllama) || model == "" {
+		model = GetDefaultModel(ProviderOllama)
+	}
+	if host == "" {
+		host = config.Defaults["ollama-host"]
+	}
+	if host == "" {
+		host = defaultOllamaHost
+	}
+	return &doctor{
+		client:      newHTTPClient(doctorTimeout),
+		provider:    LLMProvider(provider),
+		ollamaHost:  host,
+		ollamaModel: model,
+	}
+}
+
+// run runs all checks
+func (d *doctor) run() []doctorCheck {
+	checks := []doctorCheck{checkConfigFile()}
+	for _, provider := range keyProviders {
+		checks = append(checks, d.checkAPIKey(LLMProvider(provider)))
+	}
+	checks = append(checks, d.checkOllama()...)
+	checks = append(checks, checkClipboard(), checkTerminalColors(os.Getenv, term.IsTerminal(int(os.Stdout.Fd()))))
+	return checks
+}
+
+// failure returns a failed check when the provider is the one used for AI analysis, and a
+// skipped check otherwise
+func (d *doctor) failure(provider LLMProvider, check doctorCheck) doctorCheck {
+	check.status = checkSkipped
+	if provider == d.provider {
+		check.status = checkFailed
+	}
+	return check
+}
+
+// checkConfigFile checks that the config file, if any, can be read
+func checkConfigFile() doctorCheck {
+	check := doctorCheck{name: "Config file"}
+	path, err := configPath()
+	if err != nil {
+		check.status, check.detail = checkWarning, fmt.Sprintf("no config directory: %v", err)
+		check.fix = "set HOME (or AppData on Windows) so that lamp can find its config directory"
+		return check
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		check.status, check.detail = checkSkipped, fmt.Sprintf("%s does not exist", path)
+		check.fix = "run 'lamp init' to set up AI analysis"
+		return check
+	}
+	config, err := readConfigFile(path)
+	if err != nil {
+		check.status, check.detail = checkFailed, err.Error()
+		check.fix = fmt.Sprintf("fix the JSON of %s, or remove it and run 'lamp init'", path)
+		return check
+	}
+	check.detail = fmt.Sprintf("%s (%d profiles)", path, len(config.Profiles))
+	return check
+}
+
+// checkAPIKey checks that the API key of a provider is set and accepted by its API
+func (d *doctor) checkAPIKey(provider LLMProvider) doctorCheck {
+	check := doctorCheck{name: fmt.Sprintf("%s API key", provider)}
+	key, source := findAPIKey(provider)
+	if key == "" {
+		check.detail = "not set"
+		check.fix = fmt.Sprintf("run 'lamp auth set %s' or set %s", provider, getAPIKeyEnvVar(provider))
+		return d.failure(provider, check)
+	}
+
+	status, err := d.keyStatus(provider, key)
+	switch {
+	case err != nil:
+		check.status, check.detail = checkWarning, fmt.Sprintf("found in %s, but could not be checked: %v", source, err)
+		check.fix = "check your network connection and proxy settings"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusBadRequest:
+		check.status, check.detail = checkFailed, fmt.Sprintf("found in %s, but rejected by the API (status %d)", source, status)
+		check.fix = fmt.Sprintf("replace it with a valid key ('lamp auth set %s' or %s)", provider, getAPIKeyEnvVar(provider))
+	case status != http.StatusOK:
+		check.status, check.detail = checkWarning, fmt.Sprintf("found in %s, but the API answered with status %d", source, status)
+		check.fix = "try again later, the API may be unavailable"
+	default:
+		check.detail = fmt.Sprintf("valid, from %s", source)
+	}
+	return check
+}
+
+// keyStatus lists the models of a provider with an API key and returns the HTTP status
+func (d *doctor) keyStatus(provider LLMProvider, key string) (int, error) {
+	req, err := http.NewRequest("GET", keyCheckURLs[provider], nil)
+	if err != nil {
+		return 0, err
+	}
+	switch provider {
+	case ProviderAnthropic:
+		req.Header.Set("x-api-key", key)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	case ProviderOpenAI:
+		req.Header.Set("Authorization", "Bearer "+key)
+	case ProviderGemini:
+		req.Header.Set("x-goog-api-key", key)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// checkOllama checks that the Ollama server is reachable and has the model used by lamp
+func (d *doctor) checkOllama() []doctorCheck {
+	server := doctorCheck{name: "Ollama server"}
+	models, err := d.ollamaModels()
+	if err != nil {
+		server.detail = fmt.Sprintf("not reachable at %s: %v", d.ollamaHost, err)
+		server.fix = "start Ollama with 'ollama serve', or point --ollama-host (or ollama-host in 'lamp init') to your server"
+		return []doctorCheck{d.failure(ProviderOllama, server)}
+	}
+	server.detail = fmt.Sprintf("reachable at %s, %d models installed", d.ollamaHost, len(models))
+
+	model := doctorCheck{name: "Ollama model"}
+	for _, installed := range models {
+		if installed == d.ollamaModel || strings.TrimSuffix(installed, ":latest") == d.ollamaModel {
+			model.detail = fmt.Sprintf("%s is installed", d.ollamaModel)
+			return []doctorCheck{server, model}
+		}
+	}
+	model.detail = fmt.Sprintf("%s is not installed", d.ollamaModel)
+	model.fix = fmt.Sprintf("run 'ollama pull %s', or choose an installed model with --llm-model", d.ollamaModel)
+	return []doctorCheck{server, d.failure(ProviderOllama, model)}
+}
+
+// ollamaModels returns the names of the models installed on the Ollama server
+func (d *doctor) ollamaModels() ([]string, error) {
+	resp, err := d.client.Get(strings.TrimSuffix(d.ollamaHost, "/") + "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("unexpected answer: %v", err)
+	}
+	names := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		names = append(names, model.Name)
+	}
+	return names, nil
+}
+
+// checkClipboard checks that analyses can be copied to the clipboard
+func checkClipboard() doctorCheck {
+	check := doctorCheck{name: "Clipboard", detail: "available"}
+	if clipboard.Unsupported {
+		check.status, check.detail = checkWarning, "no clipboard tool found, analyses and entries cannot be copied"
+		check.fix = "install xclip or xsel (X11), or wl-clipboard (Wayland)"
+		if runtime.GOOS != "linux" {
+			check.fix = "install xclip or xsel"
+		}
+	}
+	return check
+}
+
+// checkTerminalColors checks that the terminal shows the colors of the output and the
+// interactive mode
+func checkTerminalColors(getenv func(string) string, isTerminal bool) doctorCheck {
+	check := doctorCheck{name: "Terminal colors"}
+	termName, colorTerm := getenv("TERM"), strings.ToLower(getenv("COLORTERM"))
+	switch {
+	case !isTerminal:
+		check.status, check.detail = checkWarning, "stdout is not a terminal, colors are written as escape codes"
+		check.fix = "use --porcelain or --json when piping the output to other tools"
+	case runtime.GOOS != "windows" && (termName == "" || termName == "dumb"):
+		check.status, check.detail = checkWarning, fmt.Sprintf("TERM is %q, colors are not supported", termName)
+		check.fix = "set TERM to your terminal type, e.g. export TERM=xterm-256color"
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		check.detail = "true color"
+	case strings.Contains(termName, "256color"):
+		check.detail = "256 colors"
+	default:
+		check.status, check.detail = checkWarning, fmt.Sprintf("basic colors only (TERM=%s)", termName)
+		check.fix = "use a 256-color TERM such as xterm-256color, or --theme mono in interactive mode"
+	}
+	return check
+}
+
+// printDoctorChecks writes the results of the checks and returns the number of failures
+func printDoctorChecks(w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		var symbol string
+		switch check.status {
+		case checkOK:
+			symbol = colorGreen + "✓" + colorReset
+		case checkSkipped:
+			symbol = "-"
+		case checkWarning:
+			symbol = colorYellow + "!" + colorReset
+		case checkFailed:
+			symbol = colorRed + "✗" + colorReset
+			failed++
+		}
+		_, _ = fmt.Fprintf(w, "%s %s: %s\n", symbol, check.name, check.detail)
+		if check.fix != "" && check.status != checkOK {
+			_, _ = fmt.Fprintf(w, "    Fix: %s\n", check.fix)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctorAPIKeys(t *testing.T) {
+	useTempConfigDir(t)
+	keychain := useFakeKeychain(t)
+	for _, provider := range keyProviders {
+		t.Setenv(getAPIKeyEnvVar(LLMProvider(provider)), "")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer sk-good" {
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	oldURLs := keyCheckURLs
+	keyCheckURLs = map[LLMProvider]string{ProviderOpenAI: server.URL}
+	defer func() { keyCheckURLs = oldURLs }()
+
+	d := newDoctor(Config{}, "openai", "", "")
+	check := d.checkAPIKey(ProviderOpenAI)
+	assert.Equal(t, checkFailed, check.status, "the key of the provider in use is missing")
+	assert.Contains(t, check.fix, "lamp auth set openai")
+	assert.Equal(t, checkSkipped, d.checkAPIKey(ProviderGemini).status, "other providers are optional")
+
+	t.Setenv("OPENAI_API_KEY", "sk-good")
+	check = d.checkAPIKey(ProviderOpenAI)
+	assert.Equal(t, checkOK, check.status)
+	assert.Equal(t, "valid, from the OPENAI_API_KEY environment variable", check.detail)
+
+	t.Setenv("OPENAI_API_KEY", "")
+	keychain["openai"] = "sk-bad"
+	check = d.checkAPIKey(ProviderOpenAI)
+	assert.Equal(t, checkFailed, check.status)
+	assert.Contains(t, check.detail, "found in the system keychain, but rejected by the API (status 401)")
+}
+
+func TestDoctorOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		_, _ = w.Write([]byte(`{"models": [{"name": "llama3:latest"}, {"name": "mistral:7b"}]}`))
+	}))
+	defer server.Close()
+
+	checks := newDoctor(Config{}, "ollama", "llama3", server.URL+"/").checkOllama()
+	require.Len(t, checks, 2)
+	assert.Equal(t, checkOK, checks[0].status)
+	assert.Contains(t, checks[0].detail, "2 models installed")
+	assert.Equal(t, checkOK, checks[1].status)
+
+	config := Config{Defaults: Profile{"llm-provider": "ollama", "llm-model": "codellama", "ollama-host": server.URL}}
+	checks = newDoctor(config, "", "", "").checkOllama()
+	require.Len(t, checks, 2)
+	assert.Equal(t, checkFailed, checks[1].status)
+	assert.Equal(t, "run 'ollama pull codellama', or choose an installed model with --llm-model", checks[1].fix)
+
+	server.Close()
+	checks = newDoctor(Config{}, "anthropic", "", server.URL).checkOllama()
+	require.Len(t, checks, 1)
+	assert.Equal(t, checkSkipped, checks[0].status, "Ollama is optional unless it is the provider")
+	assert.Contains(t, checks[0].detail, "not reachable")
+}
+
+func TestCheckTerminalColors(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	assert.Equal(t, checkWarning, checkTerminalColors(env(nil), false).status)
+	assert.Equal(t, "true color", checkTerminalColors(env(map[string]string{"TERM": "xterm", "COLORTERM": "truecolor"}), true).detail)
+	assert.Equal(t, "256 colors", checkTerminalColors(env(map[string]string{"TERM": "screen-256color"}), true).detail)
+	assert.Equal(t, checkWarning, checkTerminalColors(env(map[string]string{"TERM": "xterm"}), true).status)
+}
+
+func TestPrintDoctorChecks(t *testing.T) {
+	var out bytes.Buffer
+	failed := printDoctorChecks(&out, []doctorCheck{
+		{name: "Clipboard", status: checkOK, detail: "available"},
+		{name: "gemini API key", status: checkSkipped, detail: "not set", fix: "run 'lamp auth set gemini'"},
+		{name: "openai API key", status: checkFailed, detail: "rejected", fix: "replace it"},
+	})
+	assert.Equal(t, 1, failed)
+	assert.Contains(t, out.String(), "Clipboard: available\n")
+	assert.Contains(t, out.String(), "- gemini API key: not set\n    Fix: run 'lamp auth set gemini'\n")
+	assert.Contains(t, out.String(), "openai API key: rejected\n    Fix: replace it\n")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSMTPPort is the submission port, upgraded to TLS with STARTTLS
+	defaultSMTPPort = 587
+	// smtpTimeout bounds the connection to the SMTP server
+	smtpTimeout = 30 * time.Second
+	// smtpPasswordEnvVar overrides the SMTP password of the config file
+	smtpPasswordEnvVar = "LAMP_SMTP_PASSWORD"
+)
+
+// SMTPSettings are the mail server settings of --email-to, read from the config file
+type SMTPSettings struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"` // Defaults to 587
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"` // Overridden by LAMP_SMTP_PASSWORD
+	From     string `json:"from"`
+	TLS      bool   `json:"tls,omitempty"` // Connect with TLS (usually port 465) instead of STARTTLS
+}
+
+// loadSMTPSettings returns the SMTP settings of the config file, with the password of the
+// environment if set
+func loadSMTPSettings() (SMTPSettings, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return SMTPSettings{}, err
+	}
+	if config.SMTP == nil || config.SMTP.Host == "" || config.SMTP.From == "" {
+		return SMTPSettings{}, fmt.Errorf("--email-to requires the smtp host and from address in the config file")
+	}
+	settings := *config.SMTP
+	if settings.Port == 0 {
+		settings.Port = defaultSMTPPort
+	}
+	if password := os.Getenv(smtpPasswordEnvVar); password != "" {
+		settings.Password = password
+	}
+	return settings, nil
+}
+
+// buildEmail returns a message with the analysis of a report as Markdown text and its
+// findings attached
+func buildEmail(from string, to []string, report runReport, date time.Time) ([]byte, error) {
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+
+	header := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		from, strings.Join(to, ", "), mime.QEncoding.Encode("utf-8", report.Title), date.Format(time.RFC1123Z), writer.Boundary())
+	message.WriteString(header)
+
+	text, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	body := strings.TrimSpace(report.Markdown) + "\n"
+	if findings := report.findingsMarkdown(); findings != "" {
+		body += "\n## Findings\n\n" + findings
+	}
+	qp := quotedprintable.NewWriter(text)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	var findings bytes.Buffer
+	if err := writeFindings(report.Findings, &findings); err != nil {
+		return nil, err
+	}
+	attachment, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {`attachment; filename="lamp-findings.sarif.json"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(findings.Bytes())
+	for len(encoded) > 76 {
+		_, _ = fmt.Fprintf(attachment, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	_, _ = fmt.Fprintf(attachment, "%s\r\n", encoded)
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// sendEmail emails a report to the recipients through the SMTP server of the settings
+func sendEmail(settings SMTPSettings, to []string, report runReport, out io.Writer) error {
+	message, err := buildEmail(settings.From, to, report, time.Now())
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	tlsConfig := tlsClientConfig(settings.Host)
+	var conn net.Conn
+	if settings.TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpTimeout}, "tcp", address, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, smtpTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("error connecting to the SMTP server: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("error connecting to the SMTP server: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !settings.TLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("error starting TLS with the SMTP server: %v", err)
+		}
+	}
+	if settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)); err != nil {
+			return fmt.Errorf("error authenticating with the SMTP server: %v", err)
+		}
+	}
+
+	if err := client.Mail(settings.From); err != nil {
+		return fmt.Errorf("error sending the email: %v", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("error sending the email to %s: %v", recipient, err)
+		}
+	}
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending the email: %v", err)
+	}
+	if _, err := data.Write(message); err != nil {
+		return fmt.Errorf("error sending the email: %v", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("error sending the email: %v", err)
+	}
+	_ = client.Quit()
+
+	_, _ = fmt.Fprintf(out, "Emailed the analysis to %s\n", strings.Join(to, ", "))
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one message without authentication and returns its envelope and data
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "MAIL"), strings.HasPrefix(line, "RCPT"):
+				lines = append(lines, line)
+				reply("250 OK")
+			case line == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				lines = append(lines, data.String())
+				reply("250 Queued")
+			case line == "QUIT":
+				reply("221 Bye")
+				received <- lines
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSendEmail(t *testing.T) {
+	address, received := fakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	settings := SMTPSettings{Host: host, Port: portNumber, From: "lamp@example.com"}
+	report := runReport{
+		Title:    "Log analysis of mattermost.log",
+		Markdown: "## Summary\n\nDatabase timeouts\n",
+		Findings: []Finding{{RuleID: findingPanic, Severity: "error", Title: "Panic", Message: "panic: boom", Count: 1}},
+	}
+	var out bytes.Buffer
+	require.NoError(t, sendEmail(settings, []string{"support@example.com", "oncall@example.com"}, report, &out))
+	assert.Equal(t, "Emailed the analysis to support@example.com, oncall@example.com\n", out.String())
+
+	var lines []string
+	select {
+	case lines = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email received")
+	}
+	require.Len(t, lines, 4)
+	assert.Equal(t, "MAIL FROM:<lamp@example.com>", lines[0])
+	assert.Equal(t, "RCPT TO:<support@example.com>", lines[1])
+
+	message, err := mail.ReadMessage(strings.NewReader(lines[3]))
+	require.NoError(t, err)
+	assert.Equal(t, "Log analysis of mattermost.log", message.Header.Get("Subject"))
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	text, err := parts.NextPart()
+	require.NoError(t, err)
+	body, err := io.ReadAll(text)
+	require.NoError(t, err)
+	assert.Equal(t, "## Summary\n\nDatabase timeouts\n\n## Findings\n\n- **Panic** (error, 1 entries, `lamp/panic`): panic: boom\n", strings.ReplaceAll(string(body), "\r\n", "\n"))
+	attachment, err := parts.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "lamp-findings.sarif.json", attachment.FileName())
+	encoded, err := io.ReadAll(attachment)
+	require.NoError(t, err)
+	sarif, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Contains(t, string(sarif), `"ruleId": "lamp/panic"`)
+}
+
+func TestLoadSMTPSettings(t *testing.T) {
+	path := useTempConfigDir(t)
+	_, err := loadSMTPSettings()
+	assert.EqualError(t, err, "--email-to requires the smtp host and from address in the config file")
+
+	require.NoError(t, writeConfigFile(Config{SMTP: &SMTPSettings{Host: "smtp.example.com", From: "lamp@example.com", Password: "file"}}, path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "a config file with an SMTP password is only readable by the user")
+
+	settings, err := loadSMTPSettings()
+	require.NoError(t, err)
+	assert.Equal(t, defaultSMTPPort, settings.Port)
+	assert.Equal(t, "file", settings.Password)
+
+	t.Setenv(smtpPasswordEnvVar, "env")
+	settings, err = loadSMTPSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "env", settings.Password)
+}
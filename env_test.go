@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVarName(t *testing.T) {
+	assert.Equal(t, "LAMP_LLM_PROVIDER", envVarName("llm-provider"))
+	assert.Equal(t, "LAMP_MAX_ENTRIES", envVarName("max-entries"))
+	assert.Equal(t, "LAMP_JSON", envVarName("json"))
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	var provider, level string
+	var trimmed bool
+	cmd := newProfileTestCommand(&provider, &level, &trimmed)
+	require.NoError(t, cmd.ParseFlags([]string{"--level", "warn"}))
+
+	t.Setenv("LAMP_LLM_PROVIDER", "ollama")
+	t.Setenv("LAMP_LEVEL", "error")
+	t.Setenv("LAMP_TRIM", "true")
+	require.NoError(t, applyEnvironment(cmd))
+	assert.Equal(t, "ollama", provider)
+	assert.Equal(t, "warn", level, "flags on the command line take precedence")
+	assert.True(t, trimmed)
+
+	// The environment takes precedence over the profile
+	require.NoError(t, applyProfile(cmd, `profile "triage"`, Profile{"llm-provider": "openai"}))
+	assert.Equal(t, "ollama", provider)
+
+	t.Setenv("LAMP_TRIM", "maybe")
+	err := applyEnvironment(newProfileTestCommand(&provider, &level, &trimmed))
+	assert.ErrorContains(t, err, "invalid value for LAMP_TRIM")
+}
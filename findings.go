@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// sarifSchema and sarifVersion identify the SARIF format of exported findings
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	// findingsMaxEvidence is the maximum number of entries cited by an exported finding
+	findingsMaxEvidence = 10
+)
+
+// Rule IDs of the findings detected by lamp itself. Findings of analysis rules use
+// rule/<id of the rule>.
+const (
+	findingErrorBurst = "lamp/error-burst"
+	findingPanic      = "lamp/panic"
+)
+
+// Finding is an issue detected in the logs, exported with --findings
+type Finding struct {
+	RuleID    string
+	Severity  string // error, warning or info
+	Title     string
+	Message   string
+	Key       string // Identifies the same issue across runs, e.g. the normalized panic message
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Evidence  []LogEntry // Entries the finding is based on, at most findingsMaxEvidence
+}
+
+// collectFindings returns the findings of the analysis rules, the error bursts and the panics
+// of the logs
+func collectFindings(analysis LogAnalysis, logs []LogEntry) []Finding {
+	var findings []Finding
+	for _, rule := range analysis.RuleFindings {
+		message := rule.Title
+		if rule.Summary != "" {
+			message += ": " + rule.Summary
+		}
+		findings = append(findings, Finding{
+			RuleID:    "rule/" + rule.ID,
+			Severity:  rule.Severity,
+			Title:     rule.Title,
+			Message:   message,
+			Key:       rule.ID,
+			Count:     rule.Count,
+			FirstSeen: rule.FirstSeen,
+			LastSeen:  rule.LastSeen,
+			Evidence:  rule.Evidence,
+		})
+	}
+
+	for _, burst := range analysis.Incidents.ErrorBursts {
+		finding := Finding{
+			RuleID:   findingErrorBurst,
+			Severity: "warning",
+			Title:    "Error burst",
+			Message: fmt.Sprintf("%d errors in %s, from %s to %s", burst.Count, burst.Duration().Round(time.Second),
+				burst.Start.Format("2006-01-02 15:04:05"), burst.End.Format("2006-01-02 15:04:05")),
+			Key:       burst.Start.UTC().Format(time.RFC3339),
+			Count:     burst.Count,
+			FirstSeen: burst.Start,
+			LastSeen:  burst.End,
+		}
+		for _, log := range logs {
+			if len(finding.Evidence) == findingsMaxEvidence {
+				break
+			}
+			if isErrorLevel(log.Level) && !log.Timestamp.Before(burst.Start) && !log.Timestamp.After(burst.End) {
+				finding.Evidence = append(finding.Evidence, log)
+			}
+		}
+		findings = append(findings, finding)
+	}
+
+	return append(findings, detectPanics(logs)...)
+}
+
+// isPanicEntry reports whether an entry logs a panic, recovered or not
+func isPanicEntry(log LogEntry) bool {
+	return strings.EqualFold(log.Level, "panic") || strings.Contains(strings.ToLower(log.Message), "panic")
+}
+
+// detectPanics returns a finding per distinct panic message, most frequent first
+func detectPanics(logs []LogEntry) []Finding {
+	byKey := make(map[string]*Finding)
+	var keys []string
+	for _, log := range logs {
+		if !isPanicEntry(log) {
+			continue
+		}
+		count := 1
+		if log.DuplicateCount > 1 {
+			count = log.DuplicateCount
+		}
+		key := normalizeLogMessage(log.Message)
+		finding, ok := byKey[key]
+		if !ok {
+			finding = &Finding{
+				RuleID:    findingPanic,
+				Severity:  "error",
+				Title:     "Panic",
+				Message:   log.Message,
+				Key:       key,
+				FirstSeen: log.Timestamp,
+				LastSeen:  log.Timestamp,
+			}
+			byKey[key] = finding
+			keys = append(keys, key)
+		}
+		finding.Count += count
+		if log.Timestamp.Before(finding.FirstSeen) {
+			finding.FirstSeen = log.Timestamp
+		}
+		if log.Timestamp.After(finding.LastSeen) {
+			finding.LastSeen = log.Timestamp
+		}
+		if len(finding.Evidence) < findingsMaxEvidence {
+			finding.Evidence = append(finding.Evidence, log)
+		}
+	}
+
+	findings := make([]Finding, 0, len(keys))
+	for _, key := range keys {
+		findings = append(findings, *byKey[key])
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Count > findings[j].Count
+	})
+	return findings
+}
+
+// sarifLog is the root of a SARIF file
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          sarifProperties   `json:"properties"`
+}
+
+// sarifProperties are the lamp-specific properties of a result
+type sarifProperties struct {
+	Title     string    `json:"title"`
+	Severity  string    `json:"severity"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          sarifMessage          `json:"message"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// fingerprint identifies the issue of a finding across runs
+func (f Finding) fingerprint() string {
+	sum := sha256.Sum256([]byte(f.RuleID + "\x00" + f.Key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// sarifLevel returns the SARIF level of a severity
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error":
+		return "error"
+	case "info":
+		return "note"
+	}
+	return "warning"
+}
+
+// newSARIFLog converts findings to a SARIF log. Each finding is a result citing its evidence
+// entries by file and line, with a fingerprint that stays the same across runs so that
+// automation can recognize issues it has already seen.
+func newSARIFLog(findings []Finding) sarifLog {
+	driver := sarifDriver{
+		Name:           "lamp",
+		Version:        currentVersion(),
+		InformationURI: "https://github.com/svelle/lamp",
+		Rules:          []sarifRule{},
+	}
+	results := []sarifResult{}
+	seenRules := make(map[string]bool)
+	for _, finding := range findings {
+		if !seenRules[finding.RuleID] {
+			seenRules[finding.RuleID] = true
+			driver.Rules = append(driver.Rules, sarifRule{
+				ID:                   finding.RuleID,
+				Name:                 finding.Title,
+				ShortDescription:     sarifMessage{Text: finding.Title},
+				DefaultConfiguration: sarifConfiguration{Level: sarifLevel(finding.Severity)},
+			})
+		}
+
+		result := sarifResult{
+			RuleID:              finding.RuleID,
+			Level:               sarifLevel(finding.Severity),
+			Message:             sarifMessage{Text: finding.Message},
+			PartialFingerprints: map[string]string{"lampFinding/v1": finding.fingerprint()},
+			Properties: sarifProperties{
+				Title:     finding.Title,
+				Severity:  finding.Severity,
+				Count:     finding.Count,
+				FirstSeen: finding.FirstSeen.UTC(),
+				LastSeen:  finding.LastSeen.UTC(),
+			},
+		}
+		for _, entry := range finding.Evidence {
+			if entry.SourceFile == "" {
+				continue
+			}
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(entry.SourceFile)}},
+				Message:          sarifMessage{Text: fmt.Sprintf("%s %s", porcelainTime(entry.Timestamp), truncateString(entry.Message, 200))},
+			}
+			if entry.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: entry.Line}
+			}
+			result.Locations = append(result.Locations, location)
+		}
+		results = append(results, result)
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+// writeFindings writes findings as an indented SARIF log
+func writeFindings(findings []Finding, w io.Writer) error {
+	data, err := json.MarshalIndent(newSARIFLog(findings), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// exportFindings writes the findings of the logs to a file, or to output if filePath is "-"
+func exportFindings(analysis LogAnalysis, logs []LogEntry, filePath string, output io.Writer) error {
+	findings := collectFindings(analysis, logs)
+	if filePath == "-" {
+		return writeFindings(findings, output)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	return writeFindings(findings, file)
+}
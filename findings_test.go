@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectFindings(t *testing.T) {
+	initLogger()
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+
+	var lines []string
+	lines = append(lines, `{"timestamp":"2025-01-01 10:00:00.000 Z","level":"info","msg":"Server is initializing..."}`)
+	for i := 0; i < 6; i++ {
+		lines = append(lines, fmt.Sprintf(`{"timestamp":"2025-01-01 10:10:0%d.000 Z","level":"error","msg":"Failed to ping DB: timeout"}`, i))
+	}
+	lines = append(lines,
+		`{"timestamp":"2025-01-01 10:20:00.000 Z","level":"error","msg":"Recovered from panic: index out of range [3]"}`,
+		`{"timestamp":"2025-01-01 10:25:00.000 Z","level":"error","msg":"Recovered from panic: index out of range [7]"}`)
+	path := filepath.Join(t.TempDir(), "mattermost.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	logs, err := parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 9)
+	assert.Equal(t, path, logs[1].SourceFile)
+	assert.Equal(t, 2, logs[1].Line)
+
+	rules, err := parseRules("team.rules", `when message contains "ping DB" then finding("DB unreachable", severity="error")`)
+	require.NoError(t, err)
+	analysis := analyzeLogs(logs, false, 10)
+	analysis.RuleFindings = evaluateRules(rules, logs, false)
+
+	findings := collectFindings(analysis, logs)
+	require.Len(t, findings, 3)
+
+	assert.Equal(t, "rule/db-unreachable", findings[0].RuleID)
+	assert.Equal(t, "DB unreachable", findings[0].Message)
+	assert.Len(t, findings[0].Evidence, 6)
+
+	assert.Equal(t, findingErrorBurst, findings[1].RuleID)
+	assert.Equal(t, 6, findings[1].Count)
+	assert.Equal(t, start.Add(10*time.Minute), findings[1].FirstSeen)
+	require.Len(t, findings[1].Evidence, 6)
+	assert.Equal(t, 2, findings[1].Evidence[0].Line)
+
+	assert.Equal(t, findingPanic, findings[2].RuleID)
+	assert.Equal(t, 2, findings[2].Count, "panics differing by numbers are the same finding")
+	assert.Equal(t, 8, findings[2].Evidence[0].Line)
+}
+
+func TestWriteFindings(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	findings := []Finding{
+		{
+			RuleID: "rule/db-unreachable", Severity: "error", Title: "DB unreachable", Message: "DB unreachable: check the database",
+			Key: "db-unreachable", Count: 2, FirstSeen: ts, LastSeen: ts.Add(time.Minute),
+			Evidence: []LogEntry{
+				{Timestamp: ts, Message: "Failed to ping DB", SourceFile: "logs/mattermost.log", Line: 12},
+				{Timestamp: ts, Message: "Failed to ping DB", SourceFile: "plugin.log"},
+				{Timestamp: ts, Message: "No file"},
+			},
+		},
+		{RuleID: "rule/slow", Severity: "info", Title: "Slow", Message: "Slow", Key: "slow", Count: 1},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeFindings(findings, &buf))
+
+	var sarif sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &sarif))
+	assert.Equal(t, "2.1.0", sarif.Version)
+	require.Len(t, sarif.Runs, 1)
+	run := sarif.Runs[0]
+	assert.Equal(t, "lamp", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "note", run.Tool.Driver.Rules[1].DefaultConfiguration.Level)
+
+	require.Len(t, run.Results, 2)
+	result := run.Results[0]
+	assert.Equal(t, "rule/db-unreachable", result.RuleID)
+	assert.Equal(t, "error", result.Level)
+	assert.Equal(t, 2, result.Properties.Count)
+	require.Len(t, result.Locations, 2, "entries without a file are not cited")
+	assert.Equal(t, "logs/mattermost.log", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, &sarifRegion{StartLine: 12}, result.Locations[0].PhysicalLocation.Region)
+	assert.Nil(t, result.Locations[1].PhysicalLocation.Region, "unknown lines have no region")
+	assert.Equal(t, "2025-01-01T10:00:00Z Failed to ping DB", result.Locations[0].Message.Text)
+
+	// Fingerprints only depend on the rule and key of a finding
+	findings[0].Count, findings[0].Evidence = 5, nil
+	again := newSARIFLog(findings)
+	assert.Equal(t, result.PartialFingerprints, again.Runs[0].Results[0].PartialFingerprints)
+	assert.NotEqual(t, result.PartialFingerprints, run.Results[1].PartialFingerprints)
+
+	// Without findings the file is still a valid SARIF log
+	buf.Reset()
+	require.NoError(t, writeFindings(nil, &buf))
+	assert.Contains(t, buf.String(), `"results": []`)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// followPollInterval is how often followed files are checked for new lines
+const followPollInterval = 500 * time.Millisecond
+
+// fileTailer reads the lines appended to a file since the last poll
+type fileTailer struct {
+	path    string
+	offset  int64
+	partial string // Incomplete last line, completed by a later poll
+}
+
+// newFileTailer creates a tailer that only reports lines written after it was created
+func newFileTailer(path string) (*fileTailer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileTailer{path: path, offset: info.Size()}, nil
+}
+
+// poll returns the complete lines appended since the last poll. A file that shrank is
+// assumed to have been truncated or rotated and is read again from the start.
+func (t *fileTailer) poll() ([]string, error) {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+		t.partial = ""
+	}
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(data))
+
+	text := t.partial + string(data)
+	lastNewline := strings.LastIndexByte(text, '\n')
+	if lastNewline < 0 {
+		t.partial = text
+		return nil, nil
+	}
+	t.partial = text[lastNewline+1:]
+
+	var lines []string
+	for _, line := range strings.Split(text[:lastNewline], "\n") {
+		if line = validUTF8(strings.TrimRight(line, "\r")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// followLogFiles polls files for new lines until stop is closed and passes the new entries
+// matching the command line filters to onEntries
+func followLogFiles(paths []string, stop <-chan struct{}, onEntries func([]LogEntry)) error {
+	regex, start, end, err := parseFilterOptions(regexSearch, startTime, endTime)
+	if err != nil {
+		return err
+	}
+
+	tailers := make([]*fileTailer, 0, len(paths))
+	for _, path := range paths {
+		tailer, err := newFileTailer(path)
+		if err != nil {
+			return err
+		}
+		tailers = append(tailers, tailer)
+	}
+
+	go func() {
+		ticker := time.NewTicker(followPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			var entries []LogEntry
+			for _, tailer := range tailers {
+				lines, err := tailer.poll()
+				if err != nil {
+					logger.Debug("failed to read followed file", "file", tailer.path, "error", err)
+					continue
+				}
+				for _, line := range lines {
+					entry, err := parseLine(line)
+					if err != nil {
+						continue
+					}
+					entry.Raw = line
+					entry.SourceFile = tailer.path
+					setEntryNode(&entry)
+					entry.Seq = nextEntrySeq()
+					if shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, start, end) {
+						entries = append(entries, entry)
+					}
+				}
+			}
+			if len(entries) > 0 {
+				onEntries(entries)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mattermost.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing line\n"), 0o644))
+
+	tailer, err := newFileTailer(path)
+	require.NoError(t, err)
+
+	lines, err := tailer.poll()
+	require.NoError(t, err)
+	assert.Empty(t, lines, "lines written before following are skipped")
+
+	appendToFile(t, path, "first\r\nsecond\nthi")
+	lines, err = tailer.poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, lines)
+
+	appendToFile(t, path, "rd\n")
+	lines, err = tailer.poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third"}, lines, "partial lines are completed by later writes")
+
+	// A truncated or rotated file is read from the start
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o644))
+	lines, err = tailer.poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rotated"}, lines)
+}
+
+func TestFollowLogFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mattermost.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	followed := make(chan []LogEntry, 1)
+	require.NoError(t, followLogFiles([]string{path}, stop, func(entries []LogEntry) { followed <- entries }))
+
+	appendToFile(t, path, `{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"followed","hostname":"app-1"}`+"\n")
+	select {
+	case entries := <-followed:
+		require.Len(t, entries, 1)
+		assert.Equal(t, "followed", entries[0].Message)
+		assert.Equal(t, path, entries[0].SourceFile)
+		assert.Equal(t, "app-1", entries[0].Node)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the new line was not followed")
+	}
+}
+
+func appendToFile(t *testing.T, path, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(text)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
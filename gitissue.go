@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// gitIssueTimeout bounds each request made to GitHub or GitLab
+	gitIssueTimeout = 30 * time.Second
+	// gitIssueMaxExcerpts is the maximum number of log lines quoted in an issue
+	gitIssueMaxExcerpts = 10
+	// gitIssueMaxBody is the longest issue body sent, below the limits of GitHub and GitLab
+	gitIssueMaxBody = 60000
+)
+
+// issueSettings are the repository and credentials of --create-issue
+type issueSettings struct {
+	Repo     string // e.g. owner/repo, https://github.com/owner/repo or https://gitlab.com/group/project
+	Platform string // github or gitlab, detected from the repository host if empty
+	Token    string
+	Labels   []string
+}
+
+// validate checks that the settings needed to open an issue are set
+func (s issueSettings) validate() error {
+	missing := []string{}
+	if s.Repo == "" {
+		missing = append(missing, "--issue-repo")
+	}
+	if s.Token == "" {
+		missing = append(missing, "--issue-token")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("--create-issue requires %s", strings.Join(missing, ", "))
+	}
+	_, err := parseIssueRepo(s.Repo, s.Platform)
+	return err
+}
+
+// issueRepo is a repository issues are opened in
+type issueRepo struct {
+	name     string // GitHub or GitLab, for messages
+	platform string // github or gitlab
+	apiURL   string // Base URL of the REST API
+	path     string // owner/repo, or group/subgroup/project on GitLab
+}
+
+// parseIssueRepo parses a repository as owner/repo (on github.com), host/path or a URL.
+// github.com is GitHub and hosts with gitlab in their name are GitLab; other hosts need
+// the platform, e.g. GitHub Enterprise or a self-managed GitLab.
+func parseIssueRepo(repo, platform string) (issueRepo, error) {
+	raw := strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	if !strings.Contains(raw, "://") {
+		if first, _, _ := strings.Cut(raw, "/"); !strings.Contains(first, ".") {
+			raw = "github.com/" + raw
+		}
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return issueRepo{}, fmt.Errorf("invalid --issue-repo %q: %v", repo, err)
+	}
+	path := strings.Trim(u.Path, "/")
+	if strings.Count(path, "/") < 1 {
+		return issueRepo{}, fmt.Errorf("invalid --issue-repo %q: expected owner/repo or a repository URL", repo)
+	}
+
+	if platform == "" {
+		switch {
+		case u.Host == "github.com":
+			platform = "github"
+		case strings.Contains(u.Host, "gitlab"):
+			platform = "gitlab"
+		default:
+			return issueRepo{}, fmt.Errorf("cannot tell whether %s is GitHub or GitLab, set --issue-platform", u.Host)
+		}
+	}
+
+	target := issueRepo{platform: platform, path: path}
+	switch platform {
+	case "github":
+		target.name = "GitHub"
+		if u.Host == "github.com" {
+			target.apiURL = "https://api.github.com"
+		} else {
+			target.apiURL = fmt.Sprintf("%s://%s/api/v3", u.Scheme, u.Host)
+		}
+		if strings.Count(path, "/") != 1 {
+			return issueRepo{}, fmt.Errorf("invalid --issue-repo %q: GitHub repositories are owner/repo", repo)
+		}
+	case "gitlab":
+		target.name = "GitLab"
+		target.apiURL = fmt.Sprintf("%s://%s/api/v4", u.Scheme, u.Host)
+	default:
+		return issueRepo{}, fmt.Errorf("invalid --issue-platform %q (expected github or gitlab)", platform)
+	}
+	return target, nil
+}
+
+// issueBody returns the redacted markdown of an issue: the analysis, the findings and an
+// excerpt of the log lines they are based on
+func issueBody(report runReport) string {
+	var body strings.Builder
+	body.WriteString("## Analysis\n\n")
+	body.WriteString(strings.TrimSpace(report.Markdown))
+	body.WriteString("\n")
+
+	if findings := report.findingsMarkdown(); findings != "" {
+		body.WriteString("\n## Findings\n\n")
+		body.WriteString(findings)
+	}
+
+	if excerpts := report.excerpts(gitIssueMaxExcerpts); len(excerpts) > 0 {
+		body.WriteString("\n## Log excerpts\n\n```\n")
+		for _, line := range excerpts {
+			body.WriteString(truncateString(line, 500))
+			body.WriteString("\n")
+		}
+		body.WriteString("```\n")
+	}
+	body.WriteString("\n_Filed by lamp; email addresses, IP addresses, IDs and secrets are redacted._\n")
+
+	text := redact(body.String())
+	if len(text) > gitIssueMaxBody {
+		text = strings.ToValidUTF8(text[:gitIssueMaxBody], "") + "\n\n_(truncated)_\n"
+	}
+	return text
+}
+
+// createGitIssue opens an issue with the report in a GitHub or GitLab repository
+func createGitIssue(settings issueSettings, report runReport, out io.Writer) error {
+	repo, err := parseIssueRepo(settings.Repo, settings.Platform)
+	if err != nil {
+		return err
+	}
+
+	var endpoint string
+	payload := map[string]any{"title": redact(report.Title)}
+	switch repo.platform {
+	case "github":
+		endpoint = fmt.Sprintf("%s/repos/%s/issues", repo.apiURL, repo.path)
+		payload["body"] = issueBody(report)
+		if len(settings.Labels) > 0 {
+			payload["labels"] = settings.Labels
+		}
+	case "gitlab":
+		endpoint = fmt.Sprintf("%s/projects/%s/issues", repo.apiURL, url.PathEscape(repo.path))
+		payload["description"] = issueBody(report)
+		if len(settings.Labels) > 0 {
+			payload["labels"] = strings.Join(settings.Labels, ",")
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if repo.platform == "github" {
+		request.Header.Set("Accept", "application/vnd.github+json")
+		request.Header.Set("Authorization", "Bearer "+settings.Token)
+	} else {
+		request.Header.Set("PRIVATE-TOKEN", settings.Token)
+	}
+
+	resp, err := newHTTPClient(gitIssueTimeout).Do(request)
+	if err != nil {
+		return fmt.Errorf("error creating the issue: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error creating the issue: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		var answer struct {
+			Message any `json:"message"` // A string on GitHub, a string or an object on GitLab
+		}
+		detail := ""
+		if json.Unmarshal(body, &answer) == nil && answer.Message != nil {
+			detail = fmt.Sprintf(": %v", answer.Message)
+		}
+		return fmt.Errorf("error creating the issue: %s answered with %s%s", repo.name, resp.Status, detail)
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"` // GitHub
+		WebURL  string `json:"web_url"`  // GitLab
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return fmt.Errorf("error reading the created issue: %v", err)
+	}
+	_, _ = fmt.Fprintf(out, "Created issue %s\n", created.HTMLURL+created.WebURL)
+	return nil
+}
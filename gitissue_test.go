@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIssueRepo(t *testing.T) {
+	for _, tc := range []struct {
+		repo, platform string
+		expected       issueRepo
+	}{
+		{"svelle/lamp", "", issueRepo{name: "GitHub", platform: "github", apiURL: "https://api.github.com", path: "svelle/lamp"}},
+		{"https://github.com/svelle/lamp.git", "", issueRepo{name: "GitHub", platform: "github", apiURL: "https://api.github.com", path: "svelle/lamp"}},
+		{"gitlab.com/support/team/incidents", "", issueRepo{name: "GitLab", platform: "gitlab", apiURL: "https://gitlab.com/api/v4", path: "support/team/incidents"}},
+		{"https://git.example.com/support/incidents", "gitlab", issueRepo{name: "GitLab", platform: "gitlab", apiURL: "https://git.example.com/api/v4", path: "support/incidents"}},
+		{"https://github.example.com/support/incidents", "github", issueRepo{name: "GitHub", platform: "github", apiURL: "https://github.example.com/api/v3", path: "support/incidents"}},
+	} {
+		repo, err := parseIssueRepo(tc.repo, tc.platform)
+		require.NoError(t, err, tc.repo)
+		assert.Equal(t, tc.expected, repo, tc.repo)
+	}
+
+	_, err := parseIssueRepo("lamp", "")
+	assert.ErrorContains(t, err, "expected owner/repo or a repository URL")
+	_, err = parseIssueRepo("https://git.example.com/support/incidents", "")
+	assert.ErrorContains(t, err, "cannot tell whether git.example.com is GitHub or GitLab")
+	_, err = parseIssueRepo("https://github.com/a/b/c", "")
+	assert.ErrorContains(t, err, "GitHub repositories are owner/repo")
+	_, err = parseIssueRepo("a/b", "gitea")
+	assert.ErrorContains(t, err, `invalid --issue-platform "gitea"`)
+}
+
+func TestIssueBody(t *testing.T) {
+	report := runReport{
+		Markdown: "Errors for user jane@example.com\n",
+		Findings: []Finding{{
+			RuleID: findingPanic, Severity: "error", Title: "Panic", Message: "panic: boom", Count: 2,
+			Evidence: []LogEntry{{Message: "panic: boom", Raw: `{"msg":"panic: boom","ip":"10.0.0.1"}`}},
+		}},
+	}
+	body := issueBody(report)
+	assert.Contains(t, body, "## Analysis\n\nErrors for user [email]\n")
+	assert.Contains(t, body, "- **Panic** (error, 2 entries, `lamp/panic`): panic: boom\n")
+	assert.Contains(t, body, "## Log excerpts\n\n```\n{\"msg\":\"panic: boom\",\"ip\":\"[ip]\"}\n```\n")
+}
+
+func TestCreateGitIssue(t *testing.T) {
+	var path, token string
+	var payload map[string]any
+	var decodeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		token = r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")
+		decodeErr = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"html_url": "https://github.example.com/support/incidents/issues/7", "web_url": ""}`))
+	}))
+	defer server.Close()
+
+	report := runReport{Title: "Log analysis of mattermost.log", Markdown: "All good"}
+	var out bytes.Buffer
+	settings := issueSettings{Repo: server.URL + "/support/incidents", Platform: "github", Token: "ghp_x", Labels: []string{"incident", "lamp"}}
+	require.NoError(t, createGitIssue(settings, report, &out))
+	assert.Equal(t, "Created issue https://github.example.com/support/incidents/issues/7\n", out.String())
+	require.NoError(t, decodeErr)
+	assert.Equal(t, "/api/v3/repos/support/incidents/issues", path)
+	assert.Equal(t, "Bearer ghp_x", token)
+	assert.Equal(t, "Log analysis of mattermost.log", payload["title"])
+	assert.Contains(t, payload["body"], "All good")
+	assert.Equal(t, []any{"incident", "lamp"}, payload["labels"])
+
+	settings = issueSettings{Repo: server.URL + "/support/team/incidents", Platform: "gitlab", Token: "glpat-x", Labels: []string{"incident", "lamp"}}
+	require.NoError(t, createGitIssue(settings, report, &out))
+	require.NoError(t, decodeErr)
+	assert.Equal(t, "/api/v4/projects/support%2Fteam%2Fincidents/issues", path)
+	assert.Equal(t, "glpat-x", token)
+	assert.Contains(t, payload["description"], "All good")
+	assert.Equal(t, "incident,lamp", payload["labels"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+	}))
+	defer failing.Close()
+	settings.Repo = failing.URL + "/support/incidents"
+	assert.EqualError(t, createGitIssue(settings, report, &out), "error creating the issue: GitLab answered with 404 Not Found: Not Found")
+}
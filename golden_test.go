@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files with the current output: go test -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "update the golden files of testdata/golden")
+
+// assertGolden compares output, with its colors stripped, to the golden file of a name in
+// testdata/golden, or writes the golden file with -update
+func assertGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	output = ansiEscape.ReplaceAll(output, nil)
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, output, 0o644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err, "run go test -run %s -update to create the golden file", t.Name())
+	assert.Equal(t, string(expected), string(output), "the output differs from %s, run go test -run %s -update to update it if expected", path, t.Name())
+}
+
+// goldenLogs are the entries of the golden files: a server restart, errors of a failing
+// database with a duplicate, and the delivery of push notifications
+func goldenLogs(t *testing.T) []LogEntry {
+	entry := func(timestamp, level, source, message, user string) LogEntry {
+		return LogEntry{Timestamp: mustParseTime(t, timestamp), Level: level, Source: source, Message: message, User: user,
+			Extras: map[string]string{}}
+	}
+	notification := func(timestamp, ackID, status, platform string) LogEntry {
+		return LogEntry{Timestamp: mustParseTime(t, timestamp), Level: "info", Message: "Notification " + status, LogSource: "notifications",
+			AckID: ackID, Type: "message", Status: status, Extras: map[string]string{"platform": platform}}
+	}
+	logs := []LogEntry{
+		entry("2025-01-06 09:00:00.000 Z", "info", "app/server.go:180", "Server is initializing...", ""),
+		entry("2025-01-06 09:00:02.000 Z", "info", "app/server.go:420", "Starting Server...", ""),
+		entry("2025-01-06 09:05:00.000 Z", "info", "app/login.go:88", "User logged in", "alice"),
+		entry("2025-01-06 09:10:00.000 Z", "warn", "sqlstore/store.go:310", "Slow query detected", ""),
+		entry("2025-01-06 09:12:00.000 Z", "error", "sqlstore/store.go:512", "Failed to connect to the database", ""),
+		entry("2025-01-06 09:12:30.000 Z", "error", "sqlstore/store.go:512", "Failed to connect to the database", ""),
+		entry("2025-01-06 10:20:00.000 Z", "error", "app/post.go:77", "Failed to create post", "bob"),
+		entry("2025-01-06 10:45:00.000 Z", "debug", "app/web.go:12", "Request handled", "alice"),
+		notification("2025-01-06 10:00:00.000 Z", "a1", "Sent", "ios"),
+		notification("2025-01-06 10:00:01.500 Z", "a1", "Received", "ios"),
+		notification("2025-01-06 10:01:00.000 Z", "a2", "Sent", "android"),
+		notification("2025-01-06 10:01:03.000 Z", "a2", "Received", "android"),
+		notification("2025-01-06 10:02:00.000 Z", "a3", "Sent", "android"),
+		notification("2025-01-06 10:02:00.500 Z", "a3", "Not Sent", "android"),
+	}
+	for i := range logs {
+		logs[i].Seq = int64(i + 1)
+	}
+	sortEntries(logs)
+	return logs
+}
+
+func TestGoldenAnalysis(t *testing.T) {
+	initLogger()
+	logs := goldenLogs(t)
+	deduplicated := trimDuplicateLogInfo(append([]LogEntry(nil), logs...))
+	require.Less(t, len(deduplicated), len(logs), "the golden logs have a duplicate")
+
+	tests := []struct {
+		name    string
+		logs    []LogEntry
+		dupes   bool
+		verbose bool
+	}{
+		{"analysis_compact.txt", logs, false, false},
+		{"analysis_verbose.txt", logs, false, true},
+		{"analysis_deduplicated_compact.txt", deduplicated, true, false},
+		{"analysis_deduplicated_verbose.txt", deduplicated, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			analyzeAndDisplayStats(tt.logs, &out, tt.dupes, tt.verbose, 10, false)
+			assertGolden(t, tt.name, out.Bytes())
+		})
+	}
+
+	t.Run("notification_stats.txt", func(t *testing.T) {
+		var notifications []LogEntry
+		for _, log := range logs {
+			if log.LogSource == "notifications" {
+				notifications = append(notifications, log)
+			}
+		}
+		var out bytes.Buffer
+		analyzeAndDisplayStats(notifications, &out, false, true, 10, false)
+		assertGolden(t, "notification_stats.txt", out.Bytes())
+	})
+}
+
+func TestGoldenExports(t *testing.T) {
+	logs := goldenLogs(t)
+	logs[2].Extras["request_id"] = "r1"
+	dir := t.TempDir()
+
+	t.Run("export.json", func(t *testing.T) {
+		path := filepath.Join(dir, "export.json")
+		require.NoError(t, writeLogsToJSON(logs, path))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assertGolden(t, "export.json", data)
+	})
+
+	t.Run("export.ndjson", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeLogsNDJSON(logs, &out))
+		assertGolden(t, "export.ndjson", out.Bytes())
+	})
+
+	t.Run("export.csv", func(t *testing.T) {
+		path := filepath.Join(dir, "export.csv")
+		require.NoError(t, exportToCSV(logs, path, csvOptions{Delimiter: ','}))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assertGolden(t, "export.csv", data)
+	})
+
+	t.Run("export_expanded.csv", func(t *testing.T) {
+		path := filepath.Join(dir, "export_expanded.csv")
+		require.NoError(t, exportToCSV(logs, path, csvOptions{Delimiter: ';', ExpandExtras: true}))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assertGolden(t, "export_expanded.csv", data)
+	})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// historyFileName is the file in the config directory recent runs are recorded in
+const historyFileName = "history.json"
+
+// historyLimit is the number of runs kept in the history
+const historyLimit = 20
+
+// historyExcludedFlags are not recorded in the history: secrets and flags that only make
+// sense for one run
+var historyExcludedFlags = []string{"api-key", "jira-token", "issue-token", "ticket-token", "webhook-secret", "splunk-token", "help"}
+
+// HistoryEntry is a recorded run of a command analyzing logs
+type HistoryEntry struct {
+	RunAt   time.Time `json:"run_at"`
+	Dir     string    `json:"dir"`     // Working directory, which relative flag paths refer to
+	Command string    `json:"command"` // file, notification or support-packet
+	Paths   []string  `json:"paths"`
+	Flags   []string  `json:"flags,omitempty"` // Flags given on the command line (see commandLineFlags)
+}
+
+// sameRun reports whether two entries record the same invocation
+func (h HistoryEntry) sameRun(other HistoryEntry) bool {
+	return h.Dir == other.Dir && h.Command == other.Command &&
+		slices.Equal(h.Paths, other.Paths) && slices.Equal(h.Flags, other.Flags)
+}
+
+// commandLine returns the entry as a lamp command line
+func (h HistoryEntry) commandLine() string {
+	words := append([]string{"lamp", h.Command}, h.Paths...)
+	words = append(words, h.Flags...)
+	for i, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\"'\\$`*?") {
+			words[i] = strconv.Quote(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// commandLineFlags returns the flags of a command that were given on the command line, as
+// --name=value or --name for true booleans. It must be called before the environment and the profile set other flags.
+func commandLineFlags(cmd *cobra.Command) []string {
+	var flags []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if slices.Contains(historyExcludedFlags, f.Name) {
+			return
+		}
+		value := f.Value.String()
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, item := range slice.GetSlice() {
+				flags = append(flags, fmt.Sprintf("--%s=%s", f.Name, item))
+			}
+			return
+		}
+		if f.Value.Type() == "bool" && value == "true" {
+			flags = append(flags, "--"+f.Name)
+			return
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%s", f.Name, value))
+	})
+	return flags
+}
+
+// historyPath returns the path of the history file
+func historyPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFileName), nil
+}
+
+// loadHistory reads the history, most recent run first. A missing file yields an empty
+// history.
+func loadHistory(path string) ([]HistoryEntry, error) {
+	var history []HistoryEntry
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("invalid history file %s: %v", path, err)
+	}
+	return history, nil
+}
+
+// recordHistory adds a run to the top of the history, removing an earlier identical run and
+// the runs beyond historyLimit
+func recordHistory(path string, entry HistoryEntry) error {
+	history, err := loadHistory(path)
+	if err != nil {
+		return err
+	}
+	history = slices.DeleteFunc(history, entry.sameRun)
+	history = append([]HistoryEntry{entry}, history...)
+	if len(history) > historyLimit {
+		history = history[:historyLimit]
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// recordRun records a run of a command analyzing logs with the flags given on its command
+// line. Failing to record it does not stop the run.
+func recordRun(cmd *cobra.Command, paths []string, flags []string) {
+	path, err := historyPath()
+	if err != nil {
+		logger.Debug("not recording the run in the history", "error", err)
+		return
+	}
+	dir, _ := os.Getwd()
+	entry := HistoryEntry{
+		RunAt:   time.Now(),
+		Dir:     dir,
+		Command: cmd.Name(),
+		Paths:   newSessionSource(cmd.Name(), paths).Paths,
+		Flags:   flags,
+	}
+	if err := recordHistory(path, entry); err != nil {
+		logger.Warn("failed to record the run in the history", "error", err)
+	}
+}
+
+// printHistory lists the runs of the history, numbered from 1 for the most recent
+func printHistory(w io.Writer, history []HistoryEntry) {
+	for i, entry := range history {
+		_, _ = fmt.Fprintf(w, "%2d  %s  %s\n", i+1, entry.RunAt.Local().Format("2006-01-02 15:04"), entry.commandLine())
+	}
+}
+
+// rerunHistory runs the command of a history entry again from its working directory, with
+// the environment and profile applied as on a new command line
+func rerunHistory(entry HistoryEntry) error {
+	var command *cobra.Command
+	switch entry.Command {
+	case "file":
+		command = fileCmd
+	case "notification":
+		command = notificationCmd
+	case "support-packet":
+		command = supportPacketCmd
+	default:
+		return fmt.Errorf("unsupported command %q in the history", entry.Command)
+	}
+
+	if entry.Dir != "" {
+		if err := os.Chdir(entry.Dir); err != nil {
+			return fmt.Errorf("error changing to the directory of the run: %v", err)
+		}
+	}
+	if err := command.ParseFlags(entry.Flags); err != nil {
+		return fmt.Errorf("error parsing the flags of the run: %v", err)
+	}
+	if err := command.ValidateArgs(entry.Paths); err != nil {
+		return err
+	}
+	if err := rootCmd.PersistentPreRunE(command, entry.Paths); err != nil {
+		return err
+	}
+	return command.RunE(command, entry.Paths)
+}
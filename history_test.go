@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandLineFlags(t *testing.T) {
+	var level, key string
+	var trimmed bool
+	var users []string
+	cmd := &cobra.Command{Use: "file"}
+	cmd.Flags().StringVar(&level, "level", "", "")
+	cmd.Flags().StringVar(&key, "api-key", "", "")
+	cmd.Flags().BoolVar(&trimmed, "trim", false, "")
+	cmd.Flags().StringSliceVar(&users, "users", nil, "")
+	cmd.Flags().Int("top", 10, "")
+
+	require.NoError(t, cmd.ParseFlags([]string{"--level", "error", "--api-key=secret", "--trim", "--users=a,b"}))
+	assert.Equal(t, []string{"--level=error", "--trim", "--users=a", "--users=b"}, commandLineFlags(cmd),
+		"the API key and flags left at their default are not recorded")
+}
+
+func TestRecordHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	history, err := loadHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	run := func(n int, flags ...string) HistoryEntry {
+		return HistoryEntry{
+			RunAt:   time.Date(2025, 1, 1, 10, n, 0, 0, time.UTC),
+			Dir:     "/logs",
+			Command: "file",
+			Paths:   []string{fmt.Sprintf("/logs/%d.log", n)},
+			Flags:   flags,
+		}
+	}
+	require.NoError(t, recordHistory(path, run(1)))
+	require.NoError(t, recordHistory(path, run(2, "--trim")))
+	again := run(1)
+	again.RunAt = again.RunAt.Add(time.Hour)
+	require.NoError(t, recordHistory(path, again))
+
+	history, err = loadHistory(path)
+	require.NoError(t, err)
+	require.Len(t, history, 2, "running the same command again moves it to the top")
+	assert.Equal(t, again, history[0])
+	assert.Equal(t, run(2, "--trim"), history[1])
+
+	for n := 3; n < 3+historyLimit; n++ {
+		require.NoError(t, recordHistory(path, run(n)))
+	}
+	history, err = loadHistory(path)
+	require.NoError(t, err)
+	assert.Len(t, history, historyLimit)
+	assert.Equal(t, run(2+historyLimit), history[0])
+}
+
+func TestHistoryCommandLine(t *testing.T) {
+	entry := HistoryEntry{
+		Command: "support-packet",
+		Paths:   []string{"/tmp/my packet.zip"},
+		Flags:   []string{"--level=error", "--search=connection refused", "--trim"},
+	}
+	assert.Equal(t, `lamp support-packet "/tmp/my packet.zip" --level=error "--search=connection refused" --trim`, entry.commandLine())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// httpTLSConfig is the TLS configuration of the connections to LLM providers and
+// integrations, set from --ca-cert and --insecure-skip-verify. Nil trusts the system roots.
+var httpTLSConfig *tls.Config
+
+// configureHTTPTLS trusts the certificates of a PEM file on top of the system roots, for
+// proxies that intercept TLS with a corporate CA, or turns off certificate verification
+func configureHTTPTLS(caCertFile string, insecure bool) error {
+	httpTLSConfig = nil
+	if caCertFile == "" && !insecure {
+		return nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caCertFile != "" {
+		pem, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return fmt.Errorf("error reading --ca-cert: %v", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("invalid --ca-cert %s: no PEM certificate found", caCertFile)
+		}
+		config.RootCAs = roots
+	}
+	if insecure {
+		logger.Warn("TLS certificates are not verified (--insecure-skip-verify)")
+	}
+	httpTLSConfig = config
+	return nil
+}
+
+// newHTTPClient returns a client with a timeout that goes through the proxy of HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY and uses the TLS configuration of configureHTTPTLS
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if httpTLSConfig != nil {
+		transport.TLSClientConfig = httpTLSConfig.Clone()
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// tlsClientConfig returns the TLS configuration of a connection to a server that is not made
+// over HTTP, such as SMTP
+func tlsClientConfig(serverName string) *tls.Config {
+	config := &tls.Config{}
+	if httpTLSConfig != nil {
+		config = httpTLSConfig.Clone()
+	}
+	config.ServerName = serverName
+	return config
+}
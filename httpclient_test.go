@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureHTTPTLS(t *testing.T) {
+	initLogger()
+	t.Cleanup(func() { httpTLSConfig = nil })
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	get := func() error {
+		resp, err := newHTTPClient(5 * time.Second).Get(server.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, configureHTTPTLS("", false))
+	err := get()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+
+	// The certificate of the test server stands for the CA of an intercepting proxy
+	caFile := filepath.Join(t.TempDir(), "proxy-ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certificate, 0o600))
+	require.NoError(t, configureHTTPTLS(caFile, false))
+	assert.NoError(t, get())
+	assert.Equal(t, "smtp.example.com", tlsClientConfig("smtp.example.com").ServerName)
+	assert.NotNil(t, tlsClientConfig("smtp.example.com").RootCAs)
+
+	require.NoError(t, configureHTTPTLS("", true))
+	assert.NoError(t, get())
+
+	notPEM := filepath.Join(t.TempDir(), "ca.der")
+	require.NoError(t, os.WriteFile(notPEM, server.Certificate().Raw, 0o600))
+	assert.EqualError(t, configureHTTPTLS(notPEM, false), "invalid --ca-cert "+notPEM+": no PEM certificate found")
+	assert.Nil(t, httpTLSConfig)
+}
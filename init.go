@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Places the setup wizard can store an API key in
+const (
+	keyStorageEnv      = "env"
+	keyStorageKeychain = "keychain"
+	keyStorageConfig   = "config"
+)
+
+// defaultOllamaHost is the Ollama server URL suggested by the setup wizard
+const defaultOllamaHost = "http://localhost:11434"
+
+// wizardOption is an answer offered by the setup wizard
+type wizardOption struct {
+	value string
+	label string
+}
+
+// setupWizard asks the questions of 'lamp init' and updates the configuration accordingly
+type setupWizard struct {
+	in         *bufio.Reader
+	out        io.Writer
+	readSecret func() (string, error) // Reads an API key, without echoing it on a terminal
+	purpose    string                 // What the answers are for, in the error of a closed input
+}
+
+// newSetupWizard returns a wizard reading answers from in and writing questions to out
+func newSetupWizard(in io.Reader, out io.Writer) *setupWizard {
+	w := &setupWizard{in: bufio.NewReader(in), out: out, purpose: "setup"}
+	w.readSecret = w.readLine
+	return w
+}
+
+// newTerminalSetupWizard returns a wizard for the standard input and output, hiding the API
+// key as it is typed when the input is a terminal
+func newTerminalSetupWizard() *setupWizard {
+	w := newSetupWizard(os.Stdin, os.Stdout)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		w.readSecret = func() (string, error) {
+			secret, err := term.ReadPassword(fd)
+			_, _ = fmt.Fprintln(w.out)
+			return string(secret), err
+		}
+	}
+	return w
+}
+
+// readLine reads an answer without its line ending
+func (w *setupWizard) readLine() (string, error) {
+	line, err := w.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", fmt.Errorf("%s aborted: no more input", w.purpose)
+	}
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ask asks a question and returns the answer, or the default for an empty answer
+func (w *setupWizard) ask(question, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		_, _ = fmt.Fprintf(w.out, "%s: ", question)
+	}
+	answer, err := w.readLine()
+	if err != nil || answer != "" {
+		return answer, err
+	}
+	return def, nil
+}
+
+// choose lists the options and asks for one by number or value. With other set, any value
+// that is not a number is accepted as well.
+func (w *setupWizard) choose(question string, options []wizardOption, def string, other bool) (string, error) {
+	for i, option := range options {
+		_, _ = fmt.Fprintf(w.out, "  %d) %s\n", i+1, option.label)
+	}
+	for {
+		answer, err := w.ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n >= 1 && n <= len(options) {
+				return options[n-1].value, nil
+			}
+		} else if other && answer != "" {
+			return answer, nil
+		} else {
+			for _, option := range options {
+				if option.value == answer {
+					return answer, nil
+				}
+			}
+		}
+		_, _ = fmt.Fprintf(w.out, "Please enter a number from 1 to %d\n", len(options))
+	}
+}
+
+// run asks for the LLM provider, its default model and where to keep its API key, and
+// stores the answers in the config defaults. The current settings are the default answers.
+func (w *setupWizard) run(config *Config) error {
+	_, _ = fmt.Fprintln(w.out, "This sets up AI analysis for lamp. Press Enter to keep the answer in brackets.")
+	_, _ = fmt.Fprintln(w.out)
+
+	defaults := maps.Clone(config.Defaults)
+	if defaults == nil {
+		defaults = make(Profile)
+	}
+
+	currentProvider := defaults["llm-provider"]
+	if currentProvider == "" {
+		currentProvider = string(ProviderAnthropic)
+	}
+	provider, err := w.choose("LLM provider", []wizardOption{
+		{string(ProviderAnthropic), "anthropic - Anthropic Claude"},
+		{string(ProviderOpenAI), "openai - OpenAI GPT"},
+		{string(ProviderGemini), "gemini - Google Gemini"},
+		{string(ProviderOllama), "ollama - local Ollama server, no API key needed"},
+	}, currentProvider, false)
+	if err != nil {
+		return err
+	}
+
+	var models []wizardOption
+	for _, model := range GetAvailableModels(LLMProvider(provider)) {
+		models = append(models, wizardOption{model.ID, fmt.Sprintf("%s - %s", model.ID, model.Name)})
+	}
+	currentModel := defaults["llm-model"]
+	if currentModel == "" || provider != defaults["llm-provider"] {
+		currentModel = GetDefaultModel(LLMProvider(provider))
+	}
+	_, _ = fmt.Fprintln(w.out)
+	model, err := w.choose("Default model (number or model name)", models, currentModel, true)
+	if err != nil {
+		return err
+	}
+
+	defaults["llm-provider"] = provider
+	defaults["llm-model"] = model
+	_, _ = fmt.Fprintln(w.out)
+	if LLMProvider(provider) == ProviderOllama {
+		currentHost := defaults["ollama-host"]
+		if currentHost == "" {
+			currentHost = defaultOllamaHost
+		}
+		host, err := w.ask("Ollama server URL", currentHost)
+		if err != nil {
+			return err
+		}
+		defaults["ollama-host"] = host
+	} else if err := w.setupAPIKey(config, LLMProvider(provider)); err != nil {
+		return err
+	}
+
+	config.Defaults = defaults
+	return nil
+}
+
+// setupAPIKey asks where to keep the API key of a provider and stores it in the keychain or
+// the config as requested
+func (w *setupWizard) setupAPIKey(config *Config, provider LLMProvider) error {
+	envVar := getAPIKeyEnvVar(provider)
+	current := keyStorageEnv
+	switch {
+	case hasKeychainKey(provider):
+		current = keyStorageKeychain
+	case config.APIKeys[string(provider)] != "":
+		current = keyStorageConfig
+	}
+
+	storage, err := w.choose("Where should the API key be kept", []wizardOption{
+		{keyStorageEnv, fmt.Sprintf("env - the %s environment variable, set in your shell profile", envVar)},
+		{keyStorageKeychain, "keychain - the system keychain (macOS Keychain, Windows Credential Manager, libsecret)"},
+		{keyStorageConfig, "config - the config file, in plain text readable only by you"},
+	}, current, false)
+	if err != nil {
+		return err
+	}
+
+	switch storage {
+	case keyStorageEnv:
+		delete(config.APIKeys, string(provider))
+		if getEnvAPIKey(envVar) != "" {
+			_, _ = fmt.Fprintf(w.out, "%s is set in this shell.\n", envVar)
+		} else {
+			_, _ = fmt.Fprintf(w.out, "Add this line to your shell profile:\n  export %s=<your API key>\n", envVar)
+		}
+	case keyStorageKeychain:
+		key, err := w.askAPIKey(provider, current == keyStorageKeychain)
+		if err != nil || key == "" {
+			return err
+		}
+		if err := keychainSet(string(provider), key); err != nil {
+			return fmt.Errorf("error storing the API key in the keychain: %v", err)
+		}
+		delete(config.APIKeys, string(provider))
+	case keyStorageConfig:
+		key, err := w.askAPIKey(provider, config.APIKeys[string(provider)] != "")
+		if err != nil || key == "" {
+			return err
+		}
+		if config.APIKeys == nil {
+			config.APIKeys = make(map[string]string)
+		}
+		config.APIKeys[string(provider)] = key
+	}
+
+	// A key left in the keychain would take precedence over the config file
+	if current == keyStorageKeychain && storage != keyStorageKeychain {
+		if err := keychainDelete(string(provider)); err != nil {
+			return fmt.Errorf("error removing the API key from the keychain: %v", err)
+		}
+	}
+	return nil
+}
+
+// askAPIKey reads the API key of a provider. With a key already stored, an empty answer
+// keeps it and returns an empty key.
+func (w *setupWizard) askAPIKey(provider LLMProvider, stored bool) (string, error) {
+	if stored {
+		_, _ = fmt.Fprintf(w.out, "%s API key (leave empty to keep the current key): ", provider)
+	} else {
+		_, _ = fmt.Fprintf(w.out, "%s API key: ", provider)
+	}
+	key, err := w.readSecret()
+	if err != nil {
+		return "", err
+	}
+	key = strings.TrimSpace(key)
+	if key == "" && !stored {
+		return "", fmt.Errorf("no API key entered")
+	}
+	return key, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupWizard(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	keychain := useFakeKeychain(t)
+
+	t.Run("API key in the config file", func(t *testing.T) {
+		var out bytes.Buffer
+		// Provider by number, an invalid model number, then a model by name, storage by name
+		wizard := newSetupWizard(strings.NewReader("2\n9\ngpt-4o\nconfig\nsk-test\n"), &out)
+		config := Config{Theme: "light"}
+
+		require.NoError(t, wizard.run(&config))
+		assert.Equal(t, Profile{"llm-provider": "openai", "llm-model": "gpt-4o"}, config.Defaults)
+		assert.Equal(t, map[string]string{"openai": "sk-test"}, config.APIKeys)
+		assert.Equal(t, "light", config.Theme)
+		assert.Contains(t, out.String(), "Please enter a number from 1 to")
+	})
+
+	t.Run("keeps the current settings", func(t *testing.T) {
+		config := Config{
+			Defaults: Profile{"llm-provider": "openai", "llm-model": "custom-model"},
+			APIKeys:  map[string]string{"openai": "sk-old"},
+		}
+		wizard := newSetupWizard(strings.NewReader("\n\n\n\n"), &bytes.Buffer{})
+
+		require.NoError(t, wizard.run(&config))
+		assert.Equal(t, Profile{"llm-provider": "openai", "llm-model": "custom-model"}, config.Defaults)
+		assert.Equal(t, "sk-old", config.APIKeys["openai"])
+	})
+
+	t.Run("API key in the environment", func(t *testing.T) {
+		var out bytes.Buffer
+		config := Config{APIKeys: map[string]string{"openai": "sk-old"}}
+		wizard := newSetupWizard(strings.NewReader("openai\n\nenv\n"), &out)
+
+		require.NoError(t, wizard.run(&config))
+		assert.Empty(t, config.APIKeys)
+		assert.Equal(t, GetDefaultModel(ProviderOpenAI), config.Defaults["llm-model"])
+		assert.Contains(t, out.String(), "export OPENAI_API_KEY=<your API key>")
+	})
+
+	t.Run("API key in the keychain", func(t *testing.T) {
+		config := Config{APIKeys: map[string]string{"gemini": "old"}}
+		wizard := newSetupWizard(strings.NewReader("gemini\n\nkeychain\ngm-test\n"), &bytes.Buffer{})
+
+		require.NoError(t, wizard.run(&config))
+		assert.Equal(t, "gm-test", keychain["gemini"])
+		assert.Empty(t, config.APIKeys, "the key is removed from the config file")
+
+		// Moving the key back to the config file removes it from the keychain
+		wizard = newSetupWizard(strings.NewReader("\n\nconfig\ngm-config\n"), &bytes.Buffer{})
+		require.NoError(t, wizard.run(&config))
+		assert.Equal(t, "gm-config", config.APIKeys["gemini"])
+		assert.NotContains(t, keychain, "gemini")
+	})
+
+	t.Run("Ollama", func(t *testing.T) {
+		config := Config{}
+		wizard := newSetupWizard(strings.NewReader("4\nllama3:8b\nhttp://ollama:11434\n"), &bytes.Buffer{})
+
+		require.NoError(t, wizard.run(&config))
+		assert.Equal(t, Profile{"llm-provider": "ollama", "llm-model": "llama3:8b", "ollama-host": "http://ollama:11434"}, config.Defaults)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := newSetupWizard(strings.NewReader("1\n\nconfig\n\n"), &bytes.Buffer{}).run(&Config{})
+		assert.ErrorContains(t, err, "no API key entered")
+
+		err = newSetupWizard(strings.NewReader("1\n"), &bytes.Buffer{}).run(&Config{})
+		assert.ErrorContains(t, err, "setup aborted")
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Strategies of --on-error for the inputs that cannot be read
+const (
+	onErrorSkip    = "skip"    // Warn and go on with the other inputs
+	onErrorAbort   = "abort"   // Stop at the first input that cannot be read
+	onErrorCollect = "collect" // Go on with the other inputs, then fail listing the errors
+)
+
+// onErrorStrategies are the values of --on-error
+var onErrorStrategies = []string{onErrorSkip, onErrorAbort, onErrorCollect}
+
+// inputError is an input that could not be read
+type inputError struct {
+	Path string
+	Err  error
+}
+
+// inputErrorPolicy applies --on-error to the files, archives and files of support packets
+// that cannot be read or parsed, when a command reads several of them. A command reading a
+// single input fails when it cannot be read, whatever the strategy.
+type inputErrorPolicy struct {
+	strategy string
+	errors   []inputError
+}
+
+// inputErrors is the policy of the running command, set up from --on-error
+var inputErrors = &inputErrorPolicy{strategy: onErrorSkip}
+
+// newInputErrorPolicy returns the policy of a strategy of --on-error
+func newInputErrorPolicy(strategy string) (*inputErrorPolicy, error) {
+	if !contains(onErrorStrategies, strategy) {
+		return nil, fmt.Errorf("invalid --on-error value %q, must be skip, abort or collect", strategy)
+	}
+	return &inputErrorPolicy{strategy: strategy}, nil
+}
+
+// handle applies the strategy to an input that could not be read. It returns the error to
+// stop with for abort, and records it otherwise, warning right away for skip.
+func (p *inputErrorPolicy) handle(path string, err error) error {
+	if p.strategy == onErrorAbort {
+		return fmt.Errorf("%s: %w (stopping, see --on-error)", path, err)
+	}
+	p.errors = append(p.errors, inputError{Path: path, Err: err})
+	if p.strategy == onErrorSkip {
+		logger.Warn("Input cannot be read, skipping", "file", path, "error", err)
+	}
+	return nil
+}
+
+// finish writes the summary of the inputs that could not be read, if any, and returns an
+// error for collect so that the command fails
+func (p *inputErrorPolicy) finish(w io.Writer) error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintf(w, "%d input(s) could not be read:\n", len(p.errors))
+	for _, e := range p.errors {
+		_, _ = fmt.Fprintf(w, "  %s: %v\n", e.Path, e.Err)
+	}
+	if p.strategy == onErrorCollect {
+		return fmt.Errorf("%d input(s) could not be read", len(p.errors))
+	}
+	return nil
+}
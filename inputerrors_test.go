@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputErrorPolicy(t *testing.T) {
+	initLogger()
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "mattermost.log")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"ok"}`+"\n"), 0o600))
+	missing := filepath.Join(dir, "missing.log")
+	badPacket := filepath.Join(dir, "bad.zip")
+	require.NoError(t, os.WriteFile(badPacket, []byte("not a zip"), 0o600))
+
+	_, err := newInputErrorPolicy("ignore")
+	assert.Error(t, err)
+
+	tests := []struct {
+		strategy   string
+		wantErr    bool
+		wantLogs   int
+		wantFinish bool
+	}{
+		{onErrorSkip, false, 1, false},
+		{onErrorAbort, true, 0, false},
+		{onErrorCollect, false, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			policy, err := newInputErrorPolicy(tt.strategy)
+			require.NoError(t, err)
+			defer func(saved *inputErrorPolicy) { inputErrors = saved }(inputErrors)
+			inputErrors = policy
+
+			logs, err := parseLogPaths([]string{valid, missing, badPacket}, "")
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "missing.log")
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, logs, tt.wantLogs)
+
+			var summary bytes.Buffer
+			err = policy.finish(&summary)
+			assert.Equal(t, tt.wantFinish, err != nil)
+			assert.Contains(t, summary.String(), "2 input(s) could not be read")
+			assert.Contains(t, summary.String(), "missing.log")
+			assert.Contains(t, summary.String(), "bad.zip")
+		})
+	}
+
+	t.Run("a single input fails whatever the strategy", func(t *testing.T) {
+		_, err := parseLogPaths([]string{missing}, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("no input can be read", func(t *testing.T) {
+		defer func(saved *inputErrorPolicy) { inputErrors = saved }(inputErrors)
+		inputErrors = &inputErrorPolicy{strategy: onErrorSkip}
+		_, err := parseLogPaths([]string{missing, badPacket}, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("unreadable files of a support packet", func(t *testing.T) {
+		defer func(saved *inputErrorPolicy) { inputErrors = saved }(inputErrors)
+		path := writePacket(t, map[string]string{
+			"packet/logs/mattermost.log": `{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"ok"}` + "\n",
+			"packet/logs/export.json":    `[{"timestamp":`,
+		})
+
+		inputErrors = &inputErrorPolicy{strategy: onErrorCollect}
+		logs, err := parseSupportPacket(path, packetLogSelection{}, "", "", "", "", "", "")
+		require.NoError(t, err)
+		assert.Len(t, logs, 1)
+		require.Len(t, inputErrors.errors, 1)
+		assert.Equal(t, path+":packet/logs/export.json", inputErrors.errors[0].Path)
+
+		inputErrors = &inputErrorPolicy{strategy: onErrorAbort}
+		_, err = parseSupportPacket(path, packetLogSelection{}, "", "", "", "", "", "")
+		assert.Error(t, err)
+	})
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -10,189 +13,534 @@ import (
 	"github.com/rivo/tview"
 )
 
-// launchInteractiveMode starts the interactive TUI for exploring logs
-func launchInteractiveMode(logs []LogEntry) error {
-	if len(logs) == 0 {
+// logExplorer holds the state of the interactive TUI
+type logExplorer struct {
+	app         *tview.Application
+	pages       *tview.Pages
+	logs        []LogEntry // All loaded entries, sorted by timestamp
+	filtered    []LogEntry // Entries matching the current filter (and open group)
+	matching    []int      // Indexes into logs of the entries matching the current filter
+	visible     []int      // Indexes into logs of the entries shown in the list
+	filter      string
+	filterExpr  logFilter  // Parsed filter, applied to entries appended while following
+	groupBy     string     // Current grouping mode, empty when not grouped
+	groups      []logGroup // Groups shown in the list while grouped and no group is open
+	openGroup   string     // Key of the expanded group, empty when showing the group list
+	sortBy      string     // Current sort mode, empty for timestamp order
+	search      logSearch  // Current search, highlighted without hiding other entries
+	searchHits  int        // Number of visible entries matching the search
+	bucket      *TimeRange // Histogram bucket the list is restricted to, nil for all
+	histogram   *histogramView
+	bookmarks   map[int]string // Marked entries by index into logs, with optional notes
+	logTable    *tview.Table
+	details     *tview.TextView
+	statsPanel  *tview.TextView
+	filterInput *tview.InputField
+	statusBar   *tview.TextView
+	body        *tview.Flex
+	showStats   bool
+	following   bool // Whether new entries are appended as they are written
+	autoScroll  bool // Whether the list follows new entries
+	newErrors   int  // Errors appended below the selection while not auto-scrolling
+
+	filters      *filterStore // Recent and saved filters, persisted in the config directory
+	historyPos   int          // Position in the filter history shown in the filter input, -1 if none
+	historyDraft string       // Text typed in the filter input before browsing the history
+
+	sourceDir     string         // Local server checkout callers are opened from
+	serverVersion string         // Server version the GitHub links point at, empty if unknown
+	source        *SessionSource // How the logs were loaded, recorded in saved sessions
+
+	analysis        *aiAnalysis // AI analysis shown in the findings panel, nil if none was run
+	findingsPanel   *tview.List
+	showFindings    bool
+	findingEvidence [][]int      // Indexes into logs of the entries cited by each finding
+	evidence        map[int]bool // Entries cited by the finding selected last
+}
+
+// launchInteractiveMode starts the interactive TUI for exploring logs. When followPaths is
+// not empty, entries appended to these files are added while the TUI runs. The findings of
+// an AI analysis, if not nil, are listed in the findings panel.
+func launchInteractiveMode(logs []LogEntry, followPaths []string, analysis *aiAnalysis) error {
+	if len(logs) == 0 && len(followPaths) == 0 {
 		return fmt.Errorf("no log entries to display")
 	}
 
-	// Sort logs by timestamp
-	sort.Slice(logs, func(i, j int) bool {
-		return logs[i].Timestamp.Before(logs[j].Timestamp)
-	})
+	// Select the theme before creating widgets, which read the tview default styles
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	theme, err = resolveTheme(themeName, config)
+	if err != nil {
+		return err
+	}
+	theme.apply()
 
-	app := tview.NewApplication()
+	filters, err := newExplorerFilterStore()
+	if err != nil {
+		return fmt.Errorf("failed to load filter history: %v", err)
+	}
+
+	// Sort logs by timestamp
+	sortEntries(logs)
+
+	explorer := &logExplorer{
+		app:        tview.NewApplication(),
+		logs:       logs,
+		bookmarks:  make(map[int]string),
+		following:  len(followPaths) > 0,
+		autoScroll: true,
+		filters:    filters,
+		historyPos: -1,
+
+		sourceDir:     sourceDir,
+		serverVersion: serverVersion,
+		source:        loadedSource,
+	}
 
 	// Create main layout
 	flex := tview.NewFlex().SetDirection(tview.FlexRow)
 
 	// Create header
 	header := tview.NewTextView().
-		SetTextColor(tcell.ColorAqua).
-		SetText("Mattermost Log Explorer - Press Ctrl+C to exit, Arrow keys to navigate, Enter to view details").
+		SetTextColor(theme.color(theme.Header)).
+		SetText("Mattermost Log Explorer - Press ? for key bindings, Ctrl+C to exit").
 		SetTextAlign(tview.AlignCenter)
 
-	// Create log list
-	logList := tview.NewList().
-		SetHighlightFullLine(true).
-		SetSelectedBackgroundColor(tcell.ColorDarkBlue)
+	// Create log table. Rows are rendered on demand, so only visible rows cost anything.
+	explorer.logTable = tview.NewTable().
+		SetSelectable(true, false).
+		SetSelectedStyle(tcell.StyleDefault.Background(theme.color(theme.Selection)).Foreground(theme.color(theme.SelectionText))).
+		SetContent(&logTableContent{explorer: explorer})
+	explorer.logTable.SetSelectionChangedFunc(func(row, _ int) {
+		explorer.showSelection(row)
+		explorer.selectionMoved(row)
+	})
+	explorer.logTable.SetSelectedFunc(func(row, _ int) {
+		explorer.openSelection(row)
+	})
 
 	// Create details view
-	details := tview.NewTextView()
-	details.SetDynamicColors(true).
+	explorer.details = tview.NewTextView()
+	explorer.details.SetDynamicColors(true).
 		SetBorder(true).
 		SetTitle("Log Details")
 
+	// Create stats panel (hidden until toggled)
+	explorer.statsPanel = tview.NewTextView()
+	explorer.statsPanel.SetDynamicColors(true).
+		SetBorder(true).
+		SetTitle("Stats")
+
+	// Create findings panel (hidden until toggled)
+	explorer.findingsPanel = explorer.newFindingsPanel()
+
 	// Create filter input
-	filterInput := tview.NewInputField().
+	explorer.filterInput = tview.NewInputField().
 		SetLabel("Filter: ").
-		SetFieldWidth(40)
+		SetFieldWidth(60).
+		SetPlaceholder("text, /regex, key=value, level>=warn (Up/Down for history)")
+	explorer.filterInput.SetInputCapture(explorer.handleFilterHistoryKey)
 
 	// Set done function for filter input
-	filterInput.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEnter {
-			updateLogList(logList, logs, filterInput.GetText(), details)
+	explorer.filterInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			filter := explorer.filterInput.GetText()
+			explorer.applyFilter(filter)
+			if explorer.filter == filter {
+				explorer.rememberFilter(filter)
+			}
+			explorer.app.SetFocus(explorer.logTable)
+		case tcell.KeyEscape:
+			explorer.app.SetFocus(explorer.logTable)
 		}
 	})
 
+	// Create histogram of the matching entries
+	explorer.histogram = newHistogramView(explorer)
+
 	// Create status bar
-	statusBar := tview.NewTextView().
-		SetTextColor(tcell.ColorYellow).
-		SetText(fmt.Sprintf("Total logs: %d | Time range: %s to %s",
-			len(logs),
-			logs[0].Timestamp.Format("2006-01-02 15:04:05"),
-			logs[len(logs)-1].Timestamp.Format("2006-01-02 15:04:05")))
+	explorer.statusBar = tview.NewTextView().
+		SetTextColor(theme.color(theme.Label))
 
 	// Add components to layout
+	explorer.body = tview.NewFlex().
+		AddItem(explorer.logTable, 0, 2, true).
+		AddItem(explorer.details, 0, 3, false)
 	flex.AddItem(header, 1, 1, false).
-		AddItem(filterInput, 1, 1, true).
-		AddItem(tview.NewFlex().
-			AddItem(logList, 0, 2, true).
-			AddItem(details, 0, 3, false), 0, 10, false).
-		AddItem(statusBar, 1, 1, false)
+		AddItem(explorer.filterInput, 1, 1, false).
+		AddItem(explorer.histogram, histogramHeight, 0, false).
+		AddItem(explorer.body, 0, 10, true).
+		AddItem(explorer.statusBar, 1, 1, false)
 
 	// Initialize log list
-	updateLogList(logList, logs, "", details)
+	explorer.applyFilter("")
+	if resumedSession != nil {
+		explorer.restoreSession(*resumedSession)
+	}
+	if analysis != nil {
+		explorer.loadAnalysis(analysis)
+	}
 
 	// Set up key handlers
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyTab {
-			// Toggle focus between filter and list
-			if filterInput.HasFocus() {
-				app.SetFocus(logList)
-			} else {
-				app.SetFocus(filterInput)
-			}
-			return nil
+	explorer.app.SetInputCapture(explorer.handleKey)
+
+	// Append new entries of followed files
+	if explorer.following {
+		stop := make(chan struct{})
+		defer close(stop)
+		err := followLogFiles(followPaths, stop, func(entries []LogEntry) {
+			explorer.app.QueueUpdateDraw(func() {
+				explorer.appendEntries(entries)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to follow log files: %v", err)
 		}
-		return event
-	})
+	}
 
 	// Run application
-	if err := app.SetRoot(flex, true).EnableMouse(true).Run(); err != nil {
+	explorer.pages = tview.NewPages().AddPage("main", flex, true, true)
+	if err := explorer.app.SetRoot(explorer.pages, true).EnableMouse(true).Run(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// updateLogList refreshes the log list with filtered entries
-func updateLogList(list *tview.List, logs []LogEntry, filter string, detailsView *tview.TextView) {
-	list.Clear()
+// applyFilter updates the filtered entries, the list and the stats panel. An invalid
+// filter expression is reported in the status bar and leaves the list unchanged.
+func (e *logExplorer) applyFilter(filter string) {
+	matching, err := filterLogIndices(e.logs, filter)
+	if err != nil {
+		e.statusBar.SetText(fmt.Sprintf("Invalid filter: %v", err))
+		return
+	}
 
-	filterLower := strings.ToLower(filter)
-	var filteredLogs []LogEntry
+	e.filter = filter
+	e.filterExpr, _ = parseFilterExpression(filter)
+	e.matching = matching
+	e.bucket = nil
+	e.histogram.invalidate()
+	e.refreshView()
+}
 
-	// Apply filter
-	if filter == "" {
-		filteredLogs = logs
-	} else {
-		for _, log := range logs {
-			if strings.Contains(strings.ToLower(log.Message), filterLower) ||
-				strings.Contains(strings.ToLower(log.Level), filterLower) ||
-				strings.Contains(strings.ToLower(log.Source), filterLower) {
-				filteredLogs = append(filteredLogs, log)
+// refreshView rebuilds the list from the matching entries, showing either the entries
+// or, when grouping is enabled, the groups or the entries of the open group
+func (e *logExplorer) refreshView() {
+	base := e.matching
+	if e.bucket != nil {
+		base = nil
+		for _, index := range e.matching {
+			if e.inBucket(index) {
+				base = append(base, index)
 			}
 		}
 	}
 
-	// Add logs to list
-	for i, log := range filteredLogs {
-		levelColor := getLevelColorName(log.Level)
-		timestamp := log.Timestamp.Format("15:04:05")
+	shown := base
+	if e.groupBy != "" && e.openGroup != "" {
+		shown = nil
+		for _, index := range base {
+			if e.inOpenGroup(index) {
+				shown = append(shown, index)
+			}
+		}
+	}
+
+	e.filtered = make([]LogEntry, len(shown))
+	for i, index := range shown {
+		e.filtered[i] = e.logs[index]
+	}
+
+	if e.groupBy != "" && e.openGroup == "" {
+		e.visible = nil
+		e.groups = groupLogIndices(e.logs, base, e.groupBy)
+		e.updateGroupList()
+	} else {
+		e.visible = sortLogIndices(e.logs, shown, e.sortBy)
+		e.updateLogList()
+	}
+
+	e.countHits()
+	e.updateStatusBar()
+	if e.showStats {
+		e.statsPanel.SetText(formatStatsPanel(e.filtered, len(e.logs)))
+	}
+}
+
+// inBucket reports whether an entry is in the selected histogram bucket, if any
+func (e *logExplorer) inBucket(index int) bool {
+	if e.bucket == nil {
+		return true
+	}
+	ts := e.logs[index].Timestamp
+	return !ts.Before(e.bucket.Start) && ts.Before(e.bucket.End)
+}
+
+// inOpenGroup reports whether an entry is in the expanded group, if any
+func (e *logExplorer) inOpenGroup(index int) bool {
+	return e.groupBy == "" || e.openGroup == "" || groupKey(e.logs[index], e.groupBy) == e.openGroup
+}
+
+// updateStatusBar shows the number of matching entries and the loaded time range
+func (e *logExplorer) updateStatusBar() {
+	grouping := ""
+	if e.groupBy != "" {
+		grouping = fmt.Sprintf(" | Grouped by %s", e.groupBy)
+		if e.openGroup != "" {
+			grouping += fmt.Sprintf(": %s (Esc to go back)", e.openGroup)
+		}
+	}
+	if e.bucket != nil {
+		grouping += fmt.Sprintf(" | %s to %s", e.bucket.Start.Format("15:04"), e.bucket.End.Format("15:04"))
+	}
+	if e.sortBy != "" {
+		grouping += fmt.Sprintf(" | Sorted by %s", sortDescriptions[e.sortBy])
+	}
+
+	search := ""
+	if e.search.expr != "" {
+		search = fmt.Sprintf(" | Search %q: %d hits", e.search.expr, e.searchHits)
+	}
+
+	live := ""
+	if e.following {
+		live = " | LIVE"
+		if !e.autoScroll {
+			live += " (paused, f to resume)"
+		}
+		if e.newErrors > 0 {
+			live += fmt.Sprintf(" | %d NEW ERRORS BELOW", e.newErrors)
+		}
+	}
+
+	timeRange := "no entries yet"
+	if len(e.logs) > 0 {
+		timeRange = fmt.Sprintf("%s to %s",
+			e.logs[0].Timestamp.Format("2006-01-02 15:04:05"),
+			e.logs[len(e.logs)-1].Timestamp.Format("2006-01-02 15:04:05"))
+	}
+
+	e.statusBar.SetText(fmt.Sprintf("Showing %d of %d logs | %d marked%s%s%s | Time range: %s",
+		len(e.filtered),
+		len(e.logs),
+		len(e.bookmarks),
+		grouping,
+		search,
+		live,
+		timeRange))
+}
+
+// toggleStats shows or hides the stats panel next to the details view
+func (e *logExplorer) toggleStats() {
+	e.showStats = !e.showStats
+	if e.showStats {
+		e.statsPanel.SetText(formatStatsPanel(e.filtered, len(e.logs)))
+		e.body.AddItem(e.statsPanel, 0, 2, false)
+	} else {
+		e.body.RemoveItem(e.statsPanel)
+	}
+}
+
+// filterLogEntries returns the entries matching a filter expression (see parseFilterExpression)
+func filterLogEntries(logs []LogEntry, filter string) ([]LogEntry, error) {
+	indexes, err := filterLogIndices(logs, filter)
+	if err != nil {
+		return nil, err
+	}
 
-		message := truncateString(log.Message, 80)
-		if log.DuplicateCount > 1 {
-			message = fmt.Sprintf("%s [yellow](×%d)", message, log.DuplicateCount)
+	filteredLogs := make([]LogEntry, 0, len(indexes))
+	for _, index := range indexes {
+		filteredLogs = append(filteredLogs, logs[index])
+	}
+	return filteredLogs, nil
+}
+
+// filterLogIndices returns the indexes of the entries matching a filter expression
+func filterLogIndices(logs []LogEntry, filter string) ([]int, error) {
+	var parsed logFilter
+	if strings.TrimSpace(filter) != "" {
+		var err error
+		if parsed, err = parseFilterExpression(filter); err != nil {
+			return nil, err
 		}
+	}
 
-		list.AddItem(
-			fmt.Sprintf("[%s]%s[white] [%s] %s",
-				levelColor,
-				log.Level,
-				timestamp,
-				message),
-			log.Source,
-			0,
-			func(index int) func() {
-				return func() {
-					showLogDetails(filteredLogs[index], detailsView)
-				}
-			}(i),
-		)
-	}
-
-	// Select first item if available
-	if list.GetItemCount() > 0 {
-		list.SetCurrentItem(0)
-		showLogDetails(filteredLogs[0], detailsView)
+	var indexes []int
+	for i, log := range logs {
+		if parsed.matches(log) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// updateLogList shows the visible entries in the log table, selecting the first one
+func (e *logExplorer) updateLogList() {
+	e.logTable.Select(0, 0).ScrollToBeginning()
+	if len(e.visible) > 0 {
+		e.showDetails(0)
 	} else {
-		detailsView.SetText("No matching logs found")
+		e.details.SetText("No matching logs found")
 	}
 }
 
-// showLogDetails displays detailed information about a log entry
-func showLogDetails(log LogEntry, view *tview.TextView) {
-	var sb strings.Builder
+// showDetails shows the i-th visible entry, including its bookmark note, in the details view
+func (e *logExplorer) showDetails(i int) {
+	text := formatLogDetails(e.logs[e.visible[i]], e.search.highlight)
+	if note, ok := e.bookmarks[e.visible[i]]; ok {
+		if note != "" {
+			text = fmt.Sprintf("%s %s\n\n", colorText(theme.Bookmark, "Bookmarked:"), tview.Escape(note)) + text
+		} else {
+			text = colorText(theme.Bookmark, "Bookmarked") + "\n\n" + text
+		}
+	}
 
-	sb.WriteString(fmt.Sprintf("[yellow]Timestamp:[white] %s\n", log.Timestamp.Format(time.RFC3339)))
-	sb.WriteString(fmt.Sprintf("[yellow]Level:[white] [%s]%s[white]\n\n", getLevelColorName(log.Level), log.Level))
+	e.details.SetText(text)
+	e.details.ScrollToBeginning()
+}
+
+// formatStatsPanel renders level counts, error rate and time range of the filtered entries
+func formatStatsPanel(filtered []LogEntry, total int) string {
+	if len(filtered) == 0 {
+		return fmt.Sprintf("%s 0 of %d\n", colorText(theme.Label, "Entries:"), total)
+	}
 
-	if log.Source != "" {
-		sb.WriteString(fmt.Sprintf("[yellow]Source:[white] %s\n\n", log.Source))
+	analysis := analyzeLogs(filtered, true, 5)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %d of %d\n", colorText(theme.Label, "Entries:"), len(filtered), total))
+	if analysis.TotalEntries != len(filtered) {
+		sb.WriteString(fmt.Sprintf("%s %d\n", colorText(theme.Label, "With duplicates:"), analysis.TotalEntries))
 	}
+	sb.WriteString(fmt.Sprintf("%s %.1f%%\n\n", colorText(theme.Label, "Error rate:"), analysis.ErrorRate))
 
-	if log.User != "" {
-		sb.WriteString(fmt.Sprintf("[yellow]User:[white] %s\n", log.User))
+	sb.WriteString(colorText(theme.Label, "Levels:") + "\n")
+	levels := make([]string, 0, len(analysis.LevelCounts))
+	for level := range analysis.LevelCounts {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return analysis.LevelCounts[levels[i]] > analysis.LevelCounts[levels[j]]
+	})
+	for _, level := range levels {
+		sb.WriteString(fmt.Sprintf("  %s: %d\n", colorText(getLevelColorName(level), level), analysis.LevelCounts[level]))
 	}
 
-	for key, value := range log.Extras {
-		sb.WriteString(fmt.Sprintf("[yellow]%s:[white] %s\n", key, value))
+	sb.WriteString("\n" + colorText(theme.Label, "Time range:") + "\n")
+	sb.WriteString(fmt.Sprintf("  %s\n  %s\n  (%s)\n",
+		analysis.TimeRange.Start.Format("2006-01-02 15:04:05"),
+		analysis.TimeRange.End.Format("2006-01-02 15:04:05"),
+		analysis.TimeRange.End.Sub(analysis.TimeRange.Start).Round(time.Second)))
+
+	if len(analysis.TopSources) > 0 {
+		sb.WriteString("\n" + colorText(theme.Label, "Top sources:") + "\n")
+		for _, source := range analysis.TopSources {
+			sb.WriteString(fmt.Sprintf("  %s (%d)\n", truncateString(source.Item, 30), source.Count))
+		}
 	}
 
-	sb.WriteString(fmt.Sprintf("\n[yellow]Message:[white]\n%s\n\n", log.Message))
+	return sb.String()
+}
 
+// formatLogDetails renders all fields of a log entry for the details view: the standard
+// fields, notification fields, the message, extras sorted by key (JSON values indented)
+// and the raw line
+func formatLogDetails(log LogEntry, highlight *regexp.Regexp) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "Timestamp:"), log.Timestamp.Format(time.RFC3339Nano)))
+	sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "Level:"), colorText(getLevelColorName(log.Level), tview.Escape(log.Level))))
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"Source", log.Source},
+		{"User", log.User},
+		{"Log source", log.LogSource},
+		{"Ack ID", log.AckID},
+		{"Type", log.Type},
+		{"Status", log.Status},
+		{"File", formatSourceLine(log)},
+		{"Node", log.Node},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, field.name+":"), tview.Escape(field.value)))
+		}
+	}
 	if log.DuplicateCount > 1 {
-		sb.WriteString(fmt.Sprintf("[yellow]Occurrences:[white] %d\n\n", log.DuplicateCount))
+		occurrences := fmt.Sprintf("%d", log.DuplicateCount)
+		if len(log.NodeCounts) > 0 {
+			occurrences += " (" + tview.Escape(formatNodeCounts(log.NodeCounts)) + ")"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "Occurrences:"), occurrences))
+	}
+	if lastSeen := log.lastSeen(); lastSeen.After(log.Timestamp) {
+		sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "Last seen:"), lastSeen.Format(time.RFC3339Nano)))
 	}
 
-	view.SetText(sb.String())
-	view.ScrollToBeginning()
+	sb.WriteString(fmt.Sprintf("\n%s\n%s\n", colorText(theme.Label, "Message:"), highlightMatches(log.Message, highlight)))
+
+	if len(log.Extras) > 0 {
+		keys := make([]string, 0, len(log.Extras))
+		for key := range log.Extras {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		sb.WriteString("\n" + colorText(theme.Label, "Extras:") + "\n")
+		for _, key := range keys {
+			sb.WriteString(fmt.Sprintf("  %s %s\n", colorText(theme.Key, tview.Escape(key)+":"), tview.Escape(prettyExtrasValue(log.Extras[key]))))
+		}
+	}
+
+	if log.Raw != "" {
+		sb.WriteString(fmt.Sprintf("\n%s\n%s\n", colorText(theme.Label, "Raw:"), colorText(theme.Muted, tview.Escape(log.Raw))))
+	}
+
+	return sb.String()
+}
+
+// formatSourceLine returns the file an entry was read from with its line, if known
+func formatSourceLine(log LogEntry) string {
+	if log.SourceFile != "" && log.Line > 0 {
+		return fmt.Sprintf("%s:%d", log.SourceFile, log.Line)
+	}
+	return log.SourceFile
+}
+
+// prettyExtrasValue indents JSON object and array values over multiple lines
+func prettyExtrasValue(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return value
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(trimmed), "    ", "  "); err != nil {
+		return value
+	}
+	return out.String()
 }
 
-// getLevelColorName returns the tview color name for a log level
+// getLevelColorName returns the tview color name of the active theme for a log level
 func getLevelColorName(level string) string {
 	switch strings.ToUpper(level) {
 	case "ERROR", "FATAL", "CRITICAL":
-		return "red"
+		return theme.Error
 	case "WARN", "WARNING":
-		return "yellow"
+		return theme.Warn
 	case "INFO":
-		return "green"
+		return theme.Info
 	case "DEBUG":
-		return "blue"
+		return theme.Debug
 	default:
-		return "white"
+		return theme.Text
 	}
 }
 
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bookmarksExportBase is the file name, without extension, used when exporting bookmarks
+const bookmarksExportBase = "lamp-bookmarks"
+
+// Bookmark is a marked log entry with an optional note, as exported from the TUI
+type Bookmark struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Level     string            `json:"level"`
+	Source    string            `json:"source,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Message   string            `json:"message"`
+	Extras    map[string]string `json:"extras,omitempty"`
+	Note      string            `json:"note,omitempty"`
+}
+
+// collectBookmarks returns the marked entries in log order
+func collectBookmarks(logs []LogEntry, marks map[int]string) []Bookmark {
+	indexes := make([]int, 0, len(marks))
+	for index := range marks {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	bookmarks := make([]Bookmark, 0, len(indexes))
+	for _, index := range indexes {
+		log := logs[index]
+		bookmarks = append(bookmarks, Bookmark{
+			Timestamp: log.Timestamp,
+			Level:     log.Level,
+			Source:    log.Source,
+			User:      log.User,
+			Message:   log.Message,
+			Extras:    log.Extras,
+			Note:      marks[index],
+		})
+	}
+	return bookmarks
+}
+
+// writeBookmarksMarkdown writes bookmarks as a markdown list suitable for a ticket
+func writeBookmarksMarkdown(bookmarks []Bookmark, writer io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Marked log entries\n\n")
+	for _, b := range bookmarks {
+		sb.WriteString(fmt.Sprintf("- **%s** `%s`", b.Timestamp.Format("2006-01-02 15:04:05.000"), b.Level))
+		if b.Source != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", b.Source))
+		}
+		sb.WriteString(fmt.Sprintf(": %s\n", b.Message))
+		if b.User != "" {
+			sb.WriteString(fmt.Sprintf("  - User: %s\n", b.User))
+		}
+		if b.Note != "" {
+			sb.WriteString(fmt.Sprintf("  - Note: %s\n", b.Note))
+		}
+	}
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+// writeBookmarksJSON writes bookmarks as an indented JSON array
+func writeBookmarksJSON(bookmarks []Bookmark, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bookmarks)
+}
+
+// saveBookmarks writes the bookmarks to <base>.md and <base>.json
+func saveBookmarks(bookmarks []Bookmark, base string) error {
+	writers := []struct {
+		ext   string
+		write func([]Bookmark, io.Writer) error
+	}{
+		{".md", writeBookmarksMarkdown},
+		{".json", writeBookmarksJSON},
+	}
+
+	for _, w := range writers {
+		file, err := os.Create(base + w.ext)
+		if err != nil {
+			return err
+		}
+		err = w.write(bookmarks, file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", base+w.ext, err)
+		}
+	}
+	return nil
+}
+
+// currentIndex returns the index into logs of the selected entry, or -1 if the list is empty
+func (e *logExplorer) currentIndex() int {
+	row := e.selectedRow()
+	if e.showingGroups() || row < 0 || row >= len(e.visible) {
+		return -1
+	}
+	return e.visible[row]
+}
+
+// toggleBookmark marks or unmarks the selected entry
+func (e *logExplorer) toggleBookmark() {
+	index := e.currentIndex()
+	if index < 0 {
+		return
+	}
+	if _, ok := e.bookmarks[index]; ok {
+		delete(e.bookmarks, index)
+	} else {
+		e.bookmarks[index] = ""
+	}
+	e.refreshCurrentItem()
+}
+
+// refreshCurrentItem redraws the details of the selected entry and the status bar
+func (e *logExplorer) refreshCurrentItem() {
+	e.showDetails(e.selectedRow())
+	e.updateStatusBar()
+}
+
+// jumpToBookmark selects the next (direction 1) or previous (direction -1) marked entry
+// of the list, wrapping around at either end
+func (e *logExplorer) jumpToBookmark(direction int) {
+	if len(e.bookmarks) == 0 {
+		return
+	}
+	e.jumpTo(direction, func(index int) bool {
+		_, ok := e.bookmarks[index]
+		return ok
+	})
+}
+
+// editNote opens an input to set the note of the selected entry, marking it if needed
+func (e *logExplorer) editNote() {
+	index := e.currentIndex()
+	if index < 0 {
+		return
+	}
+
+	e.promptInput("Bookmark note (Enter to save, Esc to cancel)", "Note: ", e.bookmarks[index], func(note string) {
+		e.bookmarks[index] = strings.TrimSpace(note)
+		e.refreshCurrentItem()
+	})
+}
+
+// exportBookmarks writes the marked entries to markdown and JSON files in the working directory
+func (e *logExplorer) exportBookmarks() {
+	if len(e.bookmarks) == 0 {
+		e.statusBar.SetText("No marked entries to export (press m to mark an entry)")
+		return
+	}
+
+	if err := saveBookmarks(collectBookmarks(e.logs, e.bookmarks), bookmarksExportBase); err != nil {
+		e.statusBar.SetText(fmt.Sprintf("Error exporting bookmarks: %v", err))
+		return
+	}
+	e.statusBar.SetText(fmt.Sprintf("Exported %d marked entries to %s.md and %s.json",
+		len(e.bookmarks), bookmarksExportBase, bookmarksExportBase))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/rivo/tview"
+)
+
+// clipboardWriteAll writes text to the system clipboard, replaced in tests
+var clipboardWriteAll = clipboard.WriteAll
+
+// stripColorTags removes the tview color tags from text and unescapes it
+func stripColorTags(text string) string {
+	return tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(text).
+		GetText(true)
+}
+
+// rawLogJSON returns the original line of an entry, or the entry encoded as JSON when the
+// original line is not known
+func rawLogJSON(log LogEntry) (string, error) {
+	if log.Raw != "" {
+		return log.Raw, nil
+	}
+	data, err := json.Marshal(log)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// copyToClipboard writes text to the clipboard and reports the result in the status bar
+func (e *logExplorer) copyToClipboard(text, what string) {
+	if err := clipboardWriteAll(text); err != nil {
+		e.statusBar.SetText(fmt.Sprintf("Error copying to clipboard: %v", err))
+		return
+	}
+	e.statusBar.SetText(fmt.Sprintf("Copied %s to clipboard", what))
+}
+
+// copyDetails copies the details view, i.e. the selected entry or group, as plain text
+func (e *logExplorer) copyDetails() {
+	text := strings.TrimSpace(stripColorTags(e.details.GetText(false)))
+	if text == "" {
+		return
+	}
+	e.copyToClipboard(text+"\n", "details")
+}
+
+// copyRawEntry copies the original line of the selected entry
+func (e *logExplorer) copyRawEntry() {
+	index := e.currentIndex()
+	if index < 0 {
+		return
+	}
+	text, err := rawLogJSON(e.logs[index])
+	if err != nil {
+		e.statusBar.SetText(fmt.Sprintf("Error encoding entry: %v", err))
+		return
+	}
+	e.copyToClipboard(text, "raw entry")
+}
+
+// copyStats copies the stats of the entries matching the filter as plain text, whether or
+// not the stats panel is shown
+func (e *logExplorer) copyStats() {
+	e.copyToClipboard(stripColorTags(formatStatsPanel(e.filtered, len(e.logs))), "stats")
+}
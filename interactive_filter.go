@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// levelSeverities orders log levels from least to most severe
+var levelSeverities = map[string]int{
+	"TRACE":    0,
+	"DEBUG":    1,
+	"INFO":     2,
+	"WARN":     3,
+	"WARNING":  3,
+	"ERROR":    4,
+	"CRITICAL": 5,
+	"FATAL":    5,
+	"PANIC":    6,
+}
+
+// levelSeverity returns the severity of a log level, or -1 for unknown levels
+func levelSeverity(level string) int {
+	if severity, ok := levelSeverities[strings.ToUpper(level)]; ok {
+		return severity
+	}
+	return -1
+}
+
+// filterCondition is a single condition of a TUI filter expression
+type filterCondition func(entry LogEntry) bool
+
+// logFilter is a parsed TUI filter expression. All conditions must match.
+type logFilter struct {
+	conditions []filterCondition
+}
+
+// matches reports whether an entry satisfies every condition of the filter
+func (f logFilter) matches(entry LogEntry) bool {
+	for _, condition := range f.conditions {
+		if !condition(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFilterExpression parses a TUI filter expression. Whitespace-separated terms are
+// combined with AND:
+//
+//	/pattern         regular expression over message, source, user and extras (rest of the expression)
+//	level>=warn      level comparison (>=, <=, >, <, = or !=)
+//	key=value        field contains value (level, message, source, user, file, node or any extras key)
+//	key!=value       field does not contain value
+//	word             message, level or source contains word
+func parseFilterExpression(expr string) (logFilter, error) {
+	var filter logFilter
+	expr = strings.TrimSpace(expr)
+
+	for expr != "" {
+		if strings.HasPrefix(expr, "/") {
+			pattern := strings.TrimSuffix(strings.TrimPrefix(expr, "/"), "/")
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return filter, fmt.Errorf("invalid regex: %v", err)
+			}
+			filter.conditions = append(filter.conditions, regexCondition(regex))
+			break
+		}
+
+		token := expr
+		if idx := strings.IndexAny(expr, " \t"); idx >= 0 {
+			token = expr[:idx]
+			expr = strings.TrimSpace(expr[idx:])
+		} else {
+			expr = ""
+		}
+
+		condition, err := parseFilterToken(token)
+		if err != nil {
+			return filter, err
+		}
+		filter.conditions = append(filter.conditions, condition)
+	}
+
+	return filter, nil
+}
+
+// parseFilterToken parses a single non-regex term of a filter expression
+func parseFilterToken(token string) (filterCondition, error) {
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		idx := strings.Index(token, op)
+		if idx <= 0 {
+			continue
+		}
+		key := token[:idx]
+		value := token[idx+len(op):]
+
+		if strings.EqualFold(key, "level") {
+			return levelCondition(op, value)
+		}
+		if op != "=" && op != "!=" {
+			return nil, fmt.Errorf("operator %s is only supported for level", op)
+		}
+
+		valueLower := strings.ToLower(value)
+		negate := op == "!="
+		return func(entry LogEntry) bool {
+			return strings.Contains(strings.ToLower(entryField(entry, key)), valueLower) != negate
+		}, nil
+	}
+
+	termLower := strings.ToLower(token)
+	return func(entry LogEntry) bool {
+		return strings.Contains(strings.ToLower(entry.Message), termLower) ||
+			strings.Contains(strings.ToLower(entry.Level), termLower) ||
+			strings.Contains(strings.ToLower(entry.Source), termLower)
+	}, nil
+}
+
+// levelCondition builds a condition comparing the entry level with a level
+func levelCondition(op, value string) (filterCondition, error) {
+	// Allow lists of levels for equality, e.g. level=error,warn
+	if op == "=" || op == "!=" {
+		levels := strings.Split(strings.ToUpper(value), ",")
+		negate := op == "!="
+		return func(entry LogEntry) bool {
+			return contains(levels, strings.ToUpper(entry.Level)) != negate
+		}, nil
+	}
+
+	target := levelSeverity(value)
+	if target < 0 {
+		return nil, fmt.Errorf("unknown level: %s", value)
+	}
+	return func(entry LogEntry) bool {
+		severity := levelSeverity(entry.Level)
+		if severity < 0 {
+			return false
+		}
+		switch op {
+		case ">=":
+			return severity >= target
+		case "<=":
+			return severity <= target
+		case ">":
+			return severity > target
+		default:
+			return severity < target
+		}
+	}, nil
+}
+
+// regexCondition builds a condition matching a regex against the same fields as --regex
+func regexCondition(regex *regexp.Regexp) filterCondition {
+	return func(entry LogEntry) bool {
+		return regex.MatchString(entry.Message) ||
+			regex.MatchString(entry.Source) ||
+			regex.MatchString(entry.User) ||
+			regex.MatchString(entry.ExtrasToString())
+	}
+}
+
+// entryField returns the value of a named field of a log entry, falling back to extras
+func entryField(entry LogEntry, key string) string {
+	switch strings.ToLower(key) {
+	case "level":
+		return entry.Level
+	case "message", "msg":
+		return entry.Message
+	case "source", "caller":
+		return entry.Source
+	case "user", "user_id":
+		return entry.User
+	case "log_source", "logsource":
+		return entry.LogSource
+	case "type":
+		return entry.Type
+	case "status":
+		return entry.Status
+	case "file", "source_file":
+		return entry.SourceFile
+	case "node":
+		return entry.Node
+	default:
+		return entry.Extras[key]
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// aiAnalysis is the result of an AI analysis run before the interactive mode starts
+type aiAnalysis struct {
+	report   string
+	findings []AIFinding
+	evidence [][]SessionEntry // Entries cited by each finding
+}
+
+// newAIAnalysis parses an analysis requested with findingsInstructions. Evidence numbers
+// refer to the analyzed entries; numbers out of range are dropped. A malformed findings
+// block leaves the analysis without findings.
+func newAIAnalysis(text string, analyzed []LogEntry) *aiAnalysis {
+	report, findings, err := parseAIFindings(text)
+	if err != nil {
+		logger.Warn("failed to parse the findings of the analysis", "error", err)
+	}
+
+	analysis := &aiAnalysis{report: report, findings: findings}
+	for _, finding := range citeFindings(findings, analyzed) {
+		var entries []SessionEntry
+		for _, log := range finding.Entries {
+			entries = append(entries, SessionEntry{Timestamp: log.Timestamp, Message: log.Message})
+		}
+		analysis.evidence = append(analysis.evidence, entries)
+	}
+	return analysis
+}
+
+// runInteractiveAnalysis runs an AI analysis asking for structured findings, to be shown
+// in the findings panel instead of printed
+func runInteractiveAnalysis(logs []LogEntry, config LLMConfig) (*aiAnalysis, error) {
+	var text string
+	config.Findings = true
+	config.Output = func(analysisText string) error {
+		text = analysisText
+		return nil
+	}
+	if err := analyzeWithLLM(logs, config); err != nil {
+		return nil, err
+	}
+	return newAIAnalysis(text, logsForAnalysis(logs, config.MaxEntries)), nil
+}
+
+// loadAnalysis resolves the entries cited by the findings of an analysis and fills the
+// findings panel. Cited entries that are no longer loaded are skipped.
+func (e *logExplorer) loadAnalysis(analysis *aiAnalysis) {
+	e.analysis = analysis
+	indexes := make(map[string]int, len(e.logs))
+	for i := len(e.logs) - 1; i >= 0; i-- {
+		indexes[sessionEntryKey(e.logs[i].Timestamp, e.logs[i].Message)] = i
+	}
+
+	e.findingEvidence = make([][]int, len(analysis.findings))
+	for i, entries := range analysis.evidence {
+		for _, entry := range entries {
+			if index, ok := indexes[sessionEntryKey(entry.Timestamp, entry.Message)]; ok {
+				e.findingEvidence[i] = append(e.findingEvidence[i], index)
+			}
+		}
+	}
+
+	e.findingsPanel.Clear()
+	e.findingsPanel.AddItem("Full report", "", 0, nil)
+	for i, finding := range analysis.findings {
+		severity := strings.ToUpper(finding.Severity)
+		title := fmt.Sprintf("%s %s", colorText(getLevelColorName(severity), tview.Escape(severity)), tview.Escape(finding.Title))
+		cited := fmt.Sprintf("%d cited entries", len(e.findingEvidence[i]))
+		e.findingsPanel.AddItem(title, cited, 0, nil)
+	}
+}
+
+// newFindingsPanel creates the list of findings: moving through it shows a finding in the
+// details view and Enter jumps to its first cited entry
+func (e *logExplorer) newFindingsPanel() *tview.List {
+	list := tview.NewList().
+		SetSecondaryTextColor(theme.color(theme.Muted)).
+		SetSelectedBackgroundColor(theme.color(theme.Selection)).
+		SetSelectedTextColor(theme.color(theme.SelectionText))
+	list.SetBorder(true).SetTitle("AI findings (Enter to jump, Esc to go back)")
+	list.SetChangedFunc(func(item int, _, _ string, _ rune) {
+		e.showFinding(item - 1)
+	})
+	list.SetSelectedFunc(func(item int, _, _ string, _ rune) {
+		e.selectFinding(item - 1)
+	})
+	list.SetDoneFunc(func() {
+		e.app.SetFocus(e.logTable)
+	})
+	return list
+}
+
+// toggleFindings shows the findings panel and focuses it, or hides it
+func (e *logExplorer) toggleFindings() {
+	if e.analysis == nil {
+		e.statusBar.SetText("No AI analysis (start the interactive mode with --ai-analyze)")
+		return
+	}
+
+	e.showFindings = !e.showFindings
+	if e.showFindings {
+		e.body.AddItem(e.findingsPanel, 0, 2, true)
+		e.app.SetFocus(e.findingsPanel)
+		e.showFinding(e.findingsPanel.GetCurrentItem() - 1)
+	} else {
+		e.body.RemoveItem(e.findingsPanel)
+		e.app.SetFocus(e.logTable)
+	}
+}
+
+// showFinding shows a finding, or the full report for -1, in the details view
+func (e *logExplorer) showFinding(finding int) {
+	if finding < 0 || finding >= len(e.analysis.findings) {
+		e.details.SetText(tview.Escape(e.analysis.report))
+		e.details.ScrollToBeginning()
+		return
+	}
+
+	f := e.analysis.findings[finding]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "Finding:"), tview.Escape(f.Title)))
+	sb.WriteString(fmt.Sprintf("%s %s\n\n", colorText(theme.Label, "Severity:"), colorText(getLevelColorName(f.Severity), tview.Escape(f.Severity))))
+	sb.WriteString(tview.Escape(f.Summary) + "\n")
+
+	if evidence := e.findingEvidence[finding]; len(evidence) > 0 {
+		sb.WriteString("\n" + colorText(theme.Label, "Cited entries:") + "\n")
+		for _, index := range evidence {
+			log := e.logs[index]
+			sb.WriteString(fmt.Sprintf("  %s %s %s\n",
+				log.Timestamp.Format("15:04:05"),
+				colorText(getLevelColorName(log.Level), tview.Escape(log.Level)),
+				tview.Escape(truncateString(log.Message, 120))))
+		}
+	}
+	e.details.SetText(sb.String())
+	e.details.ScrollToBeginning()
+}
+
+// selectFinding marks the entries cited by a finding and selects the first one shown in the
+// list, focusing the list
+func (e *logExplorer) selectFinding(finding int) {
+	if finding < 0 || finding >= len(e.findingEvidence) {
+		return
+	}
+
+	e.evidence = make(map[int]bool, len(e.findingEvidence[finding]))
+	for _, index := range e.findingEvidence[finding] {
+		e.evidence[index] = true
+	}
+	if len(e.evidence) == 0 {
+		e.statusBar.SetText("The finding cites no loaded entries")
+		return
+	}
+
+	e.app.SetFocus(e.logTable)
+	if !e.showingGroups() {
+		for row, index := range e.visible {
+			if e.evidence[index] {
+				e.selectRow(row)
+				return
+			}
+		}
+	}
+	e.statusBar.SetText(fmt.Sprintf("The %d cited entries are hidden by the filter or grouping", len(e.evidence)))
+}
+
+// jumpToEvidence selects the next (direction 1) or previous (direction -1) entry cited by
+// the selected finding
+func (e *logExplorer) jumpToEvidence(direction int) {
+	if len(e.evidence) == 0 {
+		e.statusBar.SetText("No finding selected (press i to list the AI findings)")
+		return
+	}
+	if !e.jumpTo(direction, func(index int) bool { return e.evidence[index] }) {
+		e.statusBar.SetText("The cited entries are hidden by the filter or grouping")
+	}
+}
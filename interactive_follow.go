@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+)
+
+// appendEntries adds entries written to followed files while the TUI runs. Entries are
+// appended after the loaded ones. With auto-scroll on and the last entry selected, the
+// selection follows the new entries; otherwise it stays and new errors are counted. When
+// the list is sorted by something else than time, the new entries are sorted in and the
+// selected entry stays selected.
+func (e *logExplorer) appendEntries(entries []LogEntry) {
+	sorted := e.sortBy != ""
+	follow := !sorted && e.autoScroll && !e.showingGroups() && e.selectedRow() >= len(e.visible)-1
+	selected := e.currentIndex()
+
+	sortEntries(entries)
+
+	first := len(e.logs)
+	e.logs = append(e.logs, entries...)
+
+	for index := first; index < len(e.logs); index++ {
+		if !e.filterExpr.matches(e.logs[index]) {
+			continue
+		}
+		e.matching = append(e.matching, index)
+		if !e.inBucket(index) || !e.inOpenGroup(index) {
+			continue
+		}
+
+		e.filtered = append(e.filtered, e.logs[index])
+		if e.showingGroups() {
+			continue
+		}
+		e.visible = append(e.visible, index)
+		if e.search.matches(e.logs[index]) {
+			e.searchHits++
+		}
+		if !follow && !sorted && isErrorLevel(e.logs[index].Level) {
+			e.newErrors++
+		}
+	}
+
+	if e.showingGroups() {
+		row := e.selectedRow()
+		e.groups = groupLogIndices(e.logs, e.filteredIndexes(), e.groupBy)
+		e.showSelection(row)
+	} else if sorted {
+		slices.Sort(e.visible)
+		e.visible = sortLogIndices(e.logs, e.visible, e.sortBy)
+		e.selectEntry(selected)
+	} else if follow && len(e.visible) > 0 {
+		e.selectRow(len(e.visible) - 1)
+	}
+
+	e.histogram.dirty = true
+	e.updateStatusBar()
+	if e.showStats {
+		e.statsPanel.SetText(formatStatsPanel(e.filtered, len(e.logs)))
+	}
+}
+
+// filteredIndexes returns the indexes of the entries matching the filter and bucket
+func (e *logExplorer) filteredIndexes() []int {
+	var indexes []int
+	for _, index := range e.matching {
+		if e.inBucket(index) {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// toggleAutoScroll pauses or resumes following new entries at the end of the list
+func (e *logExplorer) toggleAutoScroll() {
+	if !e.following {
+		return
+	}
+	e.autoScroll = !e.autoScroll
+	if e.autoScroll && !e.showingGroups() && len(e.visible) > 0 {
+		e.selectRow(len(e.visible) - 1)
+	}
+	e.updateStatusBar()
+}
+
+// selectionMoved clears the new error indicator once the last entry is selected
+func (e *logExplorer) selectionMoved(row int) {
+	if e.newErrors > 0 && !e.showingGroups() && row >= len(e.visible)-1 {
+		e.newErrors = 0
+		e.updateStatusBar()
+	}
+}
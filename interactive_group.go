@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// groupModes are the grouping modes cycled through with the g key
+var groupModes = []string{"", "level", "source", "node"}
+
+// unknownGroup is the key of the group of entries without a value for the grouping field
+const unknownGroup = "(none)"
+
+// logGroup is a set of entries sharing the same level, source file or node
+type logGroup struct {
+	Key     string
+	Indexes []int // Indexes into logs of the entries in the group
+	Errors  int
+	First   time.Time
+	Last    time.Time
+}
+
+// groupKey returns the key of the group an entry belongs to for a grouping mode
+func groupKey(entry LogEntry, by string) string {
+	var key string
+	switch by {
+	case "level":
+		key = strings.ToUpper(entry.Level)
+	case "source":
+		// Group by file rather than by line
+		key = entry.Source
+		if idx := strings.LastIndex(key, ":"); idx > 0 {
+			key = key[:idx]
+		}
+	case "node":
+		key = entry.Node
+		if key == "" {
+			key = extrasNode(entry.Extras)
+		}
+	}
+
+	if key == "" {
+		return unknownGroup
+	}
+	return key
+}
+
+// groupLogIndices groups the given entries, largest groups first
+func groupLogIndices(logs []LogEntry, indexes []int, by string) []logGroup {
+	groupsByKey := make(map[string]*logGroup)
+	var groups []*logGroup
+	for _, index := range indexes {
+		entry := logs[index]
+		key := groupKey(entry, by)
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = &logGroup{Key: key, First: entry.Timestamp, Last: entry.Timestamp}
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
+
+		group.Indexes = append(group.Indexes, index)
+		if isErrorLevel(entry.Level) {
+			group.Errors++
+		}
+		if entry.Timestamp.Before(group.First) {
+			group.First = entry.Timestamp
+		}
+		if entry.Timestamp.After(group.Last) {
+			group.Last = entry.Timestamp
+		}
+	}
+
+	result := make([]logGroup, len(groups))
+	for i, group := range groups {
+		result[i] = *group
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if len(result[i].Indexes) != len(result[j].Indexes) {
+			return len(result[i].Indexes) > len(result[j].Indexes)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// formatGroupDetails renders a summary of a group for the details view
+func formatGroupDetails(group logGroup, by string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s\n\n", colorText(theme.Label, by+":"), group.Key))
+	sb.WriteString(fmt.Sprintf("%s %d\n", colorText(theme.Label, "Entries:"), len(group.Indexes)))
+	sb.WriteString(fmt.Sprintf("%s %d\n", colorText(theme.Label, "Errors:"), group.Errors))
+	sb.WriteString(fmt.Sprintf("%s %s\n", colorText(theme.Label, "First:"), group.First.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("%s %s\n\n", colorText(theme.Label, "Last:"), group.Last.Format("2006-01-02 15:04:05")))
+	sb.WriteString("Press Enter to show the entries of this group")
+	return sb.String()
+}
+
+// cycleGroupBy switches to the next grouping mode
+func (e *logExplorer) cycleGroupBy() {
+	next := 0
+	for i, mode := range groupModes {
+		if mode == e.groupBy {
+			next = (i + 1) % len(groupModes)
+			break
+		}
+	}
+
+	e.groupBy = groupModes[next]
+	e.openGroup = ""
+	e.refreshView()
+}
+
+// updateGroupList shows the groups in the log table, selecting the first one
+func (e *logExplorer) updateGroupList() {
+	e.logTable.Select(0, 0).ScrollToBeginning()
+	if len(e.groups) > 0 {
+		e.details.SetText(formatGroupDetails(e.groups[0], e.groupBy))
+	} else {
+		e.details.SetText("No matching logs found")
+	}
+}
+
+// closeGroup returns from the entries of a group to the group list
+func (e *logExplorer) closeGroup() {
+	e.openGroup = ""
+	e.refreshView()
+}
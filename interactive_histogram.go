@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// histogramHeight is the number of rows of the histogram widget: one label row and the bars
+const histogramHeight = 4
+
+// histogramBarRunes are the partial block characters used for the top of a bar, in eighths
+var histogramBarRunes = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// histogramBucket counts the entries logged in a time bucket
+type histogramBucket struct {
+	Start  time.Time
+	Count  int
+	Levels map[string]int // Entries per upper-cased level
+}
+
+// dominantLevel returns the most frequent level of the bucket, preferring the more severe
+// level on ties
+func (b histogramBucket) dominantLevel() string {
+	dominant := ""
+	for level, count := range b.Levels {
+		if dominant == "" || count > b.Levels[dominant] ||
+			(count == b.Levels[dominant] && levelSeverity(level) > levelSeverity(dominant)) {
+			dominant = level
+		}
+	}
+	return dominant
+}
+
+// histogramBucketSize returns the bucket size for a time span shown in at most maxBuckets
+// buckets: one minute, or a whole number of minutes when the span is too long
+func histogramBucketSize(span time.Duration, maxBuckets int) time.Duration {
+	if maxBuckets < 1 {
+		maxBuckets = 1
+	}
+	minutes := int64(span / time.Minute / time.Duration(maxBuckets))
+	if span > time.Duration(maxBuckets)*time.Duration(minutes)*time.Minute {
+		minutes++
+	}
+	if minutes < 1 {
+		minutes = 1
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// buildHistogram counts the given entries, which must be sorted by timestamp, in buckets
+// starting at the minute of the first entry
+func buildHistogram(logs []LogEntry, indexes []int, maxBuckets int) ([]histogramBucket, time.Duration) {
+	if len(indexes) == 0 {
+		return nil, time.Minute
+	}
+
+	start := logs[indexes[0]].Timestamp.Truncate(time.Minute)
+	end := logs[indexes[len(indexes)-1]].Timestamp
+	size := histogramBucketSize(end.Sub(start), maxBuckets)
+
+	buckets := make([]histogramBucket, int(end.Sub(start)/size)+1)
+	for i := range buckets {
+		buckets[i] = histogramBucket{Start: start.Add(time.Duration(i) * size), Levels: make(map[string]int)}
+	}
+	for _, index := range indexes {
+		log := logs[index]
+		bucket := &buckets[int(log.Timestamp.Sub(start)/size)]
+		count := max(log.DuplicateCount, 1)
+		bucket.Count += count
+		bucket.Levels[strings.ToUpper(log.Level)] += count
+	}
+	return buckets, size
+}
+
+// histogramView is a TUI widget drawing a histogram of the entries matching the filter.
+// The cursor selects a bucket; Enter or a click restricts the list to it.
+type histogramView struct {
+	*tview.Box
+	explorer   *logExplorer
+	buckets    []histogramBucket
+	bucketSize time.Duration
+	width      int  // Width the buckets were built for
+	dirty      bool // Whether the buckets must be rebuilt
+	cursor     int
+}
+
+// newHistogramView creates the histogram widget of an explorer
+func newHistogramView(explorer *logExplorer) *histogramView {
+	return &histogramView{Box: tview.NewBox(), explorer: explorer, dirty: true}
+}
+
+// invalidate rebuilds the buckets on the next draw, e.g. after the filter changed
+func (h *histogramView) invalidate() {
+	h.dirty = true
+	h.cursor = 0
+}
+
+// update rebuilds the buckets if the data or the width changed
+func (h *histogramView) update(width int) {
+	if !h.dirty && width == h.width {
+		return
+	}
+	h.buckets, h.bucketSize = buildHistogram(h.explorer.logs, h.explorer.matching, width)
+	h.width = width
+	h.dirty = false
+	if h.cursor >= len(h.buckets) {
+		h.cursor = max(len(h.buckets)-1, 0)
+	}
+}
+
+// Draw draws the label row and one bar per bucket
+func (h *histogramView) Draw(screen tcell.Screen) {
+	h.Box.DrawForSubclass(screen, h)
+	x, y, width, height := h.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	h.update(width)
+
+	tview.Print(screen, h.label(), x, y, width, tview.AlignLeft, theme.color(theme.Label))
+	if len(h.buckets) == 0 || height < 2 {
+		return
+	}
+
+	maxCount := 0
+	for _, bucket := range h.buckets {
+		maxCount = max(maxCount, bucket.Count)
+	}
+
+	rows := height - 1
+	for i, bucket := range h.buckets {
+		style := tcell.StyleDefault.Background(theme.color(theme.Background)).Foreground(theme.color(getLevelColorName(bucket.dominantLevel())))
+		if i == h.cursor && h.HasFocus() {
+			style = style.Background(theme.color(theme.Selection))
+		}
+
+		// Bar height in eighths of a row; non-empty buckets get at least one eighth
+		eighths := 0
+		if bucket.Count > 0 {
+			eighths = max(bucket.Count*rows*8/maxCount, 1)
+		}
+		for row := 0; row < rows; row++ {
+			fill := min(max(eighths-row*8, 0), 8)
+			screen.SetContent(x+i, y+height-1-row, histogramBarRunes[fill], nil, style)
+		}
+	}
+}
+
+// label describes the bucket under the cursor and the current restriction
+func (h *histogramView) label() string {
+	if len(h.buckets) == 0 {
+		return "Histogram: no entries"
+	}
+
+	bucket := h.buckets[h.cursor]
+	var levels []string
+	for _, level := range []string{"ERROR", "WARN", "INFO", "DEBUG"} {
+		if count := bucket.Levels[level]; count > 0 {
+			levels = append(levels, fmt.Sprintf("%s %d", level, count))
+		}
+	}
+
+	label := fmt.Sprintf("%s buckets | %s: %d entries",
+		h.bucketSize, bucket.Start.Format("2006-01-02 15:04"), bucket.Count)
+	if len(levels) > 0 {
+		label += " (" + strings.Join(levels, ", ") + ")"
+	}
+	if h.explorer.bucket != nil {
+		label += fmt.Sprintf(" | showing %s only, Esc to clear", h.explorer.bucket.Start.Format("15:04"))
+	} else {
+		label += " | Enter to show this bucket only"
+	}
+	return label
+}
+
+// InputHandler moves the cursor and restricts the list to the selected bucket
+func (h *histogramView) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return h.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if len(h.buckets) == 0 {
+			return
+		}
+		switch event.Key() {
+		case tcell.KeyLeft:
+			h.cursor = max(h.cursor-1, 0)
+		case tcell.KeyRight:
+			h.cursor = min(h.cursor+1, len(h.buckets)-1)
+		case tcell.KeyHome:
+			h.cursor = 0
+		case tcell.KeyEnd:
+			h.cursor = len(h.buckets) - 1
+		case tcell.KeyEnter:
+			h.explorer.restrictToBucket(h.buckets[h.cursor].Start, h.bucketSize)
+		case tcell.KeyEscape:
+			h.explorer.clearBucket()
+		}
+	})
+}
+
+// MouseHandler selects and restricts to the clicked bucket
+func (h *histogramView) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return h.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		if !h.InRect(event.Position()) {
+			return false, nil
+		}
+		setFocus(h)
+		if action == tview.MouseLeftClick {
+			x, _, _, _ := h.GetInnerRect()
+			mouseX, _ := event.Position()
+			if i := mouseX - x; i >= 0 && i < len(h.buckets) {
+				h.cursor = i
+				h.explorer.restrictToBucket(h.buckets[i].Start, h.bucketSize)
+			}
+		}
+		return true, nil
+	})
+}
+
+// restrictToBucket shows only the entries logged in the given bucket
+func (e *logExplorer) restrictToBucket(start time.Time, size time.Duration) {
+	e.bucket = &TimeRange{Start: start, End: start.Add(size)}
+	e.refreshView()
+}
+
+// clearBucket removes the histogram bucket restriction
+func (e *logExplorer) clearBucket() {
+	if e.bucket == nil {
+		return
+	}
+	e.bucket = nil
+	e.refreshView()
+}
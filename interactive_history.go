@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// filterStoreFileName is the file in the config directory holding the filter history and
+// the saved filters of the interactive mode
+const filterStoreFileName = "filters.json"
+
+// maxFilterHistory is the number of recent filters remembered across sessions
+const maxFilterHistory = 50
+
+// maxSavedFilterKeys is the number of saved filters that can be applied with the digit keys
+const maxSavedFilterKeys = 9
+
+// SavedFilter is a filter expression saved under a name in the interactive mode
+type SavedFilter struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter"`
+}
+
+// filterStore holds the recent and saved filters of the interactive mode
+type filterStore struct {
+	History []string      `json:"history,omitempty"` // Oldest first
+	Saved   []SavedFilter `json:"saved,omitempty"`
+	path    string        // File the store is saved to, empty to keep it in memory only
+}
+
+// loadFilterStore reads the filter store from a file. A missing file yields an empty store.
+func loadFilterStore(path string) (*filterStore, error) {
+	store := &filterStore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return &filterStore{path: path}, fmt.Errorf("invalid filter file %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// save writes the store to its file, creating the config directory if needed
+func (s *filterStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+// addHistory records a filter as the most recent one, dropping older duplicates and the
+// oldest filters beyond maxFilterHistory
+func (s *filterStore) addHistory(filter string) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return
+	}
+	s.History = slices.DeleteFunc(s.History, func(f string) bool { return f == filter })
+	s.History = append(s.History, filter)
+	if len(s.History) > maxFilterHistory {
+		s.History = s.History[len(s.History)-maxFilterHistory:]
+	}
+}
+
+// saveFilter saves a filter under a name, replacing a saved filter with the same name
+func (s *filterStore) saveFilter(name, filter string) {
+	for i := range s.Saved {
+		if s.Saved[i].Name == name {
+			s.Saved[i].Filter = filter
+			return
+		}
+	}
+	s.Saved = append(s.Saved, SavedFilter{Name: name, Filter: filter})
+}
+
+// newExplorerFilterStore loads the filter store from the config directory. Without a config
+// directory the store is only kept in memory.
+func newExplorerFilterStore() (*filterStore, error) {
+	dir, err := configDir()
+	if err != nil {
+		return &filterStore{}, nil
+	}
+	return loadFilterStore(filepath.Join(dir, filterStoreFileName))
+}
+
+// rememberFilter adds an applied filter to the history and persists it
+func (e *logExplorer) rememberFilter(filter string) {
+	e.historyPos = -1
+	if strings.TrimSpace(filter) == "" {
+		return
+	}
+	e.filters.addHistory(filter)
+	if err := e.filters.save(); err != nil {
+		logger.Debug("failed to save the filter history", "error", err)
+	}
+}
+
+// handleFilterHistoryKey steps through the filter history with the Up and Down keys while
+// the filter input has focus, restoring the typed text past the most recent filter
+func (e *logExplorer) handleFilterHistoryKey(event *tcell.EventKey) *tcell.EventKey {
+	history := e.filters.History
+	switch event.Key() {
+	case tcell.KeyUp:
+		if len(history) == 0 {
+			return nil
+		}
+		if e.historyPos < 0 {
+			e.historyDraft = e.filterInput.GetText()
+			e.historyPos = len(history)
+		}
+		e.historyPos = max(e.historyPos-1, 0)
+		e.filterInput.SetText(history[e.historyPos])
+	case tcell.KeyDown:
+		if e.historyPos < 0 {
+			return nil
+		}
+		e.historyPos++
+		if e.historyPos >= len(history) {
+			e.historyPos = -1
+			e.filterInput.SetText(e.historyDraft)
+		} else {
+			e.filterInput.SetText(history[e.historyPos])
+		}
+	default:
+		return event
+	}
+	return nil
+}
+
+// useFilter shows a filter in the filter input and applies it
+func (e *logExplorer) useFilter(filter string) {
+	e.filterInput.SetText(filter)
+	e.applyFilter(filter)
+}
+
+// applySavedFilter applies the saved filter bound to a digit key, starting at 1
+func (e *logExplorer) applySavedFilter(number int) {
+	if number < 1 || number > len(e.filters.Saved) {
+		e.statusBar.SetText(fmt.Sprintf("No saved filter %d (press w to save the current filter)", number))
+		return
+	}
+	e.useFilter(e.filters.Saved[number-1].Filter)
+}
+
+// promptSaveFilter asks for a name and saves the current filter under it
+func (e *logExplorer) promptSaveFilter() {
+	if e.filter == "" {
+		e.statusBar.SetText("No filter to save (press / to enter a filter)")
+		return
+	}
+
+	filter := e.filter
+	e.promptInput("Save filter (Enter to save, Esc to cancel)", "Name: ", "", func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		e.filters.saveFilter(name, filter)
+		if err := e.filters.save(); err != nil {
+			e.statusBar.SetText(fmt.Sprintf("Error saving filter: %v", err))
+			return
+		}
+		e.statusBar.SetText(fmt.Sprintf("Saved filter %q", name))
+	})
+}
+
+// showSavedFilters lists the saved filters over the main layout: Enter or a digit applies a
+// filter, d deletes it and Esc closes the list
+func (e *logExplorer) showSavedFilters() {
+	if len(e.filters.Saved) == 0 {
+		e.statusBar.SetText("No saved filters (press w to save the current filter)")
+		return
+	}
+
+	list := tview.NewList()
+	list.SetBorder(true).SetTitle("Saved filters (Enter or 1-9 to apply, d to delete, Esc to close)")
+	closeList := func() {
+		e.pages.RemovePage(promptPage)
+		e.app.SetFocus(e.logTable)
+	}
+	for i, saved := range e.filters.Saved {
+		shortcut := rune(0)
+		if i < maxSavedFilterKeys {
+			shortcut = rune('1' + i)
+		}
+		filter := saved.Filter
+		list.AddItem(saved.Name, filter, shortcut, func() {
+			closeList()
+			e.useFilter(filter)
+		})
+	}
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			closeList()
+		case event.Key() == tcell.KeyRune && event.Rune() == 'd':
+			current := list.GetCurrentItem()
+			e.filters.Saved = slices.Delete(e.filters.Saved, current, current+1)
+			if err := e.filters.save(); err != nil {
+				e.statusBar.SetText(fmt.Sprintf("Error saving filters: %v", err))
+			}
+			closeList()
+			e.showSavedFilters()
+		default:
+			return event
+		}
+		return nil
+	})
+
+	height := min(len(e.filters.Saved)*2+2, 22)
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, height, 0, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+	e.pages.AddPage(promptPage, modal, true, true)
+	e.app.SetFocus(list)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// helpPage is the name of the page holding the help overlay
+const helpPage = "help"
+
+// keyBinding documents a key of the interactive mode in the help overlay
+type keyBinding struct {
+	keys        string
+	description string
+}
+
+// explorerKeyBindings lists the keys of the interactive mode, grouped by section
+var explorerKeyBindings = []struct {
+	section  string
+	bindings []keyBinding
+}{
+	{"Navigation", []keyBinding{
+		{"j / k, ↓ / ↑", "Next / previous entry"},
+		{"g / G, Home / End", "First / last entry"},
+		{"Ctrl+D / Ctrl+U", "Half a page down / up"},
+		{"PgDn / PgUp", "Page down / up"},
+		{"t", "Jump to the first entry at or after a time"},
+		{"Tab", "Cycle focus between filter, list and histogram"},
+		{"Enter", "Show entry details, or expand the selected group"},
+	}},
+	{"Filter and search", []keyBinding{
+		{"/", "Focus the filter (Enter applies, Esc returns to the list)"},
+		{"Ctrl+F", "Search without hiding entries"},
+		{"n / N", "Next / previous search hit"},
+		{"↑ / ↓", "In the filter: previous / next filter from the history"},
+		{"w", "Save the current filter under a name"},
+		{"F", "List saved filters to apply or delete them"},
+		{"1-9", "Apply a saved filter"},
+		{"b", "Group by level, source, node, or no grouping"},
+		{"S", "Sort by time, newest first, level severity, or duplicate count"},
+		{"Esc / Backspace", "Leave the expanded group"},
+	}},
+	{"Bookmarks", []keyBinding{
+		{"m", "Mark or unmark the selected entry"},
+		{"a", "Add a note to the selected entry"},
+		{"] / [", "Next / previous marked entry"},
+		{"x", "Export marked entries to markdown and JSON"},
+	}},
+	{"AI findings (with --ai-analyze)", []keyBinding{
+		{"i", "Show or hide the findings (Enter jumps to the cited entries)"},
+		{"e / E", "Next / previous entry cited by the selected finding"},
+	}},
+	{"Copy to clipboard", []keyBinding{
+		{"y", "Copy the details of the selected entry or group"},
+		{"Y", "Copy the raw line of the selected entry"},
+		{"c", "Copy the stats of the entries matching the filter"},
+	}},
+	{"Source code", []keyBinding{
+		{"o", "Open the caller of the selected entry in $EDITOR (needs --source-dir)"},
+		{"p", "Copy a GitHub link to the caller of the selected entry"},
+	}},
+	{"Panels", []keyBinding{
+		{"s", "Toggle the stats panel"},
+		{"f", "Toggle auto-scroll (with --follow)"},
+		{"← / →, Enter, Esc", "Histogram: move, show only a bucket, show all"},
+		{"Ctrl+S", "Save the session, restored with 'lamp resume'"},
+		{"?", "Toggle this help"},
+		{"Ctrl+C", "Quit"},
+	}},
+}
+
+// formatHelp renders the key bindings for the help overlay
+func formatHelp() string {
+	width := 0
+	for _, section := range explorerKeyBindings {
+		for _, binding := range section.bindings {
+			width = max(width, len([]rune(binding.keys)))
+		}
+	}
+
+	var sb strings.Builder
+	for i, section := range explorerKeyBindings {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(colorText(theme.Label, section.section) + "\n")
+		for _, binding := range section.bindings {
+			padding := strings.Repeat(" ", width-len([]rune(binding.keys)))
+			sb.WriteString(fmt.Sprintf("  %s%s  %s\n", colorText(theme.Key, tview.Escape(binding.keys)), padding, binding.description))
+		}
+	}
+	return sb.String()
+}
+
+// handleKey handles the keys of the interactive mode before they reach the focused widget
+func (e *logExplorer) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	// Leave all keys to the prompt input while it is open
+	if e.pages.HasPage(promptPage) {
+		return event
+	}
+
+	// Any key closes the help overlay
+	if e.pages.HasPage(helpPage) {
+		e.toggleHelp()
+		return nil
+	}
+
+	if event.Key() == tcell.KeyTab {
+		// Cycle focus between filter, list and histogram
+		switch {
+		case e.filterInput.HasFocus():
+			e.app.SetFocus(e.logTable)
+		case e.logTable.HasFocus():
+			e.app.SetFocus(e.histogram)
+		default:
+			e.app.SetFocus(e.filterInput)
+		}
+		return nil
+	}
+
+	// Single-key shortcuts only apply outside the filter input
+	if e.filterInput.HasFocus() {
+		return event
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape, tcell.KeyBackspace, tcell.KeyBackspace2:
+		if e.openGroup == "" {
+			return event
+		}
+		e.closeGroup()
+	case tcell.KeyCtrlD:
+		e.moveSelection(e.halfPage())
+	case tcell.KeyCtrlU:
+		e.moveSelection(-e.halfPage())
+	case tcell.KeyCtrlF:
+		e.promptSearch()
+	case tcell.KeyCtrlS:
+		e.saveCurrentSession()
+	case tcell.KeyRune:
+		return e.handleRune(event)
+	default:
+		return event
+	}
+	return nil
+}
+
+// handleRune handles the single-character shortcuts
+func (e *logExplorer) handleRune(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case '?':
+		e.toggleHelp()
+	case '/':
+		e.app.SetFocus(e.filterInput)
+	case 'g':
+		e.moveSelection(-e.rowCount())
+	case 'G':
+		e.moveSelection(e.rowCount())
+	case 's':
+		e.toggleStats()
+	case 'm':
+		e.toggleBookmark()
+	case 'a':
+		e.editNote()
+	case ']':
+		e.jumpToBookmark(1)
+	case '[':
+		e.jumpToBookmark(-1)
+	case 'x':
+		e.exportBookmarks()
+	case 'y':
+		e.copyDetails()
+	case 'Y':
+		e.copyRawEntry()
+	case 'c':
+		e.copyStats()
+	case 'o':
+		e.openSource()
+	case 'p':
+		e.copyPermalink()
+	case 'b':
+		e.cycleGroupBy()
+	case 'S':
+		e.cycleSort()
+	case 'w':
+		e.promptSaveFilter()
+	case 'F':
+		e.showSavedFilters()
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		e.applySavedFilter(int(event.Rune() - '0'))
+	case 't':
+		e.promptJumpToTime()
+	case 'n':
+		e.jumpToHit(1)
+	case 'N':
+		e.jumpToHit(-1)
+	case 'f':
+		e.toggleAutoScroll()
+	case 'i':
+		e.toggleFindings()
+	case 'e':
+		e.jumpToEvidence(1)
+	case 'E':
+		e.jumpToEvidence(-1)
+	default:
+		return event
+	}
+	return nil
+}
+
+// rowCount returns the number of rows of the log table
+func (e *logExplorer) rowCount() int {
+	if e.showingGroups() {
+		return len(e.groups)
+	}
+	return len(e.visible)
+}
+
+// halfPage returns half the number of rows shown by the log table
+func (e *logExplorer) halfPage() int {
+	_, _, _, height := e.logTable.GetInnerRect()
+	return max(height/2, 1)
+}
+
+// moveSelection moves the selection of the log table by delta rows, within bounds
+func (e *logExplorer) moveSelection(delta int) {
+	count := e.rowCount()
+	if count == 0 {
+		return
+	}
+	e.app.SetFocus(e.logTable)
+	e.selectRow(min(max(e.selectedRow()+delta, 0), count-1))
+}
+
+// toggleHelp shows or hides the help overlay
+func (e *logExplorer) toggleHelp() {
+	if e.pages.HasPage(helpPage) {
+		e.pages.RemovePage(helpPage)
+		return
+	}
+
+	help := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(formatHelp())
+	help.SetBorder(true).SetTitle("Key bindings (press any key to close)")
+
+	_, _, _, height := e.pages.GetRect()
+	helpHeight := min(strings.Count(help.GetText(false), "\n")+2, max(height-2, 3))
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(help, helpHeight, 0, false).
+			AddItem(nil, 0, 1, false), 72, 0, false).
+		AddItem(nil, 0, 1, false)
+	e.pages.AddPage(helpPage, modal, true, true)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// logSearch is a search in the TUI. Unlike the filter it does not hide entries: matches are
+// marked and highlighted, and n/N move between them.
+type logSearch struct {
+	expr      string
+	filter    logFilter
+	highlight *regexp.Regexp // Matches the text to highlight, nil if nothing can be highlighted
+}
+
+// newLogSearch parses a search using the filter expression syntax (see parseFilterExpression)
+func newLogSearch(expr string) (logSearch, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return logSearch{}, nil
+	}
+
+	filter, err := parseFilterExpression(expr)
+	if err != nil {
+		return logSearch{}, err
+	}
+	return logSearch{expr: expr, filter: filter, highlight: searchHighlightRegex(expr)}, nil
+}
+
+// matches reports whether an entry is a search hit. Nothing matches an empty search.
+func (s logSearch) matches(entry LogEntry) bool {
+	return s.expr != "" && s.filter.matches(entry)
+}
+
+// searchHighlightRegex builds a regex matching the text to highlight for a search: bare
+// words (case-insensitive) and the /regex term. Field and level terms are not highlighted.
+func searchHighlightRegex(expr string) *regexp.Regexp {
+	var alternatives []string
+	for expr = strings.TrimSpace(expr); expr != ""; {
+		if strings.HasPrefix(expr, "/") {
+			pattern := strings.TrimSuffix(strings.TrimPrefix(expr, "/"), "/")
+			if pattern != "" {
+				alternatives = append(alternatives, "(?:"+pattern+")")
+			}
+			break
+		}
+
+		token := expr
+		if idx := strings.IndexAny(expr, " \t"); idx >= 0 {
+			token = expr[:idx]
+			expr = strings.TrimSpace(expr[idx:])
+		} else {
+			expr = ""
+		}
+		if strings.IndexAny(token, "=<>") <= 0 {
+			alternatives = append(alternatives, "(?i:"+regexp.QuoteMeta(token)+")")
+		}
+	}
+
+	if len(alternatives) == 0 {
+		return nil
+	}
+	regex, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil
+	}
+	return regex
+}
+
+// highlightMatches escapes text for tview and highlights the parts matched by highlight
+func highlightMatches(text string, highlight *regexp.Regexp) string {
+	if highlight == nil {
+		return tview.Escape(text)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range highlight.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+		sb.WriteString(tview.Escape(text[last:match[0]]))
+		sb.WriteString(fmt.Sprintf("[%s:%s]", theme.HighlightText, theme.Highlight))
+		sb.WriteString(tview.Escape(text[match[0]:match[1]]))
+		sb.WriteString("[-:-]")
+		last = match[1]
+	}
+	sb.WriteString(tview.Escape(text[last:]))
+	return sb.String()
+}
+
+// promptSearch asks for a search and selects the first hit at or after the selected row
+func (e *logExplorer) promptSearch() {
+	e.promptInput("Search (Enter to search, empty to clear, Esc to cancel)", "Search: ", e.search.expr, func(expr string) {
+		search, err := newLogSearch(expr)
+		if err != nil {
+			e.statusBar.SetText(fmt.Sprintf("Invalid search: %v", err))
+			return
+		}
+
+		e.search = search
+		e.countHits()
+		e.showSelection(e.selectedRow())
+		if !e.showingGroups() && len(e.visible) > 0 && !e.search.matches(e.logs[e.visible[e.selectedRow()]]) {
+			e.jumpToHit(1)
+		}
+		e.updateStatusBar()
+	})
+}
+
+// countHits updates the number of visible entries matching the search
+func (e *logExplorer) countHits() {
+	e.searchHits = 0
+	if e.search.expr == "" {
+		return
+	}
+	for _, index := range e.visible {
+		if e.search.matches(e.logs[index]) {
+			e.searchHits++
+		}
+	}
+}
+
+// jumpToHit selects the next (direction 1) or previous (direction -1) search hit
+func (e *logExplorer) jumpToHit(direction int) {
+	if e.search.expr == "" {
+		return
+	}
+	if !e.jumpTo(direction, func(index int) bool {
+		return e.search.matches(e.logs[index])
+	}) {
+		e.statusBar.SetText(fmt.Sprintf("No hits for %q", e.search.expr))
+	}
+}
+
+// jumpTo selects the next (direction 1) or previous (direction -1) visible entry for which
+// match returns true, wrapping around at either end. It returns false if there is none.
+func (e *logExplorer) jumpTo(direction int, match func(index int) bool) bool {
+	count := len(e.visible)
+	if e.showingGroups() || count == 0 {
+		return false
+	}
+
+	current := e.selectedRow()
+	for step := 1; step <= count; step++ {
+		i := ((current+direction*step)%count + count) % count
+		if match(e.visible[i]) {
+			e.selectRow(i)
+			return true
+		}
+	}
+	return false
+}
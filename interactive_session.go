@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionVersion is the version of the session file format
+const sessionVersion = 1
+
+// sessionFileName is the file in the config directory a session is saved to by default
+const sessionFileName = "session.json"
+
+// Session is the saved state of an interactive mode session, restored with 'lamp resume'
+type Session struct {
+	Version   int            `json:"version"`
+	SavedAt   time.Time      `json:"saved_at"`
+	Source    *SessionSource `json:"source"`
+	Filter    string         `json:"filter,omitempty"`
+	Search    string         `json:"search,omitempty"`
+	GroupBy   string         `json:"group_by,omitempty"`
+	OpenGroup string         `json:"open_group,omitempty"`
+	SortBy    string         `json:"sort_by,omitempty"`
+	Bookmarks []SessionEntry `json:"bookmarks,omitempty"`
+	Selected  *SessionEntry  `json:"selected,omitempty"`
+}
+
+// SessionSource records how the logs of a session were loaded: the command, its paths and
+// the command line filters
+type SessionSource struct {
+	Command string   `json:"command"` // file, notification or support-packet
+	Paths   []string `json:"paths"`
+	Follow  bool     `json:"follow,omitempty"`
+	Search  string   `json:"search,omitempty"`
+	Regex   string   `json:"regex,omitempty"`
+	Level   string   `json:"level,omitempty"`
+	User    string   `json:"user,omitempty"`
+	Start   string   `json:"start,omitempty"`
+	End     string   `json:"end,omitempty"`
+}
+
+// SessionEntry identifies a log entry by timestamp and message, which survive reloading the
+// files, with the bookmark note for marked entries
+type SessionEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Note      string    `json:"note,omitempty"`
+}
+
+// newSessionSource records the command line a command loads its logs with. Paths are made
+// absolute so the session can be resumed from another directory.
+func newSessionSource(command string, paths []string) *SessionSource {
+	absolute := make([]string, len(paths))
+	for i, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		absolute[i] = path
+	}
+	return &SessionSource{
+		Command: command,
+		Paths:   absolute,
+		Follow:  follow,
+		Search:  searchTerm,
+		Regex:   regexSearch,
+		Level:   levelFilter,
+		User:    userFilter,
+		Start:   startTime,
+		End:     endTime,
+	}
+}
+
+// apply sets the command line filters of the source
+func (s *SessionSource) apply() {
+	follow = s.Follow
+	searchTerm = s.Search
+	regexSearch = s.Regex
+	levelFilter = s.Level
+	userFilter = s.User
+	startTime = s.Start
+	endTime = s.End
+}
+
+// defaultSessionPath returns the path sessions are saved to and resumed from by default
+func defaultSessionPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sessionFileName), nil
+}
+
+// saveSession writes a session as indented JSON, creating the parent directory if needed
+func saveSession(session Session, path string) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadSession reads a session file
+func loadSession(path string) (Session, error) {
+	var session Session
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return session, err
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, fmt.Errorf("invalid session file: %v", err)
+	}
+	if session.Version != sessionVersion {
+		return session, fmt.Errorf("unsupported session version %d (expected %d)", session.Version, sessionVersion)
+	}
+	if session.Source == nil || len(session.Source.Paths) == 0 {
+		return session, fmt.Errorf("session file does not record the loaded files")
+	}
+	return session, nil
+}
+
+// sessionEntryKey returns the key identifying an entry across sessions
+func sessionEntryKey(timestamp time.Time, message string) string {
+	return fmt.Sprintf("%d %s", timestamp.UnixNano(), message)
+}
+
+// captureSession returns the current state of the explorer
+func (e *logExplorer) captureSession() Session {
+	session := Session{
+		Version:   sessionVersion,
+		SavedAt:   time.Now(),
+		Source:    e.source,
+		Filter:    e.filter,
+		Search:    e.search.expr,
+		GroupBy:   e.groupBy,
+		OpenGroup: e.openGroup,
+		SortBy:    e.sortBy,
+	}
+	for _, bookmark := range collectBookmarks(e.logs, e.bookmarks) {
+		session.Bookmarks = append(session.Bookmarks, SessionEntry{
+			Timestamp: bookmark.Timestamp,
+			Message:   bookmark.Message,
+			Note:      bookmark.Note,
+		})
+	}
+	if index := e.currentIndex(); index >= 0 {
+		session.Selected = &SessionEntry{Timestamp: e.logs[index].Timestamp, Message: e.logs[index].Message}
+	}
+	return session
+}
+
+// restoreSession applies the filter, search, grouping, order, bookmarks and selection of a
+// saved session. Entries that are no longer loaded are skipped.
+func (e *logExplorer) restoreSession(session Session) {
+	indexes := make(map[string]int, len(e.logs))
+	for i := len(e.logs) - 1; i >= 0; i-- {
+		indexes[sessionEntryKey(e.logs[i].Timestamp, e.logs[i].Message)] = i
+	}
+	for _, bookmark := range session.Bookmarks {
+		if index, ok := indexes[sessionEntryKey(bookmark.Timestamp, bookmark.Message)]; ok {
+			e.bookmarks[index] = bookmark.Note
+		}
+	}
+
+	if search, err := newLogSearch(session.Search); err == nil {
+		e.search = search
+	}
+	if contains(groupModes, session.GroupBy) {
+		e.groupBy = session.GroupBy
+		e.openGroup = session.OpenGroup
+	}
+	if contains(sortModes, session.SortBy) {
+		e.sortBy = session.SortBy
+	}
+	e.useFilter(session.Filter)
+
+	if session.Selected != nil {
+		if index, ok := indexes[sessionEntryKey(session.Selected.Timestamp, session.Selected.Message)]; ok {
+			e.selectEntry(index)
+		}
+	}
+}
+
+// saveCurrentSession saves the session to the default session file
+func (e *logExplorer) saveCurrentSession() {
+	if e.source == nil {
+		e.statusBar.SetText("This session cannot be saved: the loaded files are unknown")
+		return
+	}
+	path, err := defaultSessionPath()
+	if err == nil {
+		err = saveSession(e.captureSession(), path)
+	}
+	if err != nil {
+		e.statusBar.SetText(fmt.Sprintf("Error saving session: %v", err))
+		return
+	}
+	e.statusBar.SetText(fmt.Sprintf("Session saved to %s (restore it with 'lamp resume')", path))
+}
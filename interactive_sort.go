@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// sortModes are the orders of the list cycled through with the S key: oldest first, newest
+// first, most severe level first and most duplicated first
+var sortModes = []string{"", "newest", "level", "count"}
+
+// sortDescriptions describe the sort modes in the status bar
+var sortDescriptions = map[string]string{
+	"newest": "newest first",
+	"level":  "level severity",
+	"count":  "duplicate count",
+}
+
+// sortLogIndices returns the given entries, which must be in log (timestamp) order, in the
+// order of a sort mode. Entries that compare equal keep their timestamp order.
+func sortLogIndices(logs []LogEntry, indexes []int, by string) []int {
+	sorted := slices.Clone(indexes)
+	switch by {
+	case "newest":
+		slices.Reverse(sorted)
+	case "level":
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return levelSeverity(logs[sorted[i]].Level) > levelSeverity(logs[sorted[j]].Level)
+		})
+	case "count":
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return max(logs[sorted[i]].DuplicateCount, 1) > max(logs[sorted[j]].DuplicateCount, 1)
+		})
+	}
+	return sorted
+}
+
+// cycleSort switches to the next sort mode, keeping the selected entry selected
+func (e *logExplorer) cycleSort() {
+	next := 0
+	for i, mode := range sortModes {
+		if mode == e.sortBy {
+			next = (i + 1) % len(sortModes)
+			break
+		}
+	}
+
+	selected := e.currentIndex()
+	e.sortBy = sortModes[next]
+	e.refreshView()
+	e.selectEntry(selected)
+}
+
+// selectEntry selects the row of an entry, given by its index into logs, if it is visible
+func (e *logExplorer) selectEntry(index int) {
+	if index < 0 || e.showingGroups() {
+		return
+	}
+	if row := slices.Index(e.visible, index); row >= 0 {
+		e.selectRow(row)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// serverRepositoryURL is the GitHub repository of the Mattermost server
+const serverRepositoryURL = "https://github.com/mattermost/mattermost"
+
+// monorepoMajorVersion is the first server version whose sources live under server/
+const monorepoMajorVersion = 8
+
+// serverSourceDirs maps the directory of a caller, which only keeps the last directory of
+// the file path, to the directory of the server sources it is in. Other directories are
+// assumed to be at the top of the sources.
+var serverSourceDirs = map[string]string{
+	"platform":        "app/platform",
+	"email":           "app/email",
+	"sqlstore":        "store/sqlstore",
+	"localcachelayer": "store/localcachelayer",
+	"retrylayer":      "store/retrylayer",
+	"timerlayer":      "store/timerlayer",
+	"searchlayer":     "store/searchlayer",
+	"mlog":            "shared/mlog",
+}
+
+// serverPublicDirs lists the top directories of the server sources that moved to
+// server/public rather than server/channels in the monorepo
+var serverPublicDirs = []string{"model", "plugin", "shared"}
+
+// parseCaller splits a caller such as "web/handlers.go:187" into file and line
+func parseCaller(caller string) (string, int, bool) {
+	file, lineText, ok := strings.Cut(strings.TrimSpace(caller), ":")
+	if !ok || !strings.HasSuffix(file, ".go") {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(lineText)
+	if err != nil || line < 1 {
+		return "", 0, false
+	}
+	return file, line, true
+}
+
+// serverSourcePath returns the path of a caller file in the server repository: under
+// server/ from version 8 on, or at the top of the repository for older (or unknown) versions
+func serverSourcePath(file, version string) string {
+	dir, base := path.Split(file)
+	dir = strings.TrimSuffix(dir, "/")
+	if mapped, ok := serverSourceDirs[dir]; ok {
+		dir = mapped
+	}
+	legacy := path.Join(dir, base)
+
+	if serverMajorVersion(version) < monorepoMajorVersion {
+		return legacy
+	}
+	top, _, _ := strings.Cut(legacy, "/")
+	if contains(serverPublicDirs, top) {
+		return path.Join("server/public", legacy)
+	}
+	return path.Join("server/channels", legacy)
+}
+
+// serverMajorVersion returns the major version of a server version such as "9.5.1", or 0 if
+// it is unknown
+func serverMajorVersion(version string) int {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// githubPermalink returns the GitHub URL of a caller line for a server version. Without a
+// version the link points at master, where the line may have moved.
+func githubPermalink(file string, line int, version string) string {
+	if version == "" {
+		return fmt.Sprintf("%s/blob/master/%s#L%d", serverRepositoryURL,
+			serverSourcePath(file, strconv.Itoa(monorepoMajorVersion)), line)
+	}
+	version = strings.TrimPrefix(version, "v")
+	return fmt.Sprintf("%s/blob/v%s/%s#L%d", serverRepositoryURL, version, serverSourcePath(file, version), line)
+}
+
+// findLocalSource returns the path of a caller file in a local server checkout, which may be
+// the repository itself, its server directory, or a checkout of a version before the monorepo
+func findLocalSource(sourceDir, file string) (string, bool) {
+	candidates := []string{
+		serverSourcePath(file, strconv.Itoa(monorepoMajorVersion)),
+		strings.TrimPrefix(serverSourcePath(file, strconv.Itoa(monorepoMajorVersion)), "server/"),
+		serverSourcePath(file, ""),
+	}
+	for _, candidate := range candidates {
+		local := filepath.Join(sourceDir, filepath.FromSlash(candidate))
+		if info, err := os.Stat(local); err == nil && !info.IsDir() {
+			return local, true
+		}
+	}
+	return "", false
+}
+
+// editorCommand builds the command opening a file at a line in an editor, which may include
+// space-separated arguments. VS Code style editors take file:line, others the +line convention
+// of vi and emacs.
+func editorCommand(editor, file string, line int) (*exec.Cmd, error) {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid editor %q", editor)
+	}
+	switch filepath.Base(args[0]) {
+	case "code", "code-insiders", "codium", "cursor":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d", file, line))
+	default:
+		args = append(args, fmt.Sprintf("+%d", line), file)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+// selectedCaller returns the file and line of the caller of the selected entry
+func (e *logExplorer) selectedCaller() (string, int, bool) {
+	index := e.currentIndex()
+	if index < 0 {
+		return "", 0, false
+	}
+	file, line, ok := parseCaller(e.logs[index].Source)
+	if !ok {
+		e.statusBar.SetText("The selected entry has no caller (file.go:line)")
+	}
+	return file, line, ok
+}
+
+// openSource opens the caller of the selected entry in $EDITOR, from the --source-dir checkout
+func (e *logExplorer) openSource() {
+	file, line, ok := e.selectedCaller()
+	if !ok {
+		return
+	}
+	if e.sourceDir == "" {
+		e.statusBar.SetText("Set --source-dir to a Mattermost server checkout to open sources (p copies a GitHub link)")
+		return
+	}
+	local, found := findLocalSource(e.sourceDir, file)
+	if !found {
+		e.statusBar.SetText(fmt.Sprintf("%s not found in %s", file, e.sourceDir))
+		return
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd, err := editorCommand(editor, local, line)
+	if err != nil {
+		e.statusBar.SetText(err.Error())
+		return
+	}
+
+	var runErr error
+	e.app.Suspend(func() {
+		runErr = cmd.Run()
+	})
+	if runErr != nil {
+		e.statusBar.SetText(fmt.Sprintf("Error running %s: %v", editor, runErr))
+	}
+}
+
+// copyPermalink copies the GitHub link to the caller of the selected entry
+func (e *logExplorer) copyPermalink() {
+	file, line, ok := e.selectedCaller()
+	if !ok {
+		return
+	}
+	link := githubPermalink(file, line, e.serverVersion)
+	if err := clipboardWriteAll(link); err != nil {
+		e.statusBar.SetText(link)
+		return
+	}
+	if e.serverVersion == "" {
+		e.statusBar.SetText(fmt.Sprintf("Copied %s (server version unknown, linking master)", link))
+		return
+	}
+	e.statusBar.SetText(fmt.Sprintf("Copied %s", link))
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// promptPage is the name of the page holding the prompt input
+const promptPage = "prompt"
+
+// Columns of the log table
+const (
+	columnMark = iota
+	columnLevel
+	columnTime
+	columnSource
+	columnMessage
+	columnCount
+)
+
+// jumpTimeFormats are the accepted formats for the jump to time prompt. Formats without a
+// date are taken relative to the day of the first visible entry.
+var jumpTimeFormats = []struct {
+	layout  string
+	hasDate bool
+}{
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04", true},
+	{"2006-01-02", true},
+	{"15:04:05", false},
+	{"15:04", false},
+}
+
+// logTableContent renders the rows of the log table on demand from the explorer state,
+// so that building the view does not depend on the number of entries
+type logTableContent struct {
+	tview.TableContentReadOnly
+	explorer *logExplorer
+}
+
+// GetRowCount returns the number of visible entries, or of groups in the group list
+func (c *logTableContent) GetRowCount() int {
+	if c.explorer.showingGroups() {
+		return len(c.explorer.groups)
+	}
+	return len(c.explorer.visible)
+}
+
+// GetColumnCount returns the number of table columns
+func (c *logTableContent) GetColumnCount() int {
+	return columnCount
+}
+
+// GetCell returns the cell of an entry or group row
+func (c *logTableContent) GetCell(row, column int) *tview.TableCell {
+	e := c.explorer
+	if e.showingGroups() {
+		if row >= len(e.groups) {
+			return nil
+		}
+		return groupTableCell(e.groups[row], column)
+	}
+	if row >= len(e.visible) {
+		return nil
+	}
+
+	index := e.visible[row]
+	log := e.logs[index]
+	switch column {
+	case columnMark:
+		if _, ok := e.bookmarks[index]; ok {
+			return tview.NewTableCell("*").SetTextColor(theme.color(theme.Bookmark))
+		}
+		if e.evidence[index] {
+			return tview.NewTableCell("!").SetTextColor(theme.color(theme.Warn))
+		}
+		if e.search.matches(log) {
+			return tview.NewTableCell(">").SetTextColor(theme.color(theme.Label))
+		}
+		return tview.NewTableCell(" ")
+	case columnLevel:
+		return tview.NewTableCell(log.Level).SetTextColor(theme.color(getLevelColorName(log.Level)))
+	case columnTime:
+		return tview.NewTableCell(log.Timestamp.Format("15:04:05"))
+	case columnSource:
+		return tview.NewTableCell(truncateString(log.Source, 30)).SetTextColor(theme.color(theme.Muted))
+	default:
+		message := highlightMatches(truncateString(log.Message, 200), e.search.highlight)
+		if log.DuplicateCount > 1 {
+			message = fmt.Sprintf("%s %s", message, colorText(theme.Label, fmt.Sprintf("(×%d)", log.DuplicateCount)))
+		}
+		return tview.NewTableCell(message).SetExpansion(1)
+	}
+}
+
+// groupTableCell returns a cell of a group row
+func groupTableCell(group logGroup, column int) *tview.TableCell {
+	switch column {
+	case columnLevel:
+		return tview.NewTableCell(fmt.Sprintf("%d", len(group.Indexes))).
+			SetTextColor(theme.color(theme.Label)).SetAlign(tview.AlignRight)
+	case columnTime:
+		if group.Errors > 0 {
+			return tview.NewTableCell(fmt.Sprintf("%d errors", group.Errors)).SetTextColor(theme.color(theme.Error))
+		}
+		return tview.NewTableCell("")
+	case columnMessage:
+		return tview.NewTableCell(tview.Escape(group.Key)).SetExpansion(1)
+	default:
+		return tview.NewTableCell("")
+	}
+}
+
+// showingGroups reports whether the table shows the group list
+func (e *logExplorer) showingGroups() bool {
+	return e.groupBy != "" && e.openGroup == ""
+}
+
+// selectedRow returns the selected table row
+func (e *logExplorer) selectedRow() int {
+	row, _ := e.logTable.GetSelection()
+	return row
+}
+
+// selectRow selects a table row and shows its details
+func (e *logExplorer) selectRow(row int) {
+	e.logTable.Select(row, 0)
+	e.showSelection(row)
+}
+
+// showSelection shows the details of the entry or group in a table row
+func (e *logExplorer) showSelection(row int) {
+	if e.showingGroups() {
+		if row >= 0 && row < len(e.groups) {
+			e.details.SetText(formatGroupDetails(e.groups[row], e.groupBy))
+		}
+		return
+	}
+	if row >= 0 && row < len(e.visible) {
+		e.showDetails(row)
+	}
+}
+
+// openSelection expands the selected group, or shows the details of the selected entry
+func (e *logExplorer) openSelection(row int) {
+	if e.showingGroups() {
+		if row >= 0 && row < len(e.groups) {
+			e.openGroup = e.groups[row].Key
+			e.refreshView()
+		}
+		return
+	}
+	e.showSelection(row)
+}
+
+// promptInput shows a centered input over the main layout and calls onDone with the text
+// when Enter is pressed. Esc closes the prompt without calling onDone.
+func (e *logExplorer) promptInput(title, label, text string, onDone func(string)) {
+	input := tview.NewInputField().
+		SetLabel(label).
+		SetText(text)
+	input.SetBorder(true).SetTitle(title)
+	input.SetDoneFunc(func(key tcell.Key) {
+		e.pages.RemovePage(promptPage)
+		e.app.SetFocus(e.logTable)
+		if key == tcell.KeyEnter {
+			onDone(input.GetText())
+		}
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 3, 0, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+	e.pages.AddPage(promptPage, modal, true, true)
+	e.app.SetFocus(input)
+}
+
+// promptJumpToTime asks for a timestamp and selects the first visible entry at or after it
+func (e *logExplorer) promptJumpToTime() {
+	if e.showingGroups() || len(e.visible) == 0 {
+		return
+	}
+
+	e.promptInput("Jump to time (Enter to jump, Esc to cancel)", "Time: ", "", func(text string) {
+		// The visible entry with the smallest index is the earliest, whatever the sort
+		target, err := parseJumpTime(text, e.logs[slices.Min(e.visible)].Timestamp)
+		if err != nil {
+			e.statusBar.SetText(fmt.Sprintf("Invalid time: %v", err))
+			return
+		}
+		e.jumpToTime(target)
+	})
+}
+
+// jumpToTime selects the first visible entry logged at or after target, or the last one if
+// all are earlier, in any sort mode
+func (e *logExplorer) jumpToTime(target time.Time) {
+	e.selectEntry(findEntryAtOrAfter(e.logs, e.visible, target))
+}
+
+// parseJumpTime parses a jump target. Times without a date use the date of reference.
+func parseJumpTime(text string, reference time.Time) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	for _, format := range jumpTimeFormats {
+		parsed, err := time.ParseInLocation(format.layout, text, reference.Location())
+		if err != nil {
+			continue
+		}
+		if !format.hasDate {
+			year, month, day := reference.Date()
+			parsed = time.Date(year, month, day, parsed.Hour(), parsed.Minute(), parsed.Second(), 0, reference.Location())
+		}
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("expected YYYY-MM-DD HH:MM:SS or HH:MM[:SS], got %q", text)
+}
+
+// findEntryAtOrAfter returns the index into logs of the first of the entries of indexes
+// logged at or after target, or of the last one if all are earlier, -1 if indexes is empty.
+// logs are in timestamp order, but indexes may be in any order, such as that of a sort mode,
+// so they are scanned rather than searched.
+func findEntryAtOrAfter(logs []LogEntry, indexes []int, target time.Time) int {
+	found, last := -1, -1
+	for _, index := range indexes {
+		last = max(last, index)
+		if (found < 0 || index < found) && !logs[index].Timestamp.Before(target) {
+			found = index
+		}
+	}
+	if found < 0 {
+		return last
+	}
+	return found
+}
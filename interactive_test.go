@@ -0,0 +1,789 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatStatsPanel(t *testing.T) {
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2025-01-01 10:00:00.000 Z"), Level: "info", Message: "Server started", Source: "app/server.go:10"},
+		{Timestamp: mustParseTime(t, "2025-01-01 10:05:00.000 Z"), Level: "error", Message: "Database unreachable", Source: "store/sql.go:42"},
+		{Timestamp: mustParseTime(t, "2025-01-01 10:10:00.000 Z"), Level: "error", Message: "Database unreachable", Source: "store/sql.go:42"},
+	}
+
+	t.Run("filtered set", func(t *testing.T) {
+		filtered, err := filterLogEntries(logs, "database")
+		require.NoError(t, err)
+		assert.Len(t, filtered, 2)
+
+		panel := formatStatsPanel(filtered, len(logs))
+		assert.Contains(t, panel, "2 of 3")
+		assert.Contains(t, panel, "Error rate:[-] 100.0%")
+		assert.Contains(t, panel, "[red]ERROR[-]: 2")
+		assert.Contains(t, panel, "2025-01-01 10:05:00")
+		assert.Contains(t, panel, "(5m0s)")
+	})
+
+	t.Run("empty set", func(t *testing.T) {
+		assert.Contains(t, formatStatsPanel(nil, len(logs)), "0 of 3")
+	})
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "debug", Message: "Received HTTP request", Source: "web/handlers.go:187", Extras: map[string]string{"status_code": "200"}},
+		{Timestamp: ts, Level: "warn", Message: "Slow query", Source: "store/sql.go:12", User: "alice", SourceFile: "packet/node-2/logs/mattermost.log", Node: "node-2"},
+		{Timestamp: ts, Level: "error", Message: "Failed to upload file", Source: "api4/file.go:90", Extras: map[string]string{"status_code": "500"}},
+		{Timestamp: ts, Level: "info", Message: "User logged in", User: "bob"},
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{"substring", "upload", []string{"Failed to upload file"}},
+		{"regex", "/^(Slow|User) ", []string{"Slow query", "User logged in"}},
+		{"regex with trailing slash", "/file$/", []string{"Failed to upload file"}},
+		{"regex over extras", "/status_code=5\\d\\d", []string{"Failed to upload file"}},
+		{"extras field", "status_code=200", []string{"Received HTTP request"}},
+		{"negated field", "status_code!=200 level>=info", []string{"Slow query", "Failed to upload file", "User logged in"}},
+		{"user field", "user=ali", []string{"Slow query"}},
+		{"node field", "node=node-2", []string{"Slow query"}},
+		{"file field", "file=node-2/logs", []string{"Slow query"}},
+		{"level at least", "level>=warn", []string{"Slow query", "Failed to upload file"}},
+		{"level below", "level<info", []string{"Received HTTP request"}},
+		{"level list", "level=error,debug", []string{"Received HTTP request", "Failed to upload file"}},
+		{"combined terms", "level>=warn query", []string{"Slow query"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := filterLogEntries(logs, tt.filter)
+			require.NoError(t, err)
+
+			var messages []string
+			for _, entry := range filtered {
+				messages = append(messages, entry.Message)
+			}
+			assert.Equal(t, tt.want, messages)
+		})
+	}
+
+	t.Run("invalid expressions", func(t *testing.T) {
+		_, err := parseFilterExpression("/[unclosed")
+		assert.Error(t, err)
+		_, err = parseFilterExpression("level>=loud")
+		assert.Error(t, err)
+		_, err = parseFilterExpression("user>=bob")
+		assert.Error(t, err)
+	})
+}
+
+func TestBookmarksExport(t *testing.T) {
+	logs := []LogEntry{
+		{Timestamp: mustParseTime(t, "2025-01-01 10:00:00.000 Z"), Level: "info", Message: "Server started"},
+		{Timestamp: mustParseTime(t, "2025-01-01 10:01:00.000 Z"), Level: "error", Message: "Failed to connect", Source: "store/sql.go:12", User: "alice"},
+		{Timestamp: mustParseTime(t, "2025-01-01 10:02:00.000 Z"), Level: "warn", Message: "Slow query"},
+	}
+
+	bookmarks := collectBookmarks(logs, map[int]string{2: "", 1: "root cause"})
+	require.Len(t, bookmarks, 2)
+	assert.Equal(t, "Failed to connect", bookmarks[0].Message)
+	assert.Equal(t, "root cause", bookmarks[0].Note)
+	assert.Equal(t, "Slow query", bookmarks[1].Message)
+
+	var md strings.Builder
+	require.NoError(t, writeBookmarksMarkdown(bookmarks, &md))
+	assert.Contains(t, md.String(), "- **2025-01-01 10:01:00.000** `error` (store/sql.go:12): Failed to connect\n")
+	assert.Contains(t, md.String(), "  - Note: root cause\n")
+
+	var out strings.Builder
+	require.NoError(t, writeBookmarksJSON(bookmarks, &out))
+	var decoded []Bookmark
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &decoded))
+	assert.Equal(t, bookmarks, decoded)
+}
+
+func TestGroupLogIndices(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Source: "app/server.go:10", Extras: map[string]string{"node": "app-1"}},
+		{Timestamp: ts.Add(time.Minute), Level: "error", Source: "store/sql.go:12", Extras: map[string]string{"node": "app-2"}},
+		{Timestamp: ts.Add(2 * time.Minute), Level: "error", Source: "store/sql.go:40", Extras: map[string]string{"hostname": "app-2"}},
+		{Timestamp: ts.Add(3 * time.Minute), Level: "warn"},
+	}
+	all := []int{0, 1, 2, 3}
+
+	groups := groupLogIndices(logs, all, "source")
+	require.Len(t, groups, 3)
+	assert.Equal(t, "store/sql.go", groups[0].Key)
+	assert.Equal(t, []int{1, 2}, groups[0].Indexes)
+	assert.Equal(t, 2, groups[0].Errors)
+	assert.Equal(t, ts.Add(time.Minute), groups[0].First)
+	assert.Equal(t, ts.Add(2*time.Minute), groups[0].Last)
+	assert.Equal(t, unknownGroup, groups[1].Key)
+	assert.Equal(t, "app/server.go", groups[2].Key)
+
+	groups = groupLogIndices(logs, all, "node")
+	require.Len(t, groups, 3)
+	assert.Equal(t, "app-2", groups[0].Key)
+	assert.Len(t, groups[0].Indexes, 2)
+
+	groups = groupLogIndices(logs, []int{1, 3}, "level")
+	require.Len(t, groups, 2)
+	assert.Equal(t, "ERROR", groups[0].Key)
+	assert.Equal(t, "WARN", groups[1].Key)
+}
+
+func TestJumpToTime(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts},
+		{Timestamp: ts.Add(time.Minute)},
+		{Timestamp: ts.Add(2 * time.Minute)},
+		{Timestamp: ts.Add(time.Hour)},
+	}
+	visible := []int{0, 2, 3}
+
+	target, err := parseJumpTime("10:01", ts)
+	require.NoError(t, err)
+	assert.Equal(t, ts.Add(time.Minute), target)
+	assert.Equal(t, 2, findEntryAtOrAfter(logs, visible, target))
+
+	target, err = parseJumpTime("2025-01-01 10:30:00", ts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, findEntryAtOrAfter(logs, visible, target))
+
+	target, err = parseJumpTime("2025-01-02", ts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, findEntryAtOrAfter(logs, visible, target), "later than all entries selects the last")
+	assert.Equal(t, 0, findEntryAtOrAfter(logs, visible, ts.Add(-time.Hour)))
+	assert.Equal(t, -1, findEntryAtOrAfter(logs, nil, target))
+
+	_, err = parseJumpTime("yesterday", ts)
+	assert.Error(t, err)
+
+	t.Run("sorted", func(t *testing.T) {
+		logs := []LogEntry{
+			{Timestamp: ts, Level: "info", Message: "started"},
+			{Timestamp: ts.Add(time.Minute), Level: "error", Message: "failed"},
+			{Timestamp: ts.Add(2 * time.Minute), Level: "info", Message: "retried"},
+			{Timestamp: ts.Add(3 * time.Minute), Level: "error", Message: "failed again"},
+		}
+		for _, sortBy := range []string{"", "newest", "level", "count"} {
+			explorer := newTestExplorer(logs)
+			explorer.sortBy = sortBy
+			explorer.refreshView()
+
+			explorer.jumpToTime(ts.Add(90 * time.Second))
+			assert.Equal(t, 2, explorer.currentIndex(), "sorted by %q", sortBy)
+			explorer.jumpToTime(ts.Add(-time.Minute))
+			assert.Equal(t, 0, explorer.currentIndex(), "sorted by %q", sortBy)
+			explorer.jumpToTime(ts.Add(time.Hour))
+			assert.Equal(t, 3, explorer.currentIndex(), "later than all entries selects the last, sorted by %q", sortBy)
+		}
+	})
+}
+
+func TestLogTableContent(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	explorer := &logExplorer{
+		logs: []LogEntry{
+			{Timestamp: ts, Level: "info", Message: "Server started", Source: "app/server.go:10"},
+			{Timestamp: ts.Add(time.Minute), Level: "error", Message: "Failed [db]", Source: "store/sql.go:12", DuplicateCount: 3},
+		},
+		visible:   []int{1},
+		bookmarks: map[int]string{1: ""},
+	}
+	content := &logTableContent{explorer: explorer}
+
+	assert.Equal(t, 1, content.GetRowCount())
+	assert.Equal(t, "*", content.GetCell(0, columnMark).Text)
+	assert.Equal(t, "error", content.GetCell(0, columnLevel).Text)
+	assert.Equal(t, "10:01:00", content.GetCell(0, columnTime).Text)
+	assert.Equal(t, "Failed [db[] [yellow](×3)[-]", content.GetCell(0, columnMessage).Text)
+	assert.Nil(t, content.GetCell(1, columnMessage))
+
+	explorer.groupBy = "level"
+	explorer.groups = groupLogIndices(explorer.logs, []int{0, 1}, "level")
+	assert.Equal(t, 2, content.GetRowCount())
+	assert.Equal(t, "ERROR", content.GetCell(0, columnMessage).Text)
+	assert.Equal(t, "1 errors", content.GetCell(0, columnTime).Text)
+}
+
+func TestLogSearch(t *testing.T) {
+	search, err := newLogSearch("upload level>=error /s[0-9]")
+	require.NoError(t, err)
+	assert.True(t, search.matches(LogEntry{Level: "error", Message: "Failed to upload to s3"}))
+	assert.False(t, search.matches(LogEntry{Level: "warn", Message: "Failed to upload to s3"}))
+
+	assert.Equal(t, "Failed to [black:yellow]Upload[-:-] to [black:yellow]s3[-:-] [x[]",
+		highlightMatches("Failed to Upload to s3 [x]", search.highlight))
+	assert.Equal(t, "plain [x[]", highlightMatches("plain [x]", nil))
+
+	empty, err := newLogSearch("  ")
+	require.NoError(t, err)
+	assert.False(t, empty.matches(LogEntry{Message: "anything"}))
+
+	fieldsOnly, err := newLogSearch("level=error user=bob")
+	require.NoError(t, err)
+	assert.Nil(t, fieldsOnly.highlight)
+
+	_, err = newLogSearch("/[bad")
+	assert.Error(t, err)
+}
+
+func TestBuildHistogram(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:30.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info"},
+		{Timestamp: ts.Add(10 * time.Second), Level: "error"},
+		{Timestamp: ts.Add(20 * time.Second), Level: "error", DuplicateCount: 2},
+		{Timestamp: ts.Add(2 * time.Minute), Level: "warn"},
+	}
+
+	buckets, size := buildHistogram(logs, []int{0, 1, 2, 3}, 80)
+	assert.Equal(t, time.Minute, size)
+	require.Len(t, buckets, 3)
+	assert.Equal(t, mustParseTime(t, "2025-01-01 10:00:00.000 Z"), buckets[0].Start)
+	assert.Equal(t, 4, buckets[0].Count, "duplicates count once per occurrence")
+	assert.Equal(t, "ERROR", buckets[0].dominantLevel())
+	assert.Equal(t, 0, buckets[1].Count)
+	assert.Equal(t, "", buckets[1].dominantLevel())
+	assert.Equal(t, 1, buckets[2].Levels["WARN"])
+
+	tie := histogramBucket{Levels: map[string]int{"INFO": 2, "WARN": 2}}
+	assert.Equal(t, "WARN", tie.dominantLevel())
+
+	// Long spans are shown in wider buckets
+	assert.Equal(t, 2*time.Minute, histogramBucketSize(150*time.Minute, 80))
+	assert.Equal(t, time.Minute, histogramBucketSize(80*time.Minute, 80))
+	assert.Equal(t, 18*time.Minute, histogramBucketSize(24*time.Hour, 80))
+
+	buckets, _ = buildHistogram(logs, nil, 80)
+	assert.Empty(t, buckets)
+}
+
+func TestFormatLogDetails(t *testing.T) {
+	log := LogEntry{
+		Timestamp:      mustParseTime(t, "2025-01-01 10:00:00.000 Z"),
+		Level:          "error",
+		Message:        "Failed to send push [retry]",
+		Source:         "app/notification.go:42",
+		LogSource:      "notifications",
+		AckID:          "ack123",
+		Status:         "error",
+		DuplicateCount: 4,
+		SourceFile:     "packet/node-2/logs/notifications.log",
+		Line:           7,
+		Node:           "node-2",
+		Extras: map[string]string{
+			"request_id": "abc",
+			"error":      `{"code":500,"detail":"timeout"}`,
+		},
+		Raw: `{"level":"error","msg":"Failed to send push [retry]"}`,
+	}
+
+	details := formatLogDetails(log, nil)
+	assert.Contains(t, details, "[yellow]Source:[-] app/notification.go:42\n")
+	assert.Contains(t, details, "[yellow]Log source:[-] notifications\n")
+	assert.Contains(t, details, "[yellow]Ack ID:[-] ack123\n")
+	assert.Contains(t, details, "[yellow]Status:[-] error\n")
+	assert.Contains(t, details, "[yellow]Occurrences:[-] 4\n")
+	assert.Contains(t, details, "[yellow]File:[-] packet/node-2/logs/notifications.log:7\n")
+	assert.Contains(t, details, "[yellow]Node:[-] node-2\n")
+	assert.Contains(t, details, "Failed to send push [retry[]\n")
+	assert.NotContains(t, details, "User:")
+	assert.Contains(t, details, "  [aqua]error:[-] {\n      \"code\": 500,\n      \"detail\": \"timeout\"\n    }\n  [aqua]request_id:[-] abc\n")
+	assert.Contains(t, details, "[yellow]Raw:[-]\n[gray]{\"level\":\"error\",\"msg\":\"Failed to send push [retry[]\"}[-]\n")
+
+	assert.Equal(t, "not json", prettyExtrasValue("not json"))
+	assert.Equal(t, "{broken", prettyExtrasValue("{broken"))
+}
+
+func TestAppendEntries(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	explorer := newTestExplorer([]LogEntry{
+		{Timestamp: ts, Level: "error", Message: "first error"},
+		{Timestamp: ts.Add(time.Second), Level: "info", Message: "started"},
+		{Timestamp: ts.Add(2 * time.Second), Level: "error", Message: "second error"},
+	})
+	explorer.following = true
+	explorer.applyFilter("level=error")
+	require.Equal(t, []int{0, 2}, explorer.visible)
+
+	// With the last entry selected the selection follows new entries
+	explorer.selectRow(1)
+	explorer.appendEntries([]LogEntry{{Timestamp: ts.Add(3 * time.Second), Level: "error", Message: "third error"}})
+	assert.Equal(t, []int{0, 2, 3}, explorer.visible)
+	assert.Equal(t, 2, explorer.selectedRow())
+	assert.Zero(t, explorer.newErrors)
+
+	// Scrolled up, the selection stays and new errors are counted
+	explorer.selectRow(0)
+	explorer.appendEntries([]LogEntry{
+		{Timestamp: ts.Add(5 * time.Second), Level: "error", Message: "fifth error"},
+		{Timestamp: ts.Add(4 * time.Second), Level: "info", Message: "not matching"},
+	})
+	assert.Equal(t, []int{0, 2, 3, 5}, explorer.visible)
+	assert.Equal(t, "not matching", explorer.logs[4].Message, "new entries are sorted")
+	assert.Equal(t, 0, explorer.selectedRow())
+	assert.Equal(t, 1, explorer.newErrors)
+	assert.Contains(t, explorer.statusBar.GetText(true), "1 NEW ERRORS BELOW")
+
+	// Reaching the end clears the indicator
+	explorer.selectionMoved(3)
+	assert.Zero(t, explorer.newErrors)
+}
+
+// newTestExplorer creates an explorer with its widgets, without running the application
+func newTestExplorer(logs []LogEntry) *logExplorer {
+	explorer := &logExplorer{
+		app:         tview.NewApplication(),
+		pages:       tview.NewPages(),
+		logs:        logs,
+		bookmarks:   make(map[int]string),
+		autoScroll:  true,
+		filters:     &filterStore{},
+		historyPos:  -1,
+		logTable:    tview.NewTable(),
+		details:     tview.NewTextView(),
+		statsPanel:  tview.NewTextView(),
+		filterInput: tview.NewInputField(),
+		statusBar:   tview.NewTextView(),
+	}
+	explorer.logTable.SetContent(&logTableContent{explorer: explorer})
+	explorer.logTable.SetSelectable(true, false)
+	explorer.histogram = newHistogramView(explorer)
+	explorer.applyFilter("")
+	return explorer
+}
+
+func TestHandleKey(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	var logs []LogEntry
+	for i := 0; i < 10; i++ {
+		logs = append(logs, LogEntry{Timestamp: ts.Add(time.Duration(i) * time.Second), Level: "info", Message: fmt.Sprintf("entry %d", i)})
+	}
+	explorer := newTestExplorer(logs)
+	explorer.app.SetFocus(explorer.logTable)
+	explorer.logTable.SetRect(0, 0, 80, 4)
+
+	press := func(key tcell.Key, r rune) *tcell.EventKey {
+		return explorer.handleKey(tcell.NewEventKey(key, r, tcell.ModNone))
+	}
+
+	assert.Nil(t, press(tcell.KeyRune, 'G'))
+	assert.Equal(t, 9, explorer.selectedRow())
+	assert.Nil(t, press(tcell.KeyRune, 'g'))
+	assert.Equal(t, 0, explorer.selectedRow())
+	assert.Nil(t, press(tcell.KeyCtrlD, 0))
+	assert.Equal(t, 2, explorer.selectedRow(), "half of the four table rows")
+	assert.Nil(t, press(tcell.KeyCtrlU, 0))
+	assert.Nil(t, press(tcell.KeyCtrlU, 0))
+	assert.Equal(t, 0, explorer.selectedRow())
+
+	// j/k are left to the table
+	assert.NotNil(t, press(tcell.KeyRune, 'j'))
+
+	// ? toggles the help overlay, which swallows the next key
+	assert.Nil(t, press(tcell.KeyRune, '?'))
+	assert.True(t, explorer.pages.HasPage(helpPage))
+	assert.Nil(t, press(tcell.KeyRune, 'G'))
+	assert.False(t, explorer.pages.HasPage(helpPage))
+	assert.Equal(t, 0, explorer.selectedRow())
+
+	// / focuses the filter, which then receives all keys
+	assert.Nil(t, press(tcell.KeyRune, '/'))
+	assert.True(t, explorer.filterInput.HasFocus())
+	assert.NotNil(t, press(tcell.KeyRune, 'G'))
+}
+
+func TestFormatHelp(t *testing.T) {
+	help := formatHelp()
+	for _, section := range explorerKeyBindings {
+		assert.Contains(t, help, section.section)
+		for _, binding := range section.bindings {
+			assert.Contains(t, help, binding.description)
+		}
+	}
+	assert.Contains(t, help, "[aqua]] / [[-]")
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "error", Source: "app/server.go:10", Message: "failed [x]", Raw: `{"level":"error","msg":"failed [x]"}`},
+		{Timestamp: ts.Add(time.Second), Level: "info", Message: "started"},
+	}
+	explorer := newTestExplorer(logs)
+
+	var copied string
+	clipboardWriteAll = func(text string) error {
+		copied = text
+		return nil
+	}
+	defer func() { clipboardWriteAll = clipboard.WriteAll }()
+
+	explorer.copyDetails()
+	assert.Contains(t, copied, "Level: error")
+	assert.Contains(t, copied, "Message:\nfailed [x]")
+	assert.NotContains(t, copied, "[yellow]")
+	assert.Equal(t, "Copied details to clipboard", explorer.statusBar.GetText(false))
+
+	explorer.copyRawEntry()
+	assert.Equal(t, logs[0].Raw, copied)
+
+	// Entries without an original line are copied as JSON
+	explorer.selectRow(1)
+	explorer.copyRawEntry()
+	var entry LogEntry
+	require.NoError(t, json.Unmarshal([]byte(copied), &entry))
+	assert.Equal(t, "started", entry.Message)
+
+	explorer.copyStats()
+	assert.Contains(t, copied, "Entries: 2 of 2")
+	assert.Contains(t, copied, "Error rate: 50.0%")
+
+	clipboardWriteAll = func(string) error { return fmt.Errorf("no clipboard utility") }
+	explorer.copyStats()
+	assert.Equal(t, "Error copying to clipboard: no clipboard utility", explorer.statusBar.GetText(false))
+}
+
+func TestResolveTheme(t *testing.T) {
+	for _, name := range themeNames() {
+		data, err := json.Marshal(tuiThemes[name])
+		require.NoError(t, err)
+		var colors map[string]string
+		require.NoError(t, json.Unmarshal(data, &colors))
+		for field, color := range colors {
+			assert.True(t, isValidColor(color), "%s theme %s color %q", name, field, color)
+		}
+	}
+
+	resolved, err := resolveTheme("", Config{})
+	require.NoError(t, err)
+	assert.Equal(t, tuiThemes["dark"], resolved)
+
+	// The flag takes precedence over the config theme
+	resolved, err = resolveTheme("mono", Config{Theme: "light"})
+	require.NoError(t, err)
+	assert.Equal(t, tuiThemes["mono"], resolved)
+
+	resolved, err = resolveTheme("", Config{Theme: "light", Colors: map[string]string{"selection": "#c0d8ff", "error": "maroon"}})
+	require.NoError(t, err)
+	assert.Equal(t, "#c0d8ff", resolved.Selection)
+	assert.Equal(t, "maroon", resolved.Error)
+	assert.Equal(t, tuiThemes["light"].Text, resolved.Text)
+
+	_, err = resolveTheme("solarized", Config{})
+	assert.ErrorContains(t, err, `unknown theme "solarized" (available: dark, light, mono)`)
+
+	_, err = resolveTheme("", Config{Colors: map[string]string{"selection": "not-a-color"}})
+	assert.ErrorContains(t, err, `invalid color "not-a-color" for "selection"`)
+
+	_, err = resolveTheme("", Config{Colors: map[string]string{"selected": "red"}})
+	assert.ErrorContains(t, err, "invalid config colors")
+}
+
+func TestSourceLinks(t *testing.T) {
+	file, line, ok := parseCaller("web/handlers.go:187")
+	require.True(t, ok)
+	assert.Equal(t, "web/handlers.go", file)
+	assert.Equal(t, 187, line)
+	for _, caller := range []string{"", "web/handlers.go", "notifications", "web/handlers.go:x", "main:12"} {
+		_, _, ok := parseCaller(caller)
+		assert.False(t, ok, caller)
+	}
+
+	assert.Equal(t, "server/channels/web/handlers.go", serverSourcePath("web/handlers.go", "9.5.1"))
+	assert.Equal(t, "server/channels/store/sqlstore/post_store.go", serverSourcePath("sqlstore/post_store.go", "8.0.0"))
+	assert.Equal(t, "server/public/shared/mlog/mlog.go", serverSourcePath("mlog/mlog.go", "10.1.0"))
+	assert.Equal(t, "server/public/model/post.go", serverSourcePath("model/post.go", "9.5.1"))
+	assert.Equal(t, "store/sqlstore/post_store.go", serverSourcePath("sqlstore/post_store.go", "7.8.0"))
+	assert.Equal(t, "app/platform/web_hub.go", serverSourcePath("platform/web_hub.go", ""))
+
+	assert.Equal(t, "https://github.com/mattermost/mattermost/blob/v9.5.1/server/channels/web/handlers.go#L187",
+		githubPermalink("web/handlers.go", 187, "9.5.1"))
+	assert.Equal(t, "https://github.com/mattermost/mattermost/blob/v7.8.0/app/post.go#L12",
+		githubPermalink("app/post.go", 12, "v7.8.0"))
+	assert.Equal(t, "https://github.com/mattermost/mattermost/blob/master/server/channels/app/post.go#L12",
+		githubPermalink("app/post.go", 12, ""))
+}
+
+func TestFindLocalSource(t *testing.T) {
+	dir := t.TempDir()
+	monorepo := filepath.Join(dir, "server", "channels", "web", "handlers.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(monorepo), 0o755))
+	require.NoError(t, os.WriteFile(monorepo, nil, 0o644))
+
+	local, ok := findLocalSource(dir, "web/handlers.go")
+	require.True(t, ok)
+	assert.Equal(t, monorepo, local)
+
+	// The server directory of the checkout works too
+	local, ok = findLocalSource(filepath.Join(dir, "server"), "web/handlers.go")
+	require.True(t, ok)
+	assert.Equal(t, monorepo, local)
+
+	_, ok = findLocalSource(dir, "app/post.go")
+	assert.False(t, ok)
+}
+
+func TestEditorCommand(t *testing.T) {
+	cmd, err := editorCommand("vim", "/src/app/post.go", 12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vim", "+12", "/src/app/post.go"}, cmd.Args)
+
+	cmd, err = editorCommand("code --wait", "/src/app/post.go", 12)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code", "--wait", "--goto", "/src/app/post.go:12"}, cmd.Args)
+
+	_, err = editorCommand(" ", "/src/app/post.go", 12)
+	assert.Error(t, err)
+}
+
+func TestSortLogIndices(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "a", DuplicateCount: 3},
+		{Timestamp: ts.Add(time.Second), Level: "error", Message: "b"},
+		{Timestamp: ts.Add(2 * time.Second), Level: "warn", Message: "c", DuplicateCount: 5},
+		{Timestamp: ts.Add(3 * time.Second), Level: "error", Message: "d", DuplicateCount: 3},
+	}
+	indexes := []int{0, 1, 2, 3}
+
+	assert.Equal(t, []int{0, 1, 2, 3}, sortLogIndices(logs, indexes, ""))
+	assert.Equal(t, []int{3, 2, 1, 0}, sortLogIndices(logs, indexes, "newest"))
+	assert.Equal(t, []int{1, 3, 2, 0}, sortLogIndices(logs, indexes, "level"))
+	assert.Equal(t, []int{2, 0, 3, 1}, sortLogIndices(logs, indexes, "count"))
+	assert.Equal(t, []int{0, 1, 2, 3}, indexes, "input is not modified")
+}
+
+func TestCycleSort(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	explorer := newTestExplorer([]LogEntry{
+		{Timestamp: ts, Level: "info", Message: "started"},
+		{Timestamp: ts.Add(time.Second), Level: "error", Message: "failed"},
+		{Timestamp: ts.Add(2 * time.Second), Level: "debug", Message: "details"},
+	})
+	explorer.following = true
+	explorer.selectRow(1)
+
+	// The selected entry stays selected when the order changes
+	explorer.cycleSort()
+	assert.Equal(t, "newest", explorer.sortBy)
+	assert.Equal(t, []int{2, 1, 0}, explorer.visible)
+	assert.Equal(t, 1, explorer.currentIndex())
+	assert.Contains(t, explorer.statusBar.GetText(true), "Sorted by newest first")
+
+	// New entries are sorted in without moving the selection
+	explorer.appendEntries([]LogEntry{{Timestamp: ts.Add(3 * time.Second), Level: "error", Message: "failed again"}})
+	assert.Equal(t, []int{3, 2, 1, 0}, explorer.visible)
+	assert.Equal(t, 1, explorer.currentIndex())
+	assert.Zero(t, explorer.newErrors)
+
+	explorer.cycleSort()
+	assert.Equal(t, []int{1, 3, 0, 2}, explorer.visible)
+	explorer.cycleSort()
+	explorer.cycleSort()
+	assert.Empty(t, explorer.sortBy)
+	assert.Equal(t, []int{0, 1, 2, 3}, explorer.visible)
+	assert.NotContains(t, explorer.statusBar.GetText(true), "Sorted by")
+}
+
+func TestFilterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lamp", filterStoreFileName)
+
+	store, err := loadFilterStore(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.History)
+
+	store.addHistory("level=error")
+	store.addHistory("  ")
+	store.addHistory("user=alice")
+	store.addHistory("level=error")
+	assert.Equal(t, []string{"user=alice", "level=error"}, store.History)
+
+	store.saveFilter("errors", "level>=error")
+	store.saveFilter("push", "/push")
+	store.saveFilter("errors", "level>=warn")
+	assert.Equal(t, []SavedFilter{{Name: "errors", Filter: "level>=warn"}, {Name: "push", Filter: "/push"}}, store.Saved)
+	require.NoError(t, store.save())
+
+	loaded, err := loadFilterStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, store.History, loaded.History)
+	assert.Equal(t, store.Saved, loaded.Saved)
+
+	for i := 0; i < maxFilterHistory+5; i++ {
+		loaded.addHistory(fmt.Sprintf("filter %d", i))
+	}
+	assert.Len(t, loaded.History, maxFilterHistory)
+	assert.Equal(t, fmt.Sprintf("filter %d", maxFilterHistory+4), loaded.History[maxFilterHistory-1])
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err = loadFilterStore(path)
+	assert.ErrorContains(t, err, "invalid filter file")
+}
+
+func TestFilterHistoryAndSavedFilters(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	explorer := newTestExplorer([]LogEntry{
+		{Timestamp: ts, Level: "info", Message: "started", User: "alice"},
+		{Timestamp: ts.Add(time.Second), Level: "error", Message: "failed", User: "bob"},
+	})
+	explorer.rememberFilter("level=error")
+	explorer.rememberFilter("user=alice")
+
+	// The input field only replaces its text properly once it has been laid out
+	screen := tcell.NewSimulationScreen("")
+	require.NoError(t, screen.Init())
+	explorer.filterInput.SetRect(0, 0, 80, 1)
+	explorer.filterInput.Draw(screen)
+
+	up := tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+	down := tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+	explorer.filterInput.SetText("draft")
+	assert.Nil(t, explorer.handleFilterHistoryKey(up))
+	assert.Equal(t, "user=alice", explorer.filterInput.GetText())
+	explorer.handleFilterHistoryKey(up)
+	explorer.handleFilterHistoryKey(up)
+	assert.Equal(t, "level=error", explorer.filterInput.GetText(), "the oldest filter stays selected")
+	explorer.handleFilterHistoryKey(down)
+	assert.Equal(t, "user=alice", explorer.filterInput.GetText())
+	explorer.handleFilterHistoryKey(down)
+	assert.Equal(t, "draft", explorer.filterInput.GetText(), "the typed text is restored")
+
+	explorer.filters.saveFilter("errors", "level=error")
+	explorer.applySavedFilter(1)
+	assert.Equal(t, "level=error", explorer.filterInput.GetText())
+	assert.Equal(t, []int{1}, explorer.visible)
+
+	explorer.applySavedFilter(2)
+	assert.Equal(t, "No saved filter 2 (press w to save the current filter)", explorer.statusBar.GetText(true))
+}
+
+func TestSessionSaveAndRestore(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "started", Source: "app/server.go:10"},
+		{Timestamp: ts.Add(time.Second), Level: "error", Message: "push failed", Source: "app/notification.go:42"},
+		{Timestamp: ts.Add(2 * time.Second), Level: "error", Message: "push failed again", Source: "app/notification.go:42"},
+		{Timestamp: ts.Add(3 * time.Second), Level: "warn", Message: "slow query", Source: "sqlstore/post_store.go:99"},
+	}
+
+	explorer := newTestExplorer(logs)
+	explorer.source = &SessionSource{Command: "file", Paths: []string{"/var/log/mattermost.log"}, Level: "error"}
+	explorer.bookmarks[1] = "first failure"
+	explorer.bookmarks[3] = ""
+	explorer.useFilter("level>=warn")
+	explorer.search, _ = newLogSearch("push")
+	explorer.cycleSort()
+	explorer.selectEntry(2)
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(t, saveSession(explorer.captureSession(), path))
+	session, err := loadSession(path)
+	require.NoError(t, err)
+	assert.Equal(t, "file", session.Source.Command)
+	assert.Equal(t, "error", session.Source.Level)
+	assert.Equal(t, "level>=warn", session.Filter)
+	assert.Equal(t, "newest", session.SortBy)
+	assert.Len(t, session.Bookmarks, 2)
+
+	// The entries are found again in a reloaded (here, reordered) log
+	reloaded := []LogEntry{logs[0], logs[1], {Timestamp: ts.Add(1500 * time.Millisecond), Level: "debug", Message: "new"}, logs[2], logs[3]}
+	restored := newTestExplorer(reloaded)
+	restored.restoreSession(session)
+	assert.Equal(t, "level>=warn", restored.filter)
+	assert.Equal(t, "level>=warn", restored.filterInput.GetText())
+	assert.Equal(t, "push", restored.search.expr)
+	assert.Equal(t, "newest", restored.sortBy)
+	assert.Equal(t, map[int]string{1: "first failure", 4: ""}, restored.bookmarks)
+	assert.Equal(t, 3, restored.currentIndex())
+}
+
+func TestLoadSessionErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+		return path
+	}
+
+	_, err := loadSession(write("broken.json", "{"))
+	assert.ErrorContains(t, err, "invalid session file")
+
+	_, err = loadSession(write("future.json", `{"version": 99}`))
+	assert.ErrorContains(t, err, "unsupported session version 99")
+
+	_, err = loadSession(write("no-source.json", `{"version": 1}`))
+	assert.ErrorContains(t, err, "does not record the loaded files")
+}
+
+func TestAIFindings(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	var logs []LogEntry
+	for i := 0; i < 6; i++ {
+		level := "info"
+		if i%2 == 1 {
+			level = "error"
+		}
+		logs = append(logs, LogEntry{Timestamp: ts.Add(time.Duration(i) * time.Second), Level: level, Message: fmt.Sprintf("entry %d", i)})
+	}
+
+	// The last 4 entries were analyzed: evidence 2 and 4 are entries 3 and 5, 9 is out of range
+	text := "Report\n```json\n" +
+		`{"findings": [{"title": "Errors", "severity": "error", "summary": "Failures.", "evidence": [2, 4, 9]}, {"title": "None", "severity": "info", "evidence": []}]}` +
+		"\n```"
+	analysis := newAIAnalysis(text, logsForAnalysis(logs, 4))
+	assert.Equal(t, "Report", analysis.report)
+	require.Len(t, analysis.evidence, 2)
+	assert.Len(t, analysis.evidence[0], 2)
+
+	explorer := newTestExplorer(logs)
+	explorer.findingsPanel = explorer.newFindingsPanel()
+	explorer.body = tview.NewFlex().AddItem(explorer.logTable, 0, 1, true)
+	explorer.loadAnalysis(analysis)
+	assert.Equal(t, [][]int{{3, 5}, nil}, explorer.findingEvidence)
+	assert.Equal(t, 3, explorer.findingsPanel.GetItemCount())
+
+	explorer.showFinding(0)
+	assert.Contains(t, explorer.details.GetText(true), "entry 5")
+	explorer.showFinding(-1)
+	assert.Equal(t, "Report", explorer.details.GetText(true))
+
+	// Selecting a finding jumps to its first cited entry, e / E step through the others
+	explorer.selectFinding(0)
+	assert.Equal(t, 3, explorer.currentIndex())
+	explorer.jumpToEvidence(1)
+	assert.Equal(t, 5, explorer.currentIndex())
+	explorer.jumpToEvidence(1)
+	assert.Equal(t, 3, explorer.currentIndex())
+	explorer.jumpToEvidence(-1)
+	assert.Equal(t, 5, explorer.currentIndex())
+	assert.Equal(t, "!", explorer.logTable.GetCell(5, columnMark).Text)
+
+	// Cited entries hidden by the filter are reported
+	explorer.applyFilter("level=info")
+	explorer.selectFinding(0)
+	assert.Contains(t, explorer.statusBar.GetText(true), "hidden by the filter")
+
+	explorer.selectFinding(1)
+	assert.Contains(t, explorer.statusBar.GetText(true), "cites no loaded entries")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// tuiTheme holds the colors of the interactive mode as tview color names or #rrggbb values.
+// The JSON names are the keys of the "colors" section of the config file.
+type tuiTheme struct {
+	Background    string `json:"background"`
+	Text          string `json:"text"`
+	Border        string `json:"border"`
+	Field         string `json:"field"`          // Background of the filter and prompt inputs
+	Header        string `json:"header"`         // Title line
+	Label         string `json:"label"`          // Field labels, status bar, histogram label
+	Key           string `json:"key"`            // Extras keys and key bindings
+	Muted         string `json:"muted"`          // Source column and raw line
+	Selection     string `json:"selection"`      // Background of the selected row
+	SelectionText string `json:"selection_text"` // Text of the selected row
+	Highlight     string `json:"highlight"`      // Background of search matches
+	HighlightText string `json:"highlight_text"` // Text of search matches
+	Bookmark      string `json:"bookmark"`
+	Error         string `json:"error"`
+	Warn          string `json:"warn"`
+	Info          string `json:"info"`
+	Debug         string `json:"debug"`
+}
+
+// tuiThemes lists the built-in themes selectable with --theme
+var tuiThemes = map[string]tuiTheme{
+	"dark": {
+		Background: "black", Text: "white", Border: "white", Field: "blue",
+		Header: "aqua", Label: "yellow", Key: "aqua", Muted: "gray",
+		Selection: "darkblue", SelectionText: "white", Highlight: "yellow", HighlightText: "black",
+		Bookmark: "fuchsia", Error: "red", Warn: "yellow", Info: "green", Debug: "blue",
+	},
+	"light": {
+		Background: "white", Text: "black", Border: "black", Field: "lightgray",
+		Header: "navy", Label: "darkblue", Key: "teal", Muted: "dimgray",
+		Selection: "lightskyblue", SelectionText: "black", Highlight: "gold", HighlightText: "black",
+		Bookmark: "purple", Error: "red", Warn: "darkorange", Info: "green", Debug: "blue",
+	},
+	"mono": {
+		Background: "black", Text: "white", Border: "white", Field: "gray",
+		Header: "white", Label: "white", Key: "white", Muted: "silver",
+		Selection: "white", SelectionText: "black", Highlight: "silver", HighlightText: "black",
+		Bookmark: "white", Error: "white", Warn: "white", Info: "white", Debug: "white",
+	},
+}
+
+// defaultThemeName is the theme used when neither --theme nor the config file select one
+const defaultThemeName = "dark"
+
+// theme is the active theme of the interactive mode
+var theme = tuiThemes[defaultThemeName]
+
+// themeNames returns the names of the built-in themes, sorted
+func themeNames() []string {
+	names := make([]string, 0, len(tuiThemes))
+	for name := range tuiThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveTheme returns the built-in theme called name (the config theme, or dark, if empty)
+// with the color overrides of the config applied
+func resolveTheme(name string, config Config) (tuiTheme, error) {
+	if name == "" {
+		name = config.Theme
+	}
+	if name == "" {
+		name = defaultThemeName
+	}
+	resolved, ok := tuiThemes[name]
+	if !ok {
+		return tuiTheme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(themeNames(), ", "))
+	}
+	if len(config.Colors) == 0 {
+		return resolved, nil
+	}
+
+	for key, color := range config.Colors {
+		if !isValidColor(color) {
+			return tuiTheme{}, fmt.Errorf("invalid color %q for %q in config colors", color, key)
+		}
+	}
+	data, err := json.Marshal(config.Colors)
+	if err != nil {
+		return tuiTheme{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&resolved); err != nil {
+		return tuiTheme{}, fmt.Errorf("invalid config colors: %v", err)
+	}
+	return resolved, nil
+}
+
+// isValidColor reports whether tview understands a color name or #rrggbb value
+func isValidColor(color string) bool {
+	return strings.EqualFold(color, "default") || tcell.GetColor(color) != tcell.ColorDefault
+}
+
+// color returns the tcell color of a theme color name
+func (t tuiTheme) color(name string) tcell.Color {
+	return tcell.GetColor(name)
+}
+
+// apply sets the tview default styles, which widgets read when they are created
+func (t tuiTheme) apply() {
+	tview.Styles.PrimitiveBackgroundColor = t.color(t.Background)
+	tview.Styles.ContrastBackgroundColor = t.color(t.Field)
+	tview.Styles.MoreContrastBackgroundColor = t.color(t.Selection)
+	tview.Styles.BorderColor = t.color(t.Border)
+	tview.Styles.TitleColor = t.color(t.Text)
+	tview.Styles.GraphicsColor = t.color(t.Border)
+	tview.Styles.PrimaryTextColor = t.color(t.Text)
+	tview.Styles.SecondaryTextColor = t.color(t.Label)
+	tview.Styles.TertiaryTextColor = t.color(t.Muted)
+	tview.Styles.InverseTextColor = t.color(t.SelectionText)
+	tview.Styles.ContrastSecondaryTextColor = t.color(t.Muted)
+}
+
+// colorText wraps text in a color tag, resetting to the default text color after it
+func colorText(color, text string) string {
+	return fmt.Sprintf("[%s]%s[-]", color, text)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// jiraTimeout bounds each request made to Jira
+	jiraTimeout = 30 * time.Second
+	// jiraMaxDescription is the longest description Jira accepts
+	jiraMaxDescription = 32767
+	// jiraFindingsFile and jiraAnalysisFile are the names of the files attached to tickets
+	jiraFindingsFile = "lamp-findings.sarif.json"
+	jiraAnalysisFile = "lamp-analysis.md"
+)
+
+// jiraSettings are the Jira server, project and credentials of --create-jira
+type jiraSettings struct {
+	URL       string
+	Project   string
+	IssueType string
+	User      string // Account email for Jira Cloud API tokens; empty for personal access tokens
+	Token     string
+}
+
+// validate checks that the settings needed to file a ticket are set
+func (s jiraSettings) validate() error {
+	missing := []string{}
+	if s.URL == "" {
+		missing = append(missing, "--jira-url")
+	}
+	if s.Project == "" {
+		missing = append(missing, "--jira-project")
+	}
+	if s.Token == "" {
+		missing = append(missing, "--jira-token")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("--create-jira requires %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// jiraClient files tickets with the Jira REST API (version 2, supported by Jira Cloud,
+// Server and Data Center)
+type jiraClient struct {
+	client   *http.Client
+	settings jiraSettings
+}
+
+// newJiraClient returns a client for the Jira server of the settings
+func newJiraClient(settings jiraSettings) *jiraClient {
+	settings.URL = strings.TrimSuffix(settings.URL, "/")
+	return &jiraClient{client: newHTTPClient(jiraTimeout), settings: settings}
+}
+
+// do sends an authenticated request and decodes the JSON answer into result, if not nil.
+// Jira Cloud authenticates an account email with an API token, Server and Data Center a
+// personal access token.
+func (j *jiraClient) do(req *http.Request, result any) error {
+	if j.settings.User != "" {
+		req.SetBasicAuth(j.settings.User, j.settings.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.settings.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Jira answered with %s%s", resp.Status, jiraErrorDetail(body))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+// jiraErrorDetail returns the error messages of a Jira error answer, if any
+func jiraErrorDetail(body []byte) string {
+	var answer struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if json.Unmarshal(body, &answer) != nil {
+		return ""
+	}
+	messages := answer.ErrorMessages
+	for field, message := range answer.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, message))
+	}
+	if len(messages) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(messages, "; ")
+}
+
+// createIssue creates an issue and returns its key, e.g. SUP-123
+func (j *jiraClient) createIssue(summary, description string) (string, error) {
+	payload := map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": j.settings.Project},
+			"issuetype":   map[string]string{"name": j.settings.IssueType},
+			"summary":     summary,
+			"description": description,
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", j.settings.URL+"/rest/api/2/issue", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(req, &created); err != nil {
+		return "", err
+	}
+	return created.Key, nil
+}
+
+// attach attaches a file to an issue
+func (j *jiraClient) attach(key, name string, data []byte) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", j.settings.URL+"/rest/api/2/issue/"+key+"/attachments", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Atlassian-Token", "no-check")
+	return j.do(req, nil)
+}
+
+// issueURL returns the address of an issue in the browser
+func (j *jiraClient) issueURL(key string) string {
+	return j.settings.URL + "/browse/" + key
+}
+
+// createJiraTicket files a ticket with the analysis of a report and attaches its findings.
+// An analysis too long for the description is attached in full.
+func createJiraTicket(settings jiraSettings, report runReport, out io.Writer) error {
+	client := newJiraClient(settings)
+
+	description := markdownToJira(report.Markdown)
+	truncated := len(description) > jiraMaxDescription
+	if truncated {
+		note := fmt.Sprintf("\n\n_(truncated, see %s)_", jiraAnalysisFile)
+		description = strings.ToValidUTF8(description[:jiraMaxDescription-len(note)], "") + note
+	}
+
+	key, err := client.createIssue(report.Title, description)
+	if err != nil {
+		return fmt.Errorf("error creating the Jira issue: %v", err)
+	}
+
+	var findings bytes.Buffer
+	if err := writeFindings(report.Findings, &findings); err != nil {
+		return err
+	}
+	if err := client.attach(key, jiraFindingsFile, findings.Bytes()); err != nil {
+		return fmt.Errorf("created %s, but failed to attach the findings: %v", key, err)
+	}
+	if truncated {
+		if err := client.attach(key, jiraAnalysisFile, []byte(report.Markdown)); err != nil {
+			return fmt.Errorf("created %s, but failed to attach the analysis: %v", key, err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "Created Jira issue %s: %s\n", key, client.issueURL(key))
+	return nil
+}
+
+var (
+	markdownHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	markdownBullet     = regexp.MustCompile(`^(\s*)[-*]\s+(.*)$`)
+	markdownBold       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	markdownInlineCode = regexp.MustCompile("`([^`]+)`")
+)
+
+// markdownToJira converts the markdown of an analysis to Jira wiki markup: headings, lists,
+// bold text and code. Other markdown is left as is.
+func markdownToJira(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			lines[i] = "{noformat}"
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			line = fmt.Sprintf("h%d. %s", len(m[1]), m[2])
+		} else if m := markdownBullet.FindStringSubmatch(line); m != nil {
+			line = strings.Repeat("*", len(m[1])/2+1) + " " + m[2]
+		}
+		line = markdownBold.ReplaceAllString(line, "*$1*")
+		lines[i] = markdownInlineCode.ReplaceAllString(line, "{{$1}}")
+	}
+	return strings.Join(lines, "\n")
+}
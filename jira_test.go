@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateJiraTicket(t *testing.T) {
+	// The handler records the requests and their errors, checked once the calls return, as
+	// require stops the test goroutine only
+	var issue map[string]map[string]any
+	var decodeErr, formErr error
+	var atlassianTokens []string
+	attachments := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, ok := r.BasicAuth()
+		if !ok || user != "me@example.com" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rest/api/2/issue":
+			decodeErr = json.NewDecoder(r.Body).Decode(&issue)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "10001", "key": "SUP-42"}`))
+		case "/rest/api/2/issue/SUP-42/attachments":
+			atlassianTokens = append(atlassianTokens, r.Header.Get("X-Atlassian-Token"))
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				formErr = err
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			attachments[header.Filename] = string(data)
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	settings := jiraSettings{URL: server.URL + "/", Project: "SUP", IssueType: "Bug", User: "me@example.com", Token: "secret"}
+	report := runReport{
+		Title:    "Log analysis of mattermost.log",
+		Markdown: "## Summary\n\n- **Database** timeouts in `sqlstore`\n\n```\nLEVELS: ERROR:3\n```\n",
+		Findings: []Finding{{RuleID: findingPanic, Severity: "error", Title: "Panic", Message: "panic: boom", Count: 1}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, createJiraTicket(settings, report, &out))
+	assert.Equal(t, "Created Jira issue SUP-42: "+server.URL+"/browse/SUP-42\n", out.String())
+	require.NoError(t, decodeErr)
+	require.NoError(t, formErr)
+	assert.Equal(t, []string{"no-check"}, atlassianTokens)
+
+	fields := issue["fields"]
+	assert.Equal(t, map[string]any{"key": "SUP"}, fields["project"])
+	assert.Equal(t, map[string]any{"name": "Bug"}, fields["issuetype"])
+	assert.Equal(t, "Log analysis of mattermost.log", fields["summary"])
+	assert.Equal(t, "h2. Summary\n\n* *Database* timeouts in {{sqlstore}}\n\n{noformat}\nLEVELS: ERROR:3\n{noformat}\n", fields["description"])
+
+	require.Contains(t, attachments, jiraFindingsFile)
+	assert.Contains(t, attachments[jiraFindingsFile], `"ruleId": "lamp/panic"`)
+	assert.NotContains(t, attachments, jiraAnalysisFile, "the analysis is only attached when truncated")
+
+	// A long analysis is truncated in the description and attached in full
+	report.Markdown = strings.Repeat("x", jiraMaxDescription+10)
+	require.NoError(t, createJiraTicket(settings, report, io.Discard))
+	require.NoError(t, decodeErr)
+	require.NoError(t, formErr)
+	assert.LessOrEqual(t, len(issue["fields"]["description"].(string)), jiraMaxDescription)
+	assert.Equal(t, report.Markdown, attachments[jiraAnalysisFile])
+
+	// Jira errors are reported with their messages
+	settings.Token = "wrong"
+	err := createJiraTicket(settings, report, io.Discard)
+	assert.ErrorContains(t, err, "error creating the Jira issue: Jira answered with 401 Unauthorized")
+}
+
+func TestJiraErrorDetail(t *testing.T) {
+	assert.Equal(t, ": project: project is required", jiraErrorDetail([]byte(`{"errorMessages": [], "errors": {"project": "project is required"}}`)))
+	assert.Equal(t, ": Issue does not exist", jiraErrorDetail([]byte(`{"errorMessages": ["Issue does not exist"]}`)))
+	assert.Equal(t, "", jiraErrorDetail([]byte(`<html>`)))
+}
+
+func TestJiraSettingsValidate(t *testing.T) {
+	assert.EqualError(t, jiraSettings{}.validate(), "--create-jira requires --jira-url, --jira-project, --jira-token")
+	assert.NoError(t, jiraSettings{URL: "https://example.atlassian.net", Project: "SUP", Token: "t"}.validate())
+}
@@ -0,0 +1,17 @@
+package main
+
+import "errors"
+
+// keychainService is the service API keys are stored under in the system keychain, with the
+// LLM provider as the account
+const keychainService = "lamp"
+
+// errKeychainNotFound is returned when the keychain holds no key for an account
+var errKeychainNotFound = errors.New("no key found in the keychain")
+
+// Access to the system keychain, replaced in tests
+var (
+	keychainGet    = systemKeychainGet
+	keychainSet    = systemKeychainSet
+	keychainDelete = systemKeychainDelete
+)
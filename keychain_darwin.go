@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound is the exit code of the security tool when no item matches
+const securityItemNotFound = 44
+
+// systemKeychainGet reads a key from the macOS Keychain
+func systemKeychainGet(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// systemKeychainSet adds or updates a key in the macOS Keychain. The command is passed on
+// the standard input of the security tool so the key does not show in the process list.
+func systemKeychainSet(account, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(keychainService), securityQuote(account), securityQuote(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// systemKeychainDelete removes a key from the macOS Keychain
+func systemKeychainDelete(account string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", account).Run(); err != nil {
+		return securityError(err)
+	}
+	return nil
+}
+
+// securityError maps the exit code of the security tool for a missing item to errKeychainNotFound
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return errKeychainNotFound
+	}
+	return fmt.Errorf("security: %v", err)
+}
+
+// securityQuote quotes an argument of an interactive security command
+func securityQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
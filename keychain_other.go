@@ -0,0 +1,63 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemKeychainGet reads a key from the Secret Service (GNOME Keyring, KWallet) with the
+// secret-tool command of libsecret
+func systemKeychainGet(account string) (string, error) {
+	out, err := secretTool(nil, "lookup", "service", keychainService, "account", account)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", errKeychainNotFound
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// systemKeychainSet stores a key in the Secret Service, passing it on the standard input
+func systemKeychainSet(account, secret string) error {
+	_, err := secretTool(strings.NewReader(secret), "store", "--label", fmt.Sprintf("lamp %s API key", account),
+		"service", keychainService, "account", account)
+	return err
+}
+
+// systemKeychainDelete removes a key from the Secret Service
+func systemKeychainDelete(account string) error {
+	if _, err := systemKeychainGet(account); err != nil {
+		return err
+	}
+	_, err := secretTool(nil, "clear", "service", keychainService, "account", account)
+	return err
+}
+
+// secretTool runs secret-tool and returns its output. A failure without an error message,
+// which is how lookup reports a missing key, returns no error.
+func secretTool(stdin *strings.Reader, args ...string) (string, error) {
+	cmd := exec.Command("secret-tool", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("secret-tool not found: install libsecret (e.g. the libsecret-tools package)")
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if message := strings.TrimSpace(string(exitErr.Stderr)); message != "" {
+			return "", fmt.Errorf("secret-tool: %s", message)
+		}
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret-tool: %v", err)
+	}
+	return string(out), nil
+}
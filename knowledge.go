@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxDocPassages is the number of documentation passages added to an analysis prompt
+const maxDocPassages = 3
+
+// Pages of the Mattermost configuration settings reference
+const (
+	docsEnvironmentURL    = "https://docs.mattermost.com/configure/environment-configuration-settings.html"
+	docsSiteURL           = "https://docs.mattermost.com/configure/site-configuration-settings.html"
+	docsAuthenticationURL = "https://docs.mattermost.com/configure/authentication-configuration-settings.html"
+	docsPluginsURL        = "https://docs.mattermost.com/configure/plugins-configuration-settings.html"
+)
+
+// docPassage is a passage of the Mattermost documentation, retrieved for the analyses of logs
+// containing its keywords
+type docPassage struct {
+	Title    string   `json:"title"`
+	URL      string   `json:"url"`
+	Settings []string `json:"settings,omitempty"` // Documented config.json settings, e.g. SqlSettings.MaxOpenConns
+	Text     string   `json:"text"`
+	Keywords []string `json:"keywords"` // Words or phrases of the log messages the passage is relevant to
+}
+
+// mattermostDocs is the knowledge base shipped with lamp: the configuration settings behind
+// the most common problems found in server logs
+var mattermostDocs = []docPassage{
+	{
+		Title:    "Database connection pool",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"SqlSettings.MaxOpenConns", "SqlSettings.MaxIdleConns"},
+		Text:     "MaxOpenConns is the maximum number of connections the server holds open to the database, 300 by default. It must stay below the max_connections of the database divided by the number of app servers. MaxIdleConns is the number of idle connections kept open.",
+		Keywords: []string{"too many connections", "connection pool", "max_connections", "too many clients", "database is closed", "remaining connection slots"},
+	},
+	{
+		Title:    "Database connection string",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"SqlSettings.DriverName", "SqlSettings.DataSource", "SqlSettings.DataSourceReplicas"},
+		Text:     "DataSource is the connection string of the master database, and DataSourceReplicas those of the read replicas. Connection failures usually come from the host, port, credentials or SSL mode of the connection string, or from the database being unreachable from the app server.",
+		Keywords: []string{"failed to ping db", "password authentication failed", "connection refused", "no such host", "sslmode", "pq:", "mysql", "postgres", "database"},
+	},
+	{
+		Title:    "Database query timeout",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"SqlSettings.QueryTimeout"},
+		Text:     "QueryTimeout is the number of seconds to wait for a response from the database after opening a connection and sending the query, 30 by default. Queries that time out repeatedly point to missing indexes, a busy database or long-running migrations rather than to a timeout that is too short.",
+		Keywords: []string{"context deadline exceeded", "statement timeout", "query timeout", "canceling statement", "slow query", "i/o timeout"},
+	},
+	{
+		Title:    "Maximum file size",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"FileSettings.MaxFileSize"},
+		Text:     "MaxFileSize is the maximum size of message attachments in bytes, 104857600 (100 MB) by default. A reverse proxy in front of Mattermost enforces its own limit, such as client_max_body_size in NGINX, which must be at least as large.",
+		Keywords: []string{"file too large", "too large", "request entity too large", "413", "maxfilesize", "upload", "attachment", "content length"},
+	},
+	{
+		Title:    "File storage",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"FileSettings.DriverName", "FileSettings.Directory", "FileSettings.AmazonS3Bucket", "FileSettings.AmazonS3Endpoint", "FileSettings.AmazonS3Region", "FileSettings.AmazonS3SSL"},
+		Text:     "DriverName selects local storage (local) in Directory, or Amazon S3 compatible storage (amazons3) in AmazonS3Bucket at AmazonS3Endpoint. Failures to read or write files come from the permissions of the directory for the user running Mattermost, or from the bucket, region, credentials and SSL settings of S3.",
+		Keywords: []string{"amazons3", "s3", "nosuchbucket", "access denied", "signaturedoesnotmatch", "unable to write file", "unable to read file", "filesettings", "permission denied", "minio"},
+	},
+	{
+		Title:    "Push notifications",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"EmailSettings.SendPushNotifications", "EmailSettings.PushNotificationServer", "EmailSettings.PushNotificationContents"},
+		Text:     "SendPushNotifications turns push notifications to mobile devices on, and PushNotificationServer is the push proxy they are sent through, such as the Mattermost hosted push notification service. The app servers must reach the push proxy over HTTPS.",
+		Keywords: []string{"push notification", "push proxy", "push-proxy", "hpns", "tpns", "device", "apns", "fcm"},
+	},
+	{
+		Title:    "SMTP",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"EmailSettings.SMTPServer", "EmailSettings.SMTPPort", "EmailSettings.ConnectionSecurity", "EmailSettings.EnableSMTPAuth", "EmailSettings.SkipServerCertificateVerification"},
+		Text:     "SMTPServer and SMTPPort locate the mail server used for email notifications and invitations. ConnectionSecurity is empty, TLS or STARTTLS and must match what the port expects. EnableSMTPAuth sends SMTPUsername and SMTPPassword.",
+		Keywords: []string{"smtp", "failed to send mail", "mail", "email", "starttls", "535", "authentication failed"},
+	},
+	{
+		Title:    "Site URL",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ServiceSettings.SiteURL", "ServiceSettings.AllowCorsFrom"},
+		Text:     "SiteURL is the URL users reach Mattermost at, including the protocol and any subpath. It is required by email notifications, authentication redirects and WebSocket origin checks. AllowCorsFrom lists the other origins allowed to make cross-origin requests.",
+		Keywords: []string{"siteurl", "site url", "origin", "cors", "redirect_uri", "invalid redirect", "subpath"},
+	},
+	{
+		Title:    "WebSocket connections behind a proxy",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ServiceSettings.SiteURL"},
+		Text:     "Clients receive events over a WebSocket on the SiteURL. A reverse proxy or load balancer must forward the Upgrade and Connection headers and keep idle connections open, otherwise clients reconnect continually and miss real-time updates.",
+		Keywords: []string{"websocket", "web socket", "upgrade", "1006", "connection reset", "unexpected eof", "reconnect"},
+	},
+	{
+		Title:    "Login attempts",
+		URL:      docsAuthenticationURL,
+		Settings: []string{"ServiceSettings.MaximumLoginAttempts"},
+		Text:     "MaximumLoginAttempts is the number of failed sign-in attempts after which a user is locked out until a password reset, 10 by default.",
+		Keywords: []string{"login", "maximum login attempts", "invalid credentials", "locked", "too many login attempts", "sign in"},
+	},
+	{
+		Title:    "Session lengths",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ServiceSettings.SessionLengthWebInHours", "ServiceSettings.SessionLengthMobileInHours", "ServiceSettings.SessionLengthSSOInHours", "ServiceSettings.SessionIdleTimeoutInMinutes"},
+		Text:     "SessionLengthWebInHours, SessionLengthMobileInHours and SessionLengthSSOInHours set how long sessions last before users must sign in again, 720 hours (30 days) by default for web sessions. SessionIdleTimeoutInMinutes ends inactive web sessions.",
+		Keywords: []string{"session expired", "invalid session", "session", "token", "expired"},
+	},
+	{
+		Title:    "AD/LDAP",
+		URL:      docsAuthenticationURL,
+		Settings: []string{"LdapSettings.LdapServer", "LdapSettings.LdapPort", "LdapSettings.ConnectionSecurity", "LdapSettings.BindUsername", "LdapSettings.SyncIntervalMinutes", "LdapSettings.QueryTimeout"},
+		Text:     "LdapServer, LdapPort and ConnectionSecurity locate the directory, and BindUsername and BindPassword are the account used to search it. SyncIntervalMinutes is how often users are synchronized, 60 by default. QueryTimeout bounds directory queries in seconds.",
+		Keywords: []string{"ldap", "ad/ldap", "active directory", "bind", "ldap result code", "sync"},
+	},
+	{
+		Title:    "SAML single sign-on",
+		URL:      docsAuthenticationURL,
+		Settings: []string{"SamlSettings.IdpURL", "SamlSettings.IdpDescriptorURL", "SamlSettings.IdpCertificateFile", "SamlSettings.Verify", "SamlSettings.AssertionConsumerServiceURL"},
+		Text:     "IdpURL and IdpDescriptorURL are the endpoints of the identity provider, and IdpCertificateFile its public certificate used to verify assertions. AssertionConsumerServiceURL must match the SiteURL and the configuration of the identity provider.",
+		Keywords: []string{"saml", "idp", "assertion", "sso", "identity provider"},
+	},
+	{
+		Title:    "High availability cluster",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ClusterSettings.Enable", "ClusterSettings.ClusterName", "ClusterSettings.GossipPort", "ClusterSettings.OverrideHostname"},
+		Text:     "Enable turns on high availability mode. All nodes must share the same ClusterName and reach each other on GossipPort, 8074 by default, over TCP and UDP. OverrideHostname sets the address other nodes use to reach a node.",
+		Keywords: []string{"cluster", "gossip", "memberlist", "cluster message", "node"},
+	},
+	{
+		Title:    "Elasticsearch",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ElasticsearchSettings.ConnectionURL", "ElasticsearchSettings.EnableIndexing", "ElasticsearchSettings.EnableSearching", "ElasticsearchSettings.Sniff"},
+		Text:     "ConnectionURL is the address of the Elasticsearch or OpenSearch server. EnableIndexing indexes new messages, and EnableSearching uses the index for search. Sniff discovers the other nodes of the cluster and should be off when they are not reachable from Mattermost.",
+		Keywords: []string{"elasticsearch", "opensearch", "indexing", "bulk index", "search engine"},
+	},
+	{
+		Title:    "Rate limiting",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"RateLimitSettings.Enable", "RateLimitSettings.PerSec", "RateLimitSettings.MaxBurst", "RateLimitSettings.VaryByRemoteAddr", "RateLimitSettings.VaryByHeader"},
+		Text:     "Enable limits the API requests per second, PerSec (10 by default) with bursts of MaxBurst (100 by default). Behind a proxy, VaryByRemoteAddr limits all users together unless VaryByHeader names the header holding the client address, such as X-Forwarded-For.",
+		Keywords: []string{"rate limit", "too many requests", "429", "throttl"},
+	},
+	{
+		Title:    "Plugins",
+		URL:      docsPluginsURL,
+		Settings: []string{"PluginSettings.Enable", "PluginSettings.EnableUploads", "PluginSettings.Directory", "PluginSettings.PluginStates"},
+		Text:     "Enable turns plugins on, EnableUploads allows uploading plugins from the System Console, and Directory is where plugins are extracted. PluginStates records which plugins are enabled; a plugin that fails to activate can be disabled there.",
+		Keywords: []string{"plugin", "failed to activate", "health check", "rpc"},
+	},
+	{
+		Title:    "Maximum users per team",
+		URL:      docsSiteURL,
+		Settings: []string{"TeamSettings.MaxUsersPerTeam"},
+		Text:     "MaxUsersPerTeam is the maximum number of members of a team, 50 by default. Users cannot join a team that has reached it.",
+		Keywords: []string{"max users", "maxusersperteam", "team is full", "team has reached"},
+	},
+	{
+		Title:    "Image proxy",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"ImageProxySettings.Enable", "ImageProxySettings.ImageProxyType", "ImageProxySettings.RemoteImageProxyURL"},
+		Text:     "Enable loads external images of messages through a proxy instead of directly from the browser. ImageProxyType is local, or atmos/camo with RemoteImageProxyURL for a separate proxy.",
+		Keywords: []string{"image proxy", "imageproxy", "atmos/camo", "proxy image"},
+	},
+	{
+		Title:    "Logging",
+		URL:      docsEnvironmentURL,
+		Settings: []string{"LogSettings.ConsoleLevel", "LogSettings.FileLevel", "LogSettings.EnableFile", "LogSettings.FileLocation"},
+		Text:     "ConsoleLevel and FileLevel set the level of the messages logged to the console and to mattermost.log in FileLocation. DEBUG logs help investigate a problem but should not be kept on in production.",
+		Keywords: []string{"log level", "mattermost.log", "logsettings"},
+	},
+}
+
+// loadDocPassages reads additional passages from a JSON array, e.g. passages of newer or
+// internal documentation
+func loadDocPassages(path string) ([]docPassage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading --docs-file: %v", err)
+	}
+	var passages []docPassage
+	if err := json.Unmarshal(data, &passages); err != nil {
+		return nil, fmt.Errorf("invalid --docs-file %s: %v", path, err)
+	}
+	for i, passage := range passages {
+		if passage.Title == "" || passage.Text == "" || len(passage.Keywords) == 0 {
+			return nil, fmt.Errorf("invalid --docs-file %s: passage %d needs a title, a text and keywords", path, i+1)
+		}
+	}
+	return passages, nil
+}
+
+// retrieveDocs returns the passages most relevant to the warnings and errors of the logs and
+// to the problem: those matching the most keywords, at most limit
+func retrieveDocs(passages []docPassage, logs []LogEntry, problem string, limit int) []docPassage {
+	if len(passages) == 0 {
+		return nil
+	}
+	var text strings.Builder
+	text.WriteString(strings.ToLower(problem))
+	seen := map[string]bool{}
+	for _, log := range logs {
+		if !isErrorLevel(log.Level) && !strings.EqualFold(log.Level, "warn") && !strings.EqualFold(log.Level, "warning") {
+			continue
+		}
+		if message := strings.ToLower(log.Message); !seen[message] {
+			seen[message] = true
+			text.WriteString("\n" + message)
+		}
+	}
+	query := text.String()
+
+	type scored struct {
+		passage docPassage
+		score   int
+	}
+	var matches []scored
+	for _, passage := range passages {
+		score := 0
+		for _, keyword := range passage.Keywords {
+			if strings.Contains(query, strings.ToLower(keyword)) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{passage, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	var relevant []docPassage
+	for i := 0; i < len(matches) && i < limit; i++ {
+		relevant = append(relevant, matches[i].passage)
+	}
+	return relevant
+}
+
+// docsPrompt returns the passages for the prompt, with the instruction to base configuration
+// recommendations on them
+func docsPrompt(passages []docPassage) string {
+	var prompt strings.Builder
+	prompt.WriteString("Excerpts of the Mattermost documentation relevant to these logs:\n")
+	for i, passage := range passages {
+		_, _ = fmt.Fprintf(&prompt, "\n[%d] %s (%s)\n", i+1, passage.Title, passage.URL)
+		if len(passage.Settings) > 0 {
+			_, _ = fmt.Fprintf(&prompt, "Settings: %s\n", strings.Join(passage.Settings, ", "))
+		}
+		prompt.WriteString(passage.Text + "\n")
+	}
+	prompt.WriteString("\nWhen you recommend configuration changes, only name settings from these excerpts or that you are certain exist, and link the documentation page of each setting you name.")
+	return prompt.String()
+}
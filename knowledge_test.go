@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrieveDocs(t *testing.T) {
+	logs := []LogEntry{
+		{Level: "error", Message: "Failed to ping DB: pq: sorry, too many clients already"},
+		{Level: "error", Message: "Failed to ping DB: pq: sorry, too many clients already"},
+		{Level: "warn", Message: "Unable to upload file: request body too large, content length 209715200"},
+		{Level: "info", Message: "Starting websocket hub"},
+	}
+
+	var titles []string
+	for _, passage := range retrieveDocs(mattermostDocs, logs, "", maxDocPassages) {
+		titles = append(titles, passage.Title)
+	}
+	assert.Equal(t, []string{"Maximum file size", "Database connection string", "Database connection pool"}, titles,
+		"passages matching more keywords come first, and info entries are ignored")
+
+	relevant := retrieveDocs(mattermostDocs, nil, "Users are locked out after failed login attempts", maxDocPassages)
+	require.NotEmpty(t, relevant)
+	assert.Equal(t, "Login attempts", relevant[0].Title)
+
+	assert.Empty(t, retrieveDocs(mattermostDocs, []LogEntry{{Level: "error", Message: "Everything is fine"}}, "", maxDocPassages))
+	assert.Empty(t, retrieveDocs(nil, logs, "", maxDocPassages))
+}
+
+func TestDocsInAnalysisPrompt(t *testing.T) {
+	logs := []LogEntry{{
+		Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"),
+		Level:     "error",
+		Message:   "Rate limit exceeded: 429 Too Many Requests",
+	}}
+
+	prompt, err := prepareAnalysisPrompts(logs, LLMConfig{Docs: mattermostDocs, Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(prompt.Instructions, "Excerpts of the Mattermost documentation relevant to these logs:\n\n[1] Rate limiting ("+docsEnvironmentURL+")\n"))
+	assert.Contains(t, prompt.Instructions, "Settings: RateLimitSettings.Enable, RateLimitSettings.PerSec")
+	assert.True(t, strings.HasSuffix(prompt.Instructions, "link the documentation page of each setting you name.\n\nPlease provide a detailed analysis of these logs."))
+	assert.NotContains(t, prompt.SystemPrompt+prompt.LogPrompt, "Excerpts", "the cached part of the prompt does not depend on the documentation")
+
+	thinking, err := prepareAnalysisPrompts(logs, LLMConfig{Docs: mattermostDocs, ThinkingBudget: 10000, Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(thinking.Instructions, "each setting you name."))
+	assert.Equal(t, thinking.LogPrompt+"\n\n"+thinking.Instructions, thinking.UserPrompt)
+}
+
+func TestLoadDocPassages(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "docs.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`[{"title": "Calls", "url": "https://docs.example.com/calls", "settings": ["PluginSettings.Plugins.com.mattermost.calls"], "text": "Calls needs UDP port 8443.", "keywords": ["rtc", "ice"]}]`), 0o600))
+	passages, err := loadDocPassages(valid)
+	require.NoError(t, err)
+	require.Len(t, passages, 1)
+	assert.Equal(t, []string{"rtc", "ice"}, passages[0].Keywords)
+
+	noKeywords := filepath.Join(dir, "no-keywords.json")
+	require.NoError(t, os.WriteFile(noKeywords, []byte(`[{"title": "Calls", "text": "Calls needs UDP port 8443."}]`), 0o600))
+	_, err = loadDocPassages(noKeywords)
+	assert.EqualError(t, err, "invalid --docs-file "+noKeywords+": passage 1 needs a title, a text and keywords")
+
+	_, err = loadDocPassages(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "error reading --docs-file")
+}
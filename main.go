@@ -2,14 +2,14 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"runtime"
 	"runtime/debug"
-	"sort"
 	"strings"
+	"time"
 
-	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
@@ -22,9 +22,14 @@ var (
 	startTime      string
 	endTime        string
 	jsonOutput     bool
+	ndjsonOutput   bool
 	csvOutput      string
+	csvDelimiter   string
+	csvExpandExtras bool
 	outputFile     string
 	analyze        bool
+	summarize      bool
+	securityReport bool
 	aiAnalyze      bool
 	apiKey         string
 	llmProvider    string
@@ -33,7 +38,21 @@ var (
 	trimJSON       string
 	maxEntries     int
 	problem        string
+	problemTemplateName string
+	ticketWorkspaceName string
 	thinkingBudget int
+	showThinking   bool
+	geminiSafety   string
+	temperature    float64
+	topP           float64
+	maxOutputTokens int
+	llmDebugDir    string
+	noDocs         bool
+	docsFile       string
+	embeddingProvider string
+	embeddingModel string
+	semanticLimit  int
+	digestGroupBy  string
 	ollamaHost     string
 	ollamaTimeout  int
 	interactive    bool
@@ -41,6 +60,65 @@ var (
 	quiet          bool
 	verboseAnalysis bool
 	rawOutput      bool
+	topN           int
+	fullAnalysis   bool
+	activityBucket string // Period of the activity of the analysis: hour, day, week or month
+	sloObjectives  []string      // Service level objectives, e.g. error_rate<1%
+	sloWindow      time.Duration // Windows the objectives are evaluated over
+	baselineFile   string
+	baselineOut    string
+	saveStatsFile  string
+	diffStatsFile  string
+	mermaidFile    string
+	chartPNG       string
+	pdfReport      string
+	findingsFile   string
+	createJira     bool
+	jira           jiraSettings
+	splunk         splunkSettings
+	createIssue    bool
+	gitIssue       issueSettings
+	emailTo        []string
+	ticket         ticketSettings
+	webhookURL     string
+	webhookSecret  string
+	uploadTo       string
+	uploadExpires  time.Duration
+	follow         bool
+	followFiles    []string // Log files followed in interactive mode, set by commands reading files
+	themeName      string
+	sourceDir      string
+	serverVersion  string // Mattermost server version, read from the support packet metadata
+	packetDiagnostics PacketDiagnostics // Metadata, diagnostics and profile files of the support packet
+	includeConfig  bool
+	serverConfig   string // Sanitized config.json of the server, read from the support packet with --include-config
+	proposedChangesFile string
+	packetLogs     packetLogSelection // Log files of the support packet to parse
+	nodeFilter     []string           // Only keep the entries of these cluster nodes, for the file and notification commands
+	correctSkew    bool               // Shift the entries of each node of the support packet by its clock skew
+	fastScan       bool
+	noFileProgress bool // Hides the progress of parsing large files while another progress bar is shown
+	maxMemory      string
+	maxLines       int    // Stop reading the logs after this many lines, 0 for no cap
+	maxBytes       string // Stop reading the logs after this size, such as 10G
+	keepNewest     bool   // Keep the newest entries within --max-lines and --max-bytes instead of stopping
+	loadedSource   *SessionSource // How the command loads its logs, recorded in saved sessions
+	resumedSession *Session       // Session restored by 'lamp resume'
+	profileName    string
+	porcelain      bool
+	noProgress     bool
+	updateCheck    bool
+	updateForce    bool
+	doctorProvider string
+	doctorModel    string
+	doctorOllamaHost string
+	rerunNumber    int
+	ruleFiles      []string
+	caCert         string
+	insecureSkipVerify bool
+	onError        string // Strategy for the inputs that cannot be read: skip, abort or collect
+	runSummary     string // File to write the JSON summary of the run to, "-" for stderr
+	version        string // Release version, set at build time by goreleaser (-X main.version)
 
 	// Global logger
 	logger *slog.Logger
@@ -53,8 +131,50 @@ var rootCmd = &cobra.Command{
 	Long: `lamp (Log Analyser for Mattermost Packet) allows you to parse, filter, and analyze Mattermost log files
 and support packets. It provides various filtering options, analysis capabilities,
 and AI-powered insights using LLM technology.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Only flags given on the command line are recorded in the history
+		flags := commandLineFlags(cmd)
+
+		// Apply the environment and the profile first, they may set --verbose or --quiet.
+		// Flags on the command line take precedence over the environment, which takes
+		// precedence over the profile.
+		if err := applyEnvironment(cmd); err != nil {
+			return err
+		}
+		if err := loadProfile(cmd); err != nil {
+			return err
+		}
 		initLogger()
+		if err := configureHTTPTLS(caCert, insecureSkipVerify); err != nil {
+			return err
+		}
+		policy, err := newInputErrorPolicy(onError)
+		if err != nil {
+			return err
+		}
+		inputErrors = policy
+
+		if contains([]string{"file", "notification", "support-packet", "triage"}, cmd.Name()) {
+			if err := loadParserPlugins(); err != nil {
+				return err
+			}
+			if err := loadAnalysisRules(ruleFiles); err != nil {
+				return err
+			}
+			if err := applyProblemTemplate(); err != nil {
+				return err
+			}
+			limit, err := newReadLimit(maxLines, maxBytes, keepNewest)
+			if err != nil {
+				return err
+			}
+			inputLimit = limit
+			// The triage wizard asks its questions again, there is nothing to rerun
+			if cmd.Name() != "triage" {
+				recordRun(cmd, args, flags)
+			}
+		}
+		return nil
 	},
 }
 
@@ -66,6 +186,15 @@ var fileCmd = &cobra.Command{
 		return nil, cobra.ShellCompDirectiveFilterFileExt | cobra.ShellCompDirectiveDefault
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		loadedSource = newSessionSource("file", args)
+		if follow {
+			followFiles = args
+		}
+		if err := startEntrySpill(); err != nil {
+			return err
+		}
+		defer stopEntrySpill()
+
 		if len(args) == 1 {
 			// Single file mode
 			filePath := args[0]
@@ -78,24 +207,15 @@ var fileCmd = &cobra.Command{
 				return fmt.Errorf("error parsing log file: %v", err)
 			}
 
-			return processLogs(logs)
+			return processParsedLogs(entrySpill.unspilled(entrySpill.collect(nil, logs)))
 		} else {
 			// Multiple files mode
 			var allLogs []LogEntry
 
-			// Create progress bar for file processing
-			bar := progressbar.NewOptions(len(args),
-				progressbar.OptionEnableColorCodes(true),
-				progressbar.OptionSetWidth(40),
-				progressbar.OptionShowCount(),
-				progressbar.OptionSetDescription("[cyan]Processing log files[reset]"),
-				progressbar.OptionSetTheme(progressbar.Theme{
-					Saucer:        "[green]=[reset]",
-					SaucerHead:    "[green]>[reset]",
-					SaucerPadding: " ",
-					BarStart:      "[",
-					BarEnd:        "]",
-				}))
+			// Create progress bar for file processing, instead of one per large file
+			bar := newProgressBar(len(args), "Processing log files")
+			noFileProgress = true
+			defer func() { noFileProgress = false }()
 
 			// Process each file
 			for _, filePath := range args {
@@ -104,28 +224,38 @@ var fileCmd = &cobra.Command{
 				}
 
 				if _, err := os.Stat(filePath); os.IsNotExist(err) {
-					logger.Warn("File does not exist, skipping", "file", filePath)
+					if err := inputErrors.handle(filePath, fmt.Errorf("file does not exist")); err != nil {
+						return err
+					}
 					continue
 				}
 
 				logs, err := parseLogFile(filePath, searchTerm, regexSearch, levelFilter, userFilter, startTime, endTime)
 				if err != nil {
-					logger.Warn("Error parsing log file, skipping", "file", filePath, "error", err)
+					if err := inputErrors.handle(filePath, err); err != nil {
+						return fmt.Errorf("error parsing log file %v", err)
+					}
 					continue
 				}
 
-				allLogs = append(allLogs, logs...)
+				allLogs = entrySpill.collect(allLogs, logs)
 				logger.Debug("Processed file", "file", filePath, "entries", len(logs))
 			}
+			if err := bar.Finish(); err != nil {
+				logger.Warn("Error completing progress bar", "error", err)
+			}
+			allLogs = entrySpill.unspilled(allLogs)
 
+			if entrySpill.spilled() {
+				// The spilled entries are merged in timestamp order
+				return processParsedLogs(allLogs)
+			}
 			if len(allLogs) == 0 {
 				return fmt.Errorf("no valid log entries found in any of the provided files")
 			}
 
 			// Sort all logs by timestamp
-			sort.Slice(allLogs, func(i, j int) bool {
-				return allLogs[i].Timestamp.Before(allLogs[j].Timestamp)
-			})
+			sortEntries(allLogs)
 
 			logger.Info("Finished processing files", "total_files", len(args), "total_entries", len(allLogs))
 			return processLogs(allLogs)
@@ -148,13 +278,21 @@ var notificationCmd = &cobra.Command{
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			return fmt.Errorf("notification log file '%s' does not exist", filePath)
 		}
+		loadedSource = newSessionSource("notification", args)
+		if follow {
+			followFiles = args
+		}
+		if err := startEntrySpill(); err != nil {
+			return err
+		}
+		defer stopEntrySpill()
 
 		logs, err := parseLogFile(filePath, searchTerm, regexSearch, levelFilter, userFilter, startTime, endTime)
 		if err != nil {
 			return fmt.Errorf("error parsing notification log file: %v", err)
 		}
 
-		return processLogs(logs)
+		return processParsedLogs(entrySpill.unspilled(entrySpill.collect(nil, logs)))
 	},
 }
 
@@ -173,17 +311,514 @@ var supportPacketCmd = &cobra.Command{
 		if _, err := os.Stat(packetPath); os.IsNotExist(err) {
 			return fmt.Errorf("support packet '%s' does not exist", packetPath)
 		}
+		loadedSource = newSessionSource("support-packet", args)
+		if err := startEntrySpill(); err != nil {
+			return err
+		}
+		defer stopEntrySpill()
 
-		logs, err := parseSupportPacket(packetPath, searchTerm, regexSearch, levelFilter, userFilter, startTime, endTime)
+		logs, err := parseSupportPacket(packetPath, packetLogs, searchTerm, regexSearch, levelFilter, userFilter, startTime, endTime)
 		if err != nil {
 			return fmt.Errorf("error parsing support packet: %v", err)
 		}
 
+		serverVersion, err = readSupportPacketServerVersion(packetPath)
+		if err != nil {
+			logger.Warn("Failed to read the server version from the support packet", "error", err)
+		}
+		if packetDiagnostics, err = readPacketDiagnostics(packetPath); err != nil {
+			logger.Warn("Failed to read the diagnostics of the support packet", "error", err)
+		}
+		if includeConfig {
+			if serverConfig, err = readSupportPacketConfig(packetPath); err != nil {
+				return fmt.Errorf("error reading the configuration of the support packet: %v", err)
+			}
+		}
+
+		// The skew can only be estimated with all entries in memory
+		if !entrySpill.spilled() {
+			handleClockSkew(logs, correctSkew)
+		}
+
 		if verbose {
 			fmt.Printf("Debug: processing %d log entries\n", len(logs))
 		}
 
-		return processLogs(logs)
+		return processParsedLogs(logs)
+	},
+}
+
+var triageCmd = &cobra.Command{
+	Use:   "triage [path]",
+	Short: "Triage a support packet step by step",
+	Long: `Walk through the standard support workflow for a support packet: the summary of its logs,
+the top findings, the reported problem, the entries relevant to it, and optionally the AI
+analysis of these entries with the findings and the problem as context.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"zip"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTriage(args[0])
+	},
+}
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Manage healthy-day baselines used for anomaly detection",
+}
+
+var baselineSaveCmd = &cobra.Command{
+	Use:   "save [path...]",
+	Short: "Save a baseline profile from one or more healthy log files or support packets",
+	Args:  cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterFileExt | cobra.ShellCompDirectiveDefault
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allLogs, err := parseLogPaths(args, "")
+		if err != nil {
+			return err
+		}
+		if len(allLogs) == 0 {
+			return fmt.Errorf("no valid log entries found to build a baseline")
+		}
+
+		baseline := buildBaseline(allLogs, args)
+		if err := saveBaseline(baseline, baselineOut); err != nil {
+			return fmt.Errorf("error writing baseline: %v", err)
+		}
+
+		fmt.Printf("Baseline saved to %s (%d entries, %d error signatures)\n",
+			baselineOut, baseline.TotalEntries, len(baseline.ErrorSignatures))
+		return nil
+	},
+}
+
+// parseLogPath parses a log file or a support packet (.zip), keeping the entries of a level if set
+func parseLogPath(path, level string) ([]LogEntry, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("file '%s' does not exist", path)
+	}
+	var logs []LogEntry
+	var err error
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		logs, err = parseSupportPacket(path, packetLogSelection{}, "", "", level, "", "", "")
+	} else {
+		logs, err = parseLogFile(path, "", "", level, "", "", "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing '%s': %v", path, err)
+	}
+	return logs, nil
+}
+
+// parseLogPaths parses log files and support packets (.zip), keeping the entries of a level
+// if set. The inputs that cannot be read are handled with --on-error.
+func parseLogPaths(paths []string, level string) ([]LogEntry, error) {
+	var allLogs []LogEntry
+	read := 0
+	for _, path := range paths {
+		logs, err := parseLogPath(path, level)
+		if err != nil {
+			// A single input that cannot be read fails the command whatever --on-error
+			if len(paths) == 1 {
+				return nil, err
+			}
+			if err := inputErrors.handle(path, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		read++
+		allLogs = append(allLogs, logs...)
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("none of the %d files could be read", len(paths))
+	}
+	return allLogs, nil
+}
+
+var searchSemanticCmd = &cobra.Command{
+	Use:   "search-semantic [query] [path...]",
+	Short: "Find the log entries most relevant to a description of a problem",
+	Long: `Find the log messages closest in meaning to a description of a problem, such as
+"users can't upload files", even when they are worded differently from the description.
+
+Log messages are embedded with OpenAI or Ollama embeddings. The embeddings are cached, so
+searching the same logs again only embeds the query.`,
+	Example: `  lamp search-semantic "users can't upload files" mattermost.log
+  lamp search-semantic "push notifications are late" packet.zip --embedding-provider ollama`,
+	Args: cobra.MinimumNArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveFilterFileExt | cobra.ShellCompDirectiveDefault
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := args[0]
+		if semanticLimit < 1 {
+			return fmt.Errorf("--limit must be at least 1, got %d", semanticLimit)
+		}
+		provider := LLMProvider(embeddingProvider)
+		key := apiKey
+		if provider == ProviderOpenAI && key == "" {
+			key = lookupAPIKey(provider)
+		}
+		OllamaHost = ollamaHost
+		client, err := newEmbeddingClient(provider, embeddingModel, key, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		logs, err := parseLogPaths(args[1:], levelFilter)
+		if err != nil {
+			return err
+		}
+		cache, err := loadEmbeddingCache(provider, client.model)
+		if err != nil {
+			return err
+		}
+		matches, err := semanticSearch(logs, query, client, cache, semanticLimit)
+		// Keep the embeddings computed before an error, they need not be computed again
+		if saveErr := cache.save(); saveErr != nil {
+			logger.Warn("Failed to save the embedding cache", "file", cache.path, "error", saveErr)
+		}
+		if err != nil {
+			return fmt.Errorf("error searching the logs: %v", err)
+		}
+
+		return displaySemanticMatches(matches, jsonOutput, os.Stdout)
+	},
+}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest [path...]",
+	Short: "Summarize log files or support packets in one compact block per day or week",
+	Long: `Summarize logs spanning days or weeks in one compact block per period: the number of
+entries, the error rate, the error signatures first seen in the period, and notable events
+such as restarts, error bursts and logging gaps.`,
+	Example: `  lamp digest packet.zip
+  lamp digest mattermost.log mattermost.log.1 --group-by week`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterFileExt | cobra.ShellCompDirectiveDefault
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logs, err := parseLogPaths(args, "")
+		if err != nil {
+			return err
+		}
+		periods, err := buildDigest(logs, digestGroupBy)
+		if err != nil {
+			return err
+		}
+		return displayDigest(periods, digestGroupBy, jsonOutput, os.Stdout)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [session-file]",
+	Short: "Reopen an interactive mode session saved with Ctrl+S",
+	Long: `Reload the files of a saved interactive mode session with the same command line filters,
+and restore its filter, search, grouping, sort order, bookmarks and selection. Without a
+path, the last session saved with Ctrl+S is resumed.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var path string
+		if len(args) == 1 {
+			path = args[0]
+		} else {
+			defaultPath, err := defaultSessionPath()
+			if err != nil {
+				return fmt.Errorf("error locating the saved session: %v", err)
+			}
+			path = defaultPath
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return fmt.Errorf("no saved session found (press Ctrl+S in interactive mode to save one)")
+			}
+		}
+
+		session, err := loadSession(path)
+		if err != nil {
+			return fmt.Errorf("error loading session: %v", err)
+		}
+
+		var command *cobra.Command
+		switch session.Source.Command {
+		case "file":
+			command = fileCmd
+		case "notification":
+			command = notificationCmd
+		case "support-packet":
+			command = supportPacketCmd
+		default:
+			return fmt.Errorf("unsupported command %q in session", session.Source.Command)
+		}
+
+		session.Source.apply()
+		interactive = true
+		resumedSession = &session
+		return command.RunE(command, session.Source.Paths)
+	},
+}
+
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List recently analyzed files and packets, or run one again",
+	Long: `List the recent runs of the file, notification and support-packet commands with the
+flags given on their command line, most recent first. Use --rerun with the number of a run to
+run it again from the same directory.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := historyPath()
+		if err != nil {
+			return fmt.Errorf("error locating the history: %v", err)
+		}
+		history, err := loadHistory(path)
+		if err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			return fmt.Errorf("no recent runs (they are recorded by the file, notification and support-packet commands)")
+		}
+
+		if rerunNumber == 0 {
+			printHistory(os.Stdout, history)
+			return nil
+		}
+		if rerunNumber < 1 || rerunNumber > len(history) {
+			return fmt.Errorf("no run number %d, expected 1 to %d (see 'lamp recent')", rerunNumber, len(history))
+		}
+		entry := history[rerunNumber-1]
+		_, _ = fmt.Fprintf(os.Stderr, "Running %s\n", entry.commandLine())
+		return rerunHistory(entry)
+	},
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage LLM provider API keys in the system keychain",
+}
+
+var authSetCmd = &cobra.Command{
+	Use:   "set [provider]",
+	Short: "Store the API key of a provider in the system keychain",
+	Long: `Store the API key of an LLM provider in the macOS Keychain, the Windows Credential Manager
+or the Secret Service (libsecret) on Linux. The key is read from the terminal without
+echoing it, or from the standard input. Keys in the environment take precedence.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: keyProviders,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, err := parseKeyProvider(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s API key: ", provider)
+		key, err := newTerminalSetupWizard().readSecret()
+		if err != nil {
+			return err
+		}
+		if key = strings.TrimSpace(key); key == "" {
+			return fmt.Errorf("no API key entered")
+		}
+		if err := keychainSet(string(provider), key); err != nil {
+			return fmt.Errorf("error storing the API key in the keychain: %v", err)
+		}
+
+		fmt.Printf("Stored the %s API key in the system keychain\n", provider)
+		return nil
+	},
+}
+
+var authRemoveCmd = &cobra.Command{
+	Use:       "remove [provider]",
+	Short:     "Remove the API key of a provider from the system keychain",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: keyProviders,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, err := parseKeyProvider(args[0])
+		if err != nil {
+			return err
+		}
+		if err := removeKeychainKey(provider); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed the %s API key from the system keychain\n", provider)
+		return nil
+	},
+}
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named sets of flag defaults selected with --profile",
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles of the config file with their settings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(config.Profiles) == 0 {
+			fmt.Println("No profiles defined (add one with 'lamp profile add')")
+			return nil
+		}
+		printProfiles(os.Stdout, config)
+		return nil
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add [name] [flag=value...]",
+	Short: "Add or replace a profile in the config file",
+	Long: `Save a named set of flag defaults to the config file. Commands run with --profile use
+these values for the flags not given on the command line. For example:
+
+  lamp profile add customer-triage llm-provider=openai llm-model=gpt-4o level=error trim=true
+  lamp support-packet packet.zip --profile customer-triage --ai-analyze`,
+	Args: cobra.MinimumNArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		profile, err := parseProfileSettings(fileCmd, args[1:])
+		if err != nil {
+			return err
+		}
+
+		path, err := configPath()
+		if err != nil {
+			return fmt.Errorf("error locating the config file: %v", err)
+		}
+		config, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		if config.Profiles == nil {
+			config.Profiles = make(map[string]Profile)
+		}
+		config.Profiles[name] = profile
+		if err := writeConfigFile(config, path); err != nil {
+			return fmt.Errorf("error writing config file: %v", err)
+		}
+
+		fmt.Printf("Saved profile %q to %s\n", name, path)
+		return nil
+	},
+}
+
+var ticketCmd = &cobra.Command{
+	Use:   "ticket",
+	Short: "Show the workspace of a support ticket or add notes to it, see --ticket",
+}
+
+var ticketShowCmd = &cobra.Command{
+	Use:   "show [ticket]",
+	Short: "List the runs of a ticket with their findings, and its notes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, _, err := loadTicketWorkspace(args[0])
+		if err != nil {
+			return err
+		}
+		displayTicketWorkspace(workspace, os.Stdout)
+		return nil
+	},
+}
+
+var ticketNoteCmd = &cobra.Command{
+	Use:   "note [ticket] [text...]",
+	Short: "Add a note to a ticket, given to the next AI analyses of the ticket",
+	Long: `Add a note to the workspace of a ticket, such as what the customer changed since the last
+support packet. The AI analyses of runs with --ticket are given the notes with the earlier
+findings. For example:
+
+  lamp ticket note MM-1234 "Customer raised SqlSettings.MaxOpenConns to 300"`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, err := addWorkspaceNote(args[0], strings.Join(args[1:], " "))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added a note to ticket %s (%s)\n", workspace.Ticket, countNoun(len(workspace.Notes), "note", "notes"))
+		return nil
+	},
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up the AI provider, its API key and the default model",
+	Long: `Interactively choose the LLM provider, its default model and where to keep its API key,
+and write the answers to the config file. Run it again to change the settings.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configPath()
+		if err != nil {
+			return fmt.Errorf("error locating the config file: %v", err)
+		}
+		config, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := newTerminalSetupWizard().run(&config); err != nil {
+			return err
+		}
+		if err := writeConfigFile(config, path); err != nil {
+			return fmt.Errorf("error writing config file: %v", err)
+		}
+
+		fmt.Printf("\nConfiguration written to %s\n", path)
+		return nil
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment for AI analysis, the clipboard and the terminal",
+	Long: `Check that lamp can run everything it offers: the config file, the API keys of the LLM
+providers (with a cheap request to each API), the Ollama server and its models, the clipboard,
+and the colors of the terminal. Problems are listed with how to fix them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, _ := loadConfig() // A broken config file is reported by the checks
+		checks := newDoctor(config, doctorProvider, doctorModel, doctorOllamaHost).run()
+		if failed := printDoctorChecks(os.Stdout, checks); failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
+		return nil
+	},
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update lamp to the latest release",
+	Long: `Update lamp to the latest GitHub release. The release archive for this platform is
+verified against the checksums published with the release before the binary is replaced.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u, err := newUpdater(os.Stdout)
+		if err != nil {
+			return err
+		}
+		return u.run(updateCheck, updateForce)
 	},
 }
 
@@ -195,12 +830,7 @@ var versionCmd = &cobra.Command{
 		if !ok {
 			return fmt.Errorf("could not read build information")
 		}
-		// Get version (usually from the module path)
-		version := info.Main.Version
-		if version == "(devel)" {
-			version = "dev"
-		}
-		fmt.Printf("Version:\t%s\n", version)
+		fmt.Printf("Version:\t%s\n", currentVersion())
 
 		// Extract other build information from settings
 		var commitDate, gitCommit, gitTreeState string
@@ -264,11 +894,129 @@ func init() {
 	// Enable command completion
 	rootCmd.CompletionOptions.DisableDefaultCmd = false
 
+	// Connections to LLM providers and integrations
+	rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM file of CA certificates to trust on top of the system ones, e.g. of a TLS-intercepting proxy")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Do not verify TLS certificates (insecure, prefer --ca-cert)")
+
+	// Inputs that cannot be read
+	rootCmd.PersistentFlags().StringVar(&runSummary, "run-summary", "", "Write a one-line JSON summary of the run (files parsed, entries, skipped lines, duration, outputs) to a file, or to stderr with -")
+	rootCmd.PersistentFlags().StringVar(&onError, "on-error", onErrorSkip, "What to do with the files, archives and packet files that cannot be read when reading several: skip (warn), abort, or collect (fail listing them at the end)")
+	registerFlagCompletion(rootCmd, "on-error", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return onErrorStrategies, cobra.ShellCompDirectiveNoFileComp
+	})
+
 	// Add subcommands to root command
 	rootCmd.AddCommand(fileCmd)
 	rootCmd.AddCommand(notificationCmd)
 	rootCmd.AddCommand(supportPacketCmd)
+	rootCmd.AddCommand(triageCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(recentCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(baselineCmd)
+	rootCmd.AddCommand(searchSemanticCmd)
+	rootCmd.AddCommand(digestCmd)
+	baselineCmd.AddCommand(baselineSaveCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authSetCmd)
+	authCmd.AddCommand(authRemoveCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(ticketCmd)
+	ticketCmd.AddCommand(ticketShowCmd)
+	ticketCmd.AddCommand(ticketNoteCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileAddCmd)
+
+	baselineSaveCmd.Flags().StringVar(&baselineOut, "out", "baseline.json", "Path of the baseline file to write")
+	registerFlagCompletion(baselineSaveCmd, "out", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveDefault
+	})
+
+	doctorCmd.Flags().StringVar(&doctorProvider, "llm-provider", "", "LLM provider used for AI analysis, whose problems count as failures (defaults to the config file or anthropic)")
+	doctorCmd.Flags().StringVar(&doctorModel, "llm-model", "", "Ollama model to look for on the server (defaults to the config file or the default model)")
+	doctorCmd.Flags().StringVar(&doctorOllamaHost, "ollama-host", "", "Ollama server URL (defaults to the config file or "+defaultOllamaHost+")")
+	registerFlagCompletion(doctorCmd, "llm-provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"anthropic", "openai", "gemini", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	registerFlagCompletion(doctorCmd, "llm-model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var modelNames []string
+		for _, model := range GetAvailableModels(ProviderOllama) {
+			modelNames = append(modelNames, model.ID)
+		}
+		return modelNames, cobra.ShellCompDirectiveNoFileComp
+	})
+	registerFlagCompletion(doctorCmd, "ollama-host", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	recentCmd.Flags().IntVar(&rerunNumber, "rerun", 0, "Run the invocation with this number in the list again")
+	registerFlagCompletion(recentCmd, "rerun", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	searchSemanticCmd.Flags().StringVar(&embeddingProvider, "embedding-provider", "openai", "Provider of the embeddings (openai, ollama)")
+	searchSemanticCmd.Flags().StringVar(&embeddingModel, "embedding-model", "", "Embedding model (defaults to text-embedding-3-small for openai, nomic-embed-text for ollama)")
+	searchSemanticCmd.Flags().IntVar(&semanticLimit, "limit", 10, "Number of log messages to show")
+	searchSemanticCmd.Flags().StringVar(&levelFilter, "level", "", "Only search log entries of this level")
+	searchSemanticCmd.Flags().StringVar(&apiKey, "api-key", "", "OpenAI API key (defaults to the stored key)")
+	searchSemanticCmd.Flags().StringVar(&ollamaHost, "ollama-host", "http://localhost:11434", "Ollama server URL (only for the ollama provider)")
+	searchSemanticCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the matches in JSON format")
+	registerFlagCompletion(searchSemanticCmd, "embedding-provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"openai", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	registerFlagCompletion(searchSemanticCmd, "level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"debug", "info", "warn", "error", "fatal", "panic"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	digestCmd.Flags().StringVar(&digestGroupBy, "group-by", "day", "Period of each summary block (day, week)")
+	digestCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the digest in JSON format")
+	registerFlagCompletion(digestCmd, "group-by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return digestGroupings, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	for _, cmd := range []*cobra.Command{fileCmd, notificationCmd} {
+		cmd.Flags().StringSliceVar(&nodeFilter, "node", nil, "Only keep the entries of these cluster nodes, named by the node of exported entries or their node or hostname field")
+	}
+
+	supportPacketCmd.Flags().StringSliceVar(&packetLogs.Kinds, "logs", nil, "Only load these logs of the support packet (mattermost, notifications, or the base name of other logs)")
+	supportPacketCmd.Flags().StringSliceVar(&packetLogs.Nodes, "node", nil, "Only load the logs of these nodes of the support packet")
+	// --nodes was the name of --node before entries recorded their node
+	supportPacketCmd.Flags().StringSliceVar(&packetLogs.Nodes, "nodes", nil, "Only load the logs of these nodes of the support packet")
+	if err := supportPacketCmd.Flags().MarkDeprecated("nodes", "use --node instead"); err != nil {
+		panic(fmt.Sprintf("failed to deprecate the --nodes flag: %v", err))
+	}
+	supportPacketCmd.Flags().BoolVar(&packetLogs.Prompt, "select-logs", false, "List the log files of the support packet and ask which ones to load")
+	supportPacketCmd.Flags().BoolVar(&correctSkew, "correct-skew", false, "Shift the entries of each node by the clock skew estimated from request IDs and server starts, to order the entries across nodes")
+	registerFlagCompletion(supportPacketCmd, "logs", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return packetLogCompletions(args, func(file packetLogFile) string { return file.Kind }), cobra.ShellCompDirectiveNoFileComp
+	})
+	registerFlagCompletion(supportPacketCmd, "node", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return packetLogCompletions(args, func(file packetLogFile) string { return file.Node }), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only check whether a newer release is available")
+	updateCmd.Flags().BoolVar(&updateForce, "force", false, "Install the latest release even if it is not newer, or over a development build")
+
+	resumeCmd.Flags().StringVar(&themeName, "theme", "", "Color theme of the interactive mode (dark, light, mono; defaults to the config file theme or dark)")
+	resumeCmd.Flags().StringVar(&sourceDir, "source-dir", "", "Mattermost server checkout used to open log callers in $EDITOR from interactive mode")
+	registerFlagCompletion(resumeCmd, "theme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return themeNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	registerFlagCompletion(resumeCmd, "source-dir", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	})
+
+	triageCmd.Flags().StringVar(&llmProvider, "llm-provider", "anthropic", "LLM provider of the AI analysis (anthropic, openai, gemini, ollama)")
+	triageCmd.Flags().StringVar(&llmModel, "llm-model", "", "LLM model of the AI analysis (defaults to provider-specific default)")
+	triageCmd.Flags().IntVar(&maxEntries, "max-entries", 100, "Maximum number of relevant log entries to send to LLM")
+	triageCmd.Flags().StringVar(&ollamaHost, "ollama-host", "http://localhost:11434", "Ollama server URL (only for ollama provider)")
+	triageCmd.Flags().IntVar(&ollamaTimeout, "ollama-timeout", 120, "Timeout in seconds for Ollama requests (only for ollama provider)")
+	registerFlagCompletion(triageCmd, "llm-provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"anthropic", "openai", "gemini", "ollama"}, cobra.ShellCompDirectiveNoFileComp
+	})
 
 	// Add shared flags to all file processing subcommands
 	commands := []*cobra.Command{fileCmd, notificationCmd, supportPacketCmd}
@@ -279,10 +1027,20 @@ func init() {
 		cmd.Flags().StringVar(&userFilter, "user", "", "Filter logs by username")
 		cmd.Flags().StringVar(&startTime, "start", "", "Filter logs after this time (format: 2006-01-02 15:04:05.000)")
 		cmd.Flags().StringVar(&endTime, "end", "", "Filter logs before this time (format: 2006-01-02 15:04:05.000)")
+		cmd.Flags().StringVar(&maxMemory, "max-memory", "", "Write the parsed entries to temporary files when they exceed this memory, such as 2G, and merge them for --raw, --json, --ndjson and --csv, sorted by timestamp even where the entries of a file are otherwise written in file order")
+		cmd.Flags().IntVar(&maxLines, "max-lines", 0, "Stop reading the logs after this many lines, across all files, with a warning")
+		cmd.Flags().StringVar(&maxBytes, "max-bytes", "", "Stop reading the logs after this size, such as 10G, across all files, with a warning")
+		cmd.Flags().BoolVar(&keepNewest, "keep-newest", false, "Read all logs but keep only the entries of the newest --max-lines or --max-bytes")
+		cmd.Flags().BoolVar(&fastScan, "fast", false, "Apply --level to raw lines before parsing them, or skip debug and trace lines without --level")
 		cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
-		cmd.Flags().StringVar(&csvOutput, "csv", "", "Export logs to CSV file at specified path")
+		cmd.Flags().BoolVar(&ndjsonOutput, "ndjson", false, "Output the entries as JSON Lines, one entry per line, which lamp reads back as input")
+		cmd.Flags().StringVar(&csvOutput, "csv", "", "Export logs to CSV file at specified path, compressed with gzip when it ends in .gz")
+		cmd.Flags().StringVar(&csvDelimiter, "csv-delimiter", ",", "Field separator of the CSV export: a single character, or tab")
+		cmd.Flags().BoolVar(&csvExpandExtras, "csv-expand-extras", false, "Export each extras key of the entries as its own CSV column instead of a single Extras column")
 		cmd.Flags().StringVar(&outputFile, "output", "", "Save output to file instead of stdout")
 		cmd.Flags().BoolVar(&analyze, "analyze", false, "Analyze logs and show statistics")
+		cmd.Flags().BoolVar(&summarize, "summarize", false, "Summarize the analysis in a few sentences, without AI")
+		cmd.Flags().BoolVar(&securityReport, "security-report", false, "Report authentication failures, permission denials, role changes, session revocations and suspicious IPs of server and audit logs")
 		cmd.Flags().BoolVar(&aiAnalyze, "ai-analyze", false, "Analyze logs using AI")
 		cmd.Flags().StringVar(&apiKey, "api-key", "", "API key for LLM provider")
 		cmd.Flags().StringVar(&llmProvider, "llm-provider", "anthropic", "LLM provider to use (anthropic, openai, gemini, ollama)")
@@ -291,7 +1049,19 @@ func init() {
 		cmd.Flags().StringVar(&trimJSON, "trim-json", "", "Write deduplicated logs to a JSON file at specified path")
 		cmd.Flags().IntVar(&maxEntries, "max-entries", 100, "Maximum number of log entries to send to LLM")
 		cmd.Flags().StringVar(&problem, "problem", "", "Description of the problem you're investigating")
-		cmd.Flags().IntVar(&thinkingBudget, "thinking-budget", 0, "Token budget for extended thinking mode (only supported by some models)")
+		cmd.Flags().StringVar(&problemTemplateName, "problem-template", "", "Kind of problem you're investigating, expanded to a problem statement, a --regex filter and what the AI analysis should examine (login-issues, notifications, performance, upgrade)")
+		cmd.Flags().StringVar(&ticketWorkspaceName, "ticket", "", "Support ticket the run belongs to, e.g. MM-1234: its findings are kept in the workspace of the ticket, and the AI analysis is given those of the earlier runs")
+		cmd.Flags().IntVar(&thinkingBudget, "thinking-budget", 0, "Token budget for extended thinking (Claude) or the reasoning effort of OpenAI reasoning models")
+		cmd.Flags().BoolVar(&showThinking, "show-thinking", false, "Show Claude's extended thinking before the analysis (requires --thinking-budget)")
+		cmd.Flags().StringVar(&geminiSafety, "gemini-safety", "", "Blocking threshold of the Gemini safety filters: none, only-high, medium-and-above, low-and-above (defaults to the API default)")
+		cmd.Flags().Float64Var(&temperature, "temperature", defaultTemperature, "Sampling temperature of the AI analysis, from 0 (focused) to 1, or 2 for OpenAI and Gemini")
+		cmd.Flags().Float64Var(&topP, "top-p", 0, "Nucleus sampling threshold of the AI analysis, from 0 to 1 (defaults to the provider default)")
+		cmd.Flags().IntVar(&maxOutputTokens, "max-output-tokens", defaultMaxOutputTokens, "Maximum length of the AI analysis, in tokens")
+		cmd.Flags().BoolVar(&noDocs, "no-docs", false, "Don't add passages of the Mattermost documentation to the AI analysis prompt")
+		cmd.Flags().StringVar(&docsFile, "docs-file", "", "JSON file of additional documentation passages for the AI analysis prompt")
+		cmd.Flags().BoolVar(&includeConfig, "include-config", false, "Send the sanitized configuration of the support packet with the logs for AI analysis, to propose configuration changes")
+		cmd.Flags().StringVar(&proposedChangesFile, "proposed-changes", "", "Save the configuration patch proposed by the AI analysis to this file (requires --include-config)")
+		cmd.Flags().StringVar(&llmDebugDir, "llm-debug", "", "Write the requests to the LLM provider and its raw answers to this directory, without the API key")
 		cmd.Flags().StringVar(&ollamaHost, "ollama-host", "http://localhost:11434", "Ollama server URL (only for ollama provider)")
 		cmd.Flags().IntVar(&ollamaTimeout, "ollama-timeout", 120, "Timeout in seconds for Ollama requests (only for ollama provider)")
 		cmd.Flags().BoolVar(&interactive, "interactive", false, "Launch interactive TUI mode")
@@ -299,16 +1069,82 @@ func init() {
 		cmd.Flags().BoolVar(&quiet, "quiet", false, "Only output errors")
 		cmd.Flags().BoolVar(&verboseAnalysis, "verbose-analysis", false, "Show detailed analysis with all sections")
 		cmd.Flags().BoolVar(&rawOutput, "raw", false, "Output raw log entries instead of analysis (old default behavior)")
+		cmd.Flags().IntVar(&topN, "top", 10, "Number of top sources, users, and error messages to keep in the analysis")
+		cmd.Flags().BoolVar(&fullAnalysis, "full", false, "Keep and list all sources, users, and error messages in the analysis")
+		cmd.Flags().StringVar(&activityBucket, "bucket", "", "Count the activity per hour, day, week (ISO 8601) or month over the whole time range, instead of by hour of the day, day of the week and month of the year")
+		cmd.Flags().StringArrayVar(&sloObjectives, "slo", nil, "Check a service level objective in every window of --slo-window, such as 'error_rate<1%', 'warn_rate<=5%', 'errors<10' or 'entries>0', and fail if a window violates it (can be repeated)")
+		cmd.Flags().DurationVar(&sloWindow, "slo-window", time.Hour, "Length of the windows the --slo objectives are checked over")
+		cmd.Flags().StringVar(&baselineFile, "baseline", "", "Compare the analysis against a baseline saved with 'lamp baseline save'")
+		cmd.Flags().StringVar(&saveStatsFile, "save-stats", "", "Save the statistics of the analysis to a JSON file, for the next run to compare with --diff-stats")
+		cmd.Flags().StringVar(&diffStatsFile, "diff-stats", "", "Show the changes since the run that saved the statistics with --save-stats: new, stopped and changed errors and rates")
+		cmd.Flags().BoolVar(&follow, "follow", false, "Keep reading new entries from the log files in interactive mode")
+		cmd.Flags().StringVar(&sourceDir, "source-dir", "", "Mattermost server checkout used to open log callers in $EDITOR from interactive mode")
+		cmd.Flags().StringVar(&themeName, "theme", "", "Color theme of the interactive mode (dark, light, mono; defaults to the config file theme or dark)")
+		cmd.Flags().StringVar(&mermaidFile, "mermaid", "", "Write a mermaid timeline of bursts, restarts, and key events to a file (- for stdout or the --output file)")
+		cmd.Flags().StringVar(&chartPNG, "chart-png", "", "Write the hourly activity and level distribution as a PNG chart")
+		cmd.Flags().StringVar(&pdfReport, "pdf", "", "Write a paginated PDF report with the summary, chart, top errors, findings and analysis (AI analysis with --ai-analyze)")
+		cmd.Flags().StringVar(&findingsFile, "findings", "", "Write the detected issues (rule findings, error bursts, panics) as a SARIF 2.1.0 file (- for stdout or the --output file)")
+		cmd.Flags().BoolVar(&createJira, "create-jira", false, "File a Jira ticket with the analysis and attach the findings (see --jira-url)")
+		cmd.Flags().StringVar(&jira.URL, "jira-url", "", "Jira server URL, e.g. https://example.atlassian.net")
+		cmd.Flags().StringVar(&jira.Project, "jira-project", "", "Key of the Jira project tickets are filed in")
+		cmd.Flags().StringVar(&jira.IssueType, "jira-issue-type", "Task", "Type of the Jira tickets")
+		cmd.Flags().StringVar(&jira.User, "jira-user", "", "Jira account email, for Jira Cloud API tokens (leave empty for personal access tokens)")
+		cmd.Flags().StringVar(&jira.Token, "jira-token", "", "Jira API token or personal access token")
+		cmd.Flags().StringVar(&splunk.URL, "splunk-url", "", "Send the entries to this Splunk HTTP Event Collector, e.g. https://splunk.example.com:8088")
+		cmd.Flags().StringVar(&splunk.Token, "splunk-token", "", "Token of the Splunk HTTP Event Collector")
+		cmd.Flags().StringVar(&splunk.Index, "splunk-index", "", "Splunk index of the events (defaults to the index of the token)")
+		cmd.Flags().StringVar(&splunk.Sourcetype, "splunk-sourcetype", "", "Sourcetype of the events (defaults to mattermost:server, or mattermost:notifications for notification logs)")
+		cmd.Flags().BoolVar(&createIssue, "create-issue", false, "Open a GitHub or GitLab issue with the analysis and redacted log excerpts (see --issue-repo)")
+		cmd.Flags().StringVar(&gitIssue.Repo, "issue-repo", "", "Repository issues are opened in, e.g. owner/repo or https://gitlab.com/group/project")
+		cmd.Flags().StringVar(&gitIssue.Platform, "issue-platform", "", "github or gitlab, for hosts other than github.com and gitlab hosts")
+		cmd.Flags().StringVar(&gitIssue.Token, "issue-token", "", "GitHub or GitLab token allowed to create issues")
+		cmd.Flags().StringSliceVar(&gitIssue.Labels, "issue-labels", nil, "Labels of the opened issues (comma-separated)")
+		cmd.Flags().StringSliceVar(&emailTo, "email-to", nil, "Email the analysis and the findings to these addresses, through the smtp server of the config file")
+		cmd.Flags().StringVar(&ticket.ID, "ticket-id", "", "Add the analysis as an internal note to this existing Zendesk ticket or ServiceNow record (see --ticket-system)")
+		cmd.Flags().StringVar(&ticket.System, "ticket-system", "", "Ticket system of --ticket-id: zendesk or servicenow")
+		cmd.Flags().StringVar(&ticket.URL, "ticket-url", "", "Zendesk or ServiceNow URL, e.g. https://example.zendesk.com")
+		cmd.Flags().StringVar(&ticket.User, "ticket-user", "", "Zendesk agent email or ServiceNow user name")
+		cmd.Flags().StringVar(&ticket.Token, "ticket-token", "", "Zendesk API token or ServiceNow password")
+		cmd.Flags().StringVar(&webhookURL, "webhook-url", "", "POST the analysis and the findings as JSON to this URL when processing completes")
+		cmd.Flags().StringVar(&webhookSecret, "webhook-secret", "", "Sign the --webhook-url payloads with HMAC-SHA256 in the X-Lamp-Signature header")
+		cmd.Flags().StringVar(&uploadTo, "upload", "", "Upload the written files (--output, --csv, --trim-json, --mermaid, --chart-png, --pdf, --findings) to s3://bucket/prefix/ and print download URLs")
+		cmd.Flags().DurationVar(&uploadExpires, "upload-expires", s3MaxExpiry, "How long the printed download URLs of --upload stay valid (at most 168h)")
+		cmd.Flags().BoolVar(&porcelain, "porcelain", false, "Stable, tab-separated, uncolored output for scripts, without progress bars or prompts")
+		cmd.Flags().BoolVar(&noProgress, "no-progress", false, "Report progress as plain-text lines instead of progress bars (the default when stderr is not a terminal)")
+		cmd.Flags().StringArrayVar(&ruleFiles, "rules", nil, "Load analysis rules from a file, in addition to the rule files of the config file (can be repeated)")
+		cmd.Flags().StringVar(&profileName, "profile", "", "Load flag defaults from a profile of the config file (see 'lamp profile')")
 
 		// Add custom completion for flags
 		registerFlagCompletion(cmd, "level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return []string{"debug", "info", "warn", "error", "fatal", "panic"}, cobra.ShellCompDirectiveNoFileComp
 		})
 
+		// Add theme completion
+		registerFlagCompletion(cmd, "theme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return themeNames(), cobra.ShellCompDirectiveNoFileComp
+		})
+
+		// Add profile completion from the config file
+		registerFlagCompletion(cmd, "profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			config, err := loadConfig()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			return profileNames(config), cobra.ShellCompDirectiveNoFileComp
+		})
+
 		// Add LLM provider completion
 		registerFlagCompletion(cmd, "llm-provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return []string{"anthropic", "openai", "gemini", "ollama"}, cobra.ShellCompDirectiveNoFileComp
 		})
+
+		registerFlagCompletion(cmd, "problem-template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return problemTemplateNames(), cobra.ShellCompDirectiveNoFileComp
+		})
+
+		registerFlagCompletion(cmd, "gemini-safety", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"none", "only-high", "medium-and-above", "low-and-above"}, cobra.ShellCompDirectiveNoFileComp
+		})
 		
 		// Add LLM model completion based on selected provider
 		registerFlagCompletion(cmd, "llm-model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -341,8 +1177,66 @@ func init() {
 			return nil, cobra.ShellCompDirectiveDefault
 		})
 
+		registerFlagCompletion(cmd, "mermaid", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveDefault
+		})
+
+		registerFlagCompletion(cmd, "chart-png", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"png"}, cobra.ShellCompDirectiveFilterFileExt
+		})
+
+		registerFlagCompletion(cmd, "pdf", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"pdf"}, cobra.ShellCompDirectiveFilterFileExt
+		})
+
+		registerFlagCompletion(cmd, "findings", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveDefault
+		})
+		registerFlagCompletion(cmd, "llm-debug", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		})
+		registerFlagCompletion(cmd, "proposed-changes", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		})
+		registerFlagCompletion(cmd, "docs-file", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		})
+
+		registerFlagCompletion(cmd, "jira-issue-type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"Task", "Bug", "Story", "Incident"}, cobra.ShellCompDirectiveNoFileComp
+		})
+
+		registerFlagCompletion(cmd, "issue-platform", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"github", "gitlab"}, cobra.ShellCompDirectiveNoFileComp
+		})
+
+		registerFlagCompletion(cmd, "ticket-system", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"zendesk", "servicenow"}, cobra.ShellCompDirectiveNoFileComp
+		})
+
+		registerFlagCompletion(cmd, "source-dir", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		})
+
+		registerFlagCompletion(cmd, "rules", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveDefault
+		})
+
+		registerFlagCompletion(cmd, "bucket", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return activityBuckets, cobra.ShellCompDirectiveNoFileComp
+		})
+
+		registerFlagCompletion(cmd, "baseline", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		})
+		for _, flag := range []string{"save-stats", "diff-stats"} {
+			registerFlagCompletion(cmd, flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+			})
+		}
+
 		// Add boolean flag completion
-		for _, flag := range []string{"json", "analyze", "ai-analyze", "trim", "interactive", "verbose", "quiet", "verbose-analysis", "raw"} {
+		for _, flag := range []string{"json", "ndjson", "analyze", "ai-analyze", "trim", "interactive", "verbose", "quiet", "verbose-analysis", "raw", "full", "follow", "porcelain", "no-progress", "create-jira", "create-issue", "show-thinking"} {
 			registerFlagCompletion(cmd, flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 				return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
 			})
@@ -358,7 +1252,19 @@ func init() {
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	started := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	// The inputs that could not be read are listed even if the command failed for lack of them
+	if summaryErr := inputErrors.finish(os.Stderr); err == nil {
+		err = summaryErr
+	}
+	if runSummary != "" {
+		summary := runStats.summary(cmd.Name(), time.Since(started), err)
+		if summaryErr := writeRunSummary(summary, runSummary, os.Stderr); summaryErr != nil && err == nil {
+			err = summaryErr
+		}
+	}
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -374,11 +1280,100 @@ func contains(slice []string, str string) bool {
 	return false
 }
 
+// analysisTopLimit returns the number of top items to keep in the analysis,
+// where 0 means all of them (--full)
+func analysisTopLimit() int {
+	if fullAnalysis {
+		return 0
+	}
+	return topN
+}
+
 // processLogs handles the common log processing logic
 func processLogs(logs []LogEntry) error {
 	// Note: Filtering is already applied during log parsing in parseLogFile
 	// so by the time logs reach this function, they're already filtered
 	
+	if topN < 1 {
+		return fmt.Errorf("--top must be at least 1, got %d", topN)
+	}
+	if err := validateActivityBucket(activityBucket); err != nil {
+		return err
+	}
+	slos, err := parseSLOs(sloObjectives)
+	if err != nil {
+		return err
+	}
+	if len(slos) > 0 && (interactive || rawOutput || jsonOutput || ndjsonOutput || csvOutput != "" || summarize || securityReport || aiAnalyze) {
+		return fmt.Errorf("--slo is checked by the statistical analysis, it cannot be used with --interactive, --raw, --json, --ndjson, --csv, --summarize, --security-report or --ai-analyze")
+	}
+	if sloWindow <= 0 {
+		return fmt.Errorf("--slo-window must be positive, got %s", sloWindow)
+	}
+	if ticketWorkspaceName != "" {
+		if err := validateTicketName(ticketWorkspaceName); err != nil {
+			return err
+		}
+	}
+	if follow && !interactive {
+		return fmt.Errorf("--follow requires --interactive")
+	}
+	if follow && len(followFiles) == 0 {
+		return fmt.Errorf("--follow is only supported for log files")
+	}
+	if includeConfig && !aiAnalyze {
+		return fmt.Errorf("--include-config requires --ai-analyze")
+	}
+	if includeConfig && serverConfig == "" {
+		return fmt.Errorf("--include-config is only supported for support packets")
+	}
+	if proposedChangesFile != "" && !includeConfig {
+		return fmt.Errorf("--proposed-changes requires --include-config")
+	}
+	if integrations := reportIntegrations(); len(integrations) > 0 && (interactive || rawOutput || jsonOutput || ndjsonOutput || csvOutput != "") {
+		return fmt.Errorf("%s files the analysis, it cannot be used with --interactive, --raw, --json, --ndjson or --csv", integrations[0])
+	}
+	if createJira {
+		if err := jira.validate(); err != nil {
+			return err
+		}
+	}
+	if createIssue {
+		if err := gitIssue.validate(); err != nil {
+			return err
+		}
+	}
+	if ticket.ID != "" {
+		if err := ticket.validate(); err != nil {
+			return err
+		}
+	}
+	if webhookURL != "" {
+		if err := validateWebhookURL(webhookURL); err != nil {
+			return err
+		}
+	}
+	if splunk.URL != "" {
+		if interactive {
+			return fmt.Errorf("--splunk-url cannot be used with --interactive")
+		}
+		if err := splunk.validate(); err != nil {
+			return err
+		}
+	}
+	var smtpSettings SMTPSettings
+	if len(emailTo) > 0 {
+		var err error
+		if smtpSettings, err = loadSMTPSettings(); err != nil {
+			return err
+		}
+	}
+	if uploadTo != "" {
+		if err := validateUpload(); err != nil {
+			return err
+		}
+	}
+
 	// Check for AI analysis and API key first
 	if aiAnalyze {
 		// Get provider from flag
@@ -392,12 +1387,11 @@ func processLogs(logs []LogEntry) error {
 			// Get key from flag or env
 			apiKeyValue := apiKey
 			if apiKeyValue == "" {
-				envVar := getAPIKeyEnvVar(provider)
-				apiKeyValue = os.Getenv(envVar)
+				apiKeyValue = lookupAPIKey(provider)
 				
 				if apiKeyValue == "" {
-					return fmt.Errorf("%s API key is required for AI analysis. Set with --api-key or %s environment variable", 
-						provider, envVar)
+					return fmt.Errorf("%s API key is required for AI analysis. Set with --api-key, the %s environment variable, 'lamp auth set', or 'lamp init'", 
+						provider, getAPIKeyEnvVar(provider))
 				}
 			}
 		}
@@ -421,6 +1415,24 @@ func processLogs(logs []LogEntry) error {
 		}
 	}
 
+	// Load baseline early so a bad path fails before any work is done
+	var baseline *Baseline
+	if baselineFile != "" {
+		loaded, err := loadBaseline(baselineFile)
+		if err != nil {
+			return fmt.Errorf("error loading baseline: %v", err)
+		}
+		baseline = &loaded
+	}
+	var previousStats *Baseline
+	if diffStatsFile != "" {
+		loaded, err := loadBaseline(diffStatsFile)
+		if err != nil {
+			return fmt.Errorf("error loading the statistics of the previous run: %v", err)
+		}
+		previousStats = &loaded
+	}
+
 	// Set output destination
 	output := os.Stdout
 	if outputFile != "" {
@@ -430,90 +1442,444 @@ func processLogs(logs []LogEntry) error {
 		}
 		defer func() { _ = file.Close() }()
 		output = file
-		fmt.Printf("Writing output to %s\n", outputFile)
+		runStats.output(outputFile)
+		logger.Info("Writing output", "file", outputFile)
 	}
 
 	// Handle interactive mode
 	if interactive {
-		return launchInteractiveMode(logs)
+		var analysis *aiAnalysis
+		if aiAnalyze {
+			config, err := newLLMConfig(logs, output)
+			if err != nil {
+				return err
+			}
+			if analysis, err = runInteractiveAnalysis(logs, config); err != nil {
+				return fmt.Errorf("error during LLM analysis: %v", err)
+			}
+		}
+		return launchInteractiveMode(logs, followFiles, analysis)
+	}
+
+	// Export mermaid timeline if requested
+	if mermaidFile != "" {
+		if err := exportMermaidTimeline(analyzeLogs(logs, !trim, analysisTopLimit()), mermaidFile, output); err != nil {
+			return fmt.Errorf("error writing mermaid timeline: %v", err)
+		}
+		if mermaidFile != "-" {
+			_, _ = fmt.Fprintf(output, "Mermaid timeline written to %s\n", mermaidFile)
+		}
+	}
+
+	// Export the PNG chart if requested
+	if chartPNG != "" {
+		if err := exportChartPNG(analyzeLogs(logs, !trim, analysisTopLimit()), chartPNG); err != nil {
+			return fmt.Errorf("error writing chart: %v", err)
+		}
+		_, _ = fmt.Fprintf(output, "Chart written to %s\n", chartPNG)
+	}
+
+	// Export findings if requested
+	if findingsFile != "" {
+		if err := exportFindings(analyzeLogs(logs, !trim, analysisTopLimit()), logs, findingsFile, output); err != nil {
+			return fmt.Errorf("error writing findings: %v", err)
+		}
+		if findingsFile != "-" {
+			_, _ = fmt.Fprintf(output, "Findings written to %s\n", findingsFile)
+		}
+	}
+
+	// Save the statistics for the next run if requested
+	if saveStatsFile != "" {
+		var files []string
+		if loadedSource != nil {
+			files = loadedSource.Paths
+		}
+		if err := saveStats(logs, files, saveStatsFile); err != nil {
+			return fmt.Errorf("error saving the statistics: %v", err)
+		}
+		_, _ = fmt.Fprintf(output, "Statistics saved to %s\n", saveStatsFile)
+	}
+
+	// Send the entries to Splunk if requested
+	if splunk.URL != "" {
+		if err := sendToSplunk(splunk, eachEntry(logs), output); err != nil {
+			return err
+		}
 	}
 
 	// Export to CSV if requested
 	if csvOutput != "" {
-		if err := exportToCSV(logs, csvOutput); err != nil {
+		options, err := csvExportOptions()
+		if err != nil {
+			return err
+		}
+		if err := exportToCSV(logs, csvOutput, options); err != nil {
 			return fmt.Errorf("error exporting to CSV: %v", err)
 		}
-		fmt.Printf("Logs exported to CSV file: %s\n", csvOutput)
-		return nil
+		_, _ = fmt.Fprintf(output, "Logs exported to CSV file: %s\n", csvOutput)
+		return uploadWrittenArtifacts()
+	}
+
+	// Keep the analysis displayed for the ticket, if one is filed
+	var reportMarkdown string
+	var statsOutput strings.Builder
+	analysisOutput := io.Writer(output)
+	if len(reportIntegrations()) > 0 {
+		analysisOutput = io.MultiWriter(output, &statsOutput)
 	}
 
+	// Check the service level objectives over the windows of the logs
+	sloResults := evaluateSLOs(slos, logs, sloWindow, !trim)
+
 	// Display logs in the requested format
+	var analyzedProblem string
+	var aiFindings []AIFinding
 	switch {
 	case aiAnalyze:
-		// Get provider from flag (we already validated the API key above)
-		// Validate llmProvider flag
-		supportedProviders := []string{"anthropic", "openai", "gemini", "ollama"}
-		if !contains(supportedProviders, llmProvider) {
-			return fmt.Errorf("invalid LLM provider: %s. Supported providers are: %s", llmProvider, strings.Join(supportedProviders, ", "))
-		}
-		
-		// If using Ollama, set the Ollama-related variables from the flags
-		if llmProvider == "ollama" {
-			// Set the package's Ollama variables to the values from the flags
-			OllamaHost = ollamaHost
-			OllamaTimeout = ollamaTimeout
-		}
-		
-		provider := LLMProvider(llmProvider)
-		apiKeyValue := apiKey
-		// Only get API key for providers that need one
-		if provider != ProviderOllama && apiKeyValue == "" {
-			apiKeyValue = os.Getenv(getAPIKeyEnvVar(provider))
+		config, err := newLLMConfig(logs, output)
+		if err != nil {
+			return err
 		}
-		
-		// If trim was used, ask if user wants to send all remaining lines
-		entriesForAnalysis := maxEntries
-		if trim {
-			fmt.Printf("After trimming, there are %d log entries. Would you like to analyze all of them? (y/n): ", len(logs))
-			var response string
-			_, err := fmt.Scanln(&response)
-			if err != nil {
-				// Default to 'no' if there's an error with input
-				response = "n"
+		config.Findings = true
+		config.ServerConfig = serverConfig
+		analyzedProblem = config.Problem
+		config.Output = func(analysisText string) error {
+			analysisText, changes := splitProposedChanges(analysisText)
+			if _, findings, err := parseAIFindings(analysisText); err == nil {
+				aiFindings = findings
 			}
-			
-			if strings.ToLower(response) == "y" || strings.ToLower(response) == "yes" {
-				entriesForAnalysis = len(logs)
+			reportMarkdown = withCitedEvidence(analysisText, logsForAnalysis(logs, config.MaxEntries))
+			if changes != nil {
+				reportMarkdown += "\n\n" + proposedChangesMarkdown(changes, serverConfig)
 			}
+			if err := displayAndCopyAnalysis(reportMarkdown, config.writer()); err != nil {
+				return err
+			}
+			if proposedChangesFile == "" {
+				return nil
+			}
+			if changes == nil {
+				_, _ = fmt.Fprintln(config.writer(), "The analysis proposed no configuration changes")
+				return nil
+			}
+			if err := saveProposedChanges(changes, proposedChangesFile); err != nil {
+				return fmt.Errorf("error saving the proposed changes: %v", err)
+			}
+			_, _ = fmt.Fprintf(config.writer(), "Proposed changes saved to %s\n", proposedChangesFile)
+			return nil
 		}
-		
-		// Configure LLM settings
-		model := llmModel
-		if model == "" {
-			model = GetDefaultModel(provider)
-		}
-		config := LLMConfig{
-			Provider:       provider,
-			Model:          model,
-			APIKey:         apiKeyValue,
-			MaxEntries:     entriesForAnalysis,
-			Problem:        problem,
-			ThinkingBudget: thinkingBudget,
-		}
-		
 		if err := analyzeWithLLM(logs, config); err != nil {
 			return fmt.Errorf("error during LLM analysis: %v", err)
 		}
+	case securityReport:
+		markdown, err := displaySecurityReport(logs, jsonOutput, output)
+		if err != nil {
+			return fmt.Errorf("error writing the security report: %v", err)
+		}
+		reportMarkdown = markdown
+	case summarize:
+		displaySummary(logs, analysisOutput)
 	case analyze:
-		analyzeAndDisplayStats(logs, output, !trim, verboseAnalysis)
+		displayStats(logs, analysisOutput, baseline, previousStats, sloResults)
+	case ndjsonOutput:
+		if err := writeLogsNDJSON(logs, output); err != nil {
+			return fmt.Errorf("error writing JSON Lines: %v", err)
+		}
 	case jsonOutput:
 		displayLogsJSON(logs, output)
+	case rawOutput && porcelain:
+		displayLogsPorcelain(logs, output)
 	case rawOutput:
 		displayLogsPretty(logs, output)
 	default:
 		// Default to compact analysis instead of dumping all logs
-		analyzeAndDisplayStats(logs, output, !trim, verboseAnalysis)
+		displayStats(logs, analysisOutput, baseline, previousStats, sloResults)
+	}
+
+	// Keep the findings of the run in the workspace of the ticket
+	if ticketWorkspaceName != "" {
+		var command string
+		var paths []string
+		if loadedSource != nil {
+			command, paths = loadedSource.Command, loadedSource.Paths
+		}
+		workspace, err := recordTicketRun(ticketWorkspaceName, newTicketRun(command, paths, logs, analyzedProblem, aiFindings))
+		if err != nil {
+			return fmt.Errorf("error recording the run for ticket %s: %v", ticketWorkspaceName, err)
+		}
+		_, _ = fmt.Fprintf(output, "Findings recorded for ticket %s (%s, see 'lamp ticket show %s')\n", ticketWorkspaceName,
+			countNoun(len(workspace.Runs), "run", "runs"), ticketWorkspaceName)
+	}
+
+	if len(reportIntegrations()) > 0 {
+		if reportMarkdown == "" {
+			reportMarkdown = statsMarkdown(statsOutput.String())
+		}
+		report := newRunReport(analyzeLogs(logs, !trim, analysisTopLimit()), logs, reportMarkdown)
+		if createJira {
+			if err := createJiraTicket(jira, report, output); err != nil {
+				return err
+			}
+		}
+		if createIssue {
+			if err := createGitIssue(gitIssue, report, output); err != nil {
+				return err
+			}
+		}
+		if len(emailTo) > 0 {
+			if err := sendEmail(smtpSettings, emailTo, report, output); err != nil {
+				return err
+			}
+		}
+		if ticket.ID != "" {
+			if err := addTicketNote(ticket, report, output); err != nil {
+				return err
+			}
+		}
+		if webhookURL != "" {
+			if err := postWebhook(webhookURL, webhookSecret, report, output); err != nil {
+				return err
+			}
+		}
+		if pdfReport != "" {
+			if err := exportPDFReport(report, pdfReport, output); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := uploadWrittenArtifacts(); err != nil {
+		return err
+	}
+	return sloViolationError(sloResults)
+}
+
+// artifactFiles returns the files written by the command, which --upload uploads
+func artifactFiles() []string {
+	var files []string
+	for _, file := range []string{outputFile, csvOutput, mermaidFile, chartPNG, pdfReport, findingsFile} {
+		if file != "" && file != "-" {
+			files = append(files, file)
+		}
+	}
+	if trim && trimJSON != "" {
+		files = append(files, trimJSON)
+	}
+	return files
+}
+
+// validateUpload checks the --upload location and credentials before any work is done
+func validateUpload() error {
+	if interactive {
+		return fmt.Errorf("--upload cannot be used with --interactive")
+	}
+	if len(artifactFiles()) == 0 {
+		return fmt.Errorf("--upload requires a file to upload, written with --output, --csv, --trim-json, --mermaid, --chart-png, --pdf or --findings")
+	}
+	if uploadExpires <= 0 || uploadExpires > s3MaxExpiry {
+		return fmt.Errorf("--upload-expires must be between 1s and %s, got %s", s3MaxExpiry, uploadExpires)
+	}
+	if _, err := parseS3Location(uploadTo); err != nil {
+		return err
+	}
+	_, err := newS3ClientFromEnv()
+	return err
+}
+
+// uploadWrittenArtifacts uploads the files written by the command if --upload is set. The
+// download URLs go to stdout, since the --output file is one of the uploaded files.
+func uploadWrittenArtifacts() error {
+	if uploadTo == "" {
+		return nil
+	}
+	return uploadArtifacts(uploadTo, artifactFiles(), uploadExpires, os.Stdout)
+}
+
+// reportIntegrations returns the flags of the integrations the analysis is filed with
+func reportIntegrations() []string {
+	var integrations []string
+	if createJira {
+		integrations = append(integrations, "--create-jira")
+	}
+	if createIssue {
+		integrations = append(integrations, "--create-issue")
+	}
+	if len(emailTo) > 0 {
+		integrations = append(integrations, "--email-to")
+	}
+	if ticket.ID != "" {
+		integrations = append(integrations, "--ticket-id")
+	}
+	if webhookURL != "" {
+		integrations = append(integrations, "--webhook-url")
+	}
+	if pdfReport != "" {
+		integrations = append(integrations, "--pdf")
+	}
+	return integrations
+}
+
+// displayStats writes the analysis of the logs, the deviations from the baseline, the
+// changes since the previous run and the service level objectives, if any
+func displayStats(logs []LogEntry, output io.Writer, baseline, previousStats *Baseline, sloResults []SLOResult) {
+	if !porcelain {
+		analyzeAndDisplayStats(logs, output, !trim, verboseAnalysis, analysisTopLimit(), fullAnalysis)
+		if baseline != nil {
+			displayBaselineComparison(*baseline, compareWithBaseline(*baseline, logs), output)
+		}
+		if previousStats != nil {
+			displayStatsDiff(diffStats(*previousStats, logs), output)
+		}
+		displaySLOResults(sloResults, sloWindow, output)
+		displayPacketDiagnostics(packetDiagnostics, output, verboseAnalysis)
+		return
 	}
 
+	if len(logs) == 0 {
+		writePorcelainRecord(output, "entries", "0")
+	} else {
+		displayAnalysisPorcelain(analyzeLogs(logs, !trim, analysisTopLimit()), len(logs), output)
+		if baseline != nil {
+			displayBaselineComparisonPorcelain(compareWithBaseline(*baseline, logs), output)
+		}
+		if previousStats != nil {
+			displayStatsDiffPorcelain(diffStats(*previousStats, logs), output)
+		}
+		displaySLOResultsPorcelain(sloResults, output)
+	}
+	displayPacketDiagnosticsPorcelain(packetDiagnostics, output)
+}
+
+// newLLMConfig returns the AI analysis settings of the command line flags, writing to output.
+// When the logs were trimmed, it asks whether to analyze all remaining entries.
+func newLLMConfig(logs []LogEntry, output io.Writer) (LLMConfig, error) {
+	// Get provider from flag (we already validated the API key above)
+	// Validate llmProvider flag
+	supportedProviders := []string{"anthropic", "openai", "gemini", "ollama"}
+	if !contains(supportedProviders, llmProvider) {
+		return LLMConfig{}, fmt.Errorf("invalid LLM provider: %s. Supported providers are: %s", llmProvider, strings.Join(supportedProviders, ", "))
+	}
+	
+	// If using Ollama, set the Ollama-related variables from the flags
+	if llmProvider == "ollama" {
+		// Set the package's Ollama variables to the values from the flags
+		OllamaHost = ollamaHost
+		OllamaTimeout = ollamaTimeout
+	}
+	
+	if _, err := geminiSafetySettings(geminiSafety); err != nil {
+		return LLMConfig{}, err
+	}
+	if showThinking && thinkingBudget <= 0 {
+		return LLMConfig{}, fmt.Errorf("--show-thinking requires --thinking-budget")
+	}
+	if err := validateGenerationFlags(LLMProvider(llmProvider)); err != nil {
+		return LLMConfig{}, err
+	}
+
+	provider := LLMProvider(llmProvider)
+	apiKeyValue := apiKey
+	// Only get API key for providers that need one
+	if provider != ProviderOllama && apiKeyValue == "" {
+		apiKeyValue = lookupAPIKey(provider)
+	}
+	
+	// If trim was used, ask if user wants to send all remaining lines
+	entriesForAnalysis := maxEntries
+	if trim && canPrompt() {
+		fmt.Printf("After trimming, there are %d log entries. Would you like to analyze all of them? (y/n): ", len(logs))
+		var response string
+		_, err := fmt.Scanln(&response)
+		if err != nil {
+			// Default to 'no' if there's an error with input
+			response = "n"
+		}
+		
+		if strings.ToLower(response) == "y" || strings.ToLower(response) == "yes" {
+			entriesForAnalysis = len(logs)
+		}
+	}
+	
+	// Configure LLM settings
+	model := llmModel
+	if model == "" {
+		model = GetDefaultModel(provider)
+	}
+	config := LLMConfig{
+		Provider:       provider,
+		Model:          model,
+		APIKey:         apiKeyValue,
+		MaxEntries:     entriesForAnalysis,
+		Problem:        problem,
+		ThinkingBudget: thinkingBudget,
+		ShowThinking:   showThinking,
+		GeminiSafety:   geminiSafety,
+		Writer:         output,
+
+		Temperature:     &temperature,
+		TopP:            topP,
+		MaxOutputTokens: maxOutputTokens,
+		DebugDir:        llmDebugDir,
+	}
+	if template, ok := findProblemTemplate(problemTemplateName); ok {
+		config.Problem = template.expand(problem)
+		config.Focus = template.focus
+	}
+	if ticketWorkspaceName != "" {
+		workspace, _, err := loadTicketWorkspace(ticketWorkspaceName)
+		if err != nil {
+			return LLMConfig{}, err
+		}
+		config.TicketContext = workspace.priorContext()
+	}
+	if !noDocs {
+		config.Docs = mattermostDocs
+		if docsFile != "" {
+			passages, err := loadDocPassages(docsFile)
+			if err != nil {
+				return LLMConfig{}, err
+			}
+			config.Docs = append(append([]docPassage{}, mattermostDocs...), passages...)
+		}
+	}
+
+	return config, nil
+}
+
+// validateGenerationFlags checks --temperature, --top-p and --max-output-tokens against the
+// ranges the provider accepts
+func validateGenerationFlags(provider LLMProvider) error {
+	maxTemperature := 2.0
+	if provider == ProviderAnthropic {
+		maxTemperature = 1.0
+	}
+	if temperature < 0 || temperature > maxTemperature {
+		return fmt.Errorf("invalid --temperature %g: %s accepts 0 to %g", temperature, provider, maxTemperature)
+	}
+	if topP < 0 || topP > 1 {
+		return fmt.Errorf("invalid --top-p %g: expected 0 to 1", topP)
+	}
+	if maxOutputTokens <= 0 {
+		return fmt.Errorf("invalid --max-output-tokens %d: expected a positive number", maxOutputTokens)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// canPrompt reports whether questions can be asked on the terminal, which is not the case
+// for scripts (--porcelain) or when the output goes to a file (--output)
+func canPrompt() bool {
+	return !porcelain && outputFile == ""
+}
+
+// currentVersion returns the version of the running binary: the release version, the module
+// version for 'go install' builds, or "dev"
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -345,4 +349,105 @@ func TestMultiFileCommand(t *testing.T) {
 		assert.Contains(t, output, "Thu")
 		assert.Contains(t, output, "Fri")
 	})
-}
\ No newline at end of file
+}
+
+// captureStdout returns what fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	fn()
+	require.NoError(t, w.Close())
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestOutputModes(t *testing.T) {
+	initLogger()
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "Server started", Source: "app/server.go:10"},
+		{Timestamp: ts.Add(time.Minute), Level: "error", Message: "Connection failed", Source: "app/conn.go:20"},
+	}
+
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(OllamaResponse{Message: OllamaMessage{Role: "assistant", Content: "The connection failed once."}})
+	}))
+	defer ollama.Close()
+
+	modes := []struct {
+		name     string
+		set      func(dir string)
+		expected []string
+	}{
+		{"analysis", func(string) {}, []string{"Connection failed", "ERROR"}},
+		{"analyze", func(string) { analyze = true }, []string{"Connection failed", "ERROR"}},
+		{"json", func(string) { jsonOutput = true }, []string{`"message": "Connection failed"`}},
+		{"raw", func(string) { rawOutput = true }, []string{"Server started", "Connection failed"}},
+		{"porcelain raw", func(string) { rawOutput, porcelain = true, true }, []string{"2025-01-01T10:01:00Z\terror\tapp/conn.go:20\t\t1\tConnection failed"}},
+		{"porcelain analysis", func(string) { porcelain = true }, []string{"entries\t2", "level\tERROR\t1"}},
+		{"csv", func(dir string) { csvOutput = filepath.Join(dir, "logs.csv") }, []string{"Logs exported to CSV file"}},
+		{"mermaid", func(string) { mermaidFile = "-" }, []string{"gantt", "First errors"}},
+		{"chart png", func(dir string) { chartPNG = filepath.Join(dir, "chart.png") }, []string{"Chart written to"}},
+		{"pdf", func(dir string) { pdfReport = filepath.Join(dir, "report.pdf") }, []string{"Connection failed", "PDF report written to"}},
+		{"slo", func(string) { sloObjectives = []string{"error_rate<=50%"} }, []string{"SERVICE LEVEL OBJECTIVES", "error_rate<=50%: met (1 window)"}},
+		{"ai analysis", func(string) {
+			aiAnalyze, llmProvider, ollamaHost, ollamaTimeout = true, "ollama", ollama.URL, 5
+			temperature, topP, maxOutputTokens, thinkingBudget, geminiSafety = defaultTemperature, 0, defaultMaxOutputTokens, 0, ""
+		}, []string{"Analyzing logs with ollama", "# LLM LOG ANALYSIS", "The connection failed once."}},
+	}
+
+	for _, mode := range modes {
+		for _, toFile := range []bool{false, true} {
+			name := mode.name + " to stdout"
+			if toFile {
+				name = mode.name + " to file"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				// Restore the flags the modes change, so that later tests keep their defaults
+				oldAnalyze, oldJSON, oldRaw, oldPorcelain, oldAI := analyze, jsonOutput, rawOutput, porcelain, aiAnalyze
+				oldCSV, oldMermaid, oldChart, oldPDF, oldOutputFile := csvOutput, mermaidFile, chartPNG, pdfReport, outputFile
+				oldProvider, oldHost, oldTimeout, oldOllamaHost, oldOllamaTimeout := llmProvider, ollamaHost, ollamaTimeout, OllamaHost, OllamaTimeout
+				oldTemperature, oldTopP, oldMaxOutputTokens, oldThinking, oldSafety := temperature, topP, maxOutputTokens, thinkingBudget, geminiSafety
+				oldSLO, oldTopN := sloObjectives, topN
+				t.Cleanup(func() {
+					analyze, jsonOutput, rawOutput, porcelain, aiAnalyze = oldAnalyze, oldJSON, oldRaw, oldPorcelain, oldAI
+					csvOutput, mermaidFile, chartPNG, pdfReport, outputFile = oldCSV, oldMermaid, oldChart, oldPDF, oldOutputFile
+					llmProvider, ollamaHost, ollamaTimeout, OllamaHost, OllamaTimeout = oldProvider, oldHost, oldTimeout, oldOllamaHost, oldOllamaTimeout
+					temperature, topP, maxOutputTokens, thinkingBudget, geminiSafety = oldTemperature, oldTopP, oldMaxOutputTokens, oldThinking, oldSafety
+					sloObjectives, topN = oldSLO, oldTopN
+				})
+				topN = 10
+				mode.set(dir)
+				if toFile {
+					outputFile = filepath.Join(dir, "output.txt")
+				}
+
+				stdout := captureStdout(t, func() {
+					require.NoError(t, processLogs(logs))
+				})
+
+				written := stdout
+				if toFile {
+					content, err := os.ReadFile(outputFile)
+					require.NoError(t, err)
+					written = string(content)
+					assert.Empty(t, stdout, "nothing goes to stdout with --output")
+				}
+				for _, expected := range mode.expected {
+					assert.Contains(t, written, expected)
+				}
+				if toFile {
+					assert.NotContains(t, written, "clipboard", "no questions are asked with --output")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// mermaidDateFormat is the Go layout matching the mermaid dateFormat used in the timeline
+const mermaidDateFormat = "2006-01-02 15:04:05"
+
+// mermaidMaxSignatures is the maximum number of error signatures shown as milestones
+const mermaidMaxSignatures = 5
+
+// serverStartMessages are messages logged when a Mattermost server starts
+var serverStartMessages = []string{
+	"server is initializing",
+	"starting server",
+	"current version is",
+}
+
+// detectRestarts returns the timestamps of server starts found in the logs. Several start
+// messages logged within a minute of each other count as a single start.
+func detectRestarts(logs []LogEntry) []time.Time {
+	var restarts []time.Time
+	for _, log := range logs {
+		message := strings.ToLower(log.Message)
+		for _, startMessage := range serverStartMessages {
+			if !strings.Contains(message, startMessage) {
+				continue
+			}
+			if len(restarts) == 0 || log.Timestamp.Sub(restarts[len(restarts)-1]) > time.Minute {
+				restarts = append(restarts, log.Timestamp)
+			}
+			break
+		}
+	}
+	return restarts
+}
+
+// mermaidLabel makes text safe to use as a mermaid gantt task name
+func mermaidLabel(text string) string {
+	replacer := strings.NewReplacer(":", " ", "#", "", ";", ",", "\n", " ", "\"", "'")
+	return truncateString(strings.TrimSpace(replacer.Replace(text)), 60)
+}
+
+// writeMermaidTimeline writes a mermaid gantt chart of error bursts, logging gaps,
+// restarts and first occurrences of error signatures
+func writeMermaidTimeline(analysis LogAnalysis, writer io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("```mermaid\n")
+	sb.WriteString("gantt\n")
+	sb.WriteString("    title Mattermost log timeline\n")
+	sb.WriteString("    dateFormat YYYY-MM-DD HH:mm:ss\n")
+	if analysis.TimeRange.End.Sub(analysis.TimeRange.Start) > 48*time.Hour {
+		sb.WriteString("    axisFormat %m-%d\n")
+	} else {
+		sb.WriteString("    axisFormat %H:%M\n")
+	}
+
+	sb.WriteString("    section Log\n")
+	sb.WriteString(fmt.Sprintf("    Time range :%s, %s\n",
+		analysis.TimeRange.Start.Format(mermaidDateFormat),
+		mermaidEnd(analysis.TimeRange.Start, analysis.TimeRange.End)))
+
+	if len(analysis.Restarts) > 0 {
+		sb.WriteString("    section Restarts\n")
+		for _, restart := range analysis.Restarts {
+			sb.WriteString(fmt.Sprintf("    Server start :milestone, %s, 0s\n", restart.Format(mermaidDateFormat)))
+		}
+	}
+
+	if len(analysis.Incidents.ErrorBursts) > 0 {
+		sb.WriteString("    section Error bursts\n")
+		for _, burst := range analysis.Incidents.ErrorBursts {
+			sb.WriteString(fmt.Sprintf("    %d errors :crit, %s, %s\n",
+				burst.Count, burst.Start.Format(mermaidDateFormat), mermaidEnd(burst.Start, burst.End)))
+		}
+	}
+
+	if len(analysis.LoggingGaps) > 0 {
+		sb.WriteString("    section Logging gaps\n")
+		for _, gap := range analysis.LoggingGaps {
+			sb.WriteString(fmt.Sprintf("    No logs for %s :done, %s, %s\n",
+				gap.Duration.Round(time.Second), gap.Start.Format(mermaidDateFormat), mermaidEnd(gap.Start, gap.End)))
+		}
+	}
+
+	if len(analysis.ErrorSignatures) > 0 {
+		sb.WriteString("    section First errors\n")
+		for i, sig := range analysis.ErrorSignatures {
+			if i >= mermaidMaxSignatures {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("    %s :milestone, %s, 0s\n",
+				mermaidLabel(sig.Example), sig.FirstSeen.Format(mermaidDateFormat)))
+		}
+	}
+
+	sb.WriteString("```\n")
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+// mermaidEnd formats the end of a task, ensuring tasks last at least a second so they render
+func mermaidEnd(start, end time.Time) string {
+	if !end.After(start) {
+		return "1s"
+	}
+	return end.Format(mermaidDateFormat)
+}
+
+// exportMermaidTimeline writes the mermaid timeline to a file, or to output when filePath is "-"
+func exportMermaidTimeline(analysis LogAnalysis, filePath string, output io.Writer) error {
+	if filePath == "-" {
+		return writeMermaidTimeline(analysis, output)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	return writeMermaidTimeline(analysis, file)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMermaidTimeline(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+
+	logs := []LogEntry{
+		{Timestamp: start, Level: "info", Message: "Server is initializing..."},
+		{Timestamp: start.Add(10 * time.Second), Level: "info", Message: "Current version is 9.11.0"},
+	}
+	for i := 0; i < 6; i++ {
+		logs = append(logs, LogEntry{Timestamp: start.Add(10*time.Minute + time.Duration(i)*time.Second), Level: "error", Message: "Failed to ping DB: timeout"})
+	}
+	logs = append(logs, LogEntry{Timestamp: start.Add(30 * time.Minute), Level: "info", Message: "Server is initializing..."})
+
+	analysis := analyzeLogs(logs, false, 10)
+	require.Len(t, analysis.Restarts, 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeMermaidTimeline(analysis, &buf))
+	output := buf.String()
+
+	assert.Contains(t, output, "```mermaid\ngantt\n")
+	assert.Contains(t, output, "Server start :milestone, 2025-01-01 10:00:00, 0s")
+	assert.Contains(t, output, "6 errors :crit, 2025-01-01 10:10:00, 2025-01-01 10:10:05")
+	assert.Contains(t, output, "Failed to ping DB  timeout :milestone, 2025-01-01 10:10:00, 0s")
+	assert.Contains(t, output, "section Logging gaps")
+}
@@ -77,6 +77,27 @@ var ProviderModels = map[LLMProvider][]ModelInfo{
 			MaxTokens:   4000, 
 			IsDefault:   false,
 		},
+		{
+			ID:          "o4-mini",
+			Name:        "o4-mini",
+			Description: "Fast reasoning model, effort set with --thinking-budget",
+			MaxTokens:   24000,
+			IsDefault:   false,
+		},
+		{
+			ID:          "o3",
+			Name:        "o3",
+			Description: "Most capable reasoning model for complex analysis",
+			MaxTokens:   24000,
+			IsDefault:   false,
+		},
+		{
+			ID:          "o3-mini",
+			Name:        "o3-mini",
+			Description: "Cost-effective reasoning model",
+			MaxTokens:   24000,
+			IsDefault:   false,
+		},
 	},
 	ProviderGemini: {
 		{
@@ -3,15 +3,17 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/schollz/progressbar/v3"
 )
 
 // LogEntry represents a parsed log entry from Mattermost logs
@@ -27,6 +29,43 @@ type LogEntry struct {
 	Status         string            `json:"status,omitempty"`     // For notifications: delivery status
 	Extras         map[string]string `json:"extras,omitempty"`
 	DuplicateCount int               `json:"duplicate_count,omitempty"`
+	NodeCounts     map[string]int    `json:"node_counts,omitempty"` // Occurrences per node, when the duplicates came from several nodes
+	LastSeen       *time.Time        `json:"last_seen,omitempty"`   // Latest occurrence of a deduplicated entry, Timestamp being the earliest
+	Raw            string            `json:"-"`                     // Original log line, shown in interactive mode
+	SourceFile     string            `json:"source_file,omitempty"` // File the entry was read from, its path in the packet for support packets
+	Node           string            `json:"node,omitempty"`        // Cluster node of the support packet the entry was read from
+	Line           int               `json:"-"`                     // Line of the entry in SourceFile, 0 if unknown
+	Seq            int64             `json:"-"`                     // Order the entry was read in, breaking ties between simultaneous entries
+}
+
+// entrySeq numbers the entries in the order they are read, across all parsed files
+var entrySeq atomic.Int64
+
+// nextEntrySeq returns the sequence number of the next entry read
+func nextEntrySeq() int64 {
+	return entrySeq.Add(1)
+}
+
+// entryBefore orders entries by timestamp, breaking ties by file, line and the order they
+// were read in, so that simultaneous entries are in the same order on every run
+func entryBefore(a, b *LogEntry) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	if a.SourceFile != b.SourceFile {
+		return a.SourceFile < b.SourceFile
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Seq < b.Seq
+}
+
+// sortEntries sorts entries with entryBefore
+func sortEntries(logs []LogEntry) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return entryBefore(&logs[i], &logs[j])
+	})
 }
 
 // ExtrasToString converts the Extras map to a comma-separated string of key-value pairs.
@@ -37,79 +76,235 @@ func (l *LogEntry) ExtrasToString() string {
 	for k, v := range l.Extras {
 		extras = append(extras, fmt.Sprintf("%s=%v", k, v))
 	}
+	// Sorted so that the same entry is always formatted the same, e.g. in cached AI prompts
+	sort.Strings(extras)
 	return strings.Join(extras, ", ")
 }
 
-// parseLogFile reads and parses a Mattermost log file, applying filters
+// parseLogFile reads and parses a Mattermost log file, or a file of a parser plugin, applying filters
 func parseLogFile(filePath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr string) ([]LogEntry, error) {
-	file, err := os.Open(filePath)
+	regex, startTime, endTime, err := parseFilterOptions(regexPattern, startTimeStr, endTimeStr)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = file.Close() }()
+	// The files after --max-lines or --max-bytes are not read
+	if !inputLimit.startFile() {
+		return nil, nil
+	}
 
-	// Parse time range filters if provided
-	var startTime, endTime time.Time
-	if startTimeStr != "" {
-		parsedTime, parseErr := time.Parse("2006-01-02 15:04:05.000", startTimeStr)
-		if parseErr != nil {
-			return nil, fmt.Errorf("invalid start time format: %v", parseErr)
-		}
-		startTime = parsedTime
+	// Files matching a parser plugin are parsed by it
+	if plugin, ok := parserPluginFor(filePath); ok {
+		return runStats.countParsedFile(parsePluginFile(plugin, filePath, searchTerm, regex, levelFilter, userFilter, startTime, endTime))
 	}
-	if endTimeStr != "" {
-		parsedTime, parseErr := time.Parse("2006-01-02 15:04:05.000", endTimeStr)
-		if parseErr != nil {
-			return nil, fmt.Errorf("invalid end time format: %v", parseErr)
-		}
-		endTime = parsedTime
+
+	// Tarballs of log files, such as a copy of /var/log/mattermost, are parsed file by file
+	isTar, err := isTarFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if isTar {
+		return parseTarFile(filePath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr)
 	}
 
-	// Compile regex if provided
-	var regex *regexp.Regexp
-	if regexPattern != "" {
-		regex, err = regexp.Compile(regexPattern)
-		if err != nil {
-			return nil, fmt.Errorf("invalid regex pattern: %v", err)
-		}
+	// Entries exported by lamp as a JSON array are read back as they were written; exported
+	// JSON Lines are recognized line by line by parseJSONLine
+	isArray, err := isJSONArrayFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if isArray {
+		return runStats.countParsedFile(parseJSONArrayFile(filePath, searchTerm, regex, levelFilter, userFilter, startTime, endTime))
+	}
+
+	// Notification delivery CSVs of support packets are parsed as notification logs
+	isAckCSV, err := isNotificationCSVFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if isAckCSV {
+		return runStats.countParsedFile(parseNotificationCSVFile(filePath, searchTerm, regex, levelFilter, userFilter, startTime, endTime))
 	}
 
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	// Show the progress of large files, by bytes read since their number of lines is unknown.
+	// The bytes of compressed files are counted before decompression.
+	var bar *progress
+	if info, err := file.Stat(); err == nil && info.Size() >= fileProgressMinBytes && !noFileProgress {
+		bar = newBytesProgressBar(info.Size(), "Parsing "+filepath.Base(filePath))
+	}
+	var reportedBytes int64
+
 	var logs []LogEntry
-	scanner := bufio.NewScanner(file)
+	var positions []readPosition // Positions of the entries with --keep-newest
+	scanner := bufio.NewScanner(newLogReader(file))
 
 	// Use a larger buffer for potentially long log lines
 	const maxCapacity = 512 * 1024 // 512KB
 	buf := make([]byte, maxCapacity)
 	scanner.Buffer(buf, maxCapacity)
 
+	var keepLine func(string) bool
+	if fastScan {
+		keepLine = scanLevelFilter(levelFilter)
+	}
+	// Skip parsing the JSON lines that cannot match --search and --regex
+	prefilter := rawLinePrefilter(searchTerm, regex)
+
+	lineNumber := 0
 	for scanner.Scan() {
-		line := scanner.Text()
+		lineNumber++
+		if !inputLimit.read(filePath, len(scanner.Bytes())+1) {
+			break
+		}
+		line := validUTF8(scanner.Text())
+		if bar != nil {
+			// Update the bar every megabyte rather than on every line
+			if read := file.bytesRead(); read-reportedBytes >= 1<<20 {
+				_ = bar.Add(int(read - reportedBytes))
+				reportedBytes = read
+			}
+		}
+		if keepLine != nil && !keepLine(line) {
+			continue
+		}
+		if prefilter != nil && !prefilter(line) {
+			continue
+		}
 		entry, err := parseLine(line)
+		if errors.Is(err, errUnsupportedSchema) {
+			return nil, fmt.Errorf("%s:%d: %w", filePath, lineNumber, err)
+		}
 		if err != nil {
 			logger.Debug("skipping unparseable line", "line", line, "error", err)
 			// Skip lines that couldn't be parsed
+			releaseExtras(entry.Extras)
+			runStats.lineSkipped()
 			continue
 		}
+		entry.Raw = line
+		if entry.SourceFile == "" {
+			// Exported entries keep the file they were first read from
+			entry.SourceFile = filePath
+			entry.Line = lineNumber
+		}
+		setEntryNode(&entry)
+		entry.Seq = nextEntrySeq()
 
 		// Apply filters
-		if shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, startTime, endTime) {
-			logs = append(logs, entry)
+		if !shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, startTime, endTime) {
+			releaseExtras(entry.Extras)
+			continue
+		}
+		logs = append(logs, entry)
+		runStats.entryKept()
+		if entrySpill != nil {
+			// Write the entries to disk when they exceed --max-memory
+			if logs, err = entrySpill.check(logs); err != nil {
+				return nil, err
+			}
+		}
+		if inputLimit != nil && inputLimit.keepNewest {
+			positions = append(positions, inputLimit.position())
+			// Drop the entries older than the newest lines and bytes now and then, rather
+			// than keeping the whole file in memory
+			if len(logs)%4096 == 0 {
+				logs, positions = inputLimit.evict(logs, positions)
+			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	if bar != nil {
+		_ = bar.Finish()
+	}
+	runStats.fileParsed()
+	logs, _ = inputLimit.evict(logs, positions)
 
 	return logs, nil
 }
 
+// parseFilterOptions compiles the regex and parses the time range filters, if provided
+func parseFilterOptions(regexPattern, startTimeStr, endTimeStr string) (*regexp.Regexp, time.Time, time.Time, error) {
+	var startTime, endTime time.Time
+	if startTimeStr != "" {
+		parsedTime, err := time.Parse("2006-01-02 15:04:05.000", startTimeStr)
+		if err != nil {
+			return nil, startTime, endTime, fmt.Errorf("invalid start time format: %v", err)
+		}
+		startTime = parsedTime
+	}
+	if endTimeStr != "" {
+		parsedTime, err := time.Parse("2006-01-02 15:04:05.000", endTimeStr)
+		if err != nil {
+			return nil, startTime, endTime, fmt.Errorf("invalid end time format: %v", err)
+		}
+		endTime = parsedTime
+	}
+
+	var regex *regexp.Regexp
+	if regexPattern != "" {
+		var err error
+		regex, err = regexp.Compile(regexPattern)
+		if err != nil {
+			return nil, startTime, endTime, fmt.Errorf("invalid regex pattern: %v", err)
+		}
+	}
+
+	return regex, startTime, endTime, nil
+}
+
+// scanSkippedLevels are the levels skipped by --fast when no level filter is set
+var scanSkippedLevels = []string{"debug", "trace"}
+
+// scanLevelFilter returns a check of raw lines run before they are parsed with --fast,
+// keeping the lines of the filtered level, or those not of a skipped level without filter.
+// It only looks for the level as Mattermost writes it, "level":"error" in JSON lines, the
+// prefix of plain text lines or the bracketed level of the older format, so it may drop lines
+// whose level is written differently.
+func scanLevelFilter(levelFilter string) func(line string) bool {
+	hasLevel := func(line, level string) bool {
+		if strings.HasPrefix(strings.TrimSpace(line), "{") {
+			return strings.Contains(line, `"level":"`+level+`"`) || strings.Contains(line, `"level": "`+level+`"`)
+		}
+		if isLegacyLine(line) {
+			legacyLevel, ok := legacyLineLevel(line)
+			return ok && legacyLevel == strings.ToLower(level)
+		}
+		return len(line) > len(level)+1 && strings.EqualFold(line[:len(level)], level) && line[len(level):len(level)+2] == " ["
+	}
+
+	if levelFilter != "" {
+		level := strings.ToLower(levelFilter)
+		return func(line string) bool {
+			return hasLevel(line, level) || hasLevel(line, strings.ToUpper(level))
+		}
+	}
+	return func(line string) bool {
+		for _, level := range scanSkippedLevels {
+			if hasLevel(line, level) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 // parseLine attempts to parse a single log line into a LogEntry
 func parseLine(line string) (LogEntry, error) {
 	// Check if the line is in JSON format
 	if strings.HasPrefix(strings.TrimSpace(line), "{") {
 		return parseJSONLine(line)
 	}
+	if isLegacyLine(line) {
+		return parseLegacyLine(line)
+	}
 
 	var entry LogEntry
 	// Basic format detection and parsing
@@ -121,7 +316,7 @@ func parseLine(line string) (LogEntry, error) {
 	}
 
 	// Parse log level
-	entry.Level = strings.TrimSpace(parts[0])
+	entry.Level = internedStrings.intern(strings.TrimSpace(parts[0]))
 
 	// Split remaining parts by closing bracket
 	remainingParts := strings.SplitN(parts[1], "] ", 2)
@@ -140,7 +335,7 @@ func parseLine(line string) (LogEntry, error) {
 	rest := remainingParts[1]
 
 	// Initialize extras map
-	entry.Extras = make(map[string]string)
+	entry.Extras = newExtras()
 
 	// No caller, just split on first key-value pair
 	fields := strings.Fields(rest)
@@ -165,11 +360,11 @@ func parseLine(line string) (LogEntry, error) {
 			k, v := parts[0], parts[1]
 			switch k {
 			case "caller":
-				entry.Source = strings.Trim(v, "\"")
+				entry.Source = internedStrings.intern(strings.Trim(v, "\""))
 			case "user_id":
 				entry.User = v
 			default:
-				entry.Extras[k] = v
+				entry.Extras[internedStrings.intern(k)] = v
 			}
 		}
 	}
@@ -177,91 +372,108 @@ func parseLine(line string) (LogEntry, error) {
 	return entry, nil
 }
 
-// parseJSONLine parses a JSON-formatted log line
+// parseJSONLine parses a JSON-formatted log line. The line is decoded once into a pooled
+// map, and the keys, level and caller are interned since they repeat on most lines.
 func parseJSONLine(line string) (LogEntry, error) {
 	var entry LogEntry
-	entry.Extras = make(map[string]string)
-
-	// JSONLogEntry represents a JSON-formatted log entry
-	type JSONLogEntry struct {
-		Timestamp string `json:"timestamp"`
-		Level     string `json:"level"`
-		Msg       string `json:"msg"`
-		Caller    string `json:"caller,omitempty"`
-		UserID    string `json:"user_id,omitempty"`
-		LogSource string `json:"logSource,omitempty"`
-		AckID     string `json:"ackId,omitempty"`
-		Type      string `json:"type,omitempty"`
-		Status    string `json:"status,omitempty"`
-	}
-	var jsonEntry JSONLogEntry
-
-	// Unmarshal the JSON log entry
-	if err := json.Unmarshal([]byte(line), &jsonEntry); err != nil {
-		// Try to recover from JSON parsing errors by cleaning up common issues
-		fixedLine := strings.ReplaceAll(line, "\\\"", "'")
-		if err := json.Unmarshal([]byte(fixedLine), &jsonEntry); err != nil {
-			return entry, fmt.Errorf("failed to parse JSON log: %v", err)
-		}
-	}
-
-	// Extract additional fields
-	var extra map[string]any
-	if err := json.Unmarshal([]byte(line), &extra); err != nil {
-		return entry, fmt.Errorf("failed to parse extra JSON fields: %v", err)
-	}
-	for k, v := range extra {
-		// Skip fields we already handle
-		if k == "timestamp" || k == "level" || k == "msg" || k == "caller" || k == "user_id" || 
-		   k == "logSource" || k == "ackId" || k == "type" || k == "status" {
+
+	fields := jsonFieldsPool.Get().(map[string]any)
+	defer func() {
+		clear(fields)
+		jsonFieldsPool.Put(fields)
+	}()
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return entry, fmt.Errorf("failed to parse JSON log: %v", err)
+	}
+	if isExportedEntry(fields) {
+		return parseExportedEntry([]byte(line))
+	}
+
+	entry.Extras = newExtras()
+	var timestampStr string
+	for k, v := range fields {
+		// Fields stored in the entry itself must be strings
+		var field *string
+		switch k {
+		case "timestamp":
+			field = &timestampStr
+		case "level":
+			field = &entry.Level
+		case "msg":
+			field = &entry.Message
+		case "caller":
+			field = &entry.Source
+		case "user_id":
+			field = &entry.User
+		case "logSource":
+			field = &entry.LogSource
+		case "ackId":
+			field = &entry.AckID
+		case "type":
+			field = &entry.Type
+		case "status":
+			field = &entry.Status
+		}
+		if field != nil {
+			if value, ok := v.(string); ok {
+				*field = value
+			} else if v != nil {
+				return entry, fmt.Errorf("failed to parse JSON log: field %s is not a string", k)
+			}
 			continue
 		}
 
 		// Convert non-string values to strings
 		switch val := v.(type) {
 		case string:
-			entry.Extras[k] = val
+			entry.Extras[internedStrings.intern(k)] = val
 		default:
 			// Use json.Marshal to convert other types to string representation
 			bytes, err := json.Marshal(val)
 			if err != nil {
 				return entry, fmt.Errorf("failed to marshal extra field %s: %v", k, err)
 			}
-			entry.Extras[k] = string(bytes)
+			entry.Extras[internedStrings.intern(k)] = string(bytes)
 		}
 	}
 
 	// Parse timestamp
-	timestamp, err := parseTimestamp(strings.TrimSpace(jsonEntry.Timestamp))
+	timestamp, err := parseTimestamp(strings.TrimSpace(timestampStr))
 	if err != nil {
 		return entry, err
 	}
 	entry.Timestamp = timestamp
 
-	// Set other fields
-	entry.Level = jsonEntry.Level
-	entry.Message = jsonEntry.Msg
-	entry.User = jsonEntry.UserID
-	entry.Source = jsonEntry.Caller
-	
-	// Set notification-specific fields if present
-	entry.LogSource = jsonEntry.LogSource
-	entry.AckID = jsonEntry.AckID
-	entry.Type = jsonEntry.Type
-	entry.Status = jsonEntry.Status
+	// Share the copies of the values repeated on most lines
+	entry.Level = internedStrings.intern(entry.Level)
+	entry.Source = internedStrings.intern(entry.Source)
+	entry.LogSource = internedStrings.intern(entry.LogSource)
+	entry.Type = internedStrings.intern(entry.Type)
+	entry.Status = internedStrings.intern(entry.Status)
 
 	return entry, nil
 }
 
+// mattermostTimestampFormat is the format of the timestamps Mattermost writes
+const mattermostTimestampFormat = "2006-01-02 15:04:05.000 Z"
+
 // parseTimestamp attempts to parse a timestamp string into a time.Time
 func parseTimestamp(timestampStr string) (time.Time, error) {
+	// Most timestamps are in the Mattermost format, which none of the formats tried before it
+	// parses, so try it first rather than building a parse error for each of them
+	if strings.HasSuffix(timestampStr, " Z") {
+		if t, err := time.Parse(mattermostTimestampFormat, timestampStr); err == nil {
+			return t, nil
+		}
+	}
+
 	// Try common Mattermost timestamp formats
 	formats := []string{
 		time.RFC3339,
 		time.RFC3339Nano,
 		"2006-01-02T15:04:05.000Z",
 		"2006/01/02 15:04:05",
-		"2006-01-02 15:04:05.000 Z",
+		mattermostTimestampFormat,
 		"2006-01-02 15:04:05.000 MST",
 		// Additional formats with timezone offsets
 		"2006-01-02 15:04:05.000 -07:00",
@@ -293,21 +505,7 @@ func trimDuplicateLogInfo(logs []LogEntry) []LogEntry {
 	const parallelThreshold = 1000 // Minimum log count to use parallel processing
 
 	// Create progress bar
-	bar := progressbar.NewOptions(len(logs),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetDescription("[cyan]Deduplicating logs[reset]"),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionOnCompletion(func() {
-			fmt.Println()
-		}))
+	bar := newProgressBar(len(logs), "Deduplicating logs")
 
 	// Render initial blank progress bar
 	if err := bar.RenderBlank(); err != nil {
@@ -322,8 +520,56 @@ func trimDuplicateLogInfo(logs []LogEntry) []LogEntry {
 	return trimDuplicateLogsSequential(logs, similarityThreshold, batchSize, updateInterval, bar)
 }
 
+// mergeDuplicate counts a duplicate in the entry kept for it, with the latest occurrence and,
+// once duplicates come from another node than kept, the occurrences per node, so that the
+// entries merged across a cluster still tell which nodes logged them
+func mergeDuplicate(kept *LogEntry, duplicate LogEntry) {
+	if kept.NodeCounts != nil || duplicate.Node != kept.Node {
+		if kept.NodeCounts == nil {
+			kept.NodeCounts = map[string]int{kept.Node: kept.DuplicateCount}
+		}
+		kept.NodeCounts[duplicate.Node]++
+	}
+	if duplicate.Timestamp.After(kept.lastSeen()) {
+		lastSeen := duplicate.Timestamp
+		kept.LastSeen = &lastSeen
+	}
+	kept.DuplicateCount++
+}
+
+// lastSeen returns the latest occurrence of an entry, its timestamp unless it was deduplicated
+func (l *LogEntry) lastSeen() time.Time {
+	if l.LastSeen != nil {
+		return *l.LastSeen
+	}
+	return l.Timestamp
+}
+
+// formatNodeCounts formats the occurrences per node of an entry, most first, e.g.
+// "node1: 120, node2: 3"
+func formatNodeCounts(counts map[string]int) string {
+	nodes := make([]CountedItem, 0, len(counts))
+	for node, count := range counts {
+		if node == "" {
+			node = "unknown node"
+		}
+		nodes = append(nodes, CountedItem{Item: node, Count: count})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Count != nodes[j].Count {
+			return nodes[i].Count > nodes[j].Count
+		}
+		return nodes[i].Item < nodes[j].Item
+	})
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = fmt.Sprintf("%s: %d", node.Item, node.Count)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // trimDuplicateLogsSequential performs sequential deduplication for smaller log sets
-func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, batchSize, updateInterval int, bar *progressbar.ProgressBar) []LogEntry {
+func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, batchSize, updateInterval int, bar *progress) []LogEntry {
 	var result []LogEntry
 	processedEntries := make(map[int]bool)
 	
@@ -343,7 +589,7 @@ func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, b
 	for i, entry := range logs {
 		// Update description periodically to show activity
 		if i%updateInterval == 0 {
-			bar.Describe(fmt.Sprintf("[cyan]Processed: %d/%d - Removed: %d[reset]", i, len(logs), removedCount))
+			bar.Describe(fmt.Sprintf("Processed: %d/%d - Removed: %d", i, len(logs), removedCount))
 		}
 
 		// Skip if already processed
@@ -404,11 +650,11 @@ func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, b
 				removedCount++
 
 				// Increment duplicate count for this entry
-				result[len(result)-1].DuplicateCount++
+				mergeDuplicate(&result[len(result)-1], logs[j])
 
 				// Update progress description more frequently during batch removals
 				if processedInThisIteration%10 == 0 {
-					bar.Describe(fmt.Sprintf("[cyan]Processed: %d/%d - Removed: %d[reset]", i, len(logs), removedCount))
+					bar.Describe(fmt.Sprintf("Processed: %d/%d - Removed: %d", i, len(logs), removedCount))
 				}
 			}
 		}
@@ -430,6 +676,7 @@ func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, b
 	}
 
 	// Ensure the bar is completed
+	bar.Describe(fmt.Sprintf("Processed: %d/%d - Removed: %d", len(logs), len(logs), removedCount))
 	if err := bar.Finish(); err != nil {
 		logger.Warn("Error completing progress bar", "error", err)
 	}
@@ -438,7 +685,7 @@ func trimDuplicateLogsSequential(logs []LogEntry, similarityThreshold float64, b
 }
 
 // trimDuplicateLogsParallel performs parallel deduplication for larger log sets
-func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar *progressbar.ProgressBar) []LogEntry {
+func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar *progress) []LogEntry {
 	// Normalize all messages in parallel first
 	normalizedMsgs := make([]string, len(logs))
 	
@@ -451,7 +698,7 @@ func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar
 	
 	// Use a worker pool to normalize messages in parallel
 	workersCount := runtime.NumCPU()
-	bar.Describe("[cyan]Normalizing log messages in parallel[reset]")
+	bar.Describe("Normalizing log messages in parallel")
 	
 	// Create a channel to distribute work
 	jobs := make(chan int, len(logs))
@@ -494,9 +741,10 @@ func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar
 	if err := bar.RenderBlank(); err != nil {
 		logger.Warn("Error rendering progress bar", "error", err)
 	}
-	bar.Describe("[cyan]Deduplicating logs with parallel processing[reset]")
+	bar.Describe("Deduplicating logs with parallel processing")
 	
 	var result []LogEntry
+	var positions []int // Index in logs of each entry of result
 	processedEntries := make(map[int]bool)
 	var resultMutex sync.Mutex
 	var processedMutex sync.Mutex
@@ -509,7 +757,7 @@ func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar
 		if len(indices) < 10 {  // Process small groups sequentially
 			processLogGroup(
 				logs, normalizedMsgs, indices, level, similarityThreshold, 
-				&result, processedEntries, &removedCount, bar,
+				&result, &positions, processedEntries, &removedCount, bar,
 				&resultMutex, &processedMutex, &removedMutex,
 			)
 		} else {
@@ -518,7 +766,7 @@ func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar
 				defer levelWg.Done()
 				processLogGroup(
 					logs, normalizedMsgs, idxs, lvl, similarityThreshold, 
-					&result, processedEntries, &removedCount, bar,
+					&result, &positions, processedEntries, &removedCount, bar,
 					&resultMutex, &processedMutex, &removedMutex,
 				)
 			}(level, indices)
@@ -526,8 +774,22 @@ func trimDuplicateLogsParallel(logs []LogEntry, similarityThreshold float64, bar
 	}
 	
 	levelWg.Wait()
+
+	// The level groups are deduplicated concurrently, so put the kept entries back in the
+	// order of logs to get the same result on every run
+	order := make([]int, len(result))
+	for k := range order {
+		order[k] = k
+	}
+	sort.Slice(order, func(a, b int) bool { return positions[order[a]] < positions[order[b]] })
+	ordered := make([]LogEntry, len(result))
+	for k, index := range order {
+		ordered[k] = result[index]
+	}
+	result = ordered
 	
 	// Ensure the bar is completed
+	bar.Describe(fmt.Sprintf("Processed: %d - Removed: %d", len(logs), removedCount))
 	if err := bar.Finish(); err != nil {
 		logger.Warn("Error completing progress bar", "error", err)
 	}
@@ -544,9 +806,10 @@ func processLogGroup(
 	level string, 
 	similarityThreshold float64,
 	result *[]LogEntry,
+	positions *[]int,
 	processedEntries map[int]bool,
 	removedCount *int,
-	bar *progressbar.ProgressBar,
+	bar *progress,
 	resultMutex, processedMutex, removedMutex *sync.Mutex,
 ) {
 	// Process each log entry in this level group
@@ -570,6 +833,7 @@ func processLogGroup(
 		resultMutex.Lock()
 		resultIndex := len(*result)
 		*result = append(*result, entryWithCount)
+		*positions = append(*positions, i)
 		resultMutex.Unlock()
 		
 		// Get normalized message and its words
@@ -620,7 +884,7 @@ func processLogGroup(
 				
 				// Update duplicate count
 				resultMutex.Lock()
-				(*result)[resultIndex].DuplicateCount++
+				mergeDuplicate(&(*result)[resultIndex], logs[j])
 				resultMutex.Unlock()
 			}
 		}
@@ -631,7 +895,7 @@ func processLogGroup(
 			currentRemoved := *removedCount
 			removedMutex.Unlock()
 			
-			bar.Describe(fmt.Sprintf("[cyan]Processed: %d - Removed: %d[reset]", i, currentRemoved))
+			bar.Describe(fmt.Sprintf("Processed: %d - Removed: %d", i, currentRemoved))
 		}
 		
 		// Update progress bar
@@ -838,6 +1102,34 @@ func levenshteinDistance(s1, s2 string) int {
 }
 
 // shouldIncludeEntry checks if a log entry matches all the specified filters
+// parsingNode is the cluster node of the file being parsed, set while parsing the log files
+// of a support packet, whose directories name their nodes
+var parsingNode string
+
+// nodeExtrasKeys are extras keys identifying the cluster node that logged an entry
+var nodeExtrasKeys = []string{"node", "node_id", "hostname", "host", "server"}
+
+// extrasNode returns the cluster node named by the extras of an entry, empty if none does
+func extrasNode(extras map[string]string) string {
+	for _, key := range nodeExtrasKeys {
+		if value := extras[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// setEntryNode records the cluster node of a parsed entry, unless it was exported with one:
+// the node of the file being parsed, else the node named by its extras
+func setEntryNode(entry *LogEntry) {
+	if entry.Node == "" {
+		entry.Node = parsingNode
+	}
+	if entry.Node == "" {
+		entry.Node = extrasNode(entry.Extras)
+	}
+}
+
 func shouldIncludeEntry(entry LogEntry, searchTerm string, regex *regexp.Regexp, levelFilter, userFilter string, startTime, endTime time.Time) bool {
 	// Apply level filter
 	if levelFilter != "" && !strings.EqualFold(entry.Level, levelFilter) {
@@ -849,6 +1141,13 @@ func shouldIncludeEntry(entry LogEntry, searchTerm string, regex *regexp.Regexp,
 		return false
 	}
 
+	// Apply node filter
+	if len(nodeFilter) > 0 && !slices.ContainsFunc(nodeFilter, func(node string) bool {
+		return strings.EqualFold(strings.TrimSpace(node), entry.Node)
+	}) {
+		return false
+	}
+
 	// Apply time range filters
 	if !startTime.IsZero() && entry.Timestamp.Before(startTime) {
 		return false
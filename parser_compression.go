@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/svelle/lamp/internal/zstd"
+)
+
+// Magic numbers at the start of compressed files
+var (
+	gzipMagic  = []byte{0x1F, 0x8B}
+	bzip2Magic = []byte("BZh") // Followed by the block size, 1 to 9
+	zstdMagic  = []byte{0x28, 0xB5, 0x2F, 0xFD}
+)
+
+// countingReader counts the bytes read from a reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// logFileReader reads the content of a log file, decompressed if the file is compressed
+type logFileReader struct {
+	io.Reader
+	file       *os.File
+	counted    *countingReader
+	compressed bool
+}
+
+// openLogFile opens a log file, transparently decompressing gzip, bzip2 and zstd files. They
+// are recognized by their first bytes rather than their extension, since rotated logs are
+// named in many ways, e.g. mattermost.log.1.gz or mattermost.log-20250101.
+func openLogFile(filePath string) (*logFileReader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	counted := &countingReader{r: file}
+	buffered := bufio.NewReader(counted)
+	head, _ := buffered.Peek(len(zstdMagic))
+
+	l := &logFileReader{Reader: buffered, file: file, counted: counted, compressed: true}
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("invalid gzip file %s: %v", filePath, err)
+		}
+		l.Reader = gz
+	case bytes.HasPrefix(head, bzip2Magic) && len(head) > len(bzip2Magic) && head[3] >= '1' && head[3] <= '9':
+		l.Reader = bzip2.NewReader(buffered)
+	case bytes.HasPrefix(head, zstdMagic):
+		l.Reader = zstd.NewReader(buffered)
+	default:
+		l.compressed = false
+	}
+	return l, nil
+}
+
+// Close closes the file
+func (l *logFileReader) Close() error {
+	return l.file.Close()
+}
+
+// Stat returns the information of the file, whose size is the compressed size
+func (l *logFileReader) Stat() (os.FileInfo, error) {
+	return l.file.Stat()
+}
+
+// bytesRead returns the number of bytes read from the file, before decompression, to show
+// the progress of parsing it
+func (l *logFileReader) bytesRead() int64 {
+	return l.counted.n
+}
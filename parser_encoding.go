@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// utf8BOM is the byte order mark some Windows editors write at the start of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// newLogReader returns a reader of the text of a log file as UTF-8. The byte order mark of
+// UTF-8 files is dropped, and UTF-16 files, such as logs copied with Windows tools, are
+// decoded: by their byte order mark, or else by the NUL byte next to their first character,
+// since logs start with ASCII text.
+func newLogReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(3)
+
+	var endianness unicode.Endianness
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		_, _ = buffered.Discard(len(utf8BOM))
+		return buffered
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), len(head) >= 2 && head[0] != 0 && head[1] == 0:
+		endianness = unicode.LittleEndian
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}), len(head) >= 2 && head[0] == 0 && head[1] != 0:
+		endianness = unicode.BigEndian
+	default:
+		return buffered
+	}
+	// UseBOM drops the byte order mark if there is one
+	return transform.NewReader(buffered, unicode.UTF16(endianness, unicode.UseBOM).NewDecoder())
+}
+
+// validUTF8 replaces the invalid UTF-8 bytes of a line, such as those of logs written in a
+// legacy encoding or truncated mid-character, with the replacement character
+func validUTF8(line string) string {
+	if utf8.ValidString(line) {
+		return line
+	}
+	return strings.ToValidUTF8(line, "\uFFFD")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// jsonLinesExtensions are the extensions of the files --trim-json writes as JSON Lines, one
+// entry per line like --ndjson, rather than as a JSON array
+var jsonLinesExtensions = []string{".jsonl", ".ndjson"}
+
+// isJSONLinesFile reports whether a path names a JSON Lines file
+func isJSONLinesFile(path string) bool {
+	return contains(jsonLinesExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// isExportedEntry reports whether the decoded fields of a JSON line are an entry exported by
+// lamp, which names the message "message" where Mattermost logs name it "msg"
+func isExportedEntry(fields map[string]any) bool {
+	_, isLog := fields["msg"]
+	message, isExport := fields["message"].(string)
+	return !isLog && isExport && message != ""
+}
+
+// parseExportedEntry decodes an entry exported by lamp with --ndjson, --json or --trim-json.
+// Entries keep the file and node they were read from, if exported.
+func parseExportedEntry(data []byte) (LogEntry, error) {
+	var exported struct {
+		SchemaVersion int `json:"schema_version"`
+		LogEntry
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return LogEntry{}, fmt.Errorf("failed to parse exported entry: %v", err)
+	}
+	if err := checkSchemaVersion(exported.SchemaVersion); err != nil {
+		return LogEntry{}, err
+	}
+	entry := exported.LogEntry
+	if entry.Timestamp.IsZero() {
+		return entry, fmt.Errorf("failed to parse exported entry: no timestamp")
+	}
+	entry.Level = internedStrings.intern(entry.Level)
+	entry.Source = internedStrings.intern(entry.Source)
+	entry.LogSource = internedStrings.intern(entry.LogSource)
+	return entry, nil
+}
+
+// isJSONArrayFile reports whether a file is a JSON array, such as the entries written by
+// --json or --trim-json. Only regular files are checked, as reading the start of a pipe
+// would lose it.
+func isJSONArrayFile(filePath string) (bool, error) {
+	if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+		return false, err
+	}
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	// An array starts with "[" then an object or "]", unlike the lines of logs of the older
+	// format, which start with "[" then their timestamp
+	reader := bufio.NewReader(newLogReader(file))
+	for _, expected := range []string{"[", "{]"} {
+		b, err := nextNonSpaceByte(reader)
+		if err != nil {
+			// Empty files are parsed as logs, which have no entries either
+			return false, nil
+		}
+		if !strings.ContainsRune(expected, rune(b)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// nextNonSpaceByte returns the next byte of a reader that is not JSON whitespace
+func nextNonSpaceByte(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil || !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, err
+		}
+	}
+}
+
+// parseJSONArrayFile parses the entries exported by lamp to a JSON array, one at a time,
+// applying the filters like parseLogFile
+func parseJSONArrayFile(filePath, searchTerm string, regex *regexp.Regexp, levelFilter, userFilter string, startTime, endTime time.Time) ([]LogEntry, error) {
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	decoder := json.NewDecoder(newLogReader(file))
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON array of entries: %v", err)
+	}
+
+	var logs []LogEntry
+	for index := 1; decoder.More(); index++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON array of entries: %v", err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON array of entries: %v", err)
+		}
+		entry, err := parseExportedEntry(compact.Bytes())
+		if errors.Is(err, errUnsupportedSchema) {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if err != nil {
+			logger.Debug("skipping invalid exported entry", "file", filePath, "entry", index, "error", err)
+			runStats.lineSkipped()
+			continue
+		}
+		entry.Raw = compact.String()
+		if entry.SourceFile == "" {
+			entry.SourceFile = filePath
+		}
+		setEntryNode(&entry)
+		entry.Seq = nextEntrySeq()
+
+		if !shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, startTime, endTime) {
+			continue
+		}
+		logs = append(logs, entry)
+		runStats.entryKept()
+		if entrySpill != nil {
+			// Write the entries to disk when they exceed --max-memory
+			if logs, err = entrySpill.check(logs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return logs, nil
+}
+
+// writeLogsNDJSON writes log entries as JSON Lines, one compact entry per line
+func writeLogsNDJSON(logs []LogEntry, writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	encoder := json.NewEncoder(buffered)
+	for i := range logs {
+		if err := encoder.Encode(&logs[i]); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// legacyLevels maps the levels of the format of Mattermost 5.x and older logs to the levels
+// of current logs. CRIT, the most severe, is fatal so that it counts as an error.
+var legacyLevels = map[string]string{
+	"FNST": "trace",
+	"FINE": "trace",
+	"TRAC": "trace",
+	"DEBG": "debug",
+	"INFO": "info",
+	"WARN": "warn",
+	"EROR": "error",
+	"CRIT": "fatal",
+}
+
+// legacyTimestampFormats are the timestamps of the older format, in UTC when they have no zone
+var legacyTimestampFormats = []string{
+	"2006/01/02 15:04:05 MST",
+	"2006/01/02 15:04:05.000 MST",
+	"2006/01/02 15:04:05",
+}
+
+// legacyExtraKeys maps the keys of the older format to the extras keys of current logs
+var legacyExtraKeys = map[string]string{
+	"rid": "request_id",
+	"ip":  "ip_address",
+}
+
+// isLegacyLine reports whether a line is in the format of Mattermost 5.x and older, which
+// starts with the timestamp in brackets rather than the level
+func isLegacyLine(line string) bool {
+	return strings.HasPrefix(line, "[") && strings.Contains(line, "] [")
+}
+
+// legacyLineLevel returns the level of a line of the older format, as a level of current logs
+func legacyLineLevel(line string) (string, bool) {
+	_, rest, ok := strings.Cut(line, "] [")
+	if !ok {
+		return "", false
+	}
+	level, _, ok := strings.Cut(rest, "]")
+	if !ok {
+		return "", false
+	}
+	mapped, ok := legacyLevels[strings.ToUpper(level)]
+	return mapped, ok
+}
+
+// parseLegacyLine parses a line of the format of Mattermost 5.x and older logs. The message
+// may start with the caller, in parentheses or as the API handler, and with key=value pairs:
+// [2019/01/02 15:04:05 UTC] [EROR] /api/v4/users/login:AuthorizeUser code=401 rid=abc uid=def ip=10.0.0.1 Invalid login
+func parseLegacyLine(line string) (LogEntry, error) {
+	var entry LogEntry
+	timestampStr, rest, ok := strings.Cut(strings.TrimPrefix(line, "["), "] [")
+	if !ok {
+		return entry, fmt.Errorf("invalid log format")
+	}
+	timestamp, err := parseLegacyTimestamp(timestampStr)
+	if err != nil {
+		return entry, err
+	}
+	entry.Timestamp = timestamp
+
+	level, rest, ok := strings.Cut(rest, "]")
+	if !ok {
+		return entry, fmt.Errorf("invalid log format")
+	}
+	mapped, ok := legacyLevels[strings.ToUpper(level)]
+	if !ok {
+		return entry, fmt.Errorf("unknown log level: %s", level)
+	}
+	entry.Level = mapped
+
+	entry.Extras = newExtras()
+	words := strings.Fields(rest)
+	if len(words) > 0 && isLegacyCaller(words[0]) {
+		entry.Source = internedStrings.intern(strings.Trim(words[0], "()"))
+		words = words[1:]
+	}
+	for len(words) > 0 {
+		k, v, ok := strings.Cut(words[0], "=")
+		if !ok || k == "" {
+			break
+		}
+		words = words[1:]
+		switch {
+		case v == "":
+		case k == "uid":
+			entry.User = v
+		case legacyExtraKeys[k] != "":
+			entry.Extras[legacyExtraKeys[k]] = v
+		default:
+			entry.Extras[internedStrings.intern(k)] = v
+		}
+	}
+	entry.Message = strings.Join(words, " ")
+	return entry, nil
+}
+
+// isLegacyCaller reports whether the first word of the message of the older format is the
+// caller: a function in parentheses, or the path and handler of an API request
+func isLegacyCaller(word string) bool {
+	if strings.HasPrefix(word, "(") && strings.HasSuffix(word, ")") {
+		return strings.Contains(word, ":")
+	}
+	return strings.HasPrefix(word, "/") && strings.Contains(word, ":")
+}
+
+// parseLegacyTimestamp parses a timestamp of the older format
+func parseLegacyTimestamp(timestampStr string) (time.Time, error) {
+	for _, format := range legacyTimestampFormats {
+		if t, err := time.Parse(format, timestampStr); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse timestamp: %s", timestampStr)
+}
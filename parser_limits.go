@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// inputLimit caps the lines and bytes read from the input files of a command for --max-lines
+// and --max-bytes, nil without them
+var inputLimit *readLimit
+
+// readLimit counts the lines and bytes read across the input files of a command. Once a cap
+// is reached, reading stops, or with --keep-newest goes on but only keeps the entries of the
+// newest lines and bytes.
+type readLimit struct {
+	maxLines   int   // 0 for no cap
+	maxBytes   int64 // 0 for no cap
+	keepNewest bool
+
+	maxBytesText string // --max-bytes as given, e.g. 10G
+
+	lines        int    // Lines read so far
+	bytes        int64  // Bytes read so far, after decompression
+	reachedIn    string // File being read when a cap was reached, empty until then
+	reachedCap   string // The flag of the cap reached, e.g. --max-lines 1000000
+	skippedFiles int    // Files not read since a cap was reached
+	dropped      int    // Entries dropped to keep the newest ones
+}
+
+// readPosition is the position in the input of the line an entry was parsed from
+type readPosition struct {
+	line  int
+	bytes int64
+}
+
+// newReadLimit sets up the caps of --max-lines and --max-bytes, if any
+func newReadLimit(maxLines int, maxBytes string, keepNewest bool) (*readLimit, error) {
+	if maxLines < 0 {
+		return nil, fmt.Errorf("--max-lines must be positive, got %d", maxLines)
+	}
+	limit := &readLimit{maxLines: maxLines, keepNewest: keepNewest}
+	if maxBytes != "" {
+		size, err := parseByteSize(maxBytes)
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("--max-bytes must be a size such as 500M or 10G, got %q", maxBytes)
+		}
+		limit.maxBytes = int64(size)
+		limit.maxBytesText = maxBytes
+	}
+	if limit.maxLines == 0 && limit.maxBytes == 0 {
+		if keepNewest {
+			return nil, fmt.Errorf("--keep-newest requires --max-lines or --max-bytes")
+		}
+		return nil, nil
+	}
+	if keepNewest && maxMemory != "" {
+		return nil, fmt.Errorf("--keep-newest keeps the newest entries in memory, it cannot be used with --max-memory")
+	}
+	return limit, nil
+}
+
+// startFile reports whether a file can be read, counting the files skipped once reading
+// stopped
+func (l *readLimit) startFile() bool {
+	if l == nil || l.keepNewest || l.reachedIn == "" {
+		return true
+	}
+	l.skippedFiles++
+	return false
+}
+
+// read accounts for a line of a file and reports whether to parse it: the lines beyond the
+// caps are not parsed, unless the newest are kept
+func (l *readLimit) read(file string, lineBytes int) bool {
+	if l == nil {
+		return true
+	}
+	linesExceeded := l.maxLines > 0 && l.lines+1 > l.maxLines
+	exceeded := linesExceeded || (l.maxBytes > 0 && l.bytes+int64(lineBytes) > l.maxBytes)
+	if exceeded && l.reachedIn == "" {
+		l.reachedIn = file
+		l.reachedCap = "--max-bytes " + l.maxBytesText
+		if linesExceeded {
+			l.reachedCap = "--max-lines " + strconv.Itoa(l.maxLines)
+		}
+	}
+	if exceeded && !l.keepNewest {
+		return false
+	}
+	l.lines++
+	l.bytes += int64(lineBytes)
+	return true
+}
+
+// position returns the position of the line last read
+func (l *readLimit) position() readPosition {
+	return readPosition{line: l.lines, bytes: l.bytes}
+}
+
+// evict drops the entries of a file read before the newest lines and bytes of the caps, with
+// their positions, once the caps are reached with --keep-newest. The kept entries are copied
+// when half of them are dropped, so that the dropped ones can be freed.
+func (l *readLimit) evict(logs []LogEntry, positions []readPosition) ([]LogEntry, []readPosition) {
+	if l == nil || !l.keepNewest || l.reachedIn == "" {
+		return logs, positions
+	}
+	n := 0
+	for n < len(positions) && ((l.maxLines > 0 && positions[n].line <= l.lines-l.maxLines) ||
+		(l.maxBytes > 0 && positions[n].bytes <= l.bytes-l.maxBytes)) {
+		n++
+	}
+	if n == 0 || n < len(logs)/2 {
+		return logs, positions
+	}
+	for i := range logs[:n] {
+		releaseExtras(logs[i].Extras)
+	}
+	l.dropped += n
+	return append([]LogEntry(nil), logs[n:]...), append([]readPosition(nil), positions[n:]...)
+}
+
+// newest keeps the newest entries of all files within the caps with --keep-newest, each entry
+// counting as one line and as the bytes of its line. The entries are sorted by timestamp.
+func (l *readLimit) newest(logs []LogEntry) []LogEntry {
+	if l == nil || !l.keepNewest || l.reachedIn == "" {
+		return logs
+	}
+	sortEntries(logs)
+	start := len(logs)
+	var bytes int64
+	for start > 0 {
+		size := int64(len(logs[start-1].Raw) + 1)
+		if (l.maxLines > 0 && len(logs)-start+1 > l.maxLines) || (l.maxBytes > 0 && bytes+size > l.maxBytes) {
+			break
+		}
+		bytes += size
+		start--
+	}
+	l.dropped += start
+	return logs[start:]
+}
+
+// warn tells that the logs were not read entirely because of the caps
+func (l *readLimit) warn() {
+	if l == nil || l.reachedIn == "" {
+		return
+	}
+	if l.keepNewest {
+		logger.Warn("Only the newest entries within "+l.reachedCap+" were kept", "dropped_entries", l.dropped, "from_file", l.reachedIn)
+		return
+	}
+	logger.Warn("Stopped reading the logs at "+l.reachedCap+", the rest of the logs is not analyzed (keep the newest entries instead with --keep-newest)",
+		"file", l.reachedIn, "lines", l.lines, "bytes", l.bytes, "skipped_files", l.skippedFiles)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields of the notification delivery CSV exports of support packets
+const (
+	ackCSVTimestamp = "timestamp"
+	ackCSVSentAt    = "sent_at"
+	ackCSVAckedAt   = "acked_at"
+	ackCSVAckID     = "ack_id"
+	ackCSVType      = "type"
+	ackCSVStatus    = "status"
+	ackCSVLevel     = "level"
+	ackCSVMessage   = "message"
+	ackCSVUser      = "user"
+)
+
+// ackCSVColumns maps the normalized names of the columns of notification delivery CSVs to
+// their fields. Exports name them in many ways, e.g. ack_id, AckId or Notification ID. The
+// other columns, such as platform or device_id, are kept as extras.
+var ackCSVColumns = map[string]string{
+	"timestamp":        ackCSVTimestamp,
+	"time":             ackCSVTimestamp,
+	"date":             ackCSVTimestamp,
+	"createat":         ackCSVTimestamp,
+	"createdat":        ackCSVTimestamp,
+	"sentat":           ackCSVSentAt,
+	"sendat":           ackCSVSentAt,
+	"receivedat":       ackCSVAckedAt,
+	"ackat":            ackCSVAckedAt,
+	"ackedat":          ackCSVAckedAt,
+	"deliveredat":      ackCSVAckedAt,
+	"ackid":            ackCSVAckID,
+	"notificationid":   ackCSVAckID,
+	"type":             ackCSVType,
+	"notificationtype": ackCSVType,
+	"pushtype":         ackCSVType,
+	"status":           ackCSVStatus,
+	"deliverystatus":   ackCSVStatus,
+	"level":            ackCSVLevel,
+	"message":          ackCSVMessage,
+	"msg":              ackCSVMessage,
+	"error":            ackCSVMessage,
+	"reason":           ackCSVMessage,
+	"userid":           ackCSVUser,
+	"user":             ackCSVUser,
+}
+
+// normalizeCSVColumn normalizes the name of a column for ackCSVColumns
+func normalizeCSVColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// ackCSVHeader is the header of a notification delivery CSV: the field of each column, empty
+// for the extras, and the names of the columns
+type ackCSVHeader struct {
+	fields []string
+	names  []string
+}
+
+// column returns the index of the column of a field, -1 if there is none
+func (h ackCSVHeader) column(field string) int {
+	for i, f := range h.fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// newAckCSVHeader reads the header of a CSV, reporting whether it is a notification delivery
+// CSV: it has an ack ID column, and either a status and a timestamp column or the times the
+// notification was sent and acknowledged. The CSVs exported by lamp, which have a LogSource
+// column, are not.
+func newAckCSVHeader(record []string) (ackCSVHeader, bool) {
+	header := ackCSVHeader{fields: make([]string, len(record)), names: make([]string, len(record))}
+	for i, name := range record {
+		normalized := normalizeCSVColumn(name)
+		if normalized == "logsource" {
+			return header, false
+		}
+		// The first column of a field counts, so that an "error" column doesn't override "message"
+		if field := ackCSVColumns[normalized]; field != "" && header.column(field) < 0 {
+			header.fields[i] = field
+		}
+		header.names[i] = strings.TrimSpace(name)
+	}
+	statusRows := header.column(ackCSVStatus) >= 0 && header.column(ackCSVTimestamp) >= 0
+	deliveryRows := header.column(ackCSVSentAt) >= 0 || header.column(ackCSVAckedAt) >= 0
+	return header, header.column(ackCSVAckID) >= 0 && (statusRows || deliveryRows)
+}
+
+// isNotificationCSVName reports whether a file of a support packet or tarball is named like a
+// notification delivery CSV, e.g. notification_acks.csv
+func isNotificationCSVName(name string) bool {
+	base := strings.ToLower(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	return strings.HasSuffix(base, ".csv") && (strings.Contains(base, "notification") || strings.Contains(base, "ack"))
+}
+
+// isNotificationCSVFile reports whether a file is a notification delivery CSV by its header.
+// Only regular files are checked, as reading the start of a pipe would lose it.
+func isNotificationCSVFile(filePath string) (bool, error) {
+	if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+		return false, err
+	}
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	record, err := csv.NewReader(newLogReader(file)).Read()
+	if err != nil {
+		// Files that are not CSV, such as JSON logs with quotes, are parsed as logs
+		return false, nil
+	}
+	_, ok := newAckCSVHeader(record)
+	return ok, nil
+}
+
+// parseAckCSVTime parses a time of a notification delivery CSV, in one of the formats of the
+// logs or in Unix milliseconds or seconds, as Mattermost stores them
+func parseAckCSVTime(value string) (time.Time, error) {
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if number >= 1e12 {
+			return time.UnixMilli(number).UTC(), nil
+		}
+		return time.Unix(number, 0).UTC(), nil
+	}
+	return parseTimestamp(value)
+}
+
+// ackCSVEntries turns a row of a notification delivery CSV into entries of notification logs.
+// A row with a status is a single entry. A row with the times the notification was sent and
+// acknowledged is an entry for each, or an entry with the status at the time it was sent when
+// it was not acknowledged.
+func ackCSVEntries(header ackCSVHeader, record []string) ([]LogEntry, error) {
+	value := func(field string) string {
+		if i := header.column(field); i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	base := LogEntry{
+		LogSource: "notifications",
+		AckID:     value(ackCSVAckID),
+		Type:      internedStrings.intern(value(ackCSVType)),
+		Level:     internedStrings.intern(strings.ToLower(value(ackCSVLevel))),
+		Message:   value(ackCSVMessage),
+		User:      value(ackCSVUser),
+	}
+	extras := make(map[string]string)
+	for i, name := range header.names {
+		if header.fields[i] == "" && i < len(record) && record[i] != "" && name != "" {
+			extras[internedStrings.intern(name)] = record[i]
+		}
+	}
+
+	type event struct {
+		at     string
+		status string
+	}
+	var events []event
+	status := value(ackCSVStatus)
+	if at := value(ackCSVTimestamp); at != "" && status != "" {
+		events = append(events, event{at, status})
+	} else if sent := value(ackCSVSentAt); sent != "" {
+		events = append(events, event{sent, pushSent})
+		if acked := value(ackCSVAckedAt); acked != "" {
+			events = append(events, event{acked, pushReceived})
+		} else if status != "" && pushFunnelStatuses[strings.ToLower(status)] != pushSent {
+			events = append(events, event{sent, status})
+		}
+	} else if acked := value(ackCSVAckedAt); acked != "" {
+		events = append(events, event{acked, pushReceived})
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no time")
+	}
+
+	entries := make([]LogEntry, 0, len(events))
+	for _, e := range events {
+		timestamp, err := parseAckCSVTime(e.at)
+		if err != nil {
+			return nil, err
+		}
+		entry := base
+		entry.Timestamp = timestamp
+		entry.Status = internedStrings.intern(e.status)
+		if entry.Level == "" {
+			entry.Level = "info"
+			if pushFunnelStatuses[strings.ToLower(e.status)] == pushFailed {
+				entry.Level = "error"
+			}
+		}
+		if entry.Message == "" {
+			entry.Message = "Notification " + strings.ToLower(e.status)
+		}
+		entry.Extras = newExtras()
+		for k, v := range extras {
+			entry.Extras[k] = v
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// formatCSVRecord formats a row of a CSV back to a line, the raw line of its entries
+func formatCSVRecord(record []string) string {
+	var line strings.Builder
+	writer := csv.NewWriter(&line)
+	_ = writer.Write(record)
+	writer.Flush()
+	return strings.TrimSuffix(line.String(), "\n")
+}
+
+// parseNotificationCSVFile parses a notification delivery CSV into entries of notification
+// logs, so that they count in the delivery funnel and latency of the analysis like the
+// entries of notifications.log. Filters apply like parseLogFile.
+func parseNotificationCSVFile(filePath, searchTerm string, regex *regexp.Regexp, levelFilter, userFilter string, startTime, endTime time.Time) ([]LogEntry, error) {
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := csv.NewReader(newLogReader(file))
+	// Rows with missing or extra columns are read as they are
+	reader.FieldsPerRecord = -1
+	record, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification CSV %s: %v", filePath, err)
+	}
+	header, _ := newAckCSVHeader(record)
+
+	var logs []LogEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			logger.Debug("skipping invalid notification CSV row", "file", filePath, "line", parseErr.StartLine, "error", err)
+			runStats.lineSkipped()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lineNumber, _ := reader.FieldPos(0)
+		raw := formatCSVRecord(record)
+		if !inputLimit.read(filePath, len(raw)+1) {
+			break
+		}
+
+		entries, err := ackCSVEntries(header, record)
+		if err != nil {
+			logger.Debug("skipping invalid notification CSV row", "file", filePath, "line", lineNumber, "error", err)
+			runStats.lineSkipped()
+			continue
+		}
+		for _, entry := range entries {
+			entry.Raw = raw
+			entry.SourceFile = filePath
+			entry.Line = lineNumber
+			setEntryNode(&entry)
+			entry.Seq = nextEntrySeq()
+			if !shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, startTime, endTime) {
+				releaseExtras(entry.Extras)
+				continue
+			}
+			logs = append(logs, entry)
+			runStats.entryKept()
+		}
+		if entrySpill != nil {
+			// Write the entries to disk when they exceed --max-memory
+			if logs, err = entrySpill.check(logs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return logs, nil
+}
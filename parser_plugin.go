@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ParserPlugin is an external program parsing log files the built-in parsers do not
+// understand, e.g. the logs of in-house integrations bundled in support packets. lamp writes
+// the content of a file to its standard input and reads one JSON entry per line (see
+// pluginEntry) from its standard output.
+type ParserPlugin struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command"` // Program and its arguments
+	Files   []string `json:"files"`   // Patterns of the file names it parses, e.g. "acme-*.log"
+}
+
+// pluginEntry is a log entry written by a parser plugin. The timestamp may be in RFC 3339 or
+// any format of Mattermost logs; raw defaults to the message. Line is the line of the entry in
+// the file, if the plugin knows it.
+type pluginEntry struct {
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	Source    string            `json:"source"`
+	User      string            `json:"user"`
+	Extras    map[string]string `json:"extras"`
+	Raw       string            `json:"raw"`
+	Line      int               `json:"line"`
+}
+
+// parserPlugins are the parser plugins of the config file, loaded by commands reading logs
+var parserPlugins []ParserPlugin
+
+// validateParserPlugins checks the parser plugins of the config file
+func validateParserPlugins(plugins []ParserPlugin) error {
+	for i, plugin := range plugins {
+		name := plugin.Name
+		if name == "" {
+			name = fmt.Sprintf("number %d", i+1)
+		}
+		if len(plugin.Command) == 0 || plugin.Command[0] == "" {
+			return fmt.Errorf("parser plugin %s has no command", name)
+		}
+		if len(plugin.Files) == 0 {
+			return fmt.Errorf("parser plugin %s has no file patterns", name)
+		}
+		for _, pattern := range plugin.Files {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("parser plugin %s has an invalid file pattern %q: %v", name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadParserPlugins loads the parser plugins of the config file
+func loadParserPlugins() error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := validateParserPlugins(config.Parsers); err != nil {
+		return err
+	}
+	parserPlugins = config.Parsers
+	return nil
+}
+
+// parserPluginFor returns the first parser plugin with a pattern matching the name of a file
+func parserPluginFor(path string) (ParserPlugin, bool) {
+	name := filepath.Base(path)
+	for _, plugin := range parserPlugins {
+		for _, pattern := range plugin.Files {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return plugin, true
+			}
+		}
+	}
+	return ParserPlugin{}, false
+}
+
+// hasParserPlugin reports whether a file is parsed by a parser plugin
+func hasParserPlugin(path string) bool {
+	_, ok := parserPluginFor(path)
+	return ok
+}
+
+// parsePluginFile parses a file with a parser plugin, applying the filters
+func parsePluginFile(plugin ParserPlugin, filePath, searchTerm string, regex *regexp.Regexp, levelFilter, userFilter string, startTime, endTime time.Time) ([]LogEntry, error) {
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	cmd := exec.Command(plugin.Command[0], plugin.Command[1:]...)
+	cmd.Stdin = file
+	cmd.Env = append(os.Environ(), "LAMP_FILE="+filePath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting parser plugin %s: %v", plugin.Name, err)
+	}
+
+	logs, parseErr := readPluginEntries(stdout, plugin.Name, filePath, searchTerm, regex, levelFilter, userFilter, startTime, endTime)
+	if parseErr != nil {
+		// Let the plugin stop on a closed pipe instead of blocking on a full one
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("parser plugin %s failed: %v: %s", plugin.Name, err, message)
+		}
+		return nil, fmt.Errorf("parser plugin %s failed: %v", plugin.Name, err)
+	}
+	return logs, parseErr
+}
+
+// readPluginEntries reads the entries written by a parser plugin for a file, skipping invalid ones
+func readPluginEntries(r io.Reader, pluginName, filePath, searchTerm string, regex *regexp.Regexp, levelFilter, userFilter string, startTime, endTime time.Time) ([]LogEntry, error) {
+	var logs []LogEntry
+	scanner := bufio.NewScanner(r)
+	const maxCapacity = 512 * 1024 // 512KB, as for log files
+	scanner.Buffer(make([]byte, maxCapacity), maxCapacity)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry, err := parsePluginEntry(line)
+		if err != nil {
+			logger.Debug("skipping invalid parser plugin entry", "plugin", pluginName, "line", line, "error", err)
+			runStats.lineSkipped()
+			continue
+		}
+		entry.SourceFile = filePath
+		setEntryNode(&entry)
+		entry.Seq = nextEntrySeq()
+		if shouldIncludeEntry(entry, searchTerm, regex, levelFilter, userFilter, startTime, endTime) {
+			logs = append(logs, entry)
+			runStats.entryKept()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the output of parser plugin %s: %v", pluginName, err)
+	}
+	return logs, nil
+}
+
+// parsePluginEntry parses an entry written by a parser plugin
+func parsePluginEntry(line string) (LogEntry, error) {
+	var parsed pluginEntry
+	if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+		return LogEntry{}, err
+	}
+	if parsed.Message == "" {
+		return LogEntry{}, fmt.Errorf("entry without message")
+	}
+	timestamp, err := parseTimestamp(parsed.Timestamp)
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	entry := LogEntry{
+		Timestamp: timestamp,
+		Level:     parsed.Level,
+		Message:   parsed.Message,
+		Source:    parsed.Source,
+		User:      parsed.User,
+		Extras:    parsed.Extras,
+		Raw:       parsed.Raw,
+		Line:      parsed.Line,
+	}
+	if entry.Raw == "" {
+		entry.Raw = parsed.Message
+	}
+	return entry, nil
+}
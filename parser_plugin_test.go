@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useParserPlugins sets the parser plugins for a test
+func useParserPlugins(t *testing.T, plugins ...ParserPlugin) {
+	old := parserPlugins
+	parserPlugins = plugins
+	t.Cleanup(func() { parserPlugins = old })
+}
+
+func TestParserPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugin is a shell script")
+	}
+	initLogger()
+	dir := t.TempDir()
+
+	script := filepath.Join(dir, "acme-parser")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+while IFS='|' read -r ts level msg; do
+  printf '{"timestamp": "%s", "level": "%s", "message": "%s", "extras": {"file": "%s"}}\n' "$ts" "$level" "$msg" "$(basename "$LAMP_FILE")"
+done
+echo 'not an entry'
+`), 0o755))
+	logFile := filepath.Join(dir, "acme-sync.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("2025-01-01T10:00:00Z|info|sync started\n2025-01-01 10:00:05.000 Z|error|sync failed\n"), 0o644))
+
+	useParserPlugins(t, ParserPlugin{Name: "acme", Command: []string{script}, Files: []string{"acme-*.log"}})
+
+	logs, err := parseLogFile(logFile, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 2, "invalid entries are skipped")
+	assert.Equal(t, "sync started", logs[0].Message)
+	assert.Equal(t, "2025-01-01T10:00:05Z", porcelainTime(logs[1].Timestamp))
+	assert.Equal(t, map[string]string{"file": "acme-sync.log"}, logs[1].Extras)
+	assert.Equal(t, "sync failed", logs[1].Raw)
+
+	logs, err = parseLogFile(logFile, "", "", "error", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 1, "the filters apply to plugin entries")
+	assert.Equal(t, "sync failed", logs[0].Message)
+
+	t.Run("other files use the built-in parsers", func(t *testing.T) {
+		other := filepath.Join(dir, "mattermost.log")
+		require.NoError(t, os.WriteFile(other, []byte(`{"timestamp":"2025-01-01 10:00:00.000 Z","level":"info","msg":"hello"}`+"\n"), 0o644))
+		logs, err := parseLogFile(other, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, "hello", logs[0].Message)
+	})
+
+	t.Run("failing plugin", func(t *testing.T) {
+		failing := filepath.Join(dir, "failing-parser")
+		require.NoError(t, os.WriteFile(failing, []byte("#!/bin/sh\necho 'unsupported format' >&2\nexit 3\n"), 0o755))
+		useParserPlugins(t, ParserPlugin{Name: "failing", Command: []string{failing}, Files: []string{"*.log"}})
+
+		_, err := parseLogFile(logFile, "", "", "", "", "", "")
+		assert.ErrorContains(t, err, "parser plugin failing failed: exit status 3: unsupported format")
+	})
+}
+
+func TestValidateParserPlugins(t *testing.T) {
+	assert.NoError(t, validateParserPlugins([]ParserPlugin{{Name: "acme", Command: []string{"acme-parser"}, Files: []string{"acme-*.log"}}}))
+	assert.ErrorContains(t, validateParserPlugins([]ParserPlugin{{Files: []string{"*.log"}}}), "parser plugin number 1 has no command")
+	assert.ErrorContains(t, validateParserPlugins([]ParserPlugin{{Name: "acme", Command: []string{"acme-parser"}}}), "parser plugin acme has no file patterns")
+	assert.ErrorContains(t, validateParserPlugins([]ParserPlugin{{Name: "acme", Command: []string{"acme-parser"}, Files: []string{"[.log"}}}), "invalid file pattern")
+}
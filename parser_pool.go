@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+)
+
+// internMaxStrings bounds the strings kept by the interner, so that logs with unbounded
+// distinct values cannot grow it without limit
+const internMaxStrings = 8192
+
+// internMaxLength is the longest string interned; longer ones are rarely repeated
+const internMaxLength = 128
+
+// stringInterner returns a shared copy of frequently repeated strings, such as extras keys,
+// levels and callers, so that the entries of large logs don't each hold their own copy
+type stringInterner struct {
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+// internedStrings is the interner of the strings of parsed log entries
+var internedStrings = &stringInterner{strings: make(map[string]string)}
+
+// intern returns the shared copy of s, adding it while the interner has room
+func (i *stringInterner) intern(s string) string {
+	if len(s) > internMaxLength {
+		return s
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if shared, ok := i.strings[s]; ok {
+		return shared
+	}
+	if len(i.strings) < internMaxStrings {
+		i.strings[s] = s
+	}
+	return s
+}
+
+// jsonFieldsPool holds the maps JSON lines are decoded into, which only live while a line
+// is parsed
+var jsonFieldsPool = sync.Pool{
+	New: func() any { return make(map[string]any) },
+}
+
+// extrasPool holds the extras maps of entries that were parsed and then filtered out
+var extrasPool = sync.Pool{
+	New: func() any { return make(map[string]string) },
+}
+
+// newExtras returns an empty extras map, reusing the map of a discarded entry if any
+func newExtras() map[string]string {
+	return extrasPool.Get().(map[string]string)
+}
+
+// releaseExtras makes the extras map of a discarded entry available for reuse. The entry
+// must not be used afterwards.
+func releaseExtras(extras map[string]string) {
+	if extras == nil {
+		return
+	}
+	clear(extras)
+	extrasPool.Put(extras)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+// prefilterUnsafeChars are characters that may be written differently in a raw JSON line
+// than in the parsed fields the filters match: escaped by the JSON encoder, added when
+// formatting the extras as "key=value, ...", or laid out differently when objects and
+// arrays of the extras are marshaled again
+const prefilterUnsafeChars = "\"\\<>&=, {}[]:"
+
+// rawLiteral is a piece of text that any line matching a filter must contain
+type rawLiteral struct {
+	text     string
+	foldCase bool
+}
+
+// safeLiteralPieces splits text on the characters that may differ between a raw line and
+// its parsed fields, keeping the pieces that appear verbatim in both. Pieces with digits are
+// skipped, as numbers of the extras are marshaled again, e.g. 1e3 as 1000.
+func safeLiteralPieces(text string, foldCase bool) []rawLiteral {
+	var literals []rawLiteral
+	pieces := strings.FieldsFunc(text, func(r rune) bool {
+		return r >= utf8.RuneSelf || r < ' ' || strings.ContainsRune(prefilterUnsafeChars, r)
+	})
+	for _, piece := range pieces {
+		if strings.ContainsAny(piece, "0123456789") {
+			continue
+		}
+		if foldCase {
+			piece = strings.ToLower(piece)
+		}
+		literals = append(literals, rawLiteral{text: piece, foldCase: foldCase})
+	}
+	return literals
+}
+
+// requiredLiterals returns the literals any match of a parsed regex contains
+func requiredLiterals(re *syntax.Regexp) []rawLiteral {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return safeLiteralPieces(string(re.Rune), re.Flags&syntax.FoldCase != 0)
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var literals []rawLiteral
+		for _, sub := range re.Sub {
+			literals = append(literals, requiredLiterals(sub)...)
+		}
+		return literals
+	}
+	return nil
+}
+
+// rawLinePrefilter returns a cheap check of raw JSON lines, run before parsing them, that
+// only rejects lines the search term and regex filters would reject once parsed. It
+// returns nil when the filters require no text that can be checked on the raw line.
+func rawLinePrefilter(searchTerm string, regex *regexp.Regexp) func(line string) bool {
+	literals := safeLiteralPieces(searchTerm, true)
+	if regex != nil {
+		// The regex is already compiled, so it parses
+		if re, err := syntax.Parse(regex.String(), syntax.Perl); err == nil {
+			literals = append(literals, requiredLiterals(re)...)
+		}
+	}
+	if len(literals) == 0 {
+		return nil
+	}
+
+	return func(line string) bool {
+		if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+			return true
+		}
+		lower := ""
+		for _, literal := range literals {
+			if !literal.foldCase {
+				if !strings.Contains(line, literal.text) {
+					return false
+				}
+				continue
+			}
+			if lower == "" {
+				lower = strings.ToLower(line)
+			}
+			if !strings.Contains(lower, literal.text) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -2,15 +2,232 @@ package main
 
 import (
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// parseSupportPacket extracts and parses logs from a Mattermost support packet zip file
-func parseSupportPacket(zipFilePath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr string) ([]LogEntry, error) {
+// supportPacketMetadataFiles lists the support packet files that may record the server version
+var supportPacketMetadataFiles = []string{"metadata.yaml", "support_packet.yaml", "metadata.json"}
+
+// supportPacketConfigFile is the configuration of the server in a support packet, with its
+// secrets removed
+const supportPacketConfigFile = "sanitized_config.json"
+
+// packetLogFile is a log file of a support packet
+type packetLogFile struct {
+	Name string // Path in the zip file
+	Kind string // mattermost, notifications, or the base name of other logs
+	Node string // Directory of the file, which is the node name in packets of a cluster
+	Size uint64
+}
+
+// packetLogSelection chooses which log files of a support packet are parsed
+type packetLogSelection struct {
+	Kinds  []string // Kinds of logs to parse, all if empty
+	Nodes  []string // Nodes to parse the logs of, all if empty
+	Prompt bool     // Whether to ask which of the remaining files to parse
+}
+
+// isSupportPacketLogFile reports whether a file of a support packet is a log file to parse
+func isSupportPacketLogFile(name string) bool {
+	return strings.HasSuffix(name, "mattermost.log") ||
+		strings.HasSuffix(name, "notifications.log") ||
+		strings.Contains(name, "/logs/") ||
+		strings.Contains(name, "\\logs\\") ||
+		strings.Contains(name, "notification") ||
+		isNotificationCSVName(name) ||
+		hasParserPlugin(name)
+}
+
+// newPacketLogFile returns the kind and node of a log file of a support packet
+func newPacketLogFile(name string, size uint64) packetLogFile {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	base := strings.ToLower(path.Base(slashed))
+	kind := strings.TrimSuffix(base, path.Ext(base))
+	switch {
+	case strings.Contains(base, "notification"), isNotificationCSVName(base):
+		kind = "notifications"
+	case strings.HasPrefix(base, "mattermost"):
+		kind = "mattermost"
+	}
+
+	dir := path.Dir(slashed)
+	for path.Base(dir) == "logs" {
+		dir = path.Dir(dir)
+	}
+	node := ""
+	if dir != "." && dir != "/" {
+		node = path.Base(dir)
+	}
+	return packetLogFile{Name: name, Kind: kind, Node: node, Size: size}
+}
+
+// listPacketLogFiles returns the log files of an opened support packet
+func listPacketLogFiles(reader *zip.ReadCloser) []packetLogFile {
+	var files []packetLogFile
+	for _, file := range reader.File {
+		if isSupportPacketLogFile(file.Name) {
+			files = append(files, newPacketLogFile(file.Name, file.UncompressedSize64))
+		}
+	}
+	return files
+}
+
+// listSupportPacketLogs returns the log files of a support packet
+func listSupportPacketLogs(zipFilePath string) ([]packetLogFile, error) {
+	reader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open support packet: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	return listPacketLogFiles(reader), nil
+}
+
+// packetLogCompletions returns the distinct values of a field of the log files of the support
+// packet given as argument, for shell completion
+func packetLogCompletions(args []string, field func(packetLogFile) string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	files, err := listSupportPacketLogs(args[0])
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, file := range files {
+		if value := field(file); value != "" && !contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// filterPacketLogs keeps the log files of the given kinds and nodes, case-insensitively. Empty
+// lists keep all files.
+func filterPacketLogs(files []packetLogFile, kinds, nodes []string) []packetLogFile {
+	matches := func(values []string, value string) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var kept []packetLogFile
+	for _, file := range files {
+		if matches(kinds, file.Kind) && matches(nodes, file.Node) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// parseFileNumbers parses a list of numbers and ranges from 1 to n, such as "1,3-4", or "all"
+func parseFileNumbers(answer string, n int) ([]int, error) {
+	if strings.EqualFold(strings.TrimSpace(answer), "all") {
+		numbers := make([]int, n)
+		for i := range numbers {
+			numbers[i] = i + 1
+		}
+		return numbers, nil
+	}
+
+	var numbers []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(answer, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid file number %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("invalid file number %q", part)
+			}
+		}
+		if from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("file numbers must be from 1 to %d", n)
+		}
+		for i := from; i <= to; i++ {
+			if !seen[i] {
+				seen[i] = true
+				numbers = append(numbers, i)
+			}
+		}
+	}
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("no file selected")
+	}
+	return numbers, nil
+}
+
+// promptPacketLogs lists the log files and asks which ones to parse, until the answer is valid
+func promptPacketLogs(files []packetLogFile, in io.Reader, out io.Writer) ([]packetLogFile, error) {
+	_, _ = fmt.Fprintln(out, "Log files of the support packet:")
+	for i, file := range files {
+		node := file.Node
+		if node == "" {
+			node = "-"
+		}
+		_, _ = fmt.Fprintf(out, "  %d) %s (%s, node %s, %.1f MB)\n", i+1, file.Name, file.Kind, node, float64(file.Size)/(1<<20))
+	}
+
+	w := newSetupWizard(in, out)
+	for {
+		answer, err := w.ask("Log files to load (e.g. 1,3-4)", "all")
+		if err != nil {
+			return nil, err
+		}
+		numbers, err := parseFileNumbers(answer, len(files))
+		if err != nil {
+			_, _ = fmt.Fprintln(out, err)
+			continue
+		}
+		selected := make([]packetLogFile, 0, len(numbers))
+		for _, n := range numbers {
+			selected = append(selected, files[n-1])
+		}
+		return selected, nil
+	}
+}
+
+// selectPacketLogs applies the selection to the log files of a support packet
+func selectPacketLogs(files []packetLogFile, selection packetLogSelection) ([]packetLogFile, error) {
+	selected := filterPacketLogs(files, selection.Kinds, selection.Nodes)
+	if len(selected) == 0 && len(files) > 0 {
+		var available []string
+		for _, file := range files {
+			available = append(available, fmt.Sprintf("%s (%s, node %q)", file.Name, file.Kind, file.Node))
+		}
+		return nil, fmt.Errorf("no log file of the support packet matches --logs and --node; the packet has %s",
+			strings.Join(available, ", "))
+	}
+	if selection.Prompt && len(selected) > 1 {
+		return promptPacketLogs(selected, os.Stdin, os.Stderr)
+	}
+	return selected, nil
+}
+
+// parseSupportPacket extracts and parses logs from a Mattermost support packet zip file, only
+// parsing the log files chosen by the selection
+func parseSupportPacket(zipFilePath string, selection packetLogSelection, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr string) ([]LogEntry, error) {
 	// Open the zip file
 	reader, err := zip.OpenReader(zipFilePath)
 	if err != nil {
@@ -18,6 +235,15 @@ func parseSupportPacket(zipFilePath, searchTerm, regexPattern, levelFilter, user
 	}
 	defer func() { _ = reader.Close() }()
 
+	selected, err := selectPacketLogs(listPacketLogFiles(reader), selection)
+	if err != nil {
+		return nil, err
+	}
+	chosen := make(map[string]packetLogFile, len(selected))
+	for _, file := range selected {
+		chosen[file.Name] = file
+	}
+
 	var allLogs []LogEntry
 
 	// Create a temporary directory to extract files
@@ -27,35 +253,45 @@ func parseSupportPacket(zipFilePath, searchTerm, regexPattern, levelFilter, user
 	}
 	defer func() { _ = os.RemoveAll(tempDir) }() // Clean up when done
 
-	// Look for log files in the zip
+	// Parse the chosen log files of the zip
 	for _, file := range reader.File {
-		// Check if it's a log file
-		if strings.HasSuffix(file.Name, "mattermost.log") ||
-			strings.HasSuffix(file.Name, "notifications.log") ||
-			strings.Contains(file.Name, "/logs/") ||
-			strings.Contains(file.Name, "\\logs\\") ||
-			strings.Contains(file.Name, "notification") {
-
-			// Extract the file
-			extractedPath := filepath.Join(tempDir, filepath.Base(file.Name))
-			if err := extractZipFile(file, extractedPath); err != nil {
-				logger.Warn("Failed to extract file from support packet", "file", file.Name, "error", err)
-				continue
+		logFile, ok := chosen[file.Name]
+		if !ok {
+			continue
+		}
+
+		// Extract the file
+		extractedPath := filepath.Join(tempDir, filepath.Base(file.Name))
+		if err := extractZipFile(file, extractedPath); err != nil {
+			if err := inputErrors.handle(zipFilePath+":"+file.Name, fmt.Errorf("failed to extract file: %v", err)); err != nil {
+				return nil, err
 			}
+			continue
+		}
 
-			// Parse the extracted log file
-			logs, err := parseLogFile(extractedPath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr)
-			if err != nil {
-				logger.Warn("Failed to parse log file", "file", file.Name, "error", err)
-				continue
+		// Parse the extracted log file
+		entrySpill.rename(extractedPath, file.Name)
+		parsingNode = logFile.Node
+		logs, err := parseLogFile(extractedPath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr)
+		parsingNode = ""
+		if err != nil {
+			if err := inputErrors.handle(zipFilePath+":"+file.Name, err); err != nil {
+				return nil, err
 			}
+			continue
+		}
 
-			// Add to our collection
-			allLogs = append(allLogs, logs...)
+		// Cite the file by its path in the support packet rather than the temporary copy
+		for i := range logs {
+			logs[i].SourceFile = file.Name
 		}
+
+		// Add to our collection
+		allLogs = entrySpill.collect(allLogs, logs)
 	}
+	allLogs = entrySpill.unspilled(allLogs)
 
-	if len(allLogs) == 0 {
+	if len(allLogs) == 0 && !entrySpill.spilled() {
 		fmt.Println("No log files found in the support packet or no entries matched your criteria.")
 	}
 
@@ -82,3 +318,73 @@ func extractZipFile(file *zip.File, destPath string) error {
 	_, err = io.Copy(dest, src)
 	return err
 }
+
+// readSupportPacketServerVersion returns the Mattermost server version recorded in the
+// support packet metadata, or an empty string if the packet does not record it
+func readSupportPacketServerVersion(zipFilePath string) (string, error) {
+	reader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open support packet: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	for _, file := range reader.File {
+		if !contains(supportPacketMetadataFiles, path.Base(strings.ReplaceAll(file.Name, "\\", "/"))) {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		version := parseServerVersion(src)
+		_ = src.Close()
+		if version != "" {
+			return version, nil
+		}
+	}
+	return "", nil
+}
+
+// parseServerVersion returns the value of the server_version key of a YAML metadata file, or
+// of a JSON one written with one key per line
+func parseServerVersion(reader io.Reader) string {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && strings.Trim(strings.TrimSpace(key), `"`) == "server_version" {
+			return strings.Trim(strings.TrimSuffix(strings.TrimSpace(value), ","), `"' `)
+		}
+	}
+	return ""
+}
+
+// readSupportPacketConfig returns the sanitized configuration of the support packet as compact
+// JSON. Packets of a cluster have one per node; the first is returned.
+func readSupportPacketConfig(zipFilePath string) (string, error) {
+	reader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open support packet: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	for _, file := range reader.File {
+		if path.Base(strings.ReplaceAll(file.Name, "\\", "/")) != supportPacketConfigFile {
+			continue
+		}
+		src, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(src)
+		_ = src.Close()
+		if err != nil {
+			return "", err
+		}
+		var config bytes.Buffer
+		if err := json.Compact(&config, data); err != nil || !bytes.HasPrefix(config.Bytes(), []byte("{")) {
+			return "", fmt.Errorf("%s is not a JSON object", file.Name)
+		}
+		return config.String(), nil
+	}
+	return "", fmt.Errorf("the support packet has no %s", supportPacketConfigFile)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tarMagicOffset is the offset of the "ustar" magic in the first header of a tar file
+const tarMagicOffset = 257
+
+// isTarFile reports whether a file is a tarball, compressed or not, by the magic of its first
+// header. Only regular files are checked, as reading the start of a pipe would lose it.
+func isTarFile(filePath string) (bool, error) {
+	if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+		return false, err
+	}
+	file, err := openLogFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	header := make([]byte, tarMagicOffset+5)
+	if _, err := io.ReadFull(file, header); err != nil {
+		// Files shorter than a tar header are not tarballs
+		return false, nil
+	}
+	return bytes.Equal(header[tarMagicOffset:], []byte("ustar")), nil
+}
+
+// isTarLogMember reports whether a file of a tarball looks like a log, such as
+// mattermost.log, notifications.log, a rotated mattermost.log.1.gz or a notification
+// delivery CSV
+func isTarLogMember(name string) bool {
+	return strings.Contains(strings.ToLower(path.Base(name)), ".log") || isNotificationCSVName(name) || hasParserPlugin(name)
+}
+
+// parseTarFile parses the log files of a tarball, such as a copy of /var/log/mattermost,
+// without extracting the whole tarball first. Each log file is extracted to a temporary file
+// and parsed like the other files, compressed ones included.
+func parseTarFile(tarPath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr string) ([]LogEntry, error) {
+	file, err := openLogFile(tarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	tempDir, err := os.MkdirTemp("", "lamp_tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	var allLogs []LogEntry
+	reader := tar.NewReader(file)
+	for index := 0; ; index++ {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tarball %s: %v", tarPath, err)
+		}
+		if header.Typeflag != tar.TypeReg || !isTarLogMember(header.Name) {
+			continue
+		}
+
+		// Members of different directories may have the same base name
+		extractedPath := filepath.Join(tempDir, strconv.Itoa(index)+"-"+path.Base(header.Name))
+		if err := extractTarMember(reader, extractedPath); err != nil {
+			if err := inputErrors.handle(tarPath+":"+header.Name, fmt.Errorf("failed to extract file: %v", err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		entrySpill.rename(extractedPath, header.Name)
+		logs, err := parseLogFile(extractedPath, searchTerm, regexPattern, levelFilter, userFilter, startTimeStr, endTimeStr)
+		if err != nil {
+			if err := inputErrors.handle(tarPath+":"+header.Name, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Cite the file by its path in the tarball rather than the temporary copy
+		for i := range logs {
+			logs[i].SourceFile = header.Name
+		}
+		allLogs = entrySpill.collect(allLogs, logs)
+	}
+	allLogs = entrySpill.unspilled(allLogs)
+
+	// Rotated files are merged in timestamp order
+	if !entrySpill.spilled() {
+		sortEntries(allLogs)
+	}
+	return allLogs, nil
+}
+
+// extractTarMember writes the current member of a tarball to a file
+func extractTarMember(reader *tar.Reader, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
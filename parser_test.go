@@ -1,14 +1,24 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/unicode"
 )
 
 func TestParsePlainTextLogLine(t *testing.T) {
@@ -313,6 +323,9 @@ func TestMultiFileLogProcessing(t *testing.T) {
 		for i, msg := range expectedOrder {
 			assert.Contains(t, allLogs[i].Message, msg, "Log entry %d should be %s", i, msg)
 		}
+
+		// The original line is kept for display
+		assert.Equal(t, `info [2025-01-01 10:01:15.000 Z] Config loaded caller="config/loader.go:33"`, allLogs[1].Raw)
 	})
 
 	t.Run("parse multiple log files with filters", func(t *testing.T) {
@@ -403,3 +416,824 @@ func TestMultiFileLogProcessing(t *testing.T) {
 		assert.Equal(t, 2, len(allLogs))
 	})
 }
+
+// writePacket writes a support packet of the given files and contents
+func writePacket(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "packet.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	writer := zip.NewWriter(file)
+	for name, contents := range files {
+		w, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+	return path
+}
+
+func TestReadSupportPacketServerVersion(t *testing.T) {
+	t.Run("metadata file", func(t *testing.T) {
+		path := writePacket(t, map[string]string{
+			"packet/mattermost.log": "",
+			"packet/metadata.yaml":  "version: 1\ntype: support-packet\nserver_version: \"9.5.1\"\nserver_id: abc\n",
+		})
+		version, err := readSupportPacketServerVersion(path)
+		require.NoError(t, err)
+		assert.Equal(t, "9.5.1", version)
+	})
+
+	t.Run("legacy support packet file", func(t *testing.T) {
+		path := writePacket(t, map[string]string{"support_packet.yaml": "license_to: Example\nserver_version: 7.8.0\n"})
+		version, err := readSupportPacketServerVersion(path)
+		require.NoError(t, err)
+		assert.Equal(t, "7.8.0", version)
+	})
+
+	t.Run("JSON metadata file", func(t *testing.T) {
+		path := writePacket(t, map[string]string{"metadata.json": "{\n  \"version\": 1,\n  \"server_version\": \"10.2.0\",\n  \"server_id\": \"abc\"\n}\n"})
+		version, err := readSupportPacketServerVersion(path)
+		require.NoError(t, err)
+		assert.Equal(t, "10.2.0", version)
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		path := writePacket(t, map[string]string{"mattermost.log": ""})
+		version, err := readSupportPacketServerVersion(path)
+		require.NoError(t, err)
+		assert.Empty(t, version)
+	})
+}
+
+func TestReadSupportPacketConfig(t *testing.T) {
+	path := writePacket(t, map[string]string{
+		"packet/mattermost.log":        "",
+		"packet/sanitized_config.json": "{\n  \"SqlSettings\": {\n    \"MaxOpenConns\": 100\n  }\n}\n",
+	})
+	config, err := readSupportPacketConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"SqlSettings":{"MaxOpenConns":100}}`, config)
+
+	_, err = readSupportPacketConfig(writePacket(t, map[string]string{"mattermost.log": ""}))
+	assert.EqualError(t, err, "the support packet has no sanitized_config.json")
+
+	_, err = readSupportPacketConfig(writePacket(t, map[string]string{"sanitized_config.json": "[1, 2]"}))
+	assert.EqualError(t, err, "sanitized_config.json is not a JSON object")
+}
+
+func TestSupportPacketLogSelection(t *testing.T) {
+	line := func(msg string) string {
+		return `{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"` + msg + `"}` + "\n"
+	}
+	path := writePacket(t, map[string]string{
+		"packet/node1/logs/mattermost.log":    line("node1 server"),
+		"packet/node1/logs/notifications.log": line("node1 notifications"),
+		"packet/node2/mattermost.log":         line("node2 server"),
+		"packet/node2/sanitized_config.json":  "{}",
+	})
+
+	files, err := listSupportPacketLogs(path)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	byName := make(map[string]packetLogFile)
+	for _, file := range files {
+		byName[file.Name] = file
+	}
+	assert.Equal(t, packetLogFile{Name: "packet/node1/logs/notifications.log", Kind: "notifications", Node: "node1", Size: uint64(len(line("node1 notifications")))},
+		byName["packet/node1/logs/notifications.log"])
+	assert.Equal(t, "mattermost", byName["packet/node2/mattermost.log"].Kind)
+	assert.Equal(t, "node2", byName["packet/node2/mattermost.log"].Node)
+	assert.Equal(t, "", newPacketLogFile("mattermost.log", 0).Node, "files at the root have no node")
+	assert.Equal(t, "audit", newPacketLogFile("logs/audit.log", 0).Kind)
+
+	initLogger()
+	logs, err := parseSupportPacket(path, packetLogSelection{Kinds: []string{"Mattermost"}, Nodes: []string{"node1"}}, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "node1 server", logs[0].Message)
+	assert.Equal(t, "packet/node1/logs/mattermost.log", logs[0].SourceFile)
+	assert.Equal(t, "node1", logs[0].Node)
+
+	_, err = parseSupportPacket(path, packetLogSelection{Nodes: []string{"node3"}}, "", "", "", "", "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no log file of the support packet matches --logs and --node")
+
+	t.Run("deprecated --nodes", func(t *testing.T) {
+		defer func() { packetLogs = packetLogSelection{} }()
+		flag := supportPacketCmd.Flags().Lookup("nodes")
+		require.NotNil(t, flag)
+		assert.NotEmpty(t, flag.Deprecated)
+		require.NoError(t, flag.Value.Set("node2"))
+		assert.Equal(t, []string{"node2"}, packetLogs.Nodes, "--nodes sets the nodes of --node")
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		var out bytes.Buffer
+		selected, err := promptPacketLogs(files[:3], strings.NewReader("5\n3, 1\n"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, []packetLogFile{files[2], files[0]}, selected)
+		assert.Contains(t, out.String(), "file numbers must be from 1 to 3")
+
+		selected, err = promptPacketLogs(files, strings.NewReader("\n"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, files, selected, "all files by default")
+	})
+
+	t.Run("file numbers", func(t *testing.T) {
+		numbers, err := parseFileNumbers("1,3-4, 3", 4)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 3, 4}, numbers)
+		_, err = parseFileNumbers("2-x", 4)
+		assert.EqualError(t, err, `invalid file number "2-x"`)
+		_, err = parseFileNumbers("", 4)
+		assert.EqualError(t, err, "no file selected")
+	})
+}
+
+func TestNodeFilter(t *testing.T) {
+	initLogger()
+	path := filepath.Join(t.TempDir(), "mattermost.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"first","hostname":"app-1"}
+{"timestamp":"2024-03-01 10:00:01.000 Z","level":"info","msg":"second","hostname":"app-2"}
+{"timestamp":"2024-03-01 10:00:02.000 Z","level":"info","msg":"third"}
+`), 0o644))
+
+	logs, err := parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+	assert.Equal(t, []string{"app-1", "app-2", ""}, []string{logs[0].Node, logs[1].Node, logs[2].Node},
+		"the node of an entry is named by its hostname")
+
+	nodeFilter = []string{"App-2"}
+	defer func() { nodeFilter = nil }()
+	logs, err = parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "second", logs[0].Message)
+
+	entry := LogEntry{Node: "node1", Extras: map[string]string{"hostname": "app-1"}}
+	setEntryNode(&entry)
+	assert.Equal(t, "node1", entry.Node, "exported entries keep their node")
+	parsingNode = "node2"
+	defer func() { parsingNode = "" }()
+	entry = LogEntry{Extras: map[string]string{"hostname": "app-1"}}
+	setEntryNode(&entry)
+	assert.Equal(t, "node2", entry.Node, "the node of the packet file comes first")
+}
+
+func TestFastScan(t *testing.T) {
+	initLogger()
+	path := filepath.Join(t.TempDir(), "mattermost.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"debug","msg":"Received HTTP request with level error"}`,
+		`{"timestamp":"2024-03-01 10:00:01.000 Z","level":"error","msg":"Failed to ping DB"}`,
+		`{"timestamp":"2024-03-01 10:00:02.000 Z", "level": "info", "msg":"Server is starting"}`,
+		`debug [2024-03-01 10:00:03.000 Z] Cache hit caller="cache/store.go:78"`,
+		`ERROR [2024-03-01 10:00:04.000 Z] Connection failed caller="network/conn.go:123"`,
+	}, "\n")), 0o600))
+	defer func() { fastScan = false }()
+
+	fastScan = true
+	logs, err := parseLogFile(path, "", "", "error", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "Failed to ping DB", logs[0].Message)
+	assert.Equal(t, 5, logs[1].Line, "line numbers count the skipped lines")
+
+	logs, err = parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 3, "debug lines are skipped without --level")
+	assert.Equal(t, "Server is starting", logs[1].Message)
+
+	fastScan = false
+	logs, err = parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	assert.Len(t, logs, 5)
+}
+
+func TestRawLinePrefilter(t *testing.T) {
+	lines := []string{
+		`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"error","msg":"Failed to ping DB","caller":"sqlstore/store.go:300","retries":3}`,
+		`{"timestamp":"2024-03-01 10:00:01.000 Z","level":"info","msg":"Received HTTP request","url":"/api/v4/users/me","status_code":200}`,
+		`{"timestamp":"2024-03-01 10:00:02.000 Z","level":"warn","msg":"Quote \"broken\" <b>tag</b> & café","user_id":"abc"}`,
+		`debug [2024-03-01 10:00:03.000 Z] Cache   hit caller="cache/store.go:78"`,
+	}
+	filters := []struct {
+		search string
+		regex  string
+	}{
+		{search: "PING db"},
+		{search: "status_code=200"},
+		{search: "store.go"},
+		{search: `"broken"`},
+		{search: "<b>tag</b> & café"},
+		{search: "cache hit"},
+		{regex: `^Failed to (ping|reach) DB$`},
+		{regex: `(?i)received http`},
+		{regex: `status_code=2\d\d`},
+		{regex: `users/(me|[a-z0-9]+)`},
+		{regex: `retries=[0-9]+`},
+		{regex: `Cache hit`},
+	}
+
+	rejected := 0
+	for _, filter := range filters {
+		var regex *regexp.Regexp
+		if filter.regex != "" {
+			regex = regexp.MustCompile(filter.regex)
+		}
+		prefilter := rawLinePrefilter(filter.search, regex)
+		require.NotNil(t, prefilter, "%+v", filter)
+		for _, line := range lines {
+			entry, err := parseLine(line)
+			require.NoError(t, err)
+			if !prefilter(line) {
+				rejected++
+				assert.False(t, shouldIncludeEntry(entry, filter.search, regex, "", "", time.Time{}, time.Time{}),
+					"the prefilter rejected a matching line: %+v %s", filter, line)
+			}
+		}
+	}
+	assert.Greater(t, rejected, len(filters), "most non-matching lines are rejected before parsing")
+
+	assert.Nil(t, rawLinePrefilter("", nil))
+	assert.Nil(t, rawLinePrefilter("=,", regexp.MustCompile(`.*|x`)), "nothing to check on the raw line")
+
+	t.Run("same results as without the prefilter", func(t *testing.T) {
+		// The extras are matched as "key=value, ..." sorted by key, with numbers, objects
+		// and arrays marshaled again
+		lines := []string{
+			`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"m","zeta":"x","alpha":"y"}`,
+			`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"m","n":1e3}`,
+			`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"m","obj":{"b": true, "a": [1, 2]}}`,
+		}
+		filters := []struct {
+			search string
+			regex  string
+		}{
+			{regex: `y, zeta`},
+			{search: "y, zeta"},
+			{search: "n=1000"},
+			{regex: `n=1000`},
+			{search: `{"a":[1,2],"b":true}`},
+			{regex: `"a":\[1,2\]`},
+			{search: "true}"},
+		}
+		for _, filter := range filters {
+			var regex *regexp.Regexp
+			if filter.regex != "" {
+				regex = regexp.MustCompile(filter.regex)
+			}
+			prefilter := rawLinePrefilter(filter.search, regex)
+			var withPrefilter, withoutPrefilter []string
+			for _, line := range lines {
+				entry, err := parseLine(line)
+				require.NoError(t, err)
+				if !shouldIncludeEntry(entry, filter.search, regex, "", "", time.Time{}, time.Time{}) {
+					continue
+				}
+				withoutPrefilter = append(withoutPrefilter, line)
+				if prefilter == nil || prefilter(line) {
+					withPrefilter = append(withPrefilter, line)
+				}
+			}
+			assert.NotEmpty(t, withoutPrefilter, "%+v", filter)
+			assert.Equal(t, withoutPrefilter, withPrefilter, "%+v", filter)
+		}
+	})
+}
+
+func TestParseJSONLinePooling(t *testing.T) {
+	first, err := parseJSONLine(`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"a","caller":"web/handlers.go:187","request_id":"r1","count":3}`)
+	require.NoError(t, err)
+	releaseExtras(first.Extras)
+
+	entry, err := parseJSONLine(`{"timestamp":"2024-03-01 10:00:01.000 Z","level":"info","msg":"b","caller":"web/handlers.go:187","url":"/api/v4/users"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"url": "/api/v4/users"}, entry.Extras, "reused extras maps are empty")
+	assert.Equal(t, "web/handlers.go:187", entry.Source)
+	assert.Equal(t, mustParseTime(t, "2024-03-01 10:00:01.000 Z"), entry.Timestamp)
+
+	_, err = parseJSONLine(`{"timestamp":"2024-03-01 10:00:01.000 Z","level":5,"msg":"b"}`)
+	assert.EqualError(t, err, "failed to parse JSON log: field level is not a string")
+	_, err = parseJSONLine(`{"timestamp":`)
+	assert.Error(t, err)
+
+	interner := &stringInterner{strings: make(map[string]string)}
+	shared := interner.intern(string([]byte("request_id")))
+	assert.Equal(t, "request_id", interner.intern(string([]byte("request_id"))))
+	assert.Len(t, interner.strings, 1)
+	assert.Equal(t, shared, interner.strings["request_id"])
+	interner.intern(strings.Repeat("x", internMaxLength+1))
+	assert.Len(t, interner.strings, 1, "long strings are not interned")
+}
+
+// BenchmarkParseJSONLine measures the parsing of a typical line of a Mattermost JSON log:
+// go test -run '^$' -bench ParseJSONLine -benchmem
+func BenchmarkParseJSONLine(b *testing.B) {
+	line := `{"timestamp":"2024-03-01 10:00:00.123 Z","level":"error","msg":"Failed to create post","caller":"app/post.go:187",` +
+		`"path":"/api/v4/posts","request_id":"b3q6t4n5kjrp8e9x","ip_addr":"10.0.0.12","user_id":"wn8f3a1s7jd4","method":"POST","err_where":"CreatePost","http_code":500}`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseJSONLine(line); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEntryOrdering(t *testing.T) {
+	at := mustParseTime(t, "2024-03-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: at, SourceFile: "b.log", Line: 1, Message: "b1"},
+		{Timestamp: at, SourceFile: "a.log", Line: 2, Message: "a2"},
+		{Timestamp: at.Add(-time.Millisecond), SourceFile: "b.log", Line: 9, Message: "earlier"},
+		{Timestamp: at, SourceFile: "a.log", Line: 1, Seq: 2, Message: "a1 read again"},
+		{Timestamp: at, SourceFile: "a.log", Line: 1, Seq: 1, Message: "a1"},
+	}
+	for _, reversed := range []bool{false, true} {
+		input := slices.Clone(logs)
+		if reversed {
+			slices.Reverse(input)
+		}
+		sortEntries(input)
+		var messages []string
+		for _, log := range input {
+			messages = append(messages, log.Message)
+		}
+		assert.Equal(t, []string{"earlier", "a1", "a1 read again", "a2", "b1"}, messages)
+	}
+
+	t.Run("numbers entries in the order they are read", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mattermost.log")
+		line := `{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"same time"}` + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(line, 3)), 0o600))
+		parsed, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, parsed, 3)
+		assert.Less(t, parsed[0].Seq, parsed[1].Seq)
+		assert.Less(t, parsed[1].Seq, parsed[2].Seq)
+	})
+
+	t.Run("keeps the occurrences per node of duplicates", func(t *testing.T) {
+		initLogger()
+		var input []LogEntry
+		for i := 0; i < 1000; i++ {
+			node := "node1"
+			if i%100 == 99 {
+				node = "node2"
+			}
+			input = append(input, LogEntry{Timestamp: at.Add(time.Duration(i) * time.Second), Level: "error", Source: "app/db.go",
+				Message: fmt.Sprintf("Failed to ping DB %d", i), Node: node})
+		}
+		input = append(input, LogEntry{Timestamp: at, Level: "info", Source: "app/server.go", Message: "Server started", Node: "node1"},
+			LogEntry{Timestamp: at.Add(time.Second), Level: "info", Source: "app/server.go", Message: "Server started", Node: "node1"})
+
+		for name, trimmed := range map[string][]LogEntry{
+			"sequential": trimDuplicateLogsSequential(input, 0.8, 100, 10, newPlainProgress(nil, len(input), "")),
+			"parallel":   trimDuplicateLogsParallel(input, 0.8, newPlainProgress(nil, len(input), "")),
+		} {
+			require.Len(t, trimmed, 2, name)
+			assert.Equal(t, 1000, trimmed[0].DuplicateCount, name)
+			assert.Equal(t, map[string]int{"node1": 990, "node2": 10}, trimmed[0].NodeCounts, name)
+			assert.Equal(t, "node1: 990, node2: 10", formatNodeCounts(trimmed[0].NodeCounts), name)
+			assert.Equal(t, 2, trimmed[1].DuplicateCount, name)
+			assert.Nil(t, trimmed[1].NodeCounts, "%s: duplicates of a single node have no breakdown", name)
+		}
+	})
+
+	t.Run("deduplicates in the same order in parallel", func(t *testing.T) {
+		initLogger()
+		var input []LogEntry
+		for i := 0; i < 1200; i++ {
+			input = append(input, LogEntry{
+				Timestamp: at.Add(time.Duration(i) * time.Second),
+				Level:     []string{"info", "warn", "error", "debug"}[i%4],
+				Source:    "app/file.go",
+				Message:   fmt.Sprintf("%s %d", []string{"User logged in", "Slow query", "Websocket closed", "Cache miss"}[i%7%4], i),
+			})
+		}
+		quiet := newPlainProgress(nil, len(input), "")
+		sequential := trimDuplicateLogsSequential(input, 0.8, 100, 10, quiet)
+		for run := 0; run < 3; run++ {
+			assert.Equal(t, sequential, trimDuplicateLogsParallel(input, 0.8, newPlainProgress(nil, len(input), "")))
+		}
+	})
+}
+
+func TestReadExportedEntries(t *testing.T) {
+	at := mustParseTime(t, "2024-03-01 10:00:00.000 Z")
+	exported := []LogEntry{
+		{Timestamp: at, Level: "error", Message: "db failed", Source: "app/db.go:10", User: "u1", DuplicateCount: 4, SourceFile: "packet/logs/mattermost.log", Node: "node-1", Extras: map[string]string{"err": "timeout"}},
+		{Timestamp: at.Add(time.Second), Level: "info", Message: "ok", DuplicateCount: 1},
+	}
+	dir := t.TempDir()
+
+	t.Run("JSON Lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeLogsNDJSON(exported, &buf))
+		mattermost := `{"timestamp":"2024-03-01 10:00:02.000 Z","level":"warn","msg":"slow","caller":"app/x.go:1"}` + "\n"
+		path := filepath.Join(dir, "export.jsonl")
+		require.NoError(t, os.WriteFile(path, append(buf.Bytes(), mattermost...), 0o600))
+
+		parsed, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, parsed, 3)
+		assert.Equal(t, exported[0].Extras, parsed[0].Extras)
+		assert.Equal(t, 4, parsed[0].DuplicateCount)
+		assert.Equal(t, "packet/logs/mattermost.log", parsed[0].SourceFile)
+		assert.Equal(t, "node-1", parsed[0].Node)
+		assert.Equal(t, path, parsed[1].SourceFile, "entries exported without a file cite the export")
+		assert.Equal(t, 2, parsed[1].Line)
+		assert.Equal(t, "slow", parsed[2].Message)
+		assert.Equal(t, "app/x.go:1", parsed[2].Source)
+	})
+
+	t.Run("JSON array", func(t *testing.T) {
+		path := filepath.Join(dir, "export.json")
+		require.NoError(t, writeLogsToJSON(exported, path))
+
+		parsed, err := parseLogFile(path, "", "", "error", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, parsed, 1)
+		assert.Equal(t, "db failed", parsed[0].Message)
+		assert.Equal(t, 4, parsed[0].DuplicateCount)
+		assert.Equal(t, "node-1", parsed[0].Node)
+	})
+
+	t.Run("trim output by extension", func(t *testing.T) {
+		path := filepath.Join(dir, "trimmed.ndjson")
+		require.NoError(t, writeLogsToJSON(exported, path))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 2)
+	})
+}
+
+func TestFileEncodings(t *testing.T) {
+	text := `{"timestamp":"2024-03-01 10:00:00.000 Z","level":"error","msg":"db failed","caller":"app/db.go:10"}` + "\r\n" +
+		`info [2024-03-01 10:00:01.000 Z] Server started caller="app/server.go:20"` + "\r\n"
+	utf16 := func(endianness unicode.Endianness, bom unicode.BOMPolicy) []byte {
+		data, err := unicode.UTF16(endianness, bom).NewEncoder().Bytes([]byte(text))
+		require.NoError(t, err)
+		return data
+	}
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err := gz.Write([]byte(text))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-8", []byte(text)},
+		{"UTF-8 with BOM", append([]byte("\xEF\xBB\xBF"), text...)},
+		{"UTF-16LE with BOM", utf16(unicode.LittleEndian, unicode.UseBOM)},
+		{"UTF-16BE with BOM", utf16(unicode.BigEndian, unicode.UseBOM)},
+		{"UTF-16LE without BOM", utf16(unicode.LittleEndian, unicode.IgnoreBOM)},
+		{"UTF-16BE without BOM", utf16(unicode.BigEndian, unicode.IgnoreBOM)},
+		{"gzip", gzipped.Bytes()},
+		// Written by bzip2 -9, the standard library only decompresses bzip2
+		{"bzip2", []byte("" +
+			"\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x53\x70\x39\xba\x00\x00\x56\x5b\x80\x00\x10\x50\x07\xfc" +
+			"\x12\x08\x1a\x3f\xa7\xdd\x0a\x20\x00\x8a\x86\xa6\x12\x87\x94\xc1\xa8\x37\xa9\x0c\x06\xd5\x04\xaa" +
+			"\x79\x04\x00\x00\x00\x01\xd2\x43\xc4\xc6\xad\x8e\xea\x9e\xd3\xce\xe7\x9e\xaa\xd4\x30\x41\xe7\x7c" +
+			"\x18\x64\x12\x97\xd9\x68\x51\x94\x4e\x1d\xd8\x21\x88\x8e\x14\x6b\x84\x06\xe2\x24\xda\x34\x21\x18" +
+			"\x0a\x17\x92\xc2\x17\x71\xb9\xec\x42\x64\x2b\xf6\x07\xa4\x7e\x38\x22\x25\xd9\xea\xb2\x75\x61\x59" +
+			"\xd6\xd7\x90\xb3\xa0\x42\x33\xa7\x23\xd5\xd9\x8e\xa2\x58\x94\x22\x4c\x42\xd7\xad\x75\x0f\x99\xaa" +
+			"\x50\xcf\xc5\xdc\x91\x4e\x14\x24\x14\xdc\x0e\x6e\x80")},
+		// Written by zstd -19
+		{"zstd", []byte("" +
+			"\x28\xb5\x2f\xfd\x04\x68\xfd\x03\x00\x02\x08\x1a\x19\x70\x6f\x0e\x16\xb8\x4b\xa4\x93\xa2\x16\x88" +
+			"\x91\x77\x75\x1b\xc1\x88\x90\x31\x2f\x51\xc5\xd9\xc3\x01\x40\xf0\x35\x64\xa2\x90\x67\xbf\x29\xb5" +
+			"\xf9\x6a\x56\xb4\x69\x8c\x56\x10\x25\x01\x41\xf6\x6d\xae\x8a\x08\x07\x16\x96\xf5\xee\xde\x1c\xe3" +
+			"\x7b\x2e\x1c\x72\x94\x1e\xa4\x0e\xbc\x7b\x24\xa7\x0b\xef\xaa\x9b\xef\x1e\xf9\xe2\x78\xe1\x8b\xee" +
+			"\x2e\xb1\x6e\x9b\xb6\x1d\x8e\x1d\x10\x63\x8d\xbb\x5b\xd3\x9b\x92\x27\x69\x1e\x0b\x07\x00\x4f\x08" +
+			"\xf0\xb9\x5b\xb0\xb1\x0c\xc6\x00\x06\x51\xc0\xe0\x26\x2d\x97\x07\xf9\xfb\xf2\xc3")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mattermost.log")
+			require.NoError(t, os.WriteFile(path, tt.data, 0o600))
+			logs, err := parseLogFile(path, "", "", "", "", "", "")
+			require.NoError(t, err)
+			require.Len(t, logs, 2)
+			assert.Equal(t, "db failed", logs[0].Message)
+			assert.Equal(t, "app/db.go:10", logs[0].Source)
+			assert.Equal(t, "Server started", logs[1].Message)
+		})
+	}
+
+	t.Run("invalid UTF-8 is replaced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mattermost.log")
+		line := "info [2024-03-01 10:00:01.000 Z] Caf\xE9 opened caller=\"app/x.go:1\"\n"
+		require.NoError(t, os.WriteFile(path, []byte(line), 0o600))
+		logs, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, "Caf\uFFFD opened", logs[0].Message)
+		assert.True(t, utf8.ValidString(logs[0].Raw))
+	})
+
+	t.Run("truncated zstd", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mattermost.log.zst")
+		require.NoError(t, os.WriteFile(path, []byte("\x28\xb5\x2f\xfd\x04\x58"), 0o600))
+		_, err := parseLogFile(path, "", "", "", "", "", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("JSON array with BOM", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "export.json")
+		data := "\xEF\xBB\xBF" + `[{"timestamp":"2024-03-01T10:00:00Z","level":"info","message":"exported"}]`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		logs, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, "exported", logs[0].Message)
+	})
+}
+
+func TestParseLegacyLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    LogEntry
+		wantErr bool
+	}{
+		{
+			name: "API error",
+			line: `[2019/01/02 15:04:05 UTC] [EROR] /api/v4/users/login:AuthorizeUser code=401 rid=abc123 uid= ip=10.0.0.1 Invalid credentials [details: user=bob]`,
+			want: LogEntry{
+				Timestamp: time.Date(2019, 1, 2, 15, 4, 5, 0, time.UTC),
+				Level:     "error",
+				Message:   "Invalid credentials [details: user=bob]",
+				Source:    "/api/v4/users/login:AuthorizeUser",
+				Extras:    map[string]string{"code": "401", "request_id": "abc123", "ip_address": "10.0.0.1"},
+			},
+		},
+		{
+			name: "caller in parentheses",
+			line: `[2019/01/02 15:04:07 UTC] [DEBG] (github.com/mattermost/mattermost-server/app.(*App).Foo:123) uid=u1 Cache miss`,
+			want: LogEntry{
+				Timestamp: time.Date(2019, 1, 2, 15, 4, 7, 0, time.UTC),
+				Level:     "debug",
+				Message:   "Cache miss",
+				Source:    "github.com/mattermost/mattermost-server/app.(*App).Foo:123",
+				User:      "u1",
+				Extras:    map[string]string{},
+			},
+		},
+		{
+			name: "without zone",
+			line: `[2019/01/02 15:04:08] [CRIT] Error starting server, err:listen tcp :8065: bind`,
+			want: LogEntry{
+				Timestamp: time.Date(2019, 1, 2, 15, 4, 8, 0, time.UTC),
+				Level:     "fatal",
+				Message:   "Error starting server, err:listen tcp :8065: bind",
+				Extras:    map[string]string{},
+			},
+		},
+		{
+			name:    "unknown level",
+			line:    `[2019/01/02 15:04:05 UTC] [NOPE] message`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid timestamp",
+			line:    `[yesterday] [INFO] message`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, entry)
+		})
+	}
+
+	t.Run("files", func(t *testing.T) {
+		initLogger()
+		path := filepath.Join(t.TempDir(), "mattermost.log")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+			`[2019/01/02 15:04:05 UTC] [EROR] Failed to ping DB`,
+			`[2019/01/02 15:04:06 UTC] [DEBG] Cache hit`,
+			`[2019/01/02 15:04:07 UTC] [INFO] Server is listening on :8065`,
+		}, "\n")), 0o600))
+		defer func() { fastScan = false }()
+
+		logs, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err, "logs of the older format are not JSON arrays")
+		assert.Len(t, logs, 3)
+
+		fastScan = true
+		logs, err = parseLogFile(path, "", "", "error", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, "Failed to ping DB", logs[0].Message)
+
+		logs, err = parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		assert.Len(t, logs, 2, "debug lines are skipped without --level")
+	})
+}
+
+func TestTarballInput(t *testing.T) {
+	line := func(timestamp, msg string) string {
+		return `{"timestamp":"` + timestamp + `","level":"info","msg":"` + msg + `"}` + "\n"
+	}
+	var rotated bytes.Buffer
+	gz := gzip.NewWriter(&rotated)
+	_, err := gz.Write([]byte(line("2024-03-01 09:00:00.000 Z", "Rotated entry")))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	// A gzipped tarball of /var/log/mattermost with a rotated log and a file that is not a log
+	var tarball bytes.Buffer
+	compressed := gzip.NewWriter(&tarball)
+	tw := tar.NewWriter(compressed)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "mattermost/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, member := range []struct {
+		name string
+		data []byte
+	}{
+		{"mattermost/mattermost.log", []byte(line("2024-03-01 10:00:00.000 Z", "Current entry"))},
+		{"mattermost/mattermost.log.1.gz", rotated.Bytes()},
+		{"mattermost/README.txt", []byte(line("2024-03-01 11:00:00.000 Z", "Not a log"))},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: member.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(member.data))}))
+		_, err := tw.Write(member.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, compressed.Close())
+
+	path := filepath.Join(t.TempDir(), "logs.tar.gz")
+	require.NoError(t, os.WriteFile(path, tarball.Bytes(), 0o600))
+	logs, err := parseLogFile(path, "", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "Rotated entry", logs[0].Message, "the entries of the files are merged in order")
+	assert.Equal(t, "mattermost/mattermost.log.1.gz", logs[0].SourceFile)
+	assert.Equal(t, "Current entry", logs[1].Message)
+	assert.Equal(t, "mattermost/mattermost.log", logs[1].SourceFile)
+
+	// Filters apply to the files of the tarball
+	logs, err = parseLogFile(path, "Current", "", "", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+}
+
+func TestReadLimit(t *testing.T) {
+	initLogger()
+	t.Cleanup(func() { inputLimit = nil })
+	dir := t.TempDir()
+	writeLog := func(name string, first, count int) string {
+		var lines []string
+		for i := first; i < first+count; i++ {
+			lines = append(lines, fmt.Sprintf(`{"timestamp":"2024-03-01 10:00:%02d.000 Z","level":"info","msg":"Entry %d"}`, i, i))
+		}
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+		return path
+	}
+	older := writeLog("mattermost.log.1", 0, 5)
+	newer := writeLog("mattermost.log", 5, 5)
+	parse := func(t *testing.T, limit *readLimit) []LogEntry {
+		inputLimit = limit
+		var logs []LogEntry
+		for _, path := range []string{older, newer} {
+			parsed, err := parseLogFile(path, "", "", "", "", "", "")
+			require.NoError(t, err)
+			logs = append(logs, parsed...)
+		}
+		return inputLimit.newest(logs)
+	}
+
+	t.Run("stops at the cap", func(t *testing.T) {
+		limit, err := newReadLimit(3, "", false)
+		require.NoError(t, err)
+		logs := parse(t, limit)
+		require.Len(t, logs, 3)
+		assert.Equal(t, "Entry 2", logs[2].Message)
+		assert.Equal(t, older, limit.reachedIn)
+		assert.Equal(t, "--max-lines 3", limit.reachedCap)
+		assert.Equal(t, 1, limit.skippedFiles, "the files after the cap are not read")
+	})
+
+	t.Run("keeps the newest entries", func(t *testing.T) {
+		limit, err := newReadLimit(4, "", true)
+		require.NoError(t, err)
+		logs := parse(t, limit)
+		require.Len(t, logs, 4)
+		assert.Equal(t, "Entry 6", logs[0].Message)
+		assert.Equal(t, "Entry 9", logs[3].Message)
+		assert.Equal(t, 6, limit.dropped)
+	})
+
+	t.Run("caps the bytes", func(t *testing.T) {
+		limit, err := newReadLimit(0, "200B", false)
+		require.NoError(t, err)
+		logs := parse(t, limit)
+		assert.Len(t, logs, 2, "each line is about 75 bytes")
+		assert.Equal(t, "--max-bytes 200B", limit.reachedCap)
+	})
+
+	t.Run("without caps", func(t *testing.T) {
+		limit, err := newReadLimit(0, "", false)
+		require.NoError(t, err)
+		assert.Nil(t, limit)
+		assert.Len(t, parse(t, limit), 10)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := newReadLimit(-1, "", false)
+		assert.Error(t, err)
+		_, err = newReadLimit(0, "lots", false)
+		assert.Error(t, err)
+		_, err = newReadLimit(0, "", true)
+		assert.ErrorContains(t, err, "--keep-newest requires")
+	})
+}
+
+func TestNotificationCSV(t *testing.T) {
+	initLogger()
+	dir := t.TempDir()
+
+	t.Run("delivery rows", func(t *testing.T) {
+		path := filepath.Join(dir, "notification_acks.csv")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+			"Ack ID,Type,Platform,Device ID,Sent At,Received At,Status",
+			"a1,message,ios,apple_rn-v2:d1,1709287200000,1709287201500,delivered",
+			"a2,message,android,android_rn-v2:d2,2024-03-01 10:00:05.000 Z,,failed",
+			"a3,clear,android,android_rn-v2:d3,not a time,,",
+		}, "\n")+"\n"), 0o600))
+
+		logs, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 4, "the row without a valid time is skipped")
+		assert.Equal(t, "a1", logs[0].AckID)
+		assert.Equal(t, "notifications", logs[0].LogSource)
+		assert.Equal(t, "sent", logs[0].Status)
+		assert.Equal(t, mustParseTime(t, "2024-03-01 10:00:00.000 Z"), logs[0].Timestamp)
+		assert.Equal(t, "ios", logs[0].Extras["Platform"])
+		assert.Equal(t, "received", logs[1].Status)
+		assert.Equal(t, 2, logs[1].Line)
+		assert.Equal(t, "failed", logs[3].Status)
+		assert.Equal(t, "error", logs[3].Level)
+		assert.Equal(t, "Notification failed", logs[3].Message)
+
+		push := analyzePushProxy(logs)
+		assert.Equal(t, 2, push.Sent)
+		assert.Equal(t, 1, push.Received)
+		assert.Equal(t, 1, push.Undelivered)
+		latency := analyzeNotificationLatency(logs)
+		require.Len(t, latency.Platforms, 1)
+		assert.Equal(t, 1500*time.Millisecond, latency.Platforms[0].Median)
+	})
+
+	t.Run("status rows", func(t *testing.T) {
+		path := filepath.Join(dir, "acks.csv")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+			"timestamp,ack_id,status,user_id",
+			"2024-03-01T10:00:00Z,a1,sent,u1",
+			"2024-03-01T10:00:01Z,a1,received,u1",
+		}, "\n")+"\n"), 0o600))
+
+		logs, err := parseLogFile(path, "", "", "", "", "", "")
+		require.NoError(t, err)
+		require.Len(t, logs, 2)
+		assert.Equal(t, "u1", logs[0].User)
+		assert.Equal(t, "received", logs[1].Status)
+
+		logs, err = parseLogFile(path, "", "", "", "", "2024-03-01 10:00:00.500", "")
+		require.NoError(t, err)
+		assert.Len(t, logs, 1, "filters apply to the rows")
+	})
+
+	t.Run("other CSVs", func(t *testing.T) {
+		// The CSVs exported by lamp have the columns of notification logs too
+		export := filepath.Join(dir, "export.csv")
+		require.NoError(t, exportToCSV([]LogEntry{{Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Level: "info", Message: "Exported", AckID: "a1", Status: "sent"}},
+			export, csvOptions{Delimiter: ','}))
+		users := filepath.Join(dir, "users.csv")
+		require.NoError(t, os.WriteFile(users, []byte("id,username\nu1,alice\n"), 0o600))
+		for _, path := range []string{export, users} {
+			isAckCSV, err := isNotificationCSVFile(path)
+			require.NoError(t, err)
+			assert.False(t, isAckCSV, path)
+		}
+	})
+
+	assert.True(t, isNotificationCSVName("packet/node1/notification_acks.csv"))
+	assert.False(t, isNotificationCSVName("packet/node1/users.csv"))
+	assert.Equal(t, "notifications", newPacketLogFile("packet/node1/push_acks.csv", 0).Kind)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Layout of the PDF report, in points on A4 pages. The body is set in Courier, whose
+// characters are all 0.6 em wide, so that lines are wrapped without font metrics.
+const (
+	pdfPageWidth     = 595
+	pdfPageHeight    = 842
+	pdfMargin        = 50
+	pdfBodySize      = 9
+	pdfBodyLeading   = 12
+	pdfLineChars     = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfBodySize * 6) // Courier characters per line
+	pdfTitleSize     = 16
+	pdfHeadingSize   = 12
+	pdfMaxSignatures = 10
+)
+
+// pdfDocument lays out text and images on the pages of a PDF file
+type pdfDocument struct {
+	pages  []*bytes.Buffer // Content stream of each page
+	y      float64         // Baseline of the next line on the current page, from the bottom
+	images [][]byte        // Image XObjects, referenced as /Im1, /Im2...
+}
+
+// newPDFDocument returns a document with an empty first page
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{}
+	doc.newPage()
+	return doc
+}
+
+// newPage starts a new page
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// reserve starts a new page if the current one has less than height points left
+func (d *pdfDocument) reserve(height float64) {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+}
+
+// text draws a line of text at the current position with a font of the document, /F1
+// (Courier) or /F2 (Helvetica-Bold)
+func (d *pdfDocument) text(font string, size, leading float64, line string) {
+	d.reserve(leading)
+	d.y -= leading
+	_, _ = fmt.Fprintf(d.pages[len(d.pages)-1], "BT %s %g Tf %d %g Td (%s) Tj ET\n", font, size, pdfMargin, d.y, pdfString(line))
+}
+
+// heading draws a heading with some space above it, keeping it with the next lines
+func (d *pdfDocument) heading(size float64, heading string) {
+	d.reserve(size*2 + 3*pdfBodyLeading)
+	d.y -= size / 2
+	d.text("/F2", size, size*1.5, heading)
+}
+
+// paragraph draws text wrapped to the width of the page, keeping the indentation of
+// wrapped lines, and an empty line after it
+func (d *pdfDocument) paragraph(text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		for _, wrapped := range pdfWrap(line, pdfLineChars) {
+			d.text("/F1", pdfBodySize, pdfBodyLeading, wrapped)
+		}
+	}
+	d.y -= pdfBodyLeading
+}
+
+// image draws an image scaled to the width of the page
+func (d *pdfDocument) image(rgb []byte, width, height int) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(rgb); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	var xobject bytes.Buffer
+	_, _ = fmt.Fprintf(&xobject, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n",
+		width, height, compressed.Len())
+	xobject.Write(compressed.Bytes())
+	xobject.WriteString("\nendstream")
+	d.images = append(d.images, xobject.Bytes())
+
+	drawWidth := float64(pdfPageWidth - 2*pdfMargin)
+	drawHeight := drawWidth * float64(height) / float64(width)
+	d.reserve(drawHeight + pdfBodyLeading)
+	d.y -= drawHeight
+	_, _ = fmt.Fprintf(d.pages[len(d.pages)-1], "q %g 0 0 %g %d %g cm /Im%d Do Q\n", drawWidth, drawHeight, pdfMargin, d.y, len(d.images))
+	d.y -= pdfBodyLeading
+	return nil
+}
+
+// write writes the document with its title in the metadata and page numbers in the footers
+func (d *pdfDocument) write(title string, w io.Writer) error {
+	// Objects 1 and 2 are the catalog and the page tree, 3 and 4 the fonts, then come the
+	// images and the page and content stream of each page
+	var objects []string
+	pageIDs := make([]string, len(d.pages))
+	firstPage := 5 + len(d.images)
+	for i := range d.pages {
+		pageIDs[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageIDs, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	var xobjects []string
+	for i, image := range d.images {
+		objects = append(objects, string(image))
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, 5+i))
+	}
+	for i, page := range d.pages {
+		content := page.String() + fmt.Sprintf("BT /F1 8 Tf %d %d Td (Page %d of %d) Tj ET\n", pdfMargin, pdfMargin/2, i+1, len(d.pages))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject << %s >> >> >>",
+				pdfPageWidth, pdfPageHeight, firstPage+2*i+1, strings.Join(xobjects, " ")),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Title (%s) /Producer (lamp) >>", pdfString(title)))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		_, _ = fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	_, _ = fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		_, _ = fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	_, _ = fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfString escapes text for a PDF string in the WinAnsi encoding of the fonts, replacing
+// the characters it cannot encode and the control characters with "?"
+func pdfString(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\t':
+			sb.WriteString("    ")
+		case r < ' ':
+			sb.WriteByte('?')
+		case r < 0x80:
+			sb.WriteRune(r)
+		default:
+			if b, ok := charmap.Windows1252.EncodeRune(r); ok {
+				sb.WriteString(fmt.Sprintf("\\%03o", b))
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// pdfWrap splits a line into lines of at most width characters, at spaces when possible,
+// indenting the continuation lines like the first one
+func pdfWrap(line string, width int) []string {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	indent := strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " -*")))
+	if len(indent) > width/2 {
+		indent = ""
+	}
+	var lines []string
+	runes := []rune(line)
+	for len(runes) > width {
+		cut := width
+		for i := width; i > len(indent); i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		runes = append([]rune(indent), []rune(strings.TrimLeft(string(runes[cut:]), " "))...)
+	}
+	return append(lines, string(runes))
+}
+
+// markdown draws markdown text, with its headings as headings and without the markup of
+// code blocks and emphasis
+func (d *pdfDocument) markdown(markdown string) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			d.paragraph(strings.Join(paragraph, "\n"))
+			paragraph = nil
+		}
+	}
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(markdown, ""), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			d.heading(pdfHeadingSize-1, strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+		case trimmed == "":
+			flush()
+		default:
+			paragraph = append(paragraph, strings.NewReplacer("**", "", "__", "", "`", "").Replace(line))
+		}
+	}
+	flush()
+}
+
+// writePDFReport writes a report as a PDF document: the summary of the analysis, the chart
+// of the activity, the most frequent errors, the findings and the AI or statistical analysis
+func writePDFReport(report runReport, w io.Writer) error {
+	doc := newPDFDocument()
+	analysis := report.Analysis
+	doc.text("/F2", pdfTitleSize, pdfTitleSize*1.5, report.Title)
+	for _, source := range report.Sources {
+		doc.text("/F1", pdfBodySize, pdfBodyLeading, source)
+	}
+
+	doc.heading(pdfHeadingSize, "Summary")
+	doc.paragraph(strings.Join(summarizeAnalysis(analysis), "\n"))
+
+	if analysis.TotalEntries > 0 {
+		doc.heading(pdfHeadingSize, "Activity")
+		chart := renderChart(analysis)
+		bounds := chart.Bounds()
+		rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				pixel := chart.RGBAAt(x, y)
+				rgb = append(rgb, pixel.R, pixel.G, pixel.B)
+			}
+		}
+		if err := doc.image(rgb, bounds.Dx(), bounds.Dy()); err != nil {
+			return err
+		}
+	}
+
+	if signatures := analysis.ErrorSignatures; len(signatures) > 0 {
+		doc.heading(pdfHeadingSize, "Top Errors")
+		var lines []string
+		for _, signature := range signatures[:min(len(signatures), pdfMaxSignatures)] {
+			ongoing := ""
+			if signature.Ongoing {
+				ongoing = ", ongoing"
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s, %s to %s%s)", signature.Example, countNoun(signature.Count, "time", "times"),
+				signature.FirstSeen.Format(summaryTimeFormat), signature.LastSeen.Format(summaryTimeFormat), ongoing))
+		}
+		doc.paragraph(strings.Join(lines, "\n"))
+	}
+
+	if findings := report.findingsMarkdown(); findings != "" {
+		doc.heading(pdfHeadingSize, "Findings")
+		doc.markdown(findings)
+	}
+
+	if report.Markdown != "" {
+		doc.heading(pdfHeadingSize, "Analysis")
+		doc.markdown(report.Markdown)
+	}
+
+	return doc.write(report.Title, w)
+}
+
+// exportPDFReport writes a report to a PDF file
+func exportPDFReport(report runReport, filePath string, out io.Writer) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("error writing the PDF report: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	runStats.output(filePath)
+
+	if err := writePDFReport(report, file); err != nil {
+		return fmt.Errorf("error writing the PDF report: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing the PDF report: %v", err)
+	}
+	_, _ = fmt.Fprintf(out, "PDF report written to %s\n", filePath)
+	return nil
+}
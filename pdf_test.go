@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePDFReport(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{{Timestamp: start, Level: "info", Message: "Server started"}}
+	for i := 0; i < 6; i++ {
+		logs = append(logs, LogEntry{Timestamp: start.Add(time.Duration(i) * time.Second), Level: "error", Message: "Failed to ping DB (timeout)"})
+	}
+	analysis := analyzeLogs(logs, false, 10)
+	markdown := "# Root Cause\n\nThe **database** was unreachable.\n\n```\ncode\n```\n" + strings.Repeat("A line of the analysis long enough to be wrapped on the width of the page of the report.\n", 100)
+	report := runReport{Title: "Log analysis of mattermost.log", Markdown: markdown, Analysis: analysis,
+		Findings: collectFindings(analysis, logs), Sources: []string{"/tmp/mattermost.log"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePDFReport(report, &buf))
+	pdf := buf.String()
+
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	for _, text := range []string{"(Summary)", "(Activity)", "(Top Errors)", "(Findings)", "(Analysis)", "(Root Cause)",
+		"(The database was unreachable.)", `Failed to ping DB \(timeout\)`, "/Subtype /Image /Width 1000 /Height 500", "(Page 1 of 3)"} {
+		assert.Contains(t, pdf, text)
+	}
+	assert.Contains(t, pdf, "(code)", "the fences of code blocks are removed, not their content")
+	assert.Contains(t, pdf, "/Count 3")
+
+	// The cross-reference table points at the objects
+	xref := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)
+	require.Len(t, xref, 2)
+	offset, err := strconv.Atoi(xref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(pdf[offset:], "xref\n0 "))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[offset:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		position, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[position:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+
+	assert.Error(t, exportPDFReport(report, filepath.Join(t.TempDir(), "missing", "report.pdf"), &buf))
+}
+
+func TestPDFString(t *testing.T) {
+	assert.Equal(t, `a \(b\) \\ c`, pdfString(`a (b) \ c`))
+	assert.Equal(t, `caf\351 \225 ?`, pdfString("café • →"))
+	assert.Equal(t, "a    b?", pdfString("a\tb\x01"))
+}
+
+func TestPDFWrap(t *testing.T) {
+	assert.Equal(t, []string{"short"}, pdfWrap("short", 10))
+	assert.Equal(t, []string{"one two", "three four"}, pdfWrap("one two three four", 10))
+	assert.Equal(t, []string{"- one two", "  three", "  four"}, pdfWrap("- one two three four", 10))
+	assert.Equal(t, []string{"abcdefghij", "klm"}, pdfWrap("abcdefghijklm", 10), "words longer than the line are cut")
+}
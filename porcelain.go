@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Porcelain output (--porcelain) is meant for scripts: one record per line, with the record
+// type and its fields separated by tabs, no colors and no decoration. Records and fields are
+// only ever added at the end, so existing scripts keep working.
+
+// porcelainEscaper escapes the characters that would break a record in a field
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writePorcelainRecord writes a tab-separated record
+func writePorcelainRecord(w io.Writer, fields ...string) {
+	for i, field := range fields {
+		fields[i] = porcelainEscaper.Replace(field)
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(fields, "\t"))
+}
+
+// porcelainTime formats a timestamp of a record, empty for the zero time
+func porcelainTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// porcelainFloat formats a number of a record
+func porcelainFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// displayLogsPorcelain writes one record per entry: timestamp, level, source, user, number
+// of occurrences and message
+func displayLogsPorcelain(logs []LogEntry, w io.Writer) {
+	for _, log := range logs {
+		writePorcelainRecord(w,
+			porcelainTime(log.Timestamp),
+			log.Level,
+			log.Source,
+			log.User,
+			strconv.Itoa(max(log.DuplicateCount, 1)),
+			log.Message)
+	}
+}
+
+// displayAnalysisPorcelain writes the analysis as records named after their content, e.g.
+// "level\terror\t12". uniqueEntries is the number of entries after deduplication.
+func displayAnalysisPorcelain(analysis LogAnalysis, uniqueEntries int, w io.Writer) {
+	writePorcelainRecord(w, "entries", strconv.Itoa(analysis.TotalEntries))
+	writePorcelainRecord(w, "unique_entries", strconv.Itoa(uniqueEntries))
+	writePorcelainRecord(w, "start", porcelainTime(analysis.TimeRange.Start))
+	writePorcelainRecord(w, "end", porcelainTime(analysis.TimeRange.End))
+	writePorcelainRecord(w, "error_rate", porcelainFloat(analysis.ErrorRate))
+
+	levels := make([]string, 0, len(analysis.LevelCounts))
+	for level := range analysis.LevelCounts {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		writePorcelainRecord(w, "level", level, strconv.Itoa(analysis.LevelCounts[level]))
+	}
+
+	counted := []struct {
+		record string
+		items  []CountedItem
+	}{
+		{"source", analysis.TopSources},
+		{"user", analysis.TopUsers},
+		{"error", analysis.TopErrorMessages},
+		{"hour", analysis.BusiestHours},
+		{"activity", analysis.Activity},
+		{"ip", analysis.TopIPs},
+		{"user_agent", analysis.TopUserAgents},
+	}
+	for _, c := range counted {
+		for _, item := range c.items {
+			writePorcelainRecord(w, c.record, item.Item, strconv.Itoa(item.Count))
+		}
+	}
+
+	for _, signature := range analysis.ErrorSignatures {
+		writePorcelainRecord(w, "signature",
+			signature.Signature,
+			strconv.Itoa(signature.Count),
+			porcelainTime(signature.FirstSeen),
+			porcelainTime(signature.LastSeen),
+			strconv.FormatBool(signature.Ongoing))
+	}
+	for _, finding := range analysis.RuleFindings {
+		writePorcelainRecord(w, "rule_finding",
+			finding.Title,
+			finding.Severity,
+			strconv.Itoa(finding.Count),
+			porcelainTime(finding.FirstSeen),
+			porcelainTime(finding.LastSeen))
+	}
+	for _, burst := range analysis.Incidents.ErrorBursts {
+		writePorcelainRecord(w, "burst", porcelainTime(burst.Start), porcelainTime(burst.End), strconv.Itoa(burst.Count))
+	}
+	for _, gap := range analysis.LoggingGaps {
+		writePorcelainRecord(w, "gap", porcelainTime(gap.Start), porcelainTime(gap.End), porcelainFloat(gap.Duration.Seconds()))
+	}
+	for _, restart := range analysis.Restarts {
+		writePorcelainRecord(w, "restart", porcelainTime(restart))
+	}
+	for _, skew := range analysis.ClockSkews {
+		writePorcelainRecord(w, "clock_skew", skew.Node, skew.Reference, porcelainFloat(skew.Offset.Seconds()), strconv.Itoa(skew.Samples), skew.Method)
+	}
+	for _, metric := range analysis.RuntimeMetrics {
+		writePorcelainRecord(w, "runtime_metric",
+			metric.Name,
+			porcelainFloat(metric.Min),
+			porcelainFloat(metric.Max),
+			strconv.FormatBool(metric.LeakSuspected))
+	}
+	for _, run := range analysis.ComplianceExports.Runs {
+		writePorcelainRecord(w, "export_run",
+			porcelainTime(run.Start),
+			run.Status,
+			porcelainFloat(run.Duration().Seconds()),
+			strconv.Itoa(run.Exported))
+	}
+	if exports := analysis.ComplianceExports; exports.Stopped {
+		writePorcelainRecord(w, "export_stopped", porcelainTime(exports.LastRun), porcelainFloat(exports.SinceLastRun.Seconds()))
+	}
+	for _, run := range analysis.DataRetention.Runs {
+		writePorcelainRecord(w, "retention_run",
+			porcelainTime(run.Start),
+			run.Status,
+			porcelainFloat(run.Duration().Seconds()),
+			strconv.Itoa(run.Policies),
+			strconv.Itoa(run.RowsDeleted),
+			strconv.FormatBool(run.TimedOut),
+			strconv.Itoa(run.DBWarnings))
+	}
+	for _, c := range analysis.ErrorCorrelations {
+		writePorcelainRecord(w, "error_correlation",
+			c.Field,
+			c.Value,
+			strconv.Itoa(c.Errors),
+			strconv.Itoa(c.Total),
+			porcelainFloat(c.ErrorShare),
+			porcelainFloat(c.OverallShare))
+	}
+	for _, host := range analysis.FetchErrors {
+		writePorcelainRecord(w, "fetch_host",
+			host.Host,
+			strconv.Itoa(host.Count),
+			host.Reason,
+			strconv.FormatBool(host.Consistent))
+	}
+	for _, code := range analysis.PushProxy.ErrorCodes {
+		writePorcelainRecord(w, "push_error", code.Item, strconv.Itoa(code.Count))
+	}
+	if push := analysis.PushProxy; push.Sent+push.Received+push.Undelivered > 0 {
+		writePorcelainRecord(w, "push_delivery",
+			strconv.Itoa(push.Sent),
+			strconv.Itoa(push.Received),
+			strconv.Itoa(push.Undelivered),
+			strconv.Itoa(push.UndeliveredNearFail))
+	}
+	for _, p := range analysis.NotificationLatency.Platforms {
+		writePorcelainRecord(w, "notification_latency",
+			p.Platform,
+			strconv.Itoa(p.Count),
+			porcelainFloat(p.Median.Seconds()),
+			porcelainFloat(p.P95.Seconds()),
+			porcelainFloat(p.Max.Seconds()))
+		for bucket, count := range p.Histogram {
+			bound := "+Inf"
+			if bucket < len(latencyBucketBounds) {
+				bound = porcelainFloat(latencyBucketBounds[bucket].Seconds())
+			}
+			writePorcelainRecord(w, "notification_latency_bucket", p.Platform, bound, strconv.Itoa(count))
+		}
+	}
+	for _, d := range analysis.NotificationLatency.Degraded {
+		writePorcelainRecord(w, "latency_degradation",
+			d.Platform,
+			porcelainTime(d.Start),
+			porcelainTime(d.End),
+			strconv.Itoa(d.Count),
+			porcelainFloat(d.Median.Seconds()))
+	}
+}
+
+// displayStatsDiffPorcelain writes the changes since the previous run: the rates per hour of
+// the entries and the error rates, then one record per level and per new, changed or
+// stopped error signature
+func displayStatsDiffPorcelain(diff StatsDiff, w io.Writer) {
+	previous, current := diff.Previous, diff.Current
+	writePorcelainRecord(w, "stats_diff",
+		porcelainFloat(float64(previous.TotalEntries)/previous.durationHours()),
+		porcelainFloat(float64(current.TotalEntries)/current.durationHours()),
+		porcelainFloat(previous.errorRate()),
+		porcelainFloat(current.errorRate()),
+		strconv.FormatBool(diff.Incremental))
+	for _, level := range diff.Levels {
+		writePorcelainRecord(w, "stats_diff_level", level.Item, porcelainFloat(level.Previous), porcelainFloat(level.Current))
+	}
+	signatures := []struct {
+		kind    string
+		changes []StatsChange
+	}{{"new", diff.NewSignatures}, {"changed", diff.ChangedSignatures}, {"stopped", diff.GoneSignatures}}
+	for _, signature := range signatures {
+		for _, change := range signature.changes {
+			writePorcelainRecord(w, "stats_diff_signature", signature.kind, change.Item, porcelainFloat(change.Previous), porcelainFloat(change.Current))
+		}
+	}
+}
+
+// displaySLOResultsPorcelain writes one record per service level objective with its number
+// of windows and violations, then one record per violating window
+func displaySLOResultsPorcelain(results []SLOResult, w io.Writer) {
+	for _, result := range results {
+		writePorcelainRecord(w, "slo", result.SLO.Objective, strconv.Itoa(result.Windows), strconv.Itoa(len(result.Violations)))
+		for _, violation := range result.Violations {
+			writePorcelainRecord(w, "slo_violation", result.SLO.Objective, porcelainTime(violation.Start), porcelainTime(violation.End),
+				strconv.Itoa(violation.Entries), porcelainFloat(violation.Value))
+		}
+	}
+}
+
+// displayBaselineComparisonPorcelain writes one record per deviation from the baseline:
+// kind, item, baseline value, current value and z-score
+func displayBaselineComparisonPorcelain(deviations []BaselineDeviation, w io.Writer) {
+	for _, d := range deviations {
+		writePorcelainRecord(w, "deviation",
+			d.Kind,
+			d.Item,
+			porcelainFloat(d.Baseline),
+			porcelainFloat(d.Current),
+			porcelainFloat(d.Score))
+	}
+}
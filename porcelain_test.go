@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayLogsPorcelain(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "error", Source: "app/x.go:1", User: "alice", Message: "multi\nline\twith tab", DuplicateCount: 3},
+		{Timestamp: ts.Add(1500 * time.Millisecond), Level: "info", Message: `back\slash`},
+	}
+
+	var out bytes.Buffer
+	displayLogsPorcelain(logs, &out)
+	assert.Equal(t,
+		"2025-01-01T10:00:00Z\terror\tapp/x.go:1\talice\t3\tmulti\\nline\\twith tab\n"+
+			"2025-01-01T10:00:01.5Z\tinfo\t\t\t1\tback\\\\slash\n",
+		out.String())
+	assert.NotContains(t, out.String(), "\033[")
+}
+
+func TestDisplayAnalysisPorcelain(t *testing.T) {
+	ts := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	analysis := LogAnalysis{
+		TotalEntries:     3,
+		TimeRange:        TimeRange{Start: ts, End: ts.Add(time.Minute)},
+		LevelCounts:      map[string]int{"info": 1, "error": 2},
+		ErrorRate:        66.5,
+		TopSources:       []CountedItem{{"app/x.go:1", 2}},
+		TopErrorMessages: []CountedItem{{"boom", 2}},
+		ErrorSignatures:  []ErrorSignature{{Signature: "boom", Count: 2, FirstSeen: ts, LastSeen: ts.Add(time.Minute), Ongoing: true}},
+		LoggingGaps:      []LogGap{{Start: ts, End: ts.Add(30 * time.Second), Duration: 30 * time.Second}},
+		RuleFindings:     []RuleFinding{{Title: "Boom", Severity: "error", Count: 2, FirstSeen: ts, LastSeen: ts.Add(time.Minute)}},
+	}
+
+	var out bytes.Buffer
+	displayAnalysisPorcelain(analysis, 2, &out)
+	assert.Equal(t, "entries\t3\n"+
+		"unique_entries\t2\n"+
+		"start\t2025-01-01T10:00:00Z\n"+
+		"end\t2025-01-01T10:01:00Z\n"+
+		"error_rate\t66.5\n"+
+		"level\terror\t2\n"+
+		"level\tinfo\t1\n"+
+		"source\tapp/x.go:1\t2\n"+
+		"error\tboom\t2\n"+
+		"signature\tboom\t2\t2025-01-01T10:00:00Z\t2025-01-01T10:01:00Z\ttrue\n"+
+		"rule_finding\tBoom\terror\t2\t2025-01-01T10:00:00Z\t2025-01-01T10:01:00Z\n"+
+		"gap\t2025-01-01T10:00:00Z\t2025-01-01T10:00:30Z\t30\n",
+		out.String())
+
+	out.Reset()
+	displayBaselineComparisonPorcelain([]BaselineDeviation{{Kind: "error_rate", Item: "error rate", Baseline: 1.5, Current: 12, Score: 4.25}}, &out)
+	assert.Equal(t, "deviation\terror_rate\terror rate\t1.5\t12\t4.25\n", out.String())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// problemTemplate is a kind of problem reported by customers, with the entries relevant to it
+// and what the AI analysis should examine. --problem-template and the triage wizard offer them.
+type problemTemplate struct {
+	name      string
+	label     string
+	statement string   // The problem, as told to the AI analysis
+	pattern   string   // Regex matching the relevant entries, as given to --regex
+	focus     []string // What the AI analysis should examine, in order
+}
+
+// problemTemplates are the problems of --problem-template and of the triage wizard
+var problemTemplates = []problemTemplate{
+	{
+		name:  "login-issues",
+		label: "Users cannot log in or are logged out (SSO, LDAP, sessions, MFA)",
+		statement: "Users report that they cannot log in or that they are logged out unexpectedly. " +
+			"Determine whether authentication fails, and for which users and methods, or whether sessions are revoked or expire.",
+		pattern: `(?i)login|log in|authenticat|saml|ldap|oauth|openid|session|mfa|password`,
+		focus: []string{
+			"Errors of the SAML, LDAP, OAuth or OpenID Connect providers: certificates, attributes, clock skew and unreachable servers",
+			"Sessions revoked, expired or invalidated, and the users they belong to",
+			"Failed MFA and password checks, locked accounts and rate limiting",
+			"Whether the failures affect all users or a subset, and since when",
+		},
+	},
+	{
+		name:  "notifications",
+		label: "Push or email notifications are missing or late",
+		statement: "Users report that push or email notifications are missing or delayed. " +
+			"Determine where the notifications are lost or held up between the server, the push proxy and the mail server.",
+		pattern: `(?i)notification|push|apns|fcm|email|smtp`,
+		focus: []string{
+			"Errors sending to the push proxy, APNS or FCM, and the devices or users they concern",
+			"SMTP connection, authentication and TLS errors, and email batching",
+			"Delays between the posts and their notifications, and queues backing up",
+			"Notification preferences and channel mutes that explain notifications that are not sent",
+		},
+	},
+	{
+		name:  "performance",
+		label: "Slowness, timeouts or high load",
+		statement: "Users report that Mattermost is slow or that requests time out. " +
+			"Determine which requests or jobs are slow, since when, and the resource or dependency they wait for.",
+		pattern: `(?i)timeout|timed out|deadline exceeded|context canceled|slow|latency|too many|lock wait`,
+		focus: []string{
+			"Slow or timed out API requests and database queries, with their endpoints and durations",
+			"Database connection pool exhaustion, lock waits and replica lag",
+			"Spikes of requests, websocket connections or jobs at the time of the slowness",
+			"Timeouts of the plugins, the file store, the search engine and the cluster",
+		},
+	},
+	{
+		name:  "upgrade",
+		label: "Problems since an upgrade or a migration",
+		statement: "Problems started after upgrading Mattermost or migrating its database. " +
+			"Determine whether the upgrade or the migrations completed, and what fails since then.",
+		pattern: `(?i)upgrade|migrat|schema|version`,
+		focus: []string{
+			"Database migrations that failed, were skipped or are still running",
+			"Nodes of the cluster running different versions, and the schema version",
+			"Plugins or integrations incompatible with the new version",
+			"Configuration settings that were removed, renamed or changed their default",
+		},
+	},
+}
+
+// problemTemplateNames returns the names of the problem templates
+func problemTemplateNames() []string {
+	names := make([]string, len(problemTemplates))
+	for i, template := range problemTemplates {
+		names[i] = template.name
+	}
+	return names
+}
+
+// findProblemTemplate returns the problem template of a name
+func findProblemTemplate(name string) (problemTemplate, bool) {
+	for _, template := range problemTemplates {
+		if template.name == name {
+			return template, true
+		}
+	}
+	return problemTemplate{}, false
+}
+
+// expand returns the problem told to the AI analysis: the statement of the template, followed
+// by the user's own description of the problem, if any
+func (t problemTemplate) expand(description string) string {
+	if description == "" {
+		return t.statement
+	}
+	return fmt.Sprintf("%s The customer describes it as: %q.", t.statement, description)
+}
+
+// focusInstructions returns the areas the AI analysis should examine, as a list
+func focusInstructions(focus []string) string {
+	if len(focus) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Examine in particular:")
+	for _, area := range focus {
+		sb.WriteString("\n- " + area)
+	}
+	return sb.String()
+}
+
+// applyProblemTemplate checks --problem-template and narrows the entries to those relevant to
+// its problem, unless --regex is set
+func applyProblemTemplate() error {
+	if problemTemplateName == "" {
+		return nil
+	}
+	template, ok := findProblemTemplate(problemTemplateName)
+	if !ok {
+		return fmt.Errorf("invalid --problem-template %q: expected %s", problemTemplateName, strings.Join(problemTemplateNames(), ", "))
+	}
+	if regexSearch == "" {
+		regexSearch = template.pattern
+	}
+	return nil
+}
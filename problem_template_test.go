@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemTemplate(t *testing.T) {
+	oldTemplate, oldRegex, oldProblem, oldProvider, oldMaxOutputTokens := problemTemplateName, regexSearch, problem, llmProvider, maxOutputTokens
+	oldOllamaHost, oldOllamaTimeout := OllamaHost, OllamaTimeout
+	t.Cleanup(func() {
+		problemTemplateName, regexSearch, problem, llmProvider, maxOutputTokens = oldTemplate, oldRegex, oldProblem, oldProvider, oldMaxOutputTokens
+		OllamaHost, OllamaTimeout = oldOllamaHost, oldOllamaTimeout
+	})
+
+	problemTemplateName = "notifications"
+	require.NoError(t, applyProblemTemplate())
+	template, _ := findProblemTemplate("notifications")
+	assert.Equal(t, template.pattern, regexSearch, "the entries are narrowed to the problem")
+
+	// --regex takes precedence over the filter of the template
+	regexSearch = "apns"
+	require.NoError(t, applyProblemTemplate())
+	assert.Equal(t, "apns", regexSearch)
+
+	// The user's description of the problem follows the statement of the template
+	problem, llmProvider, maxOutputTokens = "iOS users get no pushes", "ollama", defaultMaxOutputTokens
+	config, err := newLLMConfig(nil, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, template.statement+` The customer describes it as: "iOS users get no pushes".`, config.Problem)
+	assert.Equal(t, template.focus, config.Focus)
+
+	prompt, err := prepareAnalysisPrompts([]LogEntry{{Level: "error", Message: "Failed to send push"}},
+		LLMConfig{Problem: template.expand(""), Focus: template.focus, Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Instructions, "I'm investigating this problem: "+template.statement+"\n\nExamine in particular:\n- "+template.focus[0])
+
+	problemTemplateName = "slow"
+	assert.EqualError(t, applyProblemTemplate(), `invalid --problem-template "slow": expected login-issues, notifications, performance, upgrade`)
+}
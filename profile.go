@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Profile is a named set of flag defaults, e.g. {"llm-provider": "openai", "level": "error"}
+type Profile map[string]string
+
+// profileNames returns the names of the profiles of a configuration, sorted
+func profileNames(config Config) []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedFlags returns the flag names set by a profile, sorted
+func (p Profile) sortedFlags() []string {
+	flags := make([]string, 0, len(p))
+	for flag := range p {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// applyProfile sets the flags of a command to the values of a profile, described by source
+// in errors. Flags given on the command line or in the environment take precedence.
+func applyProfile(cmd *cobra.Command, source string, profile Profile) error {
+	for _, flag := range profile.sortedFlags() {
+		f := cmd.Flags().Lookup(flag)
+		if f == nil || flag == "profile" {
+			return fmt.Errorf("%s sets unknown flag --%s", source, flag)
+		}
+		if f.Changed {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, profile[flag]); err != nil {
+			return fmt.Errorf("%s has an invalid value for --%s: %v", source, flag, err)
+		}
+	}
+	return nil
+}
+
+// loadProfile applies the profile selected with --profile, if any, and then the defaults of
+// the config file to a command. Commands without a --profile flag do not read the config.
+func loadProfile(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup("profile") == nil {
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if profileName != "" {
+		profile, ok := config.Profiles[profileName]
+		if !ok {
+			return fmt.Errorf("unknown profile %q (see 'lamp profile list')", profileName)
+		}
+		if err := applyProfile(cmd, fmt.Sprintf("profile %q", profileName), profile); err != nil {
+			return err
+		}
+	}
+	return applyProfile(cmd, "the config file defaults", config.Defaults)
+}
+
+// parseProfileSettings parses flag=value arguments into a profile, checking that each flag
+// is accepted by cmd
+func parseProfileSettings(cmd *cobra.Command, settings []string) (Profile, error) {
+	profile := make(Profile, len(settings))
+	for _, setting := range settings {
+		flag, value, ok := strings.Cut(setting, "=")
+		flag = strings.TrimPrefix(flag, "--")
+		if !ok || flag == "" {
+			return nil, fmt.Errorf("invalid setting %q, expected flag=value", setting)
+		}
+		if cmd.Flags().Lookup(flag) == nil || flag == "profile" {
+			return nil, fmt.Errorf("unknown flag --%s", flag)
+		}
+		profile[flag] = value
+	}
+	return profile, nil
+}
+
+// printProfiles lists the profiles of a configuration with their settings
+func printProfiles(w io.Writer, config Config) {
+	for _, name := range profileNames(config) {
+		_, _ = fmt.Fprintln(w, name)
+		profile := config.Profiles[name]
+		for _, flag := range profile.sortedFlags() {
+			_, _ = fmt.Fprintf(w, "  %s=%s\n", flag, profile[flag])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newProfileTestCommand returns a command with a few flags of the file commands
+func newProfileTestCommand(provider *string, level *string, trimmed *bool) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringVar(provider, "llm-provider", "anthropic", "")
+	cmd.Flags().StringVar(level, "level", "", "")
+	cmd.Flags().BoolVar(trimmed, "trim", false, "")
+	cmd.Flags().String("profile", "", "")
+	return cmd
+}
+
+func TestApplyProfile(t *testing.T) {
+	var provider, level string
+	var trimmed bool
+	cmd := newProfileTestCommand(&provider, &level, &trimmed)
+	require.NoError(t, cmd.ParseFlags([]string{"--level", "warn"}))
+
+	profile := Profile{"llm-provider": "openai", "level": "error", "trim": "true"}
+	require.NoError(t, applyProfile(cmd, `profile "triage"`, profile))
+	assert.Equal(t, "openai", provider)
+	assert.Equal(t, "warn", level, "flags on the command line take precedence")
+	assert.True(t, trimmed)
+
+	err := applyProfile(cmd, `profile "triage"`, Profile{"nope": "1"})
+	assert.ErrorContains(t, err, `profile "triage" sets unknown flag --nope`)
+	err = applyProfile(cmd, `profile "triage"`, Profile{"profile": "other"})
+	assert.ErrorContains(t, err, "unknown flag --profile")
+	err = applyProfile(newProfileTestCommand(&provider, &level, &trimmed), `profile "triage"`, Profile{"trim": "maybe"})
+	assert.ErrorContains(t, err, "invalid value for --trim")
+}
+
+func TestParseProfileSettings(t *testing.T) {
+	var provider, level string
+	var trimmed bool
+	cmd := newProfileTestCommand(&provider, &level, &trimmed)
+
+	profile, err := parseProfileSettings(cmd, []string{"llm-provider=openai", "--level=error", "trim=true"})
+	require.NoError(t, err)
+	assert.Equal(t, Profile{"llm-provider": "openai", "level": "error", "trim": "true"}, profile)
+
+	_, err = parseProfileSettings(cmd, []string{"level"})
+	assert.ErrorContains(t, err, "expected flag=value")
+	_, err = parseProfileSettings(cmd, []string{"colour=red"})
+	assert.ErrorContains(t, err, "unknown flag --colour")
+}
+
+func TestPrintProfiles(t *testing.T) {
+	config := Config{Profiles: map[string]Profile{
+		"triage": {"level": "error", "llm-provider": "openai"},
+		"local":  {"llm-provider": "ollama"},
+	}}
+
+	var out bytes.Buffer
+	printProfiles(&out, config)
+	assert.Equal(t, "local\n  llm-provider=ollama\ntriage\n  level=error\n  llm-provider=openai\n", out.String())
+}
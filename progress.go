@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// plainProgressInterval is the minimum time between two plain-text progress lines
+const plainProgressInterval = 5 * time.Second
+
+// fileProgressMinBytes is the size from which the parsing of a file shows its progress
+const fileProgressMinBytes = 32 << 20
+
+// progress reports the progress of processing items, as a progress bar on a terminal or as
+// periodic plain-text lines otherwise, so that logs of cron jobs and CI runs stay readable
+type progress struct {
+	bar *progressbar.ProgressBar // Nil when reporting plain-text lines
+
+	mu          sync.Mutex
+	out         io.Writer // Destination of plain-text lines, nil to report nothing
+	interval    time.Duration
+	description string
+	current     int
+	max         int
+	lastReport  time.Time
+	finished    bool
+}
+
+// newProgressBar returns the progress shown on stderr while processing total items. It
+// falls back to plain-text lines when stderr is not a terminal or with --no-progress; these
+// are hidden with --quiet. With --porcelain nothing is shown.
+func newProgressBar(total int, description string) *progress {
+	if !porcelain && (noProgress || !term.IsTerminal(int(os.Stderr.Fd()))) {
+		var out io.Writer
+		if !quiet {
+			out = os.Stderr
+		}
+		return newPlainProgress(out, total, description)
+	}
+
+	options := append(progressBarOptions(description), progressbar.OptionShowCount())
+	return &progress{bar: progressbar.NewOptions(total, options...)}
+}
+
+// newBytesProgressBar returns the progress shown on stderr while reading total bytes, with
+// the throughput and the time left. Unlike newProgressBar it is only shown on a terminal:
+// without one, or with --no-progress, --quiet or --porcelain, nothing is reported.
+func newBytesProgressBar(total int64, description string) *progress {
+	if porcelain || noProgress || quiet || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return newPlainProgress(nil, 0, description)
+	}
+
+	options := append(progressBarOptions(description),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetPredictTime(true),
+		progressbar.OptionThrottle(100*time.Millisecond))
+	return &progress{bar: progressbar.NewOptions64(total, options...)}
+}
+
+// progressBarOptions returns the look of the progress bars shown on stderr
+func progressBarOptions(description string) []progressbar.Option {
+	return []progressbar.Option{
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionSetDescription(colorDescription(description)),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "[green]=[reset]",
+			SaucerHead:    "[green]>[reset]",
+			SaucerPadding: " ",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+		progressbar.OptionSetVisibility(!porcelain),
+		progressbar.OptionOnCompletion(func() {
+			if !porcelain {
+				_, _ = fmt.Fprintln(os.Stderr)
+			}
+		}),
+	}
+}
+
+// newPlainProgress returns a progress writing plain-text lines to out
+func newPlainProgress(out io.Writer, total int, description string) *progress {
+	return &progress{
+		out:         out,
+		interval:    plainProgressInterval,
+		description: description,
+		max:         total,
+		lastReport:  time.Now(),
+	}
+}
+
+// colorDescription colors the description of a progress bar
+func colorDescription(description string) string {
+	return "[cyan]" + description + "[reset]"
+}
+
+// Add adds n processed items, writing a plain-text line when the interval has passed since
+// the last one
+func (p *progress) Add(n int) error {
+	if p.bar != nil {
+		return p.bar.Add(n)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = min(p.current+n, p.max)
+	if !p.finished && time.Since(p.lastReport) >= p.interval {
+		p.report()
+	}
+	return nil
+}
+
+// Describe changes the description of the progress
+func (p *progress) Describe(description string) {
+	if p.bar != nil {
+		p.bar.Describe(colorDescription(description))
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.description = description
+}
+
+// RenderBlank shows the progress before any item is processed
+func (p *progress) RenderBlank() error {
+	if p.bar != nil {
+		return p.bar.RenderBlank()
+	}
+	return nil
+}
+
+// Finish marks all items as processed, writing the final plain-text line
+func (p *progress) Finish() error {
+	if p.bar != nil {
+		return p.bar.Finish()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = p.max
+	if !p.finished {
+		p.finished = true
+		p.report()
+	}
+	return nil
+}
+
+// Reset starts over with no processed items
+func (p *progress) Reset() {
+	if p.bar != nil {
+		p.bar.Reset()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = 0
+	p.finished = false
+	p.lastReport = time.Now()
+}
+
+// ChangeMax changes the number of items to process
+func (p *progress) ChangeMax(total int) {
+	if p.bar != nil {
+		p.bar.ChangeMax(total)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.max = total
+}
+
+// report writes a plain-text line such as "Deduplicating logs (250/1000, 25%)"
+func (p *progress) report() {
+	p.lastReport = time.Now()
+	if p.out == nil {
+		return
+	}
+	percent := 100
+	if p.max > 0 {
+		percent = p.current * 100 / p.max
+	}
+	_, _ = fmt.Fprintf(p.out, "%s (%d/%d, %d%%)\n", p.description, p.current, p.max, percent)
+}
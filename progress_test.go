@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlainProgress(t *testing.T) {
+	t.Run("reports at the interval and on completion", func(t *testing.T) {
+		var out bytes.Buffer
+		p := newPlainProgress(&out, 4, "Processing log files")
+		p.interval = time.Hour
+
+		require.NoError(t, p.RenderBlank())
+		require.NoError(t, p.Add(1))
+		assert.Empty(t, out.String(), "nothing is reported before the interval")
+
+		p.lastReport = time.Now().Add(-2 * time.Hour)
+		require.NoError(t, p.Add(1))
+		require.NoError(t, p.Add(2))
+		require.NoError(t, p.Finish())
+		assert.Equal(t, "Processing log files (2/4, 50%)\nProcessing log files (4/4, 100%)\n", out.String())
+	})
+
+	t.Run("reports each phase after a reset", func(t *testing.T) {
+		var out bytes.Buffer
+		p := newPlainProgress(&out, 2, "Normalizing")
+		require.NoError(t, p.Finish())
+
+		p.Reset()
+		p.ChangeMax(3)
+		p.Describe("Deduplicating")
+		require.NoError(t, p.Add(3))
+		require.NoError(t, p.Finish())
+		assert.Equal(t, "Normalizing (2/2, 100%)\nDeduplicating (3/3, 100%)\n", out.String())
+	})
+
+	t.Run("reports nothing without output", func(t *testing.T) {
+		p := newPlainProgress(nil, 1, "Deduplicating logs")
+		assert.NoError(t, p.Add(1))
+		assert.NoError(t, p.Finish())
+	})
+}
+
+func TestBytesProgressBar(t *testing.T) {
+	// Tests don't run on a terminal, where the progress of large files is not reported
+	p := newBytesProgressBar(64<<20, "Parsing mattermost.log")
+	assert.Nil(t, p.bar)
+	assert.Nil(t, p.out)
+	assert.NoError(t, p.Add(1<<20))
+	assert.NoError(t, p.Finish())
+}
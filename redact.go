@@ -0,0 +1,26 @@
+package main
+
+import "regexp"
+
+// redactions replace personal data and secrets in text shared outside the team, in order:
+// secrets first so that their values are not partially replaced by the later patterns
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9._~+/=-]+`), "$1 [redacted]"},
+	{regexp.MustCompile(`(?i)\b(password|passwd|secret|token|api[_-]?key|access[_-]?key)(["']?\s*[:=]\s*["']?)[^\s"',}]+`), "$1$2[redacted]"},
+	{regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`), "://[redacted]@"},
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[email]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "[ip]"},
+	{regexp.MustCompile(`\b[a-z0-9]{26}\b`), "[id]"}, // Mattermost user, channel and team IDs
+}
+
+// redact masks the email addresses, IPv4 addresses, Mattermost IDs, credentials in URLs and
+// values of secret-looking keys in text
+func redact(text string) string {
+	for _, r := range redactions {
+		text = r.pattern.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ansiEscape matches the color codes of the terminal output
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// runReport is the outcome of a command analyzing logs, as filed in ticketing systems
+type runReport struct {
+	Title    string    // e.g. "Log analysis of mattermost.log"
+	Markdown string    // The AI analysis, or the statistical analysis in a code block
+	Findings []Finding // Detected issues, attached as a SARIF file
+	Analysis LogAnalysis
+	Sources  []string // Paths of the analyzed files
+}
+
+// newRunReport returns the report of the analysis of the logs loaded by the command
+func newRunReport(analysis LogAnalysis, logs []LogEntry, markdown string) runReport {
+	title := "Log analysis"
+	var sources []string
+	if loadedSource != nil && len(loadedSource.Paths) > 0 {
+		sources = loadedSource.Paths
+		names := make([]string, len(loadedSource.Paths))
+		for i, path := range loadedSource.Paths {
+			names[i] = filepath.Base(path)
+		}
+		title = fmt.Sprintf("Log analysis of %s", strings.Join(names, ", "))
+	}
+	return runReport{
+		Title:    title,
+		Markdown: markdown,
+		Findings: collectFindings(analysis, logs),
+		Analysis: analysis,
+		Sources:  sources,
+	}
+}
+
+// statsMarkdown returns the terminal output of the statistical analysis as a markdown code
+// block, without colors
+func statsMarkdown(output string) string {
+	return "```\n" + strings.TrimSpace(ansiEscape.ReplaceAllString(output, "")) + "\n```\n"
+}
+
+// excerpts returns up to limit log lines the findings are based on, starting with the first
+// entry of each finding
+func (r runReport) excerpts(limit int) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	add := func(entry LogEntry) {
+		line := entry.Raw
+		if line == "" {
+			line = entry.Message
+		}
+		if len(lines) < limit && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	for _, finding := range r.Findings {
+		if len(finding.Evidence) > 0 {
+			add(finding.Evidence[0])
+		}
+	}
+	for _, finding := range r.Findings {
+		for _, entry := range finding.Evidence {
+			add(entry)
+		}
+	}
+	return lines
+}
+
+// findingsMarkdown returns the findings as a markdown list, empty without findings
+func (r runReport) findingsMarkdown() string {
+	var list strings.Builder
+	for _, finding := range r.Findings {
+		_, _ = fmt.Fprintf(&list, "- **%s** (%s, %d entries, `%s`): %s\n",
+			finding.Title, finding.Severity, finding.Count, finding.RuleID, finding.Message)
+	}
+	return list.String()
+}
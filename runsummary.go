@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runStatistics counts what the running command read and wrote, for --run-summary. Files
+// are parsed in parallel, hence the atomic counters.
+type runStatistics struct {
+	filesParsed  atomic.Int64
+	entries      atomic.Int64 // Entries kept by the filters
+	skippedLines atomic.Int64 // Lines and entries that could not be parsed
+
+	mu      sync.Mutex
+	outputs []string // Files written, in the order they were created
+}
+
+// runStats are the statistics of the running command
+var runStats = &runStatistics{}
+
+// RunSummary is the one-line JSON summary of a run written by --run-summary
+type RunSummary struct {
+	SchemaVersion int      `json:"schema_version"`
+	Command       string   `json:"command"`
+	Status        string   `json:"status"` // ok or error
+	Error         string   `json:"error,omitempty"`
+	FilesParsed   int64    `json:"files_parsed"`
+	FilesFailed   int      `json:"files_failed"` // Inputs skipped or collected by --on-error
+	Entries       int64    `json:"entries"`
+	SkippedLines  int64    `json:"skipped_lines"`
+	DurationMs    int64    `json:"duration_ms"`
+	Outputs       []string `json:"outputs"`
+}
+
+// fileParsed counts a parsed file
+func (s *runStatistics) fileParsed() {
+	s.filesParsed.Add(1)
+}
+
+// countParsedFile counts the file parsed by a parser when it succeeded, passing its result through
+func (s *runStatistics) countParsedFile(logs []LogEntry, err error) ([]LogEntry, error) {
+	if err == nil {
+		s.fileParsed()
+	}
+	return logs, err
+}
+
+// entryKept counts an entry kept by the filters
+func (s *runStatistics) entryKept() {
+	s.entries.Add(1)
+}
+
+// lineSkipped counts a line or entry that could not be parsed
+func (s *runStatistics) lineSkipped() {
+	s.skippedLines.Add(1)
+}
+
+// output records a file written by the command
+func (s *runStatistics) output(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs = append(s.outputs, path)
+}
+
+// summary returns the summary of the run of a command that took duration and ended with err
+func (s *runStatistics) summary(command string, duration time.Duration, err error) RunSummary {
+	s.mu.Lock()
+	outputs := append([]string{}, s.outputs...)
+	s.mu.Unlock()
+
+	summary := RunSummary{
+		SchemaVersion: schemaVersion,
+		Command:       command,
+		Status:        "ok",
+		FilesParsed:   s.filesParsed.Load(),
+		FilesFailed:   len(inputErrors.errors),
+		Entries:       s.entries.Load(),
+		SkippedLines:  s.skippedLines.Load(),
+		DurationMs:    duration.Milliseconds(),
+		Outputs:       outputs,
+	}
+	if err != nil {
+		summary.Status = "error"
+		summary.Error = err.Error()
+	}
+	return summary
+}
+
+// writeRunSummary writes the summary of the run as a line of JSON to path, or to stderr
+// when path is "-"
+func writeRunSummary(summary RunSummary, path string, stderr io.Writer) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = stderr.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing run summary: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSummary(t *testing.T) {
+	initLogger()
+	defer func(saved *runStatistics) { runStats = saved }(runStats)
+	runStats = &runStatistics{}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mattermost.log")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"started"}`+"\n"+
+			"not a log line\n"+
+			`{"timestamp":"2024-03-01 10:00:01.000 Z","level":"error","msg":"failed"}`+"\n"), 0o600))
+
+	logs, err := parseLogFile(path, "", "", "error", "", "", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	csvPath := filepath.Join(dir, "logs.csv")
+	require.NoError(t, exportToCSV(logs, csvPath, csvOptions{}))
+
+	summary := runStats.summary("file", 1500*time.Millisecond, nil)
+	assert.Equal(t, schemaVersion, summary.SchemaVersion)
+	assert.Equal(t, "ok", summary.Status)
+	assert.EqualValues(t, 1, summary.FilesParsed)
+	assert.EqualValues(t, 1, summary.Entries, "entries dropped by the filters are not counted")
+	assert.EqualValues(t, 1, summary.SkippedLines)
+	assert.EqualValues(t, 1500, summary.DurationMs)
+	assert.Equal(t, []string{csvPath}, summary.Outputs)
+
+	t.Run("written as a line of JSON to stderr", func(t *testing.T) {
+		var stderr bytes.Buffer
+		require.NoError(t, writeRunSummary(runStats.summary("file", 0, errors.New("no entries")), "-", &stderr))
+		assert.Equal(t, 1, bytes.Count(stderr.Bytes(), []byte("\n")))
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(stderr.Bytes(), &decoded))
+		assert.Equal(t, "error", decoded["status"])
+		assert.Equal(t, "no entries", decoded["error"])
+	})
+
+	t.Run("written to a file", func(t *testing.T) {
+		summaryPath := filepath.Join(dir, "summary.json")
+		require.NoError(t, writeRunSummary(runStats.summary("file", 0, nil), summaryPath, nil))
+		data, err := os.ReadFile(summaryPath)
+		require.NoError(t, err)
+		var decoded RunSummary
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, summary.Outputs, decoded.Outputs)
+	})
+
+	t.Run("no outputs is an empty list", func(t *testing.T) {
+		data, err := json.Marshal((&runStatistics{}).summary("file", 0, nil))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"outputs":[]`)
+	})
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Timeout bounds each upload to object storage
+	s3Timeout = 5 * time.Minute
+	// s3MaxExpiry is the longest validity of a presigned URL accepted by S3
+	s3MaxExpiry = 7 * 24 * time.Hour
+	// s3DefaultRegion is used when neither AWS_REGION nor AWS_DEFAULT_REGION is set
+	s3DefaultRegion = "us-east-1"
+)
+
+// s3Location is the bucket and key prefix of --upload
+type s3Location struct {
+	Bucket string
+	Prefix string // Empty or ending with a slash
+}
+
+// parseS3Location parses an s3://bucket/prefix/ URL. The prefix is a folder, so a missing
+// trailing slash is added.
+func parseS3Location(location string) (s3Location, error) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return s3Location{}, fmt.Errorf("invalid --upload %q: expected s3://bucket/prefix/", location)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return s3Location{}, fmt.Errorf("invalid --upload %q: missing bucket", location)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return s3Location{Bucket: bucket, Prefix: prefix}, nil
+}
+
+// s3Client uploads objects with requests signed with AWS Signature Version 4, which S3 and
+// compatible object storage (MinIO, Ceph, Cloudflare R2...) accept
+type s3Client struct {
+	client       *http.Client
+	endpoint     *url.URL // Custom endpoint addressed path-style, nil for AWS
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+// newS3ClientFromEnv returns a client with the credentials, region and endpoint of the
+// standard AWS environment variables
+func newS3ClientFromEnv() (*s3Client, error) {
+	s := &s3Client{
+		client:       newHTTPClient(s3Timeout),
+		region:       firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), s3DefaultRegion),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		now:          time.Now,
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("--upload requires credentials in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if endpoint := firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		s.endpoint = u
+	}
+	return s, nil
+}
+
+// firstNonEmpty returns the first of the values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// objectURL returns the address of an object. AWS buckets are addressed virtual-hosted style,
+// except for names with dots that do not match the TLS certificate of S3.
+func (s *s3Client) objectURL(bucket, key string) *url.URL {
+	escapedKey := awsURIEscape(key, false)
+	switch {
+	case s.endpoint != nil:
+		u := *s.endpoint
+		u.Path = u.Path + "/" + bucket + "/" + key
+		u.RawPath = s.endpoint.EscapedPath() + "/" + awsURIEscape(bucket, true) + "/" + escapedKey
+		return &u
+	case strings.Contains(bucket, "."):
+		return &url.URL{Scheme: "https", Host: s.awsHost(), Path: "/" + bucket + "/" + key, RawPath: "/" + awsURIEscape(bucket, true) + "/" + escapedKey}
+	default:
+		return &url.URL{Scheme: "https", Host: bucket + "." + s.awsHost(), Path: "/" + key, RawPath: "/" + escapedKey}
+	}
+}
+
+// awsHost returns the S3 endpoint of the region
+func (s *s3Client) awsHost() string {
+	if s.region == "us-east-1" {
+		return "s3.amazonaws.com"
+	}
+	return "s3." + s.region + ".amazonaws.com"
+}
+
+// put uploads an object
+func (s *s3Client) put(bucket, key string, data []byte, contentType string) error {
+	u := s.objectURL(bucket, key)
+	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	payloadHash := sha256Hex(data)
+	now := s.now().UTC()
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": u.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	signedHeaders, signature := s.sign("PUT", u, headers, payloadHash, now)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(now), signedHeaders, signature))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("S3 answered with %s%s", resp.Status, s3ErrorDetail(body))
+	}
+	return nil
+}
+
+// s3ErrorDetail returns the message of an S3 XML error answer, if any
+func s3ErrorDetail(body []byte) string {
+	text := string(body)
+	start := strings.Index(text, "<Message>")
+	end := strings.Index(text, "</Message>")
+	if start < 0 || end < start {
+		return ""
+	}
+	return ": " + text[start+len("<Message>"):end]
+}
+
+// presign returns a URL that downloads an object without credentials until it expires
+func (s *s3Client) presign(bucket, key string, expires time.Duration) string {
+	u := s.objectURL(bucket, key)
+	now := s.now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	u.RawQuery = awsCanonicalQuery(query)
+
+	_, signature := s.sign("GET", u, map[string]string{"host": u.Host}, "UNSIGNED-PAYLOAD", now)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+// scope returns the credential scope of the requests signed at a time
+func (s *s3Client) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// sign returns the signed header names and the Signature Version 4 of a request, whose
+// query is already canonical
+func (s *s3Client) sign(method string, u *url.URL, headers map[string]string, payloadHash string, now time.Time) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		_, _ = fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method, u.EscapedPath(), u.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), s.scope(now), sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with a key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// awsURIEscape percent-encodes everything but unreserved characters, as Signature Version 4
+// expects, keeping slashes unless escapeSlash is set
+func awsURIEscape(value string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		case b == '/' && !escapeSlash:
+			escaped.WriteByte(b)
+		default:
+			_, _ = fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// awsCanonicalQuery returns a query string sorted by name and escaped for signing
+func awsCanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, awsURIEscape(name, true)+"="+awsURIEscape(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uploadArtifacts uploads files under the prefix of an s3:// location and prints their S3
+// paths and presigned download URLs
+func uploadArtifacts(location string, files []string, expires time.Duration, out io.Writer) error {
+	target, err := parseS3Location(location)
+	if err != nil {
+		return err
+	}
+	client, err := newS3ClientFromEnv()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error reading %s for upload: %v", file, err)
+		}
+		name := filepath.Base(file)
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		key := target.Prefix + name
+		if err := client.put(target.Bucket, key, data, contentType); err != nil {
+			return fmt.Errorf("error uploading %s: %v", file, err)
+		}
+		_, _ = fmt.Fprintf(out, "Uploaded s3://%s/%s\n  %s\n", target.Bucket, key, client.presign(target.Bucket, key, expires))
+	}
+	return nil
+}
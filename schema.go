@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// schemaVersion is the version of the JSON schema of the entries and analyses lamp exports.
+// Adding fields keeps the version, so readers must ignore the fields they don't know;
+// removing, renaming or changing the meaning of a field increments it.
+const schemaVersion = 1
+
+// errUnsupportedSchema is returned when reading entries exported by a newer lamp
+var errUnsupportedSchema = errors.New("unsupported schema version")
+
+// checkSchemaVersion returns an error if entries of the schema version cannot be read.
+// Exports that predate the versioning have no version, and are read as version 1.
+func checkSchemaVersion(version int) error {
+	if version < 0 || version > schemaVersion {
+		return fmt.Errorf("%w %d (this version of lamp reads up to %d, upgrade it to read the file)", errUnsupportedSchema, version, schemaVersion)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the entry with the schema version, so that every entry lamp exports,
+// as JSON, JSON Lines or in an analysis, tells readers how to interpret it
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	// entry has the fields of LogEntry without this method, so it encodes them by default
+	type entry LogEntry
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schema_version"`
+		entry
+	}{schemaVersion, entry(e)})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	at := mustParseTime(t, "2024-03-01 10:00:00.000 Z")
+	entry := LogEntry{Timestamp: at, Level: "error", Message: "db failed", Raw: "raw", Seq: 3, DuplicateCount: 2}
+
+	t.Run("exported entries carry the version", func(t *testing.T) {
+		data, err := json.Marshal(entry)
+		require.NoError(t, err)
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(data, &fields))
+		assert.Equal(t, float64(schemaVersion), fields["schema_version"])
+		assert.Equal(t, "db failed", fields["message"])
+		assert.Equal(t, float64(2), fields["duplicate_count"])
+		assert.NotContains(t, fields, "Raw")
+		assert.NotContains(t, fields, "Seq")
+
+		parsed, err := parseExportedEntry(data)
+		require.NoError(t, err)
+		assert.Equal(t, entry.Message, parsed.Message)
+		assert.Equal(t, entry.DuplicateCount, parsed.DuplicateCount)
+	})
+
+	tests := []struct {
+		name    string
+		line    string
+		wantErr bool
+	}{
+		{"unversioned exports are read as version 1", `{"timestamp":"2024-03-01T10:00:00Z","level":"info","message":"before versioning"}`, false},
+		{"unknown fields are ignored", `{"schema_version":1,"timestamp":"2024-03-01T10:00:00Z","level":"info","message":"later field","added_later":true}`, false},
+		{"newer versions are rejected", `{"schema_version":2,"timestamp":"2024-03-01T10:00:00Z","level":"info","message":"from the future"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"export.jsonl", "export.json"} {
+				data := tt.line + "\n"
+				if name == "export.json" {
+					data = "[" + tt.line + "]\n"
+				}
+				path := filepath.Join(t.TempDir(), name)
+				require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+				parsed, err := parseLogFile(path, "", "", "", "", "", "")
+				if tt.wantErr {
+					require.ErrorIs(t, err, errUnsupportedSchema, name)
+					assert.Contains(t, err.Error(), "upgrade")
+					continue
+				}
+				require.NoError(t, err, name)
+				require.Len(t, parsed, 1, name)
+			}
+		})
+	}
+}
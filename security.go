@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// securityMaxEvents is the number of events listed per category of the security report
+	securityMaxEvents = 20
+	// suspiciousAuthFailures is the number of authentication failures from an IP that makes it suspicious
+	suspiciousAuthFailures = 5
+	// suspiciousTargetedUsers is the number of users with authentication failures from an IP
+	// that makes it suspicious, as in credential stuffing
+	suspiciousTargetedUsers = 3
+	// suspiciousPermissionDenied is the number of permission denials of an IP that makes it suspicious
+	suspiciousPermissionDenied = 10
+)
+
+// Categories of security events
+const (
+	securityAuthFailure      = "auth_failure"
+	securityPermissionDenied = "permission_denied"
+	securityRoleChange       = "role_change"
+	securitySessionRevoked   = "session_revoked"
+)
+
+// securityCategories are the categories of the report in order, with their titles
+var securityCategories = []struct{ ID, Title string }{
+	{securityAuthFailure, "Authentication failures"},
+	{securityPermissionDenied, "Permission denied"},
+	{securityRoleChange, "Admin role changes"},
+	{securitySessionRevoked, "Token and session invalidations"},
+}
+
+// Phrases of the server log messages of each category, matched in lower case
+var (
+	authFailurePhrases = []string{"invalid_credentials", "invalid login", "login failed", "failed login", "failed to login",
+		"authentication failed", "incorrect password", "invalid password", "invalid mfa", "too many login attempts", "max_login_attempts"}
+	permissionDeniedPhrases = []string{"permissions.app_error", "permission denied", "do not have the appropriate permissions", "insufficient permissions", "forbidden"}
+	sessionRevokedPhrases   = []string{"revoke", "revoking", "invalidate session", "invalidating session", "invalidate all sessions"}
+)
+
+// SecurityEvent is a log or audit entry relevant to security
+type SecurityEvent struct {
+	Time     time.Time `json:"time"`
+	Category string    `json:"category"`
+	User     string    `json:"user,omitempty"`
+	IP       string    `json:"ip,omitempty"`
+	Event    string    `json:"event"`            // Audit event name, or the log message
+	Status   string    `json:"status,omitempty"` // Status of audit events, e.g. fail
+	Detail   string    `json:"detail,omitempty"` // Error of failed audit events, or the parameters of the request
+}
+
+// SuspiciousIP is a client address with repeated authentication failures or denials
+type SuspiciousIP struct {
+	IP               string    `json:"ip"`
+	AuthFailures     int       `json:"auth_failures"`
+	TargetedUsers    int       `json:"targeted_users"` // Users with authentication failures from the address
+	PermissionDenied int       `json:"permission_denied"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	Reasons          []string  `json:"reasons"`
+}
+
+// SecurityReport gathers the security events of the logs for a security team
+type SecurityReport struct {
+	SchemaVersion int                        `json:"schema_version"`
+	TimeRange     TimeRange                  `json:"time_range"`
+	TotalEntries  int                        `json:"total_entries"`
+	Events        map[string][]SecurityEvent `json:"events"` // Category -> events in chronological order
+	SuspiciousIPs []SuspiciousIP             `json:"suspicious_ips"`
+}
+
+// auditActor returns the actor of an audit log record: who made the request, and from where.
+// Failed logins have no user yet, the login ID of the request stands for it.
+func auditActor(entry LogEntry) (user, ip string) {
+	var actor struct {
+		UserID    string `json:"user_id"`
+		IPAddress string `json:"ip_address"`
+	}
+	if raw := entry.Extras["actor"]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &actor)
+	}
+	if actor.UserID == "" && entry.Extras["event"] != "" {
+		var event struct {
+			Parameters struct {
+				LoginID string `json:"login_id"`
+			} `json:"parameters"`
+		}
+		_ = json.Unmarshal([]byte(entry.Extras["event"]), &event)
+		actor.UserID = event.Parameters.LoginID
+	}
+	return actor.UserID, actor.IPAddress
+}
+
+// auditDetail returns the error description of a failed audit record, or the parameters of
+// its request, e.g. the user and the roles of a role change
+func auditDetail(entry LogEntry) string {
+	var failure struct {
+		Description string `json:"description"`
+	}
+	if raw := entry.Extras["error"]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &failure)
+		if failure.Description != "" {
+			return failure.Description
+		}
+	}
+	var event struct {
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if raw := entry.Extras["event"]; raw != "" && json.Unmarshal([]byte(raw), &event) == nil && len(event.Parameters) > 2 {
+		return string(event.Parameters)
+	}
+	return ""
+}
+
+// securityEventOf returns the security event of an entry, if it is one. Audit records are
+// classified by their event name and status, server log entries by their message.
+func securityEventOf(entry LogEntry) (SecurityEvent, bool) {
+	user, ip := auditActor(entry)
+	if user == "" {
+		user = entry.User
+	}
+	if ip == "" {
+		ip = entry.Extras["ip_address"]
+	}
+	event := SecurityEvent{Time: entry.Timestamp, User: user, IP: ip, Event: entry.Message}
+
+	name := entry.Extras["event_name"]
+	lowerName := strings.ToLower(name)
+	failed := strings.EqualFold(entry.Status, "fail")
+	message := strings.ToLower(entry.Message)
+	if name != "" {
+		event.Event = name
+		event.Status = entry.Status
+		event.Detail = auditDetail(entry)
+	}
+
+	switch {
+	case name != "" && (strings.Contains(lowerName, "role") || strings.Contains(lowerName, "guest")):
+		event.Category = securityRoleChange
+	case name != "" && (strings.Contains(lowerName, "revoke") || lowerName == "disableuseraccesstoken"):
+		event.Category = securitySessionRevoked
+	case name != "" && failed && (strings.Contains(lowerName, "login") || strings.Contains(lowerName, "mfa") || strings.Contains(lowerName, "password")):
+		event.Category = securityAuthFailure
+	case name != "" && failed && strings.Contains(entry.Extras["error"], `"status_code":403`):
+		event.Category = securityPermissionDenied
+	case name != "":
+		return event, false
+	case containsAny(message, authFailurePhrases):
+		event.Category = securityAuthFailure
+	case containsAny(message, permissionDeniedPhrases) || entry.Extras["status_code"] == "403":
+		event.Category = securityPermissionDenied
+	case containsAny(message, sessionRevokedPhrases) && (strings.Contains(message, "session") || strings.Contains(message, "token")):
+		event.Category = securitySessionRevoked
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// containsAny reports whether text contains any of the phrases
+func containsAny(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSecurityReport classifies the security events of server and audit logs and flags the
+// client addresses behind repeated failures
+func buildSecurityReport(logs []LogEntry) SecurityReport {
+	report := SecurityReport{TotalEntries: len(logs), Events: map[string][]SecurityEvent{}}
+	for i, entry := range logs {
+		if i == 0 || entry.Timestamp.Before(report.TimeRange.Start) {
+			report.TimeRange.Start = entry.Timestamp
+		}
+		if entry.Timestamp.After(report.TimeRange.End) {
+			report.TimeRange.End = entry.Timestamp
+		}
+	}
+
+	type ipActivity struct {
+		SuspiciousIP
+		users map[string]bool
+	}
+	byIP := map[string]*ipActivity{}
+	for _, entry := range logs {
+		event, ok := securityEventOf(entry)
+		if !ok {
+			continue
+		}
+		report.Events[event.Category] = append(report.Events[event.Category], event)
+
+		if event.IP == "" || (event.Category != securityAuthFailure && event.Category != securityPermissionDenied) {
+			continue
+		}
+		activity, exists := byIP[event.IP]
+		if !exists {
+			activity = &ipActivity{SuspiciousIP: SuspiciousIP{IP: event.IP, FirstSeen: event.Time, LastSeen: event.Time}, users: map[string]bool{}}
+			byIP[event.IP] = activity
+		}
+		if event.Category == securityAuthFailure {
+			activity.AuthFailures++
+			if event.User != "" {
+				activity.users[event.User] = true
+			}
+		} else {
+			activity.PermissionDenied++
+		}
+		if event.Time.Before(activity.FirstSeen) {
+			activity.FirstSeen = event.Time
+		}
+		if event.Time.After(activity.LastSeen) {
+			activity.LastSeen = event.Time
+		}
+	}
+
+	for _, events := range report.Events {
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	}
+	for _, activity := range byIP {
+		activity.TargetedUsers = len(activity.users)
+		if activity.AuthFailures >= suspiciousAuthFailures {
+			activity.Reasons = append(activity.Reasons, fmt.Sprintf("%d authentication failures", activity.AuthFailures))
+		}
+		if activity.TargetedUsers >= suspiciousTargetedUsers {
+			activity.Reasons = append(activity.Reasons, fmt.Sprintf("failures for %d users", activity.TargetedUsers))
+		}
+		if activity.PermissionDenied >= suspiciousPermissionDenied {
+			activity.Reasons = append(activity.Reasons, fmt.Sprintf("%d permission denials", activity.PermissionDenied))
+		}
+		if len(activity.Reasons) > 0 {
+			report.SuspiciousIPs = append(report.SuspiciousIPs, activity.SuspiciousIP)
+		}
+	}
+	sort.Slice(report.SuspiciousIPs, func(i, j int) bool {
+		a, b := report.SuspiciousIPs[i], report.SuspiciousIPs[j]
+		if a.AuthFailures+a.PermissionDenied != b.AuthFailures+b.PermissionDenied {
+			return a.AuthFailures+a.PermissionDenied > b.AuthFailures+b.PermissionDenied
+		}
+		return a.IP < b.IP
+	})
+	return report
+}
+
+// markdownCell escapes text for a Markdown table cell
+func markdownCell(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(truncateString(text, 100))
+}
+
+// securityReportMarkdown formats the report for handoff to a security team
+func securityReportMarkdown(report SecurityReport) string {
+	const timeFormat = "2006-01-02 15:04:05"
+	var out strings.Builder
+	out.WriteString("# Security Event Report\n\n")
+	_, _ = fmt.Fprintf(&out, "Period: %s to %s (%s)\n\n", report.TimeRange.Start.Format(timeFormat),
+		report.TimeRange.End.Format(timeFormat), countNoun(report.TotalEntries, "log entry", "log entries"))
+
+	out.WriteString("## Summary\n\n| Category | Events | Users | IP addresses | First seen | Last seen |\n|---|---|---|---|---|---|\n")
+	for _, category := range securityCategories {
+		events := report.Events[category.ID]
+		if len(events) == 0 {
+			_, _ = fmt.Fprintf(&out, "| %s | 0 | - | - | - | - |\n", category.Title)
+			continue
+		}
+		users, ips := map[string]bool{}, map[string]bool{}
+		for _, event := range events {
+			if event.User != "" {
+				users[event.User] = true
+			}
+			if event.IP != "" {
+				ips[event.IP] = true
+			}
+		}
+		_, _ = fmt.Fprintf(&out, "| %s | %d | %d | %d | %s | %s |\n", category.Title, len(events), len(users), len(ips),
+			events[0].Time.Format(timeFormat), events[len(events)-1].Time.Format(timeFormat))
+	}
+
+	out.WriteString("\n## Suspicious IP addresses\n\n")
+	if len(report.SuspiciousIPs) == 0 {
+		out.WriteString("None.\n")
+	} else {
+		out.WriteString("| IP address | Authentication failures | Users targeted | Permission denied | First seen | Last seen | Reason |\n|---|---|---|---|---|---|---|\n")
+		for _, ip := range report.SuspiciousIPs {
+			_, _ = fmt.Fprintf(&out, "| %s | %d | %d | %d | %s | %s | %s |\n", ip.IP, ip.AuthFailures, ip.TargetedUsers, ip.PermissionDenied,
+				ip.FirstSeen.Format(timeFormat), ip.LastSeen.Format(timeFormat), strings.Join(ip.Reasons, ", "))
+		}
+	}
+
+	for _, category := range securityCategories {
+		events := report.Events[category.ID]
+		if len(events) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(&out, "\n## %s\n\n| Time | User | IP address | Event | Status | Detail |\n|---|---|---|---|---|---|\n", category.Title)
+		for _, event := range events[:min(len(events), securityMaxEvents)] {
+			_, _ = fmt.Fprintf(&out, "| %s | %s | %s | %s | %s | %s |\n", event.Time.Format(timeFormat), markdownCell(event.User),
+				markdownCell(event.IP), markdownCell(event.Event), markdownCell(event.Status), markdownCell(event.Detail))
+		}
+		if more := len(events) - securityMaxEvents; more > 0 {
+			_, _ = fmt.Fprintf(&out, "\n%d more events, not listed.\n", more)
+		}
+	}
+	return out.String()
+}
+
+// displaySecurityReport writes the security report as Markdown or JSON, and returns the
+// Markdown for the integrations filing the analysis
+func displaySecurityReport(logs []LogEntry, asJSON bool, output io.Writer) (string, error) {
+	report := buildSecurityReport(logs)
+	if asJSON {
+		report.SchemaVersion = schemaVersion
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		_, err = fmt.Fprintln(output, string(data))
+		return "", err
+	}
+	markdown := securityReportMarkdown(report)
+	_, err := fmt.Fprint(output, markdown)
+	return markdown, err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSecurityReport(t *testing.T) {
+	lines := []string{
+		`{"timestamp":"2024-03-01 10:05:00.000 Z","level":"info","msg":"","event_name":"updateUserRoles","status":"success","actor":{"user_id":"admin1","ip_address":"10.0.0.5"},"event":{"parameters":{"user_id":"bob","roles":"system_user system_admin"}}}`,
+		`{"timestamp":"2024-03-01 10:06:00.000 Z","level":"info","msg":"","event_name":"revokeAllSessionsForUser","status":"success","actor":{"user_id":"admin1","ip_address":"10.0.0.5"}}`,
+		`{"timestamp":"2024-03-01 10:07:00.000 Z","level":"error","msg":"You do not have the appropriate permissions.","user_id":"dave","ip_address":"198.51.100.7"}`,
+		`{"timestamp":"2024-03-01 10:08:00.000 Z","level":"info","msg":"","event_name":"getPost","status":"success","actor":{"user_id":"dave","ip_address":"198.51.100.7"}}`,
+		`{"timestamp":"2024-03-01 10:09:00.000 Z","level":"info","msg":"","event_name":"createPost","status":"fail","actor":{"user_id":"dave","ip_address":"198.51.100.7"},"error":{"description":"no permission","status_code":403}}`,
+		`{"timestamp":"2024-03-01 10:10:00.000 Z","level":"warn","msg":"Login failed: invalid_credentials","user_id":"erin","ip_address":"192.0.2.1"}`,
+		`{"timestamp":"2024-03-01 10:11:00.000 Z","level":"info","msg":"Server is starting"}`,
+	}
+	// Credential stuffing: failed logins for many users from one address
+	for i, user := range []string{"alice", "bob", "carol", "alice", "alice"} {
+		lines = append(lines, fmt.Sprintf(`{"timestamp":"2024-03-01 09:00:0%d.000 Z","level":"info","msg":"","event_name":"login","status":"fail","actor":{"user_id":"","ip_address":"203.0.113.9"},"event":{"parameters":{"login_id":"%s"}},"error":{"description":"invalid credentials","status_code":401}}`, i, user))
+	}
+	var logs []LogEntry
+	for _, line := range lines {
+		entry, err := parseJSONLine(line)
+		require.NoError(t, err)
+		logs = append(logs, entry)
+	}
+
+	report := buildSecurityReport(logs)
+	assert.Equal(t, len(logs), report.TotalEntries)
+	assert.Equal(t, mustParseTime(t, "2024-03-01 09:00:00.000 Z"), report.TimeRange.Start)
+
+	auth := report.Events[securityAuthFailure]
+	require.Len(t, auth, 6)
+	assert.Equal(t, SecurityEvent{Time: mustParseTime(t, "2024-03-01 09:00:00.000 Z"), Category: securityAuthFailure,
+		User: "alice", IP: "203.0.113.9", Event: "login", Status: "fail", Detail: "invalid credentials"}, auth[0])
+	assert.Equal(t, "erin", auth[5].User, "server log messages are classified too")
+
+	denied := report.Events[securityPermissionDenied]
+	require.Len(t, denied, 2)
+	assert.Equal(t, "You do not have the appropriate permissions.", denied[0].Event)
+	assert.Equal(t, "createPost", denied[1].Event)
+
+	roles := report.Events[securityRoleChange]
+	require.Len(t, roles, 1)
+	assert.Equal(t, `{"roles":"system_user system_admin","user_id":"bob"}`, roles[0].Detail)
+	require.Len(t, report.Events[securitySessionRevoked], 1)
+	assert.Equal(t, "revokeAllSessionsForUser", report.Events[securitySessionRevoked][0].Event)
+
+	require.Len(t, report.SuspiciousIPs, 1)
+	suspicious := report.SuspiciousIPs[0]
+	assert.Equal(t, "203.0.113.9", suspicious.IP)
+	assert.Equal(t, 5, suspicious.AuthFailures)
+	assert.Equal(t, 3, suspicious.TargetedUsers)
+	assert.Equal(t, []string{"5 authentication failures", "failures for 3 users"}, suspicious.Reasons)
+}
+
+func TestSecurityReportMarkdown(t *testing.T) {
+	entry, err := parseJSONLine(`{"timestamp":"2024-03-01 10:00:00.000 Z","level":"info","msg":"","event_name":"login","status":"fail","actor":{"ip_address":"203.0.113.9"},"event":{"parameters":{"login_id":"alice|bob"}}}`)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	markdown, err := displaySecurityReport([]LogEntry{entry}, false, &out)
+	require.NoError(t, err)
+	assert.Equal(t, out.String(), markdown)
+	assert.True(t, strings.HasPrefix(markdown, "# Security Event Report\n\nPeriod: 2024-03-01 10:00:00 to 2024-03-01 10:00:00 (1 log entry)\n"))
+	assert.Contains(t, markdown, "| Authentication failures | 1 | 1 | 1 | 2024-03-01 10:00:00 | 2024-03-01 10:00:00 |\n")
+	assert.Contains(t, markdown, "| Admin role changes | 0 | - | - | - | - |\n")
+	assert.Contains(t, markdown, "## Suspicious IP addresses\n\nNone.\n")
+	assert.Contains(t, markdown, `| 2024-03-01 10:00:00 | alice\|bob | 203.0.113.9 | login | fail | {"login_id":"alice\|bob"} |`)
+	assert.NotContains(t, markdown, "## Permission denied", "categories without events are only in the summary")
+
+	out.Reset()
+	markdown, err = displaySecurityReport([]LogEntry{entry}, true, &out)
+	require.NoError(t, err)
+	assert.Empty(t, markdown)
+	var report SecurityReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Len(t, report.Events[securityAuthFailure], 1)
+}
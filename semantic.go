@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// embeddingTimeout bounds each embedding request
+	embeddingTimeout = 2 * time.Minute
+	// embeddingBatchSize is the number of messages embedded per request
+	embeddingBatchSize = 100
+)
+
+// defaultEmbeddingModels are the embedding models used without --embedding-model
+var defaultEmbeddingModels = map[LLMProvider]string{
+	ProviderOpenAI: "text-embedding-3-small",
+	ProviderOllama: "nomic-embed-text",
+}
+
+// embeddingClient embeds texts with the embedding API of OpenAI or Ollama
+type embeddingClient struct {
+	provider LLMProvider
+	model    string
+	apiKey   string
+	baseURL  string
+	client   *http.Client
+	w        io.Writer // Destination of the retry messages
+}
+
+// newEmbeddingClient returns a client of a provider's embedding API, with its default model if
+// model is empty
+func newEmbeddingClient(provider LLMProvider, model, apiKey string, w io.Writer) (*embeddingClient, error) {
+	if _, ok := defaultEmbeddingModels[provider]; !ok {
+		return nil, fmt.Errorf("invalid --embedding-provider %q (expected openai or ollama)", provider)
+	}
+	if model == "" {
+		model = defaultEmbeddingModels[provider]
+	}
+	baseURL := strings.TrimSuffix(OllamaHost, "/")
+	if provider == ProviderOpenAI {
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key for openai embeddings, set --api-key or %s", getAPIKeyEnvVar(provider))
+		}
+		baseURL = providerURLs[ProviderOpenAI]
+	}
+	return &embeddingClient{provider: provider, model: model, apiKey: apiKey, baseURL: baseURL, client: newHTTPClient(embeddingTimeout), w: w}, nil
+}
+
+// embed returns the embeddings of texts, in order
+func (c *embeddingClient) embed(texts []string) ([][]float32, error) {
+	endpoint := c.baseURL + "/api/embed"
+	if c.provider == ProviderOpenAI {
+		endpoint = c.baseURL + "/v1/embeddings"
+	}
+	data, err := json.Marshal(map[string]any{"model": c.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" && c.provider == ProviderOpenAI {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	name := "OpenAI API"
+	if c.provider == ProviderOllama {
+		name = "Ollama"
+	}
+	body, err := sendLLMRequest(c.client, req, name, c.w, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// OpenAI answers {"data": [{"index", "embedding"}]}, Ollama {"embeddings": [...]}
+	var answer struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil {
+		return nil, fmt.Errorf("error parsing embeddings: %v", err)
+	}
+	vectors := answer.Embeddings
+	if c.provider == ProviderOpenAI {
+		vectors = make([][]float32, len(answer.Data))
+		for _, item := range answer.Data {
+			if item.Index < 0 || item.Index >= len(vectors) {
+				return nil, fmt.Errorf("error parsing embeddings: index %d out of range", item.Index)
+			}
+			vectors[item.Index] = item.Embedding
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d messages", name, len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// embeddingCache keeps the embeddings of messages on disk, so searching the same logs again
+// only embeds the query. Messages are keyed by their SHA-256.
+type embeddingCache struct {
+	path    string
+	vectors map[string][]float32
+	changed bool
+}
+
+// embeddingCachePath returns the cache file of an embedding model, e.g.
+// ~/.cache/lamp/embeddings/openai-text-embedding-3-small.json on Linux
+func embeddingCachePath(provider LLMProvider, model string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(fmt.Sprintf("%s-%s.json", provider, model))
+	return filepath.Join(dir, "lamp", "embeddings", name), nil
+}
+
+// loadEmbeddingCache reads the cache of an embedding model. A missing or unreadable cache
+// starts empty.
+func loadEmbeddingCache(provider LLMProvider, model string) (*embeddingCache, error) {
+	path, err := embeddingCachePath(provider, model)
+	if err != nil {
+		return nil, err
+	}
+	cache := &embeddingCache{path: path, vectors: map[string][]float32{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache, nil
+	}
+	if err := json.Unmarshal(data, &cache.vectors); err != nil {
+		logger.Warn("Ignoring the unreadable embedding cache", "file", path, "error", err)
+		cache.vectors = map[string][]float32{}
+	}
+	return cache, nil
+}
+
+// embeddingKey returns the cache key of a message
+func embeddingKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// save writes the cache if embeddings were added
+func (c *embeddingCache) save() error {
+	if !c.changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c.vectors)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o600)
+}
+
+// embedAll returns the embeddings of texts, embedding only those not in the cache
+func (c *embeddingCache) embedAll(client *embeddingClient, texts []string) ([][]float32, error) {
+	var missing []string
+	for _, text := range texts {
+		if _, ok := c.vectors[embeddingKey(text)]; !ok {
+			missing = append(missing, text)
+		}
+	}
+	if len(missing) > 0 {
+		bar := newProgressBar(len(missing), "Embedding log messages")
+		for start := 0; start < len(missing); start += embeddingBatchSize {
+			batch := missing[start:min(start+embeddingBatchSize, len(missing))]
+			vectors, err := client.embed(batch)
+			if err != nil {
+				return nil, err
+			}
+			for i, text := range batch {
+				c.vectors[embeddingKey(text)] = vectors[i]
+			}
+			c.changed = true
+			_ = bar.Add(len(batch))
+		}
+		_ = bar.Finish()
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = c.vectors[embeddingKey(text)]
+	}
+	return vectors, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, 0 if either is empty
+// or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// semanticMatch is a log message relevant to a query, with the entries that logged it
+type semanticMatch struct {
+	SchemaVersion int        `json:"schema_version"`
+	Score         float64    `json:"score"`
+	Message       string     `json:"message"`
+	Count         int        `json:"count"`
+	Entries       []LogEntry `json:"entries"`
+}
+
+// semanticSearch returns the limit messages of the logs most similar in meaning to the query.
+// Each distinct message is embedded once.
+func semanticSearch(logs []LogEntry, query string, client *embeddingClient, cache *embeddingCache, limit int) ([]semanticMatch, error) {
+	var messages []string
+	byMessage := map[string]*semanticMatch{}
+	for _, entry := range logs {
+		match, ok := byMessage[entry.Message]
+		if !ok {
+			match = &semanticMatch{Message: entry.Message}
+			byMessage[entry.Message] = match
+			messages = append(messages, entry.Message)
+		}
+		match.Count++
+		match.Entries = append(match.Entries, entry)
+	}
+
+	queryVectors, err := client.embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := cache.embedAll(client, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]semanticMatch, 0, len(messages))
+	for i, message := range messages {
+		match := byMessage[message]
+		match.Score = cosineSimilarity(queryVectors[0], vectors[i])
+		matches = append(matches, *match)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// displaySemanticMatches writes the matches, most relevant first, with the time and level of
+// the last entry of each message, or as JSON
+func displaySemanticMatches(matches []semanticMatch, asJSON bool, w io.Writer) error {
+	if asJSON {
+		for i := range matches {
+			matches[i].SchemaVersion = schemaVersion
+		}
+		data, err := json.MarshalIndent(matches, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	if len(matches) == 0 {
+		_, _ = fmt.Fprintln(w, "No log entries found")
+		return nil
+	}
+	for _, match := range matches {
+		last := match.Entries[len(match.Entries)-1]
+		_, _ = fmt.Fprintf(w, "%.2f  %s %s[%s]%s %s", match.Score, last.Timestamp.Format("2006-01-02 15:04:05.000"),
+			getLevelColor(last.Level), strings.ToUpper(last.Level), colorReset, match.Message)
+		if match.Count > 1 {
+			_, _ = fmt.Fprintf(w, " (%d times)", match.Count)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmbedding embeds a text on three axes: uploads, database and everything else
+func fakeEmbedding(text string) []float32 {
+	text = strings.ToLower(text)
+	switch {
+	case strings.Contains(text, "upload") || strings.Contains(text, "file"):
+		return []float32{1, 0.1, 0}
+	case strings.Contains(text, "database") || strings.Contains(text, "db"):
+		return []float32{0, 1, 0.1}
+	default:
+		return []float32{0.1, 0, 1}
+	}
+}
+
+// newEmbeddingServer returns an embedding API answering with fakeEmbedding, recording the
+// texts it embedded
+func newEmbeddingServer(t *testing.T, provider LLMProvider, embedded *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		// require would only stop the goroutine of the handler
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&request)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*embedded = append(*embedded, request.Input...)
+
+		if provider == ProviderOllama {
+			assert.Equal(t, "/api/embed", r.URL.Path)
+			assert.Equal(t, "nomic-embed-text", request.Model)
+			var embeddings [][]float32
+			for _, text := range request.Input {
+				embeddings = append(embeddings, fakeEmbedding(text))
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+			return
+		}
+
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		assert.Equal(t, "text-embedding-3-small", request.Model)
+		var data []map[string]any
+		for i := len(request.Input) - 1; i >= 0; i-- { // Out of order, as the API allows
+			data = append(data, map[string]any{"index": i, "embedding": fakeEmbedding(request.Input[i])})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+}
+
+func TestSemanticSearch(t *testing.T) {
+	initLogger()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ts := mustParseTime(t, "2024-03-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "info", Message: "Server started"},
+		{Timestamp: ts, Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: ts, Level: "error", Message: "Unable to save the attachment: file too large"},
+		{Timestamp: ts, Level: "error", Message: "Unable to save the attachment: file too large"},
+	}
+
+	for _, provider := range []LLMProvider{ProviderOpenAI, ProviderOllama} {
+		t.Run(string(provider), func(t *testing.T) {
+			var embedded []string
+			server := newEmbeddingServer(t, provider, &embedded)
+			defer server.Close()
+			useProviderURL(t, provider, server.URL)
+
+			client, err := newEmbeddingClient(provider, "", "sk-test", &bytes.Buffer{})
+			require.NoError(t, err)
+			cache, err := loadEmbeddingCache(provider, client.model)
+			require.NoError(t, err)
+
+			matches, err := semanticSearch(logs, "users can't upload files", client, cache, 2)
+			require.NoError(t, err)
+			require.Len(t, matches, 2)
+			assert.Equal(t, "Unable to save the attachment: file too large", matches[0].Message)
+			assert.Equal(t, 2, matches[0].Count)
+			assert.Len(t, matches[0].Entries, 2)
+			assert.InDelta(t, 1.0, matches[0].Score, 0.001)
+			assert.Equal(t, []string{"users can't upload files", "Server started", "Failed to ping DB", "Unable to save the attachment: file too large"}, embedded,
+				"each distinct message is embedded once")
+
+			// The messages are cached, only the query is embedded again
+			require.NoError(t, cache.save())
+			embedded = nil
+			cache, err = loadEmbeddingCache(provider, client.model)
+			require.NoError(t, err)
+			matches, err = semanticSearch(logs, "the database is down", client, cache, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "Failed to ping DB", matches[0].Message)
+			assert.Equal(t, []string{"the database is down"}, embedded)
+
+			var out bytes.Buffer
+			require.NoError(t, displaySemanticMatches(matches, false, &out))
+			assert.Contains(t, out.String(), "1.00  2024-03-01 10:00:00.000 ")
+			assert.Contains(t, out.String(), "Failed to ping DB\n")
+		})
+	}
+
+	_, err := newEmbeddingClient(ProviderAnthropic, "", "sk-test", &bytes.Buffer{})
+	assert.EqualError(t, err, `invalid --embedding-provider "anthropic" (expected openai or ollama)`)
+	_, err = newEmbeddingClient(ProviderOpenAI, "", "", &bytes.Buffer{})
+	assert.EqualError(t, err, "no API key for openai embeddings, set --api-key or OPENAI_API_KEY")
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, cosineSimilarity([]float32{1, 2}, []float32{2, 4}), 0.0001)
+	assert.InDelta(t, 0.0, cosineSimilarity([]float32{1, 0}, []float32{0, 1}), 0.0001)
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{1}, []float32{1, 2}))
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{0, 0}, []float32{1, 2}))
+}
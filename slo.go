@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sloMaxWindows is the number of violating windows listed per objective
+const sloMaxWindows = 10
+
+// sloMetrics are the metrics of --slo objectives: the percentage of error and warning
+// entries, and the number of error entries and of all entries of each window
+var sloMetrics = []string{"error_rate", "warn_rate", "errors", "entries"}
+
+// sloPattern matches objectives such as error_rate<1% or entries>=100
+var sloPattern = regexp.MustCompile(`^\s*([a-z_]+)\s*(<=|>=|<|>)\s*([0-9]+(?:\.[0-9]+)?)\s*(%?)\s*$`)
+
+// SLO is a service level objective of --slo: a metric that must stay below or above a
+// target in every window of --slo-window
+type SLO struct {
+	Objective string // As given, e.g. error_rate<1%
+	Metric    string // One of sloMetrics
+	Op        string // <, <=, > or >=
+	Target    float64
+}
+
+// SLOWindow is a window of an objective with the value of its metric
+type SLOWindow struct {
+	Start   time.Time
+	End     time.Time
+	Entries int
+	Value   float64
+}
+
+// SLOResult is the evaluation of an objective over the windows of the logs
+type SLOResult struct {
+	SLO        SLO
+	Windows    int         // Windows evaluated, without those lacking entries for rates
+	Violations []SLOWindow // Windows that missed the target, in chronological order
+}
+
+// parseSLO parses an objective such as error_rate<1%. Rates are percentages, with or
+// without the % sign.
+func parseSLO(objective string) (SLO, error) {
+	match := sloPattern.FindStringSubmatch(objective)
+	if match == nil {
+		return SLO{}, fmt.Errorf("invalid --slo %q: expected a metric, <, <=, > or >= and a target, such as error_rate<1%%", objective)
+	}
+	slo := SLO{Objective: strings.Join(strings.Fields(objective), ""), Metric: match[1], Op: match[2]}
+	if !contains(sloMetrics, slo.Metric) {
+		return SLO{}, fmt.Errorf("invalid --slo %q: unknown metric %s, expected %s", objective, slo.Metric, strings.Join(sloMetrics, ", "))
+	}
+	if match[4] == "%" && !slo.isRate() {
+		return SLO{}, fmt.Errorf("invalid --slo %q: %s is a number of entries, not a percentage", objective, slo.Metric)
+	}
+	slo.Target, _ = strconv.ParseFloat(match[3], 64)
+	return slo, nil
+}
+
+// parseSLOs parses the objectives of --slo
+func parseSLOs(objectives []string) ([]SLO, error) {
+	var slos []SLO
+	for _, objective := range objectives {
+		slo, err := parseSLO(objective)
+		if err != nil {
+			return nil, err
+		}
+		slos = append(slos, slo)
+	}
+	return slos, nil
+}
+
+// isRate reports whether the metric of the objective is a percentage of the entries
+func (s SLO) isRate() bool {
+	return strings.HasSuffix(s.Metric, "_rate")
+}
+
+// met reports whether a value of the metric meets the target
+func (s SLO) met(value float64) bool {
+	switch s.Op {
+	case "<":
+		return value < s.Target
+	case "<=":
+		return value <= s.Target
+	case ">":
+		return value > s.Target
+	default:
+		return value >= s.Target
+	}
+}
+
+// formatValue formats a value of the metric of the objective
+func (s SLO) formatValue(value float64) string {
+	if s.isRate() {
+		return fmt.Sprintf("%.2f%%", value)
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+// evaluateSLOs evaluates the objectives over consecutive windows of the logs, aligned on
+// multiples of the window so that successive runs use the same windows. Deduplicated entries
+// count as many times as they occurred with showDupes.
+func evaluateSLOs(slos []SLO, logs []LogEntry, window time.Duration, showDupes bool) []SLOResult {
+	if len(slos) == 0 || len(logs) == 0 {
+		return nil
+	}
+
+	// Count the entries, errors and warnings of each window, from the first to the last entry
+	start, end := logs[0].Timestamp, logs[0].Timestamp
+	for _, log := range logs {
+		if log.Timestamp.Before(start) {
+			start = log.Timestamp
+		}
+		if log.Timestamp.After(end) {
+			end = log.Timestamp
+		}
+	}
+	start = start.Truncate(window)
+	type windowCounts struct{ entries, errors, warnings int }
+	counts := make([]windowCounts, int(end.Sub(start)/window)+1)
+	for _, log := range logs {
+		count := 1
+		if showDupes && log.DuplicateCount > 1 {
+			count = log.DuplicateCount
+		}
+		c := &counts[int(log.Timestamp.Sub(start)/window)]
+		c.entries += count
+		switch {
+		case isErrorLevel(log.Level):
+			c.errors += count
+		case strings.EqualFold(log.Level, "warn") || strings.EqualFold(log.Level, "warning"):
+			c.warnings += count
+		}
+	}
+
+	results := make([]SLOResult, 0, len(slos))
+	for _, slo := range slos {
+		result := SLOResult{SLO: slo}
+		for i, c := range counts {
+			var value float64
+			switch slo.Metric {
+			case "error_rate", "warn_rate":
+				if c.entries == 0 {
+					continue
+				}
+				value = float64(c.errors) / float64(c.entries) * 100
+				if slo.Metric == "warn_rate" {
+					value = float64(c.warnings) / float64(c.entries) * 100
+				}
+			case "errors":
+				value = float64(c.errors)
+			default:
+				value = float64(c.entries)
+			}
+			result.Windows++
+			if !slo.met(value) {
+				windowStart := start.Add(time.Duration(i) * window)
+				result.Violations = append(result.Violations, SLOWindow{Start: windowStart, End: windowStart.Add(window), Entries: c.entries, Value: value})
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// displaySLOResults writes whether each objective was met, with the windows that violated it
+func displaySLOResults(results []SLOResult, window time.Duration, writer io.Writer) {
+	if len(results) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(writer, "%sSERVICE LEVEL OBJECTIVES%s (windows of %s)\n", colorHeaderBold, colorReset, formatSpan(window))
+	for _, result := range results {
+		if len(result.Violations) == 0 {
+			_, _ = fmt.Fprintf(writer, "%s✓%s %s: met (%s)\n", colorGreen, colorReset, result.SLO.Objective,
+				countNoun(result.Windows, "window", "windows"))
+			continue
+		}
+		_, _ = fmt.Fprintf(writer, "%s✗%s %s: violated in %d of %s\n", colorRed, colorReset, result.SLO.Objective,
+			len(result.Violations), countNoun(result.Windows, "window", "windows"))
+		for _, violation := range result.Violations[:min(len(result.Violations), sloMaxWindows)] {
+			_, _ = fmt.Fprintf(writer, "  %s to %s: %s (%s)\n", violation.Start.Format("2006-01-02 15:04"), violation.End.Format("2006-01-02 15:04"),
+				result.SLO.formatValue(violation.Value), countNoun(violation.Entries, "entry", "entries"))
+		}
+		if len(result.Violations) > sloMaxWindows {
+			_, _ = fmt.Fprintf(writer, "  ... and %d more\n", len(result.Violations)-sloMaxWindows)
+		}
+	}
+	_, _ = fmt.Fprintln(writer)
+}
+
+// sloViolationError returns an error naming the objectives that were violated, if any, so
+// that the command fails when the logs miss an objective
+func sloViolationError(results []SLOResult) error {
+	var violated []string
+	for _, result := range results {
+		if len(result.Violations) > 0 {
+			violated = append(violated, result.SLO.Objective)
+		}
+	}
+	if len(violated) == 0 {
+		return nil
+	}
+	return fmt.Errorf("service level objectives violated: %s", strings.Join(violated, ", "))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSLO(t *testing.T) {
+	slo, err := parseSLO("error_rate < 1.5%")
+	require.NoError(t, err)
+	assert.Equal(t, SLO{Objective: "error_rate<1.5%", Metric: "error_rate", Op: "<", Target: 1.5}, slo)
+
+	slo, err = parseSLO("entries>=100")
+	require.NoError(t, err)
+	assert.Equal(t, SLO{Objective: "entries>=100", Metric: "entries", Op: ">=", Target: 100}, slo)
+	assert.True(t, slo.met(100))
+	assert.False(t, slo.met(99))
+
+	for _, objective := range []string{"error_rate", "error_rate=1%", "latency<1s", "errors<5%"} {
+		_, err := parseSLO(objective)
+		assert.ErrorContains(t, err, "invalid --slo", objective)
+	}
+}
+
+func TestEvaluateSLOs(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 10:15:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: start, Level: "info", Message: "Server started"},
+		{Timestamp: start.Add(10 * time.Minute), Level: "info", Message: "User logged in"},
+		// Nothing logged between 11:00 and 12:00
+		{Timestamp: start.Add(2 * time.Hour), Level: "error", Message: "Failed to ping DB", DuplicateCount: 3},
+		{Timestamp: start.Add(2*time.Hour + time.Minute), Level: "info", Message: "Reconnected"},
+	}
+	slos, err := parseSLOs([]string{"error_rate<1%", "entries>0"})
+	require.NoError(t, err)
+
+	results := evaluateSLOs(slos, logs, time.Hour, true)
+	require.Len(t, results, 2)
+
+	// Windows are aligned on the hour, and windows without entries have no error rate
+	errorRate := results[0]
+	assert.Equal(t, 2, errorRate.Windows)
+	require.Len(t, errorRate.Violations, 1)
+	assert.Equal(t, SLOWindow{Start: mustParseTime(t, "2025-01-01 12:00:00.000 Z"), End: mustParseTime(t, "2025-01-01 13:00:00.000 Z"),
+		Entries: 4, Value: 75}, errorRate.Violations[0])
+
+	entries := results[1]
+	assert.Equal(t, 3, entries.Windows)
+	require.Len(t, entries.Violations, 1)
+	assert.Equal(t, mustParseTime(t, "2025-01-01 11:00:00.000 Z"), entries.Violations[0].Start)
+
+	var buf bytes.Buffer
+	displaySLOResults(results, time.Hour, &buf)
+	output := buf.String()
+	assert.Contains(t, output, "windows of 1 hour")
+	assert.Contains(t, output, "error_rate<1%: violated in 1 of 2 windows")
+	assert.Contains(t, output, "2025-01-01 12:00 to 2025-01-01 13:00: 75.00% (4 entries)")
+	assert.EqualError(t, sloViolationError(results), "service level objectives violated: error_rate<1%, entries>0")
+
+	// Deduplicated entries only count once without showDupes
+	assert.InDelta(t, 50.0, evaluateSLOs(slos, logs, time.Hour, false)[0].Violations[0].Value, 0.01)
+	assert.NoError(t, sloViolationError(evaluateSLOs(slos, logs[:2], time.Hour, true)))
+}
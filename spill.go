@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Approximate memory held by a parsed entry besides the text of its fields, used to keep
+// the parsed entries within --max-memory
+const (
+	entryBaseBytes = 240 // LogEntry struct
+	extraBaseBytes = 64  // Map slot and string headers of an extra
+)
+
+// entrySpill keeps the parsed entries within --max-memory by writing them to temporary
+// files, nil without --max-memory
+var entrySpill *logSpill
+
+// logSpill holds the entries parsed by a command, writing them to a temporary file as a
+// run sorted by timestamp whenever they exceed the memory budget. The runs are merged back
+// in timestamp order to write the entries.
+type logSpill struct {
+	budget  int64
+	used    int64             // Approximate memory of the entries not written to a run
+	entries []LogEntry        // Entries of the parsed files, not written to a run
+	dir     string            // Temporary directory of the runs, created by the first run
+	runs    []string          // Run files, in the order they were written
+	names   map[string]string // Names cited for the entries of temporary files
+}
+
+// newEntrySpill sets up the spill of the parsed entries for --max-memory, if set
+func newEntrySpill(maxMemory string) (*logSpill, error) {
+	if maxMemory == "" {
+		return nil, nil
+	}
+	budget, err := parseByteSize(maxMemory)
+	if err != nil || budget < 1<<20 {
+		return nil, fmt.Errorf("--max-memory must be a size of at least 1M, such as 512M or 2G, got %q", maxMemory)
+	}
+	return &logSpill{budget: int64(budget), names: make(map[string]string)}, nil
+}
+
+// entryMemory returns the approximate memory held by a parsed entry
+func entryMemory(log *LogEntry) int64 {
+	size := entryBaseBytes + len(log.Message) + len(log.Source) + len(log.User) + len(log.AckID) +
+		len(log.Raw) + len(log.SourceFile) + len(log.Node)
+	for key, value := range log.Extras {
+		size += extraBaseBytes + len(key) + len(value)
+	}
+	return int64(size)
+}
+
+// check accounts for the entry last appended to the entries of the file being parsed. When
+// the entries exceed the budget, it writes them with those of the files parsed before to a
+// run and returns no entries.
+func (s *logSpill) check(logs []LogEntry) ([]LogEntry, error) {
+	s.used += entryMemory(&logs[len(logs)-1])
+	if s.used <= s.budget {
+		return logs, nil
+	}
+	if err := s.writeRun(append(s.entries, logs...)); err != nil {
+		return nil, err
+	}
+	s.entries = nil
+	s.used = 0
+	return nil, nil
+}
+
+// collect adds the entries parsed from a file to all, or keeps them in the spill with
+// --max-memory
+func (s *logSpill) collect(all, logs []LogEntry) []LogEntry {
+	if s == nil {
+		return append(all, logs...)
+	}
+	s.entries = append(s.entries, logs...)
+	return all
+}
+
+// unspilled returns all with the entries kept in the spill, unless some were written to
+// runs, in which case they can only be read back with each
+func (s *logSpill) unspilled(all []LogEntry) []LogEntry {
+	if s == nil || s.spilled() {
+		return all
+	}
+	all = append(all, s.entries...)
+	s.entries = nil
+	s.used = 0
+	return all
+}
+
+// rename cites the entries parsed from a temporary file by name, such as the path of the
+// file in a support packet
+func (s *logSpill) rename(path, name string) {
+	if s != nil {
+		s.names[path] = name
+	}
+}
+
+// spilled reports whether entries were written to runs
+func (s *logSpill) spilled() bool {
+	return s != nil && len(s.runs) > 0
+}
+
+// writeRun sorts entries with entryBefore and writes them to a new run file
+func (s *logSpill) writeRun(entries []LogEntry) error {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "lamp_spill")
+		if err != nil {
+			return fmt.Errorf("failed to create spill directory: %v", err)
+		}
+		s.dir = dir
+	}
+
+	// Cite the entries by name before sorting them, as the merge of the runs compares names
+	for i := range entries {
+		if name, ok := s.names[entries[i].SourceFile]; ok {
+			entries[i].SourceFile = name
+		}
+	}
+	sortEntries(entries)
+
+	path := filepath.Join(s.dir, fmt.Sprintf("run-%d.gob", len(s.runs)))
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	buffered := bufio.NewWriter(file)
+	encoder := gob.NewEncoder(buffered)
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("failed to write spill file: %v", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+	s.runs = append(s.runs, path)
+	logger.Debug("Spilled parsed entries to disk", "file", path, "entries", len(entries))
+	return nil
+}
+
+// runCursor reads the entries of a run in order
+type runCursor struct {
+	run     int // Index of the run, which orders simultaneous entries
+	file    *os.File
+	decoder *gob.Decoder
+	entry   LogEntry // Next entry of the run
+}
+
+// next reads the next entry of the run, returning false at its end
+func (c *runCursor) next() (bool, error) {
+	c.entry = LogEntry{}
+	if err := c.decoder.Decode(&c.entry); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read spill file: %v", err)
+	}
+	return true, nil
+}
+
+// runHeap orders run cursors by their next entry, then by run for entries comparing equal
+type runHeap []*runCursor
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	a, b := &h[i].entry, &h[j].entry
+	if entryBefore(a, b) || entryBefore(b, a) {
+		return entryBefore(a, b)
+	}
+	return h[i].run < h[j].run
+}
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)   { *h = append(*h, x.(*runCursor)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	cursor := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return cursor
+}
+
+// each calls fn with all entries in timestamp order, merging the runs after writing the
+// entries still in memory to a last one
+func (s *logSpill) each(fn func(LogEntry) error) error {
+	if len(s.entries) > 0 {
+		if err := s.writeRun(s.entries); err != nil {
+			return err
+		}
+		s.entries = nil
+		s.used = 0
+	}
+
+	cursors := make(runHeap, 0, len(s.runs))
+	defer func() {
+		for _, cursor := range cursors {
+			_ = cursor.file.Close()
+		}
+	}()
+	for i, path := range s.runs {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open spill file: %v", err)
+		}
+		cursor := &runCursor{run: i, file: file, decoder: gob.NewDecoder(bufio.NewReader(file))}
+		cursors = append(cursors, cursor)
+	}
+
+	pending := make(runHeap, 0, len(cursors))
+	for _, cursor := range cursors {
+		ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			pending = append(pending, cursor)
+		}
+	}
+	heap.Init(&pending)
+	for pending.Len() > 0 {
+		cursor := pending[0]
+		if err := fn(cursor.entry); err != nil {
+			return err
+		}
+		ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&pending, 0)
+		} else {
+			heap.Pop(&pending)
+		}
+	}
+	return nil
+}
+
+// close removes the runs
+func (s *logSpill) close() {
+	if s != nil && s.dir != "" {
+		_ = os.RemoveAll(s.dir)
+	}
+}
+
+// processParsedLogs processes the parsed entries, writing them from disk when they exceeded
+// --max-memory, or keeping the newest ones with --keep-newest
+func processParsedLogs(logs []LogEntry) error {
+	inputLimit.warn()
+	if entrySpill.spilled() {
+		return processSpilledLogs(entrySpill)
+	}
+	return processLogs(inputLimit.newest(logs))
+}
+
+// processSpilledLogs writes the entries spilled to disk as CSV, JSON, JSON Lines or raw logs, or analyzes
+// them in a single pass, sending them to Splunk first with --splunk-url. The other modes need all
+// entries in memory.
+func processSpilledLogs(s *logSpill) error {
+	if trim || interactive || aiAnalyze || securityReport || summarize || mermaidFile != "" ||
+		chartPNG != "" || findingsFile != "" || baselineFile != "" || saveStatsFile != "" || diffStatsFile != "" || len(sloObjectives) > 0 ||
+		ticketWorkspaceName != "" || len(reportIntegrations()) > 0 {
+		return fmt.Errorf("the parsed entries exceed --max-memory %s, so they can only be written with --raw, --json, --ndjson or --csv, "+
+			"or analyzed with --analyze; narrow the logs with --level, --search, --start or --end, or raise --max-memory", maxMemory)
+	}
+	if topN < 1 {
+		return fmt.Errorf("--top must be at least 1, got %d", topN)
+	}
+	if err := validateActivityBucket(activityBucket); err != nil {
+		return err
+	}
+	if uploadTo != "" {
+		if err := validateUpload(); err != nil {
+			return err
+		}
+	}
+	if splunk.URL != "" {
+		if err := splunk.validate(); err != nil {
+			return err
+		}
+	}
+
+	output := os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer func() { _ = file.Close() }()
+		output = file
+		runStats.output(outputFile)
+		logger.Info("Writing output", "file", outputFile)
+	}
+
+	if splunk.URL != "" {
+		if err := sendToSplunk(splunk, s.each, output); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case csvOutput != "":
+		options, err := csvExportOptions()
+		if err != nil {
+			return err
+		}
+		if err := exportSpilledCSV(s, csvOutput, options); err != nil {
+			return fmt.Errorf("error exporting to CSV: %v", err)
+		}
+		_, _ = fmt.Fprintf(output, "Logs exported to CSV file: %s\n", csvOutput)
+		return uploadWrittenArtifacts()
+	case analyze || (!ndjsonOutput && !jsonOutput && !rawOutput):
+		if err := displaySpilledAnalysis(s, output); err != nil {
+			return err
+		}
+	case ndjsonOutput:
+		if err := s.each(func(log LogEntry) error {
+			return writeLogsNDJSON([]LogEntry{log}, output)
+		}); err != nil {
+			return fmt.Errorf("error writing JSON Lines: %v", err)
+		}
+	case jsonOutput:
+		if err := displaySpilledJSON(s, output); err != nil {
+			return err
+		}
+	case porcelain:
+		if err := s.each(func(log LogEntry) error {
+			displayLogsPorcelain([]LogEntry{log}, output)
+			return nil
+		}); err != nil {
+			return err
+		}
+	default:
+		count := 0
+		if err := s.each(func(log LogEntry) error {
+			displayLogPretty(log, output)
+			count++
+			return nil
+		}); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(output, "\nDisplayed %d log entries\n", count)
+	}
+	return uploadWrittenArtifacts()
+}
+
+// displaySpilledAnalysis analyzes the entries spilled to disk in a single pass, which only
+// computes the statistics counting entries
+func displaySpilledAnalysis(s *logSpill, output io.Writer) error {
+	stats := newLogStats(true)
+	if err := s.each(func(log LogEntry) error {
+		stats.add(log)
+		return nil
+	}); err != nil {
+		return err
+	}
+	analysis := stats.result(analysisTopLimit())
+
+	if porcelain {
+		displayAnalysisPorcelain(analysis, analysis.TotalEntries, output)
+		displayPacketDiagnosticsPorcelain(packetDiagnostics, output)
+		return nil
+	}
+	logger.Warn("The parsed entries exceed --max-memory, so the analysis only shows the statistics counted in a single pass")
+	displayAnalysis(analysis, output, false, analysis.TotalEntries, verboseAnalysis, fullAnalysis)
+	displayPacketDiagnostics(packetDiagnostics, output, verboseAnalysis)
+	return nil
+}
+
+// exportSpilledCSV exports the entries spilled to disk to a CSV file, like exportToCSV
+func exportSpilledCSV(s *logSpill, filePath string, options csvOptions) error {
+	return writeCSVFile(filePath, options, s.each)
+}
+
+// displaySpilledJSON writes the entries spilled to disk as a JSON array, like displayLogsJSON
+func displaySpilledJSON(s *logSpill, writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	separator := "[\n  "
+	if err := s.each(func(log LogEntry) error {
+		entry, err := json.MarshalIndent(log, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("error formatting JSON: %v", err)
+		}
+		_, _ = buffered.WriteString(separator)
+		_, _ = buffered.Write(entry)
+		separator = ",\n  "
+		return nil
+	}); err != nil {
+		return err
+	}
+	_, _ = buffered.WriteString("\n]\n")
+	return buffered.Flush()
+}
+
+// startEntrySpill sets up the spill of the entries parsed by a command for --max-memory
+func startEntrySpill() error {
+	spill, err := newEntrySpill(maxMemory)
+	if err != nil {
+		return err
+	}
+	entrySpill = spill
+	return nil
+}
+
+// stopEntrySpill removes the entries spilled by a command
+func stopEntrySpill() {
+	entrySpill.close()
+	entrySpill = nil
+}
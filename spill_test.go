@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEntrySpill(t *testing.T) {
+	spill, err := newEntrySpill("")
+	require.NoError(t, err)
+	assert.Nil(t, spill, "nothing is spilled without --max-memory")
+
+	spill, err = newEntrySpill("2G")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2<<30), spill.budget)
+
+	for _, invalid := range []string{"lots", "512K", "-1G"} {
+		_, err := newEntrySpill(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLogSpill(t *testing.T) {
+	initLogger()
+	var server, notifications bytes.Buffer
+	for i := 0; i < 20; i++ {
+		// The files interleave, so that merging the runs must reorder their entries
+		_, _ = fmt.Fprintf(&server, `{"timestamp":"2024-03-01 10:00:%02d.000 Z","level":"info","msg":"server %d"}`+"\n", 2*i, i)
+		_, _ = fmt.Fprintf(&notifications, `{"timestamp":"2024-03-01 10:00:%02d.000 Z","level":"info","msg":"notification %d"}`+"\n", 2*i+1, i)
+	}
+	path := writePacket(t, map[string]string{
+		"packet/logs/mattermost.log":    server.String(),
+		"packet/logs/notifications.log": notifications.String(),
+	})
+
+	entrySpill = &logSpill{budget: 2000, names: make(map[string]string)}
+	defer stopEntrySpill()
+	logs, err := parseSupportPacket(path, packetLogSelection{}, "", "", "", "", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, logs, "spilled entries are only read back by merging the runs")
+	require.True(t, entrySpill.spilled())
+	assert.Greater(t, len(entrySpill.runs), 2)
+
+	var merged []LogEntry
+	require.NoError(t, entrySpill.each(func(log LogEntry) error {
+		merged = append(merged, log)
+		return nil
+	}))
+	require.Len(t, merged, 40)
+	assert.True(t, sort.SliceIsSorted(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	}))
+	assert.Equal(t, "server 0", merged[0].Message)
+	assert.Equal(t, "notification 19", merged[39].Message)
+	for _, log := range merged {
+		assert.Contains(t, []string{"packet/logs/mattermost.log", "packet/logs/notifications.log"}, log.SourceFile,
+			"entries cite the file of the packet rather than its temporary copy")
+		assert.Equal(t, "packet", log.Node, "spilled entries keep the node of their file")
+		assert.NotEmpty(t, log.Raw)
+		assert.NotZero(t, log.Line)
+	}
+
+	t.Run("writes the merged entries as JSON", func(t *testing.T) {
+		var spilled, direct bytes.Buffer
+		require.NoError(t, displaySpilledJSON(entrySpill, &spilled))
+		displayLogsJSON(merged, &direct)
+		assert.Equal(t, direct.String(), spilled.String())
+	})
+
+	t.Run("writes the merged entries as CSV", func(t *testing.T) {
+		dir := t.TempDir()
+		options := csvOptions{Delimiter: '\t', ExpandExtras: true}
+		spilled, direct := filepath.Join(dir, "spilled.csv.gz"), filepath.Join(dir, "direct.csv.gz")
+		require.NoError(t, exportSpilledCSV(entrySpill, spilled, options))
+		require.NoError(t, exportToCSV(merged, direct, options))
+		spilledContent, err := os.ReadFile(spilled)
+		require.NoError(t, err)
+		directContent, err := os.ReadFile(direct)
+		require.NoError(t, err)
+		assert.Equal(t, directContent, spilledContent)
+	})
+}
+
+func TestLogSpillWithinBudget(t *testing.T) {
+	spill := &logSpill{budget: 1 << 20, names: make(map[string]string)}
+	logs := []LogEntry{{Message: "first"}}
+	logs, err := spill.check(logs)
+	require.NoError(t, err)
+	all := spill.collect(nil, logs)
+	assert.Empty(t, all)
+	all = spill.unspilled(all)
+	assert.False(t, spill.spilled())
+	require.Len(t, all, 1)
+	assert.Equal(t, "first", all[0].Message)
+
+	var none *logSpill
+	assert.Len(t, none.unspilled(none.collect(nil, logs)), 1, "entries are kept in memory without --max-memory")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// splunkTimeout bounds each request made to the HTTP Event Collector
+	splunkTimeout = 60 * time.Second
+	// splunkEventPath is the endpoint of JSON events, appended to URLs without a path
+	splunkEventPath = "/services/collector/event"
+	// splunkBatchBytes is the size of the batches of events sent, below the 1 MB default
+	// max_content_length of older Splunk versions
+	splunkBatchBytes = 512 * 1024
+	// splunkServerSourcetype and splunkNotificationSourcetype are the default sourcetypes of
+	// the entries of server and notification logs
+	splunkServerSourcetype       = "mattermost:server"
+	splunkNotificationSourcetype = "mattermost:notifications"
+)
+
+// splunkSettings are the HTTP Event Collector and the metadata of the events of --splunk-url
+type splunkSettings struct {
+	URL        string
+	Token      string
+	Index      string // Empty for the default index of the token
+	Sourcetype string // Empty to use splunkServerSourcetype or splunkNotificationSourcetype
+}
+
+// validate checks that the collector is an http or https URL and that the token is set
+func (s splunkSettings) validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --splunk-url %q: expected an http or https URL", s.URL)
+	}
+	if s.Token == "" {
+		return fmt.Errorf("--splunk-url requires --splunk-token")
+	}
+	return nil
+}
+
+// endpoint returns the URL events are posted to, the event endpoint of the collector when
+// the URL has no path
+func (s splunkSettings) endpoint() string {
+	u, _ := url.Parse(s.URL)
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = splunkEventPath
+	}
+	return u.String()
+}
+
+// splunkEvent is an entry in the format of the HTTP Event Collector: the time of the entry
+// in seconds since the epoch, and the entry itself as written by --ndjson.
+type splunkEvent struct {
+	Time       float64   `json:"time"`
+	Host       string    `json:"host,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Sourcetype string    `json:"sourcetype"`
+	Index      string    `json:"index,omitempty"`
+	Event      *LogEntry `json:"event"`
+}
+
+// newSplunkEvent returns the event of an entry, with its node as host and its file as source
+func (s splunkSettings) newSplunkEvent(log LogEntry) splunkEvent {
+	sourcetype := s.Sourcetype
+	if sourcetype == "" {
+		sourcetype = splunkServerSourcetype
+		if log.LogSource == "notifications" {
+			sourcetype = splunkNotificationSourcetype
+		}
+	}
+	return splunkEvent{
+		Time:       float64(log.Timestamp.UnixMilli()) / 1000,
+		Host:       log.Node,
+		Source:     log.SourceFile,
+		Sourcetype: sourcetype,
+		Index:      s.Index,
+		Event:      &log,
+	}
+}
+
+// splunkClient sends events to an HTTP Event Collector in batches
+type splunkClient struct {
+	client   *http.Client
+	settings splunkSettings
+	batch    bytes.Buffer
+	sent     int // Events accepted by the collector
+	pending  int // Events in the batch
+}
+
+// newSplunkClient returns a client for the collector of the settings
+func newSplunkClient(settings splunkSettings) *splunkClient {
+	return &splunkClient{client: newHTTPClient(splunkTimeout), settings: settings}
+}
+
+// add adds an entry to the batch, sending the batch when it is full
+func (c *splunkClient) add(log LogEntry) error {
+	data, err := json.Marshal(c.settings.newSplunkEvent(log))
+	if err != nil {
+		return err
+	}
+	if c.batch.Len() > 0 && c.batch.Len()+len(data) > splunkBatchBytes {
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+	c.batch.Write(data)
+	c.batch.WriteByte('\n')
+	c.pending++
+	return nil
+}
+
+// flush sends the events of the batch
+func (c *splunkClient) flush() error {
+	if c.pending == 0 {
+		return nil
+	}
+	req, err := http.NewRequest("POST", c.settings.endpoint(), bytes.NewReader(c.batch.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+c.settings.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lamp/"+currentVersion())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending the entries to Splunk: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The collector explains errors in {"text": "Invalid token", "code": 4}
+		var answer struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(body, &answer) == nil && answer.Text != "" {
+			return fmt.Errorf("error sending the entries to Splunk: %s (%s)", answer.Text, resp.Status)
+		}
+		return fmt.Errorf("error sending the entries to Splunk: it answered with %s", resp.Status)
+	}
+
+	c.sent += c.pending
+	c.pending = 0
+	c.batch.Reset()
+	return nil
+}
+
+// sendToSplunk sends the entries iterated by each to the HTTP Event Collector of the settings
+func sendToSplunk(settings splunkSettings, each func(func(LogEntry) error) error, out io.Writer) error {
+	client := newSplunkClient(settings)
+	err := each(client.add)
+	if err == nil {
+		err = client.flush()
+	}
+	if err != nil {
+		if client.sent > 0 {
+			return fmt.Errorf("%v, after sending %d entries", err, client.sent)
+		}
+		return err
+	}
+
+	index := "the default index"
+	if settings.Index != "" {
+		index = "index " + settings.Index
+	}
+	_, _ = fmt.Fprintf(out, "Sent %d entries to Splunk (%s)\n", client.sent, index)
+	return nil
+}
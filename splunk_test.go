@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplunkSettings(t *testing.T) {
+	assert.NoError(t, splunkSettings{URL: "https://splunk.example.com:8088", Token: "t"}.validate())
+	assert.ErrorContains(t, splunkSettings{URL: "splunk.example.com", Token: "t"}.validate(), "invalid --splunk-url")
+	assert.ErrorContains(t, splunkSettings{URL: "https://splunk.example.com:8088"}.validate(), "--splunk-token")
+
+	assert.Equal(t, "https://splunk.example.com:8088/services/collector/event", splunkSettings{URL: "https://splunk.example.com:8088/"}.endpoint())
+	assert.Equal(t, "https://splunk.example.com/hec/event", splunkSettings{URL: "https://splunk.example.com/hec/event"}.endpoint(),
+		"URLs with a path are kept, for collectors behind a proxy")
+}
+
+func TestSendToSplunk(t *testing.T) {
+	var requests int
+	var events []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/services/collector/event", r.URL.Path)
+		assert.Equal(t, "Splunk secret", r.Header.Get("Authorization"))
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, splunkBatchBytes*2)
+		for scanner.Scan() {
+			var event map[string]any
+			// require would only stop the goroutine of the handler
+			if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event)) {
+				events = append(events, event)
+			}
+		}
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	ts := mustParseTime(t, "2024-03-01 10:00:00.250 Z")
+	logs := []LogEntry{
+		{Timestamp: ts, Level: "error", Message: "Failed to ping DB", SourceFile: "logs/mattermost.log", Node: "node1",
+			Extras: map[string]string{"error": "timeout"}},
+		{Timestamp: ts, Level: "info", Message: "Notification sent", LogSource: "notifications", AckID: "1"},
+	}
+	// Enough entries for several batches
+	for i := 0; i < 5000; i++ {
+		logs = append(logs, LogEntry{Timestamp: ts, Level: "debug", Message: strings.Repeat("x", 200)})
+	}
+
+	var out bytes.Buffer
+	settings := splunkSettings{URL: server.URL, Token: "secret", Index: "mattermost"}
+	require.NoError(t, sendToSplunk(settings, eachEntry(logs), &out))
+	assert.Equal(t, "Sent 5002 entries to Splunk (index mattermost)\n", out.String())
+	assert.Greater(t, requests, 1)
+	require.Len(t, events, len(logs))
+
+	assert.Equal(t, map[string]any{
+		"time":       1709287200.25,
+		"host":       "node1",
+		"source":     "logs/mattermost.log",
+		"sourcetype": "mattermost:server",
+		"index":      "mattermost",
+		"event": map[string]any{
+			"timestamp":      "2024-03-01T10:00:00.25Z",
+			"level":          "error",
+			"message":        "Failed to ping DB",
+			"extras":         map[string]any{"error": "timeout"},
+			"source_file":    "logs/mattermost.log",
+			"node":           "node1",
+			"schema_version": 1.0,
+		},
+	}, events[0])
+	assert.Equal(t, "mattermost:notifications", events[1]["sourcetype"])
+
+	settings.Sourcetype = "mm"
+	events = nil
+	require.NoError(t, sendToSplunk(settings, eachEntry(logs[1:2]), &out))
+	assert.Equal(t, "mm", events[0]["sourcetype"], "the sourcetype of the flag applies to all entries")
+}
+
+func TestSendToSplunkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer server.Close()
+
+	logs := []LogEntry{{Timestamp: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), Level: "info", Message: "Server started"}}
+	err := sendToSplunk(splunkSettings{URL: server.URL, Token: "wrong"}, eachEntry(logs), &bytes.Buffer{})
+	assert.EqualError(t, err, "error sending the entries to Splunk: Invalid token (403 Forbidden)")
+}
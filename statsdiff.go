@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// statsDiffMinChange is the factor by which the rate of an error signature must change
+	// to be reported
+	statsDiffMinChange = 2.0
+	// statsDiffMaxItems is the number of signatures of each kind displayed
+	statsDiffMaxItems = 10
+)
+
+// StatsDiff is the difference between the statistics of the logs and those saved by a
+// previous run with --save-stats. Rates are per hour, so that periods of different lengths
+// compare.
+type StatsDiff struct {
+	Previous    Baseline
+	Current     Baseline
+	Incremental bool // Current only covers the entries logged after the previous run
+
+	Levels            []StatsChange // Every level of either run, by name
+	NewSignatures     []StatsChange // Errors absent from the previous run, most frequent first
+	GoneSignatures    []StatsChange // Errors of the previous run that stopped, most frequent first
+	ChangedSignatures []StatsChange // Errors whose rate changed by statsDiffMinChange, largest change first
+}
+
+// StatsChange is the rate per hour of a level or an error signature in both runs
+type StatsChange struct {
+	Item     string
+	Previous float64
+	Current  float64
+}
+
+// diffStats compares the logs with the statistics of a previous run. When the logs continue
+// those of the previous run, as successive analyses of a continuously collected log do, only
+// the entries logged since then are compared.
+func diffStats(previous Baseline, logs []LogEntry) StatsDiff {
+	diff := StatsDiff{Previous: previous}
+	current := buildBaseline(logs, nil)
+	if current.TotalEntries > 0 && !current.Start.After(previous.End) && current.End.After(previous.End) {
+		var since []LogEntry
+		for _, log := range logs {
+			if log.Timestamp.After(previous.End) {
+				since = append(since, log)
+			}
+		}
+		current = buildBaseline(since, nil)
+		// The period starts where the previous one ended, not at the first new entry
+		current.Start = previous.End
+		diff.Incremental = true
+	}
+	diff.Current = current
+
+	previousHours, currentHours := previous.durationHours(), current.durationHours()
+	rates := func(previousCount, currentCount int) StatsChange {
+		return StatsChange{Previous: float64(previousCount) / previousHours, Current: float64(currentCount) / currentHours}
+	}
+
+	levels := make(map[string]bool)
+	for level := range previous.LevelCounts {
+		levels[level] = true
+	}
+	for level := range current.LevelCounts {
+		levels[level] = true
+	}
+	for level := range levels {
+		change := rates(previous.LevelCounts[level], current.LevelCounts[level])
+		change.Item = level
+		diff.Levels = append(diff.Levels, change)
+	}
+	sort.Slice(diff.Levels, func(i, j int) bool { return diff.Levels[i].Item < diff.Levels[j].Item })
+
+	for signature, count := range current.ErrorSignatures {
+		previousCount, known := previous.ErrorSignatures[signature]
+		change := rates(previousCount, count)
+		change.Item = signature
+		switch {
+		case !known:
+			diff.NewSignatures = append(diff.NewSignatures, change)
+		case max(count, previousCount) >= baselineMinCount && statsRateChanged(change):
+			diff.ChangedSignatures = append(diff.ChangedSignatures, change)
+		}
+	}
+	for signature, count := range previous.ErrorSignatures {
+		if _, ok := current.ErrorSignatures[signature]; !ok {
+			change := rates(count, 0)
+			change.Item = signature
+			diff.GoneSignatures = append(diff.GoneSignatures, change)
+		}
+	}
+
+	sortChanges := func(changes []StatsChange, key func(StatsChange) float64) {
+		sort.Slice(changes, func(i, j int) bool {
+			if key(changes[i]) != key(changes[j]) {
+				return key(changes[i]) > key(changes[j])
+			}
+			return changes[i].Item < changes[j].Item
+		})
+	}
+	sortChanges(diff.NewSignatures, func(c StatsChange) float64 { return c.Current })
+	sortChanges(diff.GoneSignatures, func(c StatsChange) float64 { return c.Previous })
+	sortChanges(diff.ChangedSignatures, func(c StatsChange) float64 { return math.Abs(c.Current - c.Previous) })
+	return diff
+}
+
+// statsRateChanged reports whether a rate went up or down by statsDiffMinChange
+func statsRateChanged(change StatsChange) bool {
+	return change.Current >= change.Previous*statsDiffMinChange || change.Previous >= change.Current*statsDiffMinChange
+}
+
+// errorRate returns the percentage of error entries of a run
+func (b Baseline) errorRate() float64 {
+	if b.TotalEntries == 0 {
+		return 0
+	}
+	return float64(b.ErrorEntries) / float64(b.TotalEntries) * 100
+}
+
+// formatRateChange formats the change of a rate, e.g. "+150%", or "new" from no entries
+func formatRateChange(previous, current float64) string {
+	if previous == 0 {
+		if current == 0 {
+			return "0%"
+		}
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", (current-previous)/previous*100)
+}
+
+// displayStatsDiff writes the changes since the previous run
+func displayStatsDiff(diff StatsDiff, writer io.Writer) {
+	previous, current := diff.Previous, diff.Current
+	_, _ = fmt.Fprintf(writer, "%sCHANGES SINCE THE PREVIOUS RUN%s\n", colorHeaderBold, colorReset)
+	_, _ = fmt.Fprintf(writer, "Previous: %d entries • %s to %s\n", previous.TotalEntries,
+		previous.Start.Format("2006-01-02 15:04:05"), previous.End.Format("2006-01-02 15:04:05"))
+	if diff.Incremental {
+		_, _ = fmt.Fprintf(writer, "Since then: %d new entries • until %s\n", current.TotalEntries, current.End.Format("2006-01-02 15:04:05"))
+	} else {
+		_, _ = fmt.Fprintf(writer, "Current: %d entries • %s to %s\n", current.TotalEntries,
+			current.Start.Format("2006-01-02 15:04:05"), current.End.Format("2006-01-02 15:04:05"))
+	}
+
+	entries := StatsChange{Previous: float64(previous.TotalEntries) / previous.durationHours(), Current: float64(current.TotalEntries) / current.durationHours()}
+	_, _ = fmt.Fprintf(writer, "%sEntries:%s %.1f/h → %.1f/h (%s) • %sError rate:%s %.1f%% → %.1f%%\n",
+		colorSubHeader, colorReset, entries.Previous, entries.Current, formatRateChange(entries.Previous, entries.Current),
+		colorSubHeader, colorReset, previous.errorRate(), current.errorRate())
+
+	var levels []string
+	for _, level := range diff.Levels {
+		levels = append(levels, fmt.Sprintf("%s%s%s %s", getLevelColor(level.Item), level.Item, colorReset, formatRateChange(level.Previous, level.Current)))
+	}
+	if len(levels) > 0 {
+		_, _ = fmt.Fprintf(writer, "%sLevels:%s %s\n", colorSubHeader, colorReset, strings.Join(levels, " • "))
+	}
+
+	for _, change := range diff.NewSignatures[:min(len(diff.NewSignatures), statsDiffMaxItems)] {
+		_, _ = fmt.Fprintf(writer, "%sNew:%s %s%s%s (%.1f/h)\n", colorSubHeader, colorReset, colorRed, truncateString(change.Item, 80), colorReset, change.Current)
+	}
+	for _, change := range diff.ChangedSignatures[:min(len(diff.ChangedSignatures), statsDiffMaxItems)] {
+		_, _ = fmt.Fprintf(writer, "%sChanged:%s %s (%.1f/h → %.1f/h, %s)\n", colorSubHeader, colorReset,
+			truncateString(change.Item, 80), change.Previous, change.Current, formatRateChange(change.Previous, change.Current))
+	}
+	for _, change := range diff.GoneSignatures[:min(len(diff.GoneSignatures), statsDiffMaxItems)] {
+		_, _ = fmt.Fprintf(writer, "%sStopped:%s %s%s%s (%.1f/h before)\n", colorSubHeader, colorReset, colorGreen, truncateString(change.Item, 80), colorReset, change.Previous)
+	}
+	if len(diff.NewSignatures)+len(diff.ChangedSignatures)+len(diff.GoneSignatures) == 0 {
+		_, _ = fmt.Fprintln(writer, "No new, stopped or changed errors")
+	}
+	_, _ = fmt.Fprintln(writer)
+}
+
+// saveStats writes the statistics of the logs for a later run to compare with --diff-stats
+func saveStats(logs []LogEntry, files []string, filePath string) error {
+	return saveBaseline(buildBaseline(logs, files), filePath)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffStats(t *testing.T) {
+	start := mustParseTime(t, "2025-01-01 00:00:00.000 Z")
+
+	// A continuously collected log: 10 hours with one DB error and one cache error per hour,
+	// then 2 more hours where the DB errors triple, the cache errors stop and SMTP fails
+	var logs []LogEntry
+	for i := 0; i < 10; i++ {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		logs = append(logs,
+			LogEntry{Timestamp: ts, Level: "error", Message: "Failed to ping DB"},
+			LogEntry{Timestamp: ts.Add(time.Minute), Level: "error", Message: "Cache miss storm"},
+			LogEntry{Timestamp: ts.Add(2 * time.Minute), Level: "info", Message: "Request handled"},
+		)
+	}
+	previous := buildBaseline(logs, []string{"mattermost.log"})
+	path := filepath.Join(t.TempDir(), "stats.json")
+	require.NoError(t, saveStats(logs, []string{"mattermost.log"}, path))
+	loaded, err := loadBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, previous.ErrorSignatures, loaded.ErrorSignatures)
+
+	for i := 0; i < 2; i++ {
+		ts := previous.End.Add(time.Duration(i)*time.Hour + 30*time.Minute)
+		for j := 0; j < 3; j++ {
+			logs = append(logs, LogEntry{Timestamp: ts.Add(time.Duration(j) * time.Second), Level: "error", Message: "Failed to ping DB"})
+		}
+		logs = append(logs,
+			LogEntry{Timestamp: ts.Add(time.Minute), Level: "error", Message: "Failed to send mail"},
+			LogEntry{Timestamp: ts.Add(2 * time.Minute), Level: "info", Message: "Request handled"},
+		)
+	}
+
+	diff := diffStats(loaded, logs)
+	assert.True(t, diff.Incremental, "the log continues the previous one")
+	assert.Equal(t, 10, diff.Current.TotalEntries, "only the entries since the previous run are compared")
+	assert.True(t, diff.Current.Start.Equal(previous.End))
+
+	require.Len(t, diff.NewSignatures, 1)
+	assert.Equal(t, normalizeLogMessage("Failed to send mail"), diff.NewSignatures[0].Item)
+	require.Len(t, diff.GoneSignatures, 1)
+	assert.Equal(t, normalizeLogMessage("Cache miss storm"), diff.GoneSignatures[0].Item)
+	require.Len(t, diff.ChangedSignatures, 1)
+	assert.Equal(t, normalizeLogMessage("Failed to ping DB"), diff.ChangedSignatures[0].Item)
+	assert.Greater(t, diff.ChangedSignatures[0].Current, 2*diff.ChangedSignatures[0].Previous)
+	require.Len(t, diff.Levels, 2)
+	assert.Equal(t, "ERROR", diff.Levels[0].Item)
+
+	var buf bytes.Buffer
+	displayStatsDiff(diff, &buf)
+	output := buf.String()
+	assert.Contains(t, output, "Since then: 10 new entries")
+	assert.Contains(t, output, "failed to send mail")
+	assert.Contains(t, output, "cache miss storm")
+
+	buf.Reset()
+	displayStatsDiffPorcelain(diff, &buf)
+	assert.Contains(t, buf.String(), "stats_diff_signature\tnew\t"+normalizeLogMessage("Failed to send mail")+"\t0\t")
+	assert.Contains(t, buf.String(), "\ttrue\n")
+
+	// An unrelated log is compared as a whole
+	other := []LogEntry{{Timestamp: start.Add(-48 * time.Hour), Level: "info", Message: "Request handled"}}
+	diff = diffStats(loaded, other)
+	assert.False(t, diff.Incremental)
+	assert.Equal(t, 1, diff.Current.TotalEntries)
+	assert.Len(t, diff.GoneSignatures, 2)
+	assert.Empty(t, diff.NewSignatures)
+
+	buf.Reset()
+	displayStatsDiff(diffStats(loaded, logs[:30]), &buf)
+	assert.Contains(t, buf.String(), "No new, stopped or changed errors")
+}
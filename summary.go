@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// summaryTopErrors is the number of error messages named in the summary
+const summaryTopErrors = 3
+
+// summaryTimeFormat is the format of the times of the summary
+const summaryTimeFormat = "2006-01-02 15:04"
+
+// summarizeAnalysis describes the analysis in a few sentences built from templates, for
+// environments where logs may not be sent to an LLM
+func summarizeAnalysis(analysis LogAnalysis) []string {
+	if analysis.TotalEntries == 0 {
+		return []string{"The logs contain no entries."}
+	}
+
+	errors := analysis.LevelCounts["ERROR"] + analysis.LevelCounts["FATAL"]
+	warnings := analysis.LevelCounts["WARN"] + analysis.LevelCounts["WARNING"]
+	timeRange := analysis.TimeRange
+	sentences := []string{fmt.Sprintf("The logs cover %s, from %s to %s, with %s; %.1f%% of them are errors (%s, %s).",
+		formatSpan(timeRange.End.Sub(timeRange.Start)), timeRange.Start.Format(summaryTimeFormat), timeRange.End.Format(summaryTimeFormat),
+		countNoun(analysis.TotalEntries, "entry", "entries"), analysis.ErrorRate,
+		countNoun(errors, "error", "errors"), countNoun(warnings, "warning", "warnings"))}
+
+	if errors == 0 {
+		sentences = append(sentences, "No errors were logged.")
+	} else {
+		sentences = append(sentences, summarizeTopErrors(analysis)...)
+	}
+	if correlations := analysis.ErrorCorrelations; len(correlations) > 0 {
+		top := correlations[0]
+		sentences = append(sentences, fmt.Sprintf("%.0f%% of the errors have %s %s, which only %.0f%% of all entries have.",
+			top.ErrorShare*100, top.Field, top.Value, top.OverallShare*100))
+	}
+
+	if bursts := analysis.Incidents.ErrorBursts; len(bursts) > 0 {
+		largest := bursts[0]
+		for _, burst := range bursts[1:] {
+			if burst.Count > largest.Count {
+				largest = burst
+			}
+		}
+		if len(bursts) == 1 {
+			sentences = append(sentences, fmt.Sprintf("A burst of %d errors in %s started at %s.",
+				largest.Count, formatSpan(largest.Duration()), largest.Start.Format(summaryTimeFormat)))
+		} else {
+			sentences = append(sentences, fmt.Sprintf("Errors came in %d bursts; the largest, %d errors in %s, started at %s.",
+				len(bursts), largest.Count, formatSpan(largest.Duration()), largest.Start.Format(summaryTimeFormat)))
+		}
+	}
+
+	switch restarts := analysis.Restarts; len(restarts) {
+	case 0:
+	case 1:
+		sentences = append(sentences, fmt.Sprintf("The server started at %s.", restarts[0].Format(summaryTimeFormat)))
+	default:
+		sentences = append(sentences, fmt.Sprintf("The server started %d times, last at %s.",
+			len(restarts), restarts[len(restarts)-1].Format(summaryTimeFormat)))
+	}
+
+	if gaps := analysis.LoggingGaps; len(gaps) > 0 {
+		sentence := fmt.Sprintf("Nothing was logged for %s from %s", formatSpan(gaps[0].Duration), gaps[0].Start.Format(summaryTimeFormat))
+		if len(gaps) > 1 {
+			sentence += fmt.Sprintf(", the longest of %d unusual silences", len(gaps))
+		}
+		sentences = append(sentences, sentence+".")
+	}
+
+	var growing []string
+	for _, metric := range analysis.RuntimeMetrics {
+		if metric.LeakSuspected {
+			growing = append(growing, metric.Name)
+		}
+	}
+	if len(growing) > 0 {
+		sentences = append(sentences, fmt.Sprintf("Runtime metrics kept growing, which suggests a leak: %s.", joinAnd(growing)))
+	}
+
+	if exports := analysis.ComplianceExports; exports.Stopped {
+		sentences = append(sentences, fmt.Sprintf("Compliance exports stopped running: the last run started %s before the end of the logs, while they are expected to run every %s.",
+			formatSpan(exports.SinceLastRun), formatSpan(exports.ExpectedInterval)))
+	} else if len(exports.Runs) > 0 && exports.LastSuccess.IsZero() {
+		sentences = append(sentences, fmt.Sprintf("No compliance export completed successfully (%s logged).", countNoun(len(exports.Runs), "run", "runs")))
+	}
+
+	if retention := analysis.DataRetention; retention.ConsistentTimeouts {
+		sentence := fmt.Sprintf("Data retention runs keep timing out (%d of %d runs)", retention.TimedOut, len(retention.Runs))
+		if retention.DBWarnings > 0 {
+			sentence += fmt.Sprintf(", with %s around them", countNoun(retention.DBWarnings, "DB latency warning", "DB latency warnings"))
+		}
+		sentences = append(sentences, sentence+".")
+	}
+
+	var failingHosts []string
+	for _, host := range analysis.FetchErrors {
+		if host.Consistent {
+			failingHosts = append(failingHosts, host.Host)
+		}
+	}
+	if len(failingHosts) > 0 {
+		sentences = append(sentences, fmt.Sprintf("Image proxy or link preview fetches consistently fail for %s, which often comes from firewall rules.", joinAnd(failingHosts)))
+	}
+
+	if push := analysis.PushProxy; push.Failures > 0 {
+		sentence := fmt.Sprintf("Contacting the push proxy failed %s", countNoun(push.Failures, "time", "times"))
+		if len(push.ErrorCodes) > 0 {
+			sentence += fmt.Sprintf(", mostly with %s", push.ErrorCodes[0].Item)
+		}
+		if push.UndeliveredNearFail > 0 {
+			sentence += fmt.Sprintf(", and %s went undelivered around these failures",
+				countNoun(push.UndeliveredNearFail, "notification", "notifications"))
+		}
+		sentences = append(sentences, sentence+".")
+	}
+
+	for _, d := range analysis.NotificationLatency.Degraded {
+		usual := time.Duration(0)
+		for _, p := range analysis.NotificationLatency.Platforms {
+			if p.Platform == d.Platform {
+				usual = p.Median
+			}
+		}
+		sentences = append(sentences, fmt.Sprintf("%s notifications were slow to arrive from %s to %s, taking %s against %s usually.",
+			d.Platform, d.Start.Format(summaryTimeFormat), d.End.Format(summaryTimeFormat), formatLatency(d.Median), formatLatency(usual)))
+	}
+
+	if findings := analysis.RuleFindings; len(findings) > 0 {
+		var titles []string
+		for _, finding := range findings {
+			titles = append(titles, finding.Title)
+		}
+		sentences = append(sentences, fmt.Sprintf("Analysis rules found: %s.", joinAnd(titles)))
+	}
+
+	if last := analysis.Incidents.LastError; !last.IsZero() {
+		if since := analysis.Incidents.TimeSinceLastError; since > 0 {
+			sentences = append(sentences, fmt.Sprintf("The last error was logged %s before the end of the logs.", formatSpan(since)))
+		} else {
+			sentences = append(sentences, "Errors were still being logged at the end of the logs.")
+		}
+	}
+	return sentences
+}
+
+// summarizeTopErrors describes the most frequent errors, with when they occurred if known
+func summarizeTopErrors(analysis LogAnalysis) []string {
+	var sentences []string
+	signatures := analysis.ErrorSignatures
+	if len(signatures) > 0 {
+		top := signatures[0]
+		sentence := fmt.Sprintf("The most frequent error is %q (%s, from %s to %s)", truncateString(top.Example, 100),
+			countNoun(top.Count, "time", "times"), top.FirstSeen.Format(summaryTimeFormat), top.LastSeen.Format(summaryTimeFormat))
+		if top.Ongoing {
+			sentence += " and it is still occurring at the end of the logs"
+		}
+		sentences = append(sentences, sentence+".")
+
+		var others []string
+		for _, signature := range signatures[1:min(len(signatures), summaryTopErrors)] {
+			others = append(others, fmt.Sprintf("%q (%s)", truncateString(signature.Example, 100), countNoun(signature.Count, "time", "times")))
+		}
+		if len(others) > 0 {
+			sentences = append(sentences, fmt.Sprintf("It is followed by %s.", joinAnd(others)))
+		}
+		if len(signatures) > summaryTopErrors {
+			sentences = append(sentences, "Other kinds of errors were logged as well.")
+		}
+		return sentences
+	}
+
+	// Trimmed logs have no signatures, only counted messages
+	var messages []string
+	for _, item := range analysis.TopErrorMessages[:min(len(analysis.TopErrorMessages), summaryTopErrors)] {
+		messages = append(messages, fmt.Sprintf("%q (%s)", truncateString(item.Item, 100), countNoun(item.Count, "time", "times")))
+	}
+	if len(messages) > 0 {
+		sentences = append(sentences, fmt.Sprintf("The most frequent errors are %s.", joinAnd(messages)))
+	}
+	return sentences
+}
+
+// countNoun returns a count with its noun, e.g. "1 entry" or "12 entries"
+func countNoun(count int, singular, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+// joinAnd joins items as in a sentence, e.g. "a, b and c"
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// formatSpan returns a duration in words, in its two largest units, e.g. "3 days 4 hours",
+// "2 minutes" or "45 seconds"
+func formatSpan(d time.Duration) string {
+	units := []struct {
+		size             time.Duration
+		singular, plural string
+	}{
+		{24 * time.Hour, "day", "days"},
+		{time.Hour, "hour", "hours"},
+		{time.Minute, "minute", "minutes"},
+		{time.Second, "second", "seconds"},
+	}
+	var parts []string
+	for _, unit := range units {
+		if n := int(d / unit.size); n > 0 {
+			parts = append(parts, countNoun(n, unit.singular, unit.plural))
+			d -= time.Duration(n) * unit.size
+		} else if len(parts) > 0 {
+			break
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "less than a second"
+	}
+	return strings.Join(parts, " ")
+}
+
+// displaySummary writes the summary of the logs, one sentence per line
+func displaySummary(logs []LogEntry, output io.Writer) {
+	analysis := analyzeLogs(logs, !trim, analysisTopLimit())
+	_, _ = fmt.Fprintf(output, "%sSUMMARY%s\n", colorHeaderBold, colorReset)
+	for _, sentence := range summarizeAnalysis(analysis) {
+		_, _ = fmt.Fprintln(output, sentence)
+	}
+	for _, sentence := range summarizePacketDiagnostics(packetDiagnostics) {
+		_, _ = fmt.Fprintln(output, sentence)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeAnalysis(t *testing.T) {
+	analysis := LogAnalysis{
+		TotalEntries: 1000,
+		TimeRange:    TimeRange{Start: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), End: mustParseTime(t, "2024-03-04 14:30:00.000 Z")},
+		LevelCounts:  map[string]int{"INFO": 880, "WARN": 70, "ERROR": 49, "FATAL": 1},
+		ErrorRate:    5,
+		ErrorSignatures: []ErrorSignature{
+			{Example: "Failed to ping DB", Count: 30, FirstSeen: mustParseTime(t, "2024-03-01 10:05:00.000 Z"), LastSeen: mustParseTime(t, "2024-03-04 14:25:00.000 Z"), Ongoing: true},
+			{Example: "Unable to upload file", Count: 15},
+			{Example: "Websocket closed", Count: 4},
+			{Example: "Plugin crashed", Count: 1},
+		},
+		Incidents: IncidentMetrics{
+			ErrorBursts: []ErrorBurst{
+				{Start: mustParseTime(t, "2024-03-02 08:00:00.000 Z"), End: mustParseTime(t, "2024-03-02 08:02:00.000 Z"), Count: 12},
+				{Start: mustParseTime(t, "2024-03-03 09:00:00.000 Z"), End: mustParseTime(t, "2024-03-03 09:00:45.000 Z"), Count: 20},
+			},
+			LastError:          mustParseTime(t, "2024-03-04 14:25:00.000 Z"),
+			TimeSinceLastError: 5 * time.Minute,
+		},
+		Restarts:       []time.Time{mustParseTime(t, "2024-03-01 10:00:00.000 Z"), mustParseTime(t, "2024-03-03 09:01:00.000 Z")},
+		LoggingGaps:    []LogGap{{Start: mustParseTime(t, "2024-03-02 23:00:00.000 Z"), Duration: 6 * time.Hour}, {Duration: time.Hour}},
+		RuntimeMetrics: []RuntimeMetric{{Name: "goroutines", LeakSuspected: true}, {Name: "heap"}},
+		RuleFindings:   []RuleFinding{{Title: "License expiring"}},
+	}
+
+	assert.Equal(t, []string{
+		"The logs cover 3 days 4 hours, from 2024-03-01 10:00 to 2024-03-04 14:30, with 1000 entries; 5.0% of them are errors (50 errors, 70 warnings).",
+		`The most frequent error is "Failed to ping DB" (30 times, from 2024-03-01 10:05 to 2024-03-04 14:25) and it is still occurring at the end of the logs.`,
+		`It is followed by "Unable to upload file" (15 times) and "Websocket closed" (4 times).`,
+		"Other kinds of errors were logged as well.",
+		"Errors came in 2 bursts; the largest, 20 errors in 45 seconds, started at 2024-03-03 09:00.",
+		"The server started 2 times, last at 2024-03-03 09:01.",
+		"Nothing was logged for 6 hours from 2024-03-02 23:00, the longest of 2 unusual silences.",
+		"Runtime metrics kept growing, which suggests a leak: goroutines.",
+		"Analysis rules found: License expiring.",
+		"The last error was logged 5 minutes before the end of the logs.",
+	}, summarizeAnalysis(analysis))
+
+	quiet := LogAnalysis{
+		TotalEntries: 1,
+		TimeRange:    TimeRange{Start: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), End: mustParseTime(t, "2024-03-01 10:00:00.000 Z")},
+		LevelCounts:  map[string]int{"INFO": 1},
+	}
+	assert.Equal(t, []string{
+		"The logs cover less than a second, from 2024-03-01 10:00 to 2024-03-01 10:00, with 1 entry; 0.0% of them are errors (0 errors, 0 warnings).",
+		"No errors were logged.",
+	}, summarizeAnalysis(quiet))
+
+	assert.Equal(t, []string{"The logs contain no entries."}, summarizeAnalysis(LogAnalysis{}))
+}
+
+func TestFormatSpan(t *testing.T) {
+	assert.Equal(t, "3 days", formatSpan(72*time.Hour+10*time.Minute))
+	assert.Equal(t, "1 hour 30 minutes", formatSpan(90*time.Minute+20*time.Second))
+	assert.Equal(t, "45 seconds", formatSpan(45*time.Second))
+	assert.Equal(t, "less than a second", formatSpan(300*time.Millisecond))
+}
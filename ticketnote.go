@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ticketNoteTimeout bounds each request made to Zendesk or ServiceNow
+	ticketNoteTimeout = 30 * time.Second
+	// ticketFindingsFile is the name of the findings attached to the note
+	ticketFindingsFile = "lamp-findings.sarif.json"
+)
+
+// serviceNowTables are the tables of the ServiceNow records a ticket number can refer to,
+// by number prefix
+var serviceNowTables = map[string]string{
+	"INC":  "incident",
+	"CS":   "sn_customerservice_case",
+	"PRB":  "problem",
+	"RITM": "sc_req_item",
+}
+
+// ticketSettings are the ticket and credentials of --ticket-id
+type ticketSettings struct {
+	ID     string // Zendesk ticket ID, or ServiceNow number such as INC0012345 or CS0001234
+	System string // zendesk or servicenow
+	URL    string // e.g. https://example.zendesk.com or https://example.service-now.com
+	User   string // Zendesk agent email or ServiceNow user name
+	Token  string // Zendesk API token or ServiceNow password
+}
+
+// validate checks that the settings needed to add a note are set
+func (s ticketSettings) validate() error {
+	missing := []string{}
+	if s.System == "" {
+		missing = append(missing, "--ticket-system")
+	}
+	if s.URL == "" {
+		missing = append(missing, "--ticket-url")
+	}
+	if s.User == "" {
+		missing = append(missing, "--ticket-user")
+	}
+	if s.Token == "" {
+		missing = append(missing, "--ticket-token")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("--ticket-id requires %s", strings.Join(missing, ", "))
+	}
+	switch s.System {
+	case "zendesk":
+		if strings.Trim(s.ID, "0123456789") != "" {
+			return fmt.Errorf("invalid --ticket-id %q: Zendesk ticket IDs are numbers", s.ID)
+		}
+	case "servicenow":
+		if _, err := serviceNowTable(s.ID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid --ticket-system %q (expected zendesk or servicenow)", s.System)
+	}
+	return nil
+}
+
+// serviceNowTable returns the table of the record with a number
+func serviceNowTable(number string) (string, error) {
+	prefix := strings.TrimRight(strings.ToUpper(number), "0123456789")
+	if table, ok := serviceNowTables[prefix]; ok && prefix != strings.ToUpper(number) {
+		return table, nil
+	}
+	return "", fmt.Errorf("invalid --ticket-id %q: expected a ServiceNow number starting with INC, CS, PRB or RITM", number)
+}
+
+// ticketNote returns the text of the note: the analysis and the findings
+func ticketNote(report runReport) string {
+	var note strings.Builder
+	_, _ = fmt.Fprintf(&note, "%s\n\n", report.Title)
+	note.WriteString(strings.TrimSpace(report.Markdown))
+	note.WriteString("\n")
+	if findings := report.findingsMarkdown(); findings != "" {
+		note.WriteString("\nFindings\n\n")
+		note.WriteString(findings)
+	}
+	return note.String()
+}
+
+// ticketClient sends authenticated requests to Zendesk or ServiceNow
+type ticketClient struct {
+	client   *http.Client
+	settings ticketSettings
+}
+
+// do sends a request and decodes the JSON answer into result, if not nil. Zendesk
+// authenticates an agent email with an API token, ServiceNow a user with a password.
+func (c *ticketClient) do(method, endpoint, contentType string, body []byte, result any) error {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	user := c.settings.User
+	if c.settings.System == "zendesk" {
+		user += "/token"
+	}
+	req.SetBasicAuth(user, c.settings.Token)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered with %s%s", ticketSystemName(c.settings.System), resp.Status, ticketErrorDetail(answer))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(answer, result)
+}
+
+// ticketSystemName returns the name of a ticket system, for messages
+func ticketSystemName(system string) string {
+	if system == "zendesk" {
+		return "Zendesk"
+	}
+	return "ServiceNow"
+}
+
+// ticketErrorDetail returns the message of a Zendesk or ServiceNow error answer, if any
+func ticketErrorDetail(body []byte) string {
+	var answer struct {
+		Error       any    `json:"error"` // A string on Zendesk, an object on ServiceNow
+		Description string `json:"description"`
+	}
+	if json.Unmarshal(body, &answer) != nil {
+		return ""
+	}
+	switch e := answer.Error.(type) {
+	case string:
+		if answer.Description != "" {
+			return fmt.Sprintf(": %s: %s", e, answer.Description)
+		}
+		return ": " + e
+	case map[string]any:
+		if message, ok := e["message"].(string); ok {
+			return ": " + message
+		}
+	}
+	return ""
+}
+
+// addTicketNote appends the report as an internal note to an existing Zendesk ticket or
+// ServiceNow record, with the findings attached
+func addTicketNote(settings ticketSettings, report runReport, out io.Writer) error {
+	settings.URL = strings.TrimSuffix(settings.URL, "/")
+	client := &ticketClient{client: newHTTPClient(ticketNoteTimeout), settings: settings}
+
+	var findings bytes.Buffer
+	if err := writeFindings(report.Findings, &findings); err != nil {
+		return err
+	}
+
+	var err error
+	if settings.System == "zendesk" {
+		err = client.addZendeskNote(ticketNote(report), findings.Bytes())
+	} else {
+		err = client.addServiceNowNote(ticketNote(report), findings.Bytes())
+	}
+	if err != nil {
+		return fmt.Errorf("error adding the note to %s: %v", settings.ID, err)
+	}
+	_, _ = fmt.Fprintf(out, "Added the analysis as an internal note to %s ticket %s\n", ticketSystemName(settings.System), settings.ID)
+	return nil
+}
+
+// addZendeskNote uploads the findings and adds a private comment with them to the ticket
+func (c *ticketClient) addZendeskNote(note string, findings []byte) error {
+	var upload struct {
+		Upload struct {
+			Token string `json:"token"`
+		} `json:"upload"`
+	}
+	endpoint := fmt.Sprintf("%s/api/v2/uploads.json?filename=%s", c.settings.URL, url.QueryEscape(ticketFindingsFile))
+	if err := c.do("POST", endpoint, "application/json", findings, &upload); err != nil {
+		return fmt.Errorf("error uploading the findings: %v", err)
+	}
+
+	payload := map[string]any{
+		"ticket": map[string]any{
+			"comment": map[string]any{
+				"body":    note,
+				"public":  false,
+				"uploads": []string{upload.Upload.Token},
+			},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.do("PUT", fmt.Sprintf("%s/api/v2/tickets/%s.json", c.settings.URL, c.settings.ID), "application/json", data, nil)
+}
+
+// addServiceNowNote adds a work note to the record with the ticket number and attaches the
+// findings to it
+func (c *ticketClient) addServiceNowNote(note string, findings []byte) error {
+	table, err := serviceNowTable(c.settings.ID)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("sysparm_query", "number="+strings.ToUpper(c.settings.ID))
+	query.Set("sysparm_fields", "sys_id")
+	query.Set("sysparm_limit", "1")
+	var records struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err := c.do("GET", fmt.Sprintf("%s/api/now/table/%s?%s", c.settings.URL, table, query.Encode()), "", nil, &records); err != nil {
+		return err
+	}
+	if len(records.Result) == 0 {
+		return fmt.Errorf("no %s record with this number", table)
+	}
+	sysID := records.Result[0].SysID
+
+	data, err := json.Marshal(map[string]string{"work_notes": note})
+	if err != nil {
+		return err
+	}
+	if err := c.do("PATCH", fmt.Sprintf("%s/api/now/table/%s/%s", c.settings.URL, table, sysID), "application/json", data, nil); err != nil {
+		return err
+	}
+
+	attachment := url.Values{}
+	attachment.Set("table_name", table)
+	attachment.Set("table_sys_id", sysID)
+	attachment.Set("file_name", ticketFindingsFile)
+	if err := c.do("POST", c.settings.URL+"/api/now/attachment/file?"+attachment.Encode(), "application/json", findings, nil); err != nil {
+		return fmt.Errorf("added the note, but failed to attach the findings: %v", err)
+	}
+	return nil
+}
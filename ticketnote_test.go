@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ticketReport = runReport{
+	Title:    "Log analysis of mattermost.log",
+	Markdown: "## Summary\n\nDatabase timeouts\n",
+	Findings: []Finding{{RuleID: findingPanic, Severity: "error", Title: "Panic", Message: "panic: boom", Count: 1}},
+}
+
+func TestAddZendeskNote(t *testing.T) {
+	// The handler records the requests, checked once the calls return
+	var upload, uploadName string
+	var ticket map[string]map[string]map[string]any
+	var decodeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, ok := r.BasicAuth()
+		if !ok || user != "agent@example.com/token" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "Couldn't authenticate you"}`))
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v2/uploads.json":
+			uploadName = r.URL.Query().Get("filename")
+			data, _ := io.ReadAll(r.Body)
+			upload = string(data)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"upload": {"token": "upload-token"}}`))
+		case "PUT /api/v2/tickets/4242.json":
+			decodeErr = json.NewDecoder(r.Body).Decode(&ticket)
+			_, _ = w.Write([]byte(`{"ticket": {"id": 4242}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	settings := ticketSettings{ID: "4242", System: "zendesk", URL: server.URL + "/", User: "agent@example.com", Token: "secret"}
+	require.NoError(t, settings.validate())
+	var out bytes.Buffer
+	require.NoError(t, addTicketNote(settings, ticketReport, &out))
+	assert.Equal(t, "Added the analysis as an internal note to Zendesk ticket 4242\n", out.String())
+
+	require.NoError(t, decodeErr)
+	assert.Equal(t, ticketFindingsFile, uploadName)
+	assert.Contains(t, upload, `"ruleId": "lamp/panic"`)
+	comment := ticket["ticket"]["comment"]
+	assert.Equal(t, false, comment["public"])
+	assert.Equal(t, []any{"upload-token"}, comment["uploads"])
+	assert.Equal(t, "Log analysis of mattermost.log\n\n## Summary\n\nDatabase timeouts\n\nFindings\n\n- **Panic** (error, 1 entries, `lamp/panic`): panic: boom\n", comment["body"])
+
+	settings.Token = "wrong"
+	err := addTicketNote(settings, ticketReport, &out)
+	assert.EqualError(t, err, "error adding the note to 4242: error uploading the findings: Zendesk answered with 401 Unauthorized: Couldn't authenticate you")
+}
+
+func TestAddServiceNowNote(t *testing.T) {
+	// The handler records the requests, checked once the calls return
+	var workNotes map[string]string
+	var attachment string
+	var attachmentQuery url.Values
+	var decodeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "lamp" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/now/table/sn_customerservice_case":
+			if r.URL.Query().Get("sysparm_query") != "number=CS0001234" {
+				_, _ = w.Write([]byte(`{"result": []}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"result": [{"sys_id": "abc123"}]}`))
+		case "PATCH /api/now/table/sn_customerservice_case/abc123":
+			decodeErr = json.NewDecoder(r.Body).Decode(&workNotes)
+			_, _ = w.Write([]byte(`{"result": {}}`))
+		case "POST /api/now/attachment/file":
+			attachmentQuery = r.URL.Query()
+			data, _ := io.ReadAll(r.Body)
+			attachment = string(data)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"result": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"message": "No Record found"}, "status": "failure"}`))
+		}
+	}))
+	defer server.Close()
+
+	settings := ticketSettings{ID: "cs0001234", System: "servicenow", URL: server.URL, User: "lamp", Token: "secret"}
+	require.NoError(t, settings.validate())
+	var out bytes.Buffer
+	require.NoError(t, addTicketNote(settings, ticketReport, &out))
+	assert.Equal(t, "Added the analysis as an internal note to ServiceNow ticket cs0001234\n", out.String())
+	require.NoError(t, decodeErr)
+	assert.Equal(t, "sn_customerservice_case", attachmentQuery.Get("table_name"))
+	assert.Equal(t, "abc123", attachmentQuery.Get("table_sys_id"))
+	assert.Equal(t, ticketFindingsFile, attachmentQuery.Get("file_name"))
+	assert.Contains(t, workNotes["work_notes"], "Database timeouts")
+	assert.Contains(t, attachment, `"ruleId": "lamp/panic"`)
+
+	settings.ID = "CS0009999"
+	err := addTicketNote(settings, ticketReport, &out)
+	assert.EqualError(t, err, "error adding the note to CS0009999: no sn_customerservice_case record with this number")
+
+	settings.ID = "PRB0000001"
+	err = addTicketNote(settings, ticketReport, &out)
+	assert.EqualError(t, err, "error adding the note to PRB0000001: ServiceNow answered with 404 Not Found: No Record found")
+}
+
+func TestTicketSettingsValidate(t *testing.T) {
+	assert.EqualError(t, ticketSettings{ID: "42"}.validate(), "--ticket-id requires --ticket-system, --ticket-url, --ticket-user, --ticket-token")
+
+	settings := ticketSettings{ID: "42", System: "zendesk", URL: "https://example.zendesk.com", User: "agent@example.com", Token: "secret"}
+	assert.NoError(t, settings.validate())
+
+	settings.ID = "INC42"
+	assert.EqualError(t, settings.validate(), `invalid --ticket-id "INC42": Zendesk ticket IDs are numbers`)
+
+	settings.System = "servicenow"
+	assert.NoError(t, settings.validate())
+	settings.ID = "CHG0000042"
+	assert.EqualError(t, settings.validate(), `invalid --ticket-id "CHG0000042": expected a ServiceNow number starting with INC, CS, PRB or RITM`)
+	settings.ID = "INC"
+	assert.Error(t, settings.validate())
+
+	settings.System = "freshdesk"
+	assert.EqualError(t, settings.validate(), `invalid --ticket-system "freshdesk" (expected zendesk or servicenow)`)
+}
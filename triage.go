@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// triageMaxFindings is the number of findings highlighted by the triage wizard
+	triageMaxFindings = 5
+	// triageMaxSignatures is the number of error signatures listed by the triage wizard
+	triageMaxSignatures = 3
+	// triageMaxEntries is the number of the latest relevant errors and warnings shown
+	triageMaxEntries = 10
+	// triageTimeFormat is the format of the time the problem started, asked by the wizard
+	triageTimeFormat = "2006-01-02 15:04"
+)
+
+// triageOther is the answer of the problems not in problemTemplates, described by the user
+// and narrowed to the errors
+const triageOther = "other"
+
+// triageWizard walks through the standard support workflow for a support packet: the
+// summary, the top findings, the reported problem, the entries relevant to it and, if wanted,
+// the AI analysis of these entries with the context collected along the way
+type triageWizard struct {
+	*setupWizard
+	packetPath string
+	logs       []LogEntry
+	analysis   LogAnalysis
+	findings   []Finding // Most severe and frequent first
+}
+
+// newTriageWizard returns a wizard for the entries of a support packet, reading answers from
+// in and writing to out
+func newTriageWizard(packetPath string, logs []LogEntry, in io.Reader, out io.Writer) *triageWizard {
+	prompts := newSetupWizard(in, out)
+	prompts.purpose = "triage"
+	analysis := analyzeLogs(logs, true, analysisTopLimit())
+	findings := collectFindings(analysis, logs)
+	sort.SliceStable(findings, func(i, j int) bool {
+		rankI, rankJ := slices.Index(ruleSeverities, findings[i].Severity), slices.Index(ruleSeverities, findings[j].Severity)
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return findings[i].Count > findings[j].Count
+	})
+	return &triageWizard{setupWizard: prompts, packetPath: packetPath, logs: logs, analysis: analysis, findings: findings}
+}
+
+// step writes the heading of a step of the wizard
+func (w *triageWizard) step(n int, title string) {
+	_, _ = fmt.Fprintf(w.out, "\n%sStep %d of 4 · %s%s\n", colorHeaderBold, n, title, colorReset)
+}
+
+// run walks through the workflow. analyze runs the AI analysis of the relevant entries with
+// the problem and what to examine, if the user asks for it.
+func (w *triageWizard) run(analyze func(logs []LogEntry, problem string, focus []string) error) error {
+	_, _ = fmt.Fprintf(w.out, "%sTRIAGE OF %s%s\n", colorHeaderBold, w.packetPath, colorReset)
+	if len(w.logs) == 0 {
+		_, _ = fmt.Fprintln(w.out, "The support packet has no log entries to triage.")
+		return nil
+	}
+
+	w.step(1, "Summary")
+	for _, sentence := range summarizeAnalysis(w.analysis) {
+		_, _ = fmt.Fprintln(w.out, sentence)
+	}
+	for _, sentence := range summarizePacketDiagnostics(packetDiagnostics) {
+		_, _ = fmt.Fprintln(w.out, sentence)
+	}
+
+	w.step(2, "Top findings")
+	w.displayFindings()
+
+	w.step(3, "Reported problem")
+	options := make([]wizardOption, 0, len(problemTemplates)+1)
+	for _, template := range problemTemplates {
+		options = append(options, wizardOption{template.name, template.label})
+	}
+	options = append(options, wizardOption{triageOther, "Something else"})
+	choice, err := w.choose("What is the reported problem?", options, "", false)
+	if err != nil {
+		return err
+	}
+	description, err := w.ask("Describe it in the customer's words (optional)", "")
+	if err != nil {
+		return err
+	}
+	var since time.Time
+	for {
+		answer, err := w.ask("When did it start? ("+triageTimeFormat+", empty for the whole packet)", "")
+		if err != nil {
+			return err
+		}
+		if answer == "" {
+			break
+		}
+		if since, err = time.ParseInLocation(triageTimeFormat, answer, w.analysis.TimeRange.Start.Location()); err == nil {
+			break
+		}
+		_, _ = fmt.Fprintf(w.out, "Please enter a time such as %s\n", w.analysis.TimeRange.Start.Format(triageTimeFormat))
+	}
+
+	w.step(4, "Relevant entries")
+	relevant, command := w.filter(choice, since)
+	_, _ = fmt.Fprintf(w.out, "%d of %d entries are relevant to the problem. To see them all:\n  %s\n", len(relevant), len(w.logs), command)
+	if len(relevant) == 0 {
+		return nil
+	}
+	w.displayRelevant(relevant)
+
+	analyzeAnswer, err := w.ask("\nAnalyze the relevant entries with AI, with the findings and the problem as context? (y/n)", "n")
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(analyzeAnswer, "y") && !strings.EqualFold(analyzeAnswer, "yes") {
+		return nil
+	}
+	template, _ := findProblemTemplate(choice)
+	return analyze(relevant, w.problemStatement(choice, description, since), template.focus)
+}
+
+// displayFindings writes the most severe findings and the most frequent errors
+func (w *triageWizard) displayFindings() {
+	for _, finding := range w.findings[:min(len(w.findings), triageMaxFindings)] {
+		_, _ = fmt.Fprintf(w.out, "- [%s] %s: %s (%s, last at %s)\n", finding.Severity, finding.Title, truncateString(finding.Message, 100),
+			countNoun(finding.Count, "time", "times"), finding.LastSeen.Format("2006-01-02 15:04:05"))
+	}
+	signatures := w.analysis.ErrorSignatures
+	for _, signature := range signatures[:min(len(signatures), triageMaxSignatures)] {
+		ongoing := ""
+		if signature.Ongoing {
+			ongoing = ", ongoing"
+		}
+		_, _ = fmt.Fprintf(w.out, "- Frequent error: %s (%s%s)\n", truncateString(signature.Example, 100), countNoun(signature.Count, "time", "times"), ongoing)
+	}
+	if len(w.findings) == 0 && len(signatures) == 0 {
+		_, _ = fmt.Fprintln(w.out, "No findings or errors stand out.")
+	}
+}
+
+// filter returns the entries relevant to a problem since a time, and the command line
+// showing them
+func (w *triageWizard) filter(choice string, since time.Time) ([]LogEntry, string) {
+	command := "lamp support-packet " + shellQuote(w.packetPath)
+	var pattern *regexp.Regexp
+	if template, ok := findProblemTemplate(choice); ok {
+		pattern = regexp.MustCompile(template.pattern)
+		command += " --regex " + shellQuote(template.pattern)
+	} else {
+		command += " --level error"
+	}
+	if !since.IsZero() {
+		command += " --start " + shellQuote(since.Format("2006-01-02 15:04:05.000"))
+	}
+	command += " --raw"
+
+	var relevant []LogEntry
+	for _, log := range w.logs {
+		if !since.IsZero() && log.Timestamp.Before(since) {
+			continue
+		}
+		if pattern == nil && !isErrorLevel(log.Level) {
+			continue
+		}
+		if pattern != nil && !shouldIncludeEntry(log, "", pattern, "", "", time.Time{}, time.Time{}) {
+			continue
+		}
+		relevant = append(relevant, log)
+	}
+	return relevant, command
+}
+
+// displayRelevant writes the levels and the most frequent errors of the relevant entries,
+// and the latest errors and warnings among them
+func (w *triageWizard) displayRelevant(relevant []LogEntry) {
+	analysis := analyzeLogs(relevant, true, analysisTopLimit())
+	_, _ = fmt.Fprintf(w.out, "%sLevels:%s %s\n", colorSubHeader, colorReset, formatLevelDistribution(analysis.LevelCounts, analysis.TotalEntries, false))
+	for _, signature := range analysis.ErrorSignatures[:min(len(analysis.ErrorSignatures), triageMaxSignatures)] {
+		_, _ = fmt.Fprintf(w.out, "- Frequent error: %s (%s)\n", truncateString(signature.Example, 100), countNoun(signature.Count, "time", "times"))
+	}
+
+	var latest []LogEntry
+	for i := len(relevant) - 1; i >= 0 && len(latest) < triageMaxEntries; i-- {
+		if level := strings.ToLower(relevant[i].Level); isErrorLevel(level) || level == "warn" || level == "warning" {
+			latest = append(latest, relevant[i])
+		}
+	}
+	if len(latest) == 0 {
+		return
+	}
+	slices.Reverse(latest)
+	_, _ = fmt.Fprintf(w.out, "\n%sLatest errors and warnings:%s\n", colorSubHeader, colorReset)
+	for _, log := range latest {
+		displayLogPretty(log, w.out)
+	}
+}
+
+// problemStatement returns the problem told to the AI analysis: the reported problem, the
+// customer's description and the findings of the triage
+func (w *triageWizard) problemStatement(choice, description string, since time.Time) string {
+	var sb strings.Builder
+	if template, ok := findProblemTemplate(choice); ok {
+		sb.WriteString(template.expand(description))
+	} else if description != "" {
+		_, _ = fmt.Fprintf(&sb, "The customer describes it as: %q.", description)
+	}
+	if !since.IsZero() {
+		_, _ = fmt.Fprintf(&sb, " It started around %s.", since.Format(triageTimeFormat))
+	}
+	if len(w.findings) > 0 {
+		sb.WriteString("\n\nFindings of lamp in the whole support packet:")
+		for _, finding := range w.findings[:min(len(w.findings), triageMaxFindings)] {
+			_, _ = fmt.Fprintf(&sb, "\n- [%s] %s: %s (%s)", finding.Severity, finding.Title, finding.Message, countNoun(finding.Count, "time", "times"))
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// shellQuote quotes an argument of a command line for POSIX shells, if needed
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// runTriage parses a support packet and walks through the triage wizard on the terminal,
+// analyzing the relevant entries with the AI provider of the flags if asked to
+func runTriage(packetPath string) error {
+	if _, err := os.Stat(packetPath); os.IsNotExist(err) {
+		return fmt.Errorf("support packet '%s' does not exist", packetPath)
+	}
+	logs, err := parseSupportPacket(packetPath, packetLogSelection{}, "", "", "", "", "", "")
+	if err != nil {
+		return fmt.Errorf("error parsing support packet: %v", err)
+	}
+	if serverVersion, err = readSupportPacketServerVersion(packetPath); err != nil {
+		logger.Warn("Failed to read the server version from the support packet", "error", err)
+	}
+	if packetDiagnostics, err = readPacketDiagnostics(packetPath); err != nil {
+		logger.Warn("Failed to read the diagnostics of the support packet", "error", err)
+	}
+
+	wizard := newTriageWizard(packetPath, logs, os.Stdin, os.Stdout)
+	return wizard.run(func(relevant []LogEntry, problemStatement string, focus []string) error {
+		config, err := newLLMConfig(relevant, os.Stdout)
+		if err != nil {
+			return err
+		}
+		config.Problem, config.Focus = problemStatement, focus
+		if err := analyzeWithLLM(relevant, config); err != nil {
+			return fmt.Errorf("error during LLM analysis: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriageWizard(t *testing.T) {
+	initLogger()
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	logs := []LogEntry{
+		{Timestamp: start, Level: "info", Message: "Server started"},
+		{Timestamp: start.Add(time.Minute), Level: "error", Message: "SAML login failed"},
+		{Timestamp: start.Add(2 * time.Minute), Level: "error", Message: "Failed to ping DB"},
+		{Timestamp: start.Add(time.Hour), Level: "warn", Message: "Session expired for user"},
+		{Timestamp: start.Add(2 * time.Hour), Level: "info", Message: "User logged in"},
+	}
+
+	t.Run("login issues since a time", func(t *testing.T) {
+		var out bytes.Buffer
+		// Problem by number, no description, an invalid time then a valid one, analysis wanted
+		wizard := newTriageWizard("packet.zip", logs, strings.NewReader("1\nSSO broken\nyesterday\n2025-01-01 10:30\ny\n"), &out)
+
+		var analyzed []LogEntry
+		var problem string
+		var focus []string
+		require.NoError(t, wizard.run(func(relevant []LogEntry, statement string, areas []string) error {
+			analyzed, problem, focus = relevant, statement, areas
+			return nil
+		}))
+
+		output := out.String()
+		assert.Contains(t, output, "TRIAGE OF packet.zip")
+		assert.Contains(t, output, "Step 2 of 4 · Top findings")
+		assert.Contains(t, output, "- Frequent error:")
+		assert.Contains(t, output, "Please enter a time such as 2025-01-01 10:00")
+		assert.Contains(t, output, "1 of 5 entries are relevant to the problem. To see them all:\n"+
+			"  lamp support-packet packet.zip --regex '"+problemTemplates[0].pattern+"' --start '2025-01-01 10:30:00.000' --raw")
+		assert.Contains(t, output, "Session expired for user")
+
+		require.Len(t, analyzed, 1)
+		assert.Equal(t, "Session expired for user", analyzed[0].Message)
+		assert.Contains(t, problem, "cannot log in")
+		assert.Contains(t, problem, `The customer describes it as: "SSO broken".`)
+		assert.Contains(t, problem, "It started around 2025-01-01 10:30.")
+		assert.Equal(t, problemTemplates[0].focus, focus)
+	})
+
+	t.Run("other problem without analysis", func(t *testing.T) {
+		var out bytes.Buffer
+		wizard := newTriageWizard("my packet.zip", logs, strings.NewReader("other\n\n\n\n"), &out)
+
+		require.NoError(t, wizard.run(func([]LogEntry, string, []string) error {
+			t.Fatal("the analysis was not asked for")
+			return nil
+		}))
+		assert.Contains(t, out.String(), "2 of 5 entries are relevant to the problem. To see them all:\n"+
+			"  lamp support-packet 'my packet.zip' --level error --raw")
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		wizard := newTriageWizard("packet.zip", logs, strings.NewReader("2\n"), &bytes.Buffer{})
+		assert.EqualError(t, wizard.run(nil), "triage aborted: no more input")
+	})
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latestReleaseURL is the GitHub API endpoint of the latest lamp release
+const latestReleaseURL = "https://api.github.com/repos/svelle/lamp/releases/latest"
+
+// updateTimeout bounds each request made while updating
+const updateTimeout = 2 * time.Minute
+
+// githubRelease is the part of a GitHub release used to update lamp
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a file attached to a GitHub release
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset returns the asset of the release with the given name
+func (r githubRelease) asset(name string) (githubAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return githubAsset{}, false
+}
+
+// checksumsAsset returns the checksums file of the release, named <project>_<version>_checksums.txt
+func (r githubRelease) checksumsAsset() (githubAsset, bool) {
+	for _, asset := range r.Assets {
+		if strings.HasSuffix(asset.Name, "checksums.txt") {
+			return asset, true
+		}
+	}
+	return githubAsset{}, false
+}
+
+// updater replaces the running lamp binary with the latest release
+type updater struct {
+	client     *http.Client
+	releaseURL string
+	current    string // Version of the running binary
+	executable string // Path of the binary to replace
+	goos       string
+	goarch     string
+	out        io.Writer
+}
+
+// newUpdater returns an updater for the running binary, writing its progress to out
+func newUpdater(out io.Writer) (*updater, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error locating the lamp binary: %v", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return nil, fmt.Errorf("error locating the lamp binary: %v", err)
+	}
+	return &updater{
+		client:     newHTTPClient(updateTimeout),
+		releaseURL: latestReleaseURL,
+		current:    currentVersion(),
+		executable: executable,
+		goos:       runtime.GOOS,
+		goarch:     runtime.GOARCH,
+		out:        out,
+	}, nil
+}
+
+// run updates the binary if a newer release exists. With check set it only reports whether
+// one does; with force set it also replaces development builds and the same version.
+func (u *updater) run(check, force bool) error {
+	release, err := u.latestRelease()
+	if err != nil {
+		return err
+	}
+
+	development := u.current == "dev"
+	if !force && !development && compareVersions(release.TagName, u.current) <= 0 {
+		_, _ = fmt.Fprintf(u.out, "lamp %s is the latest version\n", u.current)
+		return nil
+	}
+	if check {
+		_, _ = fmt.Fprintf(u.out, "lamp %s is available (current version: %s), run 'lamp update' to install it\n", release.TagName, u.current)
+		return nil
+	}
+	if development && !force {
+		return fmt.Errorf("this is a development build, use --force to replace it with lamp %s", release.TagName)
+	}
+
+	archiveName := releaseArchiveName(u.goos, u.goarch)
+	archiveAsset, ok := release.asset(archiveName)
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s/%s (%s)", release.TagName, u.goos, u.goarch, archiveName)
+	}
+	checksumsAsset, ok := release.checksumsAsset()
+	if !ok {
+		return fmt.Errorf("release %s has no checksums file, not updating", release.TagName)
+	}
+
+	_, _ = fmt.Fprintf(u.out, "Downloading lamp %s (%s)...\n", release.TagName, archiveName)
+	archive, err := u.download(archiveAsset.URL)
+	if err != nil {
+		return err
+	}
+	checksums, err := u.download(checksumsAsset.URL)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(archive, archiveName, parseChecksums(checksums)); err != nil {
+		return err
+	}
+
+	binaryName := "lamp"
+	if u.goos == "windows" {
+		binaryName = "lamp.exe"
+	}
+	binary, err := extractBinary(archive, archiveName, binaryName)
+	if err != nil {
+		return fmt.Errorf("error extracting %s: %v", archiveName, err)
+	}
+	if err := replaceExecutable(u.executable, binary, u.goos); err != nil {
+		return fmt.Errorf("error replacing %s: %v", u.executable, err)
+	}
+
+	_, _ = fmt.Fprintf(u.out, "Updated %s from %s to %s\n", u.executable, u.current, release.TagName)
+	return nil
+}
+
+// latestRelease fetches the latest release from GitHub
+func (u *updater) latestRelease() (githubRelease, error) {
+	body, err := u.download(u.releaseURL)
+	if err != nil {
+		return githubRelease{}, fmt.Errorf("error checking for updates: %v", err)
+	}
+	var release githubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return githubRelease{}, fmt.Errorf("error parsing the latest release: %v", err)
+	}
+	if release.TagName == "" {
+		return githubRelease{}, fmt.Errorf("error parsing the latest release: no tag name")
+	}
+	return release, nil
+}
+
+// download fetches a URL
+func (u *updater) download(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lamp/"+u.current)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %v", url, err)
+	}
+	return body, nil
+}
+
+// releaseArchiveName returns the name of the release archive for a platform, as built by
+// .goreleaser.yaml (e.g. lamp_Darwin_arm64.tar.gz or lamp_Windows_x86_64.zip)
+func releaseArchiveName(goos, goarch string) string {
+	arch := goarch
+	if goarch == "amd64" {
+		arch = "x86_64"
+	}
+	extension := ".tar.gz"
+	if goos == "windows" {
+		extension = ".zip"
+	}
+	return fmt.Sprintf("lamp_%s_%s%s", strings.ToUpper(goos[:1])+goos[1:], arch, extension)
+}
+
+// compareVersions compares two versions such as v1.2.3, returning -1, 0 or 1. Pre-release
+// and build suffixes are ignored.
+func compareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numbers of a version, e.g. [1 2 3] for v1.2.3-rc1
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// parseChecksums parses a checksums file with "<sha256>  <file name>" lines
+func parseChecksums(data []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return checksums
+}
+
+// verifyChecksum checks the SHA-256 checksum of a downloaded file against the checksums file
+func verifyChecksum(data []byte, name string, checksums map[string]string) error {
+	expected, ok := checksums[name]
+	if !ok {
+		return fmt.Errorf("the checksums file has no checksum for %s, not updating", name)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s (expected %s, got %s), not updating", name, expected, actual)
+	}
+	return nil
+}
+
+// extractBinary returns the file named binaryName from a .tar.gz or .zip release archive
+func extractBinary(archive []byte, archiveName, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range reader.File {
+			if filepath.Base(file.Name) != binaryName {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = rc.Close() }()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("%s not found in the archive", binaryName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in the archive", binaryName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			return io.ReadAll(reader)
+		}
+	}
+}
+
+// replaceExecutable replaces the binary at path with a new one. The new binary is written
+// next to it first so that a failed write leaves the old one intact. Windows does not allow
+// replacing a running binary, so the old one is moved aside to path.old.
+func replaceExecutable(path string, binary []byte, goos string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lamp-update-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+
+	if goos != "windows" {
+		return os.Rename(tmp.Name(), path)
+	}
+	old := path + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(path, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Rename(old, path)
+		return err
+	}
+	return nil
+}
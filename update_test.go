@@ -0,0 +1,160 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("v1.2.3", "1.2.3"))
+	assert.Equal(t, 1, compareVersions("v1.10.0", "1.9.9"))
+	assert.Equal(t, -1, compareVersions("v1.2", "1.2.1"))
+	assert.Equal(t, 0, compareVersions("v2.0.0-rc1", "2.0.0"))
+}
+
+func TestReleaseArchiveName(t *testing.T) {
+	assert.Equal(t, "lamp_Linux_x86_64.tar.gz", releaseArchiveName("linux", "amd64"))
+	assert.Equal(t, "lamp_Darwin_arm64.tar.gz", releaseArchiveName("darwin", "arm64"))
+	assert.Equal(t, "lamp_Windows_x86_64.zip", releaseArchiveName("windows", "amd64"))
+}
+
+// tarGzArchive returns a release archive holding a file
+func tarGzArchive(t *testing.T, name string, content []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("hi"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestExtractBinary(t *testing.T) {
+	binary, err := extractBinary(tarGzArchive(t, "lamp", []byte("new lamp")), "lamp_Linux_x86_64.tar.gz", "lamp")
+	require.NoError(t, err)
+	assert.Equal(t, "new lamp", string(binary))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("lamp.exe")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("new lamp.exe"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	binary, err = extractBinary(buf.Bytes(), "lamp_Windows_x86_64.zip", "lamp.exe")
+	require.NoError(t, err)
+	assert.Equal(t, "new lamp.exe", string(binary))
+
+	_, err = extractBinary(tarGzArchive(t, "other", nil), "lamp_Linux_x86_64.tar.gz", "lamp")
+	assert.ErrorContains(t, err, "lamp not found")
+}
+
+func TestUpdater(t *testing.T) {
+	archive := tarGzArchive(t, "lamp", []byte("lamp 1.3.0"))
+	sum := sha256.Sum256(archive)
+	checksums := fmt.Sprintf("%s  lamp_Linux_x86_64.tar.gz\n", hex.EncodeToString(sum[:]))
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(githubRelease{TagName: "v1.3.0", Assets: []githubAsset{
+			{Name: "lamp_Linux_x86_64.tar.gz", URL: server.URL + "/archive"},
+			{Name: "lamp_1.3.0_checksums.txt", URL: server.URL + "/checksums"},
+		}})
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte(checksums)) })
+
+	newTestUpdater := func(t *testing.T, current string) (*updater, *bytes.Buffer) {
+		executable := filepath.Join(t.TempDir(), "lamp")
+		require.NoError(t, os.WriteFile(executable, []byte("lamp "+current), 0o755))
+		var out bytes.Buffer
+		return &updater{
+			client:     server.Client(),
+			releaseURL: server.URL + "/latest",
+			current:    current,
+			executable: executable,
+			goos:       "linux",
+			goarch:     "amd64",
+			out:        &out,
+		}, &out
+	}
+	binary := func(t *testing.T, u *updater) string {
+		content, err := os.ReadFile(u.executable)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("replaces an older version", func(t *testing.T) {
+		u, out := newTestUpdater(t, "1.2.0")
+		require.NoError(t, u.run(false, false))
+		assert.Equal(t, "lamp 1.3.0", binary(t, u))
+		assert.Contains(t, out.String(), "from 1.2.0 to v1.3.0")
+
+		info, err := os.Stat(u.executable)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+		entries, err := os.ReadDir(filepath.Dir(u.executable))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "no temporary file is left behind")
+	})
+
+	t.Run("keeps the latest version", func(t *testing.T) {
+		u, out := newTestUpdater(t, "1.3.0")
+		require.NoError(t, u.run(false, false))
+		assert.Equal(t, "lamp 1.3.0", binary(t, u))
+		assert.Contains(t, out.String(), "1.3.0 is the latest version")
+	})
+
+	t.Run("only checks", func(t *testing.T) {
+		u, out := newTestUpdater(t, "1.2.0")
+		require.NoError(t, u.run(true, false))
+		assert.Equal(t, "lamp 1.2.0", binary(t, u))
+		assert.Contains(t, out.String(), "lamp v1.3.0 is available")
+	})
+
+	t.Run("requires force for development builds", func(t *testing.T) {
+		u, _ := newTestUpdater(t, "dev")
+		assert.ErrorContains(t, u.run(false, false), "development build")
+		assert.Equal(t, "lamp dev", binary(t, u))
+
+		require.NoError(t, u.run(false, true))
+		assert.Equal(t, "lamp 1.3.0", binary(t, u))
+	})
+
+	t.Run("rejects a checksum mismatch", func(t *testing.T) {
+		good := checksums
+		checksums = fmt.Sprintf("%064d  lamp_Linux_x86_64.tar.gz\n", 0)
+		defer func() { checksums = good }()
+
+		u, _ := newTestUpdater(t, "1.2.0")
+		assert.ErrorContains(t, u.run(false, false), "checksum mismatch")
+		assert.Equal(t, "lamp 1.2.0", binary(t, u))
+	})
+
+	t.Run("reports a missing build", func(t *testing.T) {
+		u, _ := newTestUpdater(t, "1.2.0")
+		u.goos = "freebsd"
+		assert.ErrorContains(t, u.run(false, false), "no build for freebsd/amd64")
+	})
+}
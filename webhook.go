@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds the request made to the webhook
+	webhookTimeout = 30 * time.Second
+	// webhookEvent is the event of the payloads sent when processing completes
+	webhookEvent = "analysis.completed"
+	// webhookSignatureHeader carries the HMAC-SHA256 of the payload with --webhook-secret
+	webhookSignatureHeader = "X-Lamp-Signature"
+	// webhookTopErrors is the number of most common error messages in the summary
+	webhookTopErrors = 10
+)
+
+// webhookPayload is the JSON posted to --webhook-url. Its fields are a stable interface for
+// automations: fields may be added, but not renamed or removed.
+type webhookPayload struct {
+	Event       string           `json:"event"`
+	Version     string           `json:"lamp_version"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Title       string           `json:"title"`
+	Sources     []string         `json:"sources"`
+	Summary     webhookSummary   `json:"summary"`
+	Analysis    string           `json:"analysis"` // The AI analysis, or the statistical analysis in a code block
+	Findings    []webhookFinding `json:"findings"`
+}
+
+// webhookSummary are the key statistics of the analyzed logs
+type webhookSummary struct {
+	TotalEntries int                `json:"total_entries"`
+	Start        *time.Time         `json:"start,omitempty"`
+	End          *time.Time         `json:"end,omitempty"`
+	Levels       map[string]int     `json:"levels"`
+	ErrorRate    float64            `json:"error_rate"` // Percentage of error entries
+	ErrorBursts  int                `json:"error_bursts"`
+	Restarts     int                `json:"restarts"`
+	TopErrors    []webhookCountItem `json:"top_errors"`
+}
+
+// webhookCountItem is a counted value of the summary
+type webhookCountItem struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// webhookFinding is a detected issue, as in the SARIF export
+type webhookFinding struct {
+	RuleID      string            `json:"rule_id"`
+	Severity    string            `json:"severity"`
+	Title       string            `json:"title"`
+	Message     string            `json:"message"`
+	Fingerprint string            `json:"fingerprint"` // Same as lampFinding/v1 in the SARIF export
+	Count       int               `json:"count"`
+	FirstSeen   time.Time         `json:"first_seen"`
+	LastSeen    time.Time         `json:"last_seen"`
+	Evidence    []webhookEvidence `json:"evidence"`
+}
+
+// webhookEvidence is a log entry a finding is based on
+type webhookEvidence struct {
+	File      string    `json:"file,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+}
+
+// newWebhookPayload returns the payload of a report, generated at a time
+func newWebhookPayload(report runReport, generatedAt time.Time) webhookPayload {
+	analysis := report.Analysis
+	summary := webhookSummary{
+		TotalEntries: analysis.TotalEntries,
+		Levels:       analysis.LevelCounts,
+		ErrorRate:    analysis.ErrorRate,
+		ErrorBursts:  len(analysis.Incidents.ErrorBursts),
+		Restarts:     len(analysis.Restarts),
+		TopErrors:    []webhookCountItem{},
+	}
+	if summary.Levels == nil {
+		summary.Levels = map[string]int{}
+	}
+	if !analysis.TimeRange.Start.IsZero() {
+		start, end := analysis.TimeRange.Start.UTC(), analysis.TimeRange.End.UTC()
+		summary.Start, summary.End = &start, &end
+	}
+	for i, item := range analysis.TopErrorMessages {
+		if i == webhookTopErrors {
+			break
+		}
+		summary.TopErrors = append(summary.TopErrors, webhookCountItem{Message: item.Item, Count: item.Count})
+	}
+
+	findings := []webhookFinding{}
+	for _, finding := range report.Findings {
+		evidence := []webhookEvidence{}
+		for _, entry := range finding.Evidence {
+			evidence = append(evidence, webhookEvidence{
+				File:      entry.SourceFile,
+				Line:      entry.Line,
+				Timestamp: entry.Timestamp.UTC(),
+				Level:     entry.Level,
+				Message:   entry.Message,
+			})
+		}
+		findings = append(findings, webhookFinding{
+			RuleID:      finding.RuleID,
+			Severity:    finding.Severity,
+			Title:       finding.Title,
+			Message:     finding.Message,
+			Fingerprint: finding.fingerprint(),
+			Count:       finding.Count,
+			FirstSeen:   finding.FirstSeen.UTC(),
+			LastSeen:    finding.LastSeen.UTC(),
+			Evidence:    evidence,
+		})
+	}
+
+	sources := report.Sources
+	if sources == nil {
+		sources = []string{}
+	}
+	return webhookPayload{
+		Event:       webhookEvent,
+		Version:     currentVersion(),
+		GeneratedAt: generatedAt.UTC(),
+		Title:       report.Title,
+		Sources:     sources,
+		Summary:     summary,
+		Analysis:    report.Markdown,
+		Findings:    findings,
+	}
+}
+
+// validateWebhookURL checks that the webhook is an http or https URL
+func validateWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --webhook-url %q: expected an http or https URL", webhookURL)
+	}
+	return nil
+}
+
+// webhookSignature returns the value of the signature header of a payload
+func webhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook posts the payload of a report to a webhook, signed if a secret is set
+func postWebhook(webhookURL, secret string, report runReport, out io.Writer) error {
+	data, err := json.Marshal(newWebhookPayload(report, time.Now()))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lamp/"+currentVersion())
+	if secret != "" {
+		req.Header.Set(webhookSignatureHeader, webhookSignature(secret, data))
+	}
+
+	resp, err := newHTTPClient(webhookTimeout).Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting to the webhook: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error posting to the webhook: it answered with %s", resp.Status)
+	}
+
+	_, _ = fmt.Fprintf(out, "Posted the analysis to the webhook (%d findings)\n", len(report.Findings))
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhookPayload(t *testing.T) {
+	panicEntry := LogEntry{
+		Timestamp:  mustParseTime(t, "2024-03-01 10:00:05.000 Z"),
+		Level:      "error",
+		Message:    "panic: boom",
+		SourceFile: "mattermost.log",
+		Line:       12,
+	}
+	report := runReport{
+		Title:    "Log analysis of mattermost.log",
+		Markdown: "## Summary\n",
+		Sources:  []string{"/var/log/mattermost.log"},
+		Analysis: LogAnalysis{
+			TotalEntries:     20,
+			TimeRange:        TimeRange{Start: mustParseTime(t, "2024-03-01 10:00:00.000 Z"), End: mustParseTime(t, "2024-03-01 11:00:00.000 Z")},
+			LevelCounts:      map[string]int{"info": 15, "error": 5},
+			ErrorRate:        25,
+			TopErrorMessages: []CountedItem{{Item: "panic: boom", Count: 1}},
+			Restarts:         []time.Time{mustParseTime(t, "2024-03-01 10:30:00.000 Z")},
+		},
+		Findings: []Finding{{
+			RuleID: findingPanic, Severity: "error", Title: "Panic", Message: "panic: boom", Key: "panic: boom", Count: 1,
+			FirstSeen: panicEntry.Timestamp, LastSeen: panicEntry.Timestamp, Evidence: []LogEntry{panicEntry},
+		}},
+	}
+
+	payload := newWebhookPayload(report, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, "analysis.completed", decoded["event"])
+	assert.Equal(t, "2024-03-02T00:00:00Z", decoded["generated_at"])
+	assert.Equal(t, []any{"/var/log/mattermost.log"}, decoded["sources"])
+	assert.Equal(t, "## Summary\n", decoded["analysis"])
+	assert.Equal(t, map[string]any{
+		"total_entries": 20.0,
+		"start":         "2024-03-01T10:00:00Z",
+		"end":           "2024-03-01T11:00:00Z",
+		"levels":        map[string]any{"info": 15.0, "error": 5.0},
+		"error_rate":    25.0,
+		"error_bursts":  0.0,
+		"restarts":      1.0,
+		"top_errors":    []any{map[string]any{"message": "panic: boom", "count": 1.0}},
+	}, decoded["summary"])
+
+	findings := decoded["findings"].([]any)
+	require.Len(t, findings, 1)
+	finding := findings[0].(map[string]any)
+	assert.Equal(t, "lamp/panic", finding["rule_id"])
+	assert.Equal(t, report.Findings[0].fingerprint(), finding["fingerprint"])
+	assert.Equal(t, []any{map[string]any{
+		"file": "mattermost.log", "line": 12.0, "timestamp": "2024-03-01T10:00:05Z", "level": "error", "message": "panic: boom",
+	}}, finding["evidence"])
+
+	// Empty lists stay lists for consumers that iterate them
+	data, err = json.Marshal(newWebhookPayload(runReport{}, time.Now()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"sources":[]`)
+	assert.Contains(t, string(data), `"findings":[]`)
+	assert.Contains(t, string(data), `"levels":{}`)
+	assert.NotContains(t, string(data), `"start"`)
+}
+
+func TestPostWebhook(t *testing.T) {
+	var body []byte
+	var signature string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(webhookSignatureHeader)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	report := runReport{Title: "Log analysis of mattermost.log", Findings: []Finding{{RuleID: findingPanic, Title: "Panic"}}}
+	var out bytes.Buffer
+	require.NoError(t, postWebhook(server.URL+"/hooks/lamp", "s3cret", report, &out))
+	assert.Equal(t, "Posted the analysis to the webhook (1 findings)\n", out.String())
+	assert.Equal(t, webhookSignature("s3cret", body), signature)
+	assert.Contains(t, string(body), `"title":"Log analysis of mattermost.log"`)
+
+	require.NoError(t, postWebhook(server.URL, "", report, &out))
+	assert.Empty(t, signature, "payloads are only signed with a secret")
+
+	status = http.StatusBadGateway
+	assert.EqualError(t, postWebhook(server.URL, "", report, &out), "error posting to the webhook: it answered with 502 Bad Gateway")
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	assert.NoError(t, validateWebhookURL("https://automation.example.com/hooks/lamp"))
+	assert.EqualError(t, validateWebhookURL("automation.example.com/hooks"), `invalid --webhook-url "automation.example.com/hooks": expected an http or https URL`)
+	assert.Error(t, validateWebhookURL("ftp://automation.example.com"))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ticketsDirName is the directory of the ticket workspaces, in the config directory
+	ticketsDirName = "tickets"
+	// workspaceContextRuns is the number of the latest runs of a ticket given to the AI analysis
+	workspaceContextRuns = 5
+	// workspaceMaxFindings is the number of findings kept per run, most severe first
+	workspaceMaxFindings = 10
+)
+
+// ticketNamePattern matches the ticket names of --ticket, which name the workspace files
+var ticketNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// TicketWorkspace accumulates the runs of lamp and the notes of a support ticket, across the
+// support packets and logs of the ticket
+type TicketWorkspace struct {
+	Ticket string       `json:"ticket"`
+	Runs   []TicketRun  `json:"runs,omitempty"`
+	Notes  []TicketNote `json:"notes,omitempty"`
+}
+
+// TicketRun is a run of lamp for a ticket, with the findings of its logs
+type TicketRun struct {
+	RunAt      time.Time       `json:"run_at"`
+	Command    string          `json:"command"`
+	Paths      []string        `json:"paths,omitempty"`
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	Entries    int             `json:"entries"`
+	Errors     int             `json:"errors"`
+	Problem    string          `json:"problem,omitempty"`
+	Findings   []TicketFinding `json:"findings,omitempty"`    // Findings of the analysis rules, bursts and panics
+	AIFindings []AIFinding     `json:"ai_findings,omitempty"` // Findings of the AI analysis, if any
+}
+
+// TicketFinding is a finding of a run, without its evidence
+type TicketFinding struct {
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Key      string `json:"key,omitempty"`
+	Count    int    `json:"count"`
+}
+
+// TicketNote is a note added to a ticket with 'lamp ticket note'
+type TicketNote struct {
+	AddedAt time.Time `json:"added_at"`
+	Text    string    `json:"text"`
+}
+
+// validateTicketName checks that a ticket name can name a workspace file
+func validateTicketName(name string) error {
+	if !ticketNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid --ticket %q: expected letters, digits, dots, dashes and underscores, such as MM-1234", name)
+	}
+	return nil
+}
+
+// ticketWorkspacePath returns the path of the workspace of a ticket
+func ticketWorkspacePath(name string) (string, error) {
+	if err := validateTicketName(name); err != nil {
+		return "", err
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ticketsDirName, name+".json"), nil
+}
+
+// loadTicketWorkspace reads the workspace of a ticket. A ticket without a workspace yet has an
+// empty one.
+func loadTicketWorkspace(name string) (TicketWorkspace, string, error) {
+	path, err := ticketWorkspacePath(name)
+	if err != nil {
+		return TicketWorkspace{}, "", err
+	}
+	workspace := TicketWorkspace{Ticket: name}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return workspace, path, nil
+	}
+	if err != nil {
+		return TicketWorkspace{}, "", err
+	}
+	if err := json.Unmarshal(data, &workspace); err != nil {
+		return TicketWorkspace{}, "", fmt.Errorf("invalid ticket workspace %s: %v", path, err)
+	}
+	return workspace, path, nil
+}
+
+// saveTicketWorkspace writes the workspace of a ticket
+func saveTicketWorkspace(workspace TicketWorkspace, path string) error {
+	data, err := json.MarshalIndent(workspace, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newTicketRun returns the run of a command over logs, with the findings of the analysis,
+// most severe first, and those of the AI analysis
+func newTicketRun(command string, paths []string, logs []LogEntry, problem string, aiFindings []AIFinding) TicketRun {
+	analysis := analyzeLogs(logs, true, analysisTopLimit())
+	run := TicketRun{
+		RunAt:      time.Now(),
+		Command:    command,
+		Paths:      paths,
+		Start:      analysis.TimeRange.Start,
+		End:        analysis.TimeRange.End,
+		Entries:    analysis.TotalEntries,
+		Problem:    problem,
+		AIFindings: aiFindings,
+	}
+	for level, count := range analysis.LevelCounts {
+		if isErrorLevel(level) {
+			run.Errors += count
+		}
+	}
+	findings := collectFindings(analysis, logs)
+	for _, severity := range ruleSeverities {
+		for _, finding := range findings {
+			if finding.Severity == severity && len(run.Findings) < workspaceMaxFindings {
+				run.Findings = append(run.Findings, TicketFinding{Severity: finding.Severity, Title: finding.Title,
+					Message: finding.Message, Key: finding.Key, Count: finding.Count})
+			}
+		}
+	}
+	return run
+}
+
+// recordTicketRun adds a run to the workspace of a ticket
+func recordTicketRun(name string, run TicketRun) (TicketWorkspace, error) {
+	workspace, path, err := loadTicketWorkspace(name)
+	if err != nil {
+		return TicketWorkspace{}, err
+	}
+	workspace.Runs = append(workspace.Runs, run)
+	return workspace, saveTicketWorkspace(workspace, path)
+}
+
+// addWorkspaceNote adds a note to the workspace of a ticket
+func addWorkspaceNote(name, text string) (TicketWorkspace, error) {
+	workspace, path, err := loadTicketWorkspace(name)
+	if err != nil {
+		return TicketWorkspace{}, err
+	}
+	workspace.Notes = append(workspace.Notes, TicketNote{AddedAt: time.Now(), Text: text})
+	return workspace, saveTicketWorkspace(workspace, path)
+}
+
+// describe returns the command, inputs, time range and size of a run on one line
+func (r TicketRun) describe() string {
+	names := make([]string, len(r.Paths))
+	for i, path := range r.Paths {
+		names[i] = filepath.Base(path)
+	}
+	return fmt.Sprintf("%s %s of %s: %s, %s, from %s to %s", r.Command, strings.Join(names, ", "),
+		r.RunAt.Format("2006-01-02 15:04"), countNoun(r.Entries, "entry", "entries"), countNoun(r.Errors, "error", "errors"),
+		r.Start.Format("2006-01-02 15:04"), r.End.Format("2006-01-02 15:04"))
+}
+
+// writeFindings writes the problem and the findings of a run, one per line
+func (r TicketRun) writeFindings(w io.Writer) {
+	if r.Problem != "" {
+		_, _ = fmt.Fprintf(w, "  Problem: %s\n", r.Problem)
+	}
+	for _, finding := range r.Findings {
+		_, _ = fmt.Fprintf(w, "  - [%s] %s: %s (%s)\n", finding.Severity, finding.Title, finding.Message, countNoun(finding.Count, "time", "times"))
+	}
+	for _, finding := range r.AIFindings {
+		_, _ = fmt.Fprintf(w, "  - AI [%s] %s: %s\n", finding.Severity, finding.Title, finding.Summary)
+	}
+	if len(r.Findings) == 0 && len(r.AIFindings) == 0 {
+		_, _ = fmt.Fprintln(w, "  No findings")
+	}
+}
+
+// priorContext returns the latest runs and the notes of the workspace for the AI analysis of
+// new logs, asking whether the earlier findings persist. It is empty for a new ticket.
+func (ws TicketWorkspace) priorContext() string {
+	if len(ws.Runs) == 0 && len(ws.Notes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "Earlier analyses of ticket %s, oldest first:\n", ws.Ticket)
+	for _, run := range ws.Runs[max(0, len(ws.Runs)-workspaceContextRuns):] {
+		_, _ = fmt.Fprintf(&sb, "\n%s\n", run.describe())
+		run.writeFindings(&sb)
+	}
+	if len(ws.Notes) > 0 {
+		sb.WriteString("\nNotes of the support engineer:\n")
+		for _, note := range ws.Notes {
+			_, _ = fmt.Fprintf(&sb, "- %s: %s\n", note.AddedAt.Format("2006-01-02 15:04"), note.Text)
+		}
+	}
+	sb.WriteString("\nFor each earlier finding, say whether these logs show that it persists, that it is resolved, or cannot tell, and point out the new issues.")
+	return sb.String()
+}
+
+// displayTicketWorkspace writes the runs and the notes of a workspace
+func displayTicketWorkspace(ws TicketWorkspace, w io.Writer) {
+	_, _ = fmt.Fprintf(w, "%sTICKET %s%s (%s, %s)\n", colorHeaderBold, ws.Ticket, colorReset,
+		countNoun(len(ws.Runs), "run", "runs"), countNoun(len(ws.Notes), "note", "notes"))
+	for i, run := range ws.Runs {
+		_, _ = fmt.Fprintf(w, "\n%d) %s\n", i+1, run.describe())
+		run.writeFindings(w)
+	}
+	if len(ws.Notes) > 0 {
+		_, _ = fmt.Fprintf(w, "\n%sNotes:%s\n", colorSubHeader, colorReset)
+		for _, note := range ws.Notes {
+			_, _ = fmt.Fprintf(w, "- %s: %s\n", note.AddedAt.Format("2006-01-02 15:04"), note.Text)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketWorkspace(t *testing.T) {
+	useTempConfigDir(t)
+	initLogger()
+	start := mustParseTime(t, "2025-01-01 10:00:00.000 Z")
+	var logs []LogEntry
+	for i := range 6 {
+		logs = append(logs, LogEntry{Timestamp: start.Add(time.Duration(i) * time.Second), Level: "error", Message: "panic: runtime error: invalid memory address"})
+	}
+	logs = append(logs, LogEntry{Timestamp: start.Add(time.Minute), Level: "info", Message: "Server started"})
+
+	// A new ticket has no context for the AI analysis
+	workspace, _, err := loadTicketWorkspace("MM-1234")
+	require.NoError(t, err)
+	assert.Empty(t, workspace.priorContext())
+
+	run := newTicketRun("support-packet", []string{"/tmp/packet-1.zip"}, logs, "Users cannot log in",
+		[]AIFinding{{Title: "Nil pointer in the SAML handler", Severity: "error", Summary: "The login handler panics"}})
+	assert.Equal(t, 7, run.Entries)
+	assert.Equal(t, 6, run.Errors)
+	require.NotEmpty(t, run.Findings)
+	assert.Equal(t, "error", run.Findings[0].Severity)
+
+	_, err = recordTicketRun("MM-1234", run)
+	require.NoError(t, err)
+	workspace, err = addWorkspaceNote("MM-1234", "Customer upgraded to 10.5")
+	require.NoError(t, err)
+	require.Len(t, workspace.Runs, 1)
+
+	// The next analyses are given the earlier findings and the notes
+	workspace, _, err = loadTicketWorkspace("MM-1234")
+	require.NoError(t, err)
+	context := workspace.priorContext()
+	assert.Contains(t, context, "Earlier analyses of ticket MM-1234, oldest first:")
+	assert.Contains(t, context, "support-packet packet-1.zip of ")
+	assert.Contains(t, context, "7 entries, 6 errors, from 2025-01-01 10:00 to 2025-01-01 10:01")
+	assert.Contains(t, context, "  Problem: Users cannot log in\n")
+	assert.Contains(t, context, "  - AI [error] Nil pointer in the SAML handler: The login handler panics\n")
+	assert.Contains(t, context, ": Customer upgraded to 10.5\n")
+	assert.Contains(t, context, "whether these logs show that it persists")
+
+	prompt, err := prepareAnalysisPrompts(logs, LLMConfig{TicketContext: context, Problem: "Users cannot log in", Writer: &bytes.Buffer{}})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.Instructions, context+"\n\nI'm investigating this problem: Users cannot log in")
+
+	var buf bytes.Buffer
+	displayTicketWorkspace(workspace, &buf)
+	assert.Contains(t, buf.String(), "(1 run, 1 note)")
+	assert.Contains(t, buf.String(), "1) support-packet packet-1.zip")
+
+	_, _, err = loadTicketWorkspace("../MM-1234")
+	assert.EqualError(t, err, `invalid --ticket "../MM-1234": expected letters, digits, dots, dashes and underscores, such as MM-1234`)
+}